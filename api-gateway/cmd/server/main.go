@@ -9,13 +9,16 @@ import (
 	"syscall"
 	"time"
 
+	"truckify/api-gateway/internal/compose"
 	"truckify/api-gateway/internal/config"
+	"truckify/api-gateway/internal/graph"
 	"truckify/api-gateway/internal/middleware"
 	"truckify/api-gateway/internal/router"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
 	sharedMiddleware "truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
@@ -26,6 +29,12 @@ func main() {
 	log := logger.New("api-gateway", cfg.LogLevel)
 	log.Info("Starting API Gateway", "port", cfg.Port)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "api-gateway")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize Redis for rate limiting and caching
 	redisClient, err := database.NewRedisClient(database.RedisConfig{
 		Host:     cfg.RedisHost,
@@ -39,21 +48,70 @@ func main() {
 	defer database.CloseRedisClient(redisClient)
 	log.Info("Connected to Redis")
 
-	// Initialize JWT manager
-	jwtManager := jwt.NewJWTManager(
-		cfg.JWTSecret,
-		15*time.Minute, // Access token TTL
-		7*24*time.Hour, // Refresh token TTL
-	)
+	// Initialize JWT verification. With a JWKS URL the gateway only holds the
+	// auth service's public keys and cannot mint tokens itself.
+	var jwtManager *jwt.JWTManager
+	switch {
+	case cfg.JWKSURL != "":
+		keys := jwt.NewRemoteKeySet(cfg.JWKSURL, cfg.JWKSCacheTTL)
+		if err := keys.Refresh(); err != nil {
+			log.Warn("Failed to fetch JWKS, will retry on first request", "url", cfg.JWKSURL, "error", err)
+		}
+		jwtManager = jwt.NewVerifier(keys)
+		log.Info("Verifying tokens with JWKS", "url", cfg.JWKSURL)
+	case cfg.JWTSecret != "":
+		jwtManager = jwt.NewJWTManager(
+			cfg.JWTSecret,
+			15*time.Minute, // Access token TTL
+			7*24*time.Hour, // Refresh token TTL
+		)
+	default:
+		log.Fatal("Either JWKS_URL or JWT_SECRET must be set")
+	}
 
 	// Initialize middlewares
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager, jwt.NewRedisDenylist(redisClient))
 	rateLimiter := sharedMiddleware.NewRateLimiter(redisClient, cfg.RateLimitRequests, cfg.RateLimitWindow)
+	trustedProxies, err := sharedMiddleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	rateLimiter.SetTrustedProxies(trustedProxies)
+	authMiddleware.SetTrustedProxies(trustedProxies)
 
 	// Setup router
-	r := router.NewRouter(log, authMiddleware, rateLimiter, cfg.AllowedOrigins)
+	r := router.NewRouter(log, authMiddleware, rateLimiter, cfg.AllowedOrigins, compose.NewRedisCache(redisClient),
+		graph.Limits{MaxDepth: cfg.GraphQLMaxDepth, MaxComplexity: cfg.GraphQLMaxComplexity})
+
+	r.SetTrustedProxies(trustedProxies)
+
+	// API keys are verified by the auth service, reached through the routing table
+	authMiddleware.SetKeyVerifier(r)
 	handler := r.Setup()
 
+	// Load the routing table and pick up changes to it without a restart
+	routes, err := config.LoadRoutes(cfg.RoutesFile)
+	if err != nil {
+		log.Fatal("Failed to load routes", "file", cfg.RoutesFile, "error", err)
+	}
+	if err := r.Load(routes); err != nil {
+		log.Fatal("Failed to build routing table", "error", err)
+	}
+	defer r.Close()
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go config.WatchRoutes(watchCtx, cfg.RoutesFile, cfg.RoutesReload,
+		func(routes *config.Routes) {
+			if err := r.Load(routes); err != nil {
+				log.Error("Failed to reload routes", "error", err)
+			}
+		},
+		func(err error) {
+			log.Error("Failed to reload routes, keeping previous table", "error", err)
+		},
+	)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Port),
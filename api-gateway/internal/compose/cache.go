@@ -0,0 +1,39 @@
+package compose
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache holds fragment data shared between callers. Errors count as misses,
+// so a cache outage only costs upstream calls.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// RedisCache is a Cache in Redis, shared by every gateway instance
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache creates a cache on a Redis client
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+// Get returns the value stored under key
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores value under key for ttl
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	c.client.Set(ctx, key, value, ttl)
+}
@@ -0,0 +1,251 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"truckify/api-gateway/internal/proxy"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/response"
+)
+
+// DefaultTimeout bounds a fragment that does not set its own timeout
+const DefaultTimeout = 2 * time.Second
+
+// maxFragmentBytes caps how much of an upstream response is read
+const maxFragmentBytes = 4 << 20
+
+// Field error codes for fragments that could not be fetched. Errors
+// returned by the upstream keep the upstream's code.
+const (
+	CodeTimeout       = "TIMEOUT"
+	CodeUnavailable   = "UNAVAILABLE"
+	CodeUpstreamError = "UPSTREAM_ERROR"
+)
+
+var fragmentResults = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_view_fragments_total",
+	Help: "Fragments fetched for composed views, by result.",
+}, []string{"view", "fragment", "result"})
+
+// Doer sends a request to an upstream service
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Fragment is one field of a view, fetched from an upstream service
+type Fragment struct {
+	Name     string
+	Upstream string
+	// Path builds the upstream path and query from the view request
+	Path func(r *http.Request) string
+	// Timeout bounds the upstream call; DefaultTimeout when zero
+	Timeout time.Duration
+	// Required fragments fail the whole view, e.g. the job of a job view
+	Required bool
+	// CacheTTL shares the upstream data between callers for a while. Only
+	// set it for data that is the same whoever asks.
+	CacheTTL time.Duration
+	// Transform reshapes the upstream data for the view
+	Transform func(r *http.Request, data json.RawMessage) (json.RawMessage, error)
+}
+
+// View is an aggregate endpoint whose fragments are fetched concurrently
+type View struct {
+	Name      string
+	Fragments []Fragment
+}
+
+// FieldError reports why a fragment is missing from a view
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Status is the upstream's HTTP status, when it answered
+	Status int `json:"status,omitempty"`
+}
+
+// Composer serves views by fanning out to the upstream services
+type Composer struct {
+	upstreams func(name string) Doer
+	cache     Cache
+	logger    *logger.Logger
+}
+
+// NewComposer creates a composer. upstreams looks up an upstream pool by
+// name and returns nil for unknown names; cache may be nil.
+func NewComposer(upstreams func(name string) Doer, cache Cache, log *logger.Logger) *Composer {
+	return &Composer{upstreams: upstreams, cache: cache, logger: log}
+}
+
+// Handler serves a view. Fragments that fail are null and reported under
+// "errors" with the rest of the view returned as usual, unless the fragment
+// is required, in which case its error is the response.
+func (c *Composer) Handler(view *View) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, _ := r.Context().Value("request_id").(string)
+
+		data := make([]json.RawMessage, len(view.Fragments))
+		errs := make([]*FieldError, len(view.Fragments))
+
+		var wg sync.WaitGroup
+		for i := range view.Fragments {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data[i], errs[i] = c.fetch(r, view, &view.Fragments[i])
+			}(i)
+		}
+		wg.Wait()
+
+		result := make(map[string]interface{}, len(view.Fragments)+1)
+		fieldErrors := map[string]*FieldError{}
+		for i, f := range view.Fragments {
+			if fe := errs[i]; fe != nil {
+				if f.Required {
+					response.Error(w, requiredStatus(fe), fe.Code, fe.Message, f.Name, requestID)
+					return
+				}
+				result[f.Name] = nil
+				fieldErrors[f.Name] = fe
+				continue
+			}
+			result[f.Name] = data[i]
+		}
+		if len(fieldErrors) > 0 {
+			result["errors"] = fieldErrors
+		}
+		response.Success(w, result, requestID)
+	})
+}
+
+// fetch returns a fragment's data from the cache or its upstream
+func (c *Composer) fetch(r *http.Request, view *View, f *Fragment) (json.RawMessage, *FieldError) {
+	path := f.Path(r)
+	cacheKey := "view_cache:" + f.Upstream + ":" + path
+
+	data, cached := c.cached(r.Context(), f, cacheKey)
+	if !cached {
+		var fe *FieldError
+		data, fe = c.call(r, f, path)
+		if fe != nil {
+			result := "error"
+			if fe.Code == CodeTimeout {
+				result = "timeout"
+			}
+			fragmentResults.WithLabelValues(view.Name, f.Name, result).Inc()
+			return nil, fe
+		}
+		if c.cache != nil && f.CacheTTL > 0 {
+			c.cache.Set(r.Context(), cacheKey, data, f.CacheTTL)
+		}
+		fragmentResults.WithLabelValues(view.Name, f.Name, "ok").Inc()
+	} else {
+		fragmentResults.WithLabelValues(view.Name, f.Name, "cached").Inc()
+	}
+
+	if f.Transform != nil {
+		transformed, err := f.Transform(r, data)
+		if err != nil {
+			c.logger.Warn("Failed to transform view fragment", "view", view.Name, "fragment", f.Name, "error", err)
+			return nil, &FieldError{Code: CodeUpstreamError, Message: "Unexpected response from " + f.Upstream}
+		}
+		data = transformed
+	}
+	return data, nil
+}
+
+func (c *Composer) cached(ctx context.Context, f *Fragment, key string) (json.RawMessage, bool) {
+	if c.cache == nil || f.CacheTTL <= 0 {
+		return nil, false
+	}
+	return c.cache.Get(ctx, key)
+}
+
+// call makes the upstream request for a fragment within its timeout
+func (c *Composer) call(r *http.Request, f *Fragment, path string) (json.RawMessage, *FieldError) {
+	upstream := c.upstreams(f.Upstream)
+	if upstream == nil {
+		return nil, &FieldError{Code: CodeUnavailable, Message: f.Upstream + " is not configured"}
+	}
+
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+f.Upstream+path, nil)
+	if err != nil {
+		return nil, &FieldError{Code: CodeUpstreamError, Message: "Invalid request for " + f.Name}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := upstream.Do(req)
+	if err != nil {
+		return nil, c.callError(r, f, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFragmentBytes))
+	if err != nil {
+		return nil, c.callError(r, f, err)
+	}
+
+	var envelope struct {
+		Data  json.RawMessage     `json:"data"`
+		Error *response.ErrorInfo `json:"error"`
+	}
+	decodeErr := json.Unmarshal(body, &envelope)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		fe := &FieldError{Code: CodeUpstreamError, Message: f.Upstream + " returned an error", Status: resp.StatusCode}
+		if decodeErr == nil && envelope.Error != nil {
+			fe.Code, fe.Message = envelope.Error.Code, envelope.Error.Message
+		}
+		return nil, fe
+	}
+	if decodeErr != nil {
+		c.logger.Warn("Invalid view fragment response", "fragment", f.Name, "upstream", f.Upstream, "error", decodeErr)
+		return nil, &FieldError{Code: CodeUpstreamError, Message: "Unexpected response from " + f.Upstream, Status: resp.StatusCode}
+	}
+	if len(envelope.Data) == 0 {
+		envelope.Data = json.RawMessage("null")
+	}
+	return envelope.Data, nil
+}
+
+// callError describes an upstream call that got no response
+func (c *Composer) callError(r *http.Request, f *Fragment, err error) *FieldError {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &FieldError{Code: CodeTimeout, Message: f.Upstream + " did not respond in time"}
+	case errors.Is(err, proxy.ErrCircuitOpen), errors.Is(err, proxy.ErrNoHealthyTargets):
+		return &FieldError{Code: CodeUnavailable, Message: f.Upstream + " is unavailable"}
+	}
+	if !errors.Is(r.Context().Err(), context.Canceled) {
+		c.logger.Warn("View fragment request failed", "fragment", f.Name, "upstream", f.Upstream, "error", err)
+	}
+	return &FieldError{Code: CodeUpstreamError, Message: "Request to " + f.Upstream + " failed"}
+}
+
+// requiredStatus is the status of a view whose required fragment failed
+func requiredStatus(fe *FieldError) int {
+	switch {
+	case fe.Status >= http.StatusBadRequest && fe.Status < http.StatusInternalServerError:
+		return fe.Status
+	case fe.Code == CodeTimeout:
+		return http.StatusGatewayTimeout
+	case fe.Code == CodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
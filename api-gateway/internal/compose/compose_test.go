@@ -0,0 +1,211 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/response"
+)
+
+// fakeUpstream serves upstream calls in process
+type fakeUpstream struct {
+	handler http.HandlerFunc
+	calls   atomic.Int32
+}
+
+func (f *fakeUpstream) Do(req *http.Request) (*http.Response, error) {
+	f.calls.Add(1)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		f.handler(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return rec.Result(), nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+func ok(data interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { response.Success(w, data, "") }
+}
+
+// mapCache is an in-process Cache
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func (c *mapCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+type viewResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Job          json.RawMessage        `json:"job"`
+		Bids         json.RawMessage        `json:"bids"`
+		Matches      json.RawMessage        `json:"matches"`
+		Conversation json.RawMessage        `json:"conversation"`
+		Ratings      json.RawMessage        `json:"ratings"`
+		Errors       map[string]*FieldError `json:"errors"`
+	} `json:"data"`
+	Error *response.ErrorInfo `json:"error"`
+}
+
+func serveView(t *testing.T, c *Composer, view *View, jobID string) (int, viewResponse) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/views/job/"+jobID, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": jobID})
+	r = r.WithContext(context.WithValue(r.Context(), "user_id", "user-1"))
+
+	w := httptest.NewRecorder()
+	c.Handler(view).ServeHTTP(w, r)
+
+	var body viewResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestPartialResultsWithFieldErrors(t *testing.T) {
+	upstreams := map[string]*fakeUpstream{
+		"job":     {handler: ok(map[string]string{"id": "job-1"})},
+		"bidding": {handler: ok([]string{"bid-1"})},
+		"matching": {handler: func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}},
+		"rating": {handler: func(w http.ResponseWriter, r *http.Request) {
+			response.Forbidden(w, "Not your job", "", "")
+		}},
+	}
+	c := NewComposer(func(name string) Doer {
+		if u, ok := upstreams[name]; ok {
+			return u
+		}
+		return nil
+	}, nil, logger.New("test", "error"))
+
+	view := &View{Name: "test", Fragments: []Fragment{
+		{Name: "job", Upstream: "job", Path: jobPath("/jobs/%s"), Required: true},
+		{Name: "bids", Upstream: "bidding", Path: jobPath("/jobs/%s/bids")},
+		{Name: "matches", Upstream: "matching", Path: jobPath("/matches/job/%s"), Timeout: 20 * time.Millisecond},
+		{Name: "ratings", Upstream: "rating", Path: jobPath("/ratings/job/%s")},
+		{Name: "conversation", Upstream: "notification", Path: fixedPath("/messages/conversations")},
+	}}
+
+	code, body := serveView(t, c, view, "job-1")
+	if code != http.StatusOK || !body.Success {
+		t.Fatalf("expected a successful partial view, got %d", code)
+	}
+	if string(body.Data.Job) != `{"id":"job-1"}` || string(body.Data.Bids) != `["bid-1"]` {
+		t.Fatalf("unexpected fragments: job %s, bids %s", body.Data.Job, body.Data.Bids)
+	}
+	if string(body.Data.Matches) != "null" {
+		t.Fatalf("failed fragment should be null, got %s", body.Data.Matches)
+	}
+
+	want := map[string]string{"matches": CodeTimeout, "ratings": "FORBIDDEN", "conversation": CodeUnavailable}
+	if len(body.Data.Errors) != len(want) {
+		t.Fatalf("expected errors for %v, got %v", want, body.Data.Errors)
+	}
+	for field, code := range want {
+		if fe := body.Data.Errors[field]; fe == nil || fe.Code != code {
+			t.Fatalf("expected %s error for %s, got %+v", code, field, fe)
+		}
+	}
+	if body.Data.Errors["ratings"].Status != http.StatusForbidden {
+		t.Fatalf("expected upstream status to be reported, got %d", body.Data.Errors["ratings"].Status)
+	}
+}
+
+func TestRequiredFragmentFailsView(t *testing.T) {
+	bids := &fakeUpstream{handler: ok([]string{})}
+	upstreams := map[string]*fakeUpstream{
+		"job": {handler: func(w http.ResponseWriter, r *http.Request) {
+			response.NotFound(w, "job not found", "", "")
+		}},
+		"bidding": bids,
+	}
+	c := NewComposer(func(name string) Doer { return upstreams[name] }, nil, logger.New("test", "error"))
+
+	code, body := serveView(t, c, &View{Name: "test", Fragments: []Fragment{
+		{Name: "job", Upstream: "job", Path: jobPath("/jobs/%s"), Required: true},
+		{Name: "bids", Upstream: "bidding", Path: jobPath("/jobs/%s/bids")},
+	}}, "missing")
+
+	if code != http.StatusNotFound || body.Error == nil || body.Error.Code != "NOT_FOUND" {
+		t.Fatalf("expected the job's 404, got %d %+v", code, body.Error)
+	}
+}
+
+func TestSharedFragmentsAreCached(t *testing.T) {
+	rating := &fakeUpstream{handler: ok(map[string]int{"average": 5})}
+	job := &fakeUpstream{handler: ok(map[string]string{"id": "job-1"})}
+	upstreams := map[string]*fakeUpstream{"rating": rating, "job": job}
+	cache := &mapCache{values: map[string][]byte{}}
+	c := NewComposer(func(name string) Doer { return upstreams[name] }, cache, logger.New("test", "error"))
+
+	view := &View{Name: "test", Fragments: []Fragment{
+		{Name: "job", Upstream: "job", Path: jobPath("/jobs/%s")},
+		{Name: "ratings", Upstream: "rating", Path: jobPath("/ratings/job/%s"), CacheTTL: time.Minute},
+	}}
+	for i := 0; i < 3; i++ {
+		_, body := serveView(t, c, view, "job-1")
+		if string(body.Data.Ratings) != `{"average":5}` {
+			t.Fatalf("unexpected ratings %s", body.Data.Ratings)
+		}
+	}
+
+	if n := rating.calls.Load(); n != 1 {
+		t.Fatalf("expected cached ratings to be fetched once, got %d", n)
+	}
+	if n := job.calls.Load(); n != 3 {
+		t.Fatalf("expected uncached job to be fetched every time, got %d", n)
+	}
+	if _, ok := cache.values["view_cache:rating:/ratings/job/job-1"]; !ok {
+		t.Fatalf("expected ratings under their upstream path, got %v", cache.values)
+	}
+}
+
+func TestJobConversation(t *testing.T) {
+	notification := &fakeUpstream{handler: ok([]map[string]string{
+		{"id": "c-1", "job_id": "job-1"},
+		{"id": "c-2", "job_id": "job-2"},
+	})}
+	c := NewComposer(func(name string) Doer { return notification }, nil, logger.New("test", "error"))
+	view := &View{Name: "test", Fragments: []Fragment{
+		{Name: "conversation", Upstream: "notification", Path: fixedPath("/messages/conversations"), Transform: jobConversation},
+	}}
+
+	_, body := serveView(t, c, view, "job-2")
+	if string(body.Data.Conversation) != `{"id":"c-2","job_id":"job-2"}` {
+		t.Fatalf("expected the job's conversation, got %s", body.Data.Conversation)
+	}
+
+	_, body = serveView(t, c, view, "job-3")
+	if string(body.Data.Conversation) != "null" {
+		t.Fatalf("expected no conversation, got %s", body.Data.Conversation)
+	}
+}
@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// JobView is everything the job detail screen shows, served at
+// /api/v1/views/job/{id}. Each service still authorizes its own fragment
+// against the caller's identity.
+var JobView = &View{
+	Name: "job",
+	Fragments: []Fragment{
+		{Name: "job", Upstream: "job", Path: jobPath("/jobs/%s"), Timeout: 3 * time.Second, Required: true},
+		{Name: "bids", Upstream: "bidding", Path: jobPath("/jobs/%s/bids")},
+		{Name: "matches", Upstream: "matching", Path: jobPath("/matches/job/%s")},
+		{Name: "tracking", Upstream: "tracking", Path: jobPath("/tracking/job/%s")},
+		{Name: "stops", Upstream: "tracking", Path: jobPath("/tracking/job/%s/stops")},
+		{Name: "conversation", Upstream: "notification", Path: jobPath("/messages/conversations?job_id=%s"), Transform: jobConversation},
+		{Name: "ratings", Upstream: "rating", Path: jobPath("/ratings/job/%s"), CacheTTL: time.Minute},
+	},
+}
+
+// DriverHomeView is the driver app's home screen, served at
+// /api/v1/views/driver-home
+var DriverHomeView = &View{
+	Name: "driver-home",
+	Fragments: []Fragment{
+		{Name: "driver", Upstream: "driver", Path: fixedPath("/driver")},
+		{Name: "pending_matches", Upstream: "matching", Path: fixedPath("/matches/pending")},
+		{Name: "bids", Upstream: "bidding", Path: fixedPath("/bids")},
+		// Open jobs are the same for every driver, so one fetch serves them all
+		{Name: "open_jobs", Upstream: "job", Path: fixedPath("/jobs?status=pending&limit=20"), CacheTTL: 15 * time.Second},
+		{Name: "conversations", Upstream: "notification", Path: fixedPath("/messages/conversations")},
+		{Name: "notifications", Upstream: "notification", Path: userPath("/notifications/user/%s")},
+		{Name: "ratings", Upstream: "rating", Path: userPath("/ratings/user/%s"), CacheTTL: time.Minute},
+	},
+}
+
+// fixedPath is a path that does not depend on the request
+func fixedPath(path string) func(*http.Request) string {
+	return func(*http.Request) string { return path }
+}
+
+// jobPath fills the job ID of the view request into a path
+func jobPath(format string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return fmt.Sprintf(format, url.PathEscape(mux.Vars(r)["id"]))
+	}
+}
+
+// userPath fills the caller's user ID into a path
+func userPath(format string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		userID, _ := r.Context().Value("user_id").(string)
+		return fmt.Sprintf(format, url.PathEscape(userID))
+	}
+}
+
+// jobConversation picks the job's conversation out of the caller's
+// conversations, or null if there is none yet
+func jobConversation(r *http.Request, data json.RawMessage) (json.RawMessage, error) {
+	var conversations []json.RawMessage
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, err
+	}
+
+	jobID := mux.Vars(r)["id"]
+	for _, raw := range conversations {
+		var c struct {
+			JobID string `json:"job_id"`
+		}
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, err
+		}
+		if c.JobID == jobID {
+			return raw, nil
+		}
+	}
+	return json.RawMessage("null"), nil
+}
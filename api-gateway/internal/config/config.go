@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 
 	"truckify/shared/pkg/config"
@@ -8,32 +9,46 @@ import (
 
 // Config holds the API Gateway configuration
 type Config struct {
-	Port              string
-	LogLevel          string
-	JWTSecret         string
-	RedisHost         string
-	RedisPort         int
-	RedisPassword     string
-	RateLimitRequests int
-	RateLimitWindow   time.Duration
-	ConsulHost        string
-	ConsulPort        int
-	AllowedOrigins    []string
+	Port                 string
+	LogLevel             string
+	JWTSecret            string
+	JWKSURL              string
+	JWKSCacheTTL         time.Duration
+	RedisHost            string
+	RedisPort            int
+	RedisPassword        string
+	RateLimitRequests    int
+	RateLimitWindow      time.Duration
+	TrustedProxies       []string
+	ConsulHost           string
+	ConsulPort           int
+	AllowedOrigins       []string
+	RoutesFile           string
+	RoutesReload         time.Duration
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		Port:              config.GetEnv("PORT", "8000"),
-		LogLevel:          config.GetEnv("LOG_LEVEL", "info"),
-		JWTSecret:         config.MustGetEnv("JWT_SECRET"),
-		RedisHost:         config.GetEnv("REDIS_HOST", "localhost"),
-		RedisPort:         config.GetEnvInt("REDIS_PORT", 6379),
-		RedisPassword:     config.GetEnv("REDIS_PASSWORD", ""),
-		RateLimitRequests: config.GetEnvInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitWindow:   time.Duration(config.GetEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
-		ConsulHost:        config.GetEnv("CONSUL_HOST", "localhost"),
-		ConsulPort:        config.GetEnvInt("CONSUL_PORT", 8500),
-		AllowedOrigins:    []string{config.GetEnv("ALLOWED_ORIGINS", "*")},
+		Port:                 config.GetEnv("PORT", "8000"),
+		LogLevel:             config.GetEnv("LOG_LEVEL", "info"),
+		JWTSecret:            config.GetEnv("JWT_SECRET", ""),
+		JWKSURL:              config.GetEnv("JWKS_URL", ""),
+		JWKSCacheTTL:         time.Duration(config.GetEnvInt("JWKS_CACHE_TTL_SECONDS", 300)) * time.Second,
+		RedisHost:            config.GetEnv("REDIS_HOST", "localhost"),
+		RedisPort:            config.GetEnvInt("REDIS_PORT", 6379),
+		RedisPassword:        config.GetEnv("REDIS_PASSWORD", ""),
+		RateLimitRequests:    config.GetEnvInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitWindow:      time.Duration(config.GetEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+		TrustedProxies:       strings.Split(config.GetEnv("TRUSTED_PROXIES", ""), ","),
+		ConsulHost:           config.GetEnv("CONSUL_HOST", "localhost"),
+		ConsulPort:           config.GetEnvInt("CONSUL_PORT", 8500),
+		AllowedOrigins:       []string{config.GetEnv("ALLOWED_ORIGINS", "*")},
+		RoutesFile:           config.GetEnv("ROUTES_FILE", "config/routes.yaml"),
+		RoutesReload:         time.Duration(config.GetEnvInt("ROUTES_RELOAD_SECONDS", 5)) * time.Second,
+		GraphQLMaxDepth:      config.GetEnvInt("GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity: config.GetEnvInt("GRAPHQL_MAX_COMPLEXITY", 1000),
 	}
 }
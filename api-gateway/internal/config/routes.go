@@ -0,0 +1,365 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"truckify/shared/pkg/authz"
+)
+
+// Load balancing strategies
+const (
+	BalancerRoundRobin       = "round_robin"
+	BalancerLeastConnections = "least_connections"
+)
+
+// Route authentication requirements
+const (
+	AuthRequired = "required"
+	AuthOptional = "optional"
+	AuthNone     = "none"
+)
+
+// Rate limiting algorithms
+const (
+	RateLimitTokenBucket = "token_bucket"
+	RateLimitSlidingLog  = "sliding_log"
+)
+
+// Reserved rate limit policy names
+const (
+	// RateLimitDefault applies to routes that do not name a policy
+	RateLimitDefault = "default"
+	// RateLimitNone turns rate limiting off for a route
+	RateLimitNone = "none"
+)
+
+// Routes is the gateway routing table: the upstream service pools, the rate
+// limit policies and the routes that use them
+type Routes struct {
+	Upstreams  map[string]*Upstream  `yaml:"upstreams"`
+	RateLimits map[string]*RateLimit `yaml:"rate_limits"`
+	Routes     []*Route              `yaml:"routes"`
+}
+
+// Upstream is a pool of instances of one service
+type Upstream struct {
+	Targets        []string       `yaml:"targets"`
+	Balancer       string         `yaml:"balancer"`
+	HealthCheck    HealthCheck    `yaml:"health_check"`
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+	Retry          Retry          `yaml:"retry"`
+	// DialTimeout bounds connecting to a target
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+}
+
+// HealthCheck configures active health checks against each target
+type HealthCheck struct {
+	Path               string        `yaml:"path"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+}
+
+// CircuitBreaker configures the breaker that stops sending requests to an
+// upstream that keeps failing
+type CircuitBreaker struct {
+	// FailureThreshold consecutive failures open the breaker
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenTimeout is how long the breaker stays open before letting probe
+	// requests through
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// HalfOpenRequests probes must succeed to close the breaker again
+	HalfOpenRequests int `yaml:"half_open_requests"`
+}
+
+// Retry configures retries of idempotent requests. Only GET and HEAD
+// requests are retried, on connection errors and 502, 503 and 504 responses.
+type Retry struct {
+	// Attempts is the total number of tries; 1 disables retries
+	Attempts int `yaml:"attempts"`
+	// Backoff is the base delay before a retry. It doubles with each attempt
+	// up to MaxBackoff, and the actual wait is a random duration between half
+	// and all of it so retries from many clients spread out.
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// RateLimitQuota allows Limit requests per Window. A token_bucket (the
+// default) allows bursts of up to Limit and refills steadily; a sliding_log
+// allows at most Limit requests in any Window.
+type RateLimitQuota struct {
+	Algorithm string        `yaml:"algorithm"`
+	Limit     int           `yaml:"limit"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// RateLimit is a rate limit policy. Callers are counted by user when signed
+// in and by client IP otherwise, and get the quota for their user type if
+// there is one. User type quotas inherit unset fields from the policy.
+type RateLimit struct {
+	RateLimitQuota `yaml:",inline"`
+	UserTypes      map[string]*RateLimitQuota `yaml:"user_types"`
+}
+
+// Route forwards matching requests to an upstream. Routes are matched in
+// order, so more specific paths go first.
+type Route struct {
+	Name string `yaml:"name"`
+	// Path is a path template such as /api/v1/jobs/{id}/bids that must
+	// match exactly; Prefix matches the path and everything below it
+	Path    string   `yaml:"path"`
+	Prefix  string   `yaml:"prefix"`
+	Methods []string `yaml:"methods"`
+
+	Upstream string `yaml:"upstream"`
+	// StripPrefix is removed from the path before forwarding
+	StripPrefix string `yaml:"strip_prefix"`
+
+	// Auth is required, optional or none
+	Auth string `yaml:"auth"`
+	// Roles restricts a route to the listed user types
+	Roles []string `yaml:"roles"`
+	// Scopes opens a route to API clients and acting dispatchers holding
+	// any of the listed permissions. Routes without scopes are closed to
+	// them.
+	Scopes []string `yaml:"scopes"`
+
+	// Timeout bounds the upstream request; zero leaves it unbounded, which
+	// long-lived connections such as WebSockets need
+	Timeout time.Duration `yaml:"timeout"`
+
+	// RateLimit names the route's rate limit policy, or none
+	RateLimit string `yaml:"rate_limit"`
+}
+
+// LoadRoutes reads the routing table from a YAML or JSON file. Environment
+// variables in the file are expanded, so upstream targets can be set per
+// deployment.
+func LoadRoutes(path string) (*Routes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes Routes
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &routes); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := routes.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &routes, nil
+}
+
+// validate checks references and fills in defaults
+func (r *Routes) validate() error {
+	if len(r.Routes) == 0 {
+		return errors.New("no routes defined")
+	}
+
+	for name, up := range r.Upstreams {
+		if len(up.Targets) == 0 {
+			return fmt.Errorf("upstream %q: no targets", name)
+		}
+		for _, target := range up.Targets {
+			u, err := url.Parse(target)
+			if err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+				return fmt.Errorf("upstream %q: invalid target %q", name, target)
+			}
+		}
+
+		switch up.Balancer {
+		case "":
+			up.Balancer = BalancerRoundRobin
+		case BalancerRoundRobin, BalancerLeastConnections:
+		default:
+			return fmt.Errorf("upstream %q: unknown balancer %q", name, up.Balancer)
+		}
+
+		hc := &up.HealthCheck
+		if hc.Path == "" {
+			hc.Path = "/health"
+		}
+		if hc.Interval == 0 {
+			hc.Interval = 10 * time.Second
+		}
+		if hc.Timeout == 0 {
+			hc.Timeout = 2 * time.Second
+		}
+		if hc.UnhealthyThreshold == 0 {
+			hc.UnhealthyThreshold = 3
+		}
+		if hc.HealthyThreshold == 0 {
+			hc.HealthyThreshold = 1
+		}
+
+		cb := &up.CircuitBreaker
+		if cb.FailureThreshold == 0 {
+			cb.FailureThreshold = 5
+		}
+		if cb.OpenTimeout == 0 {
+			cb.OpenTimeout = 30 * time.Second
+		}
+		if cb.HalfOpenRequests == 0 {
+			cb.HalfOpenRequests = 1
+		}
+
+		retry := &up.Retry
+		if retry.Attempts == 0 {
+			retry.Attempts = 3
+		}
+		if retry.Backoff == 0 {
+			retry.Backoff = 50 * time.Millisecond
+		}
+		if retry.MaxBackoff == 0 {
+			retry.MaxBackoff = time.Second
+		}
+		if cb.FailureThreshold < 0 || cb.HalfOpenRequests < 0 || retry.Attempts < 0 {
+			return fmt.Errorf("upstream %q: negative circuit breaker or retry setting", name)
+		}
+
+		if up.DialTimeout == 0 {
+			up.DialTimeout = 3 * time.Second
+		}
+	}
+
+	for name, rl := range r.RateLimits {
+		if name == RateLimitNone {
+			return fmt.Errorf("rate limit %q: name is reserved", name)
+		}
+		if err := rl.RateLimitQuota.validate(nil); err != nil {
+			return fmt.Errorf("rate limit %q: %w", name, err)
+		}
+		for userType, quota := range rl.UserTypes {
+			if err := quota.validate(&rl.RateLimitQuota); err != nil {
+				return fmt.Errorf("rate limit %q, user type %q: %w", name, userType, err)
+			}
+		}
+	}
+
+	for i, route := range r.Routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route-%d", i)
+		}
+		if (route.Path == "") == (route.Prefix == "") {
+			return fmt.Errorf("route %q: exactly one of path or prefix is required", route.Name)
+		}
+		if _, ok := r.Upstreams[route.Upstream]; !ok {
+			return fmt.Errorf("route %q: unknown upstream %q", route.Name, route.Upstream)
+		}
+		for j, method := range route.Methods {
+			route.Methods[j] = strings.ToUpper(method)
+		}
+
+		switch route.Auth {
+		case "":
+			route.Auth = AuthRequired
+		case AuthRequired, AuthOptional, AuthNone:
+		default:
+			return fmt.Errorf("route %q: unknown auth requirement %q", route.Name, route.Auth)
+		}
+		if len(route.Roles) > 0 && route.Auth != AuthRequired {
+			return fmt.Errorf("route %q: roles need auth: required", route.Name)
+		}
+		if len(route.Scopes) > 0 && route.Auth == AuthNone {
+			return fmt.Errorf("route %q: scopes need auth", route.Name)
+		}
+		for _, scope := range route.Scopes {
+			if !knownPermission(scope) {
+				return fmt.Errorf("route %q: unknown scope %q", route.Name, scope)
+			}
+		}
+
+		switch route.RateLimit {
+		case "":
+			route.RateLimit = RateLimitDefault
+		case RateLimitNone, RateLimitDefault:
+		default:
+			if _, ok := r.RateLimits[route.RateLimit]; !ok {
+				return fmt.Errorf("route %q: unknown rate limit %q", route.Name, route.RateLimit)
+			}
+		}
+	}
+	return nil
+}
+
+func knownPermission(scope string) bool {
+	for _, perm := range authz.Permissions {
+		if string(perm) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a quota, filling unset fields from parent or defaults
+func (q *RateLimitQuota) validate(parent *RateLimitQuota) error {
+	if parent != nil {
+		if q.Algorithm == "" {
+			q.Algorithm = parent.Algorithm
+		}
+		if q.Limit == 0 {
+			q.Limit = parent.Limit
+		}
+		if q.Window == 0 {
+			q.Window = parent.Window
+		}
+	}
+
+	switch q.Algorithm {
+	case "":
+		q.Algorithm = RateLimitTokenBucket
+	case RateLimitTokenBucket, RateLimitSlidingLog:
+	default:
+		return fmt.Errorf("unknown algorithm %q", q.Algorithm)
+	}
+	if q.Limit <= 0 || q.Window < time.Millisecond {
+		return errors.New("limit and window must be positive")
+	}
+	return nil
+}
+
+// WatchRoutes polls the routing table file and calls onChange with the new
+// table whenever it changes. A file that fails to load is reported through
+// onError and the previous table stays in effect.
+func WatchRoutes(ctx context.Context, path string, interval time.Duration, onChange func(*Routes), onError func(error)) {
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	last := modTime()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := modTime()
+			if current.IsZero() || current.Equal(last) {
+				continue
+			}
+			last = current
+
+			routes, err := LoadRoutes(path)
+			if err != nil {
+				onError(err)
+				continue
+			}
+			onChange(routes)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRoutes(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRoutes_ShippedConfig(t *testing.T) {
+	routes, err := LoadRoutes("../../config/routes.yaml")
+	if err != nil {
+		t.Fatalf("shipped routes.yaml does not load: %v", err)
+	}
+	if len(routes.Routes) == 0 || len(routes.Upstreams) == 0 {
+		t.Fatal("expected routes and upstreams")
+	}
+}
+
+func TestLoadRoutes_Defaults(t *testing.T) {
+	t.Setenv("JOB_SERVICE_URL", "http://job-1:8006")
+	path := writeRoutes(t, `
+upstreams:
+  job:
+    targets: [${JOB_SERVICE_URL}, http://job-2:8006]
+routes:
+  - prefix: /api/v1/jobs
+    upstream: job
+    methods: [get, post]
+    timeout: 5s
+`)
+
+	routes, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	up := routes.Upstreams["job"]
+	if up.Targets[0] != "http://job-1:8006" {
+		t.Errorf("expected env expansion, got %q", up.Targets[0])
+	}
+	if up.Balancer != BalancerRoundRobin {
+		t.Errorf("expected round_robin default, got %q", up.Balancer)
+	}
+	if up.HealthCheck.Path != "/health" || up.HealthCheck.Interval != 10*time.Second {
+		t.Errorf("unexpected health check defaults: %+v", up.HealthCheck)
+	}
+
+	route := routes.Routes[0]
+	if route.Auth != AuthRequired {
+		t.Errorf("expected auth required by default, got %q", route.Auth)
+	}
+	if route.Timeout != 5*time.Second {
+		t.Errorf("expected 5s timeout, got %v", route.Timeout)
+	}
+	if route.Methods[0] != "GET" {
+		t.Errorf("expected upper-case methods, got %v", route.Methods)
+	}
+}
+
+func TestLoadRoutes_JSON(t *testing.T) {
+	path := writeRoutes(t, `{
+  "upstreams": {"auth": {"targets": ["http://auth:8001"], "balancer": "least_connections"}},
+  "routes": [{"prefix": "/api/v1/auth", "upstream": "auth", "auth": "optional", "timeout": "10s"}]
+}`)
+
+	routes, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if routes.Routes[0].Timeout != 10*time.Second {
+		t.Errorf("expected 10s timeout, got %v", routes.Routes[0].Timeout)
+	}
+}
+
+func TestLoadRoutes_RateLimits(t *testing.T) {
+	path := writeRoutes(t, `
+upstreams:
+  auth:
+    targets: [http://auth:8001]
+rate_limits:
+  login:
+    algorithm: sliding_log
+    limit: 5
+    window: 1m
+    user_types:
+      admin: {limit: 50}
+routes:
+  - path: /api/v1/auth/login
+    upstream: auth
+    rate_limit: login
+  - prefix: /api/v1/auth
+    upstream: auth
+`)
+
+	routes, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	admin := routes.RateLimits["login"].UserTypes["admin"]
+	if admin.Algorithm != RateLimitSlidingLog || admin.Limit != 50 || admin.Window != time.Minute {
+		t.Errorf("expected user type quota to inherit from the policy, got %+v", admin)
+	}
+	if routes.Routes[1].RateLimit != RateLimitDefault {
+		t.Errorf("expected default rate limit, got %q", routes.Routes[1].RateLimit)
+	}
+}
+
+func TestLoadRoutes_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no routes", "upstreams: {}", "no routes"},
+		{"unknown upstream", `
+upstreams: {}
+routes:
+  - prefix: /x
+    upstream: missing`, "unknown upstream"},
+		{"no targets", `
+upstreams:
+  a: {targets: []}
+routes:
+  - prefix: /x
+    upstream: a`, "no targets"},
+		{"bad balancer", `
+upstreams:
+  a: {targets: [http://a:1], balancer: random}
+routes:
+  - prefix: /x
+    upstream: a`, "unknown balancer"},
+		{"path and prefix", `
+upstreams:
+  a: {targets: [http://a:1]}
+routes:
+  - prefix: /x
+    path: /x/y
+    upstream: a`, "exactly one of path or prefix"},
+		{"roles without auth", `
+upstreams:
+  a: {targets: [http://a:1]}
+routes:
+  - prefix: /x
+    upstream: a
+    auth: none
+    roles: [admin]`, "roles need auth"},
+		{"unknown scope", `
+upstreams:
+  a: {targets: [http://a:1]}
+routes:
+  - prefix: /x
+    upstream: a
+    scopes: [job:teleport]`, "unknown scope"},
+		{"unknown rate limit", `
+upstreams:
+  a: {targets: [http://a:1]}
+routes:
+  - prefix: /x
+    upstream: a
+    rate_limit: missing`, "unknown rate limit"},
+		{"bad rate limit algorithm", `
+upstreams:
+  a: {targets: [http://a:1]}
+rate_limits:
+  strict: {algorithm: leaky_bucket, limit: 1, window: 1s}
+routes:
+  - prefix: /x
+    upstream: a`, "unknown algorithm"},
+		{"rate limit without window", `
+upstreams:
+  a: {targets: [http://a:1]}
+rate_limits:
+  strict: {limit: 1}
+routes:
+  - prefix: /x
+    upstream: a`, "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRoutes(writeRoutes(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
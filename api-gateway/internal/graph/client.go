@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"truckify/api-gateway/internal/compose"
+	"truckify/api-gateway/internal/proxy"
+	"truckify/shared/pkg/response"
+)
+
+// maxResponseBytes caps how much of an upstream response is read
+const maxResponseBytes = 4 << 20
+
+// Error is a field that could not be resolved. The code is the upstream's
+// error code, or one of the compose field error codes if the upstream did
+// not answer.
+type Error struct {
+	Code    string
+	Message string
+	// Status is the upstream's HTTP status, when it answered
+	Status int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions adds the code and status to the GraphQL error
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if e.Status != 0 {
+		ext["status"] = e.Status
+	}
+	return ext
+}
+
+// client makes GET requests to the upstream services as the caller
+type client struct {
+	upstreams func(name string) compose.Doer
+}
+
+// get fetches path from an upstream and decodes the data of its response
+// envelope into out. The request carries ctx, so the caller's identity is
+// forwarded and each service authorizes the caller itself.
+func (c *client) get(ctx context.Context, upstream, path string, out interface{}) error {
+	doer := c.upstreams(upstream)
+	if doer == nil {
+		return &Error{Code: compose.CodeUnavailable, Message: upstream + " is not configured"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, compose.DefaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+upstream+path, nil)
+	if err != nil {
+		return &Error{Code: compose.CodeUpstreamError, Message: "Invalid request to " + upstream}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return callError(upstream, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return callError(upstream, err)
+	}
+
+	var envelope struct {
+		Data  json.RawMessage     `json:"data"`
+		Error *response.ErrorInfo `json:"error"`
+	}
+	decodeErr := json.Unmarshal(body, &envelope)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		e := &Error{Code: compose.CodeUpstreamError, Message: upstream + " returned an error", Status: resp.StatusCode}
+		if decodeErr == nil && envelope.Error != nil {
+			e.Code, e.Message = envelope.Error.Code, envelope.Error.Message
+		}
+		return e
+	}
+	if decodeErr == nil && out != nil && len(envelope.Data) > 0 {
+		decodeErr = json.Unmarshal(envelope.Data, out)
+	}
+	if decodeErr != nil {
+		return &Error{Code: compose.CodeUpstreamError, Message: "Unexpected response from " + upstream, Status: resp.StatusCode}
+	}
+	return nil
+}
+
+// callError describes an upstream call that got no response
+func callError(upstream string, err error) *Error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: compose.CodeTimeout, Message: upstream + " did not respond in time"}
+	case errors.Is(err, proxy.ErrCircuitOpen), errors.Is(err, proxy.ErrNoHealthyTargets):
+		return &Error{Code: compose.CodeUnavailable, Message: upstream + " is unavailable"}
+	}
+	return &Error{Code: compose.CodeUpstreamError, Message: "Request to " + upstream + " failed"}
+}
+
+// notFound reports whether err is an upstream 404
+func notFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
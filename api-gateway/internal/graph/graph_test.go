@@ -0,0 +1,332 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"truckify/api-gateway/internal/compose"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/response"
+)
+
+// fakeUpstreams serves upstream calls in process and records their paths
+type fakeUpstreams struct {
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	calls    map[string][]string
+}
+
+type fakeDoer struct {
+	name string
+	f    *fakeUpstreams
+}
+
+func (d fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	d.f.mu.Lock()
+	d.f.calls[d.name] = append(d.f.calls[d.name], req.URL.RequestURI())
+	d.f.mu.Unlock()
+	rec := httptest.NewRecorder()
+	d.f.handlers[d.name](rec, req)
+	return rec.Result(), nil
+}
+
+func (f *fakeUpstreams) lookup(name string) compose.Doer {
+	if _, ok := f.handlers[name]; !ok {
+		return nil
+	}
+	return fakeDoer{name: name, f: f}
+}
+
+func (f *fakeUpstreams) callsTo(name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[name]
+}
+
+func newTestServer(handlers map[string]http.HandlerFunc, feed Feed, limits Limits) (*Server, *fakeUpstreams) {
+	f := &fakeUpstreams{handlers: handlers, calls: map[string][]string{}}
+	authorize := func(ctx context.Context, token string) (context.Context, error) {
+		if token != "valid" {
+			return nil, errors.New("invalid token")
+		}
+		return context.WithValue(ctx, "user_id", "user-1"), nil
+	}
+	return NewServer(f.lookup, feed, authorize, limits, logger.New("test", "error")), f
+}
+
+type gqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, s *Server, q string) (int, gqlResponse) {
+	t.Helper()
+	body, _ := json.Marshal(Request{Query: q})
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	var res gqlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, res
+}
+
+func TestNestedFieldsAreBatched(t *testing.T) {
+	s, f := newTestServer(map[string]http.HandlerFunc{
+		"job": func(w http.ResponseWriter, r *http.Request) {
+			response.Success(w, []map[string]interface{}{
+				{"id": "job-1", "status": "assigned", "shipper_id": "s-1", "driver_id": "user-a"},
+				{"id": "job-2", "status": "assigned", "shipper_id": "s-1", "driver_id": "user-b"},
+				{"id": "job-3", "status": "assigned", "shipper_id": "s-1", "driver_id": "user-a"},
+			}, "")
+		},
+		"driver": func(w http.ResponseWriter, r *http.Request) {
+			var drivers []map[string]interface{}
+			for _, id := range strings.Split(r.URL.Query().Get("user_ids"), ",") {
+				drivers = append(drivers, map[string]interface{}{"id": "d-" + id, "user_id": id})
+			}
+			response.Success(w, drivers, "")
+		},
+		"bidding": func(w http.ResponseWriter, r *http.Request) {
+			response.Success(w, []map[string]interface{}{{"id": "bid-" + r.URL.Path, "job_id": "x", "driver_id": "y"}}, "")
+		},
+	}, nil, Limits{})
+
+	code, res := query(t, s, `{ jobs(status: "assigned") { id driver { id userId } bids { id } } }`)
+	if code != http.StatusOK || len(res.Errors) > 0 {
+		t.Fatalf("expected success, got %d %+v", code, res.Errors)
+	}
+
+	var jobs []struct {
+		ID     string `json:"id"`
+		Driver struct {
+			UserID string `json:"userId"`
+		} `json:"driver"`
+		Bids []struct {
+			ID string `json:"id"`
+		} `json:"bids"`
+	}
+	if err := json.Unmarshal(res.Data["jobs"], &jobs); err != nil || len(jobs) != 3 {
+		t.Fatalf("unexpected jobs %s", res.Data["jobs"])
+	}
+	if jobs[1].Driver.UserID != "user-b" || jobs[2].Driver.UserID != "user-a" {
+		t.Fatalf("drivers resolved to the wrong jobs: %+v", jobs)
+	}
+	if jobs[0].Bids[0].ID != "bid-/jobs/job-1/bids" {
+		t.Fatalf("bids resolved to the wrong job: %+v", jobs[0].Bids)
+	}
+
+	if calls := f.callsTo("driver"); len(calls) != 1 || calls[0] != "/drivers?user_ids=user-a%2Cuser-b" {
+		t.Fatalf("expected one batched driver request, got %v", calls)
+	}
+	if calls := f.callsTo("bidding"); len(calls) != 3 {
+		t.Fatalf("expected a bids request per job, got %v", calls)
+	}
+	if calls := f.callsTo("job"); len(calls) != 1 || !strings.Contains(calls[0], "status=assigned") {
+		t.Fatalf("unexpected job requests %v", calls)
+	}
+}
+
+func TestUpstreamErrorsAreFieldErrors(t *testing.T) {
+	s, _ := newTestServer(map[string]http.HandlerFunc{
+		"bidding": func(w http.ResponseWriter, r *http.Request) {
+			response.NotFound(w, "Bid not found", "", "")
+		},
+	}, nil, Limits{})
+
+	code, res := query(t, s, `{ bid(id: "missing") { id } myBids { id } pendingMatches { id } }`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if string(res.Data["bid"]) != "null" {
+		t.Fatalf("expected a null bid, got %s", res.Data["bid"])
+	}
+
+	codes := map[string]bool{}
+	for _, e := range res.Errors {
+		codes[e.Extensions["code"].(string)] = true
+	}
+	if !codes["NOT_FOUND"] || !codes[compose.CodeUnavailable] {
+		t.Fatalf("expected NOT_FOUND and %s errors, got %+v", compose.CodeUnavailable, res.Errors)
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	s, f := newTestServer(map[string]http.HandlerFunc{}, nil, Limits{MaxDepth: 4, MaxComplexity: 200})
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"too deep", `{ myBids { job { bids { job { id } } } } }`, CodeQueryTooDeep},
+		{"too deep through a fragment", `{ myBids { ...b } } fragment b on Bid { job { bids { job { id } } } }`, CodeQueryTooDeep},
+		{"list limit counts", `{ jobs(limit: 100) { id status } }`, CodeQueryTooComplex},
+		{"nested lists multiply", `{ jobs { bids { id amount status } } }`, CodeQueryTooComplex},
+		{"huge limits do not overflow", `{ jobs(limit: 9223372036854775807) { id status } }`, CodeQueryTooComplex},
+		{"subscription over HTTP", `subscription { jobStatus(jobId: "j") { toStatus } }`, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, res := query(t, s, tt.query)
+			if code != http.StatusBadRequest || len(res.Errors) != 1 || res.Errors[0].Extensions["code"] != tt.code {
+				t.Fatalf("expected %s, got %d %+v", tt.code, code, res.Errors)
+			}
+		})
+	}
+
+	if len(f.calls) != 0 {
+		t.Fatalf("rejected queries should not reach upstreams, got %v", f.calls)
+	}
+
+	// Within the limits, and introspection is not counted
+	if _, res := query(t, s, `{ __schema { types { name fields { name type { name ofType { name } } } } } }`); len(res.Errors) > 0 {
+		t.Fatalf("introspection should be allowed, got %+v", res.Errors)
+	}
+}
+
+// fakeFeed delivers the events sent on its channel
+type fakeFeed struct {
+	events chan json.RawMessage
+	topics chan string
+}
+
+func (f *fakeFeed) Subscribe(ctx context.Context, topic string) (<-chan json.RawMessage, error) {
+	f.topics <- topic
+	return f.events, nil
+}
+
+func TestJobStatusSubscription(t *testing.T) {
+	feed := &fakeFeed{events: make(chan json.RawMessage, 1), topics: make(chan string, 1)}
+	s, f := newTestServer(map[string]http.HandlerFunc{
+		"job": func(w http.ResponseWriter, r *http.Request) {
+			job := map[string]string{"id": "job-1", "status": "in_transit"}
+			if r.URL.Query().Get("ids") != "" {
+				response.Success(w, []interface{}{job}, "")
+				return
+			}
+			response.Success(w, job, "")
+		},
+	}, feed, Limits{MaxDepth: 8, MaxComplexity: 100})
+
+	srv := httptest.NewServer(http.HandlerFunc(s.ServeWebSocket))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	expect := func(msgType string) wsMessage {
+		t.Helper()
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type != msgType {
+			t.Fatalf("expected %s, got %s %s", msgType, msg.Type, msg.Payload)
+		}
+		return msg
+	}
+
+	conn.WriteJSON(wsMessage{Type: "connection_init", Payload: json.RawMessage(`{"Authorization":"Bearer valid"}`)})
+	expect("connection_ack")
+
+	payload, _ := json.Marshal(Request{Query: `subscription { jobStatus(jobId: "job-1") { toStatus job { status } } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload})
+
+	if topic := <-feed.topics; topic != "job:job-1" {
+		t.Fatalf("expected the job's topic, got %s", topic)
+	}
+	if calls := f.callsTo("job"); len(calls) != 1 || calls[0] != "/jobs/job-1" {
+		t.Fatalf("expected the job to be checked first, got %v", calls)
+	}
+
+	feed.events <- json.RawMessage(`{"job_id":"job-1","from_status":"picked_up","to_status":"in_transit"}`)
+	msg := expect("next")
+	if msg.ID != "1" || !strings.Contains(string(msg.Payload), `"toStatus":"in_transit"`) || !strings.Contains(string(msg.Payload), `"job":{"status":"in_transit"}`) {
+		t.Fatalf("unexpected event %s %s", msg.ID, msg.Payload)
+	}
+
+	conn.WriteJSON(wsMessage{Type: "ping"})
+	expect("pong")
+
+	close(feed.events)
+	if msg := expect("complete"); msg.ID != "1" {
+		t.Fatalf("expected subscription 1 to complete, got %s", msg.ID)
+	}
+}
+
+func TestDriverLocationSubscriptionForbidden(t *testing.T) {
+	feed := &fakeFeed{events: make(chan json.RawMessage), topics: make(chan string, 1)}
+	s, _ := newTestServer(map[string]http.HandlerFunc{
+		"tracking": func(w http.ResponseWriter, r *http.Request) {
+			response.Forbidden(w, "forbidden", "location:read", "")
+		},
+	}, feed, Limits{MaxDepth: 8, MaxComplexity: 100})
+
+	srv := httptest.NewServer(http.HandlerFunc(s.ServeWebSocket))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	conn.WriteJSON(wsMessage{Type: "connection_init", Payload: json.RawMessage(`{"Authorization":"Bearer valid"}`)})
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %s %v", msg.Type, err)
+	}
+
+	payload, _ := json.Marshal(Request{Query: `subscription { driverLocation(driverId: "driver-1") { latitude } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload})
+
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "error" || msg.ID != "1" {
+		t.Fatalf("expected the subscription to be refused, got %s %s %v", msg.Type, msg.Payload, err)
+	}
+	select {
+	case topic := <-feed.topics:
+		t.Fatalf("a refused subscription should not reach the hub, got %s", topic)
+	default:
+	}
+}
+
+func TestSubscriptionRequiresToken(t *testing.T) {
+	s, _ := newTestServer(map[string]http.HandlerFunc{}, nil, Limits{})
+	srv := httptest.NewServer(http.HandlerFunc(s.ServeWebSocket))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	conn.WriteJSON(wsMessage{Type: "connection_init", Payload: json.RawMessage(`{"token":"forged"}`)})
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, closeForbidden) {
+		t.Fatalf("expected the connection to be closed as forbidden, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Default query limits
+const (
+	DefaultMaxDepth      = 8
+	DefaultMaxComplexity = 1000
+)
+
+// defaultListSize is the number of items assumed for a list field without
+// a limit argument when working out a query's complexity
+const defaultListSize = 10
+
+// Error codes for rejected operations
+const (
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+	CodeBadRequest      = "BAD_REQUEST"
+)
+
+// Limits bound the queries a client may send. Depth is the nesting of
+// selection sets. Complexity counts every field, with the fields under a
+// list counted once per expected item.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// checker works out the depth and complexity of an operation
+type checker struct {
+	fragments  map[string]*ast.FragmentDefinition
+	variables  map[string]interface{}
+	listFields map[string]bool
+	// visiting guards against fragment cycles, which validation rejects
+	// later but which would otherwise recurse forever here
+	visiting map[string]bool
+}
+
+// checkLimits rejects an operation that is deeper or more complex than the
+// limits allow. listFields names the fields that return lists.
+func checkLimits(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}, listFields map[string]bool, limits Limits) error {
+	c := &checker{
+		fragments:  map[string]*ast.FragmentDefinition{},
+		variables:  variables,
+		listFields: listFields,
+		visiting:   map[string]bool{},
+	}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[frag.Name.Value] = frag
+		}
+	}
+
+	depth, complexity := c.measure(op.SelectionSet)
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return &Error{Code: CodeQueryTooDeep, Message: fmt.Sprintf("Query depth %d exceeds the limit of %d", depth, limits.MaxDepth)}
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return &Error{Code: CodeQueryTooComplex, Message: fmt.Sprintf("Query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)}
+	}
+	return nil
+}
+
+// measure returns the depth and complexity of a selection set
+func (c *checker) measure(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, n int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			// Introspection is answered by the gateway itself
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			childDepth, childComplexity := c.measure(sel.SelectionSet)
+			d = childDepth + 1
+			n = 1 + saturatingMul(c.multiplier(sel), childComplexity)
+		case *ast.InlineFragment:
+			d, n = c.measure(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := c.fragments[name]
+			if !ok || c.visiting[name] {
+				continue
+			}
+			c.visiting[name] = true
+			d, n = c.measure(frag.SelectionSet)
+			delete(c.visiting, name)
+		}
+		depth = max(depth, d)
+		complexity = min(complexity+n, maxComplexity)
+	}
+	return depth, complexity
+}
+
+// maxComplexity is where complexity stops counting, far above any limit,
+// so that deeply nested lists cannot overflow it
+const maxComplexity = math.MaxInt32
+
+// saturatingMul multiplies two non-negative numbers, stopping at
+// maxComplexity
+func saturatingMul(a, b int) int {
+	if a != 0 && b > maxComplexity/a {
+		return maxComplexity
+	}
+	return a * b
+}
+
+// multiplier is how many items a field is expected to return: its limit
+// argument for lists that take one, up to the most a page can hold,
+// defaultListSize for other lists, and one otherwise
+func (c *checker) multiplier(field *ast.Field) int {
+	if !c.listFields[field.Name.Value] {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			n, err := strconv.Atoi(v.Value)
+			if errors.Is(err, strconv.ErrRange) && !strings.HasPrefix(v.Value, "-") {
+				return maxListLimit
+			}
+			if err == nil && n > 0 {
+				return min(n, maxListLimit)
+			}
+		case *ast.Variable:
+			switch n := c.variables[v.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(min(n, maxListLimit))
+				}
+			case int:
+				if n > 0 {
+					return min(n, maxListLimit)
+				}
+			}
+		}
+	}
+	return defaultListSize
+}
+
+// operation returns the operation a request selects by name, or its only
+// operation
+func operation(doc *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil, &Error{Code: CodeBadRequest, Message: "operationName is required when the document has several operations"}
+			}
+			found = op
+			continue
+		}
+		if op.Name != nil && op.Name.Value == name {
+			return op, nil
+		}
+	}
+	if found == nil {
+		return nil, &Error{Code: CodeBadRequest, Message: "Unknown operation"}
+	}
+	return found, nil
+}
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxBatchIDs is the most IDs the services accept in one batch request
+const maxBatchIDs = 100
+
+// maxConcurrentFetches bounds the requests a loader without a batch
+// endpoint makes at once
+const maxConcurrentFetches = 8
+
+// result is a loaded value or the error loading it
+type result struct {
+	value interface{}
+	err   error
+}
+
+// fetchFunc loads the values of keys, returning a result per key. Keys
+// without a result resolve to null.
+type fetchFunc func(ctx context.Context, keys []string) map[string]result
+
+// loader collects the keys resolvers ask for while a level of the query is
+// resolved, then fetches them together the first time any of them is
+// needed, so a list of jobs costs one request for their drivers rather than
+// one per job. Results are kept for the rest of the request.
+type loader struct {
+	ctx     context.Context
+	fetch   fetchFunc
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	results map[string]result
+}
+
+func newLoader(ctx context.Context, fetch fetchFunc) *loader {
+	return &loader{ctx: ctx, fetch: fetch, queued: map[string]bool{}, results: map[string]result{}}
+}
+
+// load queues key and returns a thunk for its value. graphql-go calls the
+// thunks of a level only after resolving every field at that level.
+func (l *loader) load(key string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, done := l.results[key]; !done && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, done := l.results[key]; !done {
+			l.dispatch()
+		}
+		r := l.results[key]
+		return r.value, r.err
+	}
+}
+
+// dispatch fetches every pending key. It is called with l.mu held.
+func (l *loader) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	fetched := l.fetch(l.ctx, keys)
+	for _, key := range keys {
+		delete(l.queued, key)
+		l.results[key] = fetched[key]
+	}
+}
+
+// batched fetches keys in chunks of maxBatchIDs from an endpoint that takes
+// a comma-separated list, such as /jobs?ids=. id returns the key of each
+// item in the response.
+func batched[T any](c *client, upstream, path string, id func(*T) string) fetchFunc {
+	return func(ctx context.Context, keys []string) map[string]result {
+		results := make(map[string]result, len(keys))
+		for start := 0; start < len(keys); start += maxBatchIDs {
+			chunk := keys[start:min(start+maxBatchIDs, len(keys))]
+
+			var items []*T
+			err := c.get(ctx, upstream, path+url.QueryEscape(strings.Join(chunk, ",")), &items)
+			if err != nil {
+				for _, key := range chunk {
+					results[key] = result{err: err}
+				}
+				continue
+			}
+			for _, item := range items {
+				results[id(item)] = result{value: item}
+			}
+		}
+		return results
+	}
+}
+
+// each fetches every key with its own request, a few at a time, for data
+// that has no batch endpoint. format is the path with a %s for the key.
+func each[T any](c *client, upstream, format string) fetchFunc {
+	return func(ctx context.Context, keys []string) map[string]result {
+		results := make(map[string]result, len(keys))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, maxConcurrentFetches)
+		for _, key := range keys {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(key string) {
+				defer func() { <-sem; wg.Done() }()
+				var value T
+				err := c.get(ctx, upstream, fmt.Sprintf(format, url.PathEscape(key)), &value)
+				mu.Lock()
+				results[key] = result{value: value, err: err}
+				mu.Unlock()
+			}(key)
+		}
+		wg.Wait()
+		return results
+	}
+}
+
+// loaders are the per-request loaders, one per kind of lookup
+type loaders struct {
+	jobs          *loader
+	drivers       *loader // by user ID
+	driverProfile *loader // by profile ID
+	jobBids       *loader
+	jobMatches    *loader
+	jobTracking   *loader
+	jobRatings    *loader
+	userRatings   *loader
+}
+
+// ratingList is the rating service's list response
+type ratingList struct {
+	Ratings []*Rating `json:"ratings"`
+}
+
+func newLoaders(ctx context.Context, c *client) *loaders {
+	return &loaders{
+		jobs:          newLoader(ctx, batched(c, "job", "/jobs?ids=", func(j *Job) string { return j.ID })),
+		drivers:       newLoader(ctx, batched(c, "driver", "/drivers?user_ids=", func(d *Driver) string { return d.UserID })),
+		driverProfile: newLoader(ctx, each[*Driver](c, "driver", "/driver/%s")),
+		jobBids:       newLoader(ctx, each[[]*Bid](c, "bidding", "/jobs/%s/bids")),
+		jobMatches:    newLoader(ctx, each[[]*Match](c, "matching", "/matches/job/%s")),
+		jobTracking:   newLoader(ctx, each[[]*TrackingEvent](c, "tracking", "/tracking/job/%s")),
+		jobRatings:    newLoader(ctx, each[ratingList](c, "rating", "/ratings/job/%s")),
+		userRatings:   newLoader(ctx, each[ratingList](c, "rating", "/ratings/user/%s")),
+	}
+}
+
+type loadersKey struct{}
+
+// withLoaders gives a request its own loaders
+func withLoaders(ctx context.Context, c *client) context.Context {
+	return context.WithValue(ctx, loadersKey{}, newLoaders(ctx, c))
+}
+
+// loadersFrom returns the request's loaders. Subscription events get fresh
+// loaders each time, since cached results would go stale between events.
+func loadersFrom(ctx context.Context, c *client) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(ctx, c)
+}
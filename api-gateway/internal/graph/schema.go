@@ -0,0 +1,528 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"truckify/shared/pkg/pagination"
+)
+
+// maxListLimit caps the limit argument of list fields at what the services
+// return in one page
+const maxListLimit = pagination.MaxLimit
+
+// newSchema builds the schema. Every field is resolved by calling the
+// service that owns the data as the caller, through the request's loaders.
+func (s *Server) newSchema() (graphql.Schema, error) {
+	location := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Location",
+		Fields: graphql.Fields{
+			"city":    {Type: graphql.String},
+			"state":   {Type: graphql.String},
+			"address": {Type: graphql.String},
+			"lat":     {Type: graphql.Float},
+			"lng":     {Type: graphql.Float},
+		},
+	})
+
+	job := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Job",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.NewNonNull(graphql.ID)},
+			"shipperId":    {Type: graphql.NewNonNull(graphql.ID)},
+			"driverId":     {Type: graphql.ID, Description: "User ID of the assigned driver"},
+			"status":       {Type: graphql.NewNonNull(graphql.String)},
+			"pickup":       {Type: location},
+			"delivery":     {Type: location},
+			"pickupDate":   {Type: graphql.DateTime},
+			"deliveryDate": {Type: graphql.DateTime},
+			"cargoType":    {Type: graphql.String},
+			"weight":       {Type: graphql.Float},
+			"vehicleType":  {Type: graphql.String},
+			"price":        {Type: graphql.Float},
+			"distance":     {Type: graphql.Float},
+			"notes":        {Type: graphql.String},
+			"createdAt":    {Type: graphql.DateTime},
+			"updatedAt":    {Type: graphql.DateTime},
+		},
+	})
+
+	bid := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Bid",
+		Fields: graphql.Fields{
+			"id":        {Type: graphql.NewNonNull(graphql.ID)},
+			"jobId":     {Type: graphql.NewNonNull(graphql.ID)},
+			"driverId":  {Type: graphql.NewNonNull(graphql.ID), Description: "User ID of the bidding driver"},
+			"amount":    {Type: graphql.Float},
+			"notes":     {Type: graphql.String},
+			"status":    {Type: graphql.String},
+			"expiresAt": {Type: graphql.DateTime},
+			"createdAt": {Type: graphql.DateTime},
+			"updatedAt": {Type: graphql.DateTime},
+		},
+	})
+
+	match := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Match",
+		Fields: graphql.Fields{
+			"id":         {Type: graphql.NewNonNull(graphql.ID)},
+			"jobId":      {Type: graphql.NewNonNull(graphql.ID)},
+			"driverId":   {Type: graphql.NewNonNull(graphql.ID), Description: "Profile ID of the matched driver"},
+			"score":      {Type: graphql.Float},
+			"distanceKm": {Type: graphql.Float},
+			"status":     {Type: graphql.String},
+			"expiresAt":  {Type: graphql.DateTime},
+			"createdAt":  {Type: graphql.DateTime},
+		},
+	})
+
+	vehicle := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Vehicle",
+		Fields: graphql.Fields{
+			"id":              {Type: graphql.NewNonNull(graphql.ID)},
+			"fleetId":         {Type: graphql.ID},
+			"currentDriverId": {Type: graphql.ID},
+			"type":            {Type: graphql.String},
+			"make":            {Type: graphql.String},
+			"model":           {Type: graphql.String},
+			"year":            {Type: graphql.Int},
+			"plate":           {Type: graphql.String},
+			"vin":             {Type: graphql.String},
+			"capacity":        {Type: graphql.Float},
+			"regoExpiry":      {Type: graphql.DateTime},
+			"insuranceExpiry": {Type: graphql.DateTime},
+			"status":          {Type: graphql.String},
+			"currentLocation": {Type: location},
+		},
+	})
+
+	driver := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Driver",
+		Fields: graphql.Fields{
+			"id":              {Type: graphql.NewNonNull(graphql.ID)},
+			"userId":          {Type: graphql.NewNonNull(graphql.ID)},
+			"licenseState":    {Type: graphql.String},
+			"licenseClass":    {Type: graphql.String},
+			"licenseExpiry":   {Type: graphql.DateTime},
+			"yearsExperience": {Type: graphql.Int},
+			"isAvailable":     {Type: graphql.Boolean},
+			"currentLocation": {Type: location},
+			"vehicle":         {Type: vehicle},
+			"rating":          {Type: graphql.Float},
+			"totalTrips":      {Type: graphql.Int},
+			"status":          {Type: graphql.String},
+			"createdAt":       {Type: graphql.DateTime},
+			"updatedAt":       {Type: graphql.DateTime},
+		},
+	})
+
+	fleet := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Fleet",
+		Fields: graphql.Fields{
+			"id":        {Type: graphql.NewNonNull(graphql.ID)},
+			"ownerId":   {Type: graphql.NewNonNull(graphql.ID)},
+			"name":      {Type: graphql.String},
+			"abn":       {Type: graphql.String},
+			"status":    {Type: graphql.String},
+			"createdAt": {Type: graphql.DateTime},
+			"updatedAt": {Type: graphql.DateTime},
+		},
+	})
+
+	payment := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Payment",
+		Fields: graphql.Fields{
+			"id":           {Type: graphql.NewNonNull(graphql.ID)},
+			"jobId":        {Type: graphql.NewNonNull(graphql.ID)},
+			"payerId":      {Type: graphql.ID},
+			"payeeId":      {Type: graphql.ID},
+			"amount":       {Type: graphql.Float},
+			"platformFee":  {Type: graphql.Float},
+			"driverPayout": {Type: graphql.Float},
+			"status":       {Type: graphql.String},
+			"createdAt":    {Type: graphql.DateTime},
+			"updatedAt":    {Type: graphql.DateTime},
+		},
+	})
+
+	rating := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Rating",
+		Fields: graphql.Fields{
+			"id":        {Type: graphql.NewNonNull(graphql.ID)},
+			"jobId":     {Type: graphql.NewNonNull(graphql.ID)},
+			"raterId":   {Type: graphql.ID},
+			"rateeId":   {Type: graphql.ID},
+			"rating":    {Type: graphql.Int},
+			"comment":   {Type: graphql.String},
+			"createdAt": {Type: graphql.DateTime},
+		},
+	})
+
+	trackingEvent := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TrackingEvent",
+		Fields: graphql.Fields{
+			"id":        {Type: graphql.ID},
+			"jobId":     {Type: graphql.ID},
+			"driverId":  {Type: graphql.NewNonNull(graphql.ID), Description: "User ID of the driver"},
+			"latitude":  {Type: graphql.Float},
+			"longitude": {Type: graphql.Float},
+			"speed":     {Type: graphql.Float},
+			"heading":   {Type: graphql.Float},
+			"timestamp": {Type: graphql.DateTime},
+			"eventType": {Type: graphql.String},
+		},
+	})
+
+	jobStatusEvent := graphql.NewObject(graphql.ObjectConfig{
+		Name: "JobStatusEvent",
+		Fields: graphql.Fields{
+			"jobId":      {Type: graphql.NewNonNull(graphql.ID)},
+			"shipperId":  {Type: graphql.ID},
+			"driverId":   {Type: graphql.ID},
+			"fromStatus": {Type: graphql.String},
+			"toStatus":   {Type: graphql.NewNonNull(graphql.String)},
+			"changedAt":  {Type: graphql.DateTime},
+		},
+	})
+
+	// Lists are nullable so that a service failing nulls only its field
+	// rather than the object holding it
+	listOf := func(t graphql.Type) graphql.Output {
+		return graphql.NewList(graphql.NewNonNull(t))
+	}
+	limitArg := func(def int) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{"limit": {Type: graphql.Int, DefaultValue: def}}
+	}
+
+	// Relations between types, added once every type exists
+	jobField := &graphql.Field{Type: job, Resolve: s.resolveJobOf}
+	job.AddFieldConfig("driver", &graphql.Field{Type: driver, Resolve: s.resolveJobDriver})
+	job.AddFieldConfig("bids", &graphql.Field{Type: listOf(bid), Resolve: s.resolveJobBids})
+	job.AddFieldConfig("matches", &graphql.Field{Type: listOf(match), Resolve: s.resolveJobMatches})
+	job.AddFieldConfig("tracking", &graphql.Field{
+		Type:        listOf(trackingEvent),
+		Description: "The job's most recent tracking events, oldest first",
+		Args:        limitArg(50),
+		Resolve:     s.resolveJobTracking,
+	})
+	job.AddFieldConfig("ratings", &graphql.Field{Type: listOf(rating), Resolve: s.resolveJobRatings})
+	bid.AddFieldConfig("job", jobField)
+	bid.AddFieldConfig("driver", &graphql.Field{Type: driver, Resolve: s.resolveDriverByUser})
+	match.AddFieldConfig("job", jobField)
+	match.AddFieldConfig("driver", &graphql.Field{Type: driver, Resolve: s.resolveMatchDriver})
+	driver.AddFieldConfig("ratings", &graphql.Field{Type: listOf(rating), Resolve: s.resolveDriverRatings})
+	fleet.AddFieldConfig("vehicles", &graphql.Field{Type: listOf(vehicle), Resolve: s.resolveFleetVehicles})
+	payment.AddFieldConfig("job", jobField)
+	rating.AddFieldConfig("job", jobField)
+	trackingEvent.AddFieldConfig("job", jobField)
+	jobStatusEvent.AddFieldConfig("job", jobField)
+
+	idArg := func(name string) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{name: {Type: graphql.NewNonNull(graphql.ID)}}
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"job": {Type: job, Args: idArg("id"), Resolve: s.resolveJob},
+			"jobs": {
+				Type: listOf(job),
+				Args: graphql.FieldConfigArgument{
+					"status":      {Type: graphql.String},
+					"vehicleType": {Type: graphql.String},
+					"limit":       {Type: graphql.Int, DefaultValue: 20},
+				},
+				Resolve: s.resolveJobs,
+			},
+			"bid":            {Type: bid, Args: idArg("id"), Resolve: s.resolveBid},
+			"myBids":         {Type: listOf(bid), Resolve: s.resolveMyBids},
+			"pendingMatches": {Type: listOf(match), Resolve: s.resolvePendingMatches},
+			"me":             {Type: driver, Description: "The caller's driver profile", Resolve: s.resolveMe},
+			"driver":         {Type: driver, Args: idArg("userId"), Resolve: s.resolveDriver},
+			"fleet": {
+				Type:        fleet,
+				Description: "A fleet by ID, or the caller's fleet",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.ID}},
+				Resolve:     s.resolveFleet,
+			},
+			"payment":        {Type: payment, Args: idArg("id"), Resolve: s.resolvePayment},
+			"ratings":        {Type: listOf(rating), Args: idArg("userId"), Resolve: s.resolveUserRatings},
+			"driverLocation": {Type: trackingEvent, Args: idArg("driverId"), Resolve: s.resolveDriverLocation},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"jobStatus": {
+				Type:      graphql.NewNonNull(jobStatusEvent),
+				Args:      idArg("jobId"),
+				Resolve:   eventSource,
+				Subscribe: s.subscribeJobStatus,
+			},
+			"driverLocation": {
+				Type:      graphql.NewNonNull(trackingEvent),
+				Args:      idArg("driverId"),
+				Resolve:   eventSource,
+				Subscribe: s.subscribeDriverLocation,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Subscription: subscription})
+}
+
+// listFields names the fields of a schema that return lists, for working
+// out query complexity
+func listFields(schema graphql.Schema) map[string]bool {
+	fields := map[string]bool{}
+	for name, t := range schema.TypeMap() {
+		obj, ok := t.(*graphql.Object)
+		if !ok || len(name) > 1 && name[:2] == "__" {
+			continue
+		}
+		for fieldName, field := range obj.Fields() {
+			out := field.Type
+			if nonNull, ok := out.(*graphql.NonNull); ok {
+				out = nonNull.OfType
+			}
+			if _, ok := out.(*graphql.List); ok {
+				fields[fieldName] = true
+			}
+		}
+	}
+	return fields
+}
+
+func (s *Server) loaders(ctx context.Context) *loaders {
+	return loadersFrom(ctx, s.client)
+}
+
+// get is a single lookup made for a root field
+func (s *Server) get(p graphql.ResolveParams, upstream, path string, out interface{}) error {
+	return s.client.get(p.Context, upstream, path, out)
+}
+
+// then maps the value of a loader thunk
+func then(thunk func() (interface{}, error), fn func(interface{}) interface{}) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		v, err := thunk()
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return fn(v), nil
+	}
+}
+
+// listOrEmpty turns a missing list into an empty one
+func listOrEmpty[T any](v interface{}) interface{} {
+	if list, ok := v.([]*T); ok && list != nil {
+		return list
+	}
+	return []*T{}
+}
+
+func (s *Server) resolveJob(p graphql.ResolveParams) (interface{}, error) {
+	return s.loaders(p.Context).jobs.load(p.Args["id"].(string)), nil
+}
+
+// resolveJobOf resolves the job of anything with a JobID
+func (s *Server) resolveJobOf(p graphql.ResolveParams) (interface{}, error) {
+	var jobID string
+	switch src := p.Source.(type) {
+	case *Bid:
+		jobID = src.JobID
+	case *Match:
+		jobID = src.JobID
+	case *Payment:
+		jobID = src.JobID
+	case *Rating:
+		jobID = src.JobID
+	case *TrackingEvent:
+		jobID = src.JobID
+	case *JobStatusEvent:
+		jobID = src.JobID
+	}
+	if jobID == "" {
+		return nil, nil
+	}
+	return s.loaders(p.Context).jobs.load(jobID), nil
+}
+
+func (s *Server) resolveJobs(p graphql.ResolveParams) (interface{}, error) {
+	query := url.Values{}
+	if status, ok := p.Args["status"].(string); ok {
+		query.Set("status", status)
+	}
+	if vehicleType, ok := p.Args["vehicleType"].(string); ok {
+		query.Set("vehicle_type", vehicleType)
+	}
+	query.Set("limit", strconv.Itoa(clampLimit(p.Args["limit"])))
+
+	var jobs []*Job
+	if err := s.get(p, "job", "/jobs?"+query.Encode(), &jobs); err != nil {
+		return nil, err
+	}
+	return listOrEmpty[Job](jobs), nil
+}
+
+func (s *Server) resolveJobDriver(p graphql.ResolveParams) (interface{}, error) {
+	job := p.Source.(*Job)
+	if job.DriverID == nil {
+		return nil, nil
+	}
+	return s.loaders(p.Context).drivers.load(*job.DriverID), nil
+}
+
+func (s *Server) resolveJobBids(p graphql.ResolveParams) (interface{}, error) {
+	return then(s.loaders(p.Context).jobBids.load(p.Source.(*Job).ID), listOrEmpty[Bid]), nil
+}
+
+func (s *Server) resolveJobMatches(p graphql.ResolveParams) (interface{}, error) {
+	return then(s.loaders(p.Context).jobMatches.load(p.Source.(*Job).ID), listOrEmpty[Match]), nil
+}
+
+func (s *Server) resolveJobTracking(p graphql.ResolveParams) (interface{}, error) {
+	limit := clampLimit(p.Args["limit"])
+	return then(s.loaders(p.Context).jobTracking.load(p.Source.(*Job).ID), func(v interface{}) interface{} {
+		events, _ := v.([]*TrackingEvent)
+		if len(events) > limit {
+			events = events[len(events)-limit:]
+		}
+		return listOrEmpty[TrackingEvent](events)
+	}), nil
+}
+
+func (s *Server) resolveJobRatings(p graphql.ResolveParams) (interface{}, error) {
+	return then(s.loaders(p.Context).jobRatings.load(p.Source.(*Job).ID), ratingsOf), nil
+}
+
+func (s *Server) resolveBid(p graphql.ResolveParams) (interface{}, error) {
+	var bid Bid
+	if err := s.get(p, "bidding", "/bids/"+url.PathEscape(p.Args["id"].(string)), &bid); err != nil {
+		return nil, err
+	}
+	return &bid, nil
+}
+
+func (s *Server) resolveMyBids(p graphql.ResolveParams) (interface{}, error) {
+	var bids []*Bid
+	if err := s.get(p, "bidding", "/bids", &bids); err != nil {
+		return nil, err
+	}
+	return listOrEmpty[Bid](bids), nil
+}
+
+func (s *Server) resolvePendingMatches(p graphql.ResolveParams) (interface{}, error) {
+	var matches []*Match
+	if err := s.get(p, "matching", "/matches/pending", &matches); err != nil {
+		return nil, err
+	}
+	return listOrEmpty[Match](matches), nil
+}
+
+func (s *Server) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	var driver Driver
+	if err := s.get(p, "driver", "/driver", &driver); err != nil {
+		// No profile yet
+		if notFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (s *Server) resolveDriver(p graphql.ResolveParams) (interface{}, error) {
+	return s.loaders(p.Context).drivers.load(p.Args["userId"].(string)), nil
+}
+
+// resolveDriverByUser resolves the driver of a bid, whose DriverID is a
+// user ID
+func (s *Server) resolveDriverByUser(p graphql.ResolveParams) (interface{}, error) {
+	return s.loaders(p.Context).drivers.load(p.Source.(*Bid).DriverID), nil
+}
+
+func (s *Server) resolveMatchDriver(p graphql.ResolveParams) (interface{}, error) {
+	return s.loaders(p.Context).driverProfile.load(p.Source.(*Match).DriverID), nil
+}
+
+func (s *Server) resolveDriverRatings(p graphql.ResolveParams) (interface{}, error) {
+	return then(s.loaders(p.Context).userRatings.load(p.Source.(*Driver).UserID), ratingsOf), nil
+}
+
+func (s *Server) resolveUserRatings(p graphql.ResolveParams) (interface{}, error) {
+	return then(s.loaders(p.Context).userRatings.load(p.Args["userId"].(string)), ratingsOf), nil
+}
+
+func (s *Server) resolveFleet(p graphql.ResolveParams) (interface{}, error) {
+	path := "/fleet"
+	if id, ok := p.Args["id"].(string); ok {
+		path += "/" + url.PathEscape(id)
+	}
+	var fleet Fleet
+	if err := s.get(p, "fleet", path, &fleet); err != nil {
+		// The caller has no fleet
+		if notFound(err) && path == "/fleet" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &fleet, nil
+}
+
+// resolveFleetVehicles lists a fleet's vehicles. The fleet service only
+// lists the vehicles of the caller's own fleet.
+func (s *Server) resolveFleetVehicles(p graphql.ResolveParams) (interface{}, error) {
+	fleetID := p.Source.(*Fleet).ID
+	var vehicles []*Vehicle
+	if err := s.get(p, "fleet", "/fleet/vehicles?limit=100", &vehicles); err != nil {
+		return nil, err
+	}
+	own := make([]*Vehicle, 0, len(vehicles))
+	for _, v := range vehicles {
+		if v.FleetID != nil && *v.FleetID == fleetID {
+			own = append(own, v)
+		}
+	}
+	return own, nil
+}
+
+func (s *Server) resolvePayment(p graphql.ResolveParams) (interface{}, error) {
+	var payment Payment
+	if err := s.get(p, "payment", "/payments/"+url.PathEscape(p.Args["id"].(string)), &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (s *Server) resolveDriverLocation(p graphql.ResolveParams) (interface{}, error) {
+	var location TrackingEvent
+	path := fmt.Sprintf("/tracking/driver/%s/current", url.PathEscape(p.Args["driverId"].(string)))
+	if err := s.get(p, "tracking", path, &location); err != nil {
+		if notFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+// eventSource resolves a subscription field to the event being delivered
+func eventSource(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+func ratingsOf(v interface{}) interface{} {
+	list, _ := v.(ratingList)
+	return listOrEmpty[Rating](list.Ratings)
+}
+
+// clampLimit bounds a limit argument to 1..maxListLimit
+func clampLimit(arg interface{}) int {
+	limit, _ := arg.(int)
+	return min(max(limit, 1), maxListLimit)
+}
@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"truckify/api-gateway/internal/compose"
+	"truckify/shared/pkg/logger"
+)
+
+// maxRequestBytes caps the size of a GraphQL request body
+const maxRequestBytes = 1 << 20
+
+var operations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_graphql_operations_total",
+	Help: "GraphQL operations served by the gateway, by type and result.",
+}, []string{"type", "result"})
+
+// Authorizer validates an access token and returns ctx carrying the
+// caller's identity
+type Authorizer func(ctx context.Context, token string) (context.Context, error)
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Server serves the GraphQL API. Queries are answered over HTTP POST and
+// subscriptions over WebSocket using the graphql-transport-ws protocol.
+type Server struct {
+	schema     graphql.Schema
+	listFields map[string]bool
+	client     *client
+	feed       Feed
+	authorize  Authorizer
+	limits     Limits
+	logger     *logger.Logger
+	upgrader   websocket.Upgrader
+}
+
+// NewServer creates a GraphQL server. upstreams looks up an upstream pool
+// by name, feed delivers the events subscriptions are made of, and
+// authorize checks the token a WebSocket client connects with.
+func NewServer(upstreams func(name string) compose.Doer, feed Feed, authorize Authorizer, limits Limits, log *logger.Logger) *Server {
+	s := &Server{
+		client:    &client{upstreams: upstreams},
+		feed:      feed,
+		authorize: authorize,
+		limits:    limits,
+		logger:    log,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{subprotocol},
+			// Clients authenticate with a token rather than cookies, so
+			// cross-origin connections cannot act for a user
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+	schema, err := s.newSchema()
+	if err != nil {
+		// The schema is fixed, so this is a programming error
+		panic("graph: invalid schema: " + err.Error())
+	}
+	s.schema = schema
+	s.listFields = listFields(schema)
+	return s
+}
+
+// ServeHTTP answers a query sent as a POST. The caller must already be
+// authenticated.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil || req.Query == "" {
+		writeResult(w, http.StatusBadRequest, errorResult(&Error{Code: CodeBadRequest, Message: "Expected a JSON body with a query"}))
+		return
+	}
+
+	doc, op, err := s.prepare(req)
+	if err != nil {
+		operations.WithLabelValues("unknown", "rejected").Inc()
+		writeResult(w, http.StatusBadRequest, errorResult(err))
+		return
+	}
+	if op.Operation != ast.OperationTypeQuery {
+		operations.WithLabelValues(op.Operation, "rejected").Inc()
+		writeResult(w, http.StatusBadRequest, errorResult(&Error{Code: CodeBadRequest, Message: "Only queries are served over HTTP; subscribe over WebSocket"}))
+		return
+	}
+
+	res := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(r.Context(), s.client),
+	})
+	outcome := "ok"
+	if len(res.Errors) > 0 {
+		outcome = "error"
+	}
+	operations.WithLabelValues(op.Operation, outcome).Inc()
+	writeResult(w, http.StatusOK, res)
+}
+
+// prepare parses and validates a request and checks it against the
+// limits, returning the document and the operation to run
+func (s *Server) prepare(req Request) (*ast.Document, *ast.OperationDefinition, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, nil, err
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return nil, nil, validationError(validation.Errors)
+	}
+	op, err := operation(doc, req.OperationName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkLimits(doc, op, req.Variables, s.listFields, s.limits); err != nil {
+		return nil, nil, err
+	}
+	return doc, op, nil
+}
+
+// validationError carries the errors of an invalid document
+type validationError []gqlerrors.FormattedError
+
+func (e validationError) Error() string {
+	return e[0].Message
+}
+
+// errorResult is a result made only of err
+func errorResult(err error) *graphql.Result {
+	if errs, ok := err.(validationError); ok {
+		return &graphql.Result{Errors: errs}
+	}
+	if e, ok := err.(*Error); ok {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: e.Message, Extensions: e.Extensions()}}}
+	}
+	return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+}
+
+func writeResult(w http.ResponseWriter, status int, res *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
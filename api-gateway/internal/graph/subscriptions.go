@@ -0,0 +1,373 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"truckify/api-gateway/internal/compose"
+)
+
+// subprotocol is the GraphQL over WebSocket protocol spoken to clients,
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const subprotocol = "graphql-transport-ws"
+
+// initTimeout is how long a client has to send connection_init
+const initTimeout = 10 * time.Second
+
+// Close codes defined by the protocol
+const (
+	closeBadRequest     = 4400
+	closeUnauthorized   = 4401
+	closeForbidden      = 4403
+	closeInitTimeout    = 4408
+	closeSubscriberUsed = 4409
+	closeTooManyInits   = 4429
+)
+
+// wsMessage is a graphql-transport-ws message
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Feed delivers the messages published to a topic of the notification
+// hub, such as "job:<id>", until ctx is done
+type Feed interface {
+	Subscribe(ctx context.Context, topic string) (<-chan json.RawMessage, error)
+}
+
+// HubFeed reads topics from the notification service's internal
+// /ws/topics endpoint, with one connection per subscription
+type HubFeed struct {
+	// Target returns the base URL of a healthy notification instance
+	Target func() (*url.URL, error)
+	Dialer *websocket.Dialer
+}
+
+// Subscribe connects to the hub and subscribes to topic
+func (f *HubFeed) Subscribe(ctx context.Context, topic string) (<-chan json.RawMessage, error) {
+	base, err := f.Target()
+	if err != nil {
+		return nil, err
+	}
+	u := *base
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws/topics"
+
+	dialer := f.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.WriteJSON(map[string]string{"type": "subscribe", "topic": topic}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	out := make(chan json.RawMessage)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(out)
+		for {
+			var msg struct {
+				Payload json.RawMessage `json:"payload"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			select {
+			case out <- msg.Payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// subscribeJobStatus streams a job's status changes. The caller must be
+// able to see the job.
+func (s *Server) subscribeJobStatus(p graphql.ResolveParams) (interface{}, error) {
+	jobID := p.Args["jobId"].(string)
+	if err := s.client.get(p.Context, "job", "/jobs/"+url.PathEscape(jobID), nil); err != nil {
+		return nil, err
+	}
+	return s.subscribe(p.Context, "job:"+jobID, func(data json.RawMessage) (interface{}, error) {
+		var evt JobStatusEvent
+		err := json.Unmarshal(data, &evt)
+		return &evt, err
+	})
+}
+
+// subscribeDriverLocation streams a driver's positions. The caller must be
+// able to see the driver's current location: the tracking service allows
+// the driver, their fleet operator and the shipper of the job they are on.
+// It authorizes the caller before looking the location up, so a driver who
+// has not reported a position yet answers 404 only to those callers.
+func (s *Server) subscribeDriverLocation(p graphql.ResolveParams) (interface{}, error) {
+	driverID := p.Args["driverId"].(string)
+	if err := s.client.get(p.Context, "tracking", "/tracking/driver/"+url.PathEscape(driverID)+"/current", nil); err != nil && !notFound(err) {
+		return nil, err
+	}
+	return s.subscribe(p.Context, "driver:"+driverID, func(data json.RawMessage) (interface{}, error) {
+		var evt struct {
+			TrackingEvent
+			RecordedAt time.Time `json:"recorded_at"`
+		}
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		evt.TrackingEvent.Timestamp = evt.RecordedAt
+		return &evt.TrackingEvent, nil
+	})
+}
+
+// subscribe turns a topic into the source channel graphql-go expects
+func (s *Server) subscribe(ctx context.Context, topic string, decode func(json.RawMessage) (interface{}, error)) (interface{}, error) {
+	if s.feed == nil {
+		return nil, &Error{Code: compose.CodeUnavailable, Message: "Subscriptions are not available"}
+	}
+	messages, err := s.feed.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, callError("notification", err)
+	}
+
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+		for data := range messages {
+			evt, err := decode(data)
+			if err != nil {
+				s.logger.Warn("Invalid subscription event", "topic", topic, "error", err)
+				continue
+			}
+			select {
+			case source <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return source, nil
+}
+
+// wsConn is a client connection speaking graphql-transport-ws
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex // serializes writes
+}
+
+func (c *wsConn) send(msg wsMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.conn.Close()
+}
+
+// ServeWebSocket runs subscriptions for a client. The client authenticates
+// with the Authorization header of the upgrade request, or with an
+// Authorization or token field in the connection_init payload.
+func (s *Server) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn}
+	if conn.Subprotocol() != subprotocol {
+		c.close(closeBadRequest, "Unsupported subprotocol")
+		return
+	}
+	conn.SetReadLimit(maxRequestBytes)
+	// The connection outlives the server's request timeouts
+	conn.SetWriteDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	ctx, ok := s.init(ctx, c, r.Header.Get("Authorization"))
+	if !ok {
+		return
+	}
+
+	// Running operations by ID. Each is stopped when it completes, when the
+	// client completes it, or when the connection closes.
+	type running struct{ stop context.CancelFunc }
+	var mu sync.Mutex
+	active := map[string]*running{}
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "ping":
+			c.send(wsMessage{Type: "pong"})
+		case "pong":
+		case "connection_init":
+			c.close(closeTooManyInits, "Too many initialisation requests")
+			return
+		case "subscribe":
+			var req Request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+			mu.Lock()
+			if _, taken := active[msg.ID]; taken {
+				mu.Unlock()
+				c.close(closeSubscriberUsed, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+			opCtx, stop := context.WithCancel(ctx)
+			op := &running{stop: stop}
+			active[msg.ID] = op
+			mu.Unlock()
+
+			go func(id string) {
+				defer func() {
+					mu.Lock()
+					if active[id] == op {
+						delete(active, id)
+					}
+					mu.Unlock()
+					stop()
+				}()
+				s.run(opCtx, c, id, req)
+			}(msg.ID)
+		case "complete":
+			mu.Lock()
+			if op, ok := active[msg.ID]; ok {
+				op.stop()
+				delete(active, msg.ID)
+			}
+			mu.Unlock()
+		default:
+			c.close(closeBadRequest, "Unknown message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// init waits for connection_init and authenticates the client, returning
+// the context to run its operations in
+func (s *Server) init(ctx context.Context, c *wsConn, header string) (context.Context, bool) {
+	c.conn.SetReadDeadline(time.Now().Add(initTimeout))
+	var msg wsMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		c.close(closeInitTimeout, "Connection initialisation timeout")
+		return nil, false
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	if msg.Type != "connection_init" {
+		c.close(closeUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	var payload struct {
+		Authorization string `json:"Authorization"`
+		Token         string `json:"token"`
+	}
+	if len(msg.Payload) > 0 {
+		json.Unmarshal(msg.Payload, &payload)
+	}
+	token := payload.Token
+	for _, h := range []string{payload.Authorization, header} {
+		if token == "" && strings.HasPrefix(h, "Bearer ") {
+			token = strings.TrimPrefix(h, "Bearer ")
+		}
+	}
+	if token == "" {
+		c.close(closeForbidden, "Forbidden")
+		return nil, false
+	}
+	ctx, err := s.authorize(ctx, token)
+	if err != nil {
+		c.close(closeForbidden, "Forbidden")
+		return nil, false
+	}
+	if err := c.send(wsMessage{Type: "connection_ack"}); err != nil {
+		return nil, false
+	}
+	return ctx, true
+}
+
+// run executes one operation, sending its results until it completes or
+// the client stops it
+func (s *Server) run(ctx context.Context, c *wsConn, id string, req Request) {
+	doc, op, err := s.prepare(req)
+	if err != nil {
+		operations.WithLabelValues("unknown", "rejected").Inc()
+		c.send(wsMessage{ID: id, Type: "error", Payload: mustJSON(errorResult(err).Errors)})
+		return
+	}
+
+	// Queries get one result; subscriptions one per event
+	var results <-chan *graphql.Result
+	if op.Operation == ast.OperationTypeSubscription {
+		results = graphql.ExecuteSubscription(graphql.ExecuteParams{
+			Schema:        s.schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       ctx,
+		})
+	} else {
+		ch := make(chan *graphql.Result, 1)
+		ch <- graphql.Execute(graphql.ExecuteParams{
+			Schema:        s.schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       withLoaders(ctx, s.client),
+		})
+		close(ch)
+		results = ch
+	}
+
+	first := true
+	for res := range results {
+		// A subscription that fails to start has only errors
+		if first && op.Operation == ast.OperationTypeSubscription && res.Data == nil && len(res.Errors) > 0 {
+			operations.WithLabelValues(op.Operation, "error").Inc()
+			c.send(wsMessage{ID: id, Type: "error", Payload: mustJSON(res.Errors)})
+			for range results {
+			}
+			return
+		}
+		first = false
+		if ctx.Err() == nil {
+			c.send(wsMessage{ID: id, Type: "next", Payload: mustJSON(res)})
+		}
+	}
+	operations.WithLabelValues(op.Operation, "ok").Inc()
+	if ctx.Err() == nil {
+		c.send(wsMessage{ID: id, Type: "complete"})
+	}
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
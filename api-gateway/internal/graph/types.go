@@ -0,0 +1,160 @@
+package graph
+
+import "time"
+
+// The types below decode the services' JSON responses. GraphQL fields
+// resolve to the Go field of the same name, ignoring case, so a Go field
+// is named after its GraphQL field rather than its JSON key.
+
+// Location is a job's pickup or delivery point, or a driver's or vehicle's
+// last known position
+type Location struct {
+	City    string  `json:"city"`
+	State   string  `json:"state"`
+	Address string  `json:"address"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+}
+
+// Job is served by the job service
+type Job struct {
+	ID           string    `json:"id"`
+	ShipperID    string    `json:"shipper_id"`
+	DriverID     *string   `json:"driver_id"`
+	Status       string    `json:"status"`
+	Pickup       Location  `json:"pickup"`
+	Delivery     Location  `json:"delivery"`
+	PickupDate   time.Time `json:"pickup_date"`
+	DeliveryDate time.Time `json:"delivery_date"`
+	CargoType    string    `json:"cargo_type"`
+	Weight       float64   `json:"weight"`
+	VehicleType  string    `json:"vehicle_type"`
+	Price        float64   `json:"price"`
+	Distance     float64   `json:"distance"`
+	Notes        string    `json:"notes"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Bid is served by the bidding service. DriverID is the driver's user ID.
+type Bid struct {
+	ID        string    `json:"id"`
+	JobID     string    `json:"job_id"`
+	DriverID  string    `json:"driver_id"`
+	Amount    float64   `json:"amount"`
+	Notes     string    `json:"notes"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Match is served by the matching service. DriverID is the driver's
+// profile ID.
+type Match struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"job_id"`
+	DriverID   string    `json:"driver_id"`
+	Score      float64   `json:"score"`
+	DistanceKm float64   `json:"distance_km"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Driver is a driver profile, served by the driver service
+type Driver struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	LicenseState    string    `json:"license_state"`
+	LicenseClass    string    `json:"license_class"`
+	LicenseExpiry   time.Time `json:"license_expiry"`
+	YearsExperience int       `json:"years_experience"`
+	IsAvailable     bool      `json:"is_available"`
+	CurrentLocation *Location `json:"current_location"`
+	Vehicle         *Vehicle  `json:"vehicle"`
+	Rating          float64   `json:"rating"`
+	TotalTrips      int       `json:"total_trips"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Vehicle is a driver's own vehicle or a fleet vehicle. Only fleet vehicles
+// have a fleet, VIN, status and location.
+type Vehicle struct {
+	ID              string    `json:"id"`
+	FleetID         *string   `json:"fleet_id"`
+	CurrentDriverID *string   `json:"current_driver_id"`
+	Type            string    `json:"type"`
+	Make            string    `json:"make"`
+	Model           string    `json:"model"`
+	Year            int       `json:"year"`
+	Plate           string    `json:"plate"`
+	VIN             string    `json:"vin"`
+	Capacity        float64   `json:"capacity"`
+	RegoExpiry      time.Time `json:"rego_expiry"`
+	InsuranceExpiry time.Time `json:"insurance_expiry"`
+	Status          string    `json:"status"`
+	CurrentLocation *Location `json:"current_location"`
+}
+
+// Fleet is served by the fleet service
+type Fleet struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	Name      string    `json:"name"`
+	ABN       string    `json:"abn"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Payment is served by the payment service
+type Payment struct {
+	ID           string    `json:"id"`
+	JobID        string    `json:"job_id"`
+	PayerID      string    `json:"payer_id"`
+	PayeeID      string    `json:"payee_id"`
+	Amount       float64   `json:"amount"`
+	PlatformFee  float64   `json:"platform_fee"`
+	DriverPayout float64   `json:"driver_payout"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Rating is served by the rating service
+type Rating struct {
+	ID        string    `json:"id"`
+	JobID     string    `json:"job_id"`
+	RaterID   string    `json:"rater_id"`
+	RateeID   string    `json:"ratee_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TrackingEvent is a recorded driver position, served by the tracking
+// service. DriverID is the driver's user ID.
+type TrackingEvent struct {
+	ID        string    `json:"id"`
+	JobID     string    `json:"job_id"`
+	DriverID  string    `json:"driver_id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Speed     float64   `json:"speed"`
+	Heading   float64   `json:"heading"`
+	Timestamp time.Time `json:"timestamp"`
+	EventType string    `json:"event_type"`
+}
+
+// JobStatusEvent is a job status change, delivered to subscriptions
+type JobStatusEvent struct {
+	JobID      string    `json:"job_id"`
+	ShipperID  string    `json:"shipper_id"`
+	DriverID   *string   `json:"driver_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
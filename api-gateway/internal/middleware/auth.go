@@ -2,88 +2,297 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"truckify/shared/pkg/jwt"
+	sharedMiddleware "truckify/shared/pkg/middleware"
 	"truckify/shared/pkg/response"
 )
 
-// AuthMiddleware validates JWT tokens
+var (
+	// ErrRevokedToken is returned for access tokens on the denylist
+	ErrRevokedToken = errors.New("token has been revoked")
+	// ErrInvalidAPIKey is returned for API keys that are unknown, revoked or
+	// expired
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	errMissingCredentials = errors.New("missing credentials")
+	errMalformedHeader    = errors.New("malformed authorization header")
+)
+
+// apiKeyPrefix starts every API key, which tells keys apart from access
+// tokens in the Authorization header
+const apiKeyPrefix = "tfy_"
+
+// keyCacheTTL is how long a verified API key is trusted before the auth
+// service is asked again. Revoked keys are denied sooner through the
+// denylist.
+const keyCacheTTL = time.Minute
+
+// KeyIdentity is who an API key acts as
+type KeyIdentity struct {
+	KeyID     string   `json:"key_id"`
+	UserID    string   `json:"user_id"`
+	Email     string   `json:"email"`
+	UserType  string   `json:"user_type"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rate_limit"`
+}
+
+// KeyVerifier resolves an API key to the identity it acts as. It returns
+// ErrInvalidAPIKey for keys that cannot be used.
+type KeyVerifier interface {
+	VerifyKey(ctx context.Context, key, ipAddress string) (*KeyIdentity, error)
+}
+
+// AuthMiddleware validates JWT tokens and API keys
 type AuthMiddleware struct {
 	jwtManager *jwt.JWTManager
+	denylist   jwt.Denylist
+	keys       KeyVerifier
+	proxies    sharedMiddleware.TrustedProxies
+	keyCache   keyCache
 }
 
-// NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(jwtManager *jwt.JWTManager) *AuthMiddleware {
+// NewAuthMiddleware creates a new auth middleware. Access tokens whose ID is
+// on the denylist are rejected; a nil denylist disables the check.
+func NewAuthMiddleware(jwtManager *jwt.JWTManager, denylist jwt.Denylist) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtManager: jwtManager,
+		denylist:   denylist,
+		keyCache:   keyCache{entries: map[string]keyCacheEntry{}},
 	}
 }
 
-// Authenticate validates the JWT token
-func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			requestID, _ := r.Context().Value("request_id").(string)
-			response.Unauthorized(w, "Missing authorization header", "", requestID)
-			return
+// SetKeyVerifier enables API keys, sent as X-API-Key or as a bearer token.
+// Without a verifier every API key is rejected.
+func (m *AuthMiddleware) SetKeyVerifier(keys KeyVerifier) {
+	m.keys = keys
+}
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For is believed when
+// recording the address an API key was used from
+func (m *AuthMiddleware) SetTrustedProxies(proxies sharedMiddleware.TrustedProxies) {
+	m.proxies = proxies
+}
+
+// validate checks the access token and that it has not been revoked. Tokens
+// issued to API clients are also rejected once their key is revoked, and
+// acting-as tokens once their delegation is. If the denylist cannot be
+// reached the token is rejected.
+func (m *AuthMiddleware) validate(ctx context.Context, token string) (*jwt.Claims, error) {
+	claims, err := m.jwtManager.ValidateAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkDenylist(ctx, claims.ID); err != nil {
+		return nil, err
+	}
+	if claims.ClientID != "" {
+		if err := m.checkDenylist(ctx, claims.ClientID); err != nil {
+			return nil, err
 		}
+	}
+	if claims.DelegationID != "" {
+		if err := m.checkDenylist(ctx, claims.DelegationID); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			requestID, _ := r.Context().Value("request_id").(string)
-			response.Unauthorized(w, "Invalid authorization header format", "Expected: Bearer <token>", requestID)
-			return
+func (m *AuthMiddleware) checkDenylist(ctx context.Context, id string) error {
+	if m.denylist == nil {
+		return nil
+	}
+	revoked, err := m.denylist.IsRevoked(ctx, id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrRevokedToken
+	}
+	return nil
+}
+
+// verifyKey resolves an API key through the verifier, caching the result
+func (m *AuthMiddleware) verifyKey(ctx context.Context, key, ipAddress string) (*jwt.Claims, error) {
+	if m.keys == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	identity, ok := m.keyCache.get(hash)
+	if !ok {
+		var err error
+		identity, err = m.keys.VerifyKey(ctx, key, ipAddress)
+		if err != nil {
+			return nil, err
 		}
+		m.keyCache.put(hash, identity)
+	}
+
+	// The auth service denies a key's ID when it is revoked
+	if err := m.checkDenylist(ctx, identity.KeyID); err != nil {
+		if err == ErrRevokedToken {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	return &jwt.Claims{
+		UserID:    identity.UserID,
+		Email:     identity.Email,
+		UserType:  identity.UserType,
+		ClientID:  identity.KeyID,
+		Scope:     strings.Join(identity.Scopes, " "),
+		RateLimit: identity.RateLimit,
+	}, nil
+}
+
+// authenticate checks the request's access token or API key
+func (m *AuthMiddleware) authenticate(r *http.Request) (*jwt.Claims, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return m.verifyKey(r.Context(), key, m.proxies.ClientIP(r))
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errMissingCredentials
+	}
 
-		token := parts[1]
+	// Extract token from "Bearer <token>"
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errMalformedHeader
+	}
 
-		// Validate token
-		claims, err := m.jwtManager.ValidateToken(token)
+	if strings.HasPrefix(parts[1], apiKeyPrefix) {
+		return m.verifyKey(r.Context(), parts[1], m.proxies.ClientIP(r))
+	}
+	return m.validate(r.Context(), parts[1])
+}
+
+// withClaims adds the token claims to the request context. Requests made by
+// API clients also carry the key's ID, permissions and rate limit, sessions
+// acting for an organisation carry it and the user's role in it, and
+// dispatchers acting for a user carry their own identity, the delegation
+// and its permissions.
+func withClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	ctx = context.WithValue(ctx, "user_id", claims.UserID)
+	ctx = context.WithValue(ctx, "email", claims.Email)
+	ctx = context.WithValue(ctx, "user_type", claims.UserType)
+	ctx = context.WithValue(ctx, "session_id", claims.SessionID)
+	if claims.OrgID != "" {
+		ctx = context.WithValue(ctx, "org_id", claims.OrgID)
+		ctx = context.WithValue(ctx, "org_role", claims.OrgRole)
+	}
+	if claims.ClientID != "" {
+		ctx = context.WithValue(ctx, "api_key_id", claims.ClientID)
+		ctx = context.WithValue(ctx, "api_key_rate_limit", claims.RateLimit)
+	}
+	if claims.Actor != nil {
+		ctx = context.WithValue(ctx, "actor_id", claims.Actor.UserID)
+		ctx = context.WithValue(ctx, "actor_type", claims.Actor.UserType)
+		ctx = context.WithValue(ctx, "delegation_id", claims.DelegationID)
+	}
+	if scopes := claims.Scopes(); scopes != nil {
+		ctx = context.WithValue(ctx, "scopes", scopes)
+	}
+	return ctx
+}
+
+// Authenticate validates the JWT token or API key
+func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := m.authenticate(r)
 		if err != nil {
 			requestID, _ := r.Context().Value("request_id").(string)
-			if err == jwt.ErrExpiredToken {
+			switch err {
+			case errMissingCredentials:
+				response.Unauthorized(w, "Missing authorization header", "", requestID)
+			case errMalformedHeader:
+				response.Unauthorized(w, "Invalid authorization header format", "Expected: Bearer <token>", requestID)
+			case jwt.ErrExpiredToken:
 				response.Unauthorized(w, "Token has expired", "", requestID)
-			} else {
+			case ErrRevokedToken:
+				response.Unauthorized(w, "Token has been revoked", "", requestID)
+			case jwt.ErrInvalidToken, jwt.ErrWrongTokenType:
 				response.Unauthorized(w, "Invalid token", "", requestID)
+			case ErrInvalidAPIKey:
+				response.Unauthorized(w, "Invalid API key", "", requestID)
+			default:
+				response.ServiceUnavailable(w, "Unable to verify token", "", requestID)
 			}
 			return
 		}
 
 		// Add claims to context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "email", claims.Email)
-		ctx = context.WithValue(ctx, "user_type", claims.UserType)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
 	})
 }
 
+// Authorize validates an access token from somewhere other than the
+// Authorization header, such as a WebSocket message, and returns ctx with
+// its claims. Tokens issued to API clients and acting-as tokens are not
+// accepted here.
+func (m *AuthMiddleware) Authorize(ctx context.Context, token string) (context.Context, error) {
+	claims, err := m.validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scopes() != nil {
+		return nil, jwt.ErrWrongTokenType
+	}
+	return withClaims(ctx, claims), nil
+}
+
 // OptionalAuth allows requests with or without authentication
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Extract token
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			token := parts[1]
-			claims, err := m.jwtManager.ValidateToken(token)
-			if err == nil {
-				ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-				ctx = context.WithValue(ctx, "email", claims.Email)
-				ctx = context.WithValue(ctx, "user_type", claims.UserType)
-				r = r.WithContext(ctx)
-			}
+		if claims, err := m.authenticate(r); err == nil {
+			r = r.WithContext(withClaims(r.Context(), claims))
 		}
-
 		next.ServeHTTP(w, r)
 	})
 }
+
+// keyCache remembers verified API keys by the hash of the key
+type keyCache struct {
+	mu      sync.Mutex
+	entries map[string]keyCacheEntry
+}
+
+type keyCacheEntry struct {
+	identity *KeyIdentity
+	expires  time.Time
+}
+
+func (c *keyCache) get(hash string) (*KeyIdentity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[hash]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.identity, true
+}
+
+func (c *keyCache) put(hash string, identity *KeyIdentity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for h, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, h)
+		}
+	}
+	c.entries[hash] = keyCacheEntry{identity: identity, expires: now.Add(keyCacheTTL)}
+}
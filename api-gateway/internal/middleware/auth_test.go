@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"truckify/shared/pkg/jwt"
+)
+
+type fakeDenylist map[string]bool
+
+func (d fakeDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	d[jti] = true
+	return nil
+}
+
+func (d fakeDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return d[jti], nil
+}
+
+type fakeVerifier struct {
+	keys  map[string]*KeyIdentity
+	calls int
+}
+
+func (v *fakeVerifier) VerifyKey(ctx context.Context, key, ipAddress string) (*KeyIdentity, error) {
+	v.calls++
+	if identity, ok := v.keys[key]; ok {
+		return identity, nil
+	}
+	return nil, ErrInvalidAPIKey
+}
+
+// serve runs a request through Authenticate and returns the status and the
+// context the next handler saw
+func serve(m *AuthMiddleware, setHeaders func(*http.Request)) (int, context.Context) {
+	var seen context.Context
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = r.Context() })
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	setHeaders(req)
+	rec := httptest.NewRecorder()
+	m.Authenticate(next).ServeHTTP(rec, req)
+	return rec.Code, seen
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	denylist := fakeDenylist{}
+	verifier := &fakeVerifier{keys: map[string]*KeyIdentity{
+		"tfy_good": {KeyID: "k1", UserID: "u1", UserType: "shipper", Scopes: []string{"job:read"}, RateLimit: 30},
+	}}
+	m := NewAuthMiddleware(jwt.NewJWTManager("secret", 15*time.Minute, time.Hour), denylist)
+	m.SetKeyVerifier(verifier)
+
+	status, ctx := serve(m, func(r *http.Request) { r.Header.Set("X-API-Key", "tfy_good") })
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if ctx.Value("user_id") != "u1" || ctx.Value("api_key_id") != "k1" || ctx.Value("api_key_rate_limit") != 30 {
+		t.Errorf("context is missing the key's identity")
+	}
+	if scopes, _ := ctx.Value("scopes").([]string); len(scopes) != 1 || scopes[0] != "job:read" {
+		t.Errorf("scopes = %v", scopes)
+	}
+
+	// Bearer keys work too, and the verified key is cached
+	status, _ = serve(m, func(r *http.Request) { r.Header.Set("Authorization", "Bearer tfy_good") })
+	if status != http.StatusOK || verifier.calls != 1 {
+		t.Errorf("status = %d, verifier calls = %d", status, verifier.calls)
+	}
+
+	if status, _ := serve(m, func(r *http.Request) { r.Header.Set("X-API-Key", "tfy_bad") }); status != http.StatusUnauthorized {
+		t.Errorf("unknown key: status = %d", status)
+	}
+
+	// Revoking the key takes effect despite the cache
+	denylist["k1"] = true
+	if status, _ := serve(m, func(r *http.Request) { r.Header.Set("X-API-Key", "tfy_good") }); status != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d", status)
+	}
+}
+
+func TestAuthenticate_ClientToken(t *testing.T) {
+	denylist := fakeDenylist{}
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, time.Hour)
+	m := NewAuthMiddleware(jwtManager, denylist)
+
+	token, err := jwtManager.GenerateClientToken("k1", "u1", "ops@example.com", "shipper", []string{"job:read"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token.AccessToken) }
+
+	status, ctx := serve(m, bearer)
+	if status != http.StatusOK || ctx.Value("api_key_id") != "k1" {
+		t.Fatalf("status = %d", status)
+	}
+
+	if _, err := m.Authorize(context.Background(), token.AccessToken); err == nil {
+		t.Error("client tokens are not accepted outside the Authorization header")
+	}
+
+	denylist["k1"] = true
+	if status, _ := serve(m, bearer); status != http.StatusUnauthorized {
+		t.Errorf("token of a revoked key: status = %d", status)
+	}
+}
+
+func TestAuthenticate_OrgClaims(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, time.Hour)
+	m := NewAuthMiddleware(jwtManager, nil)
+
+	pair, err := jwtManager.GenerateOrgTokenPair("s1", "u1", "ops@example.com", "shipper", "o1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, ctx := serve(m, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+pair.AccessToken) })
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if ctx.Value("org_id") != "o1" || ctx.Value("org_role") != "admin" {
+		t.Errorf("org_id = %v, org_role = %v", ctx.Value("org_id"), ctx.Value("org_role"))
+	}
+}
+
+func TestAuthenticate_ActingToken(t *testing.T) {
+	denylist := fakeDenylist{}
+	jwtManager := jwt.NewJWTManager("secret", 15*time.Minute, time.Hour)
+	m := NewAuthMiddleware(jwtManager, denylist)
+
+	actor := jwt.Actor{UserID: "d1", UserType: "dispatcher"}
+	token, err := jwtManager.GenerateActingToken("g1", "u1", "ops@example.com", "shipper", actor, []string{"job:create"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token.AccessToken) }
+
+	status, ctx := serve(m, bearer)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if ctx.Value("user_id") != "u1" || ctx.Value("actor_id") != "d1" || ctx.Value("actor_type") != "dispatcher" || ctx.Value("delegation_id") != "g1" {
+		t.Errorf("user_id = %v, actor_id = %v, actor_type = %v, delegation_id = %v",
+			ctx.Value("user_id"), ctx.Value("actor_id"), ctx.Value("actor_type"), ctx.Value("delegation_id"))
+	}
+	if scopes, _ := ctx.Value("scopes").([]string); len(scopes) != 1 || scopes[0] != "job:create" {
+		t.Errorf("scopes = %v", ctx.Value("scopes"))
+	}
+	if _, ok := ctx.Value("api_key_id").(string); ok {
+		t.Error("acting-as tokens are not API clients")
+	}
+
+	if _, err := m.Authorize(context.Background(), token.AccessToken); err == nil {
+		t.Error("acting-as tokens are not accepted outside the Authorization header")
+	}
+
+	denylist["g1"] = true
+	if status, _ := serve(m, bearer); status != http.StatusUnauthorized {
+		t.Errorf("token of a revoked delegation: status = %d", status)
+	}
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"sync/atomic"
+
+	"truckify/api-gateway/internal/config"
+)
+
+// Balancer picks the target for the next request among healthy targets
+type Balancer interface {
+	Pick(targets []*Target) *Target
+}
+
+// NewBalancer returns the balancer for a strategy name
+func NewBalancer(strategy string) Balancer {
+	if strategy == config.BalancerLeastConnections {
+		return &leastConnections{}
+	}
+	return &roundRobin{}
+}
+
+// roundRobin cycles through targets in order
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobin) Pick(targets []*Target) *Target {
+	if len(targets) == 0 {
+		return nil
+	}
+	n := b.next.Add(1) - 1
+	return targets[n%uint64(len(targets))]
+}
+
+// leastConnections picks the target with the fewest requests in flight,
+// rotating the starting point so ties are spread evenly
+type leastConnections struct {
+	next atomic.Uint64
+}
+
+func (b *leastConnections) Pick(targets []*Target) *Target {
+	if len(targets) == 0 {
+		return nil
+	}
+	start := int(b.next.Add(1) % uint64(len(targets)))
+
+	var best *Target
+	for i := range targets {
+		t := targets[(start+i)%len(targets)]
+		if best == nil || t.Active() < best.Active() {
+			best = t
+		}
+	}
+	return best
+}
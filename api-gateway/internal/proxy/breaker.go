@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"truckify/api-gateway/internal/config"
+)
+
+// ErrCircuitOpen is returned for requests rejected by an open breaker
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+// Breaker states. The values are exported as the breaker state metric.
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText encodes the state by name
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Breaker is a circuit breaker for one upstream. It is closed while requests
+// succeed and opens after FailureThreshold consecutive failures, rejecting
+// requests for OpenTimeout. It then turns half-open and lets
+// HalfOpenRequests probes through: if they all succeed it closes, and any
+// failure opens it again.
+type Breaker struct {
+	cfg      config.CircuitBreaker
+	now      func() time.Time
+	onChange func(from, to BreakerState)
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+// NewBreaker creates a closed breaker. onChange, if not nil, is called on
+// every state change with the breaker locked, so it must not call back in.
+func NewBreaker(cfg config.CircuitBreaker, onChange func(from, to BreakerState)) *Breaker {
+	return &Breaker{cfg: cfg, now: time.Now, onChange: onChange}
+}
+
+// Allow reports whether a request may be sent. Every allowed request must be
+// followed by exactly one call to Success, Failure or Release.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// Success records a request the upstream served
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		b.probes--
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// Failure records a request the upstream failed
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		b.setState(StateOpen)
+	}
+}
+
+// Release records a request that ended without telling anything about the
+// upstream, such as one the client cancelled
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// State returns the current state
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState moves to a new state and resets its counters; b.mu must be held
+func (b *Breaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.onChange != nil && from != state {
+		b.onChange(from, state)
+	}
+}
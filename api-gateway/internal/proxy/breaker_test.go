@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"truckify/api-gateway/internal/config"
+)
+
+func newTestBreaker() (*Breaker, *time.Time, *[]BreakerState) {
+	now := time.Now()
+	var changes []BreakerState
+	b := NewBreaker(config.CircuitBreaker{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 2,
+	}, func(from, to BreakerState) {
+		changes = append(changes, to)
+	})
+	b.now = func() time.Time { return now }
+	return b, &now, &changes
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _, _ := newTestBreaker()
+
+	b.Allow()
+	b.Failure()
+	b.Allow()
+	b.Failure()
+	b.Allow()
+	b.Success() // resets the count
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Failure()
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+
+	b.Allow()
+	b.Failure()
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("expected open breaker to reject requests")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b, now, changes := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Failure()
+	}
+
+	*now = now.Add(10 * time.Second)
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("expected half-open breaker to allow probes")
+	}
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("expected probes beyond the limit to be rejected")
+	}
+
+	b.Success()
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open until every probe succeeds, got %s", b.State())
+	}
+	b.Success()
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+
+	want := []BreakerState{StateOpen, StateHalfOpen, StateClosed}
+	if len(*changes) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, *changes)
+	}
+}
+
+func TestBreakerProbeFailureReopens(t *testing.T) {
+	b, now, _ := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Failure()
+	}
+
+	*now = now.Add(10 * time.Second)
+	b.Allow()
+	b.Failure()
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Error("expected reopened breaker to wait out the timeout again")
+	}
+}
+
+func TestBreakerReleaseFreesProbe(t *testing.T) {
+	b, now, _ := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Failure()
+	}
+
+	*now = now.Add(10 * time.Second)
+	b.Allow()
+	b.Allow()
+	b.Release()
+	if !b.Allow() {
+		t.Error("expected a released probe slot to be reusable")
+	}
+}
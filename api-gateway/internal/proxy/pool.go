@@ -0,0 +1,266 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"truckify/api-gateway/internal/config"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/tracing"
+)
+
+// Target is one instance of an upstream service
+type Target struct {
+	URL     *url.URL
+	healthy atomic.Bool
+	active  atomic.Int64
+
+	// Consecutive health check results, only touched by the checker
+	failures  int
+	successes int
+}
+
+// Healthy reports whether the target is taking traffic
+func (t *Target) Healthy() bool {
+	return t.healthy.Load()
+}
+
+// Active returns the number of requests in flight to the target
+func (t *Target) Active() int64 {
+	return t.active.Load()
+}
+
+// TargetStatus describes a target
+type TargetStatus struct {
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+	Active  int64  `json:"active"`
+}
+
+// UpstreamStatus describes an upstream pool
+type UpstreamStatus struct {
+	Name           string         `json:"name"`
+	Balancer       string         `json:"balancer"`
+	CircuitBreaker BreakerState   `json:"circuit_breaker"`
+	Retries        int64          `json:"retries"`
+	Rejected       int64          `json:"rejected"`
+	Targets        []TargetStatus `json:"targets"`
+}
+
+// Pool load balances requests across the instances of an upstream service.
+// It takes instances out of rotation when their health checks fail, retries
+// idempotent requests on another instance, and stops sending requests while
+// the upstream's circuit breaker is open.
+type Pool struct {
+	name     string
+	targets  []*Target
+	balancer Balancer
+	strategy string
+	health   config.HealthCheck
+	retry    config.Retry
+	client   *http.Client
+	logger   *logger.Logger
+	rewrite  func(*http.Request)
+
+	proxy     *httputil.ReverseProxy
+	transport http.RoundTripper
+	breaker   *Breaker
+	retries   atomic.Int64
+	rejected  atomic.Int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewPool creates a pool for an upstream. rewrite runs on every outgoing
+// request, and errorHandler handles requests the upstream could not serve.
+func NewPool(name string, cfg *config.Upstream, rewrite func(*http.Request), errorHandler func(http.ResponseWriter, *http.Request, error), log *logger.Logger) (*Pool, error) {
+	p := &Pool{
+		name:     name,
+		balancer: NewBalancer(cfg.Balancer),
+		strategy: cfg.Balancer,
+		health:   cfg.HealthCheck,
+		retry:    cfg.Retry,
+		client:   &http.Client{Timeout: cfg.HealthCheck.Timeout},
+		logger:   log,
+		rewrite:  rewrite,
+		transport: tracing.Transport(&http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   cfg.DialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   32,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   cfg.DialTimeout,
+			ExpectContinueTimeout: time.Second,
+		}),
+		stop: make(chan struct{}),
+	}
+
+	p.breaker = NewBreaker(cfg.CircuitBreaker, func(from, to BreakerState) {
+		if to == StateOpen {
+			log.Warn("Circuit breaker opened", "upstream", name, "from", from.String())
+			return
+		}
+		log.Info("Circuit breaker state changed", "upstream", name, "from", from.String(), "to", to.String())
+	})
+
+	p.proxy = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			// The transport points the request at a target
+			if _, ok := req.Header["User-Agent"]; !ok {
+				req.Header.Set("User-Agent", "")
+			}
+			rewrite(req)
+		},
+		Transport: roundTripperFunc(p.roundTrip),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if !errors.Is(r.Context().Err(), context.Canceled) {
+				log.Warn("Upstream request failed", "upstream", name, "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+			errorHandler(w, r, err)
+		},
+	}
+
+	for _, raw := range cfg.Targets {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		t := &Target{URL: u}
+		t.healthy.Store(true)
+		p.targets = append(p.targets, t)
+	}
+	return p, nil
+}
+
+// Name returns the upstream name
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Pick returns the next healthy target, or nil if none are healthy
+func (p *Pool) Pick() *Target {
+	return p.pickExcept(nil)
+}
+
+// pickExcept returns the next healthy target not in skip, or nil
+func (p *Pool) pickExcept(skip map[*Target]bool) *Target {
+	healthy := make([]*Target, 0, len(p.targets))
+	for _, t := range p.targets {
+		if t.Healthy() && !skip[t] {
+			healthy = append(healthy, t)
+		}
+	}
+	return p.balancer.Pick(healthy)
+}
+
+// Forward proxies the request, starting with the target
+func (p *Pool) Forward(t *Target, w http.ResponseWriter, r *http.Request) {
+	p.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), targetKey{}, t)))
+}
+
+// Do sends a request the gateway makes itself, such as a call for a
+// composed view, through the balancer, circuit breaker and retries. Only
+// the path and query of the request URL are used.
+func (p *Pool) Do(req *http.Request) (*http.Response, error) {
+	p.rewrite(req)
+	return p.roundTrip(req)
+}
+
+// Status returns the state of the upstream and every target
+func (p *Pool) Status() UpstreamStatus {
+	status := UpstreamStatus{
+		Name:           p.name,
+		Balancer:       p.strategy,
+		CircuitBreaker: p.breaker.State(),
+		Retries:        p.retries.Load(),
+		Rejected:       p.rejected.Load(),
+		Targets:        make([]TargetStatus, 0, len(p.targets)),
+	}
+	for _, t := range p.targets {
+		status.Targets = append(status.Targets, TargetStatus{URL: t.URL.String(), Healthy: t.Healthy(), Active: t.Active()})
+	}
+	return status
+}
+
+// Start runs health checks against every target until Stop is called
+func (p *Pool) Start() {
+	go func() {
+		ticker := time.NewTicker(p.health.Interval)
+		defer ticker.Stop()
+
+		p.checkAll()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkAll()
+			}
+		}
+	}()
+}
+
+// Stop ends health checking
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, t := range p.targets {
+		wg.Add(1)
+		go func(t *Target) {
+			defer wg.Done()
+			p.record(t, p.check(t))
+		}(t)
+	}
+	wg.Wait()
+}
+
+// check calls the target's health endpoint
+func (p *Pool) check(t *Target) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), p.health.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL.JoinPath(p.health.Path).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// record updates the target's state once enough consecutive checks agree
+func (p *Pool) record(t *Target, ok bool) {
+	if ok {
+		t.failures = 0
+		t.successes++
+		if !t.Healthy() && t.successes >= p.health.HealthyThreshold {
+			t.healthy.Store(true)
+			p.logger.Info("Upstream target healthy", "upstream", p.name, "target", t.URL.String())
+		}
+		return
+	}
+
+	t.successes = 0
+	t.failures++
+	if t.Healthy() && t.failures >= p.health.UnhealthyThreshold {
+		t.healthy.Store(false)
+		p.logger.Warn("Upstream target unhealthy", "upstream", p.name, "target", t.URL.String())
+	}
+}
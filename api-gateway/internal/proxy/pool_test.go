@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"truckify/api-gateway/internal/config"
+	"truckify/shared/pkg/logger"
+)
+
+func newTestPool(t *testing.T, balancer string, targets ...string) *Pool {
+	t.Helper()
+	pool, err := NewPool("test", &config.Upstream{
+		Targets:  targets,
+		Balancer: balancer,
+		HealthCheck: config.HealthCheck{
+			Path:               "/health",
+			Interval:           time.Hour,
+			Timeout:            time.Second,
+			UnhealthyThreshold: 2,
+			HealthyThreshold:   1,
+		},
+		CircuitBreaker: config.CircuitBreaker{
+			FailureThreshold: 3,
+			OpenTimeout:      time.Hour,
+			HalfOpenRequests: 1,
+		},
+		Retry: config.Retry{
+			Attempts:   3,
+			Backoff:    time.Millisecond,
+			MaxBackoff: 5 * time.Millisecond,
+		},
+		DialTimeout: time.Second,
+	}, func(*http.Request) {}, func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, logger.New("test", "error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestRoundRobin(t *testing.T) {
+	pool := newTestPool(t, config.BalancerRoundRobin, "http://a:1", "http://b:1", "http://c:1")
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, pool.Pick().URL.Host)
+	}
+	want := []string{"a:1", "b:1", "c:1", "a:1", "b:1", "c:1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	pool := newTestPool(t, config.BalancerLeastConnections, "http://a:1", "http://b:1", "http://c:1")
+	pool.targets[0].active.Store(3)
+	pool.targets[1].active.Store(1)
+	pool.targets[2].active.Store(2)
+
+	for i := 0; i < 3; i++ {
+		if got := pool.Pick().URL.Host; got != "b:1" {
+			t.Errorf("expected b:1, got %s", got)
+		}
+	}
+}
+
+func TestPickSkipsUnhealthy(t *testing.T) {
+	pool := newTestPool(t, config.BalancerRoundRobin, "http://a:1", "http://b:1")
+	pool.targets[0].healthy.Store(false)
+
+	for i := 0; i < 4; i++ {
+		if got := pool.Pick().URL.Host; got != "b:1" {
+			t.Errorf("expected b:1, got %s", got)
+		}
+	}
+
+	pool.targets[1].healthy.Store(false)
+	if pool.Pick() != nil {
+		t.Error("expected no target when all are unhealthy")
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("unexpected health check path %s", r.URL.Path)
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, config.BalancerRoundRobin, server.URL)
+	target := pool.targets[0]
+
+	// One failure is below the threshold
+	status.Store(http.StatusServiceUnavailable)
+	pool.checkAll()
+	if !target.Healthy() {
+		t.Fatal("expected target to stay healthy after one failure")
+	}
+
+	pool.checkAll()
+	if target.Healthy() {
+		t.Fatal("expected target to be unhealthy after two failures")
+	}
+
+	status.Store(http.StatusOK)
+	pool.checkAll()
+	if !target.Healthy() {
+		t.Fatal("expected target to recover after a passing check")
+	}
+}
+
+func TestForwardTracksActiveRequests(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, config.BalancerRoundRobin, server.URL)
+	target := pool.Pick()
+
+	done := make(chan struct{})
+	go func() {
+		pool.Forward(target, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs", nil))
+		close(done)
+	}()
+
+	<-started
+	if target.Active() != 1 {
+		t.Errorf("expected 1 active request, got %d", target.Active())
+	}
+	close(release)
+	<-done
+	if target.Active() != 0 {
+		t.Errorf("expected 0 active requests, got %d", target.Active())
+	}
+}
+
+func TestDoSendsToTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RequestURI() != "/jobs/1?x=y" {
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, config.BalancerRoundRobin, server.URL)
+	req, _ := http.NewRequest(http.MethodGet, "http://job/jobs/1?x=y", nil)
+	resp, err := pool.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("expected the target's response, got %d", resp.StatusCode)
+	}
+
+	pool.targets[0].healthy.Store(false)
+	req, _ = http.NewRequest(http.MethodGet, "http://job/jobs/1?x=y", nil)
+	if _, err := pool.Do(req); !errors.Is(err, ErrNoHealthyTargets) {
+		t.Errorf("expected ErrNoHealthyTargets, got %v", err)
+	}
+}
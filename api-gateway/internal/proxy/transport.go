@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sharedMiddleware "truckify/shared/pkg/middleware"
+)
+
+// ErrNoHealthyTargets is returned when every target of an upstream is
+// failing its health check
+var ErrNoHealthyTargets = errors.New("no healthy targets")
+
+type targetKey struct{}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// roundTrip sends a proxied request to its target through the circuit
+// breaker, retrying idempotent requests on another target after a failure
+func (p *Pool) roundTrip(req *http.Request) (*http.Response, error) {
+	target, _ := req.Context().Value(targetKey{}).(*Target)
+	if target == nil {
+		if target = p.Pick(); target == nil {
+			return nil, ErrNoHealthyTargets
+		}
+	}
+
+	attempts := 1
+	if retryable(req) {
+		attempts = p.retry.Attempts
+	}
+	tried := map[*Target]bool{}
+
+	for attempt := 1; ; attempt++ {
+		if !p.breaker.Allow() {
+			p.rejected.Add(1)
+			return nil, ErrCircuitOpen
+		}
+		tried[target] = true
+
+		resp, err := p.send(target, req)
+		switch {
+		case err == nil && !failedStatus(resp.StatusCode):
+			p.breaker.Success()
+			return resp, nil
+		case errors.Is(req.Context().Err(), context.Canceled):
+			// The client went away; that says nothing about the upstream
+			p.breaker.Release()
+			return resp, err
+		default:
+			p.breaker.Failure()
+		}
+
+		if attempt >= attempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), p.backoff(attempt)); err != nil {
+			return nil, err
+		}
+
+		p.retries.Add(1)
+		if next := p.pickExcept(tried); next != nil {
+			target = next
+		}
+	}
+}
+
+// send makes one attempt against a target
+func (p *Pool) send(t *Target, req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.URL.Scheme
+	out.URL.Host = t.URL.Host
+	out.Host = t.URL.Host
+
+	// Tell the service how long the gateway will wait
+	out.Header.Del(sharedMiddleware.TimeoutHeader)
+	if deadline, ok := req.Context().Deadline(); ok {
+		out.Header.Set(sharedMiddleware.TimeoutHeader, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+
+	t.active.Add(1)
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		t.active.Add(-1)
+		return nil, err
+	}
+
+	// The request stays active until the proxy is done with the body
+	done := func() { t.active.Add(-1) }
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok {
+		// Upgraded connections must stay writable for the proxy
+		resp.Body = &trackedConn{ReadWriteCloser: rwc, done: done}
+	} else {
+		resp.Body = &trackedBody{ReadCloser: resp.Body, done: done}
+	}
+	return resp, nil
+}
+
+// backoff returns the wait before the retry following attempt
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.retry.Backoff << (attempt - 1)
+	if delay <= 0 || delay > p.retry.MaxBackoff {
+		delay = p.retry.MaxBackoff
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+// retryable reports whether a request can safely be sent again
+func retryable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// failedStatus reports whether a response means the upstream could not
+// serve the request
+func failedStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// trackedBody calls done once when the body is closed
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// trackedConn is a trackedBody for upgraded connections
+type trackedConn struct {
+	io.ReadWriteCloser
+	once sync.Once
+	done func()
+}
+
+func (c *trackedConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.once.Do(c.done)
+	return err
+}
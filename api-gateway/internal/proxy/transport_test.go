@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sharedMiddleware "truckify/shared/pkg/middleware"
+)
+
+func TestRetriesIdempotentRequestsOnAnotherTarget(t *testing.T) {
+	var failing, healthy atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failing.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthy.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer good.Close()
+
+	pool := newTestPool(t, "round_robin", bad.URL, good.URL)
+
+	w := httptest.NewRecorder()
+	pool.Forward(pool.targets[0], w, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after retry, got %d", w.Code)
+	}
+	if failing.Load() != 1 || healthy.Load() != 1 {
+		t.Errorf("expected one attempt per target, got %d and %d", failing.Load(), healthy.Load())
+	}
+	if pool.Status().Retries != 1 {
+		t.Errorf("expected 1 retry, got %d", pool.Status().Retries)
+	}
+}
+
+func TestDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, "round_robin", server.URL)
+
+	w := httptest.NewRecorder()
+	pool.Forward(pool.Pick(), w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{}`)))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected upstream 503 to pass through, got %d", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls.Load())
+	}
+}
+
+func TestOpenBreakerRejectsRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, "round_robin", server.URL)
+
+	// Three attempts reach the failure threshold
+	pool.Forward(pool.Pick(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	if pool.Status().CircuitBreaker != StateOpen {
+		t.Fatalf("expected open breaker, got %s", pool.Status().CircuitBreaker)
+	}
+
+	var got error
+	pool.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	pool.Forward(pool.Pick(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs", nil))
+
+	if got != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", got)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected no calls while open, got %d total", calls.Load())
+	}
+	if pool.Status().Rejected != 1 {
+		t.Errorf("expected 1 rejected request, got %d", pool.Status().Rejected)
+	}
+}
+
+func TestPropagatesDeadline(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(sharedMiddleware.TimeoutHeader)
+	}))
+	defer server.Close()
+
+	pool := newTestPool(t, "round_robin", server.URL)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	req.Header.Set(sharedMiddleware.TimeoutHeader, "999999")
+	ctx, cancel := context.WithTimeout(req.Context(), 2*time.Second)
+	defer cancel()
+	pool.Forward(pool.Pick(), httptest.NewRecorder(), req.WithContext(ctx))
+
+	ms, err := strconv.Atoi(header)
+	if err != nil || ms <= 0 || ms > 2000 {
+		t.Errorf("expected remaining time under 2000ms, got %q", header)
+	}
+
+	// Without a deadline, a client-supplied header is not passed on
+	header = ""
+	req = httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	req.Header.Set(sharedMiddleware.TimeoutHeader, "999999")
+	pool.Forward(pool.Pick(), httptest.NewRecorder(), req)
+	if header != "" {
+		t.Errorf("expected no timeout header, got %q", header)
+	}
+}
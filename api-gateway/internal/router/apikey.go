@@ -0,0 +1,128 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"truckify/api-gateway/internal/compose"
+	"truckify/api-gateway/internal/middleware"
+	"truckify/shared/pkg/response"
+)
+
+// apiKeyVerifyPath is the auth service endpoint that verifies API keys
+const apiKeyVerifyPath = "/api-keys/verify"
+
+// internalPaths are upstream endpoints that only the gateway calls. Routes
+// answer them with not found and they are left out of the API document.
+var internalPaths = map[string]string{
+	"auth": apiKeyVerifyPath,
+}
+
+// hideInternal answers requests for an upstream's internal endpoint with
+// not found. It runs after the route's prefix is stripped, so it sees the
+// path as the upstream would.
+func hideInternal(upstream string, next http.Handler) http.Handler {
+	internal, ok := internalPaths[upstream]
+	if !ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Clean(r.URL.Path) == internal {
+			notFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// VerifyKey asks the auth service who an API key acts as
+func (router *Router) VerifyKey(ctx context.Context, key, ipAddress string) (*middleware.KeyIdentity, error) {
+	upstream := router.upstream("auth")
+	if upstream == nil {
+		return nil, fmt.Errorf("no auth upstream")
+	}
+	return verifyKey(ctx, upstream, key, ipAddress)
+}
+
+func verifyKey(ctx context.Context, upstream compose.Doer, key, ipAddress string) (*middleware.KeyIdentity, error) {
+	body, err := json.Marshal(map[string]string{"key": key, "ip_address": ipAddress})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://auth"+apiKeyVerifyPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := upstream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return nil, middleware.ErrInvalidAPIKey
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("verify API key: status %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data middleware.KeyIdentity `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	return &envelope.Data, nil
+}
+
+// requireScopes lets API clients and acting dispatchers through only if
+// they hold one of scopes, logging each request they make. Without scopes
+// they are turned away. Signed-in users acting as themselves are not
+// affected.
+func (router *Router) requireScopes(scopes []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		granted, ok := r.Context().Value("scopes").([]string)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, _ := r.Context().Value("user_id").(string)
+		if !holdsAny(granted, scopes) {
+			requestID, _ := r.Context().Value("request_id").(string)
+			if _, acting := r.Context().Value("actor_id").(string); acting {
+				response.Forbidden(w, "Delegation lacks the scope for this route", strings.Join(scopes, " "), requestID)
+				return
+			}
+			response.Forbidden(w, "API key lacks the scope for this route", strings.Join(scopes, " "), requestID)
+			return
+		}
+
+		if actorID, acting := r.Context().Value("actor_id").(string); acting {
+			delegationID, _ := r.Context().Value("delegation_id").(string)
+			router.logger.Info("Delegated request", "delegation_id", delegationID, "actor_id", actorID, "user_id", userID, "method", r.Method, "path", r.URL.Path)
+		} else {
+			keyID, _ := r.Context().Value("api_key_id").(string)
+			router.logger.Info("API key request", "api_key_id", keyID, "user_id", userID, "method", r.Method, "path", r.URL.Path)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func holdsAny(granted, wanted []string) bool {
+	for _, g := range granted {
+		for _, w := range wanted {
+			if g == w {
+				return true
+			}
+		}
+	}
+	return false
+}
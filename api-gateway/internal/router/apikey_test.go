@@ -0,0 +1,189 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"truckify/api-gateway/internal/middleware"
+	"truckify/shared/pkg/logger"
+)
+
+func TestVerifyKey(t *testing.T) {
+	auth := doerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != apiKeyVerifyPath {
+			t.Errorf("path = %s", req.URL.Path)
+		}
+		var body struct {
+			Key       string `json:"key"`
+			IPAddress string `json:"ip_address"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body.Key == "tfy_good" && body.IPAddress != "203.0.113.9" {
+			t.Errorf("ip_address = %s", body.IPAddress)
+		}
+
+		rec := httptest.NewRecorder()
+		if body.Key != "tfy_good" {
+			rec.WriteHeader(http.StatusUnauthorized)
+			return rec.Result(), nil
+		}
+		json.NewEncoder(rec).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"key_id": "k1", "user_id": "u1", "user_type": "shipper", "scopes": []string{"job:read"}, "rate_limit": 30},
+		})
+		return rec.Result(), nil
+	})
+
+	identity, err := verifyKey(context.Background(), auth, "tfy_good", "203.0.113.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.KeyID != "k1" || identity.UserID != "u1" || len(identity.Scopes) != 1 || identity.RateLimit != 30 {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	if _, err := verifyKey(context.Background(), auth, "tfy_bad", ""); err != middleware.ErrInvalidAPIKey {
+		t.Errorf("err = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	router := &Router{logger: logger.New("test", "error")}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		scopes []string
+		ctx    map[string]interface{}
+		want   int
+	}{
+		{"user", nil, map[string]interface{}{"user_id": "u1"}, http.StatusOK},
+		{"client with scope", []string{"job:read", "job:create"}, map[string]interface{}{"api_key_id": "k1", "scopes": []string{"job:create"}}, http.StatusOK},
+		{"client without scope", []string{"job:read"}, map[string]interface{}{"api_key_id": "k1", "scopes": []string{"bid:read"}}, http.StatusForbidden},
+		{"client on closed route", nil, map[string]interface{}{"api_key_id": "k1", "scopes": []string{"job:read"}}, http.StatusForbidden},
+		{"dispatcher with scope", []string{"job:create"}, map[string]interface{}{"actor_id": "d1", "delegation_id": "g1", "scopes": []string{"job:create"}}, http.StatusOK},
+		{"dispatcher on closed route", nil, map[string]interface{}{"actor_id": "d1", "delegation_id": "g1", "scopes": []string{"job:create"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			for k, v := range tt.ctx {
+				ctx = context.WithValue(ctx, k, v)
+			}
+			rec := httptest.NewRecorder()
+			router.requireScopes(tt.scopes, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil).WithContext(ctx))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHideInternal(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for path, want := range map[string]int{
+		"/api-keys/verify":    http.StatusNotFound,
+		"/api-keys/./verify":  http.StatusNotFound,
+		"/api-keys":           http.StatusOK,
+		"/api-keys/k1/events": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		hideInternal("auth", ok).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestForwardIdentity_APIClient(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "u1")
+	ctx = context.WithValue(ctx, "api_key_id", "k1")
+	ctx = context.WithValue(ctx, "scopes", []string{"job:read", "job:create"})
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer tfy_secret")
+	req.Header.Set("X-API-Key", "tfy_secret")
+
+	forwardIdentity(req)
+
+	if got := req.Header.Get("X-Permissions"); got != "job:read job:create" {
+		t.Errorf("X-Permissions = %q", got)
+	}
+	if req.Header.Get("Authorization") != "" || req.Header.Get("X-API-Key") != "" {
+		t.Error("the key must not reach the upstream")
+	}
+
+	user := httptest.NewRequest(http.MethodGet, "/jobs", nil).WithContext(context.WithValue(context.Background(), "user_id", "u1"))
+	user.Header.Set("X-Permissions", "user:manage")
+	forwardIdentity(user)
+	if _, ok := user.Header["X-Permissions"]; ok {
+		t.Error("X-Permissions from the client must be dropped")
+	}
+}
+
+func TestForwardIdentity_Org(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "u1")
+	ctx = context.WithValue(ctx, "org_id", "o1")
+	ctx = context.WithValue(ctx, "org_role", "member")
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil).WithContext(ctx)
+
+	forwardIdentity(req)
+
+	if req.Header.Get("X-Org-ID") != "o1" || req.Header.Get("X-Org-Role") != "member" {
+		t.Errorf("X-Org-ID = %q, X-Org-Role = %q", req.Header.Get("X-Org-ID"), req.Header.Get("X-Org-Role"))
+	}
+
+	spoofed := httptest.NewRequest(http.MethodGet, "/jobs", nil).WithContext(context.WithValue(context.Background(), "user_id", "u1"))
+	spoofed.Header.Set("X-Org-ID", "o2")
+	forwardIdentity(spoofed)
+	if spoofed.Header.Get("X-Org-ID") != "" {
+		t.Error("X-Org-ID from the client must be dropped")
+	}
+}
+
+func TestForwardIdentity_Acting(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "u1")
+	ctx = context.WithValue(ctx, "user_type", "shipper")
+	ctx = context.WithValue(ctx, "actor_id", "d1")
+	ctx = context.WithValue(ctx, "actor_type", "dispatcher")
+	ctx = context.WithValue(ctx, "scopes", []string{"job:create"})
+	req := httptest.NewRequest(http.MethodPost, "/jobs", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer acting-token")
+
+	forwardIdentity(req)
+
+	if req.Header.Get("X-User-ID") != "u1" || req.Header.Get("X-Actor-ID") != "d1" || req.Header.Get("X-Actor-Type") != "dispatcher" {
+		t.Errorf("X-User-ID = %q, X-Actor-ID = %q, X-Actor-Type = %q",
+			req.Header.Get("X-User-ID"), req.Header.Get("X-Actor-ID"), req.Header.Get("X-Actor-Type"))
+	}
+	if req.Header.Get("X-Permissions") != "job:create" || req.Header.Get("Authorization") != "" {
+		t.Error("acting dispatchers are limited to the delegated permissions")
+	}
+
+	spoofed := httptest.NewRequest(http.MethodPost, "/jobs", nil).WithContext(context.WithValue(context.Background(), "user_id", "u1"))
+	spoofed.Header.Set("X-Actor-ID", "d2")
+	forwardIdentity(spoofed)
+	if spoofed.Header.Get("X-Actor-ID") != "" {
+		t.Error("X-Actor-ID from the client must be dropped")
+	}
+}
+
+func TestForwardIdentity_ClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil).WithContext(context.WithValue(context.Background(), "client_ip", "203.0.113.9"))
+	req.Header.Set("X-Client-IP", "1.2.3.4")
+
+	forwardIdentity(req)
+
+	if got := req.Header.Get("X-Client-IP"); got != "203.0.113.9" {
+		t.Errorf("X-Client-IP = %q", got)
+	}
+
+	spoofed := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	spoofed.Header.Set("X-Client-IP", "1.2.3.4")
+	forwardIdentity(spoofed)
+	if spoofed.Header.Get("X-Client-IP") != "" {
+		t.Error("X-Client-IP from the client must be dropped")
+	}
+}
@@ -0,0 +1,63 @@
+package router
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	breakerStateDesc = prometheus.NewDesc(
+		"gateway_circuit_breaker_state",
+		"Circuit breaker state per upstream (0 closed, 1 open, 2 half-open).",
+		[]string{"upstream"}, nil,
+	)
+	retriesDesc = prometheus.NewDesc(
+		"gateway_upstream_retries_total",
+		"Requests retried against an upstream.",
+		[]string{"upstream"}, nil,
+	)
+	rejectedDesc = prometheus.NewDesc(
+		"gateway_circuit_breaker_rejected_total",
+		"Requests rejected by an open circuit breaker.",
+		[]string{"upstream"}, nil,
+	)
+	targetHealthyDesc = prometheus.NewDesc(
+		"gateway_upstream_target_healthy",
+		"Whether an upstream target passes its health check.",
+		[]string{"upstream", "target"}, nil,
+	)
+	targetActiveDesc = prometheus.NewDesc(
+		"gateway_upstream_target_active_requests",
+		"Requests in flight to an upstream target.",
+		[]string{"upstream", "target"}, nil,
+	)
+)
+
+// upstreamCollector exports the state of the current upstream pools
+type upstreamCollector struct {
+	router *Router
+}
+
+func (c *upstreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- retriesDesc
+	ch <- rejectedDesc
+	ch <- targetHealthyDesc
+	ch <- targetActiveDesc
+}
+
+func (c *upstreamCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.router.upstreamStatus() {
+		ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, float64(s.CircuitBreaker), s.Name)
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(s.Retries), s.Name)
+		ch <- prometheus.MustNewConstMetric(rejectedDesc, prometheus.CounterValue, float64(s.Rejected), s.Name)
+
+		for _, t := range s.Targets {
+			healthy := 0.0
+			if t.Healthy {
+				healthy = 1
+			}
+			ch <- prometheus.MustNewConstMetric(targetHealthyDesc, prometheus.GaugeValue, healthy, s.Name, t.URL)
+			ch <- prometheus.MustNewConstMetric(targetActiveDesc, prometheus.GaugeValue, float64(t.Active), s.Name, t.URL)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"truckify/api-gateway/internal/compose"
+	"truckify/api-gateway/internal/config"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/response"
+)
+
+const (
+	// apiDocTTL is how long the merged API document is served before the
+	// services are asked for theirs again
+	apiDocTTL = time.Minute
+	// apiDocTimeout bounds fetching every service's document
+	apiDocTimeout = 5 * time.Second
+)
+
+// apiDoc caches the merged API document for one routing table
+type apiDoc struct {
+	mu    sync.Mutex
+	table *routeTable
+	built time.Time
+	body  []byte
+}
+
+// openAPI serves the public API: the document each upstream serves at
+// openapi.Path, with paths as clients call them through the gateway.
+// Endpoints the gateway serves itself are not included.
+func (router *Router) openAPI(w http.ResponseWriter, r *http.Request) {
+	table := router.table.Load()
+	if table == nil {
+		notFound(w, r)
+		return
+	}
+
+	router.apiDoc.mu.Lock()
+	if router.apiDoc.table != table || time.Since(router.apiDoc.built) > apiDocTTL {
+		doc := mergeAPIDocs(table, router.fetchAPIDocs(r.Context(), table))
+		body, err := json.Marshal(doc)
+		if err != nil {
+			router.apiDoc.mu.Unlock()
+			requestID, _ := r.Context().Value("request_id").(string)
+			router.logger.Error("Failed to encode API document", "error", err)
+			response.InternalServerError(w, "Failed to build API document", "", requestID)
+			return
+		}
+		router.apiDoc.table, router.apiDoc.built, router.apiDoc.body = table, time.Now(), body
+	}
+	body := router.apiDoc.body
+	router.apiDoc.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// fetchAPIDocs asks every upstream for its document at once. Upstreams
+// that do not answer are left out and logged.
+func (router *Router) fetchAPIDocs(ctx context.Context, table *routeTable) map[string]*openapi.Document {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), apiDocTimeout)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		docs = make(map[string]*openapi.Document, len(table.pools))
+	)
+	for name, pool := range table.pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doc, err := fetchAPIDoc(ctx, name, pool)
+			if err != nil {
+				router.logger.Warn("API document unavailable", "upstream", name, "error", err)
+				return
+			}
+			mu.Lock()
+			docs[name] = doc
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return docs
+}
+
+// fetchAPIDoc gets one upstream's document
+func fetchAPIDoc(ctx context.Context, name string, upstream compose.Doer) (*openapi.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+name+openapi.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := upstream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var doc openapi.Document
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// mergeAPIDocs combines the upstream documents into one. An operation is
+// kept under the public path that the routing table sends to it, and
+// takes its security from that route; operations no route reaches, such
+// as internal endpoints, are dropped.
+func mergeAPIDocs(table *routeTable, docs map[string]*openapi.Document) *openapi.Document {
+	merged := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Truckify API",
+			Version:     "1.0.0",
+			Description: "The Truckify services as served by the API gateway",
+		},
+		Paths: map[string]*openapi.PathItem{},
+		Components: &openapi.Components{
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"apiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for path, item := range docs[name].Paths {
+			if internalPaths[name] == path {
+				continue
+			}
+			for method, op := range *item {
+				public, route := table.publicPath(name, strings.ToUpper(method), path)
+				if route == nil {
+					continue
+				}
+
+				op := *op
+				switch route.Auth {
+				case config.AuthRequired:
+					op.Security = []map[string][]string{{"bearerAuth": {}}}
+				case config.AuthOptional:
+					op.Security = []map[string][]string{{}, {"bearerAuth": {}}}
+				}
+				if len(route.Scopes) > 0 {
+					op.Security = append(op.Security, map[string][]string{"apiKeyAuth": {}})
+					op.Description = strings.TrimSpace(op.Description + "\n\nAPI keys need one of the scopes " + strings.Join(route.Scopes, ", ") + ".")
+				}
+				if len(route.Roles) > 0 {
+					op.Description = strings.TrimSpace(op.Description + "\n\nRestricted to " + strings.Join(route.Roles, ", ") + " users.")
+				}
+
+				publicItem := merged.Paths[public]
+				if publicItem == nil {
+					publicItem = &openapi.PathItem{}
+					merged.Paths[public] = publicItem
+				}
+				(*publicItem)[method] = &op
+			}
+		}
+	}
+	return merged
+}
+
+// publicPath returns the gateway path for an upstream's path and the route
+// that forwards it there, or a nil route if none does
+func (t *routeTable) publicPath(upstream, method, path string) (string, *config.Route) {
+	for _, route := range t.config.Routes {
+		if route.Upstream != upstream {
+			continue
+		}
+		public := route.StripPrefix + path
+		if t.match(method, public) == route {
+			return public, route
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"truckify/api-gateway/internal/config"
+	"truckify/shared/pkg/openapi"
+)
+
+// serviceDoc builds the document a service with these routes would serve
+func serviceDoc(title string, routes openapi.Routes) *openapi.Document {
+	r := mux.NewRouter()
+	ok := func(http.ResponseWriter, *http.Request) {}
+	for key := range routes {
+		method, path, _ := strings.Cut(key, " ")
+		r.HandleFunc(path, ok).Methods(method)
+	}
+	return openapi.Build(r, openapi.Info{Title: title}, routes)
+}
+
+func TestMergeAPIDocs(t *testing.T) {
+	routes := &config.Routes{Routes: []*config.Route{
+		{Name: "login", Path: "/api/v1/auth/login", Methods: []string{http.MethodPost}, Upstream: "auth", StripPrefix: "/api/v1/auth", Auth: config.AuthOptional},
+		{Name: "job-bids", Path: "/api/v1/jobs/{job_id}/bids", Upstream: "bidding", StripPrefix: "/api/v1", Auth: config.AuthRequired},
+		{Name: "api-keys", Prefix: "/api/v1/auth/api-keys", Upstream: "auth", StripPrefix: "/api/v1/auth", Auth: config.AuthRequired},
+		{Name: "jobs", Prefix: "/api/v1/jobs", Upstream: "job", StripPrefix: "/api/v1", Auth: config.AuthRequired, Scopes: []string{"job:read"}},
+		{Name: "admin", Prefix: "/api/v1/admin", Upstream: "auth", StripPrefix: "/api/v1", Auth: config.AuthRequired, Roles: []string{"admin"}},
+	}}
+	table := newRouteTable(routes, func(*config.Route) http.Handler { return http.NotFoundHandler() })
+
+	docs := map[string]*openapi.Document{
+		"auth": serviceDoc("auth-service", openapi.Routes{
+			"POST /login":           {Summary: "Log in"},
+			"GET /admin/users":      {Summary: "List users"},
+			"GET /health":           {Summary: "Health check"},
+			"POST /api-keys/verify": {Summary: "Verify an API key"},
+		}),
+		"job": serviceDoc("job-service", openapi.Routes{
+			"GET /jobs/{id}":      {Summary: "Get a job"},
+			"GET /jobs/{id}/bids": {Summary: "Shadowed by the bidding route"},
+		}),
+		"bidding": serviceDoc("bidding-service", openapi.Routes{
+			"GET /jobs/{jobId}/bids": {Summary: "List a job's bids"},
+		}),
+	}
+
+	merged := mergeAPIDocs(table, docs)
+
+	login := merged.Operation(http.MethodPost, "/api/v1/auth/login")
+	if login == nil {
+		t.Fatal("login is missing")
+	}
+	if len(login.Security) != 2 || len(login.Security[0]) != 0 {
+		t.Errorf("optional auth security = %v", login.Security)
+	}
+
+	users := merged.Operation(http.MethodGet, "/api/v1/admin/users")
+	if users == nil || len(users.Security) != 1 || !strings.Contains(users.Description, "Restricted to admin users") {
+		t.Errorf("admin users = %+v", users)
+	}
+
+	if op := merged.Operation(http.MethodGet, "/api/v1/jobs/{id}"); op == nil || op.Security[0]["bearerAuth"] == nil ||
+		len(op.Security) != 2 || op.Security[1]["apiKeyAuth"] == nil || !strings.Contains(op.Description, "job:read") {
+		t.Errorf("get job = %+v", op)
+	}
+	if merged.Operation(http.MethodPost, "/api/v1/auth/api-keys/verify") != nil {
+		t.Error("internal endpoints are left out")
+	}
+	if op := merged.Operation(http.MethodGet, "/api/v1/jobs/{jobId}/bids"); op == nil || op.Summary != "List a job's bids" {
+		t.Errorf("bids should come from the bidding service, got %+v", op)
+	}
+	if merged.Operation(http.MethodGet, "/api/v1/jobs/{id}/bids") != nil {
+		t.Error("job service bids are not reachable through the gateway")
+	}
+	if merged.Operation(http.MethodGet, "/api/v1/auth/health") != nil || merged.Operation(http.MethodGet, "/health") != nil {
+		t.Error("routes the gateway does not forward are left out")
+	}
+
+	if merged.Components.SecuritySchemes["bearerAuth"] == nil {
+		t.Error("bearerAuth scheme is missing")
+	}
+}
+
+func TestFetchAPIDoc(t *testing.T) {
+	want := serviceDoc("job-service", openapi.Routes{"GET /jobs": {Summary: "List jobs"}})
+	upstream := doerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != openapi.Path {
+			t.Errorf("path = %s", req.URL.Path)
+		}
+		rec := httptest.NewRecorder()
+		want.ServeHTTP(rec, req)
+		return rec.Result(), nil
+	})
+
+	doc, err := fetchAPIDoc(context.Background(), "job", upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := doc.Operation(http.MethodGet, "/jobs"); op == nil || op.Summary != "List jobs" {
+		t.Errorf("fetched document = %+v", doc)
+	}
+
+	failing := doerFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusNotFound)
+		return rec.Result(), nil
+	})
+	if _, err := fetchAPIDoc(context.Background(), "admin", failing); err == nil {
+		t.Error("expected an error for a service without a document")
+	}
+}
+
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
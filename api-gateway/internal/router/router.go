@@ -1,16 +1,26 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/mux"
+	"truckify/api-gateway/internal/compose"
+	"truckify/api-gateway/internal/config"
+	"truckify/api-gateway/internal/graph"
 	"truckify/api-gateway/internal/middleware"
+	"truckify/api-gateway/internal/proxy"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	sharedMiddleware "truckify/shared/pkg/middleware"
 	"truckify/shared/pkg/response"
+	"truckify/shared/pkg/tracing"
 )
 
 // Router holds the HTTP router and dependencies
@@ -19,7 +29,56 @@ type Router struct {
 	logger         *logger.Logger
 	authMiddleware *middleware.AuthMiddleware
 	rateLimiter    *sharedMiddleware.RateLimiter
+	proxies        sharedMiddleware.TrustedProxies
 	allowedOrigins []string
+	viewCache      compose.Cache
+	graphLimits    graph.Limits
+	table          atomic.Pointer[routeTable]
+	apiDoc         apiDoc
+}
+
+// routeTable is the routing table built from the routes config. It is
+// replaced as a whole when the config is reloaded.
+type routeTable struct {
+	handler *mux.Router
+	pools   map[string]*proxy.Pool
+	config  *config.Routes
+	// routes maps each table entry back to its config
+	routes map[*mux.Route]*config.Route
+}
+
+// newRouteTable adds a table entry for each configured route, in order,
+// served by the handler handlerFor returns
+func newRouteTable(routes *config.Routes, handlerFor func(*config.Route) http.Handler) *routeTable {
+	table := &routeTable{
+		handler: mux.NewRouter(),
+		config:  routes,
+		routes:  make(map[*mux.Route]*config.Route, len(routes.Routes)),
+	}
+	table.handler.NotFoundHandler = http.HandlerFunc(notFound)
+	for _, route := range routes.Routes {
+		var r *mux.Route
+		if route.Path != "" {
+			r = table.handler.Handle(route.Path, handlerFor(route))
+		} else {
+			r = table.handler.PathPrefix(route.Prefix).Handler(handlerFor(route))
+		}
+		if len(route.Methods) > 0 {
+			r.Methods(route.Methods...)
+		}
+		table.routes[r] = route
+	}
+	return table
+}
+
+// match returns the configured route that serves a request, or nil
+func (t *routeTable) match(method, path string) *config.Route {
+	var m mux.RouteMatch
+	req := &http.Request{Method: method, URL: &url.URL{Path: path}}
+	if !t.handler.Match(req, &m) || m.MatchErr != nil {
+		return nil
+	}
+	return t.routes[m.Route]
 }
 
 // NewRouter creates a new router instance
@@ -28,6 +87,8 @@ func NewRouter(
 	authMiddleware *middleware.AuthMiddleware,
 	rateLimiter *sharedMiddleware.RateLimiter,
 	allowedOrigins []string,
+	viewCache compose.Cache,
+	graphLimits graph.Limits,
 ) *Router {
 	return &Router{
 		mux:            mux.NewRouter(),
@@ -35,181 +96,360 @@ func NewRouter(
 		authMiddleware: authMiddleware,
 		rateLimiter:    rateLimiter,
 		allowedOrigins: allowedOrigins,
+		viewCache:      viewCache,
+		graphLimits:    graphLimits,
 	}
 }
 
+// SetTrustedProxies sets the proxies whose X-Forwarded-For is believed when
+// working out the client address passed on to services
+func (router *Router) SetTrustedProxies(proxies sharedMiddleware.TrustedProxies) {
+	router.proxies = proxies
+}
+
 // Setup configures all routes
 func (router *Router) Setup() http.Handler {
 	// Apply global middlewares
 	router.mux.Use(sharedMiddleware.RequestID)
+	router.mux.Use(sharedMiddleware.ClientIP(router.proxies))
+	router.mux.Use(tracing.Middleware)
+	router.mux.Use(metrics.Middleware("api-gateway"))
 	router.mux.Use(sharedMiddleware.Recovery(router.logger))
 	router.mux.Use(sharedMiddleware.Logger(router.logger))
 	router.mux.Use(sharedMiddleware.CORS(router.allowedOrigins))
 	router.mux.Use(sharedMiddleware.SecurityHeaders)
-	router.mux.Use(router.rateLimiter.Limit)
 
 	// Health check endpoint
 	router.mux.HandleFunc("/health", router.healthCheck).Methods(http.MethodGet)
-	router.mux.HandleFunc("/metrics", router.metricsHandler).Methods(http.MethodGet)
+	router.mux.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	metrics.Register(&upstreamCollector{router: router})
+
+	// Upstream state for operators
+	router.mux.Handle("/gateway/upstreams", router.authMiddleware.Authenticate(
+		router.rateLimiter.Limit(router.requireScopes(nil, requireRoles([]string{"admin"}, http.HandlerFunc(router.upstreams)))),
+	)).Methods(http.MethodGet)
+
+	// Composed views for the mobile app, fetched from several services at
+	// once. They and GraphQL are for the apps, not API clients.
+	composer := compose.NewComposer(router.upstream, router.viewCache, router.logger)
+	router.mux.Handle("/api/v1/views/job/{id}", router.authMiddleware.Authenticate(
+		router.rateLimiter.Limit(router.requireScopes(nil, composer.Handler(compose.JobView))),
+	)).Methods(http.MethodGet)
+	router.mux.Handle("/api/v1/views/driver-home", router.authMiddleware.Authenticate(
+		router.rateLimiter.Limit(router.requireScopes(nil, requireRoles([]string{"driver"}, composer.Handler(compose.DriverHomeView)))),
+	)).Methods(http.MethodGet)
+
+	// GraphQL over the same services. Queries need a token up front;
+	// subscription clients send theirs once connected.
+	graphServer := graph.NewServer(router.upstream, &graph.HubFeed{Target: router.target("notification")},
+		router.authMiddleware.Authorize, router.graphLimits, router.logger)
+	router.mux.Handle("/api/v1/graphql", router.authMiddleware.Authenticate(
+		router.rateLimiter.Limit(router.requireScopes(nil, graphServer)),
+	)).Methods(http.MethodPost)
+	router.mux.Handle("/api/v1/graphql", router.rateLimiter.Limit(
+		http.HandlerFunc(graphServer.ServeWebSocket),
+	)).Methods(http.MethodGet)
+
+	// The public API, merged from the services' documents
+	router.mux.Handle("/api/v1/openapi.json", router.rateLimiter.Limit(
+		http.HandlerFunc(router.openAPI),
+	)).Methods(http.MethodGet)
+
+	// Everything else goes through the routing table
+	router.mux.PathPrefix("/").HandlerFunc(router.serveRoutes)
 
-	// API v1 routes
-	api := router.mux.PathPrefix("/api/v1").Subrouter()
+	return router.mux
+}
 
-	// Public routes (no authentication required)
-	router.setupPublicRoutes(api)
+// Load builds a routing table from the routes config and swaps it in. The
+// new upstream pools start health checking before they take traffic, and
+// the previous pools are stopped once replaced.
+func (router *Router) Load(routes *config.Routes) error {
+	pools := make(map[string]*proxy.Pool, len(routes.Upstreams))
+	for name, upstream := range routes.Upstreams {
+		pool, err := proxy.NewPool(name, upstream, forwardIdentity, router.proxyError, router.logger)
+		if err != nil {
+			return fmt.Errorf("upstream %q: %w", name, err)
+		}
+		pools[name] = pool
+	}
 
-	// Protected routes (authentication required)
-	router.setupProtectedRoutes(api)
+	limits := make(map[string]func(http.Handler) http.Handler, len(routes.RateLimits))
+	for name, rl := range routes.RateLimits {
+		limits[name] = router.rateLimiter.Apply(ratePolicy(name, rl))
+	}
+	if _, ok := limits[config.RateLimitDefault]; !ok {
+		limits[config.RateLimitDefault] = router.rateLimiter.Limit
+	}
 
-	return router.mux
+	table := newRouteTable(routes, func(route *config.Route) http.Handler {
+		return router.routeHandler(route, pools[route.Upstream], limits[route.RateLimit])
+	})
+	table.pools = pools
+
+	for _, pool := range pools {
+		pool.Start()
+	}
+
+	old := router.table.Swap(table)
+	if old != nil {
+		for _, pool := range old.pools {
+			pool.Stop()
+		}
+	}
+
+	router.logger.Info("Routing table loaded", "routes", len(routes.Routes), "upstreams", len(pools))
+	return nil
 }
 
-// setupPublicRoutes configures public routes
-func (router *Router) setupPublicRoutes(api *mux.Router) {
-	// Auth service routes (public)
-	authPublic := api.PathPrefix("/auth").Subrouter()
-	authPublic.PathPrefix("").Handler(router.createProxy("http://auth-service:8001"))
-}
-
-// setupProtectedRoutes configures protected routes
-func (router *Router) setupProtectedRoutes(api *mux.Router) {
-	// Create a protected subrouter
-	protected := api.PathPrefix("").Subrouter()
-	protected.Use(router.authMiddleware.Authenticate)
-
-	// User service routes
-	protected.PathPrefix("/users").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://user-service:8002")),
-	)
-
-	// Shipper service routes
-	protected.PathPrefix("/shippers").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://shipper-service:8003")),
-	)
-
-	// Driver service routes
-	protected.PathPrefix("/drivers").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://driver-service:8004")),
-	)
-
-	// Fleet service routes
-	protected.PathPrefix("/fleets").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://fleet-service:8005")),
-	)
-
-	// Job service routes
-	protected.PathPrefix("/jobs").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://job-service:8006")),
-	)
-
-	// Matching service routes
-	protected.PathPrefix("/matching").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://matching-service:8007")),
-	)
-
-	// Bidding service routes
-	protected.PathPrefix("/bids").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://bidding-service:8008")),
-	)
-
-	// Route service routes
-	protected.PathPrefix("/routes").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://route-service:8009")),
-	)
-
-	// Backhaul service routes
-	protected.PathPrefix("/backhaul").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://backhaul-service:8010")),
-	)
-
-	// Tracking service routes
-	protected.PathPrefix("/tracking").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://tracking-service:8011")),
-	)
-
-	// Payment service routes
-	protected.PathPrefix("/payments").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://payment-service:8012")),
-	)
-
-	// Rating service routes
-	protected.PathPrefix("/ratings").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://rating-service:8013")),
-	)
-
-	// Notification service routes
-	protected.PathPrefix("/notifications").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://notification-service:8014")),
-	)
-
-	// Analytics service routes
-	protected.PathPrefix("/analytics").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://analytics-service:8015")),
-	)
-
-	// Compliance service routes
-	protected.PathPrefix("/compliance").Handler(
-		http.StripPrefix("/api/v1", router.createProxy("http://compliance-service:8016")),
-	)
-}
-
-// createProxy creates a reverse proxy for a service
-func (router *Router) createProxy(target string) http.Handler {
-	targetURL, err := url.Parse(target)
-	if err != nil {
-		router.logger.Error("Failed to parse target URL", "target", target, "error", err)
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			requestID, _ := r.Context().Value("request_id").(string)
-			response.ServiceUnavailable(w, "Service unavailable", "", requestID)
-		})
+// Close stops health checking
+func (router *Router) Close() {
+	if table := router.table.Swap(nil); table != nil {
+		for _, pool := range table.pools {
+			pool.Stop()
+		}
 	}
+}
 
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+// serveRoutes dispatches a request through the current routing table
+func (router *Router) serveRoutes(w http.ResponseWriter, r *http.Request) {
+	table := router.table.Load()
+	if table == nil {
+		notFound(w, r)
+		return
+	}
+	table.handler.ServeHTTP(w, r)
+}
 
-	// Customize the proxy director to preserve headers
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
-		req.Host = targetURL.Host
+// upstream returns the current pool for an upstream, or nil
+func (router *Router) upstream(name string) compose.Doer {
+	table := router.table.Load()
+	if table == nil {
+		return nil
+	}
+	if pool, ok := table.pools[name]; ok {
+		return pool
+	}
+	return nil
+}
 
-		// Forward user context as headers
-		if userID, ok := req.Context().Value("user_id").(string); ok {
-			req.Header.Set("X-User-ID", userID)
+// target returns a function picking a healthy instance of an upstream
+func (router *Router) target(name string) func() (*url.URL, error) {
+	return func() (*url.URL, error) {
+		table := router.table.Load()
+		if table == nil || table.pools[name] == nil {
+			return nil, proxy.ErrNoHealthyTargets
 		}
-		if email, ok := req.Context().Value("email").(string); ok {
-			req.Header.Set("X-User-Email", email)
+		t := table.pools[name].Pick()
+		if t == nil {
+			return nil, proxy.ErrNoHealthyTargets
 		}
-		if userType, ok := req.Context().Value("user_type").(string); ok {
-			req.Header.Set("X-User-Type", userType)
+		return t.URL, nil
+	}
+}
+
+// routeHandler forwards a route's requests to its upstream pool, applying
+// the route's auth requirement, rate limit, prefix stripping and timeout.
+// limit is nil for routes without a rate limit.
+func (router *Router) routeHandler(route *config.Route, pool *proxy.Pool, limit func(http.Handler) http.Handler) http.Handler {
+	template := route.Path
+	if template == "" {
+		template = route.Prefix
+	}
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := pool.Pick()
+		if target == nil {
+			requestID, _ := r.Context().Value("request_id").(string)
+			response.ServiceUnavailable(w, "Service unavailable", "no healthy instances of "+pool.Name(), requestID)
+			return
 		}
-		if requestID, ok := req.Context().Value("request_id").(string); ok {
-			req.Header.Set("X-Request-ID", requestID)
+
+		if route.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
 		}
+
+		pool.Forward(target, w, r)
+	})
+
+	handler = hideInternal(route.Upstream, handler)
+	if route.StripPrefix != "" {
+		handler = http.StripPrefix(route.StripPrefix, handler)
+	}
+	if len(route.Roles) > 0 {
+		handler = requireRoles(route.Roles, handler)
+	}
+	handler = router.requireScopes(route.Scopes, handler)
+
+	// Rate limiting runs after authentication so that callers are counted
+	// per user rather than per address
+	if limit != nil {
+		handler = limit(handler)
 	}
 
-	// Error handler
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		router.logger.Error("Proxy error", "error", err, "target", target)
+	switch route.Auth {
+	case config.AuthRequired:
+		handler = router.authMiddleware.Authenticate(handler)
+	case config.AuthOptional:
+		handler = router.authMiddleware.OptionalAuth(handler)
+	}
+
+	next := handler
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.SetRoute(r.Context(), template)
+		tracing.SpanName(r.Context(), r.Method+" "+template)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ratePolicy converts a rate limit policy from the routes config
+func ratePolicy(name string, rl *config.RateLimit) sharedMiddleware.Policy {
+	quota := func(q *config.RateLimitQuota) sharedMiddleware.Quota {
+		return sharedMiddleware.Quota{Algorithm: q.Algorithm, Limit: q.Limit, Window: q.Window}
+	}
+
+	policy := sharedMiddleware.Policy{Name: name, Default: quota(&rl.RateLimitQuota)}
+	if len(rl.UserTypes) > 0 {
+		policy.UserTypes = make(map[string]sharedMiddleware.Quota, len(rl.UserTypes))
+		for userType, q := range rl.UserTypes {
+			policy.UserTypes[userType] = quota(q)
+		}
+	}
+	return policy
+}
+
+// requireRoles rejects callers whose user type is not one of roles
+func requireRoles(roles []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userType, _ := r.Context().Value("user_type").(string)
+		for _, role := range roles {
+			if userType == role {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
 		requestID, _ := r.Context().Value("request_id").(string)
-		response.ServiceUnavailable(w, "Service unavailable", err.Error(), requestID)
+		response.Forbidden(w, "Insufficient permissions", "", requestID)
+	})
+}
+
+// forwardIdentity sets the identity headers on a proxied request
+func forwardIdentity(req *http.Request) {
+	// Identity headers are only trusted when set here from a validated token
+	req.Header.Del("X-User-ID")
+	req.Header.Del("X-User-Email")
+	req.Header.Del("X-User-Type")
+	req.Header.Del("X-Session-ID")
+	req.Header.Del("X-Org-ID")
+	req.Header.Del("X-Org-Role")
+	req.Header.Del("X-Actor-ID")
+	req.Header.Del("X-Actor-Type")
+	req.Header.Del("X-Permissions")
+	req.Header.Del("X-API-Key")
+	req.Header.Del(sharedMiddleware.ClientIPHeader)
+
+	// Forward user context as headers
+	if userID, ok := req.Context().Value("user_id").(string); ok {
+		req.Header.Set("X-User-ID", userID)
+	}
+	if email, ok := req.Context().Value("email").(string); ok {
+		req.Header.Set("X-User-Email", email)
+	}
+	if userType, ok := req.Context().Value("user_type").(string); ok {
+		req.Header.Set("X-User-Type", userType)
+	}
+	if sessionID, ok := req.Context().Value("session_id").(string); ok && sessionID != "" {
+		req.Header.Set("X-Session-ID", sessionID)
+	}
+	if orgID, ok := req.Context().Value("org_id").(string); ok {
+		req.Header.Set("X-Org-ID", orgID)
+		req.Header.Set("X-Org-Role", req.Context().Value("org_role").(string))
+	}
+	if actorID, ok := req.Context().Value("actor_id").(string); ok {
+		req.Header.Set("X-Actor-ID", actorID)
+		req.Header.Set("X-Actor-Type", req.Context().Value("actor_type").(string))
+	}
+	if requestID, ok := req.Context().Value("request_id").(string); ok {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	if clientIP, ok := req.Context().Value("client_ip").(string); ok {
+		req.Header.Set(sharedMiddleware.ClientIPHeader, clientIP)
 	}
 
-	return proxy
+	// API clients and acting dispatchers are limited to the permissions
+	// they were granted, and their credentials go no further than the
+	// gateway
+	if scopes, ok := req.Context().Value("scopes").([]string); ok {
+		req.Header.Set("X-Permissions", strings.Join(scopes, " "))
+		req.Header.Del("Authorization")
+	}
+}
+
+// proxyError handles requests an upstream could not serve. The underlying
+// error is logged by the pool and not passed on to clients.
+func (router *Router) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "GATEWAY_TIMEOUT", "Upstream timed out", "", requestID)
+	case errors.Is(err, proxy.ErrCircuitOpen):
+		w.Header().Set("Retry-After", "5")
+		response.ServiceUnavailable(w, "Service unavailable", "", requestID)
+	default:
+		response.Error(w, http.StatusBadGateway, "BAD_GATEWAY", "Upstream unavailable", "", requestID)
+	}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	response.NotFound(w, "Route not found", r.URL.Path, requestID)
 }
 
 // healthCheck handles health check requests
 func (router *Router) healthCheck(w http.ResponseWriter, r *http.Request) {
 	requestID, _ := r.Context().Value("request_id").(string)
+
+	upstreams := map[string]interface{}{}
+	for _, status := range router.upstreamStatus() {
+		healthy := 0
+		for _, t := range status.Targets {
+			if t.Healthy {
+				healthy++
+			}
+		}
+		upstreams[status.Name] = map[string]interface{}{
+			"circuit_breaker": status.CircuitBreaker,
+			"healthy":         healthy,
+			"total":           len(status.Targets),
+		}
+	}
+
 	response.Success(w, map[string]interface{}{
-		"status":  "healthy",
-		"service": "api-gateway",
+		"status":    "healthy",
+		"service":   "api-gateway",
+		"upstreams": upstreams,
 	}, requestID)
 }
 
-// metricsHandler exposes Prometheus metrics
-func (router *Router) metricsHandler(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement Prometheus metrics
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintln(w, "# Metrics endpoint - Prometheus integration pending")
+// upstreams lists every upstream with its breaker and targets
+func (router *Router) upstreams(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	response.Success(w, router.upstreamStatus(), requestID)
+}
+
+// upstreamStatus returns the state of the current pools, sorted by name
+func (router *Router) upstreamStatus() []proxy.UpstreamStatus {
+	table := router.table.Load()
+	if table == nil {
+		return []proxy.UpstreamStatus{}
+	}
+
+	status := make([]proxy.UpstreamStatus, 0, len(table.pools))
+	for _, pool := range table.pools {
+		status = append(status, pool.Status())
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
 }
@@ -1,7 +1,8 @@
 #!/bin/sh
 set -e
 
-# Create multiple databases
+# Create one database per service. Each service creates its own tables with
+# its embedded migrations.
 psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
     CREATE DATABASE auth;
     CREATE DATABASE "user";
@@ -11,8 +12,11 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
     CREATE DATABASE job;
     CREATE DATABASE matching;
     CREATE DATABASE bidding;
+    CREATE DATABASE backhaul;
+    CREATE DATABASE tracking;
     CREATE DATABASE payment;
     CREATE DATABASE rating;
+    CREATE DATABASE notification;
     CREATE DATABASE compliance;
 
     -- Grant privileges
@@ -24,8 +28,11 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
     GRANT ALL PRIVILEGES ON DATABASE job TO $POSTGRES_USER;
     GRANT ALL PRIVILEGES ON DATABASE matching TO $POSTGRES_USER;
     GRANT ALL PRIVILEGES ON DATABASE bidding TO $POSTGRES_USER;
+    GRANT ALL PRIVILEGES ON DATABASE backhaul TO $POSTGRES_USER;
+    GRANT ALL PRIVILEGES ON DATABASE tracking TO $POSTGRES_USER;
     GRANT ALL PRIVILEGES ON DATABASE payment TO $POSTGRES_USER;
     GRANT ALL PRIVILEGES ON DATABASE rating TO $POSTGRES_USER;
+    GRANT ALL PRIVILEGES ON DATABASE notification TO $POSTGRES_USER;
     GRANT ALL PRIVILEGES ON DATABASE compliance TO $POSTGRES_USER;
 EOSQL
 
@@ -21,6 +21,7 @@ SERVICES=(
   "Analytics Service|http://localhost:8015/health|200"
   "Compliance Service|http://localhost:8016/health|200"
   "Admin Service|http://localhost:8017/api/v1/admin/config|200"
+  "Audit Service|http://localhost:8018/health|200"
   "Frontend|http://localhost:5173/|200"
   "Prometheus|http://localhost:9090/|302"
   "Grafana|http://localhost:3000/|302"
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"truckify/shared/pkg/audit"
 )
 
 type ConfigField struct {
@@ -49,6 +51,9 @@ var (
 	configMutex = make(chan bool, 1)
 )
 
+// auditLog records configuration changes and message decryptions
+var auditLog audit.Recorder
+
 func init() {
 	configMutex <- true
 }
@@ -341,6 +346,7 @@ func saveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	changes := configChanges(config.Sections)
 	for _, section := range config.Sections {
 		for _, field := range section.Fields {
 			if field.Value != "" {
@@ -349,6 +355,7 @@ func saveConfig(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	recordConfigChange(r, audit.ActionConfigSaved, changes)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
@@ -438,6 +445,7 @@ func restoreConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		changes := configChanges(config.Sections)
 		for _, section := range config.Sections {
 			for _, field := range section.Fields {
 				if field.Value != "" {
@@ -445,12 +453,54 @@ func restoreConfig(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		}
+		recordConfigChange(r, audit.ActionConfigRestored, changes)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "restored"})
 }
 
+// configChanges returns the changes saving the sections would make to the
+// environment. Secret values are masked.
+func configChanges(sections []ConfigSection) map[string]audit.Change {
+	before := make(map[string]string)
+	after := make(map[string]string)
+	secret := make(map[string]bool)
+	for _, section := range sections {
+		for _, field := range section.Fields {
+			if field.Value != "" {
+				before[field.Key] = os.Getenv(field.Key)
+				after[field.Key] = field.Value
+				secret[field.Key] = field.Encrypted || field.Type == "password"
+			}
+		}
+	}
+
+	changes, _ := audit.Diff(before, after)
+	for key := range changes {
+		if secret[key] {
+			maskedBefore, _ := json.Marshal(maskValue(before[key]))
+			maskedAfter, _ := json.Marshal(maskValue(after[key]))
+			changes[key] = audit.Change{Before: maskedBefore, After: maskedAfter}
+		}
+	}
+	return changes
+}
+
+// recordConfigChange records a change to the configuration in the audit
+// log. The change is already applied, so a failure is only logged.
+func recordConfigChange(r *http.Request, action string, changes map[string]audit.Change) {
+	err := auditLog.Record(audit.RequestContext(r), audit.Entry{
+		Action:       action,
+		ResourceType: "config",
+		ResourceID:   "system",
+		Changes:      changes,
+	})
+	if err != nil {
+		log.Printf("Failed to record audit entry %s: %v", action, err)
+	}
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	allowedOrigins := getEnvOrDefault("ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:3000")
 	origins := make(map[string]bool)
@@ -553,6 +603,21 @@ func decryptMessage(w http.ResponseWriter, r *http.Request) {
 		plaintext[i] = ciphertext[i] ^ keyBytes[i%len(keyBytes)]
 	}
 
+	// Reading users' messages must leave a trail, so nothing is revealed
+	// unless it is recorded. Messages are identified by a fingerprint of
+	// their ciphertext.
+	fingerprint := sha256.Sum256(ciphertext)
+	err = auditLog.Record(audit.RequestContext(r), audit.Entry{
+		Action:       audit.ActionMessageDecrypted,
+		ResourceType: "message",
+		ResourceID:   hex.EncodeToString(fingerprint[:]),
+	})
+	if err != nil {
+		log.Printf("Failed to record audit entry %s: %v", audit.ActionMessageDecrypted, err)
+		http.Error(w, "Decryption is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"plaintext":   string(plaintext),
@@ -563,8 +628,13 @@ func decryptMessage(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	godotenv.Load()
+	auditLog = audit.NewClient(getEnvOrDefault("AUDIT_SERVICE_URL", "http://localhost:8018"), "admin-service")
 
 	router := mux.NewRouter()
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "admin-service"})
+	}).Methods("GET")
 	router.HandleFunc("/api/v1/admin/config", getConfig).Methods("GET")
 	router.HandleFunc("/api/v1/admin/config", saveConfig).Methods("POST")
 	router.HandleFunc("/api/v1/admin/config/backup", backupConfig).Methods("POST")
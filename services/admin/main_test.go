@@ -2,12 +2,22 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+
+	"truckify/shared/pkg/audit"
 )
 
+func TestMain(m *testing.M) {
+	auditLog = audit.NewMemory("admin-service")
+	os.Exit(m.Run())
+}
+
 func TestGetConfig(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
 	w := httptest.NewRecorder()
@@ -52,6 +62,90 @@ func TestSaveConfig(t *testing.T) {
 	}
 }
 
+func TestSaveConfigAudited(t *testing.T) {
+	recorder := audit.NewMemory("admin-service")
+	auditLog = recorder
+	defer func() { auditLog = audit.NewMemory("admin-service") }()
+
+	t.Setenv("audited_timeout", "30")
+	t.Setenv("audited_api_key", "sk_live_old1234")
+	config := SystemConfig{
+		Sections: []ConfigSection{
+			{
+				Name: "Test",
+				Fields: []ConfigField{
+					{Key: "audited_timeout", Value: "60"},
+					{Key: "audited_api_key", Type: "password", Value: "sk_live_new5678", Encrypted: true},
+					{Key: "audited_unchanged", Value: ""},
+				},
+			},
+		},
+	}
+
+	body, _ := json.Marshal(config)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/config", bytes.NewReader(body))
+	req.Header.Set("X-User-ID", "7f1c1a4e-1f6b-4c1e-9a57-3c2d4e5f6a7b")
+	req.Header.Set("X-User-Type", "admin")
+	w := httptest.NewRecorder()
+
+	saveConfig(w, req)
+
+	entries := recorder.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Action != audit.ActionConfigSaved || e.ActorID == nil || e.ActorID.String() != "7f1c1a4e-1f6b-4c1e-9a57-3c2d4e5f6a7b" {
+		t.Errorf("Unexpected audit entry: %+v", e)
+	}
+	if len(e.Changes) != 2 {
+		t.Errorf("Expected 2 changes, got %v", e.Changes)
+	}
+	if string(e.Changes["audited_timeout"].Before) != `"30"` || string(e.Changes["audited_timeout"].After) != `"60"` {
+		t.Errorf("Unexpected timeout change: %+v", e.Changes["audited_timeout"])
+	}
+	// Secrets are masked
+	if key := e.Changes["audited_api_key"]; bytes.Contains(key.Before, []byte("sk_live")) || bytes.Contains(key.After, []byte("sk_live")) {
+		t.Errorf("Secret recorded in the audit log: %s -> %s", key.Before, key.After)
+	}
+}
+
+func TestDecryptMessageRequiresAudit(t *testing.T) {
+	recorder := audit.NewMemory("admin-service")
+	auditLog = recorder
+	defer func() { auditLog = audit.NewMemory("admin-service") }()
+
+	body, _ := json.Marshal(DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString([]byte("ciphertext")),
+		IV:         base64.StdEncoding.EncodeToString([]byte("iv")),
+		SessionKey: base64.StdEncoding.EncodeToString([]byte("session-key")),
+	})
+	decrypt := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/decrypt", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		decryptMessage(w, req)
+		return w
+	}
+
+	if w := decrypt(); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	entries := recorder.Entries()
+	if len(entries) != 1 || entries[0].Action != audit.ActionMessageDecrypted || len(entries[0].ResourceID) != 64 {
+		t.Errorf("Unexpected audit entries: %+v", entries)
+	}
+
+	// Nothing is decrypted without a trail
+	recorder.Err = errors.New("audit service unavailable")
+	w := decrypt()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("plaintext")) {
+		t.Error("Expected no plaintext in response")
+	}
+}
+
 func TestEncryptDecrypt(t *testing.T) {
 	password := "test-password-123"
 	plaintext := "sensitive data"
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,13 +14,22 @@ import (
 	"truckify/services/analytics/internal/service"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("analytics-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Analytics Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "analytics-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	svc := service.New(
 		config.GetEnv("AUTH_SERVICE_URL", "http://localhost:8001"),
 		config.GetEnv("JOB_SERVICE_URL", "http://localhost:8006"),
@@ -32,10 +42,15 @@ func main() {
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("analytics-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "analytics-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8015")
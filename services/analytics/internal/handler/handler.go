@@ -40,7 +40,7 @@ func (h *Handler) getRequestID(r *http.Request) string {
 
 func (h *Handler) GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	reqID := h.getRequestID(r)
-	stats, err := h.svc.GetDashboardStats()
+	stats, err := h.svc.GetDashboardStats(r.Context())
 	if err != nil {
 		response.InternalServerError(w, "failed to get stats", err.Error(), reqID)
 		return
@@ -50,7 +50,7 @@ func (h *Handler) GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetJobsByStatus(w http.ResponseWriter, r *http.Request) {
 	reqID := h.getRequestID(r)
-	data, err := h.svc.GetJobsByStatus()
+	data, err := h.svc.GetJobsByStatus(r.Context())
 	if err != nil {
 		response.InternalServerError(w, "failed to get data", err.Error(), reqID)
 		return
@@ -64,7 +64,7 @@ func (h *Handler) GetJobsByDay(w http.ResponseWriter, r *http.Request) {
 	if days <= 0 {
 		days = 7
 	}
-	data, err := h.svc.GetJobsByDay(days)
+	data, err := h.svc.GetJobsByDay(r.Context(), days)
 	if err != nil {
 		response.InternalServerError(w, "failed to get data", err.Error(), reqID)
 		return
@@ -78,7 +78,7 @@ func (h *Handler) GetRevenueByDay(w http.ResponseWriter, r *http.Request) {
 	if days <= 0 {
 		days = 7
 	}
-	data, err := h.svc.GetRevenueByDay(days)
+	data, err := h.svc.GetRevenueByDay(r.Context(), days)
 	if err != nil {
 		response.InternalServerError(w, "failed to get data", err.Error(), reqID)
 		return
@@ -92,7 +92,7 @@ func (h *Handler) GetTopRoutes(w http.ResponseWriter, r *http.Request) {
 	if limit <= 0 {
 		limit = 5
 	}
-	data, err := h.svc.GetTopRoutes(limit)
+	data, err := h.svc.GetTopRoutes(r.Context(), limit)
 	if err != nil {
 		response.InternalServerError(w, "failed to get data", err.Error(), reqID)
 		return
@@ -113,7 +113,7 @@ func (h *Handler) ForecastDemand(w http.ResponseWriter, r *http.Request) {
 	if days <= 0 || days > 14 {
 		days = 7
 	}
-	data, err := h.svc.ForecastDemand(days)
+	data, err := h.svc.ForecastDemand(r.Context(), days)
 	if err != nil {
 		response.InternalServerError(w, "failed to forecast demand", err.Error(), reqID)
 		return
@@ -124,7 +124,7 @@ func (h *Handler) ForecastDemand(w http.ResponseWriter, r *http.Request) {
 // GetDemandHeatmap returns demand intensity by region
 func (h *Handler) GetDemandHeatmap(w http.ResponseWriter, r *http.Request) {
 	reqID := h.getRequestID(r)
-	data, err := h.svc.GetDemandHeatmap()
+	data, err := h.svc.GetDemandHeatmap(r.Context())
 	if err != nil {
 		response.InternalServerError(w, "failed to get heatmap", err.Error(), reqID)
 		return
@@ -147,7 +147,7 @@ func (h *Handler) GetPricingRecommendation(w http.ResponseWriter, r *http.Reques
 		basePrice = 1000 // Default base price
 	}
 
-	data, err := h.svc.GetPricingRecommendation(origin, destination, basePrice)
+	data, err := h.svc.GetPricingRecommendation(r.Context(), origin, destination, basePrice)
 	if err != nil {
 		response.InternalServerError(w, "failed to get pricing", err.Error(), reqID)
 		return
@@ -160,7 +160,7 @@ func (h *Handler) GetMarketConditions(w http.ResponseWriter, r *http.Request) {
 	reqID := h.getRequestID(r)
 	region := r.URL.Query().Get("region")
 
-	data, err := h.svc.GetMarketConditions(region)
+	data, err := h.svc.GetMarketConditions(r.Context(), region)
 	if err != nil {
 		response.InternalServerError(w, "failed to get conditions", err.Error(), reqID)
 		return
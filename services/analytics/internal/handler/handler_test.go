@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/gorilla/mux"
+	"truckify/shared/pkg/openapi"
 )
 
 func TestHealth(t *testing.T) {
@@ -56,3 +59,12 @@ func TestGetPricingRecommendation_MissingDestination(t *testing.T) {
 		t.Errorf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	New(nil).RegisterRoutes(router)
+
+	if err := openapi.Check(router, Routes); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"truckify/services/analytics/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+func intQuery(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "integer"}}
+}
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"GET /analytics/dashboard":        {Summary: "Platform totals", Response: model.DashboardStats{}},
+	"GET /analytics/jobs/status":      {Summary: "Jobs by status", Response: []model.JobsByStatus{}},
+	"GET /analytics/jobs/daily":       {Summary: "Jobs posted per day", Response: []model.JobsByDay{}, Query: []openapi.Parameter{intQuery("days", "Days back, 7 by default")}},
+	"GET /analytics/revenue/daily":    {Summary: "Revenue per day", Response: []model.RevenueByDay{}, Query: []openapi.Parameter{intQuery("days", "Days back, 7 by default")}},
+	"GET /analytics/routes/top":       {Summary: "Busiest routes", Response: []model.TopRoute{}, Query: []openapi.Parameter{intQuery("limit", "5 by default")}},
+	"GET /analytics/forecast/demand":  {Summary: "Forecast job demand", Response: []model.DemandForecast{}, Query: []openapi.Parameter{intQuery("days", "Days ahead, at most 14, 7 by default")}},
+	"GET /analytics/forecast/heatmap": {Summary: "Demand by region", Response: []model.DemandHeatmap{}},
+	"GET /analytics/pricing/recommend": {
+		Summary:  "Recommend a price for a route",
+		Response: model.PricingRecommendation{},
+		Query: []openapi.Parameter{
+			{Name: "origin", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "destination", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "base_price", In: "query", Schema: &openapi.Schema{Type: "number"}},
+		},
+	},
+	"GET /analytics/market/conditions": {
+		Summary:  "Supply and demand in a region",
+		Response: model.MarketConditions{},
+		Query:    []openapi.Parameter{{Name: "region", In: "query", Schema: &openapi.Schema{Type: "string"}}},
+	},
+	"GET /health": {Summary: "Health check", Response: map[string]string{}},
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"math"
 	"sort"
 	"time"
@@ -9,7 +10,7 @@ import (
 )
 
 // ForecastDemand predicts job demand for routes over the next N days
-func (s *Service) ForecastDemand(days int) ([]model.DemandForecast, error) {
+func (s *Service) ForecastDemand(ctx context.Context, days int) ([]model.DemandForecast, error) {
 	// Get historical job data
 	var jobs []struct {
 		Pickup    struct{ City string `json:"city"` } `json:"pickup"`
@@ -17,7 +18,7 @@ func (s *Service) ForecastDemand(days int) ([]model.DemandForecast, error) {
 		CreatedAt time.Time `json:"created_at"`
 		Status    string    `json:"status"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	// Aggregate historical data by route and day of week
 	routeHistory := make(map[string]map[int][]int) // route -> dayOfWeek -> counts per week
@@ -128,7 +129,7 @@ func (s *Service) ForecastDemand(days int) ([]model.DemandForecast, error) {
 }
 
 // GetDemandHeatmap returns demand intensity by region
-func (s *Service) GetDemandHeatmap() ([]model.DemandHeatmap, error) {
+func (s *Service) GetDemandHeatmap(ctx context.Context) ([]model.DemandHeatmap, error) {
 	var jobs []struct {
 		Pickup struct {
 			City      string  `json:"city"`
@@ -138,7 +139,7 @@ func (s *Service) GetDemandHeatmap() ([]model.DemandHeatmap, error) {
 		} `json:"pickup"`
 		Price float64 `json:"price"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	// Aggregate by city
 	cityData := make(map[string]*model.DemandHeatmap)
@@ -186,11 +187,11 @@ func (s *Service) GetDemandHeatmap() ([]model.DemandHeatmap, error) {
 }
 
 // GetPricingRecommendation calculates dynamic pricing for a route
-func (s *Service) GetPricingRecommendation(origin, destination string, basePrice float64) (*model.PricingRecommendation, error) {
+func (s *Service) GetPricingRecommendation(ctx context.Context, origin, destination string, basePrice float64) (*model.PricingRecommendation, error) {
 	route := origin + "→" + destination
 
 	// Get market conditions
-	conditions, _ := s.GetMarketConditions(origin)
+	conditions, _ := s.GetMarketConditions(ctx, origin)
 
 	// Get historical prices for this route
 	var jobs []struct {
@@ -199,7 +200,7 @@ func (s *Service) GetPricingRecommendation(origin, destination string, basePrice
 		Price    float64   `json:"price"`
 		Status   string    `json:"status"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	var routePrices []float64
 	for _, j := range jobs {
@@ -338,13 +339,13 @@ func (s *Service) GetPricingRecommendation(origin, destination string, basePrice
 }
 
 // GetMarketConditions returns current market state for a region
-func (s *Service) GetMarketConditions(region string) (*model.MarketConditions, error) {
+func (s *Service) GetMarketConditions(ctx context.Context, region string) (*model.MarketConditions, error) {
 	// Get available drivers
 	var drivers []struct {
 		IsAvailable bool   `json:"is_available"`
 		City        string `json:"city"`
 	}
-	s.fetchJSON(s.driverURL+"/drivers", &drivers)
+	s.fetchJSON(ctx, s.driverURL+"/drivers", &drivers)
 
 	availableDrivers := 0
 	for _, d := range drivers {
@@ -358,7 +359,7 @@ func (s *Service) GetMarketConditions(region string) (*model.MarketConditions, e
 		Status string `json:"status"`
 		Pickup struct{ City string `json:"city"` } `json:"pickup"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	pendingJobs := 0
 	for _, j := range jobs {
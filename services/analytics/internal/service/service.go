@@ -1,12 +1,15 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"truckify/services/analytics/internal/model"
+	"truckify/shared/pkg/tracing"
 )
 
 type Service struct {
@@ -16,6 +19,7 @@ type Service struct {
 	paymentURL string
 	fleetURL   string
 	ratingURL  string
+	client     *http.Client
 }
 
 func New(authURL, jobURL, driverURL, paymentURL, fleetURL, ratingURL string) *Service {
@@ -26,16 +30,24 @@ func New(authURL, jobURL, driverURL, paymentURL, fleetURL, ratingURL string) *Se
 		paymentURL: paymentURL,
 		fleetURL:   fleetURL,
 		ratingURL:  ratingURL,
+		client:     tracing.NewClient(10 * time.Second),
 	}
 }
 
 type apiResponse struct {
-	Success bool            `json:"success"`
-	Data    json.RawMessage `json:"data"`
+	Success  bool            `json:"success"`
+	Data     json.RawMessage `json:"data"`
+	Metadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"metadata"`
 }
 
-func (s *Service) fetchJSON(url string, result interface{}) error {
-	resp, err := http.Get(url)
+func (s *Service) fetchJSON(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -51,7 +63,58 @@ func (s *Service) fetchJSON(url string, result interface{}) error {
 	return nil
 }
 
-func (s *Service) GetDashboardStats() (*model.DashboardStats, error) {
+// fetchAll reads every page of a list endpoint into result, a pointer to
+// a slice
+func (s *Service) fetchAll(ctx context.Context, listURL string, result interface{}) error {
+	var items []json.RawMessage
+	cursor := ""
+	for {
+		u, err := url.Parse(listURL)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		q.Set("limit", "100")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var apiResp apiResponse
+		err = json.NewDecoder(resp.Body).Decode(&apiResp)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		var page []json.RawMessage
+		if apiResp.Data != nil {
+			if err := json.Unmarshal(apiResp.Data, &page); err != nil {
+				return err
+			}
+		}
+		items = append(items, page...)
+		if cursor = apiResp.Metadata.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+func (s *Service) GetDashboardStats(ctx context.Context) (*model.DashboardStats, error) {
 	stats := &model.DashboardStats{}
 
 	// Get job stats
@@ -59,7 +122,7 @@ func (s *Service) GetDashboardStats() (*model.DashboardStats, error) {
 		Status string  `json:"status"`
 		Price  float64 `json:"price"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 	
 	stats.TotalJobs = len(jobs)
 	var totalRevenue float64
@@ -82,7 +145,7 @@ func (s *Service) GetDashboardStats() (*model.DashboardStats, error) {
 		UserType    string `json:"user_type"`
 		IsAvailable bool   `json:"is_available"`
 	}
-	s.fetchJSON(s.authURL+"/admin/users", &users)
+	s.fetchAll(ctx, s.authURL+"/admin/users", &users)
 	
 	for _, u := range users {
 		switch u.UserType {
@@ -96,11 +159,11 @@ func (s *Service) GetDashboardStats() (*model.DashboardStats, error) {
 	return stats, nil
 }
 
-func (s *Service) GetJobsByStatus() ([]model.JobsByStatus, error) {
+func (s *Service) GetJobsByStatus(ctx context.Context) ([]model.JobsByStatus, error) {
 	var jobs []struct {
 		Status string `json:"status"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	counts := make(map[string]int)
 	for _, j := range jobs {
@@ -114,11 +177,11 @@ func (s *Service) GetJobsByStatus() ([]model.JobsByStatus, error) {
 	return result, nil
 }
 
-func (s *Service) GetJobsByDay(days int) ([]model.JobsByDay, error) {
+func (s *Service) GetJobsByDay(ctx context.Context, days int) ([]model.JobsByDay, error) {
 	var jobs []struct {
 		CreatedAt time.Time `json:"created_at"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	counts := make(map[string]int)
 	cutoff := time.Now().AddDate(0, 0, -days)
@@ -138,13 +201,13 @@ func (s *Service) GetJobsByDay(days int) ([]model.JobsByDay, error) {
 	return result, nil
 }
 
-func (s *Service) GetRevenueByDay(days int) ([]model.RevenueByDay, error) {
+func (s *Service) GetRevenueByDay(ctx context.Context, days int) ([]model.RevenueByDay, error) {
 	var jobs []struct {
 		Status    string    `json:"status"`
 		Price     float64   `json:"price"`
 		CreatedAt time.Time `json:"created_at"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	revenue := make(map[string]float64)
 	cutoff := time.Now().AddDate(0, 0, -days)
@@ -164,12 +227,12 @@ func (s *Service) GetRevenueByDay(days int) ([]model.RevenueByDay, error) {
 	return result, nil
 }
 
-func (s *Service) GetTopRoutes(limit int) ([]model.TopRoute, error) {
+func (s *Service) GetTopRoutes(ctx context.Context, limit int) ([]model.TopRoute, error) {
 	var jobs []struct {
 		Pickup   struct{ City string `json:"city"` } `json:"pickup"`
 		Delivery struct{ City string `json:"city"` } `json:"delivery"`
 	}
-	s.fetchJSON(s.jobURL+"/jobs/all", &jobs)
+	s.fetchJSON(ctx, s.jobURL+"/jobs/all", &jobs)
 
 	routes := make(map[string]int)
 	for _, j := range jobs {
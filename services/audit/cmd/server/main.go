@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"truckify/services/audit/internal/handler"
+	"truckify/services/audit/internal/repository"
+	"truckify/services/audit/internal/service"
+	"truckify/services/audit/migrations"
+	"truckify/shared/pkg/config"
+	"truckify/shared/pkg/database"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
+	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
+)
+
+func main() {
+	// Initialize logger
+	logLevel := config.GetEnv("LOG_LEVEL", "info")
+	log := logger.New("audit-service", logLevel)
+	log.Info("Starting Audit Service")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "audit-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Initialize database connection
+	db, err := database.NewPostgresDB(database.PostgresConfig{
+		Host:     config.GetEnv("DB_HOST", "localhost"),
+		Port:     config.GetEnvInt("DB_PORT", 5432),
+		User:     config.GetEnv("DB_USER", "truckify"),
+		Password: config.GetEnv("DB_PASSWORD", "truckify_password"),
+		Database: config.GetEnv("DB_NAME", "audit"),
+		SSLMode:  config.GetEnv("DB_SSLMODE", "disable"),
+	})
+	if err != nil {
+		log.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.ClosePostgresDB(db)
+	log.Info("Connected to PostgreSQL")
+
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
+	// Initialize repository
+	repo := repository.New(db)
+
+	// Initialize service
+	svc := service.New(repo, log)
+
+	// `verify` walks the hash chain and exits non-zero if it is broken
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		result, err := svc.Verify(context.Background())
+		if err != nil {
+			log.Fatal("Verification failed", "error", err)
+		}
+		if !result.Valid {
+			fmt.Printf("Chain broken at entry %d: %s\n", result.Break.Seq, result.Break.Reason)
+			fmt.Printf("%d entries verified before the break, head %s\n", result.Entries, result.Head)
+			os.Exit(1)
+		}
+		fmt.Printf("%d entries verified, head %s\n", result.Entries, result.Head)
+		return
+	}
+
+	// Initialize handler
+	h := handler.New(svc, log)
+
+	// Setup router
+	router := mux.NewRouter()
+
+	// Apply middlewares
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("audit-service"))
+	router.Use(middleware.Recovery(log))
+	router.Use(middleware.Logger(log))
+	router.Use(middleware.SecurityHeaders)
+
+	// Register routes
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "audit-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
+
+	// Wrap router with CORS
+	corsHandler := middleware.CORS([]string{"*"})(router)
+
+	// Create HTTP server
+	port := config.GetEnv("PORT", "8018")
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%s", port),
+		Handler:      corsHandler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		log.Info("Audit Service listening", "port", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", "error", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down Audit Service...")
+
+	// Graceful shutdown with 10 second timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Server forced to shutdown", "error", err)
+	}
+
+	log.Info("Audit Service stopped")
+}
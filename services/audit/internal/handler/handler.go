@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"truckify/services/audit/internal/model"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/response"
+	"truckify/shared/pkg/validator"
+)
+
+// ServiceInterface defines the interface for audit service operations
+type ServiceInterface interface {
+	Record(ctx context.Context, req *audit.Entry) (*model.Entry, error)
+	ListEntries(ctx context.Context, page pagination.Page) ([]model.Entry, string, error)
+}
+
+// Handler handles HTTP requests for the audit log
+type Handler struct {
+	service   ServiceInterface
+	validator *validator.Validator
+	authz     *authz.Authorizer
+	logger    *logger.Logger
+}
+
+// New creates a new handler instance
+func New(service ServiceInterface, logger *logger.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator.New(),
+		authz:     authz.New(authz.DefaultPolicy()),
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers all audit routes. Services record entries over
+// the internal network; the API gateway only exposes reading them.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/audit/entries", h.RecordEntry).Methods(http.MethodPost)
+	router.HandleFunc("/audit/entries", h.authz.RequireUnscoped(authz.AuditRead, h.ListEntries)).Methods(http.MethodGet)
+	router.HandleFunc("/health", h.Health).Methods(http.MethodGet)
+}
+
+// RecordEntry appends an entry to the audit log
+func (h *Handler) RecordEntry(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req audit.Entry
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	entry, err := h.service.Record(r.Context(), &req)
+	if err != nil {
+		h.logger.Error("Failed to record audit entry", "action", req.Action, "error", err)
+		response.InternalServerError(w, "Failed to record audit entry", "", requestID)
+		return
+	}
+
+	response.Created(w, entry, requestID)
+}
+
+// ListEntries lists audit log entries, newest first unless sorted otherwise
+func (h *Handler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	page, err := pagination.Parse(r.URL.Query(), model.EntryListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), requestID)
+		return
+	}
+
+	entries, next, err := h.service.ListEntries(r.Context(), page)
+	if err != nil {
+		h.logger.Error("Failed to list audit entries", "error", err)
+		response.InternalServerError(w, "Failed to list audit entries", "", requestID)
+		return
+	}
+
+	response.List(w, map[string]interface{}{
+		"entries": entries,
+	}, next, requestID)
+}
+
+// Health handles health check requests
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	response.Success(w, map[string]interface{}{
+		"status":  "healthy",
+		"service": "audit-service",
+	}, requestID)
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/audit/internal/model"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/openapi"
+)
+
+// entryList wraps the entries returned by the list endpoint
+type entryList struct {
+	Entries []model.Entry `json:"entries"`
+}
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /audit/entries": {Summary: "Record an audit entry", Description: "Called by services over the internal network; not exposed by the API gateway.", Request: audit.Entry{}, Response: model.Entry{}, Status: http.StatusCreated},
+	"GET /audit/entries":  {Summary: "List audit entries", Description: "Admins only. Filter by service, action, resource_type, resource_id, actor_id, on_behalf_of, ip_address, request_id or occurred_at.", Response: entryList{}, List: &model.EntryListSpec},
+	"GET /health":         {Summary: "Health check", Response: map[string]interface{}{}},
+}
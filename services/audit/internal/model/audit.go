@@ -0,0 +1,136 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
+)
+
+// GenesisHash is the previous hash of the first entry in the log
+var GenesisHash = strings.Repeat("0", 64)
+
+// Entry is an entry in the audit log. Entries are numbered from 1 without
+// gaps, and each one's hash covers its contents and the previous entry's
+// hash, so editing, removing or reordering entries breaks the chain.
+type Entry struct {
+	Seq          int64      `json:"seq"`
+	ID           uuid.UUID  `json:"id"`
+	Service      string     `json:"service"`
+	Action       string     `json:"action"`
+	ResourceType string     `json:"resource_type"`
+	ResourceID   string     `json:"resource_id"`
+	ActorID      *uuid.UUID `json:"actor_id,omitempty"`
+	ActorType    string     `json:"actor_type,omitempty"`
+	OnBehalfOf   *uuid.UUID `json:"on_behalf_of,omitempty"`
+	// Changes is stored exactly as it was hashed
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// chained is what an entry's hash covers, in a fixed order
+type chained struct {
+	Seq          int64           `json:"seq"`
+	ID           uuid.UUID       `json:"id"`
+	Service      string          `json:"service"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	ActorID      *uuid.UUID      `json:"actor_id"`
+	ActorType    string          `json:"actor_type"`
+	OnBehalfOf   *uuid.UUID      `json:"on_behalf_of"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+	IPAddress    string          `json:"ip_address"`
+	RequestID    string          `json:"request_id"`
+	OccurredAt   string          `json:"occurred_at"`
+	RecordedAt   string          `json:"recorded_at"`
+	PrevHash     string          `json:"prev_hash"`
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's contents and previous
+// hash. Times are hashed to the microsecond, as PostgreSQL stores them.
+func (e *Entry) ComputeHash() string {
+	data, _ := json.Marshal(chained{
+		Seq:          e.Seq,
+		ID:           e.ID,
+		Service:      e.Service,
+		Action:       e.Action,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		ActorID:      e.ActorID,
+		ActorType:    e.ActorType,
+		OnBehalfOf:   e.OnBehalfOf,
+		Changes:      e.Changes,
+		IPAddress:    e.IPAddress,
+		RequestID:    e.RequestID,
+		OccurredAt:   hashTime(e.OccurredAt),
+		RecordedAt:   hashTime(e.RecordedAt),
+		PrevHash:     e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain places the entry after the entry numbered prevSeq with hash
+// prevHash, and seals it
+func (e *Entry) Chain(prevSeq int64, prevHash string) {
+	e.Seq = prevSeq + 1
+	e.PrevHash = prevHash
+	e.OccurredAt = e.OccurredAt.UTC().Truncate(time.Microsecond)
+	e.RecordedAt = e.RecordedAt.UTC().Truncate(time.Microsecond)
+	e.Hash = e.ComputeHash()
+}
+
+func hashTime(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+// EntryListSpec is what the audit log can be filtered and sorted by
+var EntryListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"seq":           {Column: "seq", Kind: pagination.Number, Sortable: true},
+		"service":       {Column: "service", Kind: pagination.String},
+		"action":        {Column: "action", Kind: pagination.String},
+		"resource_type": {Column: "resource_type", Kind: pagination.String},
+		"resource_id":   {Column: "resource_id", Kind: pagination.String},
+		"actor_id":      {Column: "actor_id", Kind: pagination.ID},
+		"on_behalf_of":  {Column: "on_behalf_of", Kind: pagination.ID},
+		"ip_address":    {Column: "ip_address", Kind: pagination.String},
+		"request_id":    {Column: "request_id", Kind: pagination.String},
+		"occurred_at":   {Column: "occurred_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-seq",
+}
+
+// SortKey returns the value of an EntryListSpec sort field and the entry's ID
+func (e Entry) SortKey(field string) (interface{}, uuid.UUID) {
+	if field == "seq" {
+		return float64(e.Seq), e.ID
+	}
+	return e.OccurredAt, e.ID
+}
+
+// ChainBreak is where and why the chain stopped verifying
+type ChainBreak struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// Verification is the result of walking the chain
+type Verification struct {
+	Valid   bool  `json:"valid"`
+	Entries int64 `json:"entries"`
+	// Head is the hash of the last entry checked. Keeping it outside the
+	// database shows later whether the log was rewritten from the start.
+	Head  string      `json:"head"`
+	Break *ChainBreak `json:"break,omitempty"`
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"truckify/services/audit/internal/model"
+	"truckify/shared/pkg/pagination"
+)
+
+// entryColumns are read into an entry by scanEntry
+const entryColumns = `seq, id, service, action, resource_type, resource_id, actor_id, COALESCE(actor_type, ''),
+	on_behalf_of, changes, COALESCE(ip_address, ''), COALESCE(request_id, ''), occurred_at, recorded_at, prev_hash, hash`
+
+// Repository handles audit log data operations
+type Repository struct {
+	db *sql.DB
+}
+
+// New creates a new repository instance
+func New(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Append adds the entry to the end of the chain, setting its number and
+// hashes. Appends take turns on a table lock, so each one chains onto the
+// last committed entry; reads are not blocked.
+func (r *Repository) Append(ctx context.Context, e *model.Entry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE audit_entries IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	prevSeq, prevHash := int64(0), model.GenesisHash
+	err = tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_entries ORDER BY seq DESC LIMIT 1`).Scan(&prevSeq, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	e.Chain(prevSeq, prevHash)
+
+	var changes interface{}
+	if len(e.Changes) > 0 {
+		changes = string(e.Changes)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_entries (seq, id, service, action, resource_type, resource_id, actor_id, actor_type,
+			on_behalf_of, changes, ip_address, request_id, occurred_at, recorded_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, NULLIF($11, ''), NULLIF($12, ''), $13, $14, $15, $16)`,
+		e.Seq, e.ID, e.Service, e.Action, e.ResourceType, e.ResourceID, e.ActorID, e.ActorType,
+		e.OnBehalfOf, changes, e.IPAddress, e.RequestID, e.OccurredAt, e.RecordedAt, e.PrevHash, e.Hash)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListEntries gets a page of the audit log and the cursor of the next page
+func (r *Repository) ListEntries(ctx context.Context, page pagination.Page) ([]model.Entry, string, error) {
+	query, args := page.Apply(`SELECT `+entryColumns+` FROM audit_entries WHERE TRUE`, nil)
+
+	entries, err := r.queryEntries(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, next := pagination.Cut(page, entries, model.Entry.SortKey)
+	return entries, next, nil
+}
+
+// EntriesAfter gets up to limit entries numbered after seq, in order
+func (r *Repository) EntriesAfter(ctx context.Context, seq int64, limit int) ([]model.Entry, error) {
+	return r.queryEntries(ctx, `SELECT `+entryColumns+` FROM audit_entries WHERE seq > $1 ORDER BY seq LIMIT $2`, seq, limit)
+}
+
+func (r *Repository) queryEntries(ctx context.Context, query string, args ...interface{}) ([]model.Entry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.Entry
+	for rows.Next() {
+		var e model.Entry
+		var changes []byte
+		err := rows.Scan(&e.Seq, &e.ID, &e.Service, &e.Action, &e.ResourceType, &e.ResourceID, &e.ActorID, &e.ActorType,
+			&e.OnBehalfOf, &changes, &e.IPAddress, &e.RequestID, &e.OccurredAt, &e.RecordedAt, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if changes != nil {
+			e.Changes = changes
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
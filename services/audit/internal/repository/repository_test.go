@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/services/audit/internal/model"
+)
+
+func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *Repository) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	repo := New(db)
+	return db, mock, repo
+}
+
+func TestAppend(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	prevHash := "ab" + model.GenesisHash[2:]
+	e := &model.Entry{
+		ID:           uuid.New(),
+		Service:      "user-service",
+		Action:       "document.verified",
+		ResourceType: "document",
+		ResourceID:   uuid.NewString(),
+		Changes:      json.RawMessage(`{"status":{"before":"pending","after":"verified"}}`),
+		OccurredAt:   time.Now(),
+		RecordedAt:   time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("LOCK TABLE audit_entries IN EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT seq, hash FROM audit_entries ORDER BY seq DESC LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "hash"}).AddRow(41, prevHash))
+	mock.ExpectExec("INSERT INTO audit_entries").
+		WithArgs(int64(42), e.ID, e.Service, e.Action, e.ResourceType, e.ResourceID, nil, "",
+			nil, string(e.Changes), "", "", sqlmock.AnyArg(), sqlmock.AnyArg(), prevHash, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Append(ctx, e))
+	assert.Equal(t, int64(42), e.Seq)
+	assert.Equal(t, prevHash, e.PrevHash)
+	assert.Equal(t, e.ComputeHash(), e.Hash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppend_FirstEntry(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("LOCK TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT seq, hash FROM audit_entries").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO audit_entries").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	e := &model.Entry{ID: uuid.New(), Action: "config.saved", ResourceType: "config", OccurredAt: time.Now(), RecordedAt: time.Now()}
+	require.NoError(t, repo.Append(context.Background(), e))
+	assert.Equal(t, int64(1), e.Seq)
+	assert.Equal(t, model.GenesisHash, e.PrevHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"truckify/services/audit/internal/model"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
+)
+
+// verifyBatch is how many entries Verify reads at a time
+const verifyBatch = 1000
+
+// Lengths of the request details stored with an entry. They come from the
+// request, so longer values are cut short rather than refused.
+const (
+	maxIPAddressLength = 45
+	maxRequestIDLength = 100
+)
+
+// RepositoryInterface defines the audit log store the service uses
+type RepositoryInterface interface {
+	Append(ctx context.Context, e *model.Entry) error
+	ListEntries(ctx context.Context, page pagination.Page) ([]model.Entry, string, error)
+	EntriesAfter(ctx context.Context, seq int64, limit int) ([]model.Entry, error)
+}
+
+// Service handles audit log business logic
+type Service struct {
+	repo   RepositoryInterface
+	logger *logger.Logger
+}
+
+// New creates a new service instance
+func New(repo RepositoryInterface, log *logger.Logger) *Service {
+	return &Service{repo: repo, logger: log}
+}
+
+// Record appends an entry sent by a service to the log
+func (s *Service) Record(ctx context.Context, req *audit.Entry) (*model.Entry, error) {
+	e := &model.Entry{
+		ID:           uuid.New(),
+		Service:      req.Service,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		ActorID:      req.ActorID,
+		ActorType:    req.ActorType,
+		OnBehalfOf:   req.OnBehalfOf,
+		IPAddress:    truncate(req.IPAddress, maxIPAddressLength),
+		RequestID:    truncate(req.RequestID, maxRequestIDLength),
+		OccurredAt:   req.OccurredAt,
+		RecordedAt:   time.Now(),
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = e.RecordedAt
+	}
+	if len(req.Changes) > 0 {
+		changes, err := json.Marshal(req.Changes)
+		if err != nil {
+			return nil, err
+		}
+		e.Changes = changes
+	}
+
+	if err := s.repo.Append(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// truncate cuts s down to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// ListEntries gets a page of the audit log and the cursor of the next page
+func (s *Service) ListEntries(ctx context.Context, page pagination.Page) ([]model.Entry, string, error) {
+	return s.repo.ListEntries(ctx, page)
+}
+
+// Verify walks the chain from the first entry, checking that entries are
+// numbered without gaps, that each names the hash of the one before and
+// that each hash matches its contents. It stops at the first break.
+func (s *Service) Verify(ctx context.Context) (*model.Verification, error) {
+	result := &model.Verification{Valid: true, Head: model.GenesisHash}
+	for {
+		entries, err := s.repo.EntriesAfter(ctx, result.Entries, verifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range entries {
+			e := &entries[i]
+			switch {
+			case e.Seq != result.Entries+1:
+				result.Break = &model.ChainBreak{Seq: result.Entries + 1, Reason: fmt.Sprintf("entry is missing, the next is %d", e.Seq)}
+			case e.PrevHash != result.Head:
+				result.Break = &model.ChainBreak{Seq: e.Seq, Reason: "previous hash does not match the entry before"}
+			case e.ComputeHash() != e.Hash:
+				result.Break = &model.ChainBreak{Seq: e.Seq, Reason: "hash does not match the entry's contents"}
+			}
+			if result.Break != nil {
+				result.Valid = false
+				s.logger.Error("Audit chain broken", "seq", result.Break.Seq, "reason", result.Break.Reason)
+				return result, nil
+			}
+			result.Entries = e.Seq
+			result.Head = e.Hash
+		}
+
+		if len(entries) < verifyBatch {
+			return result, nil
+		}
+	}
+}
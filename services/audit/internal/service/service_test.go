@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/services/audit/internal/model"
+	"truckify/services/audit/internal/service"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
+)
+
+// memoryRepository chains entries like the PostgreSQL repository, and lets
+// tests tamper with them
+type memoryRepository struct {
+	entries []model.Entry
+}
+
+func (m *memoryRepository) Append(ctx context.Context, e *model.Entry) error {
+	prevSeq, prevHash := int64(0), model.GenesisHash
+	if n := len(m.entries); n > 0 {
+		prevSeq, prevHash = m.entries[n-1].Seq, m.entries[n-1].Hash
+	}
+	e.Chain(prevSeq, prevHash)
+	m.entries = append(m.entries, *e)
+	return nil
+}
+
+func (m *memoryRepository) ListEntries(ctx context.Context, page pagination.Page) ([]model.Entry, string, error) {
+	return m.entries, "", nil
+}
+
+func (m *memoryRepository) EntriesAfter(ctx context.Context, seq int64, limit int) ([]model.Entry, error) {
+	var out []model.Entry
+	for _, e := range m.entries {
+		if e.Seq > seq && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func setupTestService(t *testing.T) (*service.Service, *memoryRepository) {
+	repo := &memoryRepository{}
+	svc := service.New(repo, logger.New("test", "error"))
+
+	adminID, dispatcherID, shipperID := uuid.New(), uuid.New(), uuid.New()
+	ctx := context.Background()
+	_, err := svc.Record(ctx, &audit.Entry{Service: "auth-service", Action: audit.ActionUserStatusChanged, ResourceType: "user",
+		ResourceID: uuid.NewString(), ActorID: &adminID, ActorType: "admin", IPAddress: "203.0.113.7", RequestID: "req-1",
+		Changes: map[string]audit.Change{"status": {Before: json.RawMessage(`"active"`), After: json.RawMessage(`"suspended"`)}}})
+	require.NoError(t, err)
+	_, err = svc.Record(ctx, &audit.Entry{Service: "job-service", Action: "job.updated", ResourceType: "job",
+		ResourceID: uuid.NewString(), ActorID: &dispatcherID, ActorType: "dispatcher", OnBehalfOf: &shipperID})
+	require.NoError(t, err)
+	_, err = svc.Record(ctx, &audit.Entry{Service: "admin-service", Action: audit.ActionConfigSaved, ResourceType: "config",
+		OccurredAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	return svc, repo
+}
+
+func TestRecord_ChainsEntries(t *testing.T) {
+	_, repo := setupTestService(t)
+
+	require.Len(t, repo.entries, 3)
+	assert.Equal(t, model.GenesisHash, repo.entries[0].PrevHash)
+	for i, e := range repo.entries {
+		assert.Equal(t, int64(i+1), e.Seq)
+		assert.Len(t, e.Hash, 64)
+		if i > 0 {
+			assert.Equal(t, repo.entries[i-1].Hash, e.PrevHash)
+		}
+	}
+	assert.JSONEq(t, `{"status":{"before":"active","after":"suspended"}}`, string(repo.entries[0].Changes))
+	assert.Nil(t, repo.entries[1].Changes)
+}
+
+func TestRecord_TruncatesRequestDetails(t *testing.T) {
+	repo := &memoryRepository{}
+	svc := service.New(repo, logger.New("test", "error"))
+
+	e, err := svc.Record(context.Background(), &audit.Entry{Service: "auth-service", Action: audit.ActionUserStatusChanged,
+		ResourceType: "user", IPAddress: strings.Repeat("f", 60), RequestID: strings.Repeat("é", 150)})
+	require.NoError(t, err)
+	assert.Len(t, e.IPAddress, 45)
+	assert.Equal(t, 100, utf8.RuneCountInString(e.RequestID))
+	assert.Equal(t, e.ComputeHash(), e.Hash)
+}
+
+func TestVerify(t *testing.T) {
+	svc, repo := setupTestService(t)
+	ctx := context.Background()
+
+	result, err := svc.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Entries)
+	assert.Equal(t, repo.entries[2].Hash, result.Head)
+
+	// Rewriting what an entry says breaks its hash
+	repo.entries[0].Changes = json.RawMessage(`{"status":{"before":"active","after":"active"}}`)
+	result, err = svc.Verify(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, &model.ChainBreak{Seq: 1, Reason: "hash does not match the entry's contents"}, result.Break)
+	assert.Equal(t, int64(0), result.Entries)
+}
+
+func TestVerify_ResealedEntry(t *testing.T) {
+	svc, repo := setupTestService(t)
+
+	// Resealing an edited entry leaves the next one pointing at the old hash
+	repo.entries[1].IPAddress = "198.51.100.1"
+	repo.entries[1].Hash = repo.entries[1].ComputeHash()
+
+	result, err := svc.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.Break.Seq)
+	assert.Equal(t, "previous hash does not match the entry before", result.Break.Reason)
+	assert.Equal(t, repo.entries[1].Hash, result.Head)
+}
+
+func TestVerify_RemovedEntry(t *testing.T) {
+	svc, repo := setupTestService(t)
+
+	repo.entries = append(repo.entries[:1], repo.entries[2:]...)
+
+	result, err := svc.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, &model.ChainBreak{Seq: 2, Reason: "entry is missing, the next is 3"}, result.Break)
+}
@@ -0,0 +1,40 @@
+-- Create the audit log. Entries are chained by hash, see model.Entry.
+CREATE TABLE IF NOT EXISTS audit_entries (
+    seq BIGINT PRIMARY KEY CHECK (seq > 0),
+    id UUID NOT NULL UNIQUE,
+    service VARCHAR(50) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    actor_id UUID,
+    actor_type VARCHAR(20),
+    on_behalf_of UUID,
+    -- JSON rather than JSONB keeps the text exactly as it was hashed
+    changes JSON,
+    ip_address VARCHAR(45),
+    request_id VARCHAR(100),
+    occurred_at TIMESTAMPTZ NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL
+);
+
+CREATE INDEX idx_audit_entries_actor ON audit_entries(actor_id, seq);
+CREATE INDEX idx_audit_entries_resource ON audit_entries(resource_type, resource_id, seq);
+CREATE INDEX idx_audit_entries_action ON audit_entries(action, seq);
+CREATE INDEX idx_audit_entries_occurred_at ON audit_entries(occurred_at);
+
+-- The log is append-only. The hash chain still exposes changes made by
+-- anyone able to get around these triggers.
+CREATE OR REPLACE FUNCTION audit_entries_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER audit_entries_no_update BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
+
+CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only();
@@ -6,19 +6,29 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/email"
 	"truckify/services/auth/internal/handler"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/oidc"
 	"truckify/services/auth/internal/repository"
 	"truckify/services/auth/internal/service"
+	"truckify/services/auth/internal/sms"
+	"truckify/services/auth/migrations"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
@@ -27,8 +37,13 @@ func main() {
 	log := logger.New("auth-service", logLevel)
 	log.Info("Starting Auth Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "auth-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Load configuration
-	jwtSecret := config.MustGetEnv("JWT_SECRET")
 	accessTokenTTL := time.Duration(config.GetEnvInt("JWT_ACCESS_TTL_MINUTES", 15)) * time.Minute
 	refreshTokenTTL := time.Duration(config.GetEnvInt("JWT_REFRESH_TTL_HOURS", 168)) * time.Hour
 
@@ -47,8 +62,40 @@ func main() {
 	defer database.ClosePostgresDB(db)
 	log.Info("Connected to PostgreSQL")
 
-	// Initialize JWT manager
-	jwtManager := jwt.NewJWTManager(jwtSecret, accessTokenTTL, refreshTokenTTL)
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
+	// Initialize JWT manager. With a key directory tokens are signed with
+	// RS256/EdDSA keys published at /.well-known/jwks.json; JWT_SECRET alone
+	// keeps the shared HS256 secret.
+	var jwtManager *jwt.JWTManager
+	if keysDir := config.GetEnv("JWT_KEYS_DIR", ""); keysDir != "" {
+		keys, err := jwt.LoadKeyDir(keysDir)
+		if err != nil {
+			log.Fatal("Failed to load signing keys", "dir", keysDir, "error", err)
+		}
+		// Keep accepting tokens signed with the old secret until they expire
+		if secret := config.GetEnv("JWT_SECRET", ""); secret != "" {
+			legacy := jwt.NewHMACKey("", secret)
+			legacy.VerifyOnly = true
+			keys.Add(legacy)
+		}
+		if _, err := keys.SigningKey(time.Now()); err != nil {
+			log.Fatal("No active signing key", "dir", keysDir, "error", err)
+		}
+		jwtManager = jwt.NewKeyManager(keys, accessTokenTTL, refreshTokenTTL)
+		log.Info("Loaded signing keys", "dir", keysDir)
+	} else {
+		jwtManager = jwt.NewJWTManager(config.MustGetEnv("JWT_SECRET"), accessTokenTTL, refreshTokenTTL)
+	}
 
 	// Initialize WebAuthn
 	rpID := config.GetEnv("WEBAUTHN_RP_ID", "localhost")
@@ -67,6 +114,38 @@ func main() {
 
 	// Initialize service with WebAuthn
 	svc := service.NewWithWebAuthn(repo, jwtManager, webAuthn, log)
+	svc.SetEmailSender(email.NewService())
+	svc.SetSMSSender(sms.NewService())
+	svc.SetAuditRecorder(audit.NewClient(config.GetEnv("AUDIT_SERVICE_URL", "http://localhost:8018"), "auth-service"))
+
+	// User types whose password logins must use a second factor
+	var mfaRequired []model.UserType
+	for _, userType := range strings.Split(config.GetEnv("MFA_REQUIRED_USER_TYPES", "fleet_operator,admin"), ",") {
+		if userType = strings.TrimSpace(userType); userType != "" {
+			mfaRequired = append(mfaRequired, model.UserType(userType))
+		}
+	}
+	svc.SetMFAPolicy(mfaRequired)
+
+	// Identity providers send single sign-on users back to the web app.
+	// Providers on private addresses are only reachable for local testing.
+	ssoClient := oidc.NewClient(config.GetEnvBool("SSO_ALLOW_PRIVATE", false))
+	svc.SetSSO(ssoClient, config.GetEnv("SSO_REDIRECT_URL", "http://localhost:5173/sso/callback"))
+
+	// Revoked sessions deny their access tokens at the gateway through Redis
+	if redisHost := config.GetEnv("REDIS_HOST", ""); redisHost != "" {
+		redisClient, err := database.NewRedisClient(database.RedisConfig{
+			Host:     redisHost,
+			Port:     config.GetEnvInt("REDIS_PORT", 6379),
+			Password: config.GetEnv("REDIS_PASSWORD", ""),
+		})
+		if err != nil {
+			log.Fatal("Failed to connect to Redis", "error", err)
+		}
+		defer database.CloseRedisClient(redisClient)
+		svc.SetDenylist(jwt.NewRedisDenylist(redisClient))
+		log.Info("Connected to Redis")
+	}
 
 	// Initialize handler
 	h := handler.New(svc, log)
@@ -76,14 +155,27 @@ func main() {
 
 	// Apply middlewares
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("auth-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.SecurityHeaders)
 
 	// Register routes
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
 	h.RegisterPasskeyRoutes(router)
+	h.RegisterSessionRoutes(router)
+	h.RegisterAPIKeyRoutes(router)
+	h.RegisterMFARoutes(router)
+	h.RegisterSecurityRoutes(router)
+	h.RegisterOrgRoutes(router)
+	h.RegisterDelegationRoutes(router)
+	h.RegisterSSORoutes(router)
+	h.RegisterPhoneRoutes(router)
 	h.RegisterAdminRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "auth-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	// Wrap router with CORS (must be outermost to handle OPTIONS)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "http://localhost:3000", "*"})(router)
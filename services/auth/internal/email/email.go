@@ -92,6 +92,55 @@ func (s *Service) SendPasswordResetEmail(to, token string) error {
 	return s.send(to, subject, body)
 }
 
+func (s *Service) SendSecurityAlertEmail(to, subject, message string) error {
+	if !s.IsConfigured() {
+		fmt.Printf("[EMAIL] Security alert for %s: %s\n", to, message)
+		return nil
+	}
+
+	link := fmt.Sprintf("%s/settings", s.config.AppURL)
+
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;line-height:1.6;color:#333}.btn{background:#3b82f6;color:#fff;padding:12px 24px;text-decoration:none;border-radius:6px;display:inline-block}</style></head>
+<body>
+<h2>%s</h2>
+<p>%s</p>
+<p><a href="%s" class="btn">Review Account Activity</a></p>
+<p>If this was you, you can ignore this email.</p>
+</body>
+</html>`, template.HTMLEscapeString(subject), template.HTMLEscapeString(message), link)
+
+	return s.send(to, subject, body)
+}
+
+func (s *Service) SendInvitationEmail(to, orgName, token string) error {
+	if !s.IsConfigured() {
+		fmt.Printf("[EMAIL] Invitation to %s for %s: %s/accept-invitation?token=%s\n", orgName, to, s.config.AppURL, token)
+		return nil
+	}
+
+	subject := fmt.Sprintf("You're invited to join %s on Truckify", orgName)
+	link := fmt.Sprintf("%s/accept-invitation?token=%s", s.config.AppURL, token)
+
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><style>body{font-family:Arial,sans-serif;line-height:1.6;color:#333}.btn{background:#3b82f6;color:#fff;padding:12px 24px;text-decoration:none;border-radius:6px;display:inline-block}</style></head>
+<body>
+<h2>Join %s on Truckify</h2>
+<p>You have been invited to join %s. Sign in or create an account with this email address, then click the button below:</p>
+<p><a href="%s" class="btn">Accept Invitation</a></p>
+<p>Or copy this link: %s</p>
+<p>This link expires in 7 days.</p>
+<p>If you weren't expecting this, please ignore this email.</p>
+</body>
+</html>`, template.HTMLEscapeString(orgName), template.HTMLEscapeString(orgName), link, link)
+
+	return s.send(to, subject, body)
+}
+
 func (s *Service) send(to, subject, body string) error {
 	headers := map[string]string{
 		"From":         s.config.From,
@@ -151,6 +200,3 @@ func (s *Service) send(to, subject, body string) error {
 
 	return client.Quit()
 }
-
-// Ensure template import is used
-var _ = template.New
@@ -5,41 +5,42 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 )
 
 // RegisterAdminRoutes registers admin-only routes
 func (h *Handler) RegisterAdminRoutes(router *mux.Router) {
-	router.HandleFunc("/admin/users", h.AdminListUsers).Methods(http.MethodGet)
-	router.HandleFunc("/admin/users/{id}/status", h.AdminUpdateUserStatus).Methods(http.MethodPut)
+	router.HandleFunc("/admin/users", h.authz.RequireUnscoped(authz.UserRead, h.AdminListUsers)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/users/{id}/status", h.authz.RequireUnscoped(authz.UserManage, h.AdminUpdateUserStatus)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/users/{id}/mfa/reset", h.authz.RequireUnscoped(authz.UserManage, h.AdminResetMFA)).Methods(http.MethodPost)
 }
 
 func (h *Handler) AdminListUsers(w http.ResponseWriter, r *http.Request) {
 	reqID, _ := r.Context().Value("request_id").(string)
-	
-	userType := r.Header.Get("X-User-Type")
-	if userType != "admin" {
-		response.Forbidden(w, "Admin access required", "", reqID)
+
+	page, err := pagination.Parse(r.URL.Query(), model.UserListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), reqID)
 		return
 	}
 
-	users, err := h.service.ListUsers(r.Context())
+	users, next, err := h.service.ListUsers(r.Context(), page)
 	if err != nil {
 		response.InternalServerError(w, "Failed to list users", "", reqID)
 		return
 	}
 
-	response.Success(w, users, reqID)
+	response.List(w, users, next, reqID)
 }
 
 func (h *Handler) AdminUpdateUserStatus(w http.ResponseWriter, r *http.Request) {
 	reqID, _ := r.Context().Value("request_id").(string)
-	
-	userType := r.Header.Get("X-User-Type")
-	if userType != "admin" {
-		response.Forbidden(w, "Admin access required", "", reqID)
-		return
-	}
 
 	userID, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
@@ -60,7 +61,15 @@ func (h *Handler) AdminUpdateUserStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.service.UpdateUserStatus(r.Context(), userID, req.Status); err != nil {
+	if err := h.service.UpdateUserStatus(audit.RequestContext(r), userID, req.Status); err != nil {
+		if err == repository.ErrUserNotFound {
+			response.NotFound(w, "User not found", "", reqID)
+			return
+		}
+		if err == service.ErrAuditUnavailable {
+			response.ServiceUnavailable(w, "Status changes are unavailable", "", reqID)
+			return
+		}
 		response.InternalServerError(w, "Failed to update user", "", reqID)
 		return
 	}
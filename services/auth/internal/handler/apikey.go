@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterAPIKeyRoutes registers API key management, the gateway's key
+// verification and the OAuth 2.0 token endpoint
+func (h *Handler) RegisterAPIKeyRoutes(router *mux.Router) {
+	router.HandleFunc("/api-keys", h.CreateAPIKey).Methods(http.MethodPost)
+	router.HandleFunc("/api-keys", h.ListAPIKeys).Methods(http.MethodGet)
+	router.HandleFunc("/api-keys/verify", h.VerifyAPIKey).Methods(http.MethodPost)
+	router.HandleFunc("/api-keys/{id}", h.RevokeAPIKey).Methods(http.MethodDelete)
+	router.HandleFunc("/api-keys/{id}/events", h.ListAPIKeyEvents).Methods(http.MethodGet)
+	router.HandleFunc("/oauth/token", h.IssueClientToken).Methods(http.MethodPost)
+}
+
+// CreateAPIKey issues an API key for the authenticated user
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.keyOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAPIKeyRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(clientContext(r), userID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Created(w, key, requestID)
+}
+
+// ListAPIKeys returns the authenticated user's API keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.keyOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, keys, requestID)
+}
+
+// RevokeAPIKey revokes one of the authenticated user's API keys
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.keyOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid API key ID", "", requestID)
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(clientContext(r), userID, keyID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "API key revoked"}, requestID)
+}
+
+// ListAPIKeyEvents returns the audit trail of one of the authenticated
+// user's API keys
+func (h *Handler) ListAPIKeyEvents(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.keyOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid API key ID", "", requestID)
+		return
+	}
+
+	events, err := h.service.ListAPIKeyEvents(r.Context(), userID, keyID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, events, requestID)
+}
+
+// VerifyAPIKey authenticates an API key for the gateway. The gateway does
+// not route this endpoint, so only it can call it.
+func (h *Handler) VerifyAPIKey(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.VerifyAPIKeyRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	ctx := service.WithClientInfo(r.Context(), model.ClientInfo{IPAddress: req.IPAddress})
+	identity, err := h.service.VerifyAPIKey(ctx, req.Key)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, identity, requestID)
+}
+
+// IssueClientToken implements the OAuth 2.0 client credentials grant. It
+// answers in the OAuth wire format rather than the response envelope so
+// that standard OAuth clients can use it.
+func (h *Handler) IssueClientToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+	if grant := r.PostForm.Get("grant_type"); grant != "client_credentials" {
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only client_credentials is supported")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "Client credentials are required")
+		return
+	}
+
+	token, err := h.service.IssueClientToken(clientContext(r), clientID, clientSecret, r.PostForm.Get("scope"))
+	switch {
+	case err == nil:
+		response.Send(w, http.StatusOK, token)
+	case errors.Is(err, service.ErrInvalidClient):
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "Invalid client credentials")
+	case errors.Is(err, service.ErrInvalidScope):
+		oauthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+	default:
+		h.logger.Error("Internal error", "error", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+// keyOwner reads the authenticated user who manages API keys. API clients
+// cannot manage keys themselves, so a leaked key cannot mint others.
+func (h *Handler) keyOwner(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, bool) {
+	if _, ok := r.Header["X-Permissions"]; ok {
+		response.Forbidden(w, "API clients cannot manage API keys", "", requestID)
+		return uuid.Nil, false
+	}
+	return h.userID(w, r, requestID)
+}
+
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="truckify"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.OAuthError{Error: code, Description: description})
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterDelegationRoutes registers delegations to dispatchers and the
+// acting-as tokens dispatchers use them with
+func (h *Handler) RegisterDelegationRoutes(router *mux.Router) {
+	router.HandleFunc("/delegations", h.CreateDelegation).Methods(http.MethodPost)
+	router.HandleFunc("/delegations", h.ListDelegations).Methods(http.MethodGet)
+	router.HandleFunc("/delegations/{id}", h.RevokeDelegation).Methods(http.MethodDelete)
+	router.HandleFunc("/delegations/{id}/token", h.IssueActingToken).Methods(http.MethodPost)
+}
+
+// CreateDelegation delegates rights of the authenticated user to a
+// dispatcher
+func (h *Handler) CreateDelegation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.delegationUser(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.CreateDelegationRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	d, err := h.service.CreateDelegation(clientContext(r), userID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Created(w, d, requestID)
+}
+
+// ListDelegations returns the delegations the authenticated user granted or
+// was granted
+func (h *Handler) ListDelegations(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.delegationUser(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	delegations, err := h.service.ListDelegations(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, delegations, requestID)
+}
+
+// RevokeDelegation revokes a delegation the authenticated user granted or
+// was granted
+func (h *Handler) RevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.delegationUser(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	delegationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid delegation ID", "", requestID)
+		return
+	}
+
+	if err := h.service.RevokeDelegation(clientContext(r), userID, delegationID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Delegation revoked"}, requestID)
+}
+
+// IssueActingToken issues the authenticated dispatcher an access token
+// acting for the user who delegated to them
+func (h *Handler) IssueActingToken(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	w.Header().Set("Cache-Control", "no-store")
+
+	userID, ok := h.delegationUser(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	delegationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid delegation ID", "", requestID)
+		return
+	}
+
+	token, err := h.service.IssueActingToken(clientContext(r), userID, delegationID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, token, requestID)
+}
+
+// delegationUser reads the authenticated user who manages delegations. API
+// clients and dispatchers already acting for someone cannot, so a delegated
+// token cannot be used to delegate further.
+func (h *Handler) delegationUser(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, bool) {
+	if _, ok := r.Header["X-Permissions"]; ok {
+		response.Forbidden(w, "Scoped tokens cannot manage delegations", "", requestID)
+		return uuid.Nil, false
+	}
+	return h.userID(w, r, requestID)
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/google/uuid"
@@ -10,8 +13,10 @@ import (
 	"truckify/services/auth/internal/model"
 	"truckify/services/auth/internal/repository"
 	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/authz"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -21,6 +26,7 @@ type ServiceInterface interface {
 	Register(ctx context.Context, req *model.RegisterRequest) (*model.LoginResponse, error)
 	Login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*jwt.TokenPair, error)
+	JWKS() *jwt.JWKS
 	ChangePassword(ctx context.Context, userID uuid.UUID, req *model.ChangePasswordRequest) error
 	ForgotPassword(ctx context.Context, req *model.ForgotPasswordRequest) error
 	ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) error
@@ -33,8 +39,59 @@ type ServiceInterface interface {
 	GetUserPasskeys(ctx context.Context, userID uuid.UUID) ([]model.PasskeyCredential, error)
 	DeletePasskey(ctx context.Context, userID, passkeyID uuid.UUID) error
 	// Admin methods
-	ListUsers(ctx context.Context) ([]model.User, error)
+	ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error)
 	UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error
+	// Session methods
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]model.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	// API key methods
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, req *model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	ListAPIKeyEvents(ctx context.Context, userID, keyID uuid.UUID) ([]model.APIKeyEvent, error)
+	VerifyAPIKey(ctx context.Context, key string) (*model.APIKeyIdentity, error)
+	IssueClientToken(ctx context.Context, clientID, clientSecret, scope string) (*model.ClientTokenResponse, error)
+	// MFA methods
+	VerifyMFALogin(ctx context.Context, req *model.MFALoginRequest) (*model.LoginResponse, error)
+	BeginMFALoginEnrollment(ctx context.Context, mfaToken string) (*model.TOTPEnrollment, error)
+	GetMFAStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error)
+	DisableMFA(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error)
+	ResetMFA(ctx context.Context, userID uuid.UUID) error
+	// Security log methods
+	ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error)
+	// Organisation methods
+	CreateOrganisation(ctx context.Context, userID uuid.UUID, req *model.CreateOrganisationRequest) (*model.OrganisationResponse, error)
+	ListOrganisations(ctx context.Context, userID uuid.UUID, currentOrgID string) ([]model.OrganisationResponse, error)
+	ListMembers(ctx context.Context, userID, orgID uuid.UUID) ([]model.Membership, error)
+	InviteMember(ctx context.Context, userID, orgID uuid.UUID, req *model.InviteMemberRequest) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, userID, orgID uuid.UUID) ([]model.Invitation, error)
+	RevokeInvitation(ctx context.Context, userID, orgID, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, req *model.AcceptInvitationRequest) (*model.OrganisationResponse, error)
+	UpdateMemberRole(ctx context.Context, userID, orgID, memberID uuid.UUID, role model.OrgRole) error
+	RemoveMember(ctx context.Context, userID, orgID, memberID uuid.UUID) error
+	SwitchOrganisation(ctx context.Context, userID, orgID uuid.UUID, refreshToken string) (*jwt.TokenPair, error)
+	// Delegation methods
+	CreateDelegation(ctx context.Context, userID uuid.UUID, req *model.CreateDelegationRequest) (*model.Delegation, error)
+	ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error)
+	RevokeDelegation(ctx context.Context, userID, delegationID uuid.UUID) error
+	IssueActingToken(ctx context.Context, dispatcherID, delegationID uuid.UUID) (*model.ActingTokenResponse, error)
+	// Single sign-on methods
+	SaveIdentityProvider(ctx context.Context, userID, orgID uuid.UUID, req *model.IdentityProviderRequest) (*model.IdentityProvider, error)
+	GetIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error)
+	VerifyIdentityProviderDomain(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error)
+	DeleteIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) error
+	StartSSO(ctx context.Context, req *model.StartSSORequest) (*model.StartSSOResponse, error)
+	FinishSSO(ctx context.Context, req *model.SSOCallbackRequest) (*model.LoginResponse, error)
+	// Phone methods
+	SendLoginCode(ctx context.Context, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error)
+	PhoneLogin(ctx context.Context, req *model.PhoneLoginRequest) (*model.LoginResponse, error)
+	SendPhoneVerificationCode(ctx context.Context, userID uuid.UUID, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error)
+	VerifyPhone(ctx context.Context, userID uuid.UUID, req *model.VerifyPhoneRequest) error
 }
 
 // Handler handles HTTP requests for auth
@@ -42,6 +99,7 @@ type Handler struct {
 	service   ServiceInterface
 	validator *validator.Validator
 	logger    *logger.Logger
+	authz     *authz.Authorizer
 }
 
 // New creates a new handler instance
@@ -50,6 +108,7 @@ func New(service ServiceInterface, logger *logger.Logger) *Handler {
 		service:   service,
 		validator: validator.New(),
 		logger:    logger,
+		authz:     authz.New(authz.DefaultPolicy()),
 	}
 }
 
@@ -62,6 +121,7 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/forgot-password", h.ForgotPassword).Methods(http.MethodPost)
 	router.HandleFunc("/reset-password", h.ResetPassword).Methods(http.MethodPost)
 	router.HandleFunc("/verify-email", h.VerifyEmail).Methods(http.MethodPost)
+	router.HandleFunc("/.well-known/jwks.json", h.JWKS).Methods(http.MethodGet)
 	router.HandleFunc("/health", h.Health).Methods(http.MethodGet)
 }
 
@@ -75,7 +135,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.Register(r.Context(), &req)
+	result, err := h.service.Register(clientContext(r), &req)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
@@ -94,7 +154,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.Login(r.Context(), &req)
+	result, err := h.service.Login(clientContext(r), &req)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
@@ -113,7 +173,7 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.service.RefreshToken(r.Context(), req.RefreshToken)
+	tokens, err := h.service.RefreshToken(clientContext(r), req.RefreshToken)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
@@ -122,6 +182,12 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, tokens, requestID)
 }
 
+// JWKS publishes the public keys that verify issued tokens
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.Send(w, http.StatusOK, h.service.JWKS())
+}
+
 // ChangePassword handles password change
 func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	requestID, _ := r.Context().Value("request_id").(string)
@@ -145,7 +211,7 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.ChangePassword(r.Context(), userID, &req); err != nil {
+	if err := h.service.ChangePassword(clientContext(r), userID, &req); err != nil {
 		h.handleError(w, err, requestID)
 		return
 	}
@@ -218,11 +284,102 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 
 // handleError handles service errors and returns appropriate HTTP responses
 func (h *Handler) handleError(w http.ResponseWriter, err error, requestID string) {
+	if errors.Is(err, service.ErrInvalidScope) {
+		response.BadRequest(w, "Invalid scope", err.Error(), requestID)
+		return
+	}
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		if errors.Is(err, service.ErrSMSThrottled) {
+			response.Error(w, http.StatusTooManyRequests, "SMS_THROTTLED", "Too many codes sent to this phone number", "Try again later", requestID)
+			return
+		}
+		response.Error(w, http.StatusTooManyRequests, "LOGIN_THROTTLED", "Too many failed login attempts", "Try again later", requestID)
+		return
+	}
+
 	switch err {
 	case service.ErrInvalidCredentials:
 		response.Unauthorized(w, "Invalid credentials", "", requestID)
 	case service.ErrUserNotActive:
 		response.Forbidden(w, "User account is not active", "", requestID)
+	case service.ErrInvalidRefreshToken:
+		response.Unauthorized(w, "Invalid refresh token", "", requestID)
+	case service.ErrRefreshTokenReused:
+		response.Unauthorized(w, "Refresh token has already been used", "Session revoked, please log in again", requestID)
+	case service.ErrInvalidMFAToken:
+		response.Unauthorized(w, "Invalid or expired MFA token", "Log in again", requestID)
+	case service.ErrInvalidMFACode:
+		response.Unauthorized(w, "Invalid MFA code", "", requestID)
+	case service.ErrMFAAlreadyEnabled:
+		response.Conflict(w, "A second factor is already enabled", "", requestID)
+	case service.ErrMFANotEnabled:
+		response.Conflict(w, "No second factor is enabled", "", requestID)
+	case service.ErrMFAEnrollmentNotStarted:
+		response.Conflict(w, "TOTP enrolment has not been started", "", requestID)
+	case service.ErrMFARequired:
+		response.Forbidden(w, "A second factor is required for this account", "", requestID)
+	case service.ErrInvalidAPIKey:
+		response.Unauthorized(w, "Invalid API key", "", requestID)
+	case service.ErrOrgPermission:
+		response.Forbidden(w, "Insufficient organisation role", "", requestID)
+	case service.ErrInvitationEmailMismatch:
+		response.Forbidden(w, "Invitation was sent to another email address", "", requestID)
+	case service.ErrInvalidInvitation:
+		response.BadRequest(w, "Invalid or expired invitation", "", requestID)
+	case service.ErrPersonalOrganisation:
+		response.Conflict(w, "Personal organisations have no other members", "", requestID)
+	case service.ErrAlreadyMember:
+		response.Conflict(w, "Already a member of the organisation", "", requestID)
+	case service.ErrLastOwner:
+		response.Conflict(w, "An organisation needs at least one owner", "", requestID)
+	case service.ErrDelegationNotAllowed:
+		response.Forbidden(w, "Only shippers and fleet operators can delegate", "", requestID)
+	case service.ErrNotDispatcher:
+		response.BadRequest(w, "Rights can only be delegated to an active dispatcher", "", requestID)
+	case service.ErrInvalidDelegation:
+		response.Forbidden(w, "Delegation has expired or its grantor is not active", "", requestID)
+	case repository.ErrDelegationNotFound:
+		response.NotFound(w, "Delegation not found", "", requestID)
+	case service.ErrSSONotConfigured:
+		response.NotFound(w, "Single sign-on is not set up for this domain", "", requestID)
+	case service.ErrInvalidSSOLogin:
+		response.BadRequest(w, "Invalid or expired single sign-on login", "Start the login again", requestID)
+	case service.ErrSSOIdentity:
+		response.Forbidden(w, "Identity provider did not return a verified email in the organisation's domain", "", requestID)
+	case service.ErrSSOAccountExists:
+		response.Conflict(w, "An account with this email exists outside the organisation", "Ask an organisation admin to invite it", requestID)
+	case service.ErrSSOProviderFailed:
+		response.Error(w, http.StatusBadGateway, "BAD_GATEWAY", "Identity provider could not complete the login", "", requestID)
+	case service.ErrSSOInvalidIssuer:
+		response.BadRequest(w, "Issuer must be an https URL on a public address", "", requestID)
+	case service.ErrSSODomainNotProven:
+		response.Conflict(w, "Domain verification record not found", "Publish the verification token in a TXT record at "+model.DomainChallengePrefix+"<domain>", requestID)
+	case service.ErrSMSNotConfigured:
+		response.ServiceUnavailable(w, "Phone number logins are not available", "", requestID)
+	case service.ErrSMSFailed:
+		response.Error(w, http.StatusBadGateway, "BAD_GATEWAY", "The code could not be sent", "Try again later", requestID)
+	case service.ErrInvalidPhoneCode:
+		response.Unauthorized(w, "Invalid or expired code", "", requestID)
+	case service.ErrPhoneNotRegistered:
+		response.NotFound(w, "No account uses this phone number", "Choose a user type to register", requestID)
+	case repository.ErrPhoneTaken:
+		response.Conflict(w, "Another account uses this phone number", "", requestID)
+	case repository.ErrIdentityProviderNotFound:
+		response.NotFound(w, "Identity provider not found", "", requestID)
+	case repository.ErrDomainTaken:
+		response.Conflict(w, "Another organisation has verified this domain for single sign-on", "", requestID)
+	case repository.ErrOrganisationNotFound:
+		response.NotFound(w, "Organisation not found", "", requestID)
+	case repository.ErrMembershipNotFound:
+		response.NotFound(w, "Member not found", "", requestID)
+	case repository.ErrInvitationNotFound:
+		response.NotFound(w, "Invitation not found", "", requestID)
+	case repository.ErrAPIKeyNotFound:
+		response.NotFound(w, "API key not found", "", requestID)
+	case repository.ErrSessionNotFound:
+		response.NotFound(w, "Session not found", "", requestID)
 	case repository.ErrEmailAlreadyExists:
 		response.Conflict(w, "Email already exists", "", requestID)
 	case repository.ErrUserNotFound:
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,8 +18,12 @@ import (
 	"github.com/stretchr/testify/mock"
 	"truckify/services/auth/internal/handler"
 	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/pagination"
 )
 
 type MockService struct {
@@ -48,6 +54,11 @@ func (m *MockService) RefreshToken(ctx context.Context, refreshToken string) (*j
 	return args.Get(0).(*jwt.TokenPair), args.Error(1)
 }
 
+func (m *MockService) JWKS() *jwt.JWKS {
+	args := m.Called()
+	return args.Get(0).(*jwt.JWKS)
+}
+
 func (m *MockService) ChangePassword(ctx context.Context, userID uuid.UUID, req *model.ChangePasswordRequest) error {
 	args := m.Called(ctx, userID, req)
 	return args.Error(0)
@@ -113,12 +124,12 @@ func (m *MockService) DeletePasskey(ctx context.Context, userID, passkeyID uuid.
 	return args.Error(0)
 }
 
-func (m *MockService) ListUsers(ctx context.Context) ([]model.User, error) {
-	args := m.Called(ctx)
+func (m *MockService) ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error) {
+	args := m.Called(ctx, page)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, "", args.Error(2)
 	}
-	return args.Get(0).([]model.User), args.Error(1)
+	return args.Get(0).([]model.User), args.String(1), args.Error(2)
 }
 
 func (m *MockService) UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error {
@@ -126,6 +137,316 @@ func (m *MockService) UpdateUserStatus(ctx context.Context, userID uuid.UUID, st
 	return args.Error(0)
 }
 
+func (m *MockService) Logout(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockService) ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]model.SessionResponse, error) {
+	args := m.Called(ctx, userID, currentSessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.SessionResponse), args.Error(1)
+}
+
+func (m *MockService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockService) CreateAPIKey(ctx context.Context, userID uuid.UUID, req *model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CreatedAPIKey), args.Error(1)
+}
+
+func (m *MockService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.APIKey), args.Error(1)
+}
+
+func (m *MockService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	args := m.Called(ctx, userID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockService) ListAPIKeyEvents(ctx context.Context, userID, keyID uuid.UUID) ([]model.APIKeyEvent, error) {
+	args := m.Called(ctx, userID, keyID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.APIKeyEvent), args.Error(1)
+}
+
+func (m *MockService) VerifyAPIKey(ctx context.Context, key string) (*model.APIKeyIdentity, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKeyIdentity), args.Error(1)
+}
+
+func (m *MockService) IssueClientToken(ctx context.Context, clientID, clientSecret, scope string) (*model.ClientTokenResponse, error) {
+	args := m.Called(ctx, clientID, clientSecret, scope)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ClientTokenResponse), args.Error(1)
+}
+
+// MFA methods
+func (m *MockService) VerifyMFALogin(ctx context.Context, req *model.MFALoginRequest) (*model.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockService) BeginMFALoginEnrollment(ctx context.Context, mfaToken string) (*model.TOTPEnrollment, error) {
+	args := m.Called(ctx, mfaToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) GetMFAStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFAStatus), args.Error(1)
+}
+
+func (m *MockService) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecoveryCodesResponse), args.Error(1)
+}
+
+func (m *MockService) DisableMFA(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RecoveryCodesResponse), args.Error(1)
+}
+
+func (m *MockService) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockService) ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error) {
+	args := m.Called(ctx, userID, page)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]model.SecurityEvent), args.String(1), args.Error(2)
+}
+
+// Organisation methods
+func (m *MockService) CreateOrganisation(ctx context.Context, userID uuid.UUID, req *model.CreateOrganisationRequest) (*model.OrganisationResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OrganisationResponse), args.Error(1)
+}
+
+func (m *MockService) ListOrganisations(ctx context.Context, userID uuid.UUID, currentOrgID string) ([]model.OrganisationResponse, error) {
+	args := m.Called(ctx, userID, currentOrgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.OrganisationResponse), args.Error(1)
+}
+
+func (m *MockService) ListMembers(ctx context.Context, userID, orgID uuid.UUID) ([]model.Membership, error) {
+	args := m.Called(ctx, userID, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Membership), args.Error(1)
+}
+
+func (m *MockService) InviteMember(ctx context.Context, userID, orgID uuid.UUID, req *model.InviteMemberRequest) (*model.Invitation, error) {
+	args := m.Called(ctx, userID, orgID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invitation), args.Error(1)
+}
+
+func (m *MockService) ListInvitations(ctx context.Context, userID, orgID uuid.UUID) ([]model.Invitation, error) {
+	args := m.Called(ctx, userID, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Invitation), args.Error(1)
+}
+
+func (m *MockService) RevokeInvitation(ctx context.Context, userID, orgID, invitationID uuid.UUID) error {
+	args := m.Called(ctx, userID, orgID, invitationID)
+	return args.Error(0)
+}
+
+func (m *MockService) AcceptInvitation(ctx context.Context, userID uuid.UUID, req *model.AcceptInvitationRequest) (*model.OrganisationResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.OrganisationResponse), args.Error(1)
+}
+
+func (m *MockService) UpdateMemberRole(ctx context.Context, userID, orgID, memberID uuid.UUID, role model.OrgRole) error {
+	args := m.Called(ctx, userID, orgID, memberID, role)
+	return args.Error(0)
+}
+
+func (m *MockService) RemoveMember(ctx context.Context, userID, orgID, memberID uuid.UUID) error {
+	args := m.Called(ctx, userID, orgID, memberID)
+	return args.Error(0)
+}
+
+func (m *MockService) SwitchOrganisation(ctx context.Context, userID, orgID uuid.UUID, refreshToken string) (*jwt.TokenPair, error) {
+	args := m.Called(ctx, userID, orgID, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*jwt.TokenPair), args.Error(1)
+}
+
+func (m *MockService) CreateDelegation(ctx context.Context, userID uuid.UUID, req *model.CreateDelegationRequest) (*model.Delegation, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Delegation), args.Error(1)
+}
+
+func (m *MockService) ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Delegation), args.Error(1)
+}
+
+func (m *MockService) RevokeDelegation(ctx context.Context, userID, delegationID uuid.UUID) error {
+	args := m.Called(ctx, userID, delegationID)
+	return args.Error(0)
+}
+
+func (m *MockService) IssueActingToken(ctx context.Context, dispatcherID, delegationID uuid.UUID) (*model.ActingTokenResponse, error) {
+	args := m.Called(ctx, dispatcherID, delegationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ActingTokenResponse), args.Error(1)
+}
+
+func (m *MockService) SaveIdentityProvider(ctx context.Context, userID, orgID uuid.UUID, req *model.IdentityProviderRequest) (*model.IdentityProvider, error) {
+	args := m.Called(ctx, userID, orgID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityProvider), args.Error(1)
+}
+
+func (m *MockService) GetIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	args := m.Called(ctx, userID, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityProvider), args.Error(1)
+}
+
+func (m *MockService) VerifyIdentityProviderDomain(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	args := m.Called(ctx, userID, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityProvider), args.Error(1)
+}
+
+func (m *MockService) DeleteIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) error {
+	args := m.Called(ctx, userID, orgID)
+	return args.Error(0)
+}
+
+func (m *MockService) StartSSO(ctx context.Context, req *model.StartSSORequest) (*model.StartSSOResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StartSSOResponse), args.Error(1)
+}
+
+func (m *MockService) FinishSSO(ctx context.Context, req *model.SSOCallbackRequest) (*model.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockService) SendLoginCode(ctx context.Context, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PhoneCodeSent), args.Error(1)
+}
+
+func (m *MockService) PhoneLogin(ctx context.Context, req *model.PhoneLoginRequest) (*model.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockService) SendPhoneVerificationCode(ctx context.Context, userID uuid.UUID, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PhoneCodeSent), args.Error(1)
+}
+
+func (m *MockService) VerifyPhone(ctx context.Context, userID uuid.UUID, req *model.VerifyPhoneRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
 func setupTestHandler() (*handler.Handler, *MockService) {
 	mockService := new(MockService)
 	log := logger.New("test", "debug")
@@ -296,6 +617,125 @@ func TestRefreshToken_Success(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestRefreshToken_Reused(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	mockService.On("RefreshToken", mock.Anything, "stale_refresh_token").Return(nil, service.ErrRefreshTokenReused)
+
+	body := `{"refresh_token":"stale_refresh_token"}`
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestLogout_Success(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	mockService.On("Logout", mock.Anything, "refresh_token").Return(nil)
+
+	body := `{"refresh_token":"refresh_token"}`
+	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterSessionRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestLogoutAll_Unauthenticated(t *testing.T) {
+	h, _ := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/logout/all", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterSessionRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestListSessions_Success(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	userID := uuid.New()
+	sessionID := uuid.New()
+	mockService.On("ListSessions", mock.Anything, userID, sessionID.String()).Return([]model.SessionResponse{
+		{ID: sessionID, UserAgent: "Mozilla/5.0", IPAddress: "203.0.113.7", Current: true},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+	req.Header.Set("X-User-ID", userID.String())
+	req.Header.Set("X-Session-ID", sessionID.String())
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterSessionRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	data := response["data"].([]interface{})
+	assert.Len(t, data, 1)
+	assert.Equal(t, true, data[0].(map[string]interface{})["current"])
+	mockService.AssertExpectations(t)
+}
+
+func TestRevokeSession_NotFound(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	userID := uuid.New()
+	sessionID := uuid.New()
+	mockService.On("RevokeSession", mock.Anything, userID, sessionID).Return(repository.ErrSessionNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/sessions/"+sessionID.String(), nil)
+	req.Header.Set("X-User-ID", userID.String())
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterSessionRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestJWKS_Success(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	mockService.On("JWKS").Return(&jwt.JWKS{Keys: []jwt.JWK{
+		{KeyType: "OKP", KeyID: "2026-10", Algorithm: "EdDSA", Use: "sig", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var set jwt.JWKS
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &set))
+	assert.Len(t, set.Keys, 1)
+	assert.Equal(t, "2026-10", set.Keys[0].KeyID)
+	mockService.AssertExpectations(t)
+}
+
 func TestHealth_Success(t *testing.T) {
 	h, _ := setupTestHandler()
 
@@ -314,3 +754,464 @@ func TestHealth_Success(t *testing.T) {
 	data := response["data"].(map[string]interface{})
 	assert.Equal(t, "healthy", data["status"])
 }
+
+func TestCreateAPIKey_RejectsAPIClients(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api-keys", bytes.NewBufferString(`{"name":"tms","scopes":["job:read"]}`))
+	req.Header.Set("X-User-ID", uuid.New().String())
+	req.Header.Set("X-User-Type", "shipper")
+	req.Header.Set("X-Permissions", "job:read")
+	rr := httptest.NewRecorder()
+
+	h.CreateAPIKey(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIssueClientToken(t *testing.T) {
+	h, mockService := setupTestHandler()
+	clientID := uuid.New().String()
+	mockService.On("IssueClientToken", mock.Anything, clientID, "tfy_secret", "job:read").Return(&model.ClientTokenResponse{
+		AccessToken: "access_token", TokenType: "Bearer", ExpiresIn: 900, Scope: "job:read",
+	}, nil)
+	mockService.On("IssueClientToken", mock.Anything, clientID, "tfy_wrong", "").Return(nil, service.ErrInvalidClient)
+
+	token := func(form url.Values, user, pass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		rr := httptest.NewRecorder()
+		h.IssueClientToken(rr, req)
+		return rr
+	}
+
+	rr := token(url.Values{"grant_type": {"client_credentials"}, "scope": {"job:read"}}, clientID, "tfy_secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var issued model.ClientTokenResponse
+	json.Unmarshal(rr.Body.Bytes(), &issued)
+	assert.Equal(t, "access_token", issued.AccessToken)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	rr = token(url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}, "client_secret": {"tfy_wrong"}}, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	var oauthErr model.OAuthError
+	json.Unmarshal(rr.Body.Bytes(), &oauthErr)
+	assert.Equal(t, "invalid_client", oauthErr.Error)
+
+	rr = token(url.Values{"grant_type": {"password"}}, clientID, "tfy_secret")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &oauthErr)
+	assert.Equal(t, "unsupported_grant_type", oauthErr.Error)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestLogin_MFARequired(t *testing.T) {
+	h, mockService := setupTestHandler()
+	mockService.On("Login", mock.Anything, mock.Anything).Return(&model.LoginResponse{
+		MFA: &model.MFAPending{Token: "mfa_token", ExpiresIn: 300},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(`{"email":"fleet@example.com","password":"password123"}`))
+	rr := httptest.NewRecorder()
+	h.Login(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	assert.NotContains(t, body.Data, "access_token")
+	assert.NotContains(t, body.Data, "user")
+	assert.Equal(t, "mfa_token", body.Data["mfa"].(map[string]interface{})["token"])
+}
+
+func TestVerifyMFALogin_InvalidCode(t *testing.T) {
+	h, mockService := setupTestHandler()
+	mockService.On("VerifyMFALogin", mock.Anything, &model.MFALoginRequest{MFAToken: "mfa_token", Code: "000000"}).
+		Return(nil, service.ErrInvalidMFACode)
+
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewBufferString(`{"mfa_token":"mfa_token","code":"000000"}`))
+	rr := httptest.NewRecorder()
+	h.VerifyMFALogin(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDisableMFA_RequiredByPolicy(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	mockService.On("DisableMFA", mock.Anything, userID, "123456").Return(service.ErrMFARequired)
+
+	req := httptest.NewRequest(http.MethodPost, "/mfa/totp/disable", bytes.NewBufferString(`{"code":"123456"}`))
+	req.Header.Set("X-User-ID", userID.String())
+	rr := httptest.NewRecorder()
+	h.DisableMFA(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestLogin_Throttled(t *testing.T) {
+	h, mockService := setupTestHandler()
+
+	mockService.On("Login", mock.Anything, mock.Anything).
+		Return(nil, &service.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(`{"email":"test@example.com","password":"wrongpassword"}`))
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), "LOGIN_THROTTLED")
+}
+
+func TestListSecurityEvents(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	mockService.On("ListSecurityEvents", mock.Anything, userID, mock.MatchedBy(func(p pagination.Page) bool {
+		return len(p.Filters) == 1 && p.Filters[0].Column == "event_type"
+	})).Return([]model.SecurityEvent{
+		{ID: uuid.New(), UserID: userID, Type: model.SecurityNewDeviceLogin, UserAgent: "NewPhone/1.0", CreatedAt: time.Now()},
+	}, "next-cursor", nil)
+
+	router := mux.NewRouter()
+	h.RegisterSecurityRoutes(router)
+	list := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/security-events?type=new_device_login", nil)
+		req.Header.Set("X-User-ID", userID.String())
+		if header != "" {
+			req.Header.Set(header, "job:read")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := list("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "new_device_login")
+	assert.Contains(t, rr.Body.String(), "next-cursor")
+
+	// API clients acting for the user cannot read it
+	assert.Equal(t, http.StatusForbidden, list("X-Permissions").Code)
+	mockService.AssertNumberOfCalls(t, "ListSecurityEvents", 1)
+}
+
+func TestAdminResetMFA(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	mockService.On("ResetMFA", mock.Anything, userID).Return(nil)
+
+	router := mux.NewRouter()
+	h.RegisterAdminRoutes(router)
+	reset := func(role string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/mfa/reset", nil)
+		req.Header.Set("X-User-ID", uuid.New().String())
+		req.Header.Set("X-User-Type", role)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, reset("fleet_operator"))
+	assert.Equal(t, http.StatusOK, reset("admin"))
+	mockService.AssertNumberOfCalls(t, "ResetMFA", 1)
+}
+
+func TestListOrganisations_MarksCurrent(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	orgID := uuid.New()
+	mockService.On("ListOrganisations", mock.Anything, userID, orgID.String()).Return([]model.OrganisationResponse{
+		{Organisation: model.Organisation{ID: userID, Name: "me@example.com", Personal: true}, Role: model.OrgRoleOwner},
+		{Organisation: model.Organisation{ID: orgID, Name: "Acme Freight"}, Role: model.OrgRoleAdmin, Current: true},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/orgs", nil)
+	req.Header.Set("X-User-ID", userID.String())
+	req.Header.Set("X-Org-ID", orgID.String())
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterOrgRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Acme Freight")
+	mockService.AssertExpectations(t)
+}
+
+func TestInviteMember(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	orgID := uuid.New()
+	mockService.On("InviteMember", mock.Anything, userID, orgID, &model.InviteMemberRequest{Email: "new@example.com", Role: model.OrgRoleMember}).
+		Return(&model.Invitation{ID: uuid.New(), OrgID: orgID, Email: "new@example.com", Role: model.OrgRoleMember}, nil).Once()
+	mockService.On("InviteMember", mock.Anything, userID, orgID, &model.InviteMemberRequest{Email: "boss@example.com", Role: model.OrgRoleOwner}).
+		Return(nil, service.ErrOrgPermission).Once()
+
+	router := mux.NewRouter()
+	h.RegisterOrgRoutes(router)
+	invite := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/invitations", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", userID.String())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := invite(`{"email":"new@example.com","role":"member"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "token")
+
+	assert.Equal(t, http.StatusForbidden, invite(`{"email":"boss@example.com","role":"owner"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, invite(`{"email":"new@example.com","role":"superuser"}`).Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRemoveMember_LastOwner(t *testing.T) {
+	h, mockService := setupTestHandler()
+	userID := uuid.New()
+	orgID := uuid.New()
+	mockService.On("RemoveMember", mock.Anything, userID, orgID, userID).Return(service.ErrLastOwner)
+
+	req := httptest.NewRequest(http.MethodDelete, "/orgs/"+orgID.String()+"/members/"+userID.String(), nil)
+	req.Header.Set("X-User-ID", userID.String())
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	h.RegisterOrgRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateDelegation(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterDelegationRoutes(router)
+
+	userID := uuid.New()
+	mockService.On("CreateDelegation", mock.Anything, userID, mock.AnythingOfType("*model.CreateDelegationRequest")).
+		Return(&model.Delegation{ID: uuid.New(), GrantorID: userID, Rights: []string{"post_jobs"}}, nil)
+
+	create := func(body string, acting bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/delegations", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", userID.String())
+		req.Header.Set("X-User-Type", "shipper")
+		if acting {
+			req.Header.Set("X-Actor-ID", uuid.New().String())
+			req.Header.Set("X-Actor-Type", "dispatcher")
+			req.Header.Set("X-Permissions", "job:create")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusCreated, create(`{"dispatcher_email":"desk@example.com","rights":["post_jobs"]}`, false).Code)
+	assert.Equal(t, http.StatusBadRequest, create(`{"dispatcher_email":"desk@example.com","rights":["pay_invoices"]}`, false).Code)
+
+	// A dispatcher acting for the user cannot delegate further
+	assert.Equal(t, http.StatusForbidden, create(`{"dispatcher_email":"other@example.com","rights":["post_jobs"]}`, true).Code)
+	mockService.AssertNumberOfCalls(t, "CreateDelegation", 1)
+}
+
+func TestSSO(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterSSORoutes(router)
+
+	mockService.On("StartSSO", mock.Anything, mock.MatchedBy(func(r *model.StartSSORequest) bool { return r.Email == "ops@acme.example" })).
+		Return(&model.StartSSOResponse{AuthorizationURL: "https://idp.acme.example/authorize?state=s", ExpiresIn: 600}, nil)
+	mockService.On("StartSSO", mock.Anything, mock.Anything).Return(nil, service.ErrSSONotConfigured)
+	mockService.On("FinishSSO", mock.Anything, mock.MatchedBy(func(r *model.SSOCallbackRequest) bool { return r.State == "s" })).
+		Return(nil, service.ErrSSOProviderFailed)
+	mockService.On("FinishSSO", mock.Anything, mock.Anything).Return(nil, service.ErrInvalidSSOLogin)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return rr
+	}
+
+	rr := post("/sso/start", `{"email":"ops@acme.example"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "authorization_url")
+	assert.Equal(t, http.StatusNotFound, post("/sso/start", `{"email":"ops@example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/sso/callback", `{"state":"s"}`).Code)
+	assert.Equal(t, http.StatusBadGateway, post("/sso/callback", `{"state":"s","code":"c"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/sso/callback", `{"state":"old","code":"c"}`).Code)
+}
+
+func TestSaveIdentityProvider(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterSSORoutes(router)
+
+	userID := uuid.New()
+	orgID := uuid.New()
+	mockService.On("SaveIdentityProvider", mock.Anything, userID, orgID, mock.AnythingOfType("*model.IdentityProviderRequest")).
+		Return(&model.IdentityProvider{OrgID: orgID, Issuer: "https://idp.acme.example", ClientID: "truckify", ClientSecret: "s3cret", Domain: "acme.example"}, nil)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/orgs/"+orgID.String()+"/sso", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", userID.String())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := put(`{"issuer":"https://idp.acme.example","client_id":"truckify","client_secret":"s3cret","domain":"acme.example",
+		"role_claim":"groups","role_mapping":{"truckify-dispatch":"dispatcher"},"default_user_type":"shipper"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "s3cret")
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"issuer":"https://idp.acme.example","client_id":"truckify","client_secret":"s3cret","domain":"acme.example",
+		"role_mapping":{"truckify-admin":"admin"},"default_user_type":"shipper"}`).Code)
+	mockService.AssertNumberOfCalls(t, "SaveIdentityProvider", 1)
+}
+
+func TestVerifyIdentityProviderDomain(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterSSORoutes(router)
+
+	userID := uuid.New()
+	orgID := uuid.New()
+	mockService.On("VerifyIdentityProviderDomain", mock.Anything, userID, orgID).Return(nil, service.ErrSSODomainNotProven).Once()
+
+	verify := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orgs/"+orgID.String()+"/sso/verify", nil)
+		req.Header.Set("X-User-ID", userID.String())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := verify()
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), model.DomainChallengePrefix)
+
+	verifiedAt := time.Now()
+	mockService.On("VerifyIdentityProviderDomain", mock.Anything, userID, orgID).
+		Return(&model.IdentityProvider{OrgID: orgID, Domain: "acme.example", DomainVerifiedAt: &verifiedAt}, nil)
+	rr = verify()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "domain_verified_at")
+}
+
+func TestSendLoginCode(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterPhoneRoutes(router)
+
+	mockService.On("SendLoginCode", mock.Anything, mock.MatchedBy(func(r *model.PhoneCodeRequest) bool { return r.Phone == "+254712345678" })).
+		Return(nil, &service.ThrottledError{RetryAfter: 42 * time.Second, Cause: service.ErrSMSThrottled})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/phone/code", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	// Numbers must be in E.164
+	assert.Equal(t, http.StatusBadRequest, send(`{"phone":"0712 345 678"}`).Code)
+
+	rr := send(`{"phone":"+254712345678"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "42", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), "SMS_THROTTLED")
+}
+
+func TestPhoneLogin(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterPhoneRoutes(router)
+
+	mockService.On("PhoneLogin", mock.Anything, mock.MatchedBy(func(r *model.PhoneLoginRequest) bool { return r.UserType == "" })).
+		Return(nil, service.ErrPhoneNotRegistered)
+	mockService.On("PhoneLogin", mock.Anything, mock.Anything).Return(&model.LoginResponse{
+		User:        &model.UserResponse{ID: uuid.New(), Phone: "+254712345678", PhoneVerified: true, UserType: model.UserTypeDriver},
+		AccessToken: "access_token", RefreshToken: "refresh_token", ExpiresIn: 900,
+	}, nil)
+
+	login := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/phone/login", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, login(`{"phone":"+254712345678","code":"123456"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, login(`{"phone":"+254712345678","code":"123456","user_type":"admin"}`).Code)
+	rr := login(`{"phone":"+254712345678","code":"123456","user_type":"driver"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"email"`)
+}
+
+func TestVerifyPhone_RefusesScopedTokens(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterPhoneRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPost, "/phone/verify", bytes.NewBufferString(`{"phone":"+254712345678","code":"123456"}`))
+	req.Header.Set("X-User-ID", uuid.New().String())
+	req.Header.Set("X-Permissions", "profile:write")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertNotCalled(t, "VerifyPhone", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	h, _ := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	h.RegisterPasskeyRoutes(router)
+	h.RegisterSessionRoutes(router)
+	h.RegisterAPIKeyRoutes(router)
+	h.RegisterMFARoutes(router)
+	h.RegisterSecurityRoutes(router)
+	h.RegisterOrgRoutes(router)
+	h.RegisterDelegationRoutes(router)
+	h.RegisterSSORoutes(router)
+	h.RegisterPhoneRoutes(router)
+	h.RegisterAdminRoutes(router)
+
+	assert.NoError(t, openapi.Check(router, handler.Routes))
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	h, mockService := setupTestHandler()
+	mockService.On("Register", mock.Anything, mock.Anything).Return(&model.LoginResponse{
+		User:        &model.UserResponse{ID: uuid.New(), Email: "test@example.com", UserType: "driver", Status: "active", CreatedAt: time.Now()},
+		AccessToken: "access_token", RefreshToken: "refresh_token", ExpiresIn: 900,
+	}, nil)
+	mockService.On("Login", mock.Anything, mock.Anything).Return(nil, service.ErrInvalidCredentials)
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	router.Use(openapi.Middleware(openapi.Build(router, openapi.Info{Title: "auth-service"}, handler.Routes), func(r *http.Request, err error) {
+		t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+	}))
+
+	for _, r := range []struct{ path, body string }{
+		{"/register", `{"email":"test@example.com","password":"password123","user_type":"driver"}`},
+		{"/login", `{"email":"test@example.com","password":"wrong"}`},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, r.path, bytes.NewBufferString(r.body)))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterMFARoutes registers the second step of a password login and
+// management of the caller's second factor
+func (h *Handler) RegisterMFARoutes(router *mux.Router) {
+	router.HandleFunc("/login/mfa", h.VerifyMFALogin).Methods(http.MethodPost)
+	router.HandleFunc("/login/mfa/enroll", h.BeginMFALoginEnrollment).Methods(http.MethodPost)
+	router.HandleFunc("/mfa", h.GetMFAStatus).Methods(http.MethodGet)
+	router.HandleFunc("/mfa/totp", h.BeginTOTPEnrollment).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/totp/confirm", h.ConfirmTOTPEnrollment).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/totp/disable", h.DisableMFA).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/recovery-codes", h.RegenerateRecoveryCodes).Methods(http.MethodPost)
+}
+
+// VerifyMFALogin completes a login with the MFA token it returned and a
+// TOTP or recovery code
+func (h *Handler) VerifyMFALogin(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.MFALoginRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	result, err := h.service.VerifyMFALogin(clientContext(r), &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, result, requestID)
+}
+
+// BeginMFALoginEnrollment starts TOTP enrolment for a login that must set
+// up a second factor before it can finish
+func (h *Handler) BeginMFALoginEnrollment(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.MFAEnrollRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	enrollment, err := h.service.BeginMFALoginEnrollment(r.Context(), req.MFAToken)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, enrollment, requestID)
+}
+
+// GetMFAStatus describes the authenticated user's second factor
+func (h *Handler) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.mfaOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.GetMFAStatus(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, status, requestID)
+}
+
+// BeginTOTPEnrollment generates a TOTP secret for the authenticated user
+func (h *Handler) BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.mfaOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.service.BeginTOTPEnrollment(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, enrollment, requestID)
+}
+
+// ConfirmTOTPEnrollment enables the authenticated user's TOTP secret and
+// returns their recovery codes
+func (h *Handler) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, func(userID uuid.UUID, code string) (interface{}, error) {
+		return h.service.ConfirmTOTPEnrollment(clientContext(r), userID, code)
+	})
+}
+
+// DisableMFA removes the authenticated user's second factor
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, func(userID uuid.UUID, code string) (interface{}, error) {
+		if err := h.service.DisableMFA(clientContext(r), userID, code); err != nil {
+			return nil, err
+		}
+		return map[string]string{"message": "Second factor disabled"}, nil
+	})
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.withCode(w, r, func(userID uuid.UUID, code string) (interface{}, error) {
+		return h.service.RegenerateRecoveryCodes(clientContext(r), userID, code)
+	})
+}
+
+// AdminResetMFA removes a user's second factor so they can enrol again
+func (h *Handler) AdminResetMFA(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID", "", reqID)
+		return
+	}
+
+	if err := h.service.ResetMFA(r.Context(), userID); err != nil {
+		h.handleError(w, err, reqID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Second factor reset"}, reqID)
+}
+
+// withCode runs an action of the authenticated user that needs a code
+// from their authenticator app
+func (h *Handler) withCode(w http.ResponseWriter, r *http.Request, action func(userID uuid.UUID, code string) (interface{}, error)) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.mfaOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.MFACodeRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	result, err := action(userID, req.Code)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, result, requestID)
+}
+
+// mfaOwner reads the authenticated user, refusing API clients: a second
+// factor is only managed by its user
+func (h *Handler) mfaOwner(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, bool) {
+	if _, ok := r.Header["X-Permissions"]; ok {
+		response.Forbidden(w, "API clients cannot manage a second factor", "", requestID)
+		return uuid.Nil, false
+	}
+	return h.userID(w, r, requestID)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/jwt"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes,
+// RegisterPasskeyRoutes, RegisterSessionRoutes, RegisterAPIKeyRoutes,
+// RegisterMFARoutes, RegisterSecurityRoutes, RegisterOrgRoutes and
+// RegisterAdminRoutes
+var Routes = openapi.Routes{
+	"POST /register":             {Summary: "Register a user", Description: "User types that require a second factor get an MFA token to enrol with in place of the session tokens.", Request: model.RegisterRequest{}, Response: model.LoginResponse{}, Status: http.StatusCreated},
+	"POST /login":                {Summary: "Log in with a password", Description: "Users with a second factor, or whose user type requires one, get an MFA token in place of the session tokens. Repeated failures are answered with 429 and a Retry-After header.", Request: model.LoginRequest{}, Response: model.LoginResponse{}},
+	"POST /refresh":              {Summary: "Exchange a refresh token for new tokens", Request: model.RefreshTokenRequest{}, Response: jwt.TokenPair{}},
+	"POST /change-password":      {Summary: "Change the caller's password", Request: model.ChangePasswordRequest{}, Response: map[string]string{}},
+	"POST /forgot-password":      {Summary: "Send a password reset link", Request: model.ForgotPasswordRequest{}, Response: map[string]string{}},
+	"POST /reset-password":       {Summary: "Reset a password with a reset token", Request: model.ResetPasswordRequest{}, Response: map[string]string{}},
+	"POST /verify-email":         {Summary: "Verify an email address", Request: model.VerifyEmailRequest{}, Response: map[string]string{}},
+	"GET /.well-known/jwks.json": {Summary: "Public keys that verify issued tokens", Response: jwt.JWKS{}, Raw: true},
+	"GET /health":                {Summary: "Health check", Response: map[string]interface{}{}},
+
+	"POST /passkey/register/begin":  {Summary: "Start registering a passkey", Description: "Returns WebAuthn credential creation options."},
+	"POST /passkey/register/finish": {Summary: "Finish registering a passkey", Request: model.FinishPasskeyRegistrationRequest{}, Response: model.PasskeyCredentialResponse{}, Status: http.StatusCreated},
+	"POST /passkey/login/begin":     {Summary: "Start logging in with a passkey", Description: "Returns WebAuthn credential request options.", Request: model.BeginPasskeyLoginRequest{}},
+	"POST /passkey/login/finish":    {Summary: "Finish logging in with a passkey", Request: model.FinishPasskeyLoginRequest{}, Response: model.LoginResponse{}},
+	"GET /passkeys":                 {Summary: "List the caller's passkeys", Response: []model.PasskeyCredentialResponse{}},
+	"DELETE /passkeys/{id}":         {Summary: "Delete a passkey", Response: map[string]string{}},
+
+	"POST /logout":          {Summary: "Log out of the current session", Request: model.LogoutRequest{}, Response: map[string]string{}},
+	"POST /logout/all":      {Summary: "Log out of every session", Response: map[string]string{}},
+	"GET /sessions":         {Summary: "List the caller's sessions", Response: []model.SessionResponse{}},
+	"DELETE /sessions/{id}": {Summary: "Revoke a session", Response: map[string]string{}},
+
+	"POST /api-keys":            {Summary: "Create an API key", Description: "The key is only returned in this response.", Request: model.CreateAPIKeyRequest{}, Response: model.CreatedAPIKey{}, Status: http.StatusCreated},
+	"GET /api-keys":             {Summary: "List the caller's API keys", Response: []model.APIKey{}},
+	"DELETE /api-keys/{id}":     {Summary: "Revoke an API key", Response: map[string]string{}},
+	"GET /api-keys/{id}/events": {Summary: "List an API key's audit trail", Response: []model.APIKeyEvent{}},
+	"POST /api-keys/verify":     {Summary: "Verify an API key", Description: "Called by the API gateway; not routed from outside.", Request: model.VerifyAPIKeyRequest{}, Response: model.APIKeyIdentity{}},
+	"POST /oauth/token": {
+		Summary:     "Issue an access token to an API client",
+		Description: "OAuth 2.0 client credentials grant. The client ID is the API key's ID and the client secret the key. Errors follow RFC 6749.",
+		Request:     model.ClientTokenRequest{},
+		Form:        true,
+		Response:    model.ClientTokenResponse{},
+		Raw:         true,
+	},
+
+	"POST /login/mfa": {
+		Summary:     "Finish a login with a second factor",
+		Description: "Takes a TOTP or recovery code. A login that had to enrol confirms the enrolment with its first TOTP code and gets its recovery codes.",
+		Request:     model.MFALoginRequest{},
+		Response:    model.LoginResponse{},
+	},
+	"POST /login/mfa/enroll":   {Summary: "Start TOTP enrolment during a login that requires it", Request: model.MFAEnrollRequest{}, Response: model.TOTPEnrollment{}},
+	"GET /mfa":                 {Summary: "Describe the caller's second factor", Response: model.MFAStatus{}},
+	"POST /mfa/totp":           {Summary: "Start TOTP enrolment", Description: "The provisioning URI is usually shown as a QR code.", Response: model.TOTPEnrollment{}},
+	"POST /mfa/totp/confirm":   {Summary: "Confirm TOTP enrolment", Description: "The recovery codes are only returned in this response.", Request: model.MFACodeRequest{}, Response: model.RecoveryCodesResponse{}},
+	"POST /mfa/totp/disable":   {Summary: "Disable the caller's second factor", Request: model.MFACodeRequest{}, Response: map[string]string{}},
+	"POST /mfa/recovery-codes": {Summary: "Replace the caller's recovery codes", Request: model.MFACodeRequest{}, Response: model.RecoveryCodesResponse{}},
+
+	"GET /security-events": {Summary: "List the caller's security log", Response: []model.SecurityEvent{}, List: &model.SecurityEventListSpec},
+
+	"POST /orgs":                                   {Summary: "Create an organisation", Description: "The caller becomes its owner.", Request: model.CreateOrganisationRequest{}, Response: model.OrganisationResponse{}, Status: http.StatusCreated},
+	"GET /orgs":                                    {Summary: "List the caller's organisations", Description: "Includes the caller's personal organisation, whose ID is the caller's user ID.", Response: []model.OrganisationResponse{}},
+	"GET /orgs/{id}/members":                       {Summary: "List an organisation's members", Response: []model.Membership{}},
+	"PUT /orgs/{id}/members/{userId}":              {Summary: "Change a member's role", Description: "Owners and admins can change roles; only owners can grant or remove the owner role.", Request: model.UpdateMemberRoleRequest{}, Response: map[string]string{}},
+	"DELETE /orgs/{id}/members/{userId}":           {Summary: "Remove a member", Description: "Members can remove themselves. The last owner cannot leave.", Response: map[string]string{}},
+	"POST /orgs/{id}/invitations":                  {Summary: "Invite someone to an organisation", Description: "The invitation link is emailed and expires after 7 days.", Request: model.InviteMemberRequest{}, Response: model.Invitation{}, Status: http.StatusCreated},
+	"GET /orgs/{id}/invitations":                   {Summary: "List an organisation's pending invitations", Response: []model.Invitation{}},
+	"DELETE /orgs/{id}/invitations/{invitationId}": {Summary: "Revoke an invitation", Response: map[string]string{}},
+	"POST /orgs/{id}/switch":                       {Summary: "Act for an organisation", Description: "Rotates the session of the refresh token; the new tokens carry the organisation and the caller's role in it.", Request: model.SwitchOrganisationRequest{}, Response: jwt.TokenPair{}},
+	"POST /invitations/accept":                     {Summary: "Accept an invitation", Description: "The invitation must have been sent to the caller's email address.", Request: model.AcceptInvitationRequest{}, Response: model.OrganisationResponse{}},
+
+	"POST /delegations":            {Summary: "Delegate rights to a dispatcher", Description: "Shippers and fleet operators can let a dispatcher post jobs, accept bids, assign drivers or send messages for them.", Request: model.CreateDelegationRequest{}, Response: model.Delegation{}, Status: http.StatusCreated},
+	"GET /delegations":             {Summary: "List the delegations the caller granted or was granted", Response: []model.Delegation{}},
+	"DELETE /delegations/{id}":     {Summary: "Revoke a delegation", Description: "Either side can revoke it. Acting-as tokens issued for it stop working at once.", Response: map[string]string{}},
+	"POST /delegations/{id}/token": {Summary: "Issue an acting-as token", Description: "For the dispatcher a delegation was granted to. The token acts for the grantor, limited to the delegated rights, and expires no later than the delegation.", Response: model.ActingTokenResponse{}},
+
+	"POST /sso/start":            {Summary: "Start a single sign-on login", Description: "Looks up the identity provider of the email's domain. Send the user to the returned URL; the provider redirects them back with a state and code.", Request: model.StartSSORequest{}, Response: model.StartSSOResponse{}},
+	"POST /sso/callback":         {Summary: "Finish a single sign-on login", Description: "Exchanges the provider's code for tokens acting for the organisation. Users are provisioned on their first login, with the user type their role claim maps to.", Request: model.SSOCallbackRequest{}, Response: model.LoginResponse{}},
+	"GET /orgs/{id}/sso":         {Summary: "Describe an organisation's identity provider", Description: "The client secret is never returned.", Response: model.IdentityProvider{}},
+	"PUT /orgs/{id}/sso":         {Summary: "Set up single sign-on for an organisation", Description: "Owners only. The issuer must serve an OpenID Connect discovery document. Once the domain is verified, emails in it log in through the provider.", Request: model.IdentityProviderRequest{}, Response: model.IdentityProvider{}},
+	"DELETE /orgs/{id}/sso":      {Summary: "Turn single sign-on off for an organisation", Description: "Owners only. Provisioned users keep their accounts.", Response: map[string]string{}},
+	"POST /orgs/{id}/sso/verify": {Summary: "Verify an organisation's single sign-on domain", Description: "Owners only. Looks for the provider's verification_token in a TXT record at _truckify-challenge.<domain>. Only one organisation can verify a domain.", Response: model.IdentityProvider{}},
+
+	"POST /phone/code":         {Summary: "Text a login code to a phone number", Description: "Codes expire after 10 minutes and take five guesses. A number gets one code a minute and five an hour.", Request: model.PhoneCodeRequest{}, Response: model.PhoneCodeSent{}},
+	"POST /phone/login":        {Summary: "Log in with a texted code", Description: "Numbers without an account register one when user_type is given; without it the response is 404 and the code stays valid. Users with a second factor get an MFA challenge, as with a password.", Request: model.PhoneLoginRequest{}, Response: model.LoginResponse{}},
+	"POST /phone/verification": {Summary: "Text a code that adds a phone number to the caller's account", Request: model.PhoneCodeRequest{}, Response: model.PhoneCodeSent{}},
+	"POST /phone/verify":       {Summary: "Verify the caller's phone number", Description: "The number can then be used to log in. It replaces any number the caller had.", Request: model.VerifyPhoneRequest{}, Response: map[string]string{}},
+
+	"GET /admin/users": {Summary: "List users", Response: []model.User{}, List: &model.UserListSpec},
+	"PUT /admin/users/{id}/status": {
+		Summary: "Set a user's status",
+		Request: struct {
+			Status model.UserStatus `json:"status"`
+		}{},
+		Response: map[string]string{},
+	},
+	"POST /admin/users/{id}/mfa/reset": {Summary: "Remove a user's second factor", Description: "For users who have lost their authenticator and recovery codes.", Response: map[string]string{}},
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterOrgRoutes registers organisation, membership and invitation routes
+func (h *Handler) RegisterOrgRoutes(router *mux.Router) {
+	router.HandleFunc("/orgs", h.CreateOrganisation).Methods(http.MethodPost)
+	router.HandleFunc("/orgs", h.ListOrganisations).Methods(http.MethodGet)
+	router.HandleFunc("/orgs/{id}/members", h.ListMembers).Methods(http.MethodGet)
+	router.HandleFunc("/orgs/{id}/members/{userId}", h.UpdateMemberRole).Methods(http.MethodPut)
+	router.HandleFunc("/orgs/{id}/members/{userId}", h.RemoveMember).Methods(http.MethodDelete)
+	router.HandleFunc("/orgs/{id}/invitations", h.InviteMember).Methods(http.MethodPost)
+	router.HandleFunc("/orgs/{id}/invitations", h.ListInvitations).Methods(http.MethodGet)
+	router.HandleFunc("/orgs/{id}/invitations/{invitationId}", h.RevokeInvitation).Methods(http.MethodDelete)
+	router.HandleFunc("/orgs/{id}/switch", h.SwitchOrganisation).Methods(http.MethodPost)
+	router.HandleFunc("/invitations/accept", h.AcceptInvitation).Methods(http.MethodPost)
+}
+
+// CreateOrganisation creates an organisation owned by the authenticated user
+func (h *Handler) CreateOrganisation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.CreateOrganisationRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	org, err := h.service.CreateOrganisation(r.Context(), userID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Created(w, org, requestID)
+}
+
+// ListOrganisations returns the organisations the authenticated user
+// belongs to
+func (h *Handler) ListOrganisations(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	orgs, err := h.service.ListOrganisations(r.Context(), userID, r.Header.Get("X-Org-ID"))
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, orgs, requestID)
+}
+
+// ListMembers returns the members of one of the authenticated user's
+// organisations
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.ListMembers(r.Context(), userID, orgID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, members, requestID)
+}
+
+// UpdateMemberRole changes the role of a member of the organisation
+func (h *Handler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID", "", requestID)
+		return
+	}
+
+	var req model.UpdateMemberRoleRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	if err := h.service.UpdateMemberRole(r.Context(), userID, orgID, memberID, req.Role); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Member role updated"}, requestID)
+}
+
+// RemoveMember removes a member from the organisation, or lets the
+// authenticated user leave it
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID", "", requestID)
+		return
+	}
+
+	if err := h.service.RemoveMember(r.Context(), userID, orgID, memberID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Member removed"}, requestID)
+}
+
+// InviteMember emails an invitation to join the organisation
+func (h *Handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.InviteMemberRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	inv, err := h.service.InviteMember(r.Context(), userID, orgID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Created(w, inv, requestID)
+}
+
+// ListInvitations returns the organisation's pending invitations
+func (h *Handler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.service.ListInvitations(r.Context(), userID, orgID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, invitations, requestID)
+}
+
+// RevokeInvitation withdraws one of the organisation's pending invitations
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	invitationID, err := uuid.Parse(mux.Vars(r)["invitationId"])
+	if err != nil {
+		response.BadRequest(w, "Invalid invitation ID", "", requestID)
+		return
+	}
+
+	if err := h.service.RevokeInvitation(r.Context(), userID, orgID, invitationID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Invitation revoked"}, requestID)
+}
+
+// AcceptInvitation adds the authenticated user to the organisation of an
+// invitation
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.AcceptInvitationRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	org, err := h.service.AcceptInvitation(r.Context(), userID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, org, requestID)
+}
+
+// SwitchOrganisation moves the caller's session to the organisation and
+// returns tokens that act for it
+func (h *Handler) SwitchOrganisation(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.SwitchOrganisationRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	tokens, err := h.service.SwitchOrganisation(clientContext(r), userID, orgID, req.RefreshToken)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, tokens, requestID)
+}
+
+// orgRequest reads the authenticated user and the organisation in the path
+func (h *Handler) orgRequest(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid organisation ID", "", requestID)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, orgID, true
+}
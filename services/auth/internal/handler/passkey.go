@@ -65,7 +65,7 @@ func (h *Handler) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	cred, err := h.service.FinishPasskeyRegistration(r.Context(), userID, req.Name, req.Response)
+	cred, err := h.service.FinishPasskeyRegistration(clientContext(r), userID, req.Name, req.Response)
 	if err != nil {
 		h.handlePasskeyError(w, err, requestID)
 		return
@@ -105,7 +105,7 @@ func (h *Handler) FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.FinishPasskeyLogin(r.Context(), req.Email, req.Response)
+	result, err := h.service.FinishPasskeyLogin(clientContext(r), req.Email, req.Response)
 	if err != nil {
 		h.handlePasskeyError(w, err, requestID)
 		return
@@ -169,7 +169,7 @@ func (h *Handler) DeletePasskey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeletePasskey(r.Context(), userID, passkeyID); err != nil {
+	if err := h.service.DeletePasskey(clientContext(r), userID, passkeyID); err != nil {
 		h.handleError(w, err, requestID)
 		return
 	}
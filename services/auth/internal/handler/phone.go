@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterPhoneRoutes registers phone number logins with SMS codes and the
+// verification of users' phone numbers
+func (h *Handler) RegisterPhoneRoutes(router *mux.Router) {
+	router.HandleFunc("/phone/code", h.SendLoginCode).Methods(http.MethodPost)
+	router.HandleFunc("/phone/login", h.PhoneLogin).Methods(http.MethodPost)
+	router.HandleFunc("/phone/verification", h.SendPhoneVerificationCode).Methods(http.MethodPost)
+	router.HandleFunc("/phone/verify", h.VerifyPhone).Methods(http.MethodPost)
+}
+
+// SendLoginCode texts a login code to a phone number
+func (h *Handler) SendLoginCode(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.PhoneCodeRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	sent, err := h.service.SendLoginCode(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, sent, requestID)
+}
+
+// PhoneLogin logs in, or registers, with a code texted to a phone number
+func (h *Handler) PhoneLogin(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req model.PhoneLoginRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	result, err := h.service.PhoneLogin(clientContext(r), &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, result, requestID)
+}
+
+// SendPhoneVerificationCode texts a code that adds a phone number to the
+// authenticated user's account
+func (h *Handler) SendPhoneVerificationCode(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.phoneOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.PhoneCodeRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	sent, err := h.service.SendPhoneVerificationCode(r.Context(), userID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, sent, requestID)
+}
+
+// VerifyPhone confirms the authenticated user's phone number with the code
+// texted to it
+func (h *Handler) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.phoneOwner(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.VerifyPhoneRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	if err := h.service.VerifyPhone(clientContext(r), userID, &req); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Phone number verified"}, requestID)
+}
+
+// phoneOwner reads the authenticated user whose phone number is verified.
+// A verified number logs in to the account, so API clients and dispatchers
+// acting for the user cannot add one.
+func (h *Handler) phoneOwner(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, bool) {
+	if _, ok := r.Header["X-Permissions"]; ok {
+		response.Forbidden(w, "Scoped tokens cannot change phone numbers", "", requestID)
+		return uuid.Nil, false
+	}
+	return h.userID(w, r, requestID)
+}
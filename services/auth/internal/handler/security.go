@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterSecurityRoutes registers the caller's security log
+func (h *Handler) RegisterSecurityRoutes(router *mux.Router) {
+	router.HandleFunc("/security-events", h.ListSecurityEvents).Methods(http.MethodGet)
+}
+
+// ListSecurityEvents returns a page of the authenticated user's security log
+func (h *Handler) ListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	if _, ok := r.Header["X-Permissions"]; ok {
+		response.Forbidden(w, "API clients cannot read the security log", "", requestID)
+		return
+	}
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query(), model.SecurityEventListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), requestID)
+		return
+	}
+
+	events, next, err := h.service.ListSecurityEvents(r.Context(), userID, page)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.List(w, events, next, requestID)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterSessionRoutes registers logout and session management routes
+func (h *Handler) RegisterSessionRoutes(router *mux.Router) {
+	router.HandleFunc("/logout", h.Logout).Methods(http.MethodPost)
+	router.HandleFunc("/logout/all", h.LogoutAll).Methods(http.MethodPost)
+	router.HandleFunc("/sessions", h.ListSessions).Methods(http.MethodGet)
+	router.HandleFunc("/sessions/{id}", h.RevokeSession).Methods(http.MethodDelete)
+}
+
+// Logout revokes the session of the given refresh token
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.LogoutRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), req.RefreshToken); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Logged out successfully"}, requestID)
+}
+
+// LogoutAll revokes every session of the authenticated user
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.LogoutAll(r.Context(), userID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Logged out of all sessions"}, requestID)
+}
+
+// ListSessions returns the authenticated user's active sessions
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.service.ListSessions(r.Context(), userID, r.Header.Get("X-Session-ID"))
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, sessions, requestID)
+}
+
+// RevokeSession revokes one of the authenticated user's sessions
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, ok := h.userID(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid session ID", "", requestID)
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Session revoked"}, requestID)
+}
+
+// userID reads the authenticated user set by the API gateway
+func (h *Handler) userID(w http.ResponseWriter, r *http.Request, requestID string) (uuid.UUID, bool) {
+	userIDStr := r.Header.Get("X-User-ID")
+	if userIDStr == "" {
+		response.Unauthorized(w, "User not authenticated", "", requestID)
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID", "", requestID)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// clientContext returns the request context carrying the caller's device
+// details for session tracking and the security log
+func clientContext(r *http.Request) context.Context {
+	return service.WithClientInfo(r.Context(), model.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: middleware.ForwardedClientIP(r),
+	})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterSSORoutes registers single sign-on logins and the identity
+// provider configuration of organisations
+func (h *Handler) RegisterSSORoutes(router *mux.Router) {
+	router.HandleFunc("/sso/start", h.StartSSO).Methods(http.MethodPost)
+	router.HandleFunc("/sso/callback", h.FinishSSO).Methods(http.MethodPost)
+	router.HandleFunc("/orgs/{id}/sso", h.GetIdentityProvider).Methods(http.MethodGet)
+	router.HandleFunc("/orgs/{id}/sso", h.SaveIdentityProvider).Methods(http.MethodPut)
+	router.HandleFunc("/orgs/{id}/sso", h.DeleteIdentityProvider).Methods(http.MethodDelete)
+	router.HandleFunc("/orgs/{id}/sso/verify", h.VerifyIdentityProviderDomain).Methods(http.MethodPost)
+}
+
+// StartSSO starts a single sign-on login for the email's organisation
+func (h *Handler) StartSSO(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	var req model.StartSSORequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	result, err := h.service.StartSSO(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, result, requestID)
+}
+
+// FinishSSO finishes a single sign-on login with the state and code the
+// identity provider redirected back with
+func (h *Handler) FinishSSO(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req model.SSOCallbackRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	result, err := h.service.FinishSSO(clientContext(r), &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, result, requestID)
+}
+
+// GetIdentityProvider returns the organisation's identity provider
+func (h *Handler) GetIdentityProvider(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	idp, err := h.service.GetIdentityProvider(r.Context(), userID, orgID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, idp, requestID)
+}
+
+// SaveIdentityProvider sets up or replaces the organisation's identity
+// provider
+func (h *Handler) SaveIdentityProvider(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	var req model.IdentityProviderRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), requestID)
+		return
+	}
+
+	idp, err := h.service.SaveIdentityProvider(r.Context(), userID, orgID, &req)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, idp, requestID)
+}
+
+// VerifyIdentityProviderDomain checks the DNS record that proves the
+// organisation controls its identity provider's domain
+func (h *Handler) VerifyIdentityProviderDomain(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	idp, err := h.service.VerifyIdentityProviderDomain(r.Context(), userID, orgID)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, idp, requestID)
+}
+
+// DeleteIdentityProvider turns single sign-on off for the organisation
+func (h *Handler) DeleteIdentityProvider(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := r.Context().Value("request_id").(string)
+
+	userID, orgID, ok := h.orgRequest(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteIdentityProvider(r.Context(), userID, orgID); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response.Success(w, map[string]string{"message": "Single sign-on disabled"}, requestID)
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, so that keys can be told apart from
+// access tokens and spotted by secret scanners
+const APIKeyPrefix = "tfy_"
+
+// Events recorded in an API key's audit trail
+const (
+	APIKeyEventCreated     = "created"
+	APIKeyEventRevoked     = "revoked"
+	APIKeyEventTokenIssued = "token_issued"
+	APIKeyEventRejected    = "rejected"
+	APIKeyEventNewAddress  = "new_address"
+)
+
+// APIKey lets an integration act for the user who created it, limited to
+// the key's scopes. Scopes are authz permission names such as job:create.
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	// Prefix is the start of the key, shown so users can tell keys apart
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"-"`
+	Scopes  []string `json:"scopes"`
+	// RateLimit is the key's requests per minute; zero leaves the
+	// gateway's limits in place
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Active reports whether the key can still be used
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreatedAPIKey is a new key together with its secret, which is only
+// returned once
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyEvent is an entry in an API key's audit trail
+type APIKeyEvent struct {
+	ID        uuid.UUID `json:"id"`
+	APIKeyID  uuid.UUID `json:"api_key_id"`
+	Event     string    `json:"event"`
+	IPAddress string    `json:"ip_address"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAPIKeyRequest represents a request to issue an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	RateLimit     int      `json:"rate_limit" validate:"omitempty,min=1,max=6000"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=730"`
+}
+
+// VerifyAPIKeyRequest is sent by the API gateway to authenticate a request
+// made with an API key
+type VerifyAPIKeyRequest struct {
+	Key       string `json:"key" validate:"required"`
+	IPAddress string `json:"ip_address"`
+}
+
+// APIKeyIdentity is who a verified API key acts as
+type APIKeyIdentity struct {
+	KeyID     uuid.UUID `json:"key_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	UserType  UserType  `json:"user_type"`
+	Scopes    []string  `json:"scopes"`
+	RateLimit int       `json:"rate_limit"`
+}
+
+// ClientTokenRequest is the form of an OAuth 2.0 client credentials
+// request. The client may send its credentials with HTTP Basic
+// authentication instead of in the form.
+type ClientTokenRequest struct {
+	GrantType    string `json:"grant_type" validate:"required,eq=client_credentials"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope"`
+}
+
+// ClientTokenResponse is the OAuth 2.0 token response of the client
+// credentials grant
+type ClientTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthError is an OAuth 2.0 error response
+type OAuthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delegation lets a dispatcher act for a shipper or fleet operator, limited
+// to the rights they were delegated. Rights are authz right names such as
+// post_jobs.
+type Delegation struct {
+	ID           uuid.UUID  `json:"id"`
+	GrantorID    uuid.UUID  `json:"grantor_id"`
+	DispatcherID uuid.UUID  `json:"dispatcher_id"`
+	Rights       []string   `json:"rights"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"-"`
+}
+
+// Active reports whether the delegation can still be used
+func (d *Delegation) Active(now time.Time) bool {
+	return d.RevokedAt == nil && (d.ExpiresAt == nil || now.Before(*d.ExpiresAt))
+}
+
+// CreateDelegationRequest represents a request to delegate rights to a
+// dispatcher
+type CreateDelegationRequest struct {
+	DispatcherEmail string   `json:"dispatcher_email" validate:"required,email"`
+	Rights          []string `json:"rights" validate:"required,min=1,dive,oneof=post_jobs accept_bids assign_drivers message"`
+	ExpiresInDays   int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// ActingTokenResponse is an access token with which a dispatcher acts for
+// the user who delegated to them
+type ActingTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresIn   int64     `json:"expires_in"`
+	Scope       string    `json:"scope"`
+	ActingAs    uuid.UUID `json:"acting_as"`
+}
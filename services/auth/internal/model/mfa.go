@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFA is a user's TOTP second factor. It is pending until the first code
+// from the authenticator app has been confirmed.
+type MFA struct {
+	UserID       uuid.UUID
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// Enabled reports whether the second factor has been confirmed
+func (m *MFA) Enabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code. Only a
+// hash is kept.
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge is a login waiting on its second factor
+type MFAChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAPending is returned by a login that still needs a second factor, in
+// place of the session tokens
+type MFAPending struct {
+	// Token is exchanged with a TOTP or recovery code at /login/mfa
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+	// EnrollmentRequired is set when the user's type requires a second
+	// factor and none is set up yet; it is enrolled with the token first
+	EnrollmentRequired bool `json:"enrollment_required"`
+}
+
+// MFAStatus describes the caller's second factor
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrollment is what an authenticator app needs to add the account.
+// The provisioning URI is usually shown as a QR code.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse holds newly generated recovery codes, which are
+// only returned once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFACodeRequest carries a TOTP code, or a recovery code where one is
+// accepted
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFALoginRequest completes a login with a second factor
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAEnrollRequest starts TOTP enrolment during a login that requires it
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
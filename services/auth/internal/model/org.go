@@ -0,0 +1,96 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgRole is a member's role within an organisation
+type OrgRole string
+
+const (
+	// OrgRoleOwner can do everything an admin can and manage other owners
+	OrgRoleOwner OrgRole = "owner"
+	// OrgRoleAdmin invites, removes and changes the role of members
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleMember OrgRole = "member"
+)
+
+// ManagesMembers reports whether the role can invite and manage members
+func (r OrgRole) ManagesMembers() bool {
+	return r == OrgRoleOwner || r == OrgRoleAdmin
+}
+
+// InvitationTTL is how long an invitation can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+// Organisation owns jobs, fleets and payments on behalf of its members.
+// Every user has a personal organisation with the same ID as the user,
+// which is the one their sessions act for until they switch.
+type Organisation struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership is a user's place in an organisation
+type Membership struct {
+	OrgID     uuid.UUID `json:"org_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Role      OrgRole   `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Invitation asks someone to join an organisation. Only the hash of its
+// token is kept; the token itself is emailed to the invitee.
+type Invitation struct {
+	ID         uuid.UUID  `json:"id"`
+	OrgID      uuid.UUID  `json:"org_id"`
+	Email      string     `json:"email"`
+	Role       OrgRole    `json:"role"`
+	TokenHash  string     `json:"-"`
+	InvitedBy  uuid.UUID  `json:"invited_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// OrganisationResponse is an organisation the caller belongs to
+type OrganisationResponse struct {
+	Organisation
+	Role OrgRole `json:"role"`
+	// Current marks the organisation the caller's session acts for
+	Current bool `json:"current"`
+}
+
+// CreateOrganisationRequest represents a request to create an organisation
+type CreateOrganisationRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// InviteMemberRequest represents a request to invite someone to an
+// organisation
+type InviteMemberRequest struct {
+	Email string  `json:"email" validate:"required,email"`
+	Role  OrgRole `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// AcceptInvitationRequest represents a request to join an organisation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// UpdateMemberRoleRequest represents a request to change a member's role
+type UpdateMemberRoleRequest struct {
+	Role OrgRole `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// SwitchOrganisationRequest represents a request to act for another
+// organisation in the session of the refresh token
+type SwitchOrganisationRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
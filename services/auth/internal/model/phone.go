@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneCodePurpose says what an SMS code is for
+type PhoneCodePurpose string
+
+const (
+	// PhoneCodeLogin logs in, or registers, whoever holds the number
+	PhoneCodeLogin PhoneCodePurpose = "login"
+	// PhoneCodeLink verifies a number for the account that asked for it
+	PhoneCodeLink PhoneCodePurpose = "link"
+)
+
+// PhoneCode is a one-time code sent to a phone number by SMS. Only a hash
+// is kept.
+type PhoneCode struct {
+	ID      uuid.UUID
+	Phone   string
+	Purpose PhoneCodePurpose
+	// UserID is the account a linking code verifies the number for
+	UserID    *uuid.UUID
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// PhoneCodeRequest asks for a code to be sent to a phone number
+type PhoneCodeRequest struct {
+	Phone string `json:"phone" validate:"required,e164"`
+}
+
+// PhoneCodeSent tells the client how long the code is valid and when
+// another can be sent
+type PhoneCodeSent struct {
+	ExpiresIn int64 `json:"expires_in"`
+	ResendIn  int64 `json:"resend_in"`
+}
+
+// PhoneLoginRequest logs in with a code sent to the phone number. Numbers
+// without an account register one, which needs UserType.
+type PhoneLoginRequest struct {
+	Phone    string   `json:"phone" validate:"required,e164"`
+	Code     string   `json:"code" validate:"required,len=6,numeric"`
+	UserType UserType `json:"user_type,omitempty" validate:"omitempty,oneof=shipper driver fleet_operator dispatcher"`
+}
+
+// VerifyPhoneRequest confirms the number a linking code was sent to
+type VerifyPhoneRequest struct {
+	Phone string `json:"phone" validate:"required,e164"`
+	Code  string `json:"code" validate:"required,len=6,numeric"`
+}
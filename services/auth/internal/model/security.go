@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
+)
+
+// Security event types
+const (
+	SecurityLoginSucceeded           = "login_succeeded"
+	SecurityLoginFailed              = "login_failed"
+	SecurityAccountLocked            = "account_locked"
+	SecurityNewDeviceLogin           = "new_device_login"
+	SecurityPasswordChanged          = "password_changed"
+	SecurityPasskeyAdded             = "passkey_added"
+	SecurityPasskeyRemoved           = "passkey_removed"
+	SecurityMFAEnabled               = "mfa_enabled"
+	SecurityMFADisabled              = "mfa_disabled"
+	SecurityMFAReset                 = "mfa_reset"
+	SecurityRecoveryCodeUsed         = "recovery_code_used"
+	SecurityRecoveryCodesRegenerated = "recovery_codes_regenerated"
+	SecurityRefreshTokenReused       = "refresh_token_reused"
+	SecurityDelegationGranted        = "delegation_granted"
+	SecurityDelegationRevoked        = "delegation_revoked"
+	SecurityActingTokenIssued        = "acting_token_issued"
+	SecuritySSOLinked                = "sso_linked"
+	SecurityPhoneVerified            = "phone_verified"
+)
+
+// SecurityEvent is an entry in a user's security log
+type SecurityEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Type      string    `json:"type"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+	// ActorID is the dispatcher who acted on the user's behalf, if any
+	ActorID *uuid.UUID `json:"actor_id,omitempty"`
+}
+
+// SecurityEventListSpec is what users can filter and sort their security
+// log by
+var SecurityEventListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"type":       {Column: "event_type"},
+		"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a SecurityEventListSpec sort field and the
+// event's ID
+func (e SecurityEvent) SortKey(field string) (interface{}, uuid.UUID) {
+	return e.CreatedAt, e.ID
+}
+
+// LoginThrottle counts the failed logins of an account or client IP
+type LoginThrottle struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a session was revoked
+const (
+	RevokedLogout     = "logout"
+	RevokedLogoutAll  = "logout_all"
+	RevokedByUser     = "revoked_by_user"
+	RevokedTokenReuse = "refresh_token_reuse"
+)
+
+// Session is a refresh token family started by a login. Only the latest
+// refresh and access token IDs are kept.
+type Session struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	RefreshTokenID  string     `json:"-"`
+	AccessTokenID   string     `json:"-"`
+	AccessExpiresAt time.Time  `json:"-"`
+	UserAgent       string     `json:"user_agent"`
+	IPAddress       string     `json:"ip_address"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      time.Time  `json:"last_used_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason   *string    `json:"revoked_reason,omitempty"`
+	// OrgID is the organisation the session acts for; uuid.Nil stands for
+	// the user's personal organisation
+	OrgID uuid.UUID `json:"-"`
+}
+
+// ClientInfo describes the device a session was started from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// SessionResponse represents an active session in API responses
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// ToSessionResponse converts a Session to SessionResponse
+func (s *Session) ToSessionResponse(currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID.String() == currentID,
+	}
+}
+
+// LogoutRequest represents a logout request
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
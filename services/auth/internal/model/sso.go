@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SSOLoginTTL is how long a single sign-on login started at the identity
+// provider can be finished
+const SSOLoginTTL = 10 * time.Minute
+
+// DomainChallengePrefix is prepended to an identity provider's domain to
+// name the TXT record that proves the organisation controls it
+const DomainChallengePrefix = "_truckify-challenge."
+
+// IdentityProvider is an organisation's OpenID Connect identity provider.
+// Once the organisation proves it controls the domain, users with an email
+// in it log in through the provider, and are provisioned as members of the
+// organisation the first time they do.
+type IdentityProvider struct {
+	OrgID    uuid.UUID `json:"org_id"`
+	Issuer   string    `json:"issuer"`
+	ClientID string    `json:"client_id"`
+	// ClientSecret authenticates Truckify to the provider; it is never
+	// returned
+	ClientSecret string `json:"-"`
+	Domain       string `json:"domain"`
+	// VerificationToken is published as a TXT record at
+	// DomainChallengePrefix + Domain to verify the domain
+	VerificationToken string     `json:"verification_token"`
+	DomainVerifiedAt  *time.Time `json:"domain_verified_at,omitempty"`
+	// RoleClaim names the ID token claim whose values RoleMapping maps to a
+	// user type for new users. Users matching no value get DefaultUserType.
+	RoleClaim       string              `json:"role_claim"`
+	RoleMapping     map[string]UserType `json:"role_mapping"`
+	DefaultUserType UserType            `json:"default_user_type"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// DomainVerified reports whether the organisation has proved it controls
+// the provider's domain
+func (p *IdentityProvider) DomainVerified() bool {
+	return p.DomainVerifiedAt != nil
+}
+
+// UserTypeFor returns the user type of a new user whose role claim has the
+// given values: the first value that is mapped, or the default
+func (p *IdentityProvider) UserTypeFor(values []string) UserType {
+	for _, v := range values {
+		if userType, ok := p.RoleMapping[v]; ok {
+			return userType
+		}
+	}
+	return p.DefaultUserType
+}
+
+// IdentityProviderRequest configures an organisation's identity provider
+type IdentityProviderRequest struct {
+	Issuer          string              `json:"issuer" validate:"required,url,max=255"`
+	ClientID        string              `json:"client_id" validate:"required,max=255"`
+	ClientSecret    string              `json:"client_secret" validate:"required,max=255"`
+	Domain          string              `json:"domain" validate:"required,fqdn,max=255"`
+	RoleClaim       string              `json:"role_claim" validate:"max=100"`
+	RoleMapping     map[string]UserType `json:"role_mapping" validate:"dive,keys,required,endkeys,oneof=shipper driver fleet_operator dispatcher"`
+	DefaultUserType UserType            `json:"default_user_type" validate:"required,oneof=shipper driver fleet_operator dispatcher"`
+}
+
+// SSOLogin is a login waiting on the identity provider. It is found by the
+// hash of its state; the nonce and PKCE code verifier never leave the
+// service.
+type SSOLogin struct {
+	StateHash    string
+	OrgID        uuid.UUID
+	Nonce        string
+	CodeVerifier string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// SSOIdentity links a user to their subject at an organisation's identity
+// provider
+type SSOIdentity struct {
+	OrgID     uuid.UUID
+	Subject   string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+// StartSSORequest starts a single sign-on login for the email's domain
+type StartSSORequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// StartSSOResponse is where to send the user to log in
+type StartSSOResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	ExpiresIn        int64  `json:"expires_in"`
+}
+
+// SSOCallbackRequest finishes a single sign-on login with what the identity
+// provider redirected back with
+type SSOCallbackRequest struct {
+	State string `json:"state" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
 // UserType represents the type of user
@@ -14,6 +15,9 @@ const (
 	UserTypeDriver     UserType = "driver"
 	UserTypeFleet      UserType = "fleet_operator"
 	UserTypeDispatcher UserType = "dispatcher"
+	// Staff roles are provisioned directly and cannot self-register
+	UserTypeAdmin   UserType = "admin"
+	UserTypeSupport UserType = "support"
 )
 
 // UserStatus represents the status of a user account
@@ -33,6 +37,10 @@ type User struct {
 	UserType       UserType   `db:"user_type" json:"user_type"`
 	Status         UserStatus `db:"status" json:"status"`
 	EmailVerified  bool       `db:"email_verified" json:"email_verified"`
+	// Phone is in E.164; empty when the user has none. Accounts registered
+	// by phone have no email.
+	Phone          string     `db:"phone" json:"phone,omitempty"`
+	PhoneVerified  bool       `db:"phone_verified" json:"phone_verified"`
 	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
 	LastLoginAt    *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
@@ -41,11 +49,33 @@ type User struct {
 	ResetTokenExpiry *time.Time `db:"reset_token_expiry" json:"-"`
 }
 
-// RegisterRequest represents a user registration request
+// UserListSpec is what admins can filter and sort the user list by
+var UserListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"user_type":  {Column: "user_type"},
+		"status":     {Column: "status"},
+		"email":      {Column: "COALESCE(email, '')", Sortable: true},
+		"phone":      {Column: "phone"},
+		"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a UserListSpec sort field and the user's ID
+func (u User) SortKey(field string) (interface{}, uuid.UUID) {
+	if field == "email" {
+		return u.Email, u.ID
+	}
+	return u.CreatedAt, u.ID
+}
+
+// RegisterRequest represents a user registration request. A phone number
+// given here stays unverified until confirmed with an SMS code.
 type RegisterRequest struct {
 	Email    string   `json:"email" validate:"required,email"`
 	Password string   `json:"password" validate:"required,min=8"`
 	UserType UserType `json:"user_type" validate:"required,oneof=shipper driver fleet_operator dispatcher"`
+	Phone    string   `json:"phone,omitempty" validate:"omitempty,e164"`
 }
 
 // LoginRequest represents a user login request
@@ -54,21 +84,27 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse represents a login response
+// LoginResponse represents a login response. A login that still needs a
+// second factor carries only MFA.
 type LoginResponse struct {
-	User         *UserResponse `json:"user"`
-	AccessToken  string        `json:"access_token"`
-	RefreshToken string        `json:"refresh_token"`
-	ExpiresIn    int64         `json:"expires_in"`
+	User         *UserResponse `json:"user,omitempty"`
+	AccessToken  string        `json:"access_token,omitempty"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	ExpiresIn    int64         `json:"expires_in,omitempty"`
+	MFA          *MFAPending   `json:"mfa,omitempty"`
+	// RecoveryCodes is set when the login enrolled a second factor
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 // UserResponse represents a user in API responses
 type UserResponse struct {
 	ID            uuid.UUID  `json:"id"`
-	Email         string     `json:"email"`
+	Email         string     `json:"email,omitempty"`
 	UserType      UserType   `json:"user_type"`
 	Status        UserStatus `json:"status"`
 	EmailVerified bool       `json:"email_verified"`
+	Phone         string     `json:"phone,omitempty"`
+	PhoneVerified bool       `json:"phone_verified"`
 	CreatedAt     time.Time  `json:"created_at"`
 }
 
@@ -107,6 +143,8 @@ func (u *User) ToUserResponse() *UserResponse {
 		UserType:      u.UserType,
 		Status:        u.Status,
 		EmailVerified: u.EmailVerified,
+		Phone:         u.Phone,
+		PhoneVerified: u.PhoneVerified,
 		CreatedAt:     u.CreatedAt,
 	}
 }
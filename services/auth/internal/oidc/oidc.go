@@ -0,0 +1,315 @@
+// Package oidc implements the relying party side of OpenID Connect: provider
+// discovery, the authorization code flow with PKCE (RFC 7636) and ID token
+// verification against the provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"truckify/shared/pkg/jwt"
+	"truckify/shared/pkg/safehttp"
+)
+
+var (
+	// ErrDiscovery is returned when a provider's configuration cannot be
+	// fetched or does not describe the expected issuer
+	ErrDiscovery = errors.New("oidc discovery failed")
+	// ErrExchange is returned when the provider refuses an authorization code
+	ErrExchange = errors.New("oidc code exchange failed")
+	// ErrInvalidIDToken is returned for ID tokens that fail verification
+	ErrInvalidIDToken = errors.New("invalid ID token")
+	// ErrInvalidIssuer is returned for issuers that are not https URLs on a
+	// public address
+	ErrInvalidIssuer = errors.New("invalid issuer")
+)
+
+// Scopes are requested from every provider
+const Scopes = "openid email profile"
+
+// keySetTTL is how long a provider's signing keys are cached
+const keySetTTL = time.Hour
+
+// Provider is the part of a provider's discovery document the relying party
+// uses
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken is a verified ID token
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Claims holds every claim of the token, for mapping provider-specific
+	// claims such as groups
+	Claims map[string]interface{}
+}
+
+// Client talks to OpenID providers. Discovery documents and signing keys
+// are cached per issuer.
+type Client struct {
+	http         *http.Client
+	allowPrivate bool
+
+	mu        sync.Mutex
+	providers map[string]*Provider
+	keys      map[string]*jwt.RemoteKeySet
+}
+
+// NewClient creates a client for providers on the internet. Issuers and the
+// endpoints they publish must use https, and requests to private, loopback
+// and link-local addresses are refused, so that an organisation cannot point
+// the service at the platform's own network. allowPrivate lifts both
+// restrictions, for local testing only.
+func NewClient(allowPrivate bool) *Client {
+	httpClient := safehttp.NewClient(10 * time.Second)
+	if allowPrivate {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		http:         httpClient,
+		allowPrivate: allowPrivate,
+		providers:    map[string]*Provider{},
+		keys:         map[string]*jwt.RemoteKeySet{},
+	}
+}
+
+// checkURL accepts https URLs on public hosts, or any http(s) URL when
+// private providers are allowed
+func (c *Client) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidIssuer, raw)
+	}
+	if c.allowPrivate {
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return fmt.Errorf("%w: %q is not an http URL", ErrInvalidIssuer, raw)
+		}
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: %q must use https", ErrInvalidIssuer, raw)
+	}
+	if safehttp.PrivateHost(u.Hostname()) {
+		return fmt.Errorf("%w: %q is a private address", ErrInvalidIssuer, raw)
+	}
+	return nil
+}
+
+// Discover returns the configuration the issuer publishes at
+// /.well-known/openid-configuration
+func (c *Client) Discover(ctx context.Context, issuer string) (*Provider, error) {
+	c.mu.Lock()
+	p, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+	if err := c.checkURL(issuer); err != nil {
+		return nil, err
+	}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrDiscovery, resp.StatusCode)
+	}
+
+	p = &Provider{}
+	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	// The issuer must be exactly the one configured (OIDC Discovery §4.3)
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, p.Issuer, issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider configuration", ErrDiscovery)
+	}
+	for _, endpoint := range []string{p.AuthorizationEndpoint, p.TokenEndpoint, p.JWKSURI} {
+		if err := c.checkURL(endpoint); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+		}
+	}
+
+	c.mu.Lock()
+	c.providers[issuer] = p
+	c.mu.Unlock()
+	return p, nil
+}
+
+// AuthCodeURL returns where to send the user to log in at the provider.
+// The provider sends them back to redirectURI with the code and state.
+func (p *Provider) AuthCodeURL(clientID, redirectURI, state, nonce, codeChallenge string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {Scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange redeems an authorization code for the provider's ID token. The
+// client authenticates with HTTP Basic (client_secret_basic).
+func (c *Client) Exchange(ctx context.Context, p *Provider, clientID, clientSecret, code, redirectURI, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("%w: status %d", ErrExchange, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s %s", ErrExchange, result.Error, result.ErrorDescription)
+	}
+	if result.IDToken == "" {
+		return "", fmt.Errorf("%w: no ID token in response", ErrExchange)
+	}
+	return result.IDToken, nil
+}
+
+// Verify checks the ID token's signature against the provider's keys, that
+// it was issued by the provider to clientID and has not expired, and that
+// it carries the nonce of the login it answers
+func (c *Client) Verify(p *Provider, clientID, rawIDToken, nonce string) (*IDToken, error) {
+	keys := c.keySet(p.JWKSURI)
+	claims := gojwt.MapClaims{}
+	_, err := gojwt.ParseWithClaims(rawIDToken, claims, func(token *gojwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	},
+		gojwt.WithValidMethods([]string{jwt.AlgorithmRS256, jwt.AlgorithmEdDSA}),
+		gojwt.WithIssuer(p.Issuer),
+		gojwt.WithAudience(clientID),
+		gojwt.WithExpirationRequired(),
+		gojwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	token := &IDToken{Subject: sub, Claims: claims}
+	token.Email, _ = claims["email"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		token.EmailVerified = v
+	case string:
+		// Some providers send the flag as a string
+		token.EmailVerified = v == "true"
+	}
+	return token, nil
+}
+
+func (c *Client) keySet(jwksURI string) *jwt.RemoteKeySet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys, ok := c.keys[jwksURI]
+	if !ok {
+		keys = jwt.NewRemoteKeySet(jwksURI, keySetTTL)
+		keys.SetClient(c.http)
+		c.keys[jwksURI] = keys
+	}
+	return keys
+}
+
+// ClaimValues returns a claim as a list of strings, whether the provider
+// sends it as a single string or an array, as it commonly does for groups
+func (t *IDToken) ClaimValues(name string) []string {
+	switch v := t.Claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// RandomString returns a random URL-safe string carrying 256 bits, for
+// states, nonces and PKCE code verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of a code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"truckify/services/auth/internal/oidc"
+	"truckify/services/auth/internal/oidc/oidctest"
+)
+
+const redirectURI = "https://app.truckify.example/sso/callback"
+
+func TestCodeFlow(t *testing.T) {
+	idp := oidctest.NewProvider("truckify", "s3cret/+")
+	defer idp.Close()
+	client := oidc.NewClient(true)
+	ctx := context.Background()
+
+	p, err := client.Discover(ctx, idp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, _ := oidc.RandomString()
+	authURL := p.AuthCodeURL(idp.ClientID, redirectURI, "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	code, state, err := idp.Login(authURL, map[string]interface{}{
+		"sub":            "00u1",
+		"email":          "ops@acme.example",
+		"email_verified": true,
+		"groups":         []string{"logistics", "truckify-dispatch"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	// The code cannot be redeemed without the PKCE verifier
+	if _, err := client.Exchange(ctx, p, idp.ClientID, idp.ClientSecret, code, redirectURI, "wrong"); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("exchange with wrong verifier: err = %v, want ErrExchange", err)
+	}
+
+	code, _, _ = idp.Login(authURL, map[string]interface{}{"sub": "00u1", "email": "ops@acme.example", "email_verified": true,
+		"groups": []string{"logistics", "truckify-dispatch"}})
+	raw, err := client.Exchange(ctx, p, idp.ClientID, idp.ClientSecret, code, redirectURI, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := client.Verify(p, idp.ClientID, raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Subject != "00u1" || token.Email != "ops@acme.example" || !token.EmailVerified {
+		t.Errorf("token = %+v", token)
+	}
+	if groups := token.ClaimValues("groups"); len(groups) != 2 || groups[1] != "truckify-dispatch" {
+		t.Errorf("groups = %v", groups)
+	}
+
+	// A token answering another login is refused
+	if _, err := client.Verify(p, idp.ClientID, raw, "nonce-2"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("wrong nonce: err = %v, want ErrInvalidIDToken", err)
+	}
+	// So is one issued to another client
+	if _, err := client.Verify(p, "someone-else", raw, "nonce-1"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("wrong audience: err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	idp := oidctest.NewProvider("truckify", "secret")
+	defer idp.Close()
+
+	if _, err := oidc.NewClient(true).Discover(context.Background(), idp.URL+"/"); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Errorf("err = %v, want ErrDiscovery", err)
+	}
+}
+
+func TestDiscover_RefusesPrivateIssuers(t *testing.T) {
+	client := oidc.NewClient(false)
+	for _, issuer := range []string{
+		"http://idp.example",
+		"https://127.0.0.1:8443",
+		"https://169.254.169.254/latest",
+		"https://localhost",
+		"https://[::1]",
+		"idp.example",
+	} {
+		if _, err := client.Discover(context.Background(), issuer); !errors.Is(err, oidc.ErrInvalidIssuer) {
+			t.Errorf("Discover(%s) err = %v, want ErrInvalidIssuer", issuer, err)
+		}
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p := &oidc.Provider{AuthorizationEndpoint: "https://idp.example/authorize?tenant=acme"}
+	u, err := url.Parse(p.AuthCodeURL("client", redirectURI, "st", "no", oidc.CodeChallenge("verifier")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("tenant") != "acme" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != oidc.Scopes {
+		t.Errorf("query = %v", q)
+	}
+	// RFC 7636 appendix B
+	if got := oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %s", got)
+	}
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"truckify/services/auth/internal/model"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// API key repository methods
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, rate_limit,
+		created_at, expires_at, last_used_at, COALESCE(last_used_ip, ''), revoked_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*model.APIKey, error) {
+	k := &model.APIKey{}
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, pq.Array(&k.Scopes), &k.RateLimit,
+		&k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (r *Repository) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, rate_limit, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash, pq.Array(k.Scopes), k.RateLimit, k.CreatedAt, k.ExpiresAt,
+	)
+	return err
+}
+
+// GetAPIKeyByHash returns the key with the given hash, revoked or not
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+// GetAPIKey returns one of the user's keys that has not been revoked
+func (r *Repository) GetAPIKey(ctx context.Context, userID, id uuid.UUID) (*model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, id, userID))
+}
+
+// ListAPIKeys returns the user's keys that have not been revoked, newest first
+func (r *Repository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes one of the user's keys
+func (r *Repository) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records when and from where a key was last used
+func (r *Repository) TouchAPIKey(ctx context.Context, id uuid.UUID, ipAddress string, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1, last_used_ip = NULLIF($2, '') WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, at, ipAddress, id)
+	return err
+}
+
+func (r *Repository) CreateAPIKeyEvent(ctx context.Context, e *model.APIKeyEvent) error {
+	query := `
+		INSERT INTO api_key_events (id, api_key_id, event, ip_address, detail, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+	`
+	_, err := r.db.ExecContext(ctx, query, e.ID, e.APIKeyID, e.Event, e.IPAddress, e.Detail, e.CreatedAt)
+	return err
+}
+
+// ListAPIKeyEvents returns the latest entries of a key's audit trail
+func (r *Repository) ListAPIKeyEvents(ctx context.Context, keyID uuid.UUID, limit int) ([]model.APIKeyEvent, error) {
+	query := `
+		SELECT id, api_key_id, event, COALESCE(ip_address, ''), COALESCE(detail, ''), created_at
+		FROM api_key_events
+		WHERE api_key_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, keyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.APIKeyEvent
+	for rows.Next() {
+		var e model.APIKeyEvent
+		if err := rows.Scan(&e.ID, &e.APIKeyID, &e.Event, &e.IPAddress, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	id, userID := uuid.New(), uuid.New()
+	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "rate_limit",
+		"created_at", "expires_at", "last_used_at", "last_used_ip", "revoked_at"}).
+		AddRow(id, userID, "tms", "tfy_abcdefgh", "hash", "{job:read,job:create}", 60, time.Now(), nil, nil, "", nil)
+	mock.ExpectQuery("SELECT (.+) FROM api_keys WHERE key_hash").WithArgs("hash").WillReturnRows(rows)
+
+	key, err := repo.GetAPIKeyByHash(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, id, key.ID)
+	assert.Equal(t, []string{"job:read", "job:create"}, key.Scopes)
+	assert.Equal(t, 60, key.RateLimit)
+	assert.Nil(t, key.RevokedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID, id := uuid.New(), uuid.New()
+	mock.ExpectExec("UPDATE api_keys SET revoked_at").
+		WithArgs(sqlmock.AnyArg(), id, userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.RevokeAPIKey(context.Background(), userID, id)
+	assert.Equal(t, ErrAPIKeyNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"truckify/services/auth/internal/model"
+)
+
+// ErrDelegationNotFound is returned when no delegation matches
+var ErrDelegationNotFound = errors.New("delegation not found")
+
+// Delegation repository methods
+
+const delegationColumns = `id, grantor_id, dispatcher_id, rights, created_at, expires_at, revoked_at`
+
+func scanDelegation(row interface{ Scan(...any) error }) (*model.Delegation, error) {
+	d := &model.Delegation{}
+	err := row.Scan(&d.ID, &d.GrantorID, &d.DispatcherID, pq.Array(&d.Rights), &d.CreatedAt, &d.ExpiresAt, &d.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDelegationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *Repository) CreateDelegation(ctx context.Context, d *model.Delegation) error {
+	query := `
+		INSERT INTO delegations (id, grantor_id, dispatcher_id, rights, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		d.ID, d.GrantorID, d.DispatcherID, pq.Array(d.Rights), d.CreatedAt, d.ExpiresAt,
+	)
+	return err
+}
+
+// GetDelegation returns a delegation that has not been revoked
+func (r *Repository) GetDelegation(ctx context.Context, id uuid.UUID) (*model.Delegation, error) {
+	query := `SELECT ` + delegationColumns + ` FROM delegations WHERE id = $1 AND revoked_at IS NULL`
+	return scanDelegation(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListDelegations returns the delegations the user granted or was granted
+// that have not been revoked, newest first
+func (r *Repository) ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error) {
+	query := `SELECT ` + delegationColumns + ` FROM delegations
+		WHERE (grantor_id = $1 OR dispatcher_id = $1) AND revoked_at IS NULL
+		ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegations []model.Delegation
+	for rows.Next() {
+		d, err := scanDelegation(rows)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, *d)
+	}
+	return delegations, rows.Err()
+}
+
+// RevokeDelegation revokes a delegation the user granted or was granted
+func (r *Repository) RevokeDelegation(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE delegations SET revoked_at = $1
+		WHERE id = $2 AND (grantor_id = $3 OR dispatcher_id = $3) AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrDelegationNotFound
+	}
+	return nil
+}
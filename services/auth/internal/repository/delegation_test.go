@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDelegations(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID, dispatcherID := uuid.New(), uuid.New()
+	rows := sqlmock.NewRows([]string{"id", "grantor_id", "dispatcher_id", "rights", "created_at", "expires_at", "revoked_at"}).
+		AddRow(uuid.New(), userID, dispatcherID, "{post_jobs,message}", time.Now(), nil, nil)
+	mock.ExpectQuery(`FROM delegations\s+WHERE \(grantor_id = \$1 OR dispatcher_id = \$1\) AND revoked_at IS NULL`).
+		WithArgs(userID).
+		WillReturnRows(rows)
+
+	delegations, err := repo.ListDelegations(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 1)
+	assert.Equal(t, dispatcherID, delegations[0].DispatcherID)
+	assert.Equal(t, []string{"post_jobs", "message"}, delegations[0].Rights)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeDelegationNotFound(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID, id := uuid.New(), uuid.New()
+	mock.ExpectExec("UPDATE delegations SET revoked_at").
+		WithArgs(sqlmock.AnyArg(), id, userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.RevokeDelegation(context.Background(), userID, id)
+	assert.Equal(t, ErrDelegationNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+)
+
+var (
+	// ErrMFANotFound is returned when the user has no second factor, not
+	// even a pending one
+	ErrMFANotFound = errors.New("mfa not found")
+	// ErrMFAChallengeNotFound is returned when no MFA challenge matches
+	ErrMFAChallengeNotFound = errors.New("mfa challenge not found")
+)
+
+// MFA repository methods
+
+func (r *Repository) GetMFA(ctx context.Context, userID uuid.UUID) (*model.MFA, error) {
+	query := `SELECT user_id, totp_secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1`
+	m := &model.MFA{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&m.UserID, &m.Secret, &m.EnabledAt, &m.LastUsedStep, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFANotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SavePendingMFA stores a new TOTP secret awaiting confirmation, replacing
+// any earlier pending one. A confirmed second factor is left alone.
+func (r *Repository) SavePendingMFA(ctx context.Context, m *model.MFA) error {
+	query := `
+		INSERT INTO user_mfa (user_id, totp_secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET totp_secret = $2, last_used_step = 0, created_at = $3
+		WHERE user_mfa.enabled_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, m.UserID, m.Secret, m.CreatedAt)
+	return err
+}
+
+// EnableMFA confirms the user's pending second factor at the TOTP step that
+// confirmed it and stores their first recovery codes
+func (r *Repository) EnableMFA(ctx context.Context, userID uuid.UUID, step int64, codes []model.RecoveryCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE user_mfa SET enabled_at = $1, last_used_step = $2 WHERE user_id = $3 AND enabled_at IS NULL`,
+		time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrMFANotFound
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records that a code for the given step was accepted. It
+// returns false when that step or a later one was used before, so each
+// code only works once.
+func (r *Repository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteMFA removes the user's second factor and recovery codes
+func (r *Repository) DeleteMFA(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListRecoveryCodes returns the user's unused recovery codes
+func (r *Repository) ListRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]model.RecoveryCode, error) {
+	query := `SELECT id, user_id, code_hash, created_at FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []model.RecoveryCode
+	for rows.Next() {
+		var c model.RecoveryCode
+		if err := rows.Scan(&c.ID, &c.UserID, &c.CodeHash, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode marks a recovery code used. It returns false when the
+// code had already been used.
+func (r *Repository) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE mfa_recovery_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReplaceRecoveryCodes swaps all of the user's recovery codes for new ones
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []model.RecoveryCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, codes []model.RecoveryCode) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, c := range codes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			c.ID, userID, c.CodeHash, c.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) CreateMFAChallenge(ctx context.Context, c *model.MFAChallenge) error {
+	query := `INSERT INTO mfa_challenges (id, user_id, expires_at, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, c.ID, c.UserID, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+func (r *Repository) GetMFAChallenge(ctx context.Context, id uuid.UUID) (*model.MFAChallenge, error) {
+	query := `SELECT id, user_id, attempts, expires_at, used_at, created_at FROM mfa_challenges WHERE id = $1`
+	c := &model.MFAChallenge{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.UserID, &c.Attempts, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFAChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AttemptMFAChallenge counts a guess against the challenge and returns how
+// many there have been. It returns ErrMFAChallengeNotFound once the
+// challenge has taken max guesses, has been used or has expired; the check
+// and the count are one statement, so concurrent guesses cannot get past
+// the limit.
+func (r *Repository) AttemptMFAChallenge(ctx context.Context, id uuid.UUID, max int) (int, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE mfa_challenges SET attempts = attempts + 1
+		 WHERE id = $1 AND attempts < $2 AND used_at IS NULL AND expires_at > $3
+		 RETURNING attempts`, id, max, time.Now()).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, ErrMFAChallengeNotFound
+	}
+	return attempts, err
+}
+
+// UseMFAChallenge marks the challenge completed. It returns
+// ErrMFAChallengeNotFound when it had already been used, had taken more
+// than max guesses or has expired, so one challenge cannot start two
+// sessions.
+func (r *Repository) UseMFAChallenge(ctx context.Context, id uuid.UUID, max int) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE mfa_challenges SET used_at = $1
+		 WHERE id = $2 AND used_at IS NULL AND attempts <= $3 AND expires_at > $1`, time.Now(), id, max)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrMFAChallengeNotFound
+	}
+	return nil
+}
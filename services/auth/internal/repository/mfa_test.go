@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"truckify/services/auth/internal/model"
+)
+
+func TestUseTOTPStepRefusesReplay(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectExec("UPDATE user_mfa SET last_used_step").
+		WithArgs(int64(100), userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE user_mfa SET last_used_step").
+		WithArgs(int64(100), userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	used, err := repo.UseTOTPStep(context.Background(), userID, 100)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = repo.UseTOTPStep(context.Background(), userID, 100)
+	assert.NoError(t, err)
+	assert.False(t, used)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableMFA(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	codes := []model.RecoveryCode{{ID: uuid.New(), CodeHash: "hash-1"}, {ID: uuid.New(), CodeHash: "hash-2"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE user_mfa SET enabled_at").
+		WithArgs(sqlmock.AnyArg(), int64(42), userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM mfa_recovery_codes").WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, c := range codes {
+		mock.ExpectExec("INSERT INTO mfa_recovery_codes").
+			WithArgs(c.ID, userID, c.CodeHash, c.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.EnableMFA(context.Background(), userID, 42, codes))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableMFAAlreadyEnabled(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE user_mfa SET enabled_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.EnableMFA(context.Background(), uuid.New(), 42, nil)
+	assert.Equal(t, ErrMFANotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseMFAChallengeOnce(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectExec("UPDATE mfa_challenges SET used_at").
+		WithArgs(sqlmock.AnyArg(), id, 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.UseMFAChallenge(context.Background(), id, 5)
+	assert.Equal(t, ErrMFAChallengeNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptMFAChallengeStopsAtMax(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery("UPDATE mfa_challenges SET attempts = attempts \\+ 1\\s+WHERE id = \\$1 AND attempts < \\$2 AND used_at IS NULL AND expires_at > \\$3").
+		WithArgs(id, 5, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(5))
+	mock.ExpectQuery("UPDATE mfa_challenges SET attempts").
+		WithArgs(id, 5, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+
+	attempts, err := repo.AttemptMFAChallenge(context.Background(), id, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, attempts)
+	_, err = repo.AttemptMFAChallenge(context.Background(), id, 5)
+	assert.Equal(t, ErrMFAChallengeNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
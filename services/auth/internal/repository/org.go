@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+)
+
+var (
+	// ErrOrganisationNotFound is returned when no organisation matches
+	ErrOrganisationNotFound = errors.New("organisation not found")
+	// ErrMembershipNotFound is returned when the user is not a member of
+	// the organisation
+	ErrMembershipNotFound = errors.New("membership not found")
+	// ErrInvitationNotFound is returned when no pending invitation matches
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+// Organisation repository methods
+
+// createPersonalOrg adds the personal organisation of a new user, which
+// shares the user's ID, with the user as its owner
+func createPersonalOrg(ctx context.Context, tx *sql.Tx, user *model.User) error {
+	name := user.Email
+	if name == "" {
+		name = user.Phone
+	}
+	org := &model.Organisation{ID: user.ID, Name: name, Personal: true, CreatedBy: user.ID, CreatedAt: user.CreatedAt}
+	owner := &model.Membership{OrgID: user.ID, UserID: user.ID, Role: model.OrgRoleOwner, CreatedAt: user.CreatedAt}
+	return insertOrganisation(ctx, tx, org, owner)
+}
+
+func insertOrganisation(ctx context.Context, tx *sql.Tx, org *model.Organisation, owner *model.Membership) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO organisations (id, name, personal, created_by, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		org.ID, org.Name, org.Personal, org.CreatedBy, org.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+		owner.OrgID, owner.UserID, owner.Role, owner.CreatedAt)
+	return err
+}
+
+// CreateOrganisation creates an organisation with its first owner
+func (r *Repository) CreateOrganisation(ctx context.Context, org *model.Organisation, owner *model.Membership) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertOrganisation(ctx, tx, org, owner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) GetOrganisation(ctx context.Context, id uuid.UUID) (*model.Organisation, error) {
+	query := `SELECT id, name, personal, created_by, created_at FROM organisations WHERE id = $1`
+	org := &model.Organisation{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&org.ID, &org.Name, &org.Personal, &org.CreatedBy, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganisationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListUserOrganisations returns the organisations the user belongs to with
+// the user's role in each, personal organisation first
+func (r *Repository) ListUserOrganisations(ctx context.Context, userID uuid.UUID) ([]model.OrganisationResponse, error) {
+	query := `
+		SELECT o.id, o.name, o.personal, o.created_by, o.created_at, m.role
+		FROM org_members m JOIN organisations o ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.personal DESC, o.name
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []model.OrganisationResponse
+	for rows.Next() {
+		var o model.OrganisationResponse
+		if err := rows.Scan(&o.ID, &o.Name, &o.Personal, &o.CreatedBy, &o.CreatedAt, &o.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *Repository) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*model.Membership, error) {
+	query := `SELECT org_id, user_id, role, created_at FROM org_members WHERE org_id = $1 AND user_id = $2`
+	m := &model.Membership{}
+	err := r.db.QueryRowContext(ctx, query, orgID, userID).Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrMembershipNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ListMembers returns the members of the organisation with their emails,
+// oldest first
+func (r *Repository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Membership, error) {
+	query := `
+		SELECT m.org_id, m.user_id, COALESCE(u.email, ''), m.role, m.created_at
+		FROM org_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY m.created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []model.Membership
+	for rows.Next() {
+		var m model.Membership
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// CountOwners returns how many owners the organisation has
+func (r *Repository) CountOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM org_members WHERE org_id = $1 AND role = $2`, orgID, model.OrgRoleOwner).Scan(&n)
+	return n, err
+}
+
+func (r *Repository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role model.OrgRole) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE org_members SET role = $1 WHERE org_id = $2 AND user_id = $3`, role, orgID, userID)
+	if err != nil {
+		return err
+	}
+	return membershipAffected(result)
+}
+
+func (r *Repository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM org_members WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	return membershipAffected(result)
+}
+
+func membershipAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+// Invitation repository methods
+
+func (r *Repository) CreateInvitation(ctx context.Context, inv *model.Invitation) error {
+	query := `
+		INSERT INTO org_invitations (id, org_id, email, role, token_hash, invited_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		inv.ID, inv.OrgID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.CreatedAt, inv.ExpiresAt)
+	return err
+}
+
+// GetInvitationByHash returns the invitation with the token hash, whether
+// or not it is still pending
+func (r *Repository) GetInvitationByHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	query := `
+		SELECT id, org_id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at
+		FROM org_invitations WHERE token_hash = $1
+	`
+	inv := &model.Invitation{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		&inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// ListInvitations returns the organisation's invitations that have been
+// neither accepted nor left to expire, newest first
+func (r *Repository) ListInvitations(ctx context.Context, orgID uuid.UUID) ([]model.Invitation, error) {
+	query := `
+		SELECT id, org_id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at
+		FROM org_invitations
+		WHERE org_id = $1 AND accepted_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, orgID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []model.Invitation
+	for rows.Next() {
+		var inv model.Invitation
+		if err := rows.Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+			&inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation withdraws one of the organisation's pending invitations
+func (r *Repository) DeleteInvitation(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM org_invitations WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL`, id, orgID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation marks the invitation accepted and adds the user to its
+// organisation. It only succeeds once per invitation.
+func (r *Repository) AcceptInvitation(ctx context.Context, inv *model.Invitation, userID uuid.UUID, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE org_invitations SET accepted_at = $1 WHERE id = $2 AND accepted_at IS NULL AND expires_at > $1`, at, inv.ID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrInvitationNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+		inv.OrgID, userID, inv.Role, at)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
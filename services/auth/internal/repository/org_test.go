@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"truckify/services/auth/internal/model"
+)
+
+func TestGetMembershipNotFound(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	orgID, userID := uuid.New(), uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM org_members WHERE org_id").
+		WithArgs(orgID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "role", "created_at"}))
+
+	_, err := repo.GetMembership(context.Background(), orgID, userID)
+	assert.Equal(t, ErrMembershipNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	inv := &model.Invitation{ID: uuid.New(), OrgID: uuid.New(), Role: model.OrgRoleAdmin}
+	at := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE org_invitations SET accepted_at").
+		WithArgs(at, inv.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO org_members").
+		WithArgs(inv.OrgID, userID, model.OrgRoleAdmin, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.AcceptInvitation(context.Background(), inv, userID, at)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitationAlreadyUsed(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	inv := &model.Invitation{ID: uuid.New(), OrgID: uuid.New(), Role: model.OrgRoleMember}
+	at := time.Now()
+
+	// A concurrent accept won; nobody is added
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE org_invitations SET accepted_at").
+		WithArgs(at, inv.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.AcceptInvitation(context.Background(), inv, uuid.New(), at)
+	assert.Equal(t, ErrInvitationNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
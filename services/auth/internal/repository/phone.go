@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+)
+
+var (
+	// ErrPhoneTaken is returned when another account has verified the
+	// phone number
+	ErrPhoneTaken = errors.New("phone number already in use")
+	// ErrPhoneCodeNotFound is returned when no SMS code matches, or it has
+	// already been used
+	ErrPhoneCodeNotFound = errors.New("phone code not found")
+)
+
+// Phone repository methods
+
+// GetUserByPhone retrieves the user who has verified the phone number
+func (r *Repository) GetUserByPhone(ctx context.Context, phone string) (*model.User, error) {
+	query := `
+		SELECT id, COALESCE(email, ''), password_hash, user_type, status, email_verified,
+		       COALESCE(phone, ''), phone_verified,
+		       created_at, updated_at, last_login_at, verification_token, reset_token, reset_token_expiry
+		FROM users
+		WHERE phone = $1 AND phone_verified
+	`
+
+	user := &model.User{}
+	err := r.db.QueryRowContext(ctx, query, phone).Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.UserType,
+		&user.Status,
+		&user.EmailVerified,
+		&user.Phone,
+		&user.PhoneVerified,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LastLoginAt,
+		&user.VerificationToken,
+		&user.ResetToken,
+		&user.ResetTokenExpiry,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetVerifiedPhone gives the user the phone number, verified. Other
+// accounts that named the number without verifying it lose it.
+func (r *Repository) SetVerifiedPhone(ctx context.Context, userID uuid.UUID, phone string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET phone = NULL, updated_at = NOW() WHERE phone = $1 AND NOT phone_verified AND id <> $2 AND email IS NOT NULL`,
+		phone, userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET phone = $1, phone_verified = TRUE, updated_at = NOW() WHERE id = $2`, phone, userID)
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "users_verified_phone_key"` {
+			return ErrPhoneTaken
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreatePhoneCode stores an SMS code, first dropping codes created before
+// purgeBefore
+func (r *Repository) CreatePhoneCode(ctx context.Context, c *model.PhoneCode, purgeBefore time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM phone_codes WHERE created_at < $1`, purgeBefore); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO phone_codes (id, phone, purpose, user_id, code_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query, c.ID, c.Phone, c.Purpose, c.UserID, c.CodeHash, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+// RecentPhoneCodes returns the codes sent to the phone number since the
+// given time, newest first
+func (r *Repository) RecentPhoneCodes(ctx context.Context, phone string, since time.Time) ([]model.PhoneCode, error) {
+	query := `
+		SELECT id, phone, purpose, user_id, code_hash, attempts, expires_at, used_at, created_at
+		FROM phone_codes
+		WHERE phone = $1 AND created_at >= $2
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, phone, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []model.PhoneCode
+	for rows.Next() {
+		var c model.PhoneCode
+		if err := rows.Scan(&c.ID, &c.Phone, &c.Purpose, &c.UserID, &c.CodeHash, &c.Attempts, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// LatestPhoneCode returns the newest code sent to the phone number for the
+// purpose; only it can be used
+func (r *Repository) LatestPhoneCode(ctx context.Context, phone string, purpose model.PhoneCodePurpose) (*model.PhoneCode, error) {
+	query := `
+		SELECT id, phone, purpose, user_id, code_hash, attempts, expires_at, used_at, created_at
+		FROM phone_codes
+		WHERE phone = $1 AND purpose = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	c := &model.PhoneCode{}
+	err := r.db.QueryRowContext(ctx, query, phone, purpose).Scan(
+		&c.ID, &c.Phone, &c.Purpose, &c.UserID, &c.CodeHash, &c.Attempts, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPhoneCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AttemptPhoneCode counts a guess against the code and returns how many
+// there have been. It returns ErrPhoneCodeNotFound once the code has taken
+// max guesses; the check and the count are one statement, so concurrent
+// guesses cannot get past the limit.
+func (r *Repository) AttemptPhoneCode(ctx context.Context, id uuid.UUID, max int) (int, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE phone_codes SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2 RETURNING attempts`, id, max).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, ErrPhoneCodeNotFound
+	}
+	return attempts, err
+}
+
+// UsePhoneCode marks the code used. It returns ErrPhoneCodeNotFound when
+// it had already been used, so one code cannot be redeemed twice.
+func (r *Repository) UsePhoneCode(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE phone_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPhoneCodeNotFound
+	}
+	return nil
+}
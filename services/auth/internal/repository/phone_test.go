@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"truckify/services/auth/internal/model"
+)
+
+func TestSetVerifiedPhone(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET phone = NULL").WithArgs("+254712345678", userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET phone = \\$1, phone_verified = TRUE").WithArgs("+254712345678", userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, repo.SetVerifiedPhone(context.Background(), userID, "+254712345678"))
+
+	// Another account has verified the number
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET phone = NULL").WithArgs("+254712345678", userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE users SET phone = \\$1, phone_verified = TRUE").WithArgs("+254712345678", userID).
+		WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "users_verified_phone_key"`))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrPhoneTaken, repo.SetVerifiedPhone(context.Background(), userID, "+254712345678"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePhoneCodePurgesOldCodes(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	c := &model.PhoneCode{ID: uuid.New(), Phone: "+61412345678", Purpose: model.PhoneCodeLogin, CodeHash: "hash",
+		ExpiresAt: now.Add(10 * time.Minute), CreatedAt: now}
+	mock.ExpectExec("DELETE FROM phone_codes WHERE created_at").WithArgs(now.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO phone_codes").
+		WithArgs(c.ID, c.Phone, c.Purpose, nil, "hash", c.ExpiresAt, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.CreatePhoneCode(context.Background(), c, now.Add(-time.Hour)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsePhoneCodeOnce(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectExec("UPDATE phone_codes SET used_at").WithArgs(sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE phone_codes SET used_at").WithArgs(sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UsePhoneCode(context.Background(), id))
+	assert.Equal(t, ErrPhoneCodeNotFound, repo.UsePhoneCode(context.Background(), id))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptPhoneCodeStopsAtMax(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery("UPDATE phone_codes SET attempts = attempts \\+ 1 WHERE id = \\$1 AND attempts < \\$2").
+		WithArgs(id, 5).WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(5))
+	mock.ExpectQuery("UPDATE phone_codes SET attempts").WithArgs(id, 5).WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+
+	attempts, err := repo.AttemptPhoneCode(context.Background(), id, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, attempts)
+	_, err = repo.AttemptPhoneCode(context.Background(), id, 5)
+	assert.Equal(t, ErrPhoneCodeNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/pagination"
 )
 
 var (
@@ -25,14 +26,31 @@ func New(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-// CreateUser creates a new user in the database
+// CreateUser creates a new user in the database together with their
+// personal organisation
 func (r *Repository) CreateUser(ctx context.Context, user *model.User) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertUser adds a user and their personal organisation. Users without
+// an email or phone number store NULL, which is not unique.
+func insertUser(ctx context.Context, tx *sql.Tx, user *model.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, user_type, status, email_verified, created_at, updated_at, verification_token)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, email, password_hash, user_type, status, email_verified, created_at, updated_at, verification_token,
+		                   phone, phone_verified)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := tx.ExecContext(ctx, query,
 		user.ID,
 		user.Email,
 		user.PasswordHash,
@@ -42,6 +60,8 @@ func (r *Repository) CreateUser(ctx context.Context, user *model.User) error {
 		user.CreatedAt,
 		user.UpdatedAt,
 		user.VerificationToken,
+		user.Phone,
+		user.PhoneVerified,
 	)
 
 	if err != nil {
@@ -49,16 +69,20 @@ func (r *Repository) CreateUser(ctx context.Context, user *model.User) error {
 		if err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"` {
 			return ErrEmailAlreadyExists
 		}
+		if err.Error() == `pq: duplicate key value violates unique constraint "users_verified_phone_key"` {
+			return ErrPhoneTaken
+		}
 		return err
 	}
 
-	return nil
+	return createPersonalOrg(ctx, tx, user)
 }
 
 // GetUserByEmail retrieves a user by email
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT id, email, password_hash, user_type, status, email_verified,
+		SELECT id, COALESCE(email, ''), password_hash, user_type, status, email_verified,
+		       COALESCE(phone, ''), phone_verified,
 		       created_at, updated_at, last_login_at, verification_token, reset_token, reset_token_expiry
 		FROM users
 		WHERE email = $1
@@ -72,6 +96,8 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*model.U
 		&user.UserType,
 		&user.Status,
 		&user.EmailVerified,
+		&user.Phone,
+		&user.PhoneVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
@@ -93,7 +119,8 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*model.U
 // GetUserByID retrieves a user by ID
 func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
 	query := `
-		SELECT id, email, password_hash, user_type, status, email_verified,
+		SELECT id, COALESCE(email, ''), password_hash, user_type, status, email_verified,
+		       COALESCE(phone, ''), phone_verified,
 		       created_at, updated_at, last_login_at, verification_token, reset_token, reset_token_expiry
 		FROM users
 		WHERE id = $1
@@ -107,6 +134,8 @@ func (r *Repository) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User
 		&user.UserType,
 		&user.Status,
 		&user.EmailVerified,
+		&user.Phone,
+		&user.PhoneVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLoginAt,
@@ -225,24 +254,26 @@ func (r *Repository) ResetPassword(ctx context.Context, token, passwordHash stri
 	return nil
 }
 
-// ListUsers returns all users (admin only)
-func (r *Repository) ListUsers(ctx context.Context) ([]model.User, error) {
-	query := `SELECT id, email, user_type, status, email_verified, created_at, updated_at FROM users ORDER BY created_at DESC`
-	rows, err := r.db.QueryContext(ctx, query)
+// ListUsers returns a page of users and the cursor of the next page (admin only)
+func (r *Repository) ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error) {
+	query, args := page.Apply(`SELECT id, COALESCE(email, ''), user_type, status, email_verified, COALESCE(phone, ''), phone_verified, created_at, updated_at
+		FROM users WHERE TRUE`, nil)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	var users []model.User
 	for rows.Next() {
 		var u model.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.UserType, &u.Status, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&u.ID, &u.Email, &u.UserType, &u.Status, &u.EmailVerified, &u.Phone, &u.PhoneVerified, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, "", err
 		}
 		users = append(users, u)
 	}
-	return users, nil
+	users, next := pagination.Cut(page, users, model.User.SortKey)
+	return users, next, nil
 }
 
 // UpdateUserStatus updates a user's status (admin only)
@@ -38,10 +38,19 @@ func TestCreateUser(t *testing.T) {
 		VerificationToken: stringPtr("token123"),
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO users").
 		WithArgs(user.ID, user.Email, user.PasswordHash, user.UserType, user.Status,
-			user.EmailVerified, user.CreatedAt, user.UpdatedAt, user.VerificationToken).
+			user.EmailVerified, user.CreatedAt, user.UpdatedAt, user.VerificationToken, "", false).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Every user gets a personal organisation sharing their ID
+	mock.ExpectExec("INSERT INTO organisations").
+		WithArgs(user.ID, user.Email, true, user.ID, user.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO org_members").
+		WithArgs(user.ID, user.ID, model.OrgRoleOwner, user.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err := repo.CreateUser(ctx, user)
 	assert.NoError(t, err)
@@ -65,10 +74,12 @@ func TestCreateUserDuplicateEmail(t *testing.T) {
 		VerificationToken: stringPtr("token123"),
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO users").
 		WithArgs(user.ID, user.Email, user.PasswordHash, user.UserType, user.Status,
-			user.EmailVerified, user.CreatedAt, user.UpdatedAt, user.VerificationToken).
+			user.EmailVerified, user.CreatedAt, user.UpdatedAt, user.VerificationToken, "", false).
 		WillReturnError(sql.ErrNoRows) // Simulate duplicate email error
+	mock.ExpectRollback()
 
 	err := repo.CreateUser(ctx, user)
 	assert.Error(t, err)
@@ -84,10 +95,10 @@ func TestGetUserByEmail(t *testing.T) {
 	email := "test@example.com"
 
 	rows := sqlmock.NewRows([]string{
-		"id", "email", "password_hash", "user_type", "status", "email_verified",
+		"id", "email", "password_hash", "user_type", "status", "email_verified", "phone", "phone_verified",
 		"created_at", "updated_at", "last_login_at", "verification_token", "reset_token", "reset_token_expiry",
 	}).AddRow(
-		userID, email, "hashedpassword", model.UserTypeDriver, model.UserStatusActive, false,
+		userID, email, "hashedpassword", model.UserTypeDriver, model.UserStatusActive, false, "", false,
 		time.Now(), time.Now(), nil, nil, nil, nil,
 	)
 
@@ -129,10 +140,10 @@ func TestGetUserByID(t *testing.T) {
 	userID := uuid.New()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "email", "password_hash", "user_type", "status", "email_verified",
+		"id", "email", "password_hash", "user_type", "status", "email_verified", "phone", "phone_verified",
 		"created_at", "updated_at", "last_login_at", "verification_token", "reset_token", "reset_token_expiry",
 	}).AddRow(
-		userID, "test@example.com", "hashedpassword", model.UserTypeDriver, model.UserStatusActive, false,
+		userID, "test@example.com", "hashedpassword", model.UserTypeDriver, model.UserStatusActive, false, "+254712345678", true,
 		time.Now(), time.Now(), nil, nil, nil, nil,
 	)
 
@@ -143,6 +154,8 @@ func TestGetUserByID(t *testing.T) {
 	user, err := repo.GetUserByID(ctx, userID)
 	require.NoError(t, err)
 	assert.Equal(t, userID, user.ID)
+	assert.Equal(t, "+254712345678", user.Phone)
+	assert.True(t, user.PhoneVerified)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
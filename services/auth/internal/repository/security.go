@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/pagination"
+)
+
+// Login throttle and security event repository methods
+
+// GetLoginThrottles returns the failed-login counters of the given keys.
+// Keys without failures are left out.
+func (r *Repository) GetLoginThrottles(ctx context.Context, keys []string) ([]model.LoginThrottle, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_throttles WHERE key = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var throttles []model.LoginThrottle
+	for rows.Next() {
+		var t model.LoginThrottle
+		if err := rows.Scan(&t.Key, &t.Failures, &t.LastFailureAt, &t.LockedUntil); err != nil {
+			return nil, err
+		}
+		throttles = append(throttles, t)
+	}
+	return throttles, rows.Err()
+}
+
+// RecordLoginFailure counts a failed login against key and returns its
+// counter. A counter whose last failure is older than resetBefore starts
+// over.
+func (r *Repository) RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*model.LoginThrottle, error) {
+	query := `
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = $2
+		RETURNING key, failures, last_failure_at, locked_until
+	`
+	t := &model.LoginThrottle{}
+	err := r.db.QueryRowContext(ctx, query, key, now, resetBefore).Scan(&t.Key, &t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LockLogin refuses logins for key until the given time
+func (r *Repository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE login_throttles SET locked_until = $1 WHERE key = $2`, until, key)
+	return err
+}
+
+// ClearLoginFailures forgets the failed logins of key
+func (r *Repository) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_throttles WHERE key = $1`, key)
+	return err
+}
+
+func (r *Repository) CreateSecurityEvent(ctx context.Context, e *model.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (id, user_id, event_type, ip_address, user_agent, detail, created_at, actor_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, e.ID, e.UserID, e.Type, e.IPAddress, e.UserAgent, e.Detail, e.CreatedAt, e.ActorID)
+	return err
+}
+
+// ListSecurityEvents returns a page of the user's security log and the
+// cursor of the next page
+func (r *Repository) ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error) {
+	query, args := page.Apply(`
+		SELECT id, user_id, event_type, COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(detail, ''), created_at, actor_id
+		FROM security_events
+		WHERE user_id = $1`, []interface{}{userID})
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var events []model.SecurityEvent
+	for rows.Next() {
+		var e model.SecurityEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.IPAddress, &e.UserAgent, &e.Detail, &e.CreatedAt, &e.ActorID); err != nil {
+			return nil, "", err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	events, next := pagination.Cut(page, events, model.SecurityEvent.SortKey)
+	return events, next, nil
+}
+
+// LoginHistory reports whether the user has logged in before, and whether
+// they have from a device with the given user agent
+func (r *Repository) LoginHistory(ctx context.Context, userID uuid.UUID, userAgent string) (bool, bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM security_events WHERE user_id = $1 AND event_type = $2),
+			EXISTS (SELECT 1 FROM security_events WHERE user_id = $1 AND event_type = $2 AND user_agent = $3)
+	`
+	var anyLogin, fromDevice bool
+	err := r.db.QueryRowContext(ctx, query, userID, model.SecurityLoginSucceeded, userAgent).Scan(&anyLogin, &fromDevice)
+	return anyLogin, fromDevice, err
+}
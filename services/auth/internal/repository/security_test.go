@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/pagination"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	resetBefore := now.Add(-15 * time.Minute)
+	mock.ExpectQuery("INSERT INTO login_throttles").
+		WithArgs("account:test@example.com", now, resetBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failure_at", "locked_until"}).
+			AddRow("account:test@example.com", 4, now, nil))
+
+	throttle, err := repo.RecordLoginFailure(context.Background(), "account:test@example.com", now, resetBefore)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, throttle.Failures)
+	assert.Nil(t, throttle.LockedUntil)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListSecurityEvents(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	page, err := pagination.Parse(url.Values{"type": {"login_failed"}, "limit": {"1"}}, model.SecurityEventListSpec)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "event_type", "ip_address", "user_agent", "detail", "created_at", "actor_id"})
+	for i := 0; i < 2; i++ {
+		rows.AddRow(uuid.New(), userID, model.SecurityLoginFailed, "203.0.113.7", "", "", time.Now(), nil)
+	}
+	mock.ExpectQuery(`FROM security_events\s+WHERE user_id = \$1 AND event_type = \$2 ORDER BY created_at DESC, id DESC LIMIT \$3`).
+		WithArgs(userID, model.SecurityLoginFailed, 2).
+		WillReturnRows(rows)
+
+	events, next, err := repo.ListSecurityEvents(context.Background(), userID, page)
+
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.NotEmpty(t, next)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginHistory(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectQuery("SELECT").
+		WithArgs(userID, model.SecurityLoginSucceeded, "NewPhone/1.0").
+		WillReturnRows(sqlmock.NewRows([]string{"any", "device"}).AddRow(true, false))
+
+	loggedInBefore, fromDevice, err := repo.LoginHistory(context.Background(), userID, "NewPhone/1.0")
+
+	require.NoError(t, err)
+	assert.True(t, loggedInBefore)
+	assert.False(t, fromDevice)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
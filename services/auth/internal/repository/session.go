@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+)
+
+// ErrSessionNotFound is returned when no active session matches
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session repository methods
+
+func (r *Repository) CreateSession(ctx context.Context, s *model.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, refresh_token_id, access_token_id, access_expires_at,
+		                      user_agent, ip_address, created_at, last_used_at, expires_at, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		s.ID, s.UserID, s.RefreshTokenID, s.AccessTokenID, s.AccessExpiresAt,
+		s.UserAgent, s.IPAddress, s.CreatedAt, s.LastUsedAt, s.ExpiresAt, sessionOrg(s),
+	)
+	return err
+}
+
+func (r *Repository) GetSession(ctx context.Context, id uuid.UUID) (*model.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_id, access_token_id, access_expires_at,
+		       COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at,
+		       revoked_at, revoked_reason, org_id
+		FROM sessions WHERE id = $1
+	`
+	s := &model.Session{}
+	var orgID uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.UserID, &s.RefreshTokenID, &s.AccessTokenID, &s.AccessExpiresAt,
+		&s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt,
+		&s.RevokedAt, &s.RevokedReason, &orgID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.OrgID = orgID.UUID
+	return s, nil
+}
+
+// sessionOrg returns the org_id column of a session, NULL for the user's
+// personal organisation
+func sessionOrg(s *model.Session) uuid.NullUUID {
+	if s.OrgID == uuid.Nil || s.OrgID == s.UserID {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: s.OrgID, Valid: true}
+}
+
+// RotateSession stores the token IDs of a refreshed session. It only succeeds
+// while the session is active and still holds the refresh token ID being
+// exchanged, so two concurrent refreshes with the same token cannot both win.
+// Switching organisation rotates the session the same way.
+func (r *Repository) RotateSession(ctx context.Context, s *model.Session, previousRefreshTokenID string) error {
+	query := `
+		UPDATE sessions
+		SET refresh_token_id = $1, access_token_id = $2, access_expires_at = $3, expires_at = $4,
+		    user_agent = $5, ip_address = $6, last_used_at = $7, org_id = $8
+		WHERE id = $9 AND refresh_token_id = $10 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		s.RefreshTokenID, s.AccessTokenID, s.AccessExpiresAt, s.ExpiresAt,
+		s.UserAgent, s.IPAddress, s.LastUsedAt, sessionOrg(s), s.ID, previousRefreshTokenID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSession revokes one of the user's active sessions and returns it so
+// its access token can be denied
+func (r *Repository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, reason string) (*model.Session, error) {
+	query := `
+		UPDATE sessions SET revoked_at = $1, revoked_reason = $2
+		WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL
+		RETURNING id, access_token_id, access_expires_at
+	`
+	s := &model.Session{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, time.Now(), reason, sessionID, userID).Scan(
+		&s.ID, &s.AccessTokenID, &s.AccessExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RevokeUserSessions revokes every active session of the user and returns them
+func (r *Repository) RevokeUserSessions(ctx context.Context, userID uuid.UUID, reason string) ([]model.Session, error) {
+	query := `
+		UPDATE sessions SET revoked_at = $1, revoked_reason = $2
+		WHERE user_id = $3 AND revoked_at IS NULL
+		RETURNING id, access_token_id, access_expires_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, time.Now(), reason, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		s := model.Session{UserID: userID}
+		if err := rows.Scan(&s.ID, &s.AccessTokenID, &s.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (r *Repository) ListSessions(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	query := `
+		SELECT id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_used_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
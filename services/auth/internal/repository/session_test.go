@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"truckify/services/auth/internal/model"
+)
+
+func TestRotateSession(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	session := &model.Session{
+		ID:              uuid.New(),
+		RefreshTokenID:  "new-refresh",
+		AccessTokenID:   "new-access",
+		AccessExpiresAt: time.Now().Add(15 * time.Minute),
+		ExpiresAt:       time.Now().Add(7 * 24 * time.Hour),
+		LastUsedAt:      time.Now(),
+	}
+
+	mock.ExpectExec("UPDATE sessions").
+		WithArgs(session.RefreshTokenID, session.AccessTokenID, session.AccessExpiresAt, session.ExpiresAt,
+			session.UserAgent, session.IPAddress, session.LastUsedAt, uuid.NullUUID{}, session.ID, "old-refresh").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.RotateSession(context.Background(), session, "old-refresh")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateSessionAlreadyRotated(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE sessions").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.RotateSession(context.Background(), &model.Session{ID: uuid.New()}, "old-refresh")
+	assert.Equal(t, ErrSessionNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	expiry := time.Now().Add(10 * time.Minute)
+	rows := sqlmock.NewRows([]string{"id", "access_token_id", "access_expires_at"}).
+		AddRow(uuid.New(), "access-1", expiry).
+		AddRow(uuid.New(), "access-2", expiry)
+
+	mock.ExpectQuery("UPDATE sessions SET revoked_at").
+		WithArgs(sqlmock.AnyArg(), model.RevokedLogoutAll, userID).
+		WillReturnRows(rows)
+
+	sessions, err := repo.RevokeUserSessions(context.Background(), userID, model.RevokedLogoutAll)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "access-2", sessions[1].AccessTokenID)
+	assert.Equal(t, userID, sessions[1].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateSessionSwitchesOrg(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	session := &model.Session{ID: uuid.New(), UserID: uuid.New(), OrgID: uuid.New()}
+
+	mock.ExpectExec("UPDATE sessions").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			uuid.NullUUID{UUID: session.OrgID, Valid: true}, session.ID, "old-refresh").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.RotateSession(context.Background(), session, "old-refresh")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+)
+
+var (
+	// ErrIdentityProviderNotFound is returned when no identity provider
+	// matches
+	ErrIdentityProviderNotFound = errors.New("identity provider not found")
+	// ErrDomainTaken is returned when another organisation has verified the
+	// domain for its identity provider
+	ErrDomainTaken = errors.New("domain already has an identity provider")
+	// ErrSSOLoginNotFound is returned when no pending single sign-on login
+	// matches
+	ErrSSOLoginNotFound = errors.New("sso login not found")
+	// ErrSSOIdentityNotFound is returned when no user is linked to the
+	// subject
+	ErrSSOIdentityNotFound = errors.New("sso identity not found")
+)
+
+// Single sign-on repository methods
+
+const identityProviderColumns = `org_id, issuer, client_id, client_secret, domain, verification_token,
+		domain_verified_at, COALESCE(role_claim, ''), role_mapping, default_user_type, created_at, updated_at`
+
+func scanIdentityProvider(row interface{ Scan(...any) error }) (*model.IdentityProvider, error) {
+	p := &model.IdentityProvider{}
+	var mapping []byte
+	var verifiedAt sql.NullTime
+	err := row.Scan(&p.OrgID, &p.Issuer, &p.ClientID, &p.ClientSecret, &p.Domain, &p.VerificationToken,
+		&verifiedAt, &p.RoleClaim, &mapping, &p.DefaultUserType, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrIdentityProviderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		p.DomainVerifiedAt = &verifiedAt.Time
+	}
+	if err := json.Unmarshal(mapping, &p.RoleMapping); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SaveIdentityProvider creates or replaces an organisation's identity
+// provider
+func (r *Repository) SaveIdentityProvider(ctx context.Context, p *model.IdentityProvider) error {
+	mapping, err := json.Marshal(p.RoleMapping)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO org_identity_providers (org_id, issuer, client_id, client_secret, domain, verification_token,
+			domain_verified_at, role_claim, role_mapping, default_user_type, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12)
+		ON CONFLICT (org_id) DO UPDATE SET
+			issuer = EXCLUDED.issuer,
+			client_id = EXCLUDED.client_id,
+			client_secret = EXCLUDED.client_secret,
+			domain = EXCLUDED.domain,
+			verification_token = EXCLUDED.verification_token,
+			domain_verified_at = EXCLUDED.domain_verified_at,
+			role_claim = EXCLUDED.role_claim,
+			role_mapping = EXCLUDED.role_mapping,
+			default_user_type = EXCLUDED.default_user_type,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		p.OrgID, p.Issuer, p.ClientID, p.ClientSecret, p.Domain, p.VerificationToken,
+		p.DomainVerifiedAt, p.RoleClaim, mapping, p.DefaultUserType, p.CreatedAt, p.UpdatedAt,
+	)
+	return domainTaken(err)
+}
+
+// VerifyIdentityProviderDomain marks an organisation's domain as verified,
+// if its provider still has the verification token that was checked
+func (r *Repository) VerifyIdentityProviderDomain(ctx context.Context, orgID uuid.UUID, token string, at time.Time) error {
+	query := `
+		UPDATE org_identity_providers SET domain_verified_at = $3, updated_at = $3
+		WHERE org_id = $1 AND verification_token = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, orgID, token, at)
+	if err != nil {
+		return domainTaken(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrIdentityProviderNotFound
+	}
+	return nil
+}
+
+// domainTaken turns a clash with another organisation's verified domain
+// into ErrDomainTaken
+func domainTaken(err error) error {
+	if err != nil && err.Error() == `pq: duplicate key value violates unique constraint "org_identity_providers_verified_domain_key"` {
+		return ErrDomainTaken
+	}
+	return err
+}
+
+// GetIdentityProvider returns an organisation's identity provider
+func (r *Repository) GetIdentityProvider(ctx context.Context, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	query := `SELECT ` + identityProviderColumns + ` FROM org_identity_providers WHERE org_id = $1`
+	return scanIdentityProvider(r.db.QueryRowContext(ctx, query, orgID))
+}
+
+// GetIdentityProviderByDomain returns the identity provider serving an
+// email domain. Only providers whose domain is verified serve it.
+func (r *Repository) GetIdentityProviderByDomain(ctx context.Context, domain string) (*model.IdentityProvider, error) {
+	query := `SELECT ` + identityProviderColumns + ` FROM org_identity_providers
+		WHERE domain = $1 AND domain_verified_at IS NOT NULL`
+	return scanIdentityProvider(r.db.QueryRowContext(ctx, query, domain))
+}
+
+// DeleteIdentityProvider removes an organisation's identity provider. Its
+// users keep their accounts.
+func (r *Repository) DeleteIdentityProvider(ctx context.Context, orgID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM org_identity_providers WHERE org_id = $1`, orgID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrIdentityProviderNotFound
+	}
+	return nil
+}
+
+func (r *Repository) CreateSSOLogin(ctx context.Context, l *model.SSOLogin) error {
+	query := `
+		INSERT INTO sso_logins (state_hash, org_id, nonce, code_verifier, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query, l.StateHash, l.OrgID, l.Nonce, l.CodeVerifier, l.CreatedAt, l.ExpiresAt)
+	return err
+}
+
+// TakeSSOLogin removes and returns the unexpired login with the given state
+// hash, so that each login can only be finished once. Expired logins are
+// cleared on the way.
+func (r *Repository) TakeSSOLogin(ctx context.Context, stateHash string, now time.Time) (*model.SSOLogin, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sso_logins WHERE expires_at <= $1`, now); err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM sso_logins WHERE state_hash = $1
+		RETURNING state_hash, org_id, nonce, code_verifier, created_at, expires_at
+	`
+	l := &model.SSOLogin{}
+	err := r.db.QueryRowContext(ctx, query, stateHash).Scan(&l.StateHash, &l.OrgID, &l.Nonce, &l.CodeVerifier, &l.CreatedAt, &l.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrSSOLoginNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetSSOIdentity returns the ID of the user linked to a subject at an
+// organisation's identity provider
+func (r *Repository) GetSSOIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id FROM sso_identities WHERE org_id = $1 AND subject = $2`, orgID, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrSSOIdentityNotFound
+	}
+	return userID, err
+}
+
+// LinkSSOIdentity links an existing user to their subject at an
+// organisation's identity provider
+func (r *Repository) LinkSSOIdentity(ctx context.Context, identity *model.SSOIdentity) error {
+	query := `INSERT INTO sso_identities (org_id, subject, user_id, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, identity.OrgID, identity.Subject, identity.UserID, identity.CreatedAt)
+	return err
+}
+
+// ProvisionSSOUser creates a user who logged in through an organisation's
+// identity provider for the first time, with their personal organisation, a
+// membership of the provider's organisation and the link to their subject
+func (r *Repository) ProvisionSSOUser(ctx context.Context, user *model.User, identity *model.SSOIdentity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+		identity.OrgID, user.ID, model.OrgRoleMember, identity.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO sso_identities (org_id, subject, user_id, created_at) VALUES ($1, $2, $3, $4)`,
+		identity.OrgID, identity.Subject, user.ID, identity.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
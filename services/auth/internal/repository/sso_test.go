@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+)
+
+func TestGetIdentityProviderByDomain(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	orgID := uuid.New()
+	rows := sqlmock.NewRows([]string{"org_id", "issuer", "client_id", "client_secret", "domain", "verification_token",
+		"domain_verified_at", "role_claim", "role_mapping", "default_user_type", "created_at", "updated_at"}).
+		AddRow(orgID, "https://idp.acme.example", "truckify", "secret", "acme.example", "token",
+			time.Now(), "groups", []byte(`{"truckify-dispatch":"dispatcher"}`), "shipper", time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM org_identity_providers\\s+WHERE domain = \\$1 AND domain_verified_at IS NOT NULL").
+		WithArgs("acme.example").WillReturnRows(rows)
+
+	p, err := repo.GetIdentityProviderByDomain(context.Background(), "acme.example")
+	require.NoError(t, err)
+	assert.Equal(t, orgID, p.OrgID)
+	assert.True(t, p.DomainVerified())
+	assert.Equal(t, model.UserTypeDispatcher, p.RoleMapping["truckify-dispatch"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyIdentityProviderDomainTaken(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	orgID := uuid.New()
+	now := time.Now()
+	mock.ExpectExec("UPDATE org_identity_providers SET domain_verified_at").WithArgs(orgID, "token", now).
+		WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "org_identity_providers_verified_domain_key"`))
+
+	err := repo.VerifyIdentityProviderDomain(context.Background(), orgID, "token", now)
+	assert.Equal(t, ErrDomainTaken, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyIdentityProviderDomainTokenChanged(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	orgID := uuid.New()
+	now := time.Now()
+	mock.ExpectExec("UPDATE org_identity_providers SET domain_verified_at").WithArgs(orgID, "old-token", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.VerifyIdentityProviderDomain(context.Background(), orgID, "old-token", now)
+	assert.Equal(t, ErrIdentityProviderNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTakeSSOLogin(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec("DELETE FROM sso_logins WHERE expires_at").WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("DELETE FROM sso_logins WHERE state_hash").WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash", "org_id", "nonce", "code_verifier", "created_at", "expires_at"}))
+
+	_, err := repo.TakeSSOLogin(context.Background(), "hash", now)
+	assert.Equal(t, ErrSSOLoginNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProvisionSSOUser(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	user := &model.User{ID: uuid.New(), Email: "ops@acme.example", UserType: model.UserTypeShipper,
+		Status: model.UserStatusActive, EmailVerified: true, CreatedAt: now, UpdatedAt: now}
+	identity := &model.SSOIdentity{OrgID: uuid.New(), Subject: "00u1", UserID: user.ID, CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO organisations").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO org_members").WithArgs(user.ID, user.ID, model.OrgRoleOwner, now).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO org_members").WithArgs(identity.OrgID, user.ID, model.OrgRoleMember, now).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO sso_identities").WithArgs(identity.OrgID, "00u1", user.ID, now).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.ProvisionSSOUser(context.Background(), user, identity))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
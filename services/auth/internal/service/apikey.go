@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/shared/pkg/authz"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+	ErrInvalidClient = errors.New("invalid client credentials")
+	ErrInvalidScope  = errors.New("invalid scope")
+)
+
+// apiKeyEventLimit caps how much of a key's audit trail is returned
+const apiKeyEventLimit = 100
+
+// CreateAPIKey issues an API key acting for the user. Every scope must be a
+// permission the user's role holds, so a key can never do more than its
+// owner.
+func (s *Service) CreateAPIKey(ctx context.Context, userID uuid.UUID, req *model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+
+	scopes, err := grantableScopes(authz.Role(user.UserType), req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key := model.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:len(model.APIKeyPrefix)+8],
+		KeyHash:   hashSecret(secret),
+		Scopes:    scopes,
+		RateLimit: req.RateLimit,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateAPIKey(ctx, &key); err != nil {
+		return nil, err
+	}
+	s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventCreated, strings.Join(scopes, " "))
+
+	s.logger.Info("API key created", "user_id", userID, "api_key_id", key.ID)
+	return &model.CreatedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+// ListAPIKeys returns the user's API keys that have not been revoked
+func (s *Service) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	keys, err := s.repo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's API keys. Access tokens issued for
+// the key are denied until the longest of them would have expired.
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	if err := s.repo.RevokeAPIKey(ctx, userID, keyID); err != nil {
+		return err
+	}
+	s.recordAPIKeyEvent(ctx, keyID, model.APIKeyEventRevoked, "")
+
+	if s.denylist != nil {
+		until := time.Now().Add(s.jwtManager.AccessTokenTTL())
+		if err := s.denylist.Revoke(ctx, keyID.String(), until); err != nil {
+			s.logger.Error("Failed to deny API key", "api_key_id", keyID, "error", err)
+		}
+	}
+
+	s.logger.Info("API key revoked", "user_id", userID, "api_key_id", keyID)
+	return nil
+}
+
+// ListAPIKeyEvents returns the latest entries of the audit trail of one of
+// the user's API keys
+func (s *Service) ListAPIKeyEvents(ctx context.Context, userID, keyID uuid.UUID) ([]model.APIKeyEvent, error) {
+	if _, err := s.repo.GetAPIKey(ctx, userID, keyID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.repo.ListAPIKeyEvents(ctx, keyID, apiKeyEventLimit)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []model.APIKeyEvent{}
+	}
+	return events, nil
+}
+
+// VerifyAPIKey authenticates a request made with an API key and returns who
+// the key acts as
+func (s *Service) VerifyAPIKey(ctx context.Context, secret string) (*model.APIKeyIdentity, error) {
+	key, user, err := s.activeAPIKey(ctx, secret)
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	s.touchAPIKey(ctx, key)
+	return &model.APIKeyIdentity{
+		KeyID:     key.ID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		UserType:  user.UserType,
+		Scopes:    key.Scopes,
+		RateLimit: key.RateLimit,
+	}, nil
+}
+
+// IssueClientToken implements the OAuth 2.0 client credentials grant: the
+// key's ID is the client ID and the key itself the client secret. scope,
+// when set, narrows the token to some of the key's scopes.
+func (s *Service) IssueClientToken(ctx context.Context, clientID, clientSecret, scope string) (*model.ClientTokenResponse, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	key, user, err := s.activeAPIKey(ctx, clientSecret)
+	if err != nil {
+		if err == repository.ErrAPIKeyNotFound || err == ErrInvalidAPIKey || err == ErrUserNotActive {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	if key.ID != id {
+		return nil, ErrInvalidClient
+	}
+
+	scopes := key.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, perm := range requested {
+			if !contains(key.Scopes, perm) {
+				s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventRejected, "scope not granted: "+perm)
+				return nil, fmt.Errorf("%w: %s", ErrInvalidScope, perm)
+			}
+		}
+		scopes = requested
+	}
+
+	token, err := s.jwtManager.GenerateClientToken(key.ID.String(), user.ID.String(), user.Email, string(user.UserType), scopes, key.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.touchAPIKey(ctx, key)
+	s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventTokenIssued, strings.Join(scopes, " "))
+
+	return &model.ClientTokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// activeAPIKey looks up a key by its secret and checks that both the key
+// and its owner can still be used. Presenting a revoked or expired key is
+// recorded in its audit trail.
+func (s *Service) activeAPIKey(ctx context.Context, secret string) (*model.APIKey, *model.User, error) {
+	if !strings.HasPrefix(secret, model.APIKeyPrefix) {
+		return nil, nil, repository.ErrAPIKeyNotFound
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(ctx, hashSecret(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !key.Active(time.Now()) {
+		reason := "expired"
+		if key.RevokedAt != nil {
+			reason = "revoked"
+		}
+		s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventRejected, "key "+reason)
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.repo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventRejected, "owner not active")
+		return nil, nil, ErrUserNotActive
+	}
+
+	return key, user, nil
+}
+
+// touchAPIKey records the key's use, adding an audit entry the first time
+// it is used from a new address
+func (s *Service) touchAPIKey(ctx context.Context, key *model.APIKey) {
+	ip := clientInfoFrom(ctx).IPAddress
+	if err := s.repo.TouchAPIKey(ctx, key.ID, ip, time.Now()); err != nil {
+		s.logger.Error("Failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+	if ip != "" && ip != key.LastUsedIP {
+		s.recordAPIKeyEvent(ctx, key.ID, model.APIKeyEventNewAddress, "")
+	}
+}
+
+func (s *Service) recordAPIKeyEvent(ctx context.Context, keyID uuid.UUID, event, detail string) {
+	e := &model.APIKeyEvent{
+		ID:        uuid.New(),
+		APIKeyID:  keyID,
+		Event:     event,
+		IPAddress: clientInfoFrom(ctx).IPAddress,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAPIKeyEvent(ctx, e); err != nil {
+		s.logger.Error("Failed to record API key event", "api_key_id", keyID, "event", event, "error", err)
+	}
+}
+
+// grantableScopes checks that every scope is a permission the role holds and
+// returns them without duplicates
+func grantableScopes(role authz.Role, scopes []string) ([]string, error) {
+	policy := authz.DefaultPolicy()
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		perm := authz.Permission(scope)
+		if !knownPermission(perm) || policy.Scope(role, perm) == authz.ScopeNone {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func knownPermission(perm authz.Permission) bool {
+	for _, p := range authz.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAPIKey returns a new random API key
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return model.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret returns the hex SHA-256 of an API key, invitation token or
+// single sign-on state. Each carries 256 bits of randomness, so a fast
+// unsalted hash is enough to keep stored hashes from being useful.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/jwt"
+)
+
+var (
+	ErrDelegationNotAllowed = errors.New("only shippers and fleet operators can delegate")
+	ErrNotDispatcher        = errors.New("rights can only be delegated to an active dispatcher")
+	ErrInvalidDelegation    = errors.New("delegation has expired or its grantor is not active")
+)
+
+// CreateDelegation lets the dispatcher with the given email act for the
+// user, limited to the delegated rights
+func (s *Service) CreateDelegation(ctx context.Context, userID uuid.UUID, req *model.CreateDelegationRequest) (*model.Delegation, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+	if user.UserType != model.UserTypeShipper && user.UserType != model.UserTypeFleet {
+		return nil, ErrDelegationNotAllowed
+	}
+
+	dispatcher, err := s.repo.GetUserByEmail(ctx, req.DispatcherEmail)
+	if err == repository.ErrUserNotFound {
+		return nil, ErrNotDispatcher
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dispatcher.UserType != model.UserTypeDispatcher || dispatcher.Status != model.UserStatusActive {
+		return nil, ErrNotDispatcher
+	}
+
+	rights := make([]string, 0, len(req.Rights))
+	for _, right := range req.Rights {
+		if !contains(rights, right) {
+			rights = append(rights, right)
+		}
+	}
+
+	now := time.Now()
+	d := model.Delegation{
+		ID:           uuid.New(),
+		GrantorID:    userID,
+		DispatcherID: dispatcher.ID,
+		Rights:       rights,
+		CreatedAt:    now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		d.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateDelegation(ctx, &d); err != nil {
+		return nil, err
+	}
+	s.recordSecurityEvent(ctx, userID, model.SecurityDelegationGranted, dispatcher.Email+": "+strings.Join(rights, " "))
+
+	s.logger.Info("Delegation created", "user_id", userID, "dispatcher_id", dispatcher.ID, "delegation_id", d.ID)
+	return &d, nil
+}
+
+// ListDelegations returns the delegations the user granted or was granted
+// that have not been revoked
+func (s *Service) ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error) {
+	delegations, err := s.repo.ListDelegations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if delegations == nil {
+		delegations = []model.Delegation{}
+	}
+	return delegations, nil
+}
+
+// RevokeDelegation revokes a delegation the user granted or was granted.
+// Acting-as tokens issued for it are denied until the longest of them would
+// have expired.
+func (s *Service) RevokeDelegation(ctx context.Context, userID, delegationID uuid.UUID) error {
+	if err := s.repo.RevokeDelegation(ctx, userID, delegationID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, userID, model.SecurityDelegationRevoked, delegationID.String())
+
+	if s.denylist != nil {
+		until := time.Now().Add(s.jwtManager.AccessTokenTTL())
+		if err := s.denylist.Revoke(ctx, delegationID.String(), until); err != nil {
+			s.logger.Error("Failed to deny delegation", "delegation_id", delegationID, "error", err)
+		}
+	}
+
+	s.logger.Info("Delegation revoked", "user_id", userID, "delegation_id", delegationID)
+	return nil
+}
+
+// IssueActingToken issues the dispatcher an access token acting for the
+// user who delegated to them. The token's scopes are the permissions of the
+// delegated rights that the grantor's role holds, and it expires no later
+// than the delegation.
+func (s *Service) IssueActingToken(ctx context.Context, dispatcherID, delegationID uuid.UUID) (*model.ActingTokenResponse, error) {
+	d, err := s.repo.GetDelegation(ctx, delegationID)
+	if err != nil {
+		return nil, err
+	}
+	if d.DispatcherID != dispatcherID {
+		return nil, repository.ErrDelegationNotFound
+	}
+	now := time.Now()
+	if !d.Active(now) {
+		return nil, ErrInvalidDelegation
+	}
+
+	dispatcher, err := s.repo.GetUserByID(ctx, dispatcherID)
+	if err != nil {
+		return nil, err
+	}
+	if dispatcher.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+	grantor, err := s.repo.GetUserByID(ctx, d.GrantorID)
+	if err != nil {
+		return nil, err
+	}
+	if grantor.Status != model.UserStatusActive {
+		return nil, ErrInvalidDelegation
+	}
+
+	rights := make([]authz.Right, len(d.Rights))
+	for i, right := range d.Rights {
+		rights[i] = authz.Right(right)
+	}
+	perms := authz.DefaultPolicy().Delegate(authz.Role(grantor.UserType), rights)
+	scopes := make([]string, len(perms))
+	for i, perm := range perms {
+		scopes[i] = string(perm)
+	}
+
+	var ttl time.Duration
+	if d.ExpiresAt != nil {
+		ttl = d.ExpiresAt.Sub(now)
+	}
+	actor := jwt.Actor{UserID: dispatcher.ID.String(), UserType: string(dispatcher.UserType)}
+	token, err := s.jwtManager.GenerateActingToken(d.ID.String(), grantor.ID.String(), grantor.Email, string(grantor.UserType), actor, scopes, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(scopes, " ")
+	s.recordActorSecurityEvent(ctx, grantor.ID, &dispatcher.ID, model.SecurityActingTokenIssued, scope)
+
+	return &model.ActingTokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       scope,
+		ActingAs:    grantor.ID,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/jwt"
+)
+
+func TestCreateDelegation(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	shipper := &model.User{ID: uuid.New(), Email: "ops@example.com", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+	driver := &model.User{ID: uuid.New(), Email: "driver@example.com", UserType: model.UserTypeDriver, Status: model.UserStatusActive}
+	dispatcher := &model.User{ID: uuid.New(), Email: "desk@example.com", UserType: model.UserTypeDispatcher, Status: model.UserStatusActive}
+	mockRepo.On("GetUserByID", ctx, shipper.ID).Return(shipper, nil)
+	mockRepo.On("GetUserByID", ctx, driver.ID).Return(driver, nil)
+	mockRepo.On("GetUserByEmail", ctx, dispatcher.Email).Return(dispatcher, nil)
+	mockRepo.On("GetUserByEmail", ctx, driver.Email).Return(driver, nil)
+	mockRepo.On("CreateDelegation", ctx, mock.AnythingOfType("*model.Delegation")).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(shipper.ID, model.SecurityDelegationGranted)).Return(nil)
+
+	d, err := svc.CreateDelegation(ctx, shipper.ID, &model.CreateDelegationRequest{
+		DispatcherEmail: dispatcher.Email,
+		Rights:          []string{"post_jobs", "message", "post_jobs"},
+		ExpiresInDays:   30,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dispatcher.ID, d.DispatcherID)
+	assert.Equal(t, []string{"post_jobs", "message"}, d.Rights)
+	require.NotNil(t, d.ExpiresAt)
+
+	// Only shippers and fleet operators delegate, and only to dispatchers
+	_, err = svc.CreateDelegation(ctx, driver.ID, &model.CreateDelegationRequest{DispatcherEmail: dispatcher.Email, Rights: []string{"message"}})
+	assert.Equal(t, service.ErrDelegationNotAllowed, err)
+	_, err = svc.CreateDelegation(ctx, shipper.ID, &model.CreateDelegationRequest{DispatcherEmail: driver.Email, Rights: []string{"message"}})
+	assert.Equal(t, service.ErrNotDispatcher, err)
+	mockRepo.AssertNumberOfCalls(t, "CreateDelegation", 1)
+}
+
+func TestIssueActingToken(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	shipper := &model.User{ID: uuid.New(), Email: "ops@example.com", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+	dispatcher := &model.User{ID: uuid.New(), Email: "desk@example.com", UserType: model.UserTypeDispatcher, Status: model.UserStatusActive}
+	expiresAt := time.Now().Add(5 * time.Minute)
+	d := &model.Delegation{ID: uuid.New(), GrantorID: shipper.ID, DispatcherID: dispatcher.ID, Rights: []string{"post_jobs", "assign_drivers"}, ExpiresAt: &expiresAt}
+	mockRepo.On("GetDelegation", ctx, d.ID).Return(d, nil)
+	mockRepo.On("GetUserByID", ctx, shipper.ID).Return(shipper, nil)
+	mockRepo.On("GetUserByID", ctx, dispatcher.ID).Return(dispatcher, nil)
+	mockRepo.On("CreateSecurityEvent", ctx, mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.UserID == shipper.ID && e.Type == model.SecurityActingTokenIssued && e.ActorID != nil && *e.ActorID == dispatcher.ID
+	})).Return(nil)
+
+	// Nobody else can use the delegation
+	_, err := svc.IssueActingToken(ctx, uuid.New(), d.ID)
+	assert.Equal(t, repository.ErrDelegationNotFound, err)
+
+	token, err := svc.IssueActingToken(ctx, dispatcher.ID, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shipper.ID, token.ActingAs)
+	assert.LessOrEqual(t, token.ExpiresIn, int64(5*60))
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	claims, err := jwtManager.ValidateAccessToken(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, shipper.ID.String(), claims.UserID)
+	assert.Equal(t, dispatcher.ID.String(), claims.Actor.UserID)
+	assert.Equal(t, d.ID.String(), claims.DelegationID)
+
+	// Shippers do not see fleets, so neither do their dispatchers
+	assert.Contains(t, claims.Scopes(), "job:create")
+	assert.Contains(t, claims.Scopes(), "job:assign")
+	assert.NotContains(t, claims.Scopes(), "fleet:read")
+	assert.Equal(t, strings.Join(claims.Scopes(), " "), token.Scope)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestIssueActingToken_Expired(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	dispatcherID := uuid.New()
+	expiresAt := time.Now().Add(-time.Minute)
+	d := &model.Delegation{ID: uuid.New(), GrantorID: uuid.New(), DispatcherID: dispatcherID, Rights: []string{"message"}, ExpiresAt: &expiresAt}
+	mockRepo.On("GetDelegation", ctx, d.ID).Return(d, nil)
+
+	_, err := svc.IssueActingToken(ctx, dispatcherID, d.ID)
+	assert.Equal(t, service.ErrInvalidDelegation, err)
+}
+
+func TestRevokeDelegation_DeniesActingTokens(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	denylist := &fakeDenylist{revoked: map[string]time.Time{}}
+	svc.SetDenylist(denylist)
+	ctx := context.Background()
+
+	userID, delegationID := uuid.New(), uuid.New()
+	mockRepo.On("RevokeDelegation", ctx, userID, delegationID).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(userID, model.SecurityDelegationRevoked)).Return(nil)
+
+	require.NoError(t, svc.RevokeDelegation(ctx, userID, delegationID))
+	assert.Contains(t, denylist.revoked, delegationID.String())
+}
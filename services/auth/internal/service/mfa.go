@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/totp"
+)
+
+var (
+	ErrInvalidMFAToken         = errors.New("invalid or expired MFA token")
+	ErrInvalidMFACode          = errors.New("invalid MFA code")
+	ErrMFAAlreadyEnabled       = errors.New("a second factor is already enabled")
+	ErrMFANotEnabled           = errors.New("no second factor is enabled")
+	ErrMFAEnrollmentNotStarted = errors.New("TOTP enrolment has not been started")
+	ErrMFARequired             = errors.New("a second factor is required for this account")
+)
+
+const (
+	// mfaIssuer names the account in authenticator apps
+	mfaIssuer = "Truckify"
+	// mfaChallengeTTL is how long a login has to present its second factor
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts is how many wrong codes a challenge takes before it
+	// is spent and the login has to start over
+	mfaMaxAttempts = 5
+
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// SetMFAPolicy sets the user types that must use a second factor with
+// their password or SMS code. Passkey logins are not affected.
+func (s *Service) SetMFAPolicy(required []model.UserType) {
+	s.mfaRequired = make(map[model.UserType]bool, len(required))
+	for _, userType := range required {
+		s.mfaRequired[userType] = true
+	}
+}
+
+// beginSession finishes a password or SMS code login. Users with a second
+// factor, and users whose type requires one, get an MFA challenge instead
+// of tokens.
+func (s *Service) beginSession(ctx context.Context, user *model.User, method string) (*model.LoginResponse, error) {
+	mfa, err := s.getMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() || s.mfaRequired[user.UserType] {
+		return s.mfaChallenge(ctx, user, !mfa.Enabled())
+	}
+	return s.completeLogin(ctx, user, method, nil)
+}
+
+// completeLogin starts a session for a user who has passed every check.
+// method names how they logged in for the security log.
+func (s *Service) completeLogin(ctx context.Context, user *model.User, method string, recoveryCodes []string) (*model.LoginResponse, error) {
+	if err := s.repo.UpdateLastLogin(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to update last login", "user_id", user.ID, "error", err)
+	}
+
+	// Only a login that passed every factor forgets the account's failures
+	if user.Email != "" {
+		if err := s.repo.ClearLoginFailures(ctx, accountThrottleKey(user.Email)); err != nil {
+			s.logger.Error("Failed to clear login failures", "user_id", user.ID, "error", err)
+		}
+	}
+
+	s.logger.Info("User logged in", "user_id", user.ID, "email", user.Email)
+	s.loginSucceeded(ctx, user, method)
+
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		User:          user.ToUserResponse(),
+		AccessToken:   tokens.AccessToken,
+		RefreshToken:  tokens.RefreshToken,
+		ExpiresIn:     tokens.ExpiresIn,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// mfaChallenge records a login waiting on its second factor and returns
+// the token that stands for it
+func (s *Service) mfaChallenge(ctx context.Context, user *model.User, enroll bool) (*model.LoginResponse, error) {
+	now := time.Now()
+	challenge := &model.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(mfaChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	token, err := s.jwtManager.GenerateMFAToken(challenge.ID.String(), user.ID.String(), user.Email, string(user.UserType), mfaChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("MFA challenge issued", "user_id", user.ID, "enrollment_required", enroll)
+	return &model.LoginResponse{
+		MFA: &model.MFAPending{
+			Token:              token,
+			ExpiresIn:          int64(mfaChallengeTTL.Seconds()),
+			EnrollmentRequired: enroll,
+		},
+	}, nil
+}
+
+// VerifyMFALogin completes a login with a TOTP or recovery code. A login
+// that had to enrol confirms the enrolment with its first TOTP code and
+// returns the new recovery codes with the tokens.
+func (s *Service) VerifyMFALogin(ctx context.Context, req *model.MFALoginRequest) (*model.LoginResponse, error) {
+	challenge, user, err := s.challengeFromToken(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.getMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+
+	// Wrong codes count against the account like wrong passwords, so logging
+	// in again for a fresh challenge does not buy more guesses
+	throttleKeys := loginThrottleKeys(ctx, user.Email)
+	if err := s.checkLoginThrottle(ctx, throttleKeys); err != nil {
+		return nil, err
+	}
+	if err := s.attemptChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	var recoveryCodes []string
+	if mfa.Enabled() {
+		ok, err = s.checkCode(ctx, mfa, req.Code, true)
+	} else {
+		recoveryCodes, ok, err = s.confirmEnrollment(ctx, mfa, req.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.loginFailed(ctx, throttleKeys, user)
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.repo.UseMFAChallenge(ctx, challenge.ID, mfaMaxAttempts); err != nil {
+		if err == repository.ErrMFAChallengeNotFound {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, "mfa", recoveryCodes)
+}
+
+// BeginMFALoginEnrollment starts TOTP enrolment for a login whose user type
+// requires a second factor the user has not set up
+func (s *Service) BeginMFALoginEnrollment(ctx context.Context, mfaToken string) (*model.TOTPEnrollment, error) {
+	_, user, err := s.challengeFromToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginEnrollment(ctx, user)
+}
+
+// GetMFAStatus describes the user's second factor
+func (s *Service) GetMFAStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.MFAStatus{Required: s.mfaRequired[user.UserType]}
+	if mfa.Enabled() {
+		codes, err := s.repo.ListRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.Enabled = true
+		status.EnabledAt = mfa.EnabledAt
+		status.RecoveryCodesRemaining = len(codes)
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a TOTP secret for the user. It has to be
+// confirmed with a code before it is used.
+func (s *Service) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginEnrollment(ctx, user)
+}
+
+// ConfirmTOTPEnrollment enables the user's pending TOTP secret with a code
+// from their authenticator app and returns their recovery codes
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error) {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	codes, ok, err := s.confirmEnrollment(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA removes the user's second factor after checking a TOTP or
+// recovery code. Users whose type requires a second factor cannot.
+func (s *Service) DisableMFA(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.mfaRequired[user.UserType] {
+		return ErrMFARequired
+	}
+	mfa, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.checkCode(ctx, mfa, code, true)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	if err := s.repo.DeleteMFA(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Info("MFA disabled", "user_id", userID)
+	s.recordSecurityEvent(ctx, userID, model.SecurityMFADisabled, "")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking
+// a TOTP code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*model.RecoveryCodesResponse, error) {
+	mfa, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.checkCode(ctx, mfa, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashed, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashed); err != nil {
+		return nil, err
+	}
+	s.logger.Info("MFA recovery codes regenerated", "user_id", userID)
+	s.recordSecurityEvent(ctx, userID, model.SecurityRecoveryCodesRegenerated, "")
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// ResetMFA removes a user's second factor and recovery codes, for users who
+// have lost both (admin only). If their type requires a second factor they
+// enrol again at their next login.
+func (s *Service) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.repo.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMFA(ctx, userID); err != nil {
+		return err
+	}
+	s.logger.Warn("MFA reset by admin", "user_id", userID)
+	s.recordSecurityEvent(ctx, userID, model.SecurityMFAReset, "by an administrator")
+	return nil
+}
+
+// challengeFromToken returns the open challenge an MFA token stands for and
+// its user
+func (s *Service) challengeFromToken(ctx context.Context, token string) (*model.MFAChallenge, *model.User, error) {
+	claims, err := s.jwtManager.ValidateMFAToken(token)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	challenge, err := s.repo.GetMFAChallenge(ctx, id)
+	if err != nil {
+		if err == repository.ErrMFAChallengeNotFound {
+			return nil, nil, ErrInvalidMFAToken
+		}
+		return nil, nil, err
+	}
+	if challenge.UserID.String() != claims.UserID || challenge.UsedAt != nil ||
+		challenge.Attempts >= mfaMaxAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, nil, ErrUserNotActive
+	}
+	return challenge, user, nil
+}
+
+// attemptChallenge counts a guess against a challenge before its code is
+// checked, refusing challenges that have had all their guesses
+func (s *Service) attemptChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	attempts, err := s.repo.AttemptMFAChallenge(ctx, challenge.ID, mfaMaxAttempts)
+	if err != nil {
+		if err == repository.ErrMFAChallengeNotFound {
+			return ErrInvalidMFAToken
+		}
+		return err
+	}
+	if attempts == mfaMaxAttempts {
+		s.logger.Warn("MFA challenge on its last attempt", "user_id", challenge.UserID, "challenge_id", challenge.ID)
+	}
+	return nil
+}
+
+// beginEnrollment stores a new pending TOTP secret for the user
+func (s *Service) beginEnrollment(ctx context.Context, user *model.User) (*model.TOTPEnrollment, error) {
+	mfa, err := s.getMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePendingMFA(ctx, &model.MFA{UserID: user.ID, Secret: secret, CreatedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, mfaIssuer, user.Email),
+	}, nil
+}
+
+// confirmEnrollment enables a pending TOTP secret if code matches it and
+// returns the user's first recovery codes
+func (s *Service) confirmEnrollment(ctx context.Context, mfa *model.MFA, code string) ([]string, bool, error) {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+
+	codes, hashed, err := newRecoveryCodes(mfa.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.repo.EnableMFA(ctx, mfa.UserID, step, hashed); err != nil {
+		if err == repository.ErrMFANotFound {
+			// Confirmed concurrently, or replaced by a new enrolment
+			return nil, false, ErrMFAAlreadyEnabled
+		}
+		return nil, false, err
+	}
+
+	s.logger.Info("MFA enabled", "user_id", mfa.UserID)
+	s.recordSecurityEvent(ctx, mfa.UserID, model.SecurityMFAEnabled, "totp")
+	return codes, true, nil
+}
+
+// checkCode checks a TOTP code, or a recovery code when allowed, and uses
+// it up
+func (s *Service) checkCode(ctx context.Context, mfa *model.MFA, code string, allowRecovery bool) (bool, error) {
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now()); ok {
+		return s.repo.UseTOTPStep(ctx, mfa.UserID, step)
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false, nil
+	}
+	codes, err := s.repo.ListRecoveryCodes(ctx, mfa.UserID)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) == nil {
+			used, err := s.repo.UseRecoveryCode(ctx, c.ID)
+			if used {
+				s.logger.Info("MFA recovery code used", "user_id", mfa.UserID, "remaining", len(codes)-1)
+				s.recordSecurityEvent(ctx, mfa.UserID, model.SecurityRecoveryCodeUsed, fmt.Sprintf("%d remaining", len(codes)-1))
+			}
+			return used, err
+		}
+	}
+	return false, nil
+}
+
+// getMFA returns the user's second factor, or nil if they have none
+func (s *Service) getMFA(ctx context.Context, userID uuid.UUID) (*model.MFA, error) {
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err == repository.ErrMFANotFound {
+		return nil, nil
+	}
+	return mfa, err
+}
+
+// enabledMFA returns the user's confirmed second factor
+func (s *Service) enabledMFA(ctx context.Context, userID uuid.UUID) (*model.MFA, error) {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, ErrMFANotEnabled
+	}
+	return mfa, nil
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes, formatted for the
+// user, and their hashes for storage
+func newRecoveryCodes(userID uuid.UUID) ([]string, []model.RecoveryCode, error) {
+	now := time.Now()
+	codes := make([]string, recoveryCodeCount)
+	hashed := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+		hash, err := bcrypt.GenerateFromPassword(b, bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashed[i] = model.RecoveryCode{ID: uuid.New(), UserID: userID, CodeHash: string(hash), CreatedAt: now}
+	}
+	return codes, hashed, nil
+}
+
+// normalizeRecoveryCode drops the separator and case a user may type
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,299 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+	"truckify/services/auth/internal/totp"
+)
+
+// passwordUser returns an active user whose password is password123
+func passwordUser(userType model.UserType) *model.User {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	return &model.User{
+		ID:           uuid.New(),
+		Email:        "fleet@example.com",
+		PasswordHash: string(passwordHash),
+		UserType:     userType,
+		Status:       model.UserStatusActive,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// expectChallenge records the challenge a login creates and serves it back
+func expectChallenge(mockRepo *MockRepository, ctx context.Context) *model.MFAChallenge {
+	challenge := &model.MFAChallenge{}
+	mockRepo.On("CreateMFAChallenge", ctx, mock.AnythingOfType("*model.MFAChallenge")).
+		Run(func(args mock.Arguments) { *challenge = *args.Get(1).(*model.MFAChallenge) }).
+		Return(nil)
+	mockRepo.On("GetMFAChallenge", ctx, mock.AnythingOfType("uuid.UUID")).
+		Return(challenge, nil)
+	mockRepo.On("AttemptMFAChallenge", ctx, mock.AnythingOfType("uuid.UUID"), 5).
+		Return(1, nil).Maybe()
+	return challenge
+}
+
+func currentCode(t *testing.T, secret string) (string, int64) {
+	t.Helper()
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+	return code, step
+}
+
+func TestLogin_MFAEnabledReturnsChallenge(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
+
+	user := passwordUser(model.UserTypeShipper)
+	secret, _ := totp.GenerateSecret()
+	enabledAt := time.Now()
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(&model.MFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+	challenge := expectChallenge(mockRepo, ctx)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.AccessToken)
+	assert.Nil(t, resp.User)
+	require.NotNil(t, resp.MFA)
+	assert.False(t, resp.MFA.EnrollmentRequired)
+	assert.Equal(t, int64(300), resp.MFA.ExpiresIn)
+
+	code, step := currentCode(t, secret)
+	mockRepo.On("UseTOTPStep", ctx, user.ID, step).Return(true, nil).Once()
+	mockRepo.On("UseMFAChallenge", ctx, challenge.ID, 5).Return(nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	done, err := svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: code})
+	require.NoError(t, err)
+	assert.NotEmpty(t, done.AccessToken)
+	assert.Equal(t, user.Email, done.User.Email)
+	assert.Empty(t, done.RecoveryCodes)
+
+	// The same code cannot be used twice
+	mockRepo.On("UseTOTPStep", ctx, user.ID, step).Return(false, nil)
+	_, err = svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: code})
+	assert.Equal(t, service.ErrInvalidMFACode, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogin_RequiredTypeEnrolsFirst(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	svc.SetMFAPolicy([]model.UserType{model.UserTypeFleet})
+	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
+
+	user := passwordUser(model.UserTypeFleet)
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(nil, repository.ErrMFANotFound).Twice()
+	challenge := expectChallenge(mockRepo, ctx)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.MFA)
+	assert.True(t, resp.MFA.EnrollmentRequired)
+
+	var pending *model.MFA
+	mockRepo.On("SavePendingMFA", ctx, mock.AnythingOfType("*model.MFA")).
+		Run(func(args mock.Arguments) { pending = args.Get(1).(*model.MFA) }).Return(nil)
+	enrollment, err := svc.BeginMFALoginEnrollment(ctx, resp.MFA.Token)
+	require.NoError(t, err)
+	assert.Equal(t, pending.Secret, enrollment.Secret)
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/Truckify:fleet@example.com?")
+
+	code, step := currentCode(t, pending.Secret)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(pending, nil)
+	mockRepo.On("EnableMFA", ctx, user.ID, step, mock.MatchedBy(func(codes []model.RecoveryCode) bool {
+		return len(codes) == 10
+	})).Return(nil)
+	mockRepo.On("UseMFAChallenge", ctx, challenge.ID, 5).Return(nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	done, err := svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: code})
+	require.NoError(t, err)
+	assert.NotEmpty(t, done.AccessToken)
+	assert.Len(t, done.RecoveryCodes, 10)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, done.RecoveryCodes[0])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVerifyMFALogin_RecoveryCode(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
+
+	user := passwordUser(model.UserTypeDriver)
+	secret, _ := totp.GenerateSecret()
+	enabledAt := time.Now()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("abcdefghij"), bcrypt.MinCost)
+	codeID := uuid.New()
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(&model.MFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+	mockRepo.On("ListRecoveryCodes", ctx, user.ID).Return([]model.RecoveryCode{
+		{ID: uuid.New(), CodeHash: "$2a$04$not-a-match"},
+		{ID: codeID, CodeHash: string(hash)},
+	}, nil)
+	mockRepo.On("UseRecoveryCode", ctx, codeID).Return(true, nil)
+	challenge := expectChallenge(mockRepo, ctx)
+	mockRepo.On("UseMFAChallenge", ctx, mock.AnythingOfType("uuid.UUID"), 5).Return(nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	// Recovery codes are accepted however the user types them
+	done, err := svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: "ABCDE-FGHIJ"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, done.AccessToken)
+	mockRepo.AssertCalled(t, "UseMFAChallenge", ctx, challenge.ID, 5)
+}
+
+func TestVerifyMFALogin_SpentChallenge(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
+
+	user := passwordUser(model.UserTypeDriver)
+	enabledAt := time.Now()
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(&model.MFA{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt}, nil)
+	challenge := expectChallenge(mockRepo, ctx)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	// Too many wrong codes, expired or already used: the login starts over
+	for name, spend := range map[string]func(){
+		"attempts": func() { challenge.Attempts = 5 },
+		"expired":  func() { challenge.ExpiresAt = time.Now().Add(-time.Second) },
+		"used":     func() { now := time.Now(); challenge.UsedAt = &now },
+	} {
+		saved := *challenge
+		spend()
+		_, err := svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: "123456"})
+		assert.Equal(t, service.ErrInvalidMFAToken, err, name)
+		*challenge = saved
+	}
+
+	// Anything but an MFA token is refused
+	_, err = svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: "not-a-token", Code: "123456"})
+	assert.Equal(t, service.ErrInvalidMFAToken, err)
+	mockRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
+func TestVerifyMFALogin_ExhaustedChallenge(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
+
+	user := passwordUser(model.UserTypeDriver)
+	secret, _ := totp.GenerateSecret()
+	enabledAt := time.Now()
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(&model.MFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+	challenge := &model.MFAChallenge{}
+	mockRepo.On("CreateMFAChallenge", ctx, mock.AnythingOfType("*model.MFAChallenge")).
+		Run(func(args mock.Arguments) { *challenge = *args.Get(1).(*model.MFAChallenge) }).
+		Return(nil)
+	mockRepo.On("GetMFAChallenge", ctx, mock.AnythingOfType("uuid.UUID")).Return(challenge, nil)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	// Concurrent guesses took the last attempt after the challenge was read:
+	// the code is not even checked
+	mockRepo.On("AttemptMFAChallenge", ctx, challenge.ID, 5).Return(0, repository.ErrMFAChallengeNotFound)
+	code, _ := currentCode(t, secret)
+	_, err = svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: code})
+	assert.Equal(t, service.ErrInvalidMFAToken, err)
+	mockRepo.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
+
+func TestVerifyMFALogin_WrongCodesLockAccount(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	user := passwordUser(model.UserTypeDriver)
+	enabledAt := time.Now()
+	mockRepo.On("GetLoginThrottles", ctx, []string{"account:fleet@example.com"}).Return(nil, nil).Twice()
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(&model.MFA{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt}, nil)
+	expectChallenge(mockRepo, ctx)
+
+	// The password alone does not forget earlier failures
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.MFA)
+	mockRepo.AssertNotCalled(t, "ClearLoginFailures", mock.Anything, mock.Anything)
+
+	// A wrong code counts against the account like a wrong password
+	mockRepo.On("RecordLoginFailure", ctx, "account:fleet@example.com", mock.Anything, mock.Anything).
+		Return(&model.LoginThrottle{Key: "account:fleet@example.com", Failures: 10}, nil)
+	mockRepo.On("LockLogin", ctx, "account:fleet@example.com", mock.Anything).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityAccountLocked)).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityLoginFailed)).Return(nil)
+	_, err = svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: "000000"})
+	assert.Equal(t, service.ErrInvalidMFACode, err)
+
+	// Once locked, the challenge takes no more guesses
+	lockedUntil := time.Now().Add(15 * time.Minute)
+	mockRepo.On("GetLoginThrottles", ctx, []string{"account:fleet@example.com"}).
+		Return([]model.LoginThrottle{{Key: "account:fleet@example.com", Failures: 10, LockedUntil: &lockedUntil}}, nil)
+	_, err = svc.VerifyMFALogin(ctx, &model.MFALoginRequest{MFAToken: resp.MFA.Token, Code: "000000"})
+	var throttled *service.ThrottledError
+	assert.ErrorAs(t, err, &throttled)
+	mockRepo.AssertNumberOfCalls(t, "AttemptMFAChallenge", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDisableMFA_RequiredByPolicy(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	svc.SetMFAPolicy([]model.UserType{model.UserTypeFleet, model.UserTypeAdmin})
+	ctx := context.Background()
+
+	user := passwordUser(model.UserTypeFleet)
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+
+	err := svc.DisableMFA(ctx, user.ID, "123456")
+
+	assert.Equal(t, service.ErrMFARequired, err)
+	mockRepo.AssertNotCalled(t, "DeleteMFA", mock.Anything, mock.Anything)
+}
+
+func TestRegister_RequiredTypeGetsChallenge(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	svc.SetMFAPolicy([]model.UserType{model.UserTypeFleet})
+	ctx := context.Background()
+
+	mockRepo.On("GetUserByEmail", ctx, "fleet@example.com").Return(nil, repository.ErrUserNotFound)
+	mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User")).Return(nil)
+	mockRepo.On("CreateMFAChallenge", ctx, mock.AnythingOfType("*model.MFAChallenge")).Return(nil)
+
+	resp, err := svc.Register(ctx, &model.RegisterRequest{Email: "fleet@example.com", Password: "password123", UserType: model.UserTypeFleet})
+
+	require.NoError(t, err)
+	assert.Empty(t, resp.AccessToken)
+	require.NotNil(t, resp.MFA)
+	assert.True(t, resp.MFA.EnrollmentRequired)
+	mockRepo.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/shared/pkg/jwt"
+)
+
+var (
+	ErrOrgPermission           = errors.New("insufficient organisation role")
+	ErrPersonalOrganisation    = errors.New("personal organisations have no other members")
+	ErrInvalidInvitation       = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to another email address")
+	ErrAlreadyMember           = errors.New("already a member of the organisation")
+	ErrLastOwner               = errors.New("an organisation needs at least one owner")
+)
+
+// CreateOrganisation creates an organisation owned by the user
+func (s *Service) CreateOrganisation(ctx context.Context, userID uuid.UUID, req *model.CreateOrganisationRequest) (*model.OrganisationResponse, error) {
+	now := time.Now()
+	org := model.Organisation{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(req.Name),
+		CreatedBy: userID,
+		CreatedAt: now,
+	}
+	owner := &model.Membership{OrgID: org.ID, UserID: userID, Role: model.OrgRoleOwner, CreatedAt: now}
+	if err := s.repo.CreateOrganisation(ctx, &org, owner); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Organisation created", "org_id", org.ID, "user_id", userID)
+	return &model.OrganisationResponse{Organisation: org, Role: model.OrgRoleOwner}, nil
+}
+
+// ListOrganisations returns the organisations the user belongs to, flagging
+// the one their session acts for
+func (s *Service) ListOrganisations(ctx context.Context, userID uuid.UUID, currentOrgID string) ([]model.OrganisationResponse, error) {
+	orgs, err := s.repo.ListUserOrganisations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if orgs == nil {
+		orgs = []model.OrganisationResponse{}
+	}
+	for i := range orgs {
+		orgs[i].Current = orgs[i].ID.String() == currentOrgID
+	}
+	return orgs, nil
+}
+
+// ListMembers returns the members of one of the user's organisations
+func (s *Service) ListMembers(ctx context.Context, userID, orgID uuid.UUID) ([]model.Membership, error) {
+	if _, err := s.membership(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	members, err := s.repo.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []model.Membership{}
+	}
+	return members, nil
+}
+
+// InviteMember emails an invitation to join the organisation. Owners and
+// admins can invite; only owners can invite other owners.
+func (s *Service) InviteMember(ctx context.Context, userID, orgID uuid.UUID, req *model.InviteMemberRequest) (*model.Invitation, error) {
+	org, err := s.managedOrganisation(ctx, orgID, userID, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := &model.Invitation{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:      req.Role,
+		TokenHash: hashSecret(token),
+		InvitedBy: userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(model.InvitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Organisation invitation sent", "org_id", orgID, "invitation_id", inv.ID, "invited_by", userID)
+	if s.email != nil {
+		go func() {
+			if err := s.email.SendInvitationEmail(inv.Email, org.Name, token); err != nil {
+				s.logger.Error("Failed to send invitation email", "error", err, "email", inv.Email)
+			}
+		}()
+	}
+	return inv, nil
+}
+
+// ListInvitations returns the organisation's pending invitations
+func (s *Service) ListInvitations(ctx context.Context, userID, orgID uuid.UUID) ([]model.Invitation, error) {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	invitations, err := s.repo.ListInvitations(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if invitations == nil {
+		invitations = []model.Invitation{}
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation withdraws one of the organisation's pending invitations
+func (s *Service) RevokeInvitation(ctx context.Context, userID, orgID, invitationID uuid.UUID) error {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleMember); err != nil {
+		return err
+	}
+	return s.repo.DeleteInvitation(ctx, orgID, invitationID)
+}
+
+// AcceptInvitation adds the user to the organisation of an invitation sent
+// to their email address
+func (s *Service) AcceptInvitation(ctx context.Context, userID uuid.UUID, req *model.AcceptInvitationRequest) (*model.OrganisationResponse, error) {
+	inv, err := s.repo.GetInvitationByHash(ctx, hashSecret(req.Token))
+	if err != nil {
+		if err == repository.ErrInvitationNotFound {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if inv.AcceptedAt != nil || !now.Before(inv.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if _, err := s.repo.GetMembership(ctx, inv.OrgID, userID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if err != repository.ErrMembershipNotFound {
+		return nil, err
+	}
+
+	org, err := s.repo.GetOrganisation(ctx, inv.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AcceptInvitation(ctx, inv, userID, now); err != nil {
+		if err == repository.ErrInvitationNotFound {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Organisation invitation accepted", "org_id", inv.OrgID, "invitation_id", inv.ID, "user_id", userID)
+	return &model.OrganisationResponse{Organisation: *org, Role: inv.Role}, nil
+}
+
+// UpdateMemberRole changes a member's role. Only owners can make or unmake
+// owners, and the last owner cannot step down.
+func (s *Service) UpdateMemberRole(ctx context.Context, userID, orgID, memberID uuid.UUID, role model.OrgRole) error {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, role); err != nil {
+		return err
+	}
+	member, err := s.repo.GetMembership(ctx, orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if member.Role == role {
+		return nil
+	}
+	if err := s.checkOwnerChange(ctx, orgID, userID, member); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateMemberRole(ctx, orgID, memberID, role); err != nil {
+		return err
+	}
+	s.logger.Info("Organisation member role changed", "org_id", orgID, "member_id", memberID, "role", role, "changed_by", userID)
+	return nil
+}
+
+// RemoveMember removes a member from the organisation. Owners and admins
+// can remove others; anyone can leave.
+func (s *Service) RemoveMember(ctx context.Context, userID, orgID, memberID uuid.UUID) error {
+	if memberID != userID {
+		if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleMember); err != nil {
+			return err
+		}
+	}
+	member, err := s.repo.GetMembership(ctx, orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOwnerChange(ctx, orgID, userID, member); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveMember(ctx, orgID, memberID); err != nil {
+		return err
+	}
+	s.logger.Info("Organisation member removed", "org_id", orgID, "member_id", memberID, "removed_by", userID)
+	return nil
+}
+
+// SwitchOrganisation moves the session of the refresh token to one of the
+// user's organisations and returns its new token pair, whose claims name
+// the organisation. Switching to the user's ID returns to their personal
+// organisation.
+func (s *Service) SwitchOrganisation(ctx context.Context, userID, orgID uuid.UUID, refreshToken string) (*jwt.TokenPair, error) {
+	session, _, err := s.sessionFromRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrInvalidRefreshToken
+	}
+	if _, err := s.membership(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.rotateSession(ctx, refreshToken, &orgID)
+}
+
+// sessionOrg returns the organisation a session of the user acts for and
+// the user's role in it. Sessions start in the user's personal organisation.
+func (s *Service) sessionOrg(ctx context.Context, user *model.User, orgID uuid.UUID) (uuid.UUID, model.OrgRole, error) {
+	if orgID == uuid.Nil || orgID == user.ID {
+		return user.ID, model.OrgRoleOwner, nil
+	}
+	m, err := s.repo.GetMembership(ctx, orgID, user.ID)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return orgID, m.Role, nil
+}
+
+// membership returns the user's membership of the organisation. Users see
+// organisations they are not a member of as not found.
+func (s *Service) membership(ctx context.Context, orgID, userID uuid.UUID) (*model.Membership, error) {
+	m, err := s.repo.GetMembership(ctx, orgID, userID)
+	if err == repository.ErrMembershipNotFound {
+		return nil, repository.ErrOrganisationNotFound
+	}
+	return m, err
+}
+
+// managedOrganisation checks that the user may manage the members of the
+// organisation and grant them role, and returns the organisation
+func (s *Service) managedOrganisation(ctx context.Context, orgID, userID uuid.UUID, role model.OrgRole) (*model.Organisation, error) {
+	m, err := s.membership(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Role.ManagesMembers() || (role == model.OrgRoleOwner && m.Role != model.OrgRoleOwner) {
+		return nil, ErrOrgPermission
+	}
+	org, err := s.repo.GetOrganisation(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.Personal {
+		return nil, ErrPersonalOrganisation
+	}
+	return org, nil
+}
+
+// checkOwnerChange checks that the user may demote or remove member: only
+// owners change owners, and the last owner stays
+func (s *Service) checkOwnerChange(ctx context.Context, orgID, userID uuid.UUID, member *model.Membership) error {
+	if member.Role != model.OrgRoleOwner {
+		return nil
+	}
+	if member.UserID != userID {
+		actor, err := s.membership(ctx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if actor.Role != model.OrgRoleOwner {
+			return ErrOrgPermission
+		}
+	}
+	owners, err := s.repo.CountOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
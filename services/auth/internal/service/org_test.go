@@ -0,0 +1,219 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/jwt"
+)
+
+func TestInviteMember_EmailsToken(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	email := newFakeEmail()
+	svc.SetEmailSender(email)
+	ctx := context.Background()
+
+	ownerID := uuid.New()
+	orgID := uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, ownerID).Return(&model.Membership{OrgID: orgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID, Name: "Acme Freight"}, nil)
+	var stored *model.Invitation
+	mockRepo.On("CreateInvitation", ctx, mock.AnythingOfType("*model.Invitation")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.Invitation)
+	}).Return(nil)
+
+	inv, err := svc.InviteMember(ctx, ownerID, orgID, &model.InviteMemberRequest{Email: " New@Example.com ", Role: model.OrgRoleAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", inv.Email)
+	assert.WithinDuration(t, time.Now().Add(model.InvitationTTL), inv.ExpiresAt, time.Minute)
+
+	// Only the hash of the emailed token is stored
+	var token string
+	select {
+	case token = <-email.invitations:
+	case <-time.After(time.Second):
+		t.Fatal("no invitation email was sent")
+	}
+	sum := sha256.Sum256([]byte(token))
+	assert.Equal(t, hex.EncodeToString(sum[:]), stored.TokenHash)
+}
+
+func TestInviteMember_RoleChecks(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	adminID := uuid.New()
+	memberID := uuid.New()
+	orgID := uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, adminID).Return(&model.Membership{Role: model.OrgRoleAdmin}, nil)
+	mockRepo.On("GetMembership", ctx, orgID, memberID).Return(&model.Membership{Role: model.OrgRoleMember}, nil)
+	mockRepo.On("GetMembership", ctx, orgID, mock.Anything).Return(nil, repository.ErrMembershipNotFound)
+	mockRepo.On("GetOrganisation", ctx, adminID).Return(&model.Organisation{ID: adminID, Personal: true}, nil)
+
+	// Admins cannot make owners, members cannot invite at all, and
+	// outsiders cannot see the organisation
+	_, err := svc.InviteMember(ctx, adminID, orgID, &model.InviteMemberRequest{Email: "a@example.com", Role: model.OrgRoleOwner})
+	assert.Equal(t, service.ErrOrgPermission, err)
+	_, err = svc.InviteMember(ctx, memberID, orgID, &model.InviteMemberRequest{Email: "a@example.com", Role: model.OrgRoleMember})
+	assert.Equal(t, service.ErrOrgPermission, err)
+	_, err = svc.InviteMember(ctx, uuid.New(), orgID, &model.InviteMemberRequest{Email: "a@example.com", Role: model.OrgRoleMember})
+	assert.Equal(t, repository.ErrOrganisationNotFound, err)
+
+	// Nobody joins a personal organisation
+	mockRepo.On("GetMembership", ctx, adminID, adminID).Return(&model.Membership{Role: model.OrgRoleOwner}, nil)
+	_, err = svc.InviteMember(ctx, adminID, adminID, &model.InviteMemberRequest{Email: "a@example.com", Role: model.OrgRoleMember})
+	assert.Equal(t, service.ErrPersonalOrganisation, err)
+	mockRepo.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	orgID := uuid.New()
+	user := &model.User{ID: userID, Email: "Driver@Example.com", Status: model.UserStatusActive}
+	mockRepo.On("GetUserByID", ctx, userID).Return(user, nil)
+
+	invitation := func(token, email string, expiresAt time.Time) {
+		sum := sha256.Sum256([]byte(token))
+		mockRepo.On("GetInvitationByHash", ctx, hex.EncodeToString(sum[:])).Return(&model.Invitation{
+			ID: uuid.New(), OrgID: orgID, Email: email, Role: model.OrgRoleMember, ExpiresAt: expiresAt,
+		}, nil)
+	}
+	invitation("expired", "driver@example.com", time.Now().Add(-time.Minute))
+	invitation("someone-else", "other@example.com", time.Now().Add(time.Hour))
+	invitation("valid", "driver@example.com", time.Now().Add(time.Hour))
+	mockRepo.On("GetInvitationByHash", ctx, mock.Anything).Return(nil, repository.ErrInvitationNotFound)
+
+	_, err := svc.AcceptInvitation(ctx, userID, &model.AcceptInvitationRequest{Token: "unknown"})
+	assert.Equal(t, service.ErrInvalidInvitation, err)
+	_, err = svc.AcceptInvitation(ctx, userID, &model.AcceptInvitationRequest{Token: "expired"})
+	assert.Equal(t, service.ErrInvalidInvitation, err)
+	_, err = svc.AcceptInvitation(ctx, userID, &model.AcceptInvitationRequest{Token: "someone-else"})
+	assert.Equal(t, service.ErrInvitationEmailMismatch, err)
+
+	mockRepo.On("GetMembership", ctx, orgID, userID).Return(nil, repository.ErrMembershipNotFound)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID, Name: "Acme Freight"}, nil)
+	mockRepo.On("AcceptInvitation", ctx, mock.AnythingOfType("*model.Invitation"), userID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	org, err := svc.AcceptInvitation(ctx, userID, &model.AcceptInvitationRequest{Token: "valid"})
+	require.NoError(t, err)
+	assert.Equal(t, orgID, org.ID)
+	assert.Equal(t, model.OrgRoleMember, org.Role)
+	mockRepo.AssertNumberOfCalls(t, "AcceptInvitation", 1)
+}
+
+func TestRemoveMember_LastOwner(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	ownerID := uuid.New()
+	orgID := uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, ownerID).Return(&model.Membership{OrgID: orgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID}, nil)
+	mockRepo.On("CountOwners", ctx, orgID).Return(1, nil)
+
+	err := svc.RemoveMember(ctx, ownerID, orgID, ownerID)
+	assert.Equal(t, service.ErrLastOwner, err)
+
+	err = svc.UpdateMemberRole(ctx, ownerID, orgID, ownerID, model.OrgRoleAdmin)
+	assert.Equal(t, service.ErrLastOwner, err)
+	mockRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateMemberRole_AdminCannotDemoteOwner(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	adminID := uuid.New()
+	ownerID := uuid.New()
+	orgID := uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, adminID).Return(&model.Membership{OrgID: orgID, UserID: adminID, Role: model.OrgRoleAdmin}, nil)
+	mockRepo.On("GetMembership", ctx, orgID, ownerID).Return(&model.Membership{OrgID: orgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID}, nil)
+
+	err := svc.UpdateMemberRole(ctx, adminID, orgID, ownerID, model.OrgRoleMember)
+	assert.Equal(t, service.ErrOrgPermission, err)
+	err = svc.RemoveMember(ctx, adminID, orgID, ownerID)
+	assert.Equal(t, service.ErrOrgPermission, err)
+}
+
+func TestSwitchOrganisation(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	denylist := &fakeDenylist{revoked: map[string]time.Time{}}
+	svc.SetDenylist(denylist)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	orgID := uuid.New()
+	sessionID := uuid.New()
+	user := &model.User{ID: userID, Email: "ops@example.com", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	tokens, _ := jwtManager.GenerateOrgTokenPair(sessionID.String(), userID.String(), user.Email, "shipper", userID.String(), "owner")
+	session := &model.Session{ID: sessionID, UserID: userID, RefreshTokenID: tokens.RefreshTokenID, AccessTokenID: "personal-access", AccessExpiresAt: time.Now().Add(time.Minute)}
+
+	mockRepo.On("GetSession", ctx, sessionID).Return(session, nil)
+	mockRepo.On("GetUserByID", ctx, userID).Return(user, nil)
+	mockRepo.On("GetMembership", ctx, orgID, userID).Return(&model.Membership{OrgID: orgID, UserID: userID, Role: model.OrgRoleAdmin}, nil)
+	mockRepo.On("RotateSession", ctx, mock.MatchedBy(func(s *model.Session) bool {
+		return s.ID == sessionID && s.OrgID == orgID
+	}), tokens.RefreshTokenID).Return(nil)
+
+	// Only the session's own user can move it
+	_, err := svc.SwitchOrganisation(ctx, uuid.New(), orgID, tokens.RefreshToken)
+	assert.Equal(t, service.ErrInvalidRefreshToken, err)
+
+	switched, err := svc.SwitchOrganisation(ctx, userID, orgID, tokens.RefreshToken)
+	require.NoError(t, err)
+	claims, err := jwtManager.ValidateAccessToken(switched.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, orgID.String(), claims.OrgID)
+	assert.Equal(t, "admin", claims.OrgRole)
+
+	// The access token acting for the personal organisation is retired
+	assert.Contains(t, denylist.revoked, "personal-access")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshToken_FallsBackToPersonalOrganisation(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	orgID := uuid.New()
+	sessionID := uuid.New()
+	user := &model.User{ID: userID, Email: "ops@example.com", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	tokens, _ := jwtManager.GenerateOrgTokenPair(sessionID.String(), userID.String(), user.Email, "shipper", orgID.String(), "member")
+	session := &model.Session{ID: sessionID, UserID: userID, OrgID: orgID, RefreshTokenID: tokens.RefreshTokenID}
+
+	// The user has since been removed from the organisation
+	mockRepo.On("GetSession", ctx, sessionID).Return(session, nil)
+	mockRepo.On("GetUserByID", ctx, userID).Return(user, nil)
+	mockRepo.On("GetMembership", ctx, orgID, userID).Return(nil, repository.ErrMembershipNotFound)
+	mockRepo.On("RotateSession", ctx, mock.MatchedBy(func(s *model.Session) bool {
+		return s.OrgID == userID
+	}), tokens.RefreshTokenID).Return(nil)
+
+	refreshed, err := svc.RefreshToken(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	claims, err := jwtManager.ValidateAccessToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), claims.OrgID)
+	assert.Equal(t, "owner", claims.OrgRole)
+	mockRepo.AssertExpectations(t)
+}
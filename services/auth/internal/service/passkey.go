@@ -120,6 +120,7 @@ func (s *Service) FinishPasskeyRegistration(ctx context.Context, userID uuid.UUI
 	s.repo.DeleteChallenge(ctx, challenge.ID)
 
 	s.logger.Info("Passkey registered", "user_id", userID, "name", name)
+	s.recordSecurityEvent(ctx, userID, model.SecurityPasskeyAdded, name)
 	return passkeyCredential, nil
 }
 
@@ -220,8 +221,9 @@ func (s *Service) FinishPasskeyLogin(ctx context.Context, email, responseData st
 	s.repo.UpdatePasskeySignCount(ctx, credential.ID, credential.Authenticator.SignCount)
 	s.repo.DeleteChallenge(ctx, challenge.ID)
 	s.repo.UpdateLastLogin(ctx, user.ID)
+	s.loginSucceeded(ctx, user, "passkey")
 
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, string(user.UserType))
+	tokens, err := s.startSession(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -243,5 +245,9 @@ func (s *Service) GetUserPasskeys(ctx context.Context, userID uuid.UUID) ([]mode
 
 // DeletePasskey removes a passkey
 func (s *Service) DeletePasskey(ctx context.Context, userID, passkeyID uuid.UUID) error {
-	return s.repo.DeletePasskey(ctx, userID, passkeyID)
+	if err := s.repo.DeletePasskey(ctx, userID, passkeyID); err != nil {
+		return err
+	}
+	s.recordSecurityEvent(ctx, userID, model.SecurityPasskeyRemoved, "")
+	return nil
 }
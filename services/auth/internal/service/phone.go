@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+)
+
+var (
+	ErrSMSNotConfigured   = errors.New("SMS is not configured")
+	ErrSMSFailed          = errors.New("SMS could not be sent")
+	ErrSMSThrottled       = errors.New("too many codes sent to this phone number")
+	ErrInvalidPhoneCode   = errors.New("invalid or expired code")
+	ErrPhoneNotRegistered = errors.New("no account uses this phone number")
+)
+
+const (
+	// phoneCodeTTL is how long an SMS code can be used
+	phoneCodeTTL = 10 * time.Minute
+	// phoneCodeMaxAttempts is how many guesses spend a code
+	phoneCodeMaxAttempts = 5
+	// phoneCodeResendAfter is how long to wait between codes to one number
+	phoneCodeResendAfter = time.Minute
+	// phoneCodesPerWindow codes can be sent to one number per
+	// phoneCodeWindow, which keeps a number from being flooded
+	phoneCodesPerWindow = 5
+	phoneCodeWindow     = time.Hour
+)
+
+// SendLoginCode texts a code that logs in whoever holds the phone number,
+// registering an account for numbers that have none
+func (s *Service) SendLoginCode(ctx context.Context, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error) {
+	return s.sendPhoneCode(ctx, req.Phone, model.PhoneCodeLogin, nil)
+}
+
+// SendPhoneVerificationCode texts a code that adds the phone number to the
+// user's account, so they can log in with it
+func (s *Service) SendPhoneVerificationCode(ctx context.Context, userID uuid.UUID, req *model.PhoneCodeRequest) (*model.PhoneCodeSent, error) {
+	owner, err := s.repo.GetUserByPhone(ctx, req.Phone)
+	if err == nil && owner.ID != userID {
+		return nil, repository.ErrPhoneTaken
+	}
+	if err != nil && err != repository.ErrUserNotFound {
+		return nil, err
+	}
+	return s.sendPhoneCode(ctx, req.Phone, model.PhoneCodeLink, &userID)
+}
+
+// PhoneLogin logs in with a code texted by SendLoginCode. Numbers without
+// an account register one of the requested user type; without a user type
+// the code stays valid, so the client can ask for one and try again.
+func (s *Service) PhoneLogin(ctx context.Context, req *model.PhoneLoginRequest) (*model.LoginResponse, error) {
+	code, err := s.checkPhoneCode(ctx, req.Phone, model.PhoneCodeLogin, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByPhone(ctx, req.Phone)
+	if err == repository.ErrUserNotFound {
+		if req.UserType == "" {
+			return nil, ErrPhoneNotRegistered
+		}
+		if err := s.usePhoneCode(ctx, code); err != nil {
+			return nil, err
+		}
+		return s.registerPhoneUser(ctx, req.Phone, req.UserType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.usePhoneCode(ctx, code); err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+	return s.beginSession(ctx, user, "sms")
+}
+
+// VerifyPhone adds a phone number to the user's account with a code texted
+// by SendPhoneVerificationCode
+func (s *Service) VerifyPhone(ctx context.Context, userID uuid.UUID, req *model.VerifyPhoneRequest) error {
+	code, err := s.checkPhoneCode(ctx, req.Phone, model.PhoneCodeLink, req.Code)
+	if err != nil {
+		return err
+	}
+	if code.UserID == nil || *code.UserID != userID {
+		return ErrInvalidPhoneCode
+	}
+	if err := s.usePhoneCode(ctx, code); err != nil {
+		return err
+	}
+	if err := s.repo.SetVerifiedPhone(ctx, userID, req.Phone); err != nil {
+		return err
+	}
+
+	s.logger.Info("Phone number verified", "user_id", userID)
+	s.recordSecurityEvent(ctx, userID, model.SecurityPhoneVerified, req.Phone)
+	return nil
+}
+
+// registerPhoneUser creates an account for a phone number its holder has
+// just proved. It has no email or password.
+func (s *Service) registerPhoneUser(ctx context.Context, phone string, userType model.UserType) (*model.LoginResponse, error) {
+	now := time.Now()
+	user := &model.User{
+		ID:            uuid.New(),
+		Phone:         phone,
+		PhoneVerified: true,
+		UserType:      userType,
+		Status:        model.UserStatusActive,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User registered by phone", "user_id", user.ID, "user_type", user.UserType)
+
+	// User types that require a second factor enrol before getting tokens
+	if s.mfaRequired[user.UserType] {
+		return s.mfaChallenge(ctx, user, true)
+	}
+
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		User:         user.ToUserResponse(),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// sendPhoneCode texts a new code for the purpose, replacing any earlier
+// one. Codes to a number are spaced out and capped per hour.
+func (s *Service) sendPhoneCode(ctx context.Context, phone string, purpose model.PhoneCodePurpose, userID *uuid.UUID) (*model.PhoneCodeSent, error) {
+	if s.sms == nil {
+		return nil, ErrSMSNotConfigured
+	}
+
+	now := time.Now()
+	recent, err := s.repo.RecentPhoneCodes(ctx, phone, now.Add(-phoneCodeWindow))
+	if err != nil {
+		return nil, err
+	}
+	if len(recent) > 0 {
+		if wait := recent[0].CreatedAt.Add(phoneCodeResendAfter).Sub(now); wait > 0 {
+			return nil, &ThrottledError{RetryAfter: wait, Cause: ErrSMSThrottled}
+		}
+	}
+	if len(recent) >= phoneCodesPerWindow {
+		wait := recent[phoneCodesPerWindow-1].CreatedAt.Add(phoneCodeWindow).Sub(now)
+		return nil, &ThrottledError{RetryAfter: wait, Cause: ErrSMSThrottled}
+	}
+
+	code, err := newPhoneCode()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.PhoneCode{
+		ID:        uuid.New(),
+		Phone:     phone,
+		Purpose:   purpose,
+		UserID:    userID,
+		CodeHash:  string(hash),
+		ExpiresAt: now.Add(phoneCodeTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreatePhoneCode(ctx, c, now.Add(-phoneCodeWindow)); err != nil {
+		return nil, err
+	}
+
+	if purpose == model.PhoneCodeLink {
+		err = s.sms.SendVerificationCode(phone, code)
+	} else {
+		err = s.sms.SendLoginCode(phone, code)
+	}
+	if err != nil {
+		s.logger.Error("Failed to send SMS code", "purpose", purpose, "error", err)
+		return nil, ErrSMSFailed
+	}
+
+	return &model.PhoneCodeSent{
+		ExpiresIn: int64(phoneCodeTTL.Seconds()),
+		ResendIn:  int64(phoneCodeResendAfter.Seconds()),
+	}, nil
+}
+
+// checkPhoneCode returns the newest code for the number and purpose if it
+// matches, counting a wrong guess against it. The caller uses the code
+// once what it unlocks has been checked.
+func (s *Service) checkPhoneCode(ctx context.Context, phone string, purpose model.PhoneCodePurpose, code string) (*model.PhoneCode, error) {
+	c, err := s.repo.LatestPhoneCode(ctx, phone, purpose)
+	if err == repository.ErrPhoneCodeNotFound {
+		return nil, ErrInvalidPhoneCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.UsedAt != nil || c.Attempts >= phoneCodeMaxAttempts || time.Now().After(c.ExpiresAt) {
+		return nil, ErrInvalidPhoneCode
+	}
+
+	// The guess is counted before it is checked, so that concurrent guesses
+	// cannot get more tries than the limit between them
+	attempts, err := s.repo.AttemptPhoneCode(ctx, c.ID, phoneCodeMaxAttempts)
+	if err == repository.ErrPhoneCodeNotFound {
+		return nil, ErrInvalidPhoneCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) != nil {
+		if attempts >= phoneCodeMaxAttempts {
+			s.logger.Warn("SMS code exhausted", "code_id", c.ID, "purpose", purpose)
+		}
+		return nil, ErrInvalidPhoneCode
+	}
+	return c, nil
+}
+
+// usePhoneCode spends a code, so that it cannot be redeemed twice
+func (s *Service) usePhoneCode(ctx context.Context, c *model.PhoneCode) error {
+	if err := s.repo.UsePhoneCode(ctx, c.ID); err != nil {
+		if err == repository.ErrPhoneCodeNotFound {
+			return ErrInvalidPhoneCode
+		}
+		return err
+	}
+	return nil
+}
+
+// newPhoneCode returns a random six-digit code
+func newPhoneCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,212 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+)
+
+const testPhone = "+254712345678"
+
+// fakeSMS records the codes it is asked to send
+type fakeSMS struct {
+	codes map[string]string
+}
+
+func (f *fakeSMS) SendLoginCode(to, code string) error {
+	f.codes[to] = code
+	return nil
+}
+
+func (f *fakeSMS) SendVerificationCode(to, code string) error {
+	f.codes[to] = code
+	return nil
+}
+
+func setupPhoneService() (*service.Service, *MockRepository, *fakeSMS) {
+	svc, mockRepo := setupTestService()
+	sms := &fakeSMS{codes: map[string]string{}}
+	svc.SetSMSSender(sms)
+	return svc, mockRepo, sms
+}
+
+// phoneCode returns a stored login code for testPhone
+func phoneCode(t *testing.T, code string, attempts int) *model.PhoneCode {
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &model.PhoneCode{ID: uuid.New(), Phone: testPhone, Purpose: model.PhoneCodeLogin, CodeHash: string(hash),
+		Attempts: attempts, ExpiresAt: time.Now().Add(5 * time.Minute), CreatedAt: time.Now()}
+}
+
+func TestPhoneLogin_RegistersNewNumber(t *testing.T) {
+	svc, mockRepo, sms := setupPhoneService()
+	ctx := context.Background()
+
+	var stored *model.PhoneCode
+	mockRepo.On("RecentPhoneCodes", ctx, testPhone, mock.Anything).Return(nil, nil)
+	mockRepo.On("CreatePhoneCode", ctx, mock.AnythingOfType("*model.PhoneCode"), mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.PhoneCode)
+	}).Return(nil)
+
+	sent, err := svc.SendLoginCode(ctx, &model.PhoneCodeRequest{Phone: testPhone})
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), sent.ExpiresIn)
+	code := sms.codes[testPhone]
+	require.Len(t, code, 6)
+	assert.NotContains(t, stored.CodeHash, code)
+
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(stored, nil)
+	mockRepo.On("AttemptPhoneCode", ctx, stored.ID, 5).Return(1, nil)
+	mockRepo.On("GetUserByPhone", ctx, testPhone).Return(nil, repository.ErrUserNotFound)
+
+	// Without a user type the number cannot register, and the code stays
+	// valid for the next try
+	_, err = svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: code})
+	assert.Equal(t, service.ErrPhoneNotRegistered, err)
+	mockRepo.AssertNotCalled(t, "UsePhoneCode", mock.Anything, mock.Anything)
+
+	var created *model.User
+	mockRepo.On("UsePhoneCode", ctx, stored.ID).Return(nil)
+	mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User")).Run(func(args mock.Arguments) {
+		created = args.Get(1).(*model.User)
+	}).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	resp, err := svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: code, UserType: model.UserTypeDriver})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.AccessToken)
+	assert.Equal(t, testPhone, created.Phone)
+	assert.True(t, created.PhoneVerified)
+	assert.Empty(t, created.Email)
+	assert.Equal(t, model.UserTypeDriver, created.UserType)
+}
+
+func TestPhoneLogin_ExistingAccount(t *testing.T) {
+	svc, mockRepo, _ := setupPhoneService()
+	ctx := context.Background()
+
+	user := &model.User{ID: uuid.New(), Email: "driver@example.com", Phone: testPhone, PhoneVerified: true,
+		UserType: model.UserTypeDriver, Status: model.UserStatusActive}
+	stored := phoneCode(t, "123456", 0)
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(stored, nil)
+	mockRepo.On("GetUserByPhone", ctx, testPhone).Return(user, nil)
+	mockRepo.On("AttemptPhoneCode", ctx, stored.ID, 5).Return(1, nil)
+	mockRepo.On("UsePhoneCode", ctx, stored.ID).Return(nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(nil, repository.ErrMFANotFound)
+	mockRepo.On("ClearLoginFailures", ctx, "account:driver@example.com").Return(nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.Type == model.SecurityLoginSucceeded && e.Detail == "sms"
+	})).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	resp, err := svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, resp.User.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPhoneLogin_WrongCodes(t *testing.T) {
+	svc, mockRepo, _ := setupPhoneService()
+	ctx := context.Background()
+
+	stored := phoneCode(t, "123456", 3)
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(stored, nil).Once()
+	mockRepo.On("AttemptPhoneCode", ctx, stored.ID, 5).Return(4, nil).Once()
+	_, err := svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: "654321"})
+	assert.Equal(t, service.ErrInvalidPhoneCode, err)
+
+	// Concurrent guesses spent the last try, even though this one is right
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(stored, nil).Once()
+	mockRepo.On("AttemptPhoneCode", ctx, stored.ID, 5).Return(0, repository.ErrPhoneCodeNotFound).Once()
+	_, err = svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: "123456"})
+	assert.Equal(t, service.ErrInvalidPhoneCode, err)
+
+	// A code that has taken too many guesses is spent, even for the right
+	// code, and so is an expired one
+	exhausted := phoneCode(t, "123456", 5)
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(exhausted, nil).Once()
+	_, err = svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: "123456"})
+	assert.Equal(t, service.ErrInvalidPhoneCode, err)
+
+	expired := phoneCode(t, "123456", 0)
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLogin).Return(expired, nil).Once()
+	_, err = svc.PhoneLogin(ctx, &model.PhoneLoginRequest{Phone: testPhone, Code: "123456"})
+	assert.Equal(t, service.ErrInvalidPhoneCode, err)
+
+	mockRepo.AssertNotCalled(t, "GetUserByPhone", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSendLoginCode_Throttled(t *testing.T) {
+	svc, mockRepo, sms := setupPhoneService()
+	ctx := context.Background()
+
+	// The last code went out 10 seconds ago
+	mockRepo.On("RecentPhoneCodes", ctx, testPhone, mock.Anything).
+		Return([]model.PhoneCode{{CreatedAt: time.Now().Add(-10 * time.Second)}}, nil).Once()
+	_, err := svc.SendLoginCode(ctx, &model.PhoneCodeRequest{Phone: testPhone})
+	var throttled *service.ThrottledError
+	require.True(t, errors.As(err, &throttled))
+	assert.True(t, errors.Is(err, service.ErrSMSThrottled))
+	assert.InDelta(t, 50, throttled.RetryAfter.Seconds(), 2)
+
+	// Five codes within the hour
+	var recent []model.PhoneCode
+	for i := 1; i <= 5; i++ {
+		recent = append(recent, model.PhoneCode{CreatedAt: time.Now().Add(-time.Duration(i) * 10 * time.Minute)})
+	}
+	mockRepo.On("RecentPhoneCodes", ctx, testPhone, mock.Anything).Return(recent, nil).Once()
+	_, err = svc.SendLoginCode(ctx, &model.PhoneCodeRequest{Phone: testPhone})
+	require.True(t, errors.As(err, &throttled))
+	assert.InDelta(t, 10*60, throttled.RetryAfter.Seconds(), 2)
+
+	assert.Empty(t, sms.codes)
+	mockRepo.AssertNotCalled(t, "CreatePhoneCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyPhone(t *testing.T) {
+	svc, mockRepo, sms := setupPhoneService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Numbers another account has verified cannot be linked
+	mockRepo.On("GetUserByPhone", ctx, "+61412345678").Return(&model.User{ID: uuid.New()}, nil)
+	_, err := svc.SendPhoneVerificationCode(ctx, userID, &model.PhoneCodeRequest{Phone: "+61412345678"})
+	assert.Equal(t, repository.ErrPhoneTaken, err)
+
+	var stored *model.PhoneCode
+	mockRepo.On("GetUserByPhone", ctx, testPhone).Return(nil, repository.ErrUserNotFound)
+	mockRepo.On("RecentPhoneCodes", ctx, testPhone, mock.Anything).Return(nil, nil)
+	mockRepo.On("CreatePhoneCode", ctx, mock.MatchedBy(func(c *model.PhoneCode) bool {
+		return c.Purpose == model.PhoneCodeLink && c.UserID != nil && *c.UserID == userID
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.PhoneCode)
+	}).Return(nil)
+	_, err = svc.SendPhoneVerificationCode(ctx, userID, &model.PhoneCodeRequest{Phone: testPhone})
+	require.NoError(t, err)
+
+	mockRepo.On("LatestPhoneCode", ctx, testPhone, model.PhoneCodeLink).Return(stored, nil)
+	mockRepo.On("AttemptPhoneCode", ctx, stored.ID, 5).Return(1, nil)
+
+	// The code only verifies the number for the account that asked for it
+	err = svc.VerifyPhone(ctx, uuid.New(), &model.VerifyPhoneRequest{Phone: testPhone, Code: sms.codes[testPhone]})
+	assert.Equal(t, service.ErrInvalidPhoneCode, err)
+
+	mockRepo.On("UsePhoneCode", ctx, stored.ID).Return(nil)
+	mockRepo.On("SetVerifiedPhone", ctx, userID, testPhone).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(userID, model.SecurityPhoneVerified)).Return(nil)
+	assert.NoError(t, svc.VerifyPhone(ctx, userID, &model.VerifyPhoneRequest{Phone: testPhone, Code: sms.codes[testPhone]}))
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/shared/pkg/pagination"
+)
+
+// ErrLoginThrottled is returned, wrapped in a ThrottledError, when an
+// account or client has failed to log in too often
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// ThrottledError tells the caller how long to wait before trying to log in
+// again
+type ThrottledError struct {
+	RetryAfter time.Duration
+	// Cause says what was throttled; nil means failed logins
+	Cause error
+}
+
+func (e *ThrottledError) Error() string { return e.Unwrap().Error() }
+
+func (e *ThrottledError) Unwrap() error {
+	if e.Cause != nil {
+		return e.Cause
+	}
+	return ErrLoginThrottled
+}
+
+// LockoutPolicy says how failed password logins slow down and lock an
+// account or client IP
+type LockoutPolicy struct {
+	// DelayAfter failures make the next attempt wait BaseDelay, doubling
+	// with each further failure up to MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// LockAfter failures refuse every attempt for LockFor
+	LockAfter int
+	LockFor   time.Duration
+	// ResetAfter without a failure starts the count over
+	ResetAfter time.Duration
+}
+
+var (
+	// DefaultAccountLockout applies to the failed logins of one email
+	DefaultAccountLockout = LockoutPolicy{
+		DelayAfter: 3, BaseDelay: time.Second, MaxDelay: time.Minute,
+		LockAfter: 10, LockFor: 15 * time.Minute, ResetAfter: 15 * time.Minute,
+	}
+	// DefaultIPLockout applies to the failed logins of one client IP,
+	// whichever accounts they try
+	DefaultIPLockout = LockoutPolicy{
+		DelayAfter: 20, BaseDelay: time.Second, MaxDelay: time.Minute,
+		LockAfter: 100, LockFor: 15 * time.Minute, ResetAfter: 15 * time.Minute,
+	}
+)
+
+const (
+	accountThrottlePrefix = "account:"
+	ipThrottlePrefix      = "ip:"
+)
+
+// ListSecurityEvents returns a page of the user's security log and the
+// cursor of the next page
+func (s *Service) ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error) {
+	return s.repo.ListSecurityEvents(ctx, userID, page)
+}
+
+// loginThrottleKeys returns the failed-login counters a password login
+// for email counts against. The account is keyed by email so that
+// unknown emails are throttled like real ones.
+func loginThrottleKeys(ctx context.Context, email string) []string {
+	keys := []string{accountThrottleKey(email)}
+	if ip := clientInfoFrom(ctx).IPAddress; ip != "" {
+		keys = append(keys, ipThrottlePrefix+ip)
+	}
+	return keys
+}
+
+// accountThrottleKey returns the failed-login counter of the account with
+// email
+func accountThrottleKey(email string) string {
+	return accountThrottlePrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *Service) lockoutPolicy(key string) LockoutPolicy {
+	if strings.HasPrefix(key, ipThrottlePrefix) {
+		return s.ipLockout
+	}
+	return s.accountLockout
+}
+
+// checkLoginThrottle refuses a login while any of its keys is locked or
+// waiting out the delay of its last failure
+func (s *Service) checkLoginThrottle(ctx context.Context, keys []string) error {
+	throttles, err := s.repo.GetLoginThrottles(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, t := range throttles {
+		if w := s.lockoutPolicy(t.Key).wait(t, now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// wait returns how long the key has to wait before its next attempt
+func (p LockoutPolicy) wait(t model.LoginThrottle, now time.Time) time.Duration {
+	if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+		return t.LockedUntil.Sub(now)
+	}
+	if p.DelayAfter <= 0 || t.Failures < p.DelayAfter || now.Sub(t.LastFailureAt) >= p.ResetAfter {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.DelayAfter; i < t.Failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if wait := t.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// loginFailed counts a failed password or second-factor login against its
+// keys, locking those that reached their limit. user is nil when the email
+// is unknown.
+func (s *Service) loginFailed(ctx context.Context, keys []string, user *model.User) {
+	now := time.Now()
+	for _, key := range keys {
+		policy := s.lockoutPolicy(key)
+		t, err := s.repo.RecordLoginFailure(ctx, key, now, now.Add(-policy.ResetAfter))
+		if err != nil {
+			s.logger.Error("Failed to record login failure", "key", key, "error", err)
+			continue
+		}
+		if policy.LockAfter <= 0 || t.Failures < policy.LockAfter {
+			continue
+		}
+
+		until := now.Add(policy.LockFor)
+		if err := s.repo.LockLogin(ctx, key, until); err != nil {
+			s.logger.Error("Failed to lock login", "key", key, "error", err)
+			continue
+		}
+		s.logger.Warn("Login locked after failed attempts", "key", key, "failures", t.Failures, "until", until)
+
+		if user != nil && strings.HasPrefix(key, accountThrottlePrefix) {
+			s.recordSecurityEvent(ctx, user.ID, model.SecurityAccountLocked, fmt.Sprintf("%d failed logins", t.Failures))
+			s.securityAlert(user.Email, "Your Truckify account has been locked",
+				fmt.Sprintf("After %d failed login attempts, logins to your account are blocked until %s. If this wasn't you, someone may be trying to guess your password.",
+					t.Failures, until.UTC().Format("15:04 MST on 2 January 2006")))
+		}
+	}
+
+	if user != nil {
+		s.recordSecurityEvent(ctx, user.ID, model.SecurityLoginFailed, "")
+	}
+}
+
+// loginSucceeded records a login and alerts the user when it came from a
+// device they have not logged in from before
+func (s *Service) loginSucceeded(ctx context.Context, user *model.User, method string) {
+	info := clientInfoFrom(ctx)
+	if info.UserAgent != "" {
+		loggedInBefore, fromDevice, err := s.repo.LoginHistory(ctx, user.ID, info.UserAgent)
+		if err != nil {
+			s.logger.Error("Failed to read login history", "user_id", user.ID, "error", err)
+		} else if loggedInBefore && !fromDevice {
+			s.recordSecurityEvent(ctx, user.ID, model.SecurityNewDeviceLogin, method)
+			s.securityAlert(user.Email, "New login to your Truckify account",
+				fmt.Sprintf("Your account was just logged in to from a new device: %s. If this wasn't you, change your password and log out of all sessions.",
+					describeDevice(info.UserAgent, info.IPAddress)))
+		}
+	}
+	s.recordSecurityEvent(ctx, user.ID, model.SecurityLoginSucceeded, method)
+}
+
+// recordSecurityEvent adds an entry to the user's security log. Failing to
+// record it does not fail what is being recorded.
+func (s *Service) recordSecurityEvent(ctx context.Context, userID uuid.UUID, eventType, detail string) {
+	s.recordActorSecurityEvent(ctx, userID, nil, eventType, detail)
+}
+
+// recordActorSecurityEvent records a security event on the user's log for
+// something a dispatcher did on their behalf
+func (s *Service) recordActorSecurityEvent(ctx context.Context, userID uuid.UUID, actorID *uuid.UUID, eventType, detail string) {
+	info := clientInfoFrom(ctx)
+	e := &model.SecurityEvent{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      eventType,
+		IPAddress: info.IPAddress,
+		UserAgent: info.UserAgent,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+		ActorID:   actorID,
+	}
+	if err := s.repo.CreateSecurityEvent(ctx, e); err != nil {
+		s.logger.Error("Failed to record security event", "user_id", userID, "event", eventType, "error", err)
+	}
+}
+
+// securityAlert emails the user about suspicious activity on their
+// account. Users registered by phone have no email to alert.
+func (s *Service) securityAlert(to, subject, message string) {
+	if s.email == nil || to == "" {
+		return
+	}
+	go func() {
+		if err := s.email.SendSecurityAlertEmail(to, subject, message); err != nil {
+			s.logger.Error("Failed to send security alert email", "error", err, "email", to)
+		}
+	}()
+}
+
+// describeDevice names a device by its user agent and IP address for alerts
+func describeDevice(userAgent, ipAddress string) string {
+	switch {
+	case userAgent != "" && ipAddress != "":
+		return fmt.Sprintf("%s (IP address %s)", userAgent, ipAddress)
+	case userAgent != "":
+		return userAgent
+	case ipAddress != "":
+		return "IP address " + ipAddress
+	}
+	return "an unknown device"
+}
@@ -0,0 +1,190 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+)
+
+type alertEmail struct {
+	to      string
+	subject string
+}
+
+// fakeEmail collects the security alerts and invitation tokens the service
+// sends
+type fakeEmail struct {
+	alerts      chan alertEmail
+	invitations chan string
+}
+
+func newFakeEmail() *fakeEmail {
+	return &fakeEmail{alerts: make(chan alertEmail, 10), invitations: make(chan string, 10)}
+}
+
+func (f *fakeEmail) SendVerificationEmail(to, token string) error  { return nil }
+func (f *fakeEmail) SendPasswordResetEmail(to, token string) error { return nil }
+
+func (f *fakeEmail) SendSecurityAlertEmail(to, subject, message string) error {
+	f.alerts <- alertEmail{to: to, subject: subject}
+	return nil
+}
+
+func (f *fakeEmail) SendInvitationEmail(to, orgName, token string) error {
+	f.invitations <- token
+	return nil
+}
+
+func (f *fakeEmail) next(t *testing.T) alertEmail {
+	t.Helper()
+	select {
+	case alert := <-f.alerts:
+		return alert
+	case <-time.After(time.Second):
+		t.Fatal("no security alert was sent")
+		return alertEmail{}
+	}
+}
+
+func securityEvent(userID interface{}, eventType string) interface{} {
+	return mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.UserID == userID && e.Type == eventType
+	})
+}
+
+func TestLogin_ThrottledBeforePasswordCheck(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := service.WithClientInfo(context.Background(), model.ClientInfo{IPAddress: "203.0.113.7"})
+	now := time.Now()
+	lockedUntil := now.Add(10 * time.Minute)
+
+	for name, tc := range map[string]struct {
+		throttle model.LoginThrottle
+		want     time.Duration
+	}{
+		// The third failure waits a second, doubling with each one after
+		"delay":          {model.LoginThrottle{Key: "account:fleet@example.com", Failures: 5, LastFailureAt: now}, 4 * time.Second},
+		"capped":         {model.LoginThrottle{Key: "account:fleet@example.com", Failures: 9, LastFailureAt: now}, time.Minute},
+		"locked":         {model.LoginThrottle{Key: "account:fleet@example.com", Failures: 10, LastFailureAt: now, LockedUntil: &lockedUntil}, 10 * time.Minute},
+		"ip":             {model.LoginThrottle{Key: "ip:203.0.113.7", Failures: 21, LastFailureAt: now}, 2 * time.Second},
+		"ip under limit": {model.LoginThrottle{Key: "ip:203.0.113.7", Failures: 5, LastFailureAt: now}, 0},
+	} {
+		mockRepo.ExpectedCalls = nil
+		keys := []string{"account:fleet@example.com", "ip:203.0.113.7"}
+		mockRepo.On("GetLoginThrottles", ctx, keys).Return([]model.LoginThrottle{tc.throttle}, nil)
+		mockRepo.On("GetUserByEmail", ctx, "Fleet@Example.com").Return(nil, errors.New("stop"))
+
+		_, err := svc.Login(ctx, &model.LoginRequest{Email: "Fleet@Example.com", Password: "password123"})
+
+		var throttled *service.ThrottledError
+		if tc.want == 0 {
+			assert.False(t, errors.As(err, &throttled), name)
+			continue
+		}
+		require.True(t, errors.As(err, &throttled), name)
+		assert.ErrorIs(t, err, service.ErrLoginThrottled, name)
+		assert.InDelta(t, tc.want.Seconds(), throttled.RetryAfter.Seconds(), 1, name)
+	}
+}
+
+func TestLogin_LocksAccountAfterFailures(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	email := newFakeEmail()
+	svc.SetEmailSender(email)
+	ctx := service.WithClientInfo(context.Background(), model.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "curl/8.0"})
+
+	user := passwordUser(model.UserTypeShipper)
+	mockRepo.On("GetLoginThrottles", ctx, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("RecordLoginFailure", ctx, "account:fleet@example.com", mock.Anything, mock.Anything).
+		Return(&model.LoginThrottle{Key: "account:fleet@example.com", Failures: 10}, nil)
+	mockRepo.On("RecordLoginFailure", ctx, "ip:203.0.113.7", mock.Anything, mock.Anything).
+		Return(&model.LoginThrottle{Key: "ip:203.0.113.7", Failures: 10}, nil)
+	mockRepo.On("LockLogin", ctx, "account:fleet@example.com", mock.MatchedBy(func(until time.Time) bool {
+		return until.Sub(time.Now()) > 14*time.Minute
+	})).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityAccountLocked)).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityLoginFailed)).Return(nil)
+
+	_, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "wrong-password"})
+
+	assert.Equal(t, service.ErrInvalidCredentials, err)
+	alert := email.next(t)
+	assert.Equal(t, user.Email, alert.to)
+	assert.Contains(t, alert.subject, "locked")
+	// The IP is well under its own limit
+	mockRepo.AssertNotCalled(t, "LockLogin", ctx, "ip:203.0.113.7", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogin_UnknownEmailCountsFailure(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	mockRepo.On("GetLoginThrottles", ctx, []string{"account:nobody@example.com"}).Return(nil, nil)
+	mockRepo.On("GetUserByEmail", ctx, "nobody@example.com").Return(nil, repository.ErrUserNotFound)
+	mockRepo.On("RecordLoginFailure", ctx, "account:nobody@example.com", mock.Anything, mock.Anything).
+		Return(&model.LoginThrottle{Key: "account:nobody@example.com", Failures: 1}, nil)
+
+	_, err := svc.Login(ctx, &model.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+
+	assert.Equal(t, service.ErrInvalidCredentials, err)
+	mockRepo.AssertNotCalled(t, "CreateSecurityEvent", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogin_NewDeviceSendsAlert(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	email := newFakeEmail()
+	svc.SetEmailSender(email)
+	ctx := service.WithClientInfo(context.Background(), model.ClientInfo{IPAddress: "198.51.100.4", UserAgent: "NewPhone/1.0"})
+
+	user := passwordUser(model.UserTypeDriver)
+	mockRepo.On("GetLoginThrottles", ctx, mock.Anything).Return(nil, nil)
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("ClearLoginFailures", ctx, "account:fleet@example.com").Return(nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(nil, repository.ErrMFANotFound)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("LoginHistory", ctx, user.ID, "NewPhone/1.0").Return(true, false, nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityNewDeviceLogin)).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityLoginSucceeded)).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	resp, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.AccessToken)
+	alert := email.next(t)
+	assert.Equal(t, user.Email, alert.to)
+	assert.Contains(t, alert.subject, "New login")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogin_FirstLoginIsNotANewDevice(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	email := newFakeEmail()
+	svc.SetEmailSender(email)
+	ctx := service.WithClientInfo(context.Background(), model.ClientInfo{UserAgent: "Browser/1.0"})
+
+	user := passwordUser(model.UserTypeDriver)
+	expectLoginChecks(mockRepo, ctx)
+	mockRepo.On("GetUserByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetMFA", ctx, user.ID).Return(nil, repository.ErrMFANotFound)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockRepo.On("LoginHistory", ctx, user.ID, "Browser/1.0").Return(false, false, nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
+
+	_, err := svc.Login(ctx, &model.LoginRequest{Email: user.Email, Password: "password123"})
+
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "CreateSecurityEvent", ctx, securityEvent(user.ID, model.SecurityNewDeviceLogin))
+	assert.Empty(t, email.alerts)
+}
@@ -9,16 +9,20 @@ import (
 
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/oidc"
 	"truckify/services/auth/internal/repository"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
-	"golang.org/x/crypto/bcrypt"
+	"truckify/shared/pkg/pagination"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrUserNotActive      = errors.New("user account is not active")
+	ErrAuditUnavailable   = errors.New("audit log unavailable")
 )
 
 // RepositoryInterface defines the interface for repository operations
@@ -41,14 +45,97 @@ type RepositoryInterface interface {
 	GetChallenge(ctx context.Context, userID *uuid.UUID, challengeType string) (*model.WebAuthnChallenge, error)
 	DeleteChallenge(ctx context.Context, id uuid.UUID) error
 	// Admin methods
-	ListUsers(ctx context.Context) ([]model.User, error)
+	ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error)
 	UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error
+	// Session methods
+	CreateSession(ctx context.Context, session *model.Session) error
+	GetSession(ctx context.Context, id uuid.UUID) (*model.Session, error)
+	RotateSession(ctx context.Context, session *model.Session, previousRefreshTokenID string) error
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, reason string) (*model.Session, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID, reason string) ([]model.Session, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]model.Session, error)
+	// API key methods
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	GetAPIKey(ctx context.Context, userID, id uuid.UUID) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID, ipAddress string, at time.Time) error
+	CreateAPIKeyEvent(ctx context.Context, event *model.APIKeyEvent) error
+	ListAPIKeyEvents(ctx context.Context, keyID uuid.UUID, limit int) ([]model.APIKeyEvent, error)
+	// MFA methods
+	GetMFA(ctx context.Context, userID uuid.UUID) (*model.MFA, error)
+	SavePendingMFA(ctx context.Context, mfa *model.MFA) error
+	EnableMFA(ctx context.Context, userID uuid.UUID, step int64, codes []model.RecoveryCode) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DeleteMFA(ctx context.Context, userID uuid.UUID) error
+	ListRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]model.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []model.RecoveryCode) error
+	CreateMFAChallenge(ctx context.Context, challenge *model.MFAChallenge) error
+	GetMFAChallenge(ctx context.Context, id uuid.UUID) (*model.MFAChallenge, error)
+	AttemptMFAChallenge(ctx context.Context, id uuid.UUID, max int) (int, error)
+	UseMFAChallenge(ctx context.Context, id uuid.UUID, max int) error
+	// Login throttle and security event methods
+	GetLoginThrottles(ctx context.Context, keys []string) ([]model.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*model.LoginThrottle, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, key string) error
+	CreateSecurityEvent(ctx context.Context, event *model.SecurityEvent) error
+	ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error)
+	LoginHistory(ctx context.Context, userID uuid.UUID, userAgent string) (bool, bool, error)
+	// Organisation methods
+	CreateOrganisation(ctx context.Context, org *model.Organisation, owner *model.Membership) error
+	GetOrganisation(ctx context.Context, id uuid.UUID) (*model.Organisation, error)
+	ListUserOrganisations(ctx context.Context, userID uuid.UUID) ([]model.OrganisationResponse, error)
+	GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*model.Membership, error)
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Membership, error)
+	CountOwners(ctx context.Context, orgID uuid.UUID) (int, error)
+	UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role model.OrgRole) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	CreateInvitation(ctx context.Context, inv *model.Invitation) error
+	GetInvitationByHash(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, orgID uuid.UUID) ([]model.Invitation, error)
+	DeleteInvitation(ctx context.Context, orgID, id uuid.UUID) error
+	AcceptInvitation(ctx context.Context, inv *model.Invitation, userID uuid.UUID, at time.Time) error
+	// Single sign-on methods
+	SaveIdentityProvider(ctx context.Context, p *model.IdentityProvider) error
+	GetIdentityProvider(ctx context.Context, orgID uuid.UUID) (*model.IdentityProvider, error)
+	VerifyIdentityProviderDomain(ctx context.Context, orgID uuid.UUID, token string, at time.Time) error
+	GetIdentityProviderByDomain(ctx context.Context, domain string) (*model.IdentityProvider, error)
+	DeleteIdentityProvider(ctx context.Context, orgID uuid.UUID) error
+	CreateSSOLogin(ctx context.Context, login *model.SSOLogin) error
+	TakeSSOLogin(ctx context.Context, stateHash string, now time.Time) (*model.SSOLogin, error)
+	GetSSOIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error)
+	LinkSSOIdentity(ctx context.Context, identity *model.SSOIdentity) error
+	ProvisionSSOUser(ctx context.Context, user *model.User, identity *model.SSOIdentity) error
+	// Delegation methods
+	CreateDelegation(ctx context.Context, d *model.Delegation) error
+	GetDelegation(ctx context.Context, id uuid.UUID) (*model.Delegation, error)
+	ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error)
+	RevokeDelegation(ctx context.Context, userID, id uuid.UUID) error
+	// Phone methods
+	GetUserByPhone(ctx context.Context, phone string) (*model.User, error)
+	SetVerifiedPhone(ctx context.Context, userID uuid.UUID, phone string) error
+	CreatePhoneCode(ctx context.Context, code *model.PhoneCode, purgeBefore time.Time) error
+	RecentPhoneCodes(ctx context.Context, phone string, since time.Time) ([]model.PhoneCode, error)
+	LatestPhoneCode(ctx context.Context, phone string, purpose model.PhoneCodePurpose) (*model.PhoneCode, error)
+	AttemptPhoneCode(ctx context.Context, id uuid.UUID, max int) (int, error)
+	UsePhoneCode(ctx context.Context, id uuid.UUID) error
 }
 
 // EmailSender interface for sending emails
 type EmailSender interface {
 	SendVerificationEmail(to, token string) error
 	SendPasswordResetEmail(to, token string) error
+	SendSecurityAlertEmail(to, subject, message string) error
+	SendInvitationEmail(to, orgName, token string) error
+}
+
+// SMSSender interface for sending text messages
+type SMSSender interface {
+	SendLoginCode(to, code string) error
+	SendVerificationCode(to, code string) error
 }
 
 // Service handles auth business logic
@@ -57,7 +144,25 @@ type Service struct {
 	jwtManager *jwt.JWTManager
 	webauthn   *webauthn.WebAuthn
 	email      EmailSender
+	sms        SMSSender
+	audit      audit.Recorder
+	denylist   jwt.Denylist
 	logger     *logger.Logger
+
+	// mfaRequired holds the user types that must use a second factor
+	mfaRequired map[model.UserType]bool
+
+	// accountLockout and ipLockout throttle failed password logins
+	accountLockout LockoutPolicy
+	ipLockout      LockoutPolicy
+
+	// oidc logs users in through their organisation's identity provider,
+	// which sends them back to ssoRedirectURL
+	oidc           *oidc.Client
+	ssoRedirectURL string
+	// txtResolver looks up domain verification records; nil uses the
+	// system resolver
+	txtResolver TXTResolver
 }
 
 // New creates a new auth service
@@ -66,6 +171,9 @@ func New(repo RepositoryInterface, jwtManager *jwt.JWTManager, logger *logger.Lo
 		repo:       repo,
 		jwtManager: jwtManager,
 		logger:     logger,
+
+		accountLockout: DefaultAccountLockout,
+		ipLockout:      DefaultIPLockout,
 	}
 }
 
@@ -74,6 +182,16 @@ func (s *Service) SetEmailSender(email EmailSender) {
 	s.email = email
 }
 
+// SetSMSSender sets the SMS service, enabling phone number logins
+func (s *Service) SetSMSSender(sms SMSSender) {
+	s.sms = sms
+}
+
+// SetAuditRecorder sets where administrative actions are recorded
+func (s *Service) SetAuditRecorder(recorder audit.Recorder) {
+	s.audit = recorder
+}
+
 // NewWithWebAuthn creates a new auth service with WebAuthn support
 func NewWithWebAuthn(repo RepositoryInterface, jwtManager *jwt.JWTManager, webauthn *webauthn.WebAuthn, logger *logger.Logger) *Service {
 	return &Service{
@@ -81,6 +199,9 @@ func NewWithWebAuthn(repo RepositoryInterface, jwtManager *jwt.JWTManager, webau
 		jwtManager: jwtManager,
 		webauthn:   webauthn,
 		logger:     logger,
+
+		accountLockout: DefaultAccountLockout,
+		ipLockout:      DefaultIPLockout,
 	}
 }
 
@@ -115,6 +236,7 @@ func (s *Service) Register(ctx context.Context, req *model.RegisterRequest) (*mo
 		UserType:          req.UserType,
 		Status:            model.UserStatusActive,
 		EmailVerified:     false,
+		Phone:             req.Phone,
 		VerificationToken: &verificationToken,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
@@ -135,8 +257,13 @@ func (s *Service) Register(ctx context.Context, req *model.RegisterRequest) (*mo
 		}()
 	}
 
+	// User types that require a second factor enrol before getting tokens
+	if s.mfaRequired[user.UserType] {
+		return s.mfaChallenge(ctx, user, true)
+	}
+
 	// Generate JWT tokens
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, string(user.UserType))
+	tokens, err := s.startSession(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -151,10 +278,17 @@ func (s *Service) Register(ctx context.Context, req *model.RegisterRequest) (*mo
 
 // Login authenticates a user and returns tokens
 func (s *Service) Login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error) {
+	// Refuse accounts and clients with too many recent failures
+	throttleKeys := loginThrottleKeys(ctx, req.Email)
+	if err := s.checkLoginThrottle(ctx, throttleKeys); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
+			s.loginFailed(ctx, throttleKeys, nil)
 			return nil, ErrInvalidCredentials
 		}
 		return nil, err
@@ -162,6 +296,7 @@ func (s *Service) Login(ctx context.Context, req *model.LoginRequest) (*model.Lo
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.loginFailed(ctx, throttleKeys, user)
 		return nil, ErrInvalidCredentials
 	}
 
@@ -170,57 +305,13 @@ func (s *Service) Login(ctx context.Context, req *model.LoginRequest) (*model.Lo
 		return nil, ErrUserNotActive
 	}
 
-	// Update last login
-	if err := s.repo.UpdateLastLogin(ctx, user.ID); err != nil {
-		s.logger.Error("Failed to update last login", "user_id", user.ID, "error", err)
-	}
-
-	s.logger.Info("User logged in", "user_id", user.ID, "email", user.Email)
-
-	// Generate JWT tokens
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, string(user.UserType))
-	if err != nil {
-		return nil, err
-	}
-
-	return &model.LoginResponse{
-		User:         user.ToUserResponse(),
-		AccessToken:  tokens.AccessToken,
-		RefreshToken: tokens.RefreshToken,
-		ExpiresIn:    tokens.ExpiresIn,
-	}, nil
+	// Start a session, or ask for the second factor first
+	return s.beginSession(ctx, user, "password")
 }
 
-// RefreshToken refreshes an access token
-func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*jwt.TokenPair, error) {
-	// Validate refresh token
-	claims, err := s.jwtManager.ValidateToken(refreshToken)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get user to ensure they still exist and are active
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, errors.New("invalid user ID in token")
-	}
-
-	user, err := s.repo.GetUserByID(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	if user.Status != model.UserStatusActive {
-		return nil, ErrUserNotActive
-	}
-
-	// Generate new token pair
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, string(user.UserType))
-	if err != nil {
-		return nil, err
-	}
-
-	return tokens, nil
+// JWKS returns the public keys that verify tokens issued by this service
+func (s *Service) JWKS() *jwt.JWKS {
+	return s.jwtManager.JWKS()
 }
 
 // ChangePassword changes a user's password
@@ -247,6 +338,7 @@ func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, req *mod
 	}
 
 	s.logger.Info("Password changed", "user_id", userID)
+	s.recordSecurityEvent(ctx, userID, model.SecurityPasswordChanged, "")
 
 	return nil
 }
@@ -337,12 +429,40 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// ListUsers returns all users (admin only)
-func (s *Service) ListUsers(ctx context.Context) ([]model.User, error) {
-	return s.repo.ListUsers(ctx)
+// ListUsers returns a page of users and the cursor of the next page (admin only)
+func (s *Service) ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error) {
+	return s.repo.ListUsers(ctx, page)
 }
 
-// UpdateUserStatus updates a user's status (admin only)
+// UpdateUserStatus updates a user's status and records the change in the
+// audit log (admin only)
 func (s *Service) UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	changes, _ := audit.Diff(map[string]string{"status": string(user.Status)}, map[string]string{"status": status})
+	if err := s.recordAudit(ctx, audit.Entry{
+		Action:       audit.ActionUserStatusChanged,
+		ResourceType: "user",
+		ResourceID:   userID.String(),
+		Changes:      changes,
+	}); err != nil {
+		return err
+	}
 	return s.repo.UpdateUserStatus(ctx, userID, status)
 }
+
+// recordAudit records an administrative action before it is taken. Without
+// an entry the action is refused, so nothing an admin does goes unrecorded.
+func (s *Service) recordAudit(ctx context.Context, e audit.Entry) error {
+	if s.audit == nil {
+		return nil
+	}
+	if err := s.audit.Record(ctx, e); err != nil {
+		s.logger.Error("Failed to record audit entry", "action", e.Action, "resource_id", e.ResourceID, "error", err)
+		return ErrAuditUnavailable
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -13,8 +14,11 @@ import (
 	"truckify/services/auth/internal/model"
 	"truckify/services/auth/internal/repository"
 	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/authz"
 	"truckify/shared/pkg/jwt"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 )
 
 type MockRepository struct {
@@ -117,12 +121,12 @@ func (m *MockRepository) DeleteChallenge(ctx context.Context, id uuid.UUID) erro
 	return args.Error(0)
 }
 
-func (m *MockRepository) ListUsers(ctx context.Context) ([]model.User, error) {
-	args := m.Called(ctx)
+func (m *MockRepository) ListUsers(ctx context.Context, page pagination.Page) ([]model.User, string, error) {
+	args := m.Called(ctx, page)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, "", args.Error(2)
 	}
-	return args.Get(0).([]model.User), args.Error(1)
+	return args.Get(0).([]model.User), args.String(1), args.Error(2)
 }
 
 func (m *MockRepository) UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error {
@@ -130,6 +134,442 @@ func (m *MockRepository) UpdateUserStatus(ctx context.Context, userID uuid.UUID,
 	return args.Error(0)
 }
 
+// Session methods
+func (m *MockRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetSession(ctx context.Context, id uuid.UUID) (*model.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockRepository) RotateSession(ctx context.Context, session *model.Session, previousRefreshTokenID string) error {
+	args := m.Called(ctx, session, previousRefreshTokenID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, reason string) (*model.Session, error) {
+	args := m.Called(ctx, userID, sessionID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockRepository) RevokeUserSessions(ctx context.Context, userID uuid.UUID, reason string) ([]model.Session, error) {
+	args := m.Called(ctx, userID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+
+func (m *MockRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockRepository) GetAPIKey(ctx context.Context, userID, id uuid.UUID) (*model.APIKey, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]model.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.APIKey), args.Error(1)
+}
+
+func (m *MockRepository) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, ipAddress string, at time.Time) error {
+	args := m.Called(ctx, id, ipAddress, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateAPIKeyEvent(ctx context.Context, event *model.APIKeyEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListAPIKeyEvents(ctx context.Context, keyID uuid.UUID, limit int) ([]model.APIKeyEvent, error) {
+	args := m.Called(ctx, keyID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.APIKeyEvent), args.Error(1)
+}
+
+// MFA methods
+func (m *MockRepository) GetMFA(ctx context.Context, userID uuid.UUID) (*model.MFA, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFA), args.Error(1)
+}
+
+func (m *MockRepository) SavePendingMFA(ctx context.Context, mfa *model.MFA) error {
+	args := m.Called(ctx, mfa)
+	return args.Error(0)
+}
+
+func (m *MockRepository) EnableMFA(ctx context.Context, userID uuid.UUID, step int64, codes []model.RecoveryCode) error {
+	args := m.Called(ctx, userID, step, codes)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) DeleteMFA(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]model.RecoveryCode, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.RecoveryCode), args.Error(1)
+}
+
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []model.RecoveryCode) error {
+	args := m.Called(ctx, userID, codes)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateMFAChallenge(ctx context.Context, challenge *model.MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetMFAChallenge(ctx context.Context, id uuid.UUID) (*model.MFAChallenge, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MFAChallenge), args.Error(1)
+}
+
+func (m *MockRepository) AttemptMFAChallenge(ctx context.Context, id uuid.UUID, max int) (int, error) {
+	args := m.Called(ctx, id, max)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) UseMFAChallenge(ctx context.Context, id uuid.UUID, max int) error {
+	args := m.Called(ctx, id, max)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetLoginThrottles(ctx context.Context, keys []string) ([]model.LoginThrottle, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LoginThrottle), args.Error(1)
+}
+
+func (m *MockRepository) RecordLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (*model.LoginThrottle, error) {
+	args := m.Called(ctx, key, now, resetBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginThrottle), args.Error(1)
+}
+
+func (m *MockRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClearLoginFailures(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateSecurityEvent(ctx context.Context, event *model.SecurityEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListSecurityEvents(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.SecurityEvent, string, error) {
+	args := m.Called(ctx, userID, page)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]model.SecurityEvent), args.String(1), args.Error(2)
+}
+
+func (m *MockRepository) LoginHistory(ctx context.Context, userID uuid.UUID, userAgent string) (bool, bool, error) {
+	args := m.Called(ctx, userID, userAgent)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+// Organisation methods
+func (m *MockRepository) CreateOrganisation(ctx context.Context, org *model.Organisation, owner *model.Membership) error {
+	args := m.Called(ctx, org, owner)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetOrganisation(ctx context.Context, id uuid.UUID) (*model.Organisation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Organisation), args.Error(1)
+}
+
+func (m *MockRepository) ListUserOrganisations(ctx context.Context, userID uuid.UUID) ([]model.OrganisationResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.OrganisationResponse), args.Error(1)
+}
+
+func (m *MockRepository) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*model.Membership, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Membership), args.Error(1)
+}
+
+func (m *MockRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Membership, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Membership), args.Error(1)
+}
+
+func (m *MockRepository) CountOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	args := m.Called(ctx, orgID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role model.OrgRole) error {
+	args := m.Called(ctx, orgID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	args := m.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateInvitation(ctx context.Context, inv *model.Invitation) error {
+	args := m.Called(ctx, inv)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetInvitationByHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invitation), args.Error(1)
+}
+
+func (m *MockRepository) ListInvitations(ctx context.Context, orgID uuid.UUID) ([]model.Invitation, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Invitation), args.Error(1)
+}
+
+func (m *MockRepository) DeleteInvitation(ctx context.Context, orgID, id uuid.UUID) error {
+	args := m.Called(ctx, orgID, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AcceptInvitation(ctx context.Context, inv *model.Invitation, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, inv, userID, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateDelegation(ctx context.Context, d *model.Delegation) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetDelegation(ctx context.Context, id uuid.UUID) (*model.Delegation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Delegation), args.Error(1)
+}
+
+func (m *MockRepository) ListDelegations(ctx context.Context, userID uuid.UUID) ([]model.Delegation, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Delegation), args.Error(1)
+}
+
+func (m *MockRepository) RevokeDelegation(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveIdentityProvider(ctx context.Context, p *model.IdentityProvider) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetIdentityProvider(ctx context.Context, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityProvider), args.Error(1)
+}
+
+func (m *MockRepository) VerifyIdentityProviderDomain(ctx context.Context, orgID uuid.UUID, token string, at time.Time) error {
+	args := m.Called(ctx, orgID, token, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetIdentityProviderByDomain(ctx context.Context, domain string) (*model.IdentityProvider, error) {
+	args := m.Called(ctx, domain)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityProvider), args.Error(1)
+}
+
+func (m *MockRepository) DeleteIdentityProvider(ctx context.Context, orgID uuid.UUID) error {
+	args := m.Called(ctx, orgID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateSSOLogin(ctx context.Context, login *model.SSOLogin) error {
+	args := m.Called(ctx, login)
+	return args.Error(0)
+}
+
+func (m *MockRepository) TakeSSOLogin(ctx context.Context, stateHash string, now time.Time) (*model.SSOLogin, error) {
+	args := m.Called(ctx, stateHash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SSOLogin), args.Error(1)
+}
+
+func (m *MockRepository) GetSSOIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error) {
+	args := m.Called(ctx, orgID, subject)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockRepository) LinkSSOIdentity(ctx context.Context, identity *model.SSOIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ProvisionSSOUser(ctx context.Context, user *model.User, identity *model.SSOIdentity) error {
+	args := m.Called(ctx, user, identity)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetUserByPhone(ctx context.Context, phone string) (*model.User, error) {
+	args := m.Called(ctx, phone)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockRepository) SetVerifiedPhone(ctx context.Context, userID uuid.UUID, phone string) error {
+	args := m.Called(ctx, userID, phone)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreatePhoneCode(ctx context.Context, code *model.PhoneCode, purgeBefore time.Time) error {
+	args := m.Called(ctx, code, purgeBefore)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RecentPhoneCodes(ctx context.Context, phone string, since time.Time) ([]model.PhoneCode, error) {
+	args := m.Called(ctx, phone, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.PhoneCode), args.Error(1)
+}
+
+func (m *MockRepository) LatestPhoneCode(ctx context.Context, phone string, purpose model.PhoneCodePurpose) (*model.PhoneCode, error) {
+	args := m.Called(ctx, phone, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PhoneCode), args.Error(1)
+}
+
+func (m *MockRepository) AttemptPhoneCode(ctx context.Context, id uuid.UUID, max int) (int, error) {
+	args := m.Called(ctx, id, max)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) UsePhoneCode(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type fakeDenylist struct {
+	revoked map[string]time.Time
+}
+
+func (d *fakeDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	d.revoked[jti] = expiresAt
+	return nil
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	_, ok := d.revoked[jti]
+	return ok, nil
+}
+
 func setupTestService() (*service.Service, *MockRepository) {
 	mockRepo := new(MockRepository)
 	log := logger.New("test", "debug")
@@ -138,12 +578,23 @@ func setupTestService() (*service.Service, *MockRepository) {
 	return svc, mockRepo
 }
 
+// expectLoginChecks lets password logins through the throttle and accepts
+// the security events they record
+func expectLoginChecks(mockRepo *MockRepository, ctx context.Context) {
+	mockRepo.On("GetLoginThrottles", ctx, mock.Anything).Return(nil, nil)
+	mockRepo.On("ClearLoginFailures", ctx, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("RecordLoginFailure", ctx, mock.Anything, mock.Anything, mock.Anything).
+		Return(&model.LoginThrottle{Failures: 1}, nil).Maybe()
+	mockRepo.On("CreateSecurityEvent", ctx, mock.AnythingOfType("*model.SecurityEvent")).Return(nil).Maybe()
+}
+
 func TestRegister_Success(t *testing.T) {
 	svc, mockRepo := setupTestService()
 	ctx := context.Background()
 
 	mockRepo.On("GetUserByEmail", ctx, "test@example.com").Return(nil, repository.ErrUserNotFound)
 	mockRepo.On("CreateUser", ctx, mock.AnythingOfType("*model.User")).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*model.Session")).Return(nil)
 
 	req := &model.RegisterRequest{
 		Email:    "test@example.com",
@@ -189,6 +640,7 @@ func TestRegister_EmailAlreadyExists(t *testing.T) {
 func TestLogin_Success(t *testing.T) {
 	svc, mockRepo := setupTestService()
 	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
 
 	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	userID := uuid.New()
@@ -202,7 +654,11 @@ func TestLogin_Success(t *testing.T) {
 	}
 
 	mockRepo.On("GetUserByEmail", ctx, "test@example.com").Return(existingUser, nil)
+	mockRepo.On("GetMFA", ctx, userID).Return(nil, repository.ErrMFANotFound)
 	mockRepo.On("UpdateLastLogin", ctx, userID).Return(nil)
+	mockRepo.On("CreateSession", ctx, mock.MatchedBy(func(s *model.Session) bool {
+		return s.UserID == userID && s.RefreshTokenID != "" && s.AccessTokenID != ""
+	})).Return(nil)
 
 	req := &model.LoginRequest{
 		Email:    "test@example.com",
@@ -221,6 +677,7 @@ func TestLogin_Success(t *testing.T) {
 func TestLogin_InvalidPassword(t *testing.T) {
 	svc, mockRepo := setupTestService()
 	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
 
 	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	existingUser := &model.User{
@@ -249,6 +706,7 @@ func TestLogin_InvalidPassword(t *testing.T) {
 func TestLogin_UserNotFound(t *testing.T) {
 	svc, mockRepo := setupTestService()
 	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
 
 	mockRepo.On("GetUserByEmail", ctx, "nonexistent@example.com").Return(nil, repository.ErrUserNotFound)
 
@@ -268,6 +726,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 func TestLogin_UserNotActive(t *testing.T) {
 	svc, mockRepo := setupTestService()
 	ctx := context.Background()
+	expectLoginChecks(mockRepo, ctx)
 
 	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	existingUser := &model.User{
@@ -298,6 +757,7 @@ func TestRefreshToken_Success(t *testing.T) {
 	ctx := context.Background()
 
 	userID := uuid.New()
+	sessionID := uuid.New()
 	existingUser := &model.User{
 		ID:       userID,
 		Email:    "test@example.com",
@@ -305,17 +765,127 @@ func TestRefreshToken_Success(t *testing.T) {
 		Status:   model.UserStatusActive,
 	}
 
-	// Generate a valid refresh token
+	// Generate a valid refresh token for the session
 	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
-	tokens, _ := jwtManager.GenerateTokenPair(userID.String(), "test@example.com", "driver")
+	tokens, _ := jwtManager.GenerateSessionTokenPair(sessionID.String(), userID.String(), "test@example.com", "driver")
+	session := &model.Session{ID: sessionID, UserID: userID, RefreshTokenID: tokens.RefreshTokenID}
 
+	mockRepo.On("GetSession", ctx, sessionID).Return(session, nil)
 	mockRepo.On("GetUserByID", ctx, userID).Return(existingUser, nil)
+	mockRepo.On("RotateSession", ctx, mock.MatchedBy(func(s *model.Session) bool {
+		return s.ID == sessionID && s.RefreshTokenID != tokens.RefreshTokenID
+	}), tokens.RefreshTokenID).Return(nil)
 
 	newTokens, err := svc.RefreshToken(ctx, tokens.RefreshToken)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, newTokens)
 	assert.NotEmpty(t, newTokens.AccessToken)
+	assert.NotEqual(t, tokens.RefreshToken, newTokens.RefreshToken)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshToken_RejectsAccessToken(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	tokens, _ := jwtManager.GenerateSessionTokenPair(uuid.New().String(), uuid.New().String(), "test@example.com", "driver")
+
+	newTokens, err := svc.RefreshToken(ctx, tokens.AccessToken)
+
+	assert.Equal(t, service.ErrInvalidRefreshToken, err)
+	assert.Nil(t, newTokens)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshToken_ReuseRevokesSession(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	denylist := &fakeDenylist{revoked: map[string]time.Time{}}
+	svc.SetDenylist(denylist)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	// The session has already been rotated past this refresh token
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	stale, _ := jwtManager.GenerateSessionTokenPair(sessionID.String(), userID.String(), "test@example.com", "driver")
+	session := &model.Session{ID: sessionID, UserID: userID, RefreshTokenID: "rotated", AccessTokenID: "latest-access"}
+	revoked := &model.Session{ID: sessionID, UserID: userID, AccessTokenID: "latest-access", AccessExpiresAt: time.Now().Add(10 * time.Minute)}
+
+	mockRepo.On("GetSession", ctx, sessionID).Return(session, nil)
+	mockRepo.On("RevokeSession", ctx, userID, sessionID, model.RevokedTokenReuse).Return(revoked, nil)
+	mockRepo.On("CreateSecurityEvent", ctx, mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.UserID == userID && e.Type == model.SecurityRefreshTokenReused
+	})).Return(nil)
+
+	newTokens, err := svc.RefreshToken(ctx, stale.RefreshToken)
+
+	assert.Equal(t, service.ErrRefreshTokenReused, err)
+	assert.Nil(t, newTokens)
+	assert.Contains(t, denylist.revoked, "latest-access")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshToken_RevokedSession(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	sessionID := uuid.New()
+	revokedAt := time.Now()
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	tokens, _ := jwtManager.GenerateSessionTokenPair(sessionID.String(), userID.String(), "test@example.com", "driver")
+	session := &model.Session{ID: sessionID, UserID: userID, RefreshTokenID: tokens.RefreshTokenID, RevokedAt: &revokedAt}
+
+	mockRepo.On("GetSession", ctx, sessionID).Return(session, nil)
+
+	newTokens, err := svc.RefreshToken(ctx, tokens.RefreshToken)
+
+	assert.Equal(t, service.ErrInvalidRefreshToken, err)
+	assert.Nil(t, newTokens)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogoutAll_DeniesAccessTokens(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	denylist := &fakeDenylist{revoked: map[string]time.Time{}}
+	svc.SetDenylist(denylist)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	sessions := []model.Session{
+		{ID: uuid.New(), UserID: userID, AccessTokenID: "phone", AccessExpiresAt: time.Now().Add(5 * time.Minute)},
+		{ID: uuid.New(), UserID: userID, AccessTokenID: "laptop", AccessExpiresAt: time.Now().Add(5 * time.Minute)},
+	}
+	mockRepo.On("RevokeUserSessions", ctx, userID, model.RevokedLogoutAll).Return(sessions, nil)
+
+	err := svc.LogoutAll(ctx, userID)
+
+	assert.NoError(t, err)
+	assert.Len(t, denylist.revoked, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListSessions_MarksCurrent(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	current := uuid.New()
+	mockRepo.On("ListSessions", ctx, userID).Return([]model.Session{
+		{ID: current, UserID: userID, UserAgent: "Mozilla/5.0"},
+		{ID: uuid.New(), UserID: userID, UserAgent: "okhttp/4.9"},
+	}, nil)
+
+	sessions, err := svc.ListSessions(ctx, userID, current.String())
+
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.True(t, sessions[0].Current)
+	assert.False(t, sessions[1].Current)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -333,6 +903,9 @@ func TestChangePassword_Success(t *testing.T) {
 
 	mockRepo.On("GetUserByID", ctx, userID).Return(existingUser, nil)
 	mockRepo.On("UpdatePassword", ctx, userID, mock.AnythingOfType("string")).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, mock.MatchedBy(func(e *model.SecurityEvent) bool {
+		return e.UserID == userID && e.Type == model.SecurityPasswordChanged
+	})).Return(nil)
 
 	req := &model.ChangePasswordRequest{
 		OldPassword: "oldpassword",
@@ -369,3 +942,152 @@ func TestChangePassword_WrongOldPassword(t *testing.T) {
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestCreateAPIKey_RejectsScopeOutsideRole(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	mockRepo.On("GetUserByID", ctx, userID).Return(&model.User{ID: userID, UserType: model.UserTypeDriver, Status: model.UserStatusActive}, nil)
+
+	for _, scopes := range [][]string{{"job:create"}, {"job:read", "job:teleport"}} {
+		key, err := svc.CreateAPIKey(ctx, userID, &model.CreateAPIKeyRequest{Name: "tms", Scopes: scopes})
+
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+		assert.Nil(t, key)
+	}
+	mockRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+}
+
+func TestCreateAPIKey_DispatcherOnlyReads(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	mockRepo.On("GetUserByID", ctx, userID).Return(&model.User{ID: userID, UserType: model.UserTypeDispatcher, Status: model.UserStatusActive}, nil)
+
+	// Acting for a user takes a delegation, so a dispatcher's own key cannot
+	// change jobs or bids
+	for _, scope := range []string{"job:create", "job:update", "job:assign", "job:transition", "job:cancel", "bid:accept"} {
+		key, err := svc.CreateAPIKey(ctx, userID, &model.CreateAPIKeyRequest{Name: "tms", Scopes: []string{scope}})
+
+		assert.ErrorIs(t, err, service.ErrInvalidScope, scope)
+		assert.Nil(t, key)
+	}
+	mockRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+}
+
+func TestIssueClientToken(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	user := &model.User{ID: userID, Email: "ops@example.com", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+	mockRepo.On("GetUserByID", ctx, userID).Return(user, nil)
+	mockRepo.On("CreateAPIKeyEvent", ctx, mock.Anything).Return(nil)
+	mockRepo.On("TouchAPIKey", ctx, mock.Anything, "", mock.Anything).Return(nil)
+
+	var stored *model.APIKey
+	mockRepo.On("CreateAPIKey", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.APIKey)
+	}).Return(nil)
+
+	created, err := svc.CreateAPIKey(ctx, userID, &model.CreateAPIKeyRequest{
+		Name: "tms", Scopes: []string{"job:read", "job:create", "job:read"}, RateLimit: 120,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job:read", "job:create"}, created.Scopes)
+	assert.Contains(t, created.Key, model.APIKeyPrefix)
+	assert.NotEqual(t, created.Key, stored.KeyHash)
+	mockRepo.On("GetAPIKeyByHash", ctx, stored.KeyHash).Return(stored, nil)
+
+	token, err := svc.IssueClientToken(ctx, created.ID.String(), created.Key, "job:read")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, "job:read", token.Scope)
+
+	jwtManager := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+	claims, err := jwtManager.ValidateAccessToken(token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID.String(), claims.ClientID)
+	assert.Equal(t, userID.String(), claims.UserID)
+	assert.Equal(t, []string{"job:read"}, claims.Scopes())
+	assert.Equal(t, 120, claims.RateLimit)
+
+	_, err = svc.IssueClientToken(ctx, created.ID.String(), created.Key, "payment:read")
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	_, err = svc.IssueClientToken(ctx, uuid.New().String(), created.Key, "")
+	assert.Equal(t, service.ErrInvalidClient, err)
+}
+
+func TestVerifyAPIKey_RevokedKey(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := service.WithClientInfo(context.Background(), model.ClientInfo{IPAddress: "203.0.113.9"})
+
+	revokedAt := time.Now().Add(-time.Hour)
+	key := &model.APIKey{ID: uuid.New(), UserID: uuid.New(), RevokedAt: &revokedAt}
+	mockRepo.On("GetAPIKeyByHash", ctx, mock.Anything).Return(key, nil)
+	mockRepo.On("CreateAPIKeyEvent", ctx, mock.MatchedBy(func(e *model.APIKeyEvent) bool {
+		return e.APIKeyID == key.ID && e.Event == model.APIKeyEventRejected && e.IPAddress == "203.0.113.9"
+	})).Return(nil)
+
+	identity, err := svc.VerifyAPIKey(ctx, model.APIKeyPrefix+"revoked")
+
+	assert.Equal(t, service.ErrInvalidAPIKey, err)
+	assert.Nil(t, identity)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRevokeAPIKey_DeniesClientTokens(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	denylist := &fakeDenylist{revoked: map[string]time.Time{}}
+	svc.SetDenylist(denylist)
+	ctx := context.Background()
+
+	userID, keyID := uuid.New(), uuid.New()
+	mockRepo.On("RevokeAPIKey", ctx, userID, keyID).Return(nil)
+	mockRepo.On("CreateAPIKeyEvent", ctx, mock.Anything).Return(nil)
+
+	err := svc.RevokeAPIKey(ctx, userID, keyID)
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), denylist.revoked[keyID.String()], time.Minute)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateUserStatus_Audited(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	recorder := audit.NewMemory("auth-service")
+	svc.SetAuditRecorder(recorder)
+
+	adminID := uuid.New()
+	ctx := authz.WithPrincipal(context.Background(), authz.Principal{UserID: adminID, Role: authz.RoleAdmin})
+	user := &model.User{ID: uuid.New(), Status: model.UserStatusActive}
+	mockRepo.On("GetUserByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("UpdateUserStatus", ctx, user.ID, "suspended").Return(nil)
+
+	assert.NoError(t, svc.UpdateUserStatus(ctx, user.ID, "suspended"))
+
+	entries := recorder.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionUserStatusChanged, entries[0].Action)
+	assert.Equal(t, user.ID.String(), entries[0].ResourceID)
+	assert.Equal(t, &adminID, entries[0].ActorID)
+	assert.JSONEq(t, `"active"`, string(entries[0].Changes["status"].Before))
+	assert.JSONEq(t, `"suspended"`, string(entries[0].Changes["status"].After))
+
+	// Nothing is recorded when the user does not exist
+	missing := uuid.New()
+	mockRepo.On("GetUserByID", ctx, missing).Return(nil, repository.ErrUserNotFound)
+	assert.Equal(t, repository.ErrUserNotFound, svc.UpdateUserStatus(ctx, missing, "suspended"))
+	assert.Len(t, recorder.Entries(), 1)
+	mockRepo.AssertNotCalled(t, "UpdateUserStatus", ctx, missing, "suspended")
+
+	// Without an entry the status is left alone
+	recorder.Err = errors.New("audit service down")
+	other := &model.User{ID: uuid.New(), Status: model.UserStatusActive}
+	mockRepo.On("GetUserByID", ctx, other.ID).Return(other, nil)
+	assert.Equal(t, service.ErrAuditUnavailable, svc.UpdateUserStatus(ctx, other.ID, "suspended"))
+	mockRepo.AssertNotCalled(t, "UpdateUserStatus", ctx, other.ID, "suspended")
+}
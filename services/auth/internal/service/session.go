@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/repository"
+	"truckify/shared/pkg/jwt"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying the caller's device details,
+// which are recorded on the sessions it starts or refreshes
+func WithClientInfo(ctx context.Context, info model.ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func clientInfoFrom(ctx context.Context) model.ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(model.ClientInfo)
+	return info
+}
+
+// SetDenylist sets the store used to deny access tokens of revoked sessions
+func (s *Service) SetDenylist(denylist jwt.Denylist) {
+	s.denylist = denylist
+}
+
+// startSession creates a session for the user and issues its first token
+// pair. Sessions start out acting for the user's personal organisation.
+func (s *Service) startSession(ctx context.Context, user *model.User) (*jwt.TokenPair, error) {
+	return s.startOrgSession(ctx, user, user.ID, model.OrgRoleOwner)
+}
+
+// startOrgSession creates a session for the user acting for one of their
+// organisations, in which they have role
+func (s *Service) startOrgSession(ctx context.Context, user *model.User, orgID uuid.UUID, role model.OrgRole) (*jwt.TokenPair, error) {
+	sessionID := uuid.New()
+	tokens, err := s.jwtManager.GenerateOrgTokenPair(sessionID.String(), user.ID.String(), user.Email, string(user.UserType),
+		orgID.String(), string(role))
+	if err != nil {
+		return nil, err
+	}
+
+	info := clientInfoFrom(ctx)
+	now := time.Now()
+	session := &model.Session{
+		ID:              sessionID,
+		UserID:          user.ID,
+		RefreshTokenID:  tokens.RefreshTokenID,
+		AccessTokenID:   tokens.AccessTokenID,
+		AccessExpiresAt: tokens.AccessExpiresAt,
+		UserAgent:       info.UserAgent,
+		IPAddress:       info.IPAddress,
+		CreatedAt:       now,
+		LastUsedAt:      now,
+		ExpiresAt:       tokens.RefreshExpiresAt,
+	}
+	if orgID != user.ID {
+		session.OrgID = orgID
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. The refresh
+// token is rotated: presenting one that has already been exchanged revokes
+// the whole session, since either the client or an attacker holds a copy.
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*jwt.TokenPair, error) {
+	return s.rotateSession(ctx, refreshToken, nil)
+}
+
+// rotateSession issues a new token pair for the session of the refresh
+// token, moving it to switchTo when that is set. A session whose user has
+// left its organisation goes back to their personal one.
+func (s *Service) rotateSession(ctx context.Context, refreshToken string, switchTo *uuid.UUID) (*jwt.TokenPair, error) {
+	session, claims, err := s.sessionFromRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if claims.ID != session.RefreshTokenID {
+		s.revokeReused(ctx, session)
+		return nil, ErrRefreshTokenReused
+	}
+
+	// Get user to ensure they still exist and are active
+	user, err := s.repo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+
+	orgID := session.OrgID
+	if switchTo != nil {
+		orgID = *switchTo
+	}
+	orgID, orgRole, err := s.sessionOrg(ctx, user, orgID)
+	if err == repository.ErrMembershipNotFound {
+		orgID, orgRole, err = s.sessionOrg(ctx, user, uuid.Nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate new token pair for the same session
+	tokens, err := s.jwtManager.GenerateOrgTokenPair(session.ID.String(), user.ID.String(), user.Email, string(user.UserType),
+		orgID.String(), string(orgRole))
+	if err != nil {
+		return nil, err
+	}
+
+	info := clientInfoFrom(ctx)
+	rotated := *session
+	rotated.OrgID = orgID
+	rotated.RefreshTokenID = tokens.RefreshTokenID
+	rotated.AccessTokenID = tokens.AccessTokenID
+	rotated.AccessExpiresAt = tokens.AccessExpiresAt
+	rotated.ExpiresAt = tokens.RefreshExpiresAt
+	rotated.LastUsedAt = time.Now()
+	if info.UserAgent != "" {
+		rotated.UserAgent = info.UserAgent
+	}
+	if info.IPAddress != "" {
+		rotated.IPAddress = info.IPAddress
+	}
+
+	if err := s.repo.RotateSession(ctx, &rotated, claims.ID); err != nil {
+		if err == repository.ErrSessionNotFound {
+			// Another refresh with the same token won the race
+			s.revokeReused(ctx, session)
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+
+	// The previous access token still names the old organisation
+	previous := session.OrgID
+	if previous == uuid.Nil {
+		previous = user.ID
+	}
+	if orgID != previous {
+		s.denyAccessToken(ctx, session)
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	session, _, err := s.sessionFromRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	revoked, err := s.repo.RevokeSession(ctx, session.UserID, session.ID, model.RevokedLogout)
+	if err != nil {
+		if err == repository.ErrSessionNotFound {
+			return nil
+		}
+		return err
+	}
+	s.denyAccessToken(ctx, revoked)
+
+	s.logger.Info("User logged out", "user_id", session.UserID, "session_id", session.ID)
+	return nil
+}
+
+// LogoutAll revokes every session of the user
+func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	sessions, err := s.repo.RevokeUserSessions(ctx, userID, model.RevokedLogoutAll)
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		s.denyAccessToken(ctx, &sessions[i])
+	}
+
+	s.logger.Info("User logged out everywhere", "user_id", userID, "sessions", len(sessions))
+	return nil
+}
+
+// ListSessions returns the user's active sessions, flagging the one the
+// request was made from
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]model.SessionResponse, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, sessions[i].ToSessionResponse(currentSessionID))
+	}
+	return result, nil
+}
+
+// RevokeSession revokes one of the user's sessions
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := s.repo.RevokeSession(ctx, userID, sessionID, model.RevokedByUser)
+	if err != nil {
+		return err
+	}
+	s.denyAccessToken(ctx, revoked)
+
+	s.logger.Info("Session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// sessionFromRefreshToken validates a refresh token and loads its session
+func (s *Service) sessionFromRefreshToken(ctx context.Context, refreshToken string) (*model.Session, *jwt.Claims, error) {
+	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		if err == repository.ErrSessionNotFound {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+	if session.UserID.String() != claims.UserID {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	return session, claims, nil
+}
+
+// revokeReused revokes a session whose refresh token was presented twice
+func (s *Service) revokeReused(ctx context.Context, session *model.Session) {
+	s.logger.Warn("Refresh token reuse detected, revoking session", "user_id", session.UserID, "session_id", session.ID)
+
+	revoked, err := s.repo.RevokeSession(ctx, session.UserID, session.ID, model.RevokedTokenReuse)
+	if err != nil {
+		if err != repository.ErrSessionNotFound {
+			s.logger.Error("Failed to revoke session", "session_id", session.ID, "error", err)
+		}
+		return
+	}
+	s.denyAccessToken(ctx, revoked)
+
+	s.recordSecurityEvent(ctx, session.UserID, model.SecurityRefreshTokenReused, "")
+	if s.email != nil {
+		user, err := s.repo.GetUserByID(ctx, session.UserID)
+		if err != nil {
+			s.logger.Error("Failed to load user for security alert", "user_id", session.UserID, "error", err)
+			return
+		}
+		s.securityAlert(user.Email, "Suspicious activity on your Truckify account",
+			fmt.Sprintf("A session started from %s was ended because its refresh token was used twice, which can mean it was stolen. If you don't recognise this, change your password and log out of all sessions.",
+				describeDevice(session.UserAgent, session.IPAddress)))
+	}
+}
+
+// denyAccessToken puts the session's latest access token on the denylist so
+// the gateway stops accepting it before it expires
+func (s *Service) denyAccessToken(ctx context.Context, session *model.Session) {
+	if s.denylist == nil {
+		return
+	}
+	if err := s.denylist.Revoke(ctx, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+		s.logger.Error("Failed to deny access token", "session_id", session.ID, "error", err)
+	}
+}
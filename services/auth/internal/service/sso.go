@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/oidc"
+	"truckify/services/auth/internal/repository"
+)
+
+var (
+	ErrSSONotConfigured   = errors.New("single sign-on is not set up for this domain")
+	ErrInvalidSSOLogin    = errors.New("invalid or expired single sign-on login")
+	ErrSSOIdentity        = errors.New("identity provider did not vouch for a verified email in the organisation's domain")
+	ErrSSOAccountExists   = errors.New("an account with this email exists outside the organisation")
+	ErrSSOProviderFailed  = errors.New("identity provider could not be reached or refused the login")
+	ErrSSOInvalidIssuer   = errors.New("identity provider issuer must be an https URL on a public address")
+	ErrSSODomainNotProven = errors.New("domain verification record not found")
+)
+
+// TXTResolver looks up DNS TXT records, as net.Resolver does
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// SetSSO enables single sign-on through organisations' OpenID Connect
+// providers. Providers send users back to redirectURL, the page that
+// finishes the login.
+func (s *Service) SetSSO(client *oidc.Client, redirectURL string) {
+	s.oidc = client
+	s.ssoRedirectURL = redirectURL
+}
+
+// SetTXTResolver sets where organisations' domain verification records
+// are looked up, instead of the system resolver
+func (s *Service) SetTXTResolver(resolver TXTResolver) {
+	s.txtResolver = resolver
+}
+
+// SaveIdentityProvider sets up single sign-on for an organisation the user
+// owns. The provider's discovery document is fetched first, so that a
+// mistyped issuer is caught before anyone tries to log in. Logins only go
+// through the provider once its domain is verified.
+func (s *Service) SaveIdentityProvider(ctx context.Context, userID, orgID uuid.UUID, req *model.IdentityProviderRequest) (*model.IdentityProvider, error) {
+	if s.oidc == nil {
+		return nil, ErrSSONotConfigured
+	}
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleOwner); err != nil {
+		return nil, err
+	}
+	if _, err := s.oidc.Discover(ctx, req.Issuer); err != nil {
+		if errors.Is(err, oidc.ErrInvalidIssuer) {
+			return nil, ErrSSOInvalidIssuer
+		}
+		s.logger.Warn("Identity provider discovery failed", "org_id", orgID, "issuer", req.Issuer, "error", err)
+		return nil, ErrSSOProviderFailed
+	}
+
+	existing, err := s.repo.GetIdentityProvider(ctx, orgID)
+	if err != nil && err != repository.ErrIdentityProviderNotFound {
+		return nil, err
+	}
+
+	now := time.Now()
+	p := &model.IdentityProvider{
+		OrgID:           orgID,
+		Issuer:          req.Issuer,
+		ClientID:        req.ClientID,
+		ClientSecret:    req.ClientSecret,
+		Domain:          strings.ToLower(req.Domain),
+		RoleClaim:       req.RoleClaim,
+		RoleMapping:     req.RoleMapping,
+		DefaultUserType: req.DefaultUserType,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if p.RoleMapping == nil {
+		p.RoleMapping = map[string]model.UserType{}
+	}
+	// The domain stays verified while it is unchanged; a new domain has to
+	// be verified before it routes logins
+	if existing != nil && existing.Domain == p.Domain {
+		p.VerificationToken, p.DomainVerifiedAt = existing.VerificationToken, existing.DomainVerifiedAt
+	} else if p.VerificationToken, err = generateToken(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveIdentityProvider(ctx, p); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Identity provider saved", "user_id", userID, "org_id", orgID, "issuer", p.Issuer)
+	return p, nil
+}
+
+// VerifyIdentityProviderDomain checks that the domain of the identity
+// provider of an organisation the user owns publishes the provider's
+// verification token in a TXT record at DomainChallengePrefix + domain.
+// Until it does, the provider routes no logins and provisions no users.
+func (s *Service) VerifyIdentityProviderDomain(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleOwner); err != nil {
+		return nil, err
+	}
+	p, err := s.repo.GetIdentityProvider(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if p.DomainVerified() {
+		return p, nil
+	}
+
+	resolver := s.txtResolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	records, err := resolver.LookupTXT(ctx, model.DomainChallengePrefix+p.Domain)
+	if err != nil {
+		s.logger.Warn("Domain verification lookup failed", "org_id", orgID, "domain", p.Domain, "error", err)
+		return nil, ErrSSODomainNotProven
+	}
+	if !slices.Contains(records, p.VerificationToken) {
+		return nil, ErrSSODomainNotProven
+	}
+
+	now := time.Now()
+	if err := s.repo.VerifyIdentityProviderDomain(ctx, orgID, p.VerificationToken, now); err != nil {
+		return nil, err
+	}
+	p.DomainVerifiedAt = &now
+
+	s.logger.Info("Identity provider domain verified", "user_id", userID, "org_id", orgID, "domain", p.Domain)
+	return p, nil
+}
+
+// GetIdentityProvider returns the identity provider of an organisation
+// whose members the user manages
+func (s *Service) GetIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) (*model.IdentityProvider, error) {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	return s.repo.GetIdentityProvider(ctx, orgID)
+}
+
+// DeleteIdentityProvider turns single sign-on off for an organisation the
+// user owns. Users it provisioned keep their accounts and can reset their
+// password to log in.
+func (s *Service) DeleteIdentityProvider(ctx context.Context, userID, orgID uuid.UUID) error {
+	if _, err := s.managedOrganisation(ctx, orgID, userID, model.OrgRoleOwner); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteIdentityProvider(ctx, orgID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Identity provider deleted", "user_id", userID, "org_id", orgID)
+	return nil
+}
+
+// StartSSO starts a single sign-on login through the identity provider of
+// the email's domain and returns where to send the user. The state, nonce
+// and PKCE code verifier are kept until the login is finished.
+func (s *Service) StartSSO(ctx context.Context, req *model.StartSSORequest) (*model.StartSSOResponse, error) {
+	if s.oidc == nil {
+		return nil, ErrSSONotConfigured
+	}
+
+	idp, err := s.repo.GetIdentityProviderByDomain(ctx, emailDomain(req.Email))
+	if err == repository.ErrIdentityProviderNotFound {
+		return nil, ErrSSONotConfigured
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.oidc.Discover(ctx, idp.Issuer)
+	if err != nil {
+		s.logger.Error("Identity provider discovery failed", "org_id", idp.OrgID, "issuer", idp.Issuer, "error", err)
+		return nil, ErrSSOProviderFailed
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	login := &model.SSOLogin{
+		StateHash:    hashSecret(state),
+		OrgID:        idp.OrgID,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(model.SSOLoginTTL),
+	}
+	if err := s.repo.CreateSSOLogin(ctx, login); err != nil {
+		return nil, err
+	}
+
+	return &model.StartSSOResponse{
+		AuthorizationURL: provider.AuthCodeURL(idp.ClientID, s.ssoRedirectURL, state, nonce, oidc.CodeChallenge(verifier)),
+		ExpiresIn:        int64(model.SSOLoginTTL.Seconds()),
+	}, nil
+}
+
+// FinishSSO finishes a single sign-on login with the code the identity
+// provider sent back. Users are found by their subject at the provider;
+// the first time, an account in the organisation with the same email is
+// linked, or a new account is provisioned with the user type the role
+// claim maps to. The session acts for the organisation. Second factors are
+// left to the provider.
+func (s *Service) FinishSSO(ctx context.Context, req *model.SSOCallbackRequest) (*model.LoginResponse, error) {
+	if s.oidc == nil {
+		return nil, ErrSSONotConfigured
+	}
+
+	login, err := s.repo.TakeSSOLogin(ctx, hashSecret(req.State), time.Now())
+	if err == repository.ErrSSOLoginNotFound {
+		return nil, ErrInvalidSSOLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	idp, err := s.repo.GetIdentityProvider(ctx, login.OrgID)
+	if err == repository.ErrIdentityProviderNotFound {
+		return nil, ErrInvalidSSOLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The provider was given a domain that is not verified yet since the
+	// login started
+	if !idp.DomainVerified() {
+		return nil, ErrInvalidSSOLogin
+	}
+
+	provider, err := s.oidc.Discover(ctx, idp.Issuer)
+	if err != nil {
+		s.logger.Error("Identity provider discovery failed", "org_id", idp.OrgID, "issuer", idp.Issuer, "error", err)
+		return nil, ErrSSOProviderFailed
+	}
+	rawIDToken, err := s.oidc.Exchange(ctx, provider, idp.ClientID, idp.ClientSecret, req.Code, s.ssoRedirectURL, login.CodeVerifier)
+	if err != nil {
+		s.logger.Warn("Identity provider refused code", "org_id", idp.OrgID, "error", err)
+		return nil, ErrSSOProviderFailed
+	}
+	token, err := s.oidc.Verify(provider, idp.ClientID, rawIDToken, login.Nonce)
+	if err != nil {
+		s.logger.Warn("Invalid ID token", "org_id", idp.OrgID, "error", err)
+		return nil, ErrSSOProviderFailed
+	}
+
+	user, err := s.ssoUser(ctx, idp, token)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+
+	if err := s.repo.UpdateLastLogin(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to update last login", "user_id", user.ID, "error", err)
+	}
+	s.logger.Info("User logged in", "user_id", user.ID, "email", user.Email, "org_id", idp.OrgID)
+	s.loginSucceeded(ctx, user, "sso")
+
+	orgID, role, err := s.sessionOrg(ctx, user, idp.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.startOrgSession(ctx, user, orgID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		User:         user.ToUserResponse(),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// ssoUser returns the user an ID token stands for, linking or provisioning
+// them on their first single sign-on login
+func (s *Service) ssoUser(ctx context.Context, idp *model.IdentityProvider, token *oidc.IDToken) (*model.User, error) {
+	userID, err := s.repo.GetSSOIdentity(ctx, idp.OrgID, token.Subject)
+	if err == nil {
+		return s.repo.GetUserByID(ctx, userID)
+	}
+	if err != repository.ErrSSOIdentityNotFound {
+		return nil, err
+	}
+
+	// Only verified emails in the provider's domain, which the organisation
+	// has proved it controls, are trusted to name an account
+	email := strings.ToLower(strings.TrimSpace(token.Email))
+	if !token.EmailVerified || !idp.DomainVerified() || emailDomain(email) != idp.Domain {
+		return nil, ErrSSOIdentity
+	}
+
+	now := time.Now()
+	identity := &model.SSOIdentity{OrgID: idp.OrgID, Subject: token.Subject, CreatedAt: now}
+
+	// An existing account is only linked if it already belongs to the
+	// organisation, so a provider cannot take over accounts it does not
+	// manage
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if _, err := s.repo.GetMembership(ctx, idp.OrgID, user.ID); err == repository.ErrMembershipNotFound {
+			return nil, ErrSSOAccountExists
+		} else if err != nil {
+			return nil, err
+		}
+		identity.UserID = user.ID
+		if err := s.repo.LinkSSOIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+		s.recordSecurityEvent(ctx, user.ID, model.SecuritySSOLinked, idp.Issuer)
+		return user, nil
+	}
+	if err != repository.ErrUserNotFound {
+		return nil, err
+	}
+
+	user = &model.User{
+		ID:            uuid.New(),
+		Email:         email,
+		UserType:      idp.UserTypeFor(token.ClaimValues(idp.RoleClaim)),
+		Status:        model.UserStatusActive,
+		EmailVerified: true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	identity.UserID = user.ID
+	if err := s.repo.ProvisionSSOUser(ctx, user, identity); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User provisioned by single sign-on", "user_id", user.ID, "org_id", idp.OrgID, "user_type", user.UserType)
+	return user, nil
+}
+
+// emailDomain returns the lower-cased domain of an email address
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
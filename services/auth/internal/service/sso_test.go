@@ -0,0 +1,248 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"truckify/services/auth/internal/model"
+	"truckify/services/auth/internal/oidc"
+	"truckify/services/auth/internal/oidc/oidctest"
+	"truckify/services/auth/internal/repository"
+	"truckify/services/auth/internal/service"
+	"truckify/shared/pkg/jwt"
+)
+
+const ssoRedirectURL = "https://app.truckify.example/sso/callback"
+
+// setupSSO starts a mock identity provider configured for a new
+// organisation with the acme.example domain
+func setupSSO(t *testing.T) (*service.Service, *MockRepository, *oidctest.Provider, *model.IdentityProvider) {
+	svc, mockRepo := setupTestService()
+	idp := oidctest.NewProvider("truckify", "client-secret")
+	t.Cleanup(idp.Close)
+	svc.SetSSO(oidc.NewClient(true), ssoRedirectURL)
+
+	verifiedAt := time.Now()
+	cfg := &model.IdentityProvider{
+		OrgID:             uuid.New(),
+		Issuer:            idp.URL,
+		ClientID:          idp.ClientID,
+		ClientSecret:      idp.ClientSecret,
+		Domain:            "acme.example",
+		VerificationToken: "challenge-token",
+		DomainVerifiedAt:  &verifiedAt,
+		RoleClaim:         "groups",
+		RoleMapping:       map[string]model.UserType{"truckify-dispatch": model.UserTypeDispatcher},
+		DefaultUserType:   model.UserTypeShipper,
+	}
+	mockRepo.On("GetIdentityProviderByDomain", mock.Anything, "acme.example").Return(cfg, nil)
+	mockRepo.On("GetIdentityProvider", mock.Anything, cfg.OrgID).Return(cfg, nil)
+	return svc, mockRepo, idp, cfg
+}
+
+// loginAtProvider starts a single sign-on login for email, logs in at the
+// provider with claims and returns what the provider redirects back with.
+// The pending login can be taken once.
+func loginAtProvider(t *testing.T, svc *service.Service, mockRepo *MockRepository, idp *oidctest.Provider, email string, claims map[string]interface{}) *model.SSOCallbackRequest {
+	ctx := context.Background()
+	var pending *model.SSOLogin
+	mockRepo.On("CreateSSOLogin", ctx, mock.AnythingOfType("*model.SSOLogin")).Run(func(args mock.Arguments) {
+		pending = args.Get(1).(*model.SSOLogin)
+	}).Return(nil).Once()
+
+	started, err := svc.StartSSO(ctx, &model.StartSSORequest{Email: email})
+	require.NoError(t, err)
+	code, state, err := idp.Login(started.AuthorizationURL, claims)
+	require.NoError(t, err)
+
+	mockRepo.On("TakeSSOLogin", ctx, pending.StateHash, mock.Anything).Return(pending, nil).Once()
+	return &model.SSOCallbackRequest{State: state, Code: code}
+}
+
+func TestSSO_ProvisionsNewUser(t *testing.T) {
+	svc, mockRepo, idp, cfg := setupSSO(t)
+	ctx := context.Background()
+
+	callback := loginAtProvider(t, svc, mockRepo, idp, "Ops@Acme.example", map[string]interface{}{
+		"sub": "00u1", "email": "ops@acme.example", "email_verified": true, "groups": []string{"staff", "truckify-dispatch"},
+	})
+
+	var provisioned *model.User
+	mockRepo.On("GetSSOIdentity", ctx, cfg.OrgID, "00u1").Return(uuid.Nil, repository.ErrSSOIdentityNotFound)
+	mockRepo.On("GetUserByEmail", ctx, "ops@acme.example").Return(nil, repository.ErrUserNotFound)
+	mockRepo.On("ProvisionSSOUser", ctx, mock.AnythingOfType("*model.User"), mock.MatchedBy(func(i *model.SSOIdentity) bool {
+		return i.OrgID == cfg.OrgID && i.Subject == "00u1"
+	})).Run(func(args mock.Arguments) {
+		provisioned = args.Get(1).(*model.User)
+	}).Return(nil)
+	mockRepo.On("UpdateLastLogin", ctx, mock.Anything).Return(nil)
+	mockRepo.On("CreateSecurityEvent", ctx, mock.Anything).Return(nil)
+	mockRepo.On("GetMembership", ctx, cfg.OrgID, mock.Anything).Return(&model.Membership{OrgID: cfg.OrgID, Role: model.OrgRoleMember}, nil)
+	mockRepo.On("CreateSession", ctx, mock.MatchedBy(func(s *model.Session) bool { return s.OrgID == cfg.OrgID })).Return(nil)
+
+	resp, err := svc.FinishSSO(ctx, callback)
+	require.NoError(t, err)
+	require.NotNil(t, provisioned)
+	assert.Equal(t, model.UserTypeDispatcher, provisioned.UserType)
+	assert.True(t, provisioned.EmailVerified)
+
+	// The session acts for the organisation that owns the provider
+	claims, err := jwt.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour).ValidateAccessToken(resp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.OrgID.String(), claims.OrgID)
+	assert.Equal(t, string(model.OrgRoleMember), claims.OrgRole)
+
+	// The login cannot be finished twice
+	mockRepo.On("TakeSSOLogin", ctx, mock.Anything, mock.Anything).Return(nil, repository.ErrSSOLoginNotFound)
+	_, err = svc.FinishSSO(ctx, callback)
+	assert.Equal(t, service.ErrInvalidSSOLogin, err)
+}
+
+func TestSSO_RefusesAccountsOutsideOrganisation(t *testing.T) {
+	svc, mockRepo, idp, cfg := setupSSO(t)
+	ctx := context.Background()
+
+	existing := &model.User{ID: uuid.New(), Email: "ceo@acme.example", UserType: model.UserTypeShipper, Status: model.UserStatusActive}
+	mockRepo.On("GetSSOIdentity", ctx, cfg.OrgID, mock.Anything).Return(uuid.Nil, repository.ErrSSOIdentityNotFound)
+	mockRepo.On("GetUserByEmail", ctx, existing.Email).Return(existing, nil)
+	mockRepo.On("GetMembership", ctx, cfg.OrgID, existing.ID).Return(nil, repository.ErrMembershipNotFound)
+
+	callback := loginAtProvider(t, svc, mockRepo, idp, existing.Email, map[string]interface{}{
+		"sub": "00u2", "email": existing.Email, "email_verified": true,
+	})
+	_, err := svc.FinishSSO(ctx, callback)
+	assert.Equal(t, service.ErrSSOAccountExists, err)
+
+	// Emails the provider has not verified, or outside its domain, name no
+	// account at all
+	callback = loginAtProvider(t, svc, mockRepo, idp, existing.Email, map[string]interface{}{
+		"sub": "00u3", "email": existing.Email, "email_verified": false,
+	})
+	_, err = svc.FinishSSO(ctx, callback)
+	assert.Equal(t, service.ErrSSOIdentity, err)
+
+	callback = loginAtProvider(t, svc, mockRepo, idp, existing.Email, map[string]interface{}{
+		"sub": "00u4", "email": "someone@elsewhere.example", "email_verified": true,
+	})
+	_, err = svc.FinishSSO(ctx, callback)
+	assert.Equal(t, service.ErrSSOIdentity, err)
+
+	mockRepo.AssertNotCalled(t, "LinkSSOIdentity", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "ProvisionSSOUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSaveIdentityProvider_RefusesPrivateIssuer(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	svc.SetSSO(oidc.NewClient(false), ssoRedirectURL)
+	ctx := context.Background()
+	ownerID, orgID := uuid.New(), uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, ownerID).Return(&model.Membership{OrgID: orgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID, Name: "Acme Freight"}, nil)
+
+	// Instance metadata, and the mock provider on a loopback address
+	idp := oidctest.NewProvider("truckify", "client-secret")
+	defer idp.Close()
+	for _, issuer := range []string{"https://169.254.169.254/latest/meta-data", idp.URL} {
+		_, err := svc.SaveIdentityProvider(ctx, ownerID, orgID, &model.IdentityProviderRequest{
+			Issuer: issuer, ClientID: "truckify", ClientSecret: "client-secret", Domain: "acme.example", DefaultUserType: model.UserTypeShipper,
+		})
+		assert.Equal(t, service.ErrSSOInvalidIssuer, err, issuer)
+	}
+	mockRepo.AssertNotCalled(t, "SaveIdentityProvider", mock.Anything, mock.Anything)
+}
+
+func TestSSO_RefusesUnverifiedDomain(t *testing.T) {
+	svc, mockRepo, idp, cfg := setupSSO(t)
+	ctx := context.Background()
+
+	callback := loginAtProvider(t, svc, mockRepo, idp, "driver@acme.example", map[string]interface{}{
+		"sub": "00u1", "email": "driver@acme.example", "email_verified": true,
+	})
+	// The domain changed, and is not verified yet, while the login was at
+	// the provider
+	cfg.DomainVerifiedAt = nil
+	_, err := svc.FinishSSO(ctx, callback)
+	assert.Equal(t, service.ErrInvalidSSOLogin, err)
+
+	mockRepo.AssertNotCalled(t, "GetSSOIdentity", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "ProvisionSSOUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSaveIdentityProvider_DomainVerification(t *testing.T) {
+	svc, mockRepo, idp, cfg := setupSSO(t)
+	ctx := context.Background()
+	ownerID := uuid.New()
+	mockRepo.On("GetMembership", ctx, cfg.OrgID, ownerID).Return(&model.Membership{OrgID: cfg.OrgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, cfg.OrgID).Return(&model.Organisation{ID: cfg.OrgID, Name: "Acme Freight"}, nil)
+	mockRepo.On("SaveIdentityProvider", ctx, mock.AnythingOfType("*model.IdentityProvider")).Return(nil)
+
+	save := func(domain string) *model.IdentityProvider {
+		p, err := svc.SaveIdentityProvider(ctx, ownerID, cfg.OrgID, &model.IdentityProviderRequest{
+			Issuer: idp.URL, ClientID: "truckify", ClientSecret: "rotated-secret", Domain: domain, DefaultUserType: model.UserTypeShipper,
+		})
+		require.NoError(t, err)
+		return p
+	}
+
+	// Rotating the secret keeps the domain verified
+	p := save("ACME.example")
+	assert.Equal(t, cfg.VerificationToken, p.VerificationToken)
+	assert.True(t, p.DomainVerified())
+
+	// A new domain has to be verified again, with a new token
+	p = save("acme-freight.example")
+	assert.NotEqual(t, cfg.VerificationToken, p.VerificationToken)
+	assert.Len(t, p.VerificationToken, 64)
+	assert.False(t, p.DomainVerified())
+}
+
+// txtRecords answers TXT lookups from a map
+type txtRecords map[string][]string
+
+func (r txtRecords) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if records, ok := r[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestVerifyIdentityProviderDomain(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	ctx := context.Background()
+	ownerID, orgID := uuid.New(), uuid.New()
+	mockRepo.On("GetMembership", ctx, orgID, ownerID).Return(&model.Membership{OrgID: orgID, UserID: ownerID, Role: model.OrgRoleOwner}, nil)
+	mockRepo.On("GetOrganisation", ctx, orgID).Return(&model.Organisation{ID: orgID, Name: "Acme Freight"}, nil)
+	mockRepo.On("GetIdentityProvider", ctx, orgID).Return(&model.IdentityProvider{
+		OrgID: orgID, Domain: "acme.example", VerificationToken: "challenge-token",
+	}, nil)
+
+	// No record, or one with another token
+	for _, records := range []txtRecords{{}, {"_truckify-challenge.acme.example": {"v=spf1 -all", "other-token"}}} {
+		svc.SetTXTResolver(records)
+		_, err := svc.VerifyIdentityProviderDomain(ctx, ownerID, orgID)
+		assert.Equal(t, service.ErrSSODomainNotProven, err)
+	}
+	mockRepo.AssertNotCalled(t, "VerifyIdentityProviderDomain", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	svc.SetTXTResolver(txtRecords{"_truckify-challenge.acme.example": {"v=spf1 -all", "challenge-token"}})
+	mockRepo.On("VerifyIdentityProviderDomain", ctx, orgID, "challenge-token", mock.AnythingOfType("time.Time")).Return(nil)
+	p, err := svc.VerifyIdentityProviderDomain(ctx, ownerID, orgID)
+	require.NoError(t, err)
+	assert.True(t, p.DomainVerified())
+}
+
+func TestStartSSO_UnknownDomain(t *testing.T) {
+	svc, mockRepo := setupTestService()
+	svc.SetSSO(oidc.NewClient(true), ssoRedirectURL)
+	ctx := context.Background()
+	mockRepo.On("GetIdentityProviderByDomain", ctx, "example.com").Return(nil, repository.ErrIdentityProviderNotFound)
+
+	_, err := svc.StartSSO(ctx, &model.StartSSORequest{Email: "someone@example.com"})
+	assert.Equal(t, service.ErrSSONotConfigured, err)
+}
@@ -0,0 +1,46 @@
+// Package sms sends the auth service's text messages. Until an SMS gateway
+// is wired in, messages are printed to the console or appended to an
+// outbox file, where development tools and tests can read the codes.
+package sms
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type Service struct {
+	// outbox is the file messages are appended to; empty prints them
+	outbox string
+	mu     sync.Mutex
+}
+
+func NewService() *Service {
+	return &Service{outbox: os.Getenv("SMS_OUTBOX_FILE")}
+}
+
+func (s *Service) SendLoginCode(to, code string) error {
+	return s.send(to, fmt.Sprintf("%s is your Truckify login code. It expires in 10 minutes. Never share it.", code))
+}
+
+func (s *Service) SendVerificationCode(to, code string) error {
+	return s.send(to, fmt.Sprintf("%s is your code to add this number to your Truckify account. It expires in 10 minutes.", code))
+}
+
+func (s *Service) send(to, message string) error {
+	if s.outbox == "" {
+		fmt.Printf("[SMS] To %s: %s\n", to, message)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.outbox, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open SMS outbox: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, message)
+	return err
+}
@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Code at %d = %s, want %s", unix, got, want)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err != ErrInvalidSecret {
+		t.Errorf("err = %v, want ErrInvalidSecret", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current := Step(now)
+
+	for offset, ok := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, _ := Code(secret, current+offset)
+		step, valid := Validate(secret, code, now)
+		if valid != ok {
+			t.Errorf("offset %d: valid = %v, want %v", offset, valid, ok)
+		}
+		if valid && step != current+offset {
+			t.Errorf("offset %d: step = %d, want %d", offset, step, current+offset)
+		}
+	}
+
+	code, _ := Code(secret, current)
+	if _, ok := Validate(secret, code[:3]+" "+code[3:], now); !ok {
+		t.Error("a code with a space in the middle was refused")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("a short code was accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	raw := ProvisioningURI(rfcSecret, "Truckify", "fleet@example.com")
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Truckify:fleet@example.com" {
+		t.Errorf("uri = %s", raw)
+	}
+	q := u.Query()
+	if q.Get("secret") != rfcSecret || q.Get("issuer") != "Truckify" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("query = %v", q)
+	}
+}
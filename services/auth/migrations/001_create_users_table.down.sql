@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP TYPE IF EXISTS user_status;
+DROP TYPE IF EXISTS user_type;
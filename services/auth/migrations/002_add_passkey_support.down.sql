@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webauthn_challenges;
+DROP TABLE IF EXISTS passkey_credentials;
@@ -0,0 +1 @@
+-- Postgres cannot remove enum values; the admin and support user types stay
@@ -0,0 +1,3 @@
+-- Staff roles used by role-based access control
+ALTER TYPE user_type ADD VALUE IF NOT EXISTS 'admin';
+ALTER TYPE user_type ADD VALUE IF NOT EXISTS 'support';
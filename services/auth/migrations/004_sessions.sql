@@ -0,0 +1,18 @@
+-- Refresh token families. Each login starts a session; every refresh rotates
+-- the refresh token ID stored here, so presenting an older one is a reuse.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_id VARCHAR(64) NOT NULL,
+    access_token_id VARCHAR(64) NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_reason VARCHAR(50)
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_key_events;
+DROP TABLE IF EXISTS api_keys;
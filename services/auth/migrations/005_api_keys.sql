@@ -0,0 +1,31 @@
+-- API keys let integrations call the API for the user who created them,
+-- directly or through the OAuth client credentials grant. Only a hash of
+-- each key is kept; the prefix identifies it in listings.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(64),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+-- Audit trail of what was done with each key
+CREATE TABLE IF NOT EXISTS api_key_events (
+    id UUID PRIMARY KEY,
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(64),
+    detail VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_key_events_key ON api_key_events(api_key_id, created_at DESC);
@@ -0,0 +1,21 @@
+-- Failed password logins, counted per account (normalized email) and per
+-- client IP. Counts start over once a key has been quiet for a while.
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP
+);
+
+-- Security-relevant events of an account, shown to its user
+CREATE TABLE IF NOT EXISTS security_events (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(64),
+    user_agent VARCHAR(512),
+    detail VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_security_events_user ON security_events(user_id, created_at DESC);
@@ -0,0 +1,47 @@
+-- Organisations own jobs, fleets and payments for their members. Every user
+-- has a personal organisation sharing the user's ID, so resources that were
+-- owned by a user ID are owned by that user's personal organisation.
+CREATE TABLE IF NOT EXISTS organisations (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    personal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX idx_org_members_user ON org_members(user_id);
+
+-- Invitations to join an organisation. Only a hash of each token is kept.
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP
+);
+
+CREATE INDEX idx_org_invitations_org ON org_invitations(org_id, created_at DESC);
+
+-- The organisation a session acts for; NULL is the user's personal one
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organisations(id) ON DELETE SET NULL;
+
+-- Personal organisations for existing users
+INSERT INTO organisations (id, name, personal, created_by, created_at)
+SELECT id, email, TRUE, id, created_at FROM users
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO org_members (org_id, user_id, role, created_at)
+SELECT id, id, 'owner', created_at FROM users
+ON CONFLICT (org_id, user_id) DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE security_events DROP COLUMN IF EXISTS actor_id;
+DROP TABLE IF EXISTS delegations;
@@ -0,0 +1,16 @@
+-- Rights shippers and fleet operators delegate to dispatchers
+CREATE TABLE IF NOT EXISTS delegations (
+    id UUID PRIMARY KEY,
+    grantor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dispatcher_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rights TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_delegations_grantor ON delegations(grantor_id, created_at DESC);
+CREATE INDEX idx_delegations_dispatcher ON delegations(dispatcher_id, created_at DESC);
+
+-- The dispatcher who acted on the user's behalf
+ALTER TABLE security_events ADD COLUMN IF NOT EXISTS actor_id UUID;
@@ -0,0 +1,37 @@
+-- Organisations' OpenID Connect identity providers. The client secret is
+-- presented to the provider on every login, so it is kept as is.
+CREATE TABLE IF NOT EXISTS org_identity_providers (
+    org_id UUID PRIMARY KEY REFERENCES organisations(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret VARCHAR(255) NOT NULL,
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    role_claim VARCHAR(100),
+    role_mapping JSONB NOT NULL DEFAULT '{}',
+    default_user_type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Logins waiting on the identity provider, found by the hash of their state
+CREATE TABLE IF NOT EXISTS sso_logins (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_sso_logins_expires ON sso_logins(expires_at);
+
+-- Users' subjects at their organisation's identity provider
+CREATE TABLE IF NOT EXISTS sso_identities (
+    org_id UUID NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, subject)
+);
+
+CREATE INDEX idx_sso_identities_user ON sso_identities(user_id);
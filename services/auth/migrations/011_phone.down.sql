@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS phone_codes;
+DROP INDEX IF EXISTS users_verified_phone_key;
+-- Accounts registered by phone cannot exist without it
+DELETE FROM users WHERE email IS NULL;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_or_phone;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,26 @@
+-- Phone numbers, in E.164. Accounts registered by phone have no email.
+-- A number only identifies an account once verified, so an unverified
+-- number can sit on several accounts until one of them proves it.
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(20);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD CONSTRAINT users_email_or_phone CHECK (email IS NOT NULL OR phone IS NOT NULL);
+
+CREATE UNIQUE INDEX users_verified_phone_key ON users(phone) WHERE phone_verified;
+
+-- One-time codes sent by SMS, kept as bcrypt hashes. Only the newest code
+-- for a number and purpose counts; older rows are kept for an hour to cap
+-- how many are sent. Codes linking a number to an account name the user.
+CREATE TABLE IF NOT EXISTS phone_codes (
+    id UUID PRIMARY KEY,
+    phone VARCHAR(20) NOT NULL,
+    purpose VARCHAR(10) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_phone_codes_phone ON phone_codes(phone, created_at);
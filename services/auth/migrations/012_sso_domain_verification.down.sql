@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS org_identity_providers_verified_domain_key;
+-- Only one organisation's provider can keep a domain: the verified one, or
+-- else the first
+DELETE FROM org_identity_providers p WHERE EXISTS (
+    SELECT 1 FROM org_identity_providers o
+    WHERE o.domain = p.domain AND o.org_id <> p.org_id
+        AND (o.domain_verified_at IS NOT NULL OR (p.domain_verified_at IS NULL AND o.org_id < p.org_id))
+);
+ALTER TABLE org_identity_providers ADD CONSTRAINT org_identity_providers_domain_key UNIQUE (domain);
+ALTER TABLE org_identity_providers DROP COLUMN IF EXISTS domain_verified_at;
+ALTER TABLE org_identity_providers DROP COLUMN IF EXISTS verification_token;
@@ -0,0 +1,12 @@
+-- Organisations prove they control their single sign-on domain by
+-- publishing the provider's verification token in a DNS TXT record. Logins
+-- are only routed to verified domains, and a domain is unique among those,
+-- so claiming a domain first no longer locks its real owner out.
+ALTER TABLE org_identity_providers ADD COLUMN IF NOT EXISTS verification_token VARCHAR(64);
+ALTER TABLE org_identity_providers ADD COLUMN IF NOT EXISTS domain_verified_at TIMESTAMP;
+UPDATE org_identity_providers SET verification_token = md5(random()::text || org_id::text) WHERE verification_token IS NULL;
+ALTER TABLE org_identity_providers ALTER COLUMN verification_token SET NOT NULL;
+
+ALTER TABLE org_identity_providers DROP CONSTRAINT IF EXISTS org_identity_providers_domain_key;
+CREATE UNIQUE INDEX IF NOT EXISTS org_identity_providers_verified_domain_key
+    ON org_identity_providers(domain) WHERE domain_verified_at IS NOT NULL;
@@ -14,16 +14,26 @@ import (
 	"truckify/services/backhaul/internal/handler"
 	"truckify/services/backhaul/internal/repository"
 	"truckify/services/backhaul/internal/service"
+	"truckify/services/backhaul/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("backhaul-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Backhaul Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "backhaul-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -37,6 +47,17 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	repo := repository.New(sqlxDB)
 	svc := service.New(repo)
@@ -44,6 +65,9 @@ func main() {
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("backhaul-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
@@ -52,7 +76,9 @@ func main() {
 		w.Write([]byte(`{"status":"healthy"}`))
 	}).Methods(http.MethodGet)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "backhaul-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8010")
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/backhaul/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes and the
+// server's health check
+var Routes = openapi.Routes{
+	"POST /backhaul/find":       {Summary: "Find return loads near a route", Request: model.FindBackhaulRequest{}, Response: []model.BackhaulMatch{}},
+	"POST /backhaul":            {Summary: "Offer a return load", Request: model.BackhaulOpportunity{}, Response: model.BackhaulOpportunity{}, Status: http.StatusCreated},
+	"POST /backhaul/{id}/claim": {Summary: "Claim a return load", Response: map[string]string{}},
+	"GET /health":               {Summary: "Health check", Response: map[string]string{}, Raw: true},
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS backhaul_opportunities;
@@ -14,16 +14,28 @@ import (
 	"truckify/services/bidding/internal/handler"
 	"truckify/services/bidding/internal/repository"
 	"truckify/services/bidding/internal/service"
+	"truckify/services/bidding/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/idempotency"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("bidding-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Bidding Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "bidding-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -37,22 +49,58 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
+	// Relay outbox events to NATS
+	if natsURL := config.GetEnv("NATS_URL", ""); natsURL != "" {
+		broker, err := events.NewNATSBroker(natsURL, "bidding-service", log)
+		if err != nil {
+			log.Fatal("Failed to connect to NATS", "error", err)
+		}
+		defer broker.Close()
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go events.NewRelay(db, broker, log).Run(relayCtx)
+		log.Info("Connected to NATS")
+	}
+
+	// Replay responses to retried requests that carry an Idempotency-Key
+	idempotencyStore := idempotency.NewPostgresStore(db)
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go idempotencyStore.Run(purgeCtx, log)
+
 	sqlxDB := sqlx.NewDb(db, "postgres")
 	repo := repository.New(sqlxDB)
-	svc := service.New(repo)
+	svc := service.New(repo, config.GetEnv("JOB_SERVICE_URL", "http://localhost:8006"))
 	h := handler.New(svc)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("bidding-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
+	router.Use(idempotency.Middleware(idempotencyStore, log))
 
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status":"healthy"}`))
 	}).Methods(http.MethodGet)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "bidding-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
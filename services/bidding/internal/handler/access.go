@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/bidding/internal/model"
+	"truckify/services/bidding/internal/service"
+	"truckify/shared/pkg/authz"
+)
+
+// bidDriver resolves the driver who placed the bid as its owner
+func (h *Handler) bidDriver(r *http.Request) ([]uuid.UUID, error) {
+	bid, err := h.targetBid(r)
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{bid.DriverID}, nil
+}
+
+// bidJobShipper resolves the shipper of the job the bid is for and their
+// organisation, who decide which bid to accept
+func (h *Handler) bidJobShipper(r *http.Request) ([]uuid.UUID, error) {
+	bid, err := h.targetBid(r)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := h.service.GetJobOwners(r.Context(), bid.JobID)
+	if err == service.ErrJobNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	return owners, err
+}
+
+func (h *Handler) targetBid(r *http.Request) (*model.Bid, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	bid, err := h.service.GetBid(r.Context(), id)
+	if err == service.ErrBidNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	return bid, err
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/bidding/internal/model"
 	"truckify/services/bidding/internal/service"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -15,32 +17,34 @@ import (
 type ServiceInterface interface {
 	CreateBid(ctx context.Context, driverID uuid.UUID, req model.CreateBidRequest) (*model.Bid, error)
 	GetBid(ctx context.Context, id uuid.UUID) (*model.Bid, error)
-	GetBidsForJob(ctx context.Context, jobID uuid.UUID) ([]model.Bid, error)
-	GetDriverBids(ctx context.Context, driverID uuid.UUID) ([]model.Bid, error)
+	GetBidsForJob(ctx context.Context, jobID uuid.UUID, page pagination.Page) ([]model.Bid, string, error)
+	GetDriverBids(ctx context.Context, driverID uuid.UUID, page pagination.Page) ([]model.Bid, string, error)
 	UpdateBid(ctx context.Context, bidID, driverID uuid.UUID, req model.UpdateBidRequest) (*model.Bid, error)
 	WithdrawBid(ctx context.Context, bidID, driverID uuid.UUID) error
 	AcceptBid(ctx context.Context, bidID uuid.UUID) (*model.Bid, error)
 	RejectBid(ctx context.Context, bidID uuid.UUID) error
+	GetJobOwners(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type Handler struct {
 	service   ServiceInterface
 	validator *validator.Validator
+	authz     *authz.Authorizer
 }
 
 func New(svc ServiceInterface) *Handler {
-	return &Handler{service: svc, validator: validator.New()}
+	return &Handler{service: svc, validator: validator.New(), authz: authz.New(authz.DefaultPolicy())}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/bids", h.CreateBid).Methods(http.MethodPost)
-	router.HandleFunc("/bids", h.GetDriverBids).Methods(http.MethodGet)
-	router.HandleFunc("/bids/{id}", h.GetBid).Methods(http.MethodGet)
-	router.HandleFunc("/bids/{id}", h.UpdateBid).Methods(http.MethodPut)
-	router.HandleFunc("/bids/{id}", h.WithdrawBid).Methods(http.MethodDelete)
-	router.HandleFunc("/bids/{id}/accept", h.AcceptBid).Methods(http.MethodPost)
-	router.HandleFunc("/bids/{id}/reject", h.RejectBid).Methods(http.MethodPost)
-	router.HandleFunc("/jobs/{job_id}/bids", h.GetJobBids).Methods(http.MethodGet)
+	router.HandleFunc("/bids", h.authz.RequireUnscoped(authz.BidCreate, h.CreateBid)).Methods(http.MethodPost)
+	router.HandleFunc("/bids", h.authz.RequireUnscoped(authz.BidRead, h.GetDriverBids)).Methods(http.MethodGet)
+	router.HandleFunc("/bids/{id}", h.authz.RequireUnscoped(authz.BidRead, h.GetBid)).Methods(http.MethodGet)
+	router.HandleFunc("/bids/{id}", h.authz.RequireOwner(authz.BidUpdate, h.bidDriver, h.UpdateBid)).Methods(http.MethodPut)
+	router.HandleFunc("/bids/{id}", h.authz.RequireOwner(authz.BidUpdate, h.bidDriver, h.WithdrawBid)).Methods(http.MethodDelete)
+	router.HandleFunc("/bids/{id}/accept", h.authz.RequireOwner(authz.BidAccept, h.bidJobShipper, h.AcceptBid)).Methods(http.MethodPost)
+	router.HandleFunc("/bids/{id}/reject", h.authz.RequireOwner(authz.BidAccept, h.bidJobShipper, h.RejectBid)).Methods(http.MethodPost)
+	router.HandleFunc("/jobs/{job_id}/bids", h.authz.RequireUnscoped(authz.BidRead, h.GetJobBids)).Methods(http.MethodGet)
 }
 
 func (h *Handler) CreateBid(w http.ResponseWriter, r *http.Request) {
@@ -97,12 +101,18 @@ func (h *Handler) GetDriverBids(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bids, err := h.service.GetDriverBids(r.Context(), driverID)
+	page, err := pagination.Parse(r.URL.Query(), model.DriverBidListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	bids, next, err := h.service.GetDriverBids(r.Context(), driverID, page)
 	if err != nil {
 		response.InternalServerError(w, "Failed to get bids", "", reqID)
 		return
 	}
-	response.Success(w, bids, reqID)
+	response.List(w, bids, next, reqID)
 }
 
 func (h *Handler) GetJobBids(w http.ResponseWriter, r *http.Request) {
@@ -113,12 +123,18 @@ func (h *Handler) GetJobBids(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bids, err := h.service.GetBidsForJob(r.Context(), jobID)
+	page, err := pagination.Parse(r.URL.Query(), model.JobBidListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	bids, next, err := h.service.GetBidsForJob(r.Context(), jobID, page)
 	if err != nil {
 		response.InternalServerError(w, "Failed to get bids", "", reqID)
 		return
 	}
-	response.Success(w, bids, reqID)
+	response.List(w, bids, next, reqID)
 }
 
 func (h *Handler) UpdateBid(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/bidding/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes and the
+// server's health check
+var Routes = openapi.Routes{
+	"POST /bids":              {Summary: "Bid on a job", Request: model.CreateBidRequest{}, Response: model.Bid{}, Status: http.StatusCreated},
+	"GET /bids":               {Summary: "List the caller's bids", Response: []model.Bid{}, List: &model.DriverBidListSpec},
+	"GET /bids/{id}":          {Summary: "Get a bid", Response: model.Bid{}},
+	"PUT /bids/{id}":          {Summary: "Change a pending bid", Request: model.UpdateBidRequest{}, Response: model.Bid{}},
+	"DELETE /bids/{id}":       {Summary: "Withdraw a bid", Response: map[string]string{}},
+	"POST /bids/{id}/accept":  {Summary: "Accept a bid on the caller's job", Response: model.Bid{}},
+	"POST /bids/{id}/reject":  {Summary: "Reject a bid on the caller's job", Response: map[string]string{}},
+	"GET /jobs/{job_id}/bids": {Summary: "List a job's bids", Response: []model.Bid{}, List: &model.JobBidListSpec},
+	"GET /health":             {Summary: "Health check", Response: map[string]string{}, Raw: true},
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
 type BidStatus string
@@ -27,6 +28,31 @@ type Bid struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+var bidFields = map[string]pagination.Field{
+	"status":     {Column: "status"},
+	"amount":     {Column: "amount", Kind: pagination.Number, Sortable: true},
+	"expires_at": {Column: "expires_at", Kind: pagination.Time, Sortable: true},
+	"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+}
+
+// DriverBidListSpec is what a driver can filter and sort their bids by
+var DriverBidListSpec = pagination.Spec{Fields: bidFields, DefaultSort: "-created_at"}
+
+// JobBidListSpec is what a job's bids can be filtered and sorted by; the
+// lowest bids come first
+var JobBidListSpec = pagination.Spec{Fields: bidFields, DefaultSort: "amount"}
+
+// SortKey returns the value of a bid sort field and the bid's ID
+func (b Bid) SortKey(field string) (interface{}, uuid.UUID) {
+	switch field {
+	case "amount":
+		return b.Amount, b.ID
+	case "expires_at":
+		return b.ExpiresAt, b.ID
+	}
+	return b.CreatedAt, b.ID
+}
+
 type CreateBidRequest struct {
 	JobID  uuid.UUID `json:"job_id" validate:"required"`
 	Amount float64   `json:"amount" validate:"required,gt=0"`
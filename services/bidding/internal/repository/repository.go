@@ -7,8 +7,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"truckify/services/bidding/internal/model"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/pagination"
 )
 
+const eventSource = "bidding-service"
+
 type Repository struct {
 	db *sqlx.DB
 }
@@ -18,10 +22,28 @@ func New(db *sqlx.DB) *Repository {
 }
 
 func (r *Repository) Create(ctx context.Context, bid *model.Bid) error {
+	evt, err := events.New(ctx, eventSource, events.TypeBidPlaced, 1, bid.ID.String(), events.BidPlacedV1{
+		BidID: bid.ID, JobID: bid.JobID, DriverID: bid.DriverID, Amount: bid.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO bids (id, job_id, driver_id, amount, notes, status, expires_at, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := r.db.ExecContext(ctx, query, bid.ID, bid.JobID, bid.DriverID, bid.Amount, bid.Notes, bid.Status, bid.ExpiresAt, bid.CreatedAt, bid.UpdatedAt)
-	return err
+	if _, err := tx.ExecContext(ctx, query, bid.ID, bid.JobID, bid.DriverID, bid.Amount, bid.Notes, bid.Status, bid.ExpiresAt, bid.CreatedAt, bid.UpdatedAt); err != nil {
+		return err
+	}
+	if err := events.WriteOutbox(ctx, tx, evt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*model.Bid, error) {
@@ -33,16 +55,25 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*model.Bid, err
 	return &bid, err
 }
 
-func (r *Repository) GetByJobID(ctx context.Context, jobID uuid.UUID) ([]model.Bid, error) {
-	var bids []model.Bid
-	err := r.db.SelectContext(ctx, &bids, "SELECT * FROM bids WHERE job_id = $1 ORDER BY amount ASC", jobID)
-	return bids, err
+// GetByJobID returns a page of a job's bids and the cursor of the next page
+func (r *Repository) GetByJobID(ctx context.Context, jobID uuid.UUID, page pagination.Page) ([]model.Bid, string, error) {
+	return r.list(ctx, "SELECT * FROM bids WHERE job_id = $1", jobID, page)
+}
+
+// GetByDriverID returns a page of a driver's bids and the cursor of the
+// next page
+func (r *Repository) GetByDriverID(ctx context.Context, driverID uuid.UUID, page pagination.Page) ([]model.Bid, string, error) {
+	return r.list(ctx, "SELECT * FROM bids WHERE driver_id = $1", driverID, page)
 }
 
-func (r *Repository) GetByDriverID(ctx context.Context, driverID uuid.UUID) ([]model.Bid, error) {
+func (r *Repository) list(ctx context.Context, query string, id uuid.UUID, page pagination.Page) ([]model.Bid, string, error) {
+	query, args := page.Apply(query, []interface{}{id})
 	var bids []model.Bid
-	err := r.db.SelectContext(ctx, &bids, "SELECT * FROM bids WHERE driver_id = $1 ORDER BY created_at DESC", driverID)
-	return bids, err
+	if err := r.db.SelectContext(ctx, &bids, query, args...); err != nil {
+		return nil, "", err
+	}
+	bids, next := pagination.Cut(page, bids, model.Bid.SortKey)
+	return bids, next, nil
 }
 
 func (r *Repository) GetByJobAndDriver(ctx context.Context, jobID, driverID uuid.UUID) (*model.Bid, error) {
@@ -65,10 +96,32 @@ func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status mode
 	return err
 }
 
-func (r *Repository) RejectOtherBids(ctx context.Context, jobID, acceptedBidID uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE bids SET status = $1, updated_at = NOW() WHERE job_id = $2 AND id != $3 AND status = $4",
-		model.BidStatusRejected, jobID, acceptedBidID, model.BidStatusPending)
-	return err
+// Accept marks the bid accepted and rejects the job's other pending bids in one transaction
+func (r *Repository) Accept(ctx context.Context, bid *model.Bid) error {
+	evt, err := events.New(ctx, eventSource, events.TypeBidAccepted, 1, bid.ID.String(), events.BidAcceptedV1{
+		BidID: bid.ID, JobID: bid.JobID, DriverID: bid.DriverID, Amount: bid.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE bids SET status = $1, updated_at = NOW() WHERE id = $2", model.BidStatusAccepted, bid.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE bids SET status = $1, updated_at = NOW() WHERE job_id = $2 AND id != $3 AND status = $4",
+		model.BidStatusRejected, bid.JobID, bid.ID, model.BidStatusPending); err != nil {
+		return err
+	}
+	if err := events.WriteOutbox(ctx, tx, evt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
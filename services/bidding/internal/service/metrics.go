@@ -0,0 +1,11 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var bidsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "truckify_bids_accepted_total",
+	Help: "Bids accepted by shippers.",
+})
@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"truckify/services/bidding/internal/model"
 	"truckify/services/bidding/internal/repository"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/tracing"
 )
 
 var (
@@ -16,14 +22,17 @@ var (
 	ErrNotBidOwner     = errors.New("not authorized to modify this bid")
 	ErrBidNotPending   = errors.New("bid is no longer pending")
 	ErrInvalidBidState = errors.New("invalid bid state for this operation")
+	ErrJobNotFound     = errors.New("job not found")
 )
 
 type Service struct {
-	repo *repository.Repository
+	repo      *repository.Repository
+	jobSvcURL string
+	client    *http.Client
 }
 
-func New(repo *repository.Repository) *Service {
-	return &Service{repo: repo}
+func New(repo *repository.Repository, jobSvcURL string) *Service {
+	return &Service{repo: repo, jobSvcURL: jobSvcURL, client: tracing.NewClient(5 * time.Second)}
 }
 
 func (s *Service) CreateBid(ctx context.Context, driverID uuid.UUID, req model.CreateBidRequest) (*model.Bid, error) {
@@ -61,12 +70,12 @@ func (s *Service) GetBid(ctx context.Context, id uuid.UUID) (*model.Bid, error)
 	return bid, nil
 }
 
-func (s *Service) GetBidsForJob(ctx context.Context, jobID uuid.UUID) ([]model.Bid, error) {
-	return s.repo.GetByJobID(ctx, jobID)
+func (s *Service) GetBidsForJob(ctx context.Context, jobID uuid.UUID, page pagination.Page) ([]model.Bid, string, error) {
+	return s.repo.GetByJobID(ctx, jobID, page)
 }
 
-func (s *Service) GetDriverBids(ctx context.Context, driverID uuid.UUID) ([]model.Bid, error) {
-	return s.repo.GetByDriverID(ctx, driverID)
+func (s *Service) GetDriverBids(ctx context.Context, driverID uuid.UUID, page pagination.Page) ([]model.Bid, string, error) {
+	return s.repo.GetByDriverID(ctx, driverID, page)
 }
 
 func (s *Service) UpdateBid(ctx context.Context, bidID, driverID uuid.UUID, req model.UpdateBidRequest) (*model.Bid, error) {
@@ -114,12 +123,12 @@ func (s *Service) AcceptBid(ctx context.Context, bidID uuid.UUID) (*model.Bid, e
 		return nil, ErrBidNotPending
 	}
 
-	if err := s.repo.UpdateStatus(ctx, bidID, model.BidStatusAccepted); err != nil {
+	if err := s.repo.Accept(ctx, bid); err != nil {
 		return nil, err
 	}
-	s.repo.RejectOtherBids(ctx, bid.JobID, bidID)
 
 	bid.Status = model.BidStatusAccepted
+	bidsAccepted.Inc()
 	return bid, nil
 }
 
@@ -133,3 +142,40 @@ func (s *Service) RejectBid(ctx context.Context, bidID uuid.UUID) error {
 	}
 	return s.repo.UpdateStatus(ctx, bidID, model.BidStatusRejected)
 }
+
+// GetJobOwners looks up the shipper who posted a job and the organisation
+// that owns it in the job service, forwarding the caller's identity from
+// the context
+func (s *Service) GetJobOwners(ctx context.Context, jobID uuid.UUID) ([]uuid.UUID, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/jobs/%s", s.jobSvcURL, jobID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := authz.PrincipalFrom(ctx); ok {
+		p.Forward(req)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrJobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("job service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			ShipperID uuid.UUID `json:"shipper_id"`
+			OrgID     uuid.UUID `json:"org_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{result.Data.ShipperID, result.Data.OrgID}, nil
+}
@@ -0,0 +1,13 @@
+-- Responses to requests sent with an Idempotency-Key, replayed on retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(400) PRIMARY KEY,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
@@ -0,0 +1,6 @@
+-- Events the relay failed to publish too many times are set aside, so the
+-- events behind them keep flowing
+ALTER TABLE event_outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(occurred_at) WHERE published_at IS NULL AND dead_at IS NULL;
@@ -13,16 +13,27 @@ import (
 	"truckify/services/compliance/internal/handler"
 	"truckify/services/compliance/internal/repository"
 	"truckify/services/compliance/internal/service"
+	"truckify/services/compliance/migrations"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("compliance-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Compliance Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "compliance-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -36,16 +47,32 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	repo := repository.New(db)
-	svc := service.New(repo)
+	svc := service.New(repo, audit.NewClient(config.GetEnv("AUDIT_SERVICE_URL", "http://localhost:8018"), "compliance-service"), log)
 	h := handler.New(svc)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("compliance-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "compliance-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8016")
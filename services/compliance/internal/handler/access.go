@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/shared/pkg/authz"
+)
+
+// policyHolder resolves the user who holds the insurance policy
+func (h *Handler) policyHolder(r *http.Request) ([]uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	return h.holderOf(r, id)
+}
+
+// claimHolder resolves the holder of the policy a claim was made against
+func (h *Handler) claimHolder(r *http.Request) ([]uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	claim, err := h.svc.GetClaim(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		return nil, authz.ErrResourceNotFound
+	}
+	return h.holderOf(r, claim.PolicyID)
+}
+
+func (h *Handler) holderOf(r *http.Request, policyID uuid.UUID) ([]uuid.UUID, error) {
+	policy, err := h.svc.GetPolicy(r.Context(), policyID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, authz.ErrResourceNotFound
+	}
+	return []uuid.UUID{policy.UserID}, nil
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"truckify/services/compliance/internal/model"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -15,40 +18,41 @@ import (
 type ServiceInterface interface {
 	CreatePolicy(ctx context.Context, userID uuid.UUID, req *model.CreatePolicyRequest) (*model.InsurancePolicy, error)
 	GetPolicy(ctx context.Context, id uuid.UUID) (*model.InsurancePolicy, error)
-	GetUserPolicies(ctx context.Context, userID uuid.UUID) ([]model.InsurancePolicy, error)
+	GetUserPolicies(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsurancePolicy, string, error)
 	GetExpiringPolicies(ctx context.Context, days int) ([]model.InsurancePolicy, error)
 	VerifyPolicy(ctx context.Context, id, verifiedBy uuid.UUID, approve bool) error
 	CreateClaim(ctx context.Context, userID uuid.UUID, req *model.CreateClaimRequest) (*model.InsuranceClaim, error)
 	GetClaim(ctx context.Context, id uuid.UUID) (*model.InsuranceClaim, error)
-	GetUserClaims(ctx context.Context, userID uuid.UUID) ([]model.InsuranceClaim, error)
+	GetUserClaims(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsuranceClaim, string, error)
 	GetPolicyClaims(ctx context.Context, policyID uuid.UUID) ([]model.InsuranceClaim, error)
 	UpdateClaimStatus(ctx context.Context, id uuid.UUID, req *model.UpdateClaimStatusRequest) error
 	AddClaimDocument(ctx context.Context, claimID uuid.UUID, docID string) error
 }
 
 type Handler struct {
-	svc ServiceInterface
-	val *validator.Validator
+	svc   ServiceInterface
+	val   *validator.Validator
+	authz *authz.Authorizer
 }
 
 func New(svc ServiceInterface) *Handler {
-	return &Handler{svc: svc, val: validator.New()}
+	return &Handler{svc: svc, val: validator.New(), authz: authz.New(authz.DefaultPolicy())}
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
 	// Policies
-	r.HandleFunc("/insurance/policies", h.CreatePolicy).Methods("POST")
-	r.HandleFunc("/insurance/policies", h.GetMyPolicies).Methods("GET")
-	r.HandleFunc("/insurance/policies/expiring", h.GetExpiringPolicies).Methods("GET")
-	r.HandleFunc("/insurance/policies/{id}", h.GetPolicy).Methods("GET")
-	r.HandleFunc("/insurance/policies/{id}/verify", h.VerifyPolicy).Methods("POST")
+	r.HandleFunc("/insurance/policies", h.authz.RequireUnscoped(authz.PolicyCreate, h.CreatePolicy)).Methods("POST")
+	r.HandleFunc("/insurance/policies", h.authz.RequireUnscoped(authz.PolicyRead, h.GetMyPolicies)).Methods("GET")
+	r.HandleFunc("/insurance/policies/expiring", h.authz.RequireUnscoped(authz.PolicyVerify, h.GetExpiringPolicies)).Methods("GET")
+	r.HandleFunc("/insurance/policies/{id}", h.authz.RequireOwner(authz.PolicyRead, h.policyHolder, h.GetPolicy)).Methods("GET")
+	r.HandleFunc("/insurance/policies/{id}/verify", h.authz.RequireUnscoped(authz.PolicyVerify, h.VerifyPolicy)).Methods("POST")
 	// Claims
-	r.HandleFunc("/insurance/claims", h.CreateClaim).Methods("POST")
-	r.HandleFunc("/insurance/claims", h.GetMyClaims).Methods("GET")
-	r.HandleFunc("/insurance/claims/{id}", h.GetClaim).Methods("GET")
-	r.HandleFunc("/insurance/claims/{id}/status", h.UpdateClaimStatus).Methods("PUT")
-	r.HandleFunc("/insurance/claims/{id}/documents", h.AddClaimDocument).Methods("POST")
-	r.HandleFunc("/insurance/policies/{id}/claims", h.GetPolicyClaims).Methods("GET")
+	r.HandleFunc("/insurance/claims", h.authz.RequireUnscoped(authz.ClaimCreate, h.CreateClaim)).Methods("POST")
+	r.HandleFunc("/insurance/claims", h.authz.RequireUnscoped(authz.ClaimRead, h.GetMyClaims)).Methods("GET")
+	r.HandleFunc("/insurance/claims/{id}", h.authz.RequireOwner(authz.ClaimRead, h.claimHolder, h.GetClaim)).Methods("GET")
+	r.HandleFunc("/insurance/claims/{id}/status", h.authz.RequireUnscoped(authz.ClaimReview, h.UpdateClaimStatus)).Methods("PUT")
+	r.HandleFunc("/insurance/claims/{id}/documents", h.authz.RequireOwner(authz.ClaimUpdate, h.claimHolder, h.AddClaimDocument)).Methods("POST")
+	r.HandleFunc("/insurance/policies/{id}/claims", h.authz.RequireOwner(authz.ClaimRead, h.policyHolder, h.GetPolicyClaims)).Methods("GET")
 	r.HandleFunc("/health", h.Health).Methods("GET")
 }
 
@@ -109,7 +113,13 @@ func (h *Handler) GetMyPolicies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	policies, err := h.svc.GetUserPolicies(r.Context(), userID)
+	page, err := pagination.Parse(r.URL.Query(), model.PolicyListSpec)
+	if err != nil {
+		response.BadRequest(w, "invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	policies, next, err := h.svc.GetUserPolicies(r.Context(), userID, page)
 	if err != nil {
 		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
 		return
@@ -117,7 +127,7 @@ func (h *Handler) GetMyPolicies(w http.ResponseWriter, r *http.Request) {
 	if policies == nil {
 		policies = []model.InsurancePolicy{}
 	}
-	response.Success(w, policies, reqID)
+	response.List(w, policies, next, reqID)
 }
 
 func (h *Handler) GetExpiringPolicies(w http.ResponseWriter, r *http.Request) {
@@ -160,7 +170,7 @@ func (h *Handler) VerifyPolicy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.VerifyPolicy(r.Context(), id, userID, req.Approve); err != nil {
+	if err := h.svc.VerifyPolicy(audit.RequestContext(r), id, userID, req.Approve); err != nil {
 		response.InternalServerError(w, "verify failed", err.Error(), reqID)
 		return
 	}
@@ -213,7 +223,13 @@ func (h *Handler) GetMyClaims(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err := h.svc.GetUserClaims(r.Context(), userID)
+	page, err := pagination.Parse(r.URL.Query(), model.ClaimListSpec)
+	if err != nil {
+		response.BadRequest(w, "invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	claims, next, err := h.svc.GetUserClaims(r.Context(), userID, page)
 	if err != nil {
 		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
 		return
@@ -221,7 +237,7 @@ func (h *Handler) GetMyClaims(w http.ResponseWriter, r *http.Request) {
 	if claims == nil {
 		claims = []model.InsuranceClaim{}
 	}
-	response.Success(w, claims, reqID)
+	response.List(w, claims, next, reqID)
 }
 
 func (h *Handler) GetPolicyClaims(w http.ResponseWriter, r *http.Request) {
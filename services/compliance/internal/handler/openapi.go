@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/compliance/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /insurance/policies": {Summary: "Add an insurance policy", Request: model.CreatePolicyRequest{}, Response: model.InsurancePolicy{}, Status: http.StatusCreated},
+	"GET /insurance/policies":  {Summary: "List the caller's policies", Response: []model.InsurancePolicy{}, List: &model.PolicyListSpec},
+	"GET /insurance/policies/expiring": {
+		Summary:  "List policies about to expire",
+		Response: []model.InsurancePolicy{},
+		Query: []openapi.Parameter{
+			{Name: "days", In: "query", Description: "Days ahead, 30 by default", Schema: &openapi.Schema{Type: "integer"}},
+		},
+	},
+	"GET /insurance/policies/{id}": {Summary: "Get a policy", Response: model.InsurancePolicy{}},
+	"POST /insurance/policies/{id}/verify": {
+		Summary: "Approve or reject a policy",
+		Request: struct {
+			Approve bool `json:"approve"`
+		}{},
+		Response: map[string]string{},
+	},
+	"GET /insurance/policies/{id}/claims": {Summary: "List a policy's claims", Response: []model.InsuranceClaim{}},
+
+	"POST /insurance/claims":            {Summary: "Lodge a claim", Request: model.CreateClaimRequest{}, Response: model.InsuranceClaim{}, Status: http.StatusCreated},
+	"GET /insurance/claims":             {Summary: "List the caller's claims", Response: []model.InsuranceClaim{}, List: &model.ClaimListSpec},
+	"GET /insurance/claims/{id}":        {Summary: "Get a claim", Response: model.InsuranceClaim{}},
+	"PUT /insurance/claims/{id}/status": {Summary: "Review a claim", Request: model.UpdateClaimStatusRequest{}, Response: map[string]string{}},
+	"POST /insurance/claims/{id}/documents": {
+		Summary: "Attach a document to a claim",
+		Request: struct {
+			DocumentID string `json:"document_id" validate:"required"`
+		}{},
+		Response: map[string]string{},
+	},
+
+	"GET /health": {Summary: "Health check", Response: map[string]string{}},
+}
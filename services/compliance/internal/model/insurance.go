@@ -3,6 +3,7 @@ package model
 import (
 	"time"
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
 type InsurancePolicy struct {
@@ -41,6 +42,50 @@ type InsuranceClaim struct {
 	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// PolicyListSpec is what a user's policies can be filtered and sorted by
+var PolicyListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"status":      {Column: "status"},
+		"policy_type": {Column: "policy_type"},
+		"end_date":    {Column: "end_date", Kind: pagination.Time, Sortable: true},
+		"created_at":  {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a PolicyListSpec sort field and the
+// policy's ID
+func (p InsurancePolicy) SortKey(field string) (interface{}, uuid.UUID) {
+	if field == "end_date" {
+		return p.EndDate, p.ID
+	}
+	return p.CreatedAt, p.ID
+}
+
+// ClaimListSpec is what a user's claims can be filtered and sorted by
+var ClaimListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"status":        {Column: "c.status"},
+		"incident_type": {Column: "c.incident_type"},
+		"claim_amount":  {Column: "c.claim_amount", Kind: pagination.Number, Sortable: true},
+		"incident_date": {Column: "c.incident_date", Kind: pagination.Time, Sortable: true},
+		"created_at":    {Column: "c.created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+	IDColumn:    "c.id",
+}
+
+// SortKey returns the value of a ClaimListSpec sort field and the claim's ID
+func (c InsuranceClaim) SortKey(field string) (interface{}, uuid.UUID) {
+	switch field {
+	case "claim_amount":
+		return c.ClaimAmount, c.ID
+	case "incident_date":
+		return c.IncidentDate, c.ID
+	}
+	return c.CreatedAt, c.ID
+}
+
 type CreatePolicyRequest struct {
 	VehicleID      *string `json:"vehicle_id"`
 	PolicyNumber   string  `json:"policy_number" validate:"required"`
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"truckify/services/compliance/internal/model"
+	"truckify/shared/pkg/pagination"
 )
 
 type Repository struct{ db *sql.DB }
@@ -32,10 +33,11 @@ func (r *Repository) GetPolicy(ctx context.Context, id uuid.UUID) (*model.Insura
 	return &p, err
 }
 
-func (r *Repository) GetUserPolicies(ctx context.Context, userID uuid.UUID) ([]model.InsurancePolicy, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, vehicle_id, policy_number, provider, policy_type, coverage_amount, premium, start_date, end_date, status, document_id, verified_at, verified_by, created_at, updated_at FROM insurance_policies WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+func (r *Repository) GetUserPolicies(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsurancePolicy, string, error) {
+	query, args := page.Apply(`SELECT id, user_id, vehicle_id, policy_number, provider, policy_type, coverage_amount, premium, start_date, end_date, status, document_id, verified_at, verified_by, created_at, updated_at FROM insurance_policies WHERE user_id = $1`, []interface{}{userID})
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -43,11 +45,12 @@ func (r *Repository) GetUserPolicies(ctx context.Context, userID uuid.UUID) ([]m
 	for rows.Next() {
 		var p model.InsurancePolicy
 		if err := rows.Scan(&p.ID, &p.UserID, &p.VehicleID, &p.PolicyNumber, &p.Provider, &p.PolicyType, &p.CoverageAmount, &p.Premium, &p.StartDate, &p.EndDate, &p.Status, &p.DocumentID, &p.VerifiedAt, &p.VerifiedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		policies = append(policies, p)
 	}
-	return policies, nil
+	policies, next := pagination.Cut(page, policies, model.InsurancePolicy.SortKey)
+	return policies, next, nil
 }
 
 func (r *Repository) GetExpiringPolicies(ctx context.Context, days int) ([]model.InsurancePolicy, error) {
@@ -119,11 +122,12 @@ func (r *Repository) GetPolicyClaims(ctx context.Context, policyID uuid.UUID) ([
 	return claims, nil
 }
 
-func (r *Repository) GetUserClaims(ctx context.Context, userID uuid.UUID) ([]model.InsuranceClaim, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT c.id, c.policy_id, c.job_id, c.claim_number, c.incident_date, c.incident_type, c.description, c.claim_amount, c.status, c.documents, c.resolution_notes, c.resolved_at, c.created_at, c.updated_at 
-		FROM insurance_claims c JOIN insurance_policies p ON c.policy_id = p.id WHERE p.user_id = $1 ORDER BY c.created_at DESC`, userID)
+func (r *Repository) GetUserClaims(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsuranceClaim, string, error) {
+	query, args := page.Apply(`SELECT c.id, c.policy_id, c.job_id, c.claim_number, c.incident_date, c.incident_type, c.description, c.claim_amount, c.status, c.documents, c.resolution_notes, c.resolved_at, c.created_at, c.updated_at 
+		FROM insurance_claims c JOIN insurance_policies p ON c.policy_id = p.id WHERE p.user_id = $1`, []interface{}{userID})
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -132,12 +136,13 @@ func (r *Repository) GetUserClaims(ctx context.Context, userID uuid.UUID) ([]mod
 		var c model.InsuranceClaim
 		var docsJSON []byte
 		if err := rows.Scan(&c.ID, &c.PolicyID, &c.JobID, &c.ClaimNumber, &c.IncidentDate, &c.IncidentType, &c.Description, &c.ClaimAmount, &c.Status, &docsJSON, &c.ResolutionNotes, &c.ResolvedAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		json.Unmarshal(docsJSON, &c.Documents)
 		claims = append(claims, c)
 	}
-	return claims, nil
+	claims, next := pagination.Cut(page, claims, model.InsuranceClaim.SortKey)
+	return claims, next, nil
 }
 
 func (r *Repository) UpdateClaimStatus(ctx context.Context, id uuid.UUID, status string, notes *string) error {
@@ -8,11 +8,20 @@ import (
 	"github.com/google/uuid"
 	"truckify/services/compliance/internal/model"
 	"truckify/services/compliance/internal/repository"
+	"truckify/shared/pkg/audit"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 )
 
-type Service struct{ repo *repository.Repository }
+type Service struct {
+	repo   *repository.Repository
+	audit  audit.Recorder
+	logger *logger.Logger
+}
 
-func New(repo *repository.Repository) *Service { return &Service{repo: repo} }
+func New(repo *repository.Repository, recorder audit.Recorder, log *logger.Logger) *Service {
+	return &Service{repo: repo, audit: recorder, logger: log}
+}
 
 func (s *Service) CreatePolicy(ctx context.Context, userID uuid.UUID, req *model.CreatePolicyRequest) (*model.InsurancePolicy, error) {
 	startDate, _ := time.Parse("2006-01-02", req.StartDate)
@@ -61,20 +70,51 @@ func (s *Service) GetPolicy(ctx context.Context, id uuid.UUID) (*model.Insurance
 	return s.repo.GetPolicy(ctx, id)
 }
 
-func (s *Service) GetUserPolicies(ctx context.Context, userID uuid.UUID) ([]model.InsurancePolicy, error) {
-	return s.repo.GetUserPolicies(ctx, userID)
+func (s *Service) GetUserPolicies(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsurancePolicy, string, error) {
+	return s.repo.GetUserPolicies(ctx, userID, page)
 }
 
 func (s *Service) GetExpiringPolicies(ctx context.Context, days int) ([]model.InsurancePolicy, error) {
 	return s.repo.GetExpiringPolicies(ctx, days)
 }
 
+// VerifyPolicy approves or cancels a policy and records the review in the
+// audit log
 func (s *Service) VerifyPolicy(ctx context.Context, id, verifiedBy uuid.UUID, approve bool) error {
+	before, err := s.repo.GetPolicy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("policy not found")
+	}
+
 	status := "active"
 	if !approve {
 		status = "cancelled"
 	}
-	return s.repo.VerifyPolicy(ctx, id, verifiedBy, status)
+	if err := s.repo.VerifyPolicy(ctx, id, verifiedBy, status); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetPolicy(ctx, id)
+	if err != nil || after == nil {
+		s.logger.Error("Failed to read verified policy for the audit log", "policy_id", id, "error", err)
+		return nil
+	}
+	changes, err := audit.Diff(before, after)
+	if err == nil {
+		err = s.audit.Record(ctx, audit.Entry{
+			Action:       audit.ActionPolicyVerified,
+			ResourceType: "insurance_policy",
+			ResourceID:   id.String(),
+			Changes:      changes,
+		})
+	}
+	if err != nil {
+		s.logger.Error("Failed to record audit entry", "action", audit.ActionPolicyVerified, "resource_id", id, "error", err)
+	}
+	return nil
 }
 
 func (s *Service) CreateClaim(ctx context.Context, userID uuid.UUID, req *model.CreateClaimRequest) (*model.InsuranceClaim, error) {
@@ -124,8 +164,8 @@ func (s *Service) GetClaim(ctx context.Context, id uuid.UUID) (*model.InsuranceC
 	return s.repo.GetClaim(ctx, id)
 }
 
-func (s *Service) GetUserClaims(ctx context.Context, userID uuid.UUID) ([]model.InsuranceClaim, error) {
-	return s.repo.GetUserClaims(ctx, userID)
+func (s *Service) GetUserClaims(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.InsuranceClaim, string, error) {
+	return s.repo.GetUserClaims(ctx, userID, page)
 }
 
 func (s *Service) GetPolicyClaims(ctx context.Context, policyID uuid.UUID) ([]model.InsuranceClaim, error) {
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS insurance_claims;
+DROP TABLE IF EXISTS insurance_policies;
@@ -0,0 +1,10 @@
+// Package migrations holds the service's database schema, applied with
+// `migrate up` or at startup when DB_AUTO_MIGRATE is set
+package migrations
+
+import "embed"
+
+// FS contains the versioned SQL migrations
+//
+//go:embed *.sql
+var FS embed.FS
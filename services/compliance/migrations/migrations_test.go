@@ -0,0 +1,14 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"truckify/services/compliance/migrations"
+	"truckify/shared/pkg/database"
+)
+
+func TestMigrations(t *testing.T) {
+	if _, err := database.LoadMigrations(migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,8 +22,12 @@ type ServiceInterface interface {
 	UpdateLocation(userID uuid.UUID, req *model.UpdateLocationRequest) error
 	AddVehicle(userID uuid.UUID, req *model.AddVehicleRequest) (*model.Vehicle, error)
 	GetAvailableDrivers(vehicleType string, limit int) ([]*model.DriverProfile, error)
+	GetDriversByUserIDs(userIDs []uuid.UUID) ([]*model.DriverProfile, error)
 }
 
+// maxBatchIDs caps the IDs accepted by one batch lookup
+const maxBatchIDs = 100
+
 type Handler struct {
 	svc ServiceInterface
 	val *validator.Validator
@@ -41,6 +46,7 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/driver/availability", h.ToggleAvailability).Methods("PUT")
 	r.HandleFunc("/driver/vehicle", h.AddVehicle).Methods("POST")
 	r.HandleFunc("/drivers/available", h.GetAvailableDrivers).Methods("GET")
+	r.HandleFunc("/drivers", h.GetDriversByUserIDs).Methods("GET")
 	r.HandleFunc("/health", h.Health).Methods("GET")
 }
 
@@ -263,6 +269,42 @@ func (h *Handler) GetAvailableDrivers(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, drivers, reqID)
 }
 
+// GetDriversByUserIDs returns the profiles of the drivers with the given
+// user IDs, so callers resolving many bids or jobs can look them up at once
+func (h *Handler) GetDriversByUserIDs(w http.ResponseWriter, r *http.Request) {
+	reqID := h.getRequestID(r)
+	raw := r.URL.Query().Get("user_ids")
+	if raw == "" {
+		response.BadRequest(w, "user_ids is required", "", reqID)
+		return
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxBatchIDs {
+		response.BadRequest(w, "too many user_ids", "at most "+strconv.Itoa(maxBatchIDs), reqID)
+		return
+	}
+	userIDs := make([]uuid.UUID, 0, len(parts))
+	for _, part := range parts {
+		id, err := uuid.Parse(strings.TrimSpace(part))
+		if err != nil {
+			response.BadRequest(w, "invalid user id", part, reqID)
+			return
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	drivers, err := h.svc.GetDriversByUserIDs(userIDs)
+	if err != nil {
+		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
+		return
+	}
+	if drivers == nil {
+		drivers = []*model.DriverProfile{}
+	}
+	response.Success(w, drivers, reqID)
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	reqID := h.getRequestID(r)
 	response.Success(w, map[string]string{"status": "healthy", "service": "driver-service"}, reqID)
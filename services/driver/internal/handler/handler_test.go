@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/driver/internal/model"
 	"truckify/services/driver/internal/repository"
+	"truckify/shared/pkg/openapi"
 )
 
 type mockService struct {
@@ -59,6 +60,13 @@ func (m *mockService) AddVehicle(userID uuid.UUID, req *model.AddVehicleRequest)
 	return m.vehicle, nil
 }
 
+func (m *mockService) GetDriversByUserIDs(userIDs []uuid.UUID) ([]*model.DriverProfile, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.drivers, nil
+}
+
 func (m *mockService) GetAvailableDrivers(vehicleType string, limit int) ([]*model.DriverProfile, error) {
 	if m.err != nil {
 		return nil, m.err
@@ -234,3 +242,35 @@ func TestGetAvailableDrivers_Success(t *testing.T) {
 		t.Error("expected data in response")
 	}
 }
+
+func TestGetDriversByUserIDs(t *testing.T) {
+	mock := &mockService{
+		drivers: []*model.DriverProfile{{ID: uuid.New(), UserID: uuid.New()}},
+	}
+	h := &Handler{svc: mock, val: nil}
+
+	req := httptest.NewRequest("GET", "/drivers?user_ids="+uuid.New().String()+","+uuid.New().String(), nil)
+	w := httptest.NewRecorder()
+	h.GetDriversByUserIDs(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+
+	for _, query := range []string{"", "?user_ids=not-a-uuid"} {
+		req = httptest.NewRequest("GET", "/drivers"+query, nil)
+		w = httptest.NewRecorder()
+		h.GetDriversByUserIDs(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	New(&mockService{}).RegisterRoutes(router)
+
+	if err := openapi.Check(router, Routes); err != nil {
+		t.Fatal(err)
+	}
+}
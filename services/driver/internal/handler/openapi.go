@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/driver/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /driver":         {Summary: "Create the caller's driver profile", Request: model.CreateDriverRequest{}, Response: model.DriverProfile{}, Status: http.StatusCreated},
+	"GET /driver":          {Summary: "Get the caller's driver profile", Response: model.DriverProfile{}},
+	"PUT /driver":          {Summary: "Update the caller's driver profile", Request: model.UpdateDriverRequest{}, Response: model.DriverProfile{}},
+	"GET /driver/{id}":     {Summary: "Get a driver profile", Response: model.DriverProfile{}},
+	"PUT /driver/location": {Summary: "Update the caller's location", Request: model.UpdateLocationRequest{}, Response: map[string]string{}},
+	"PUT /driver/availability": {
+		Summary: "Set whether the caller is available",
+		Request: struct {
+			IsAvailable bool `json:"is_available"`
+		}{},
+		Response: model.DriverProfile{},
+	},
+	"POST /driver/vehicle": {Summary: "Add the caller's vehicle", Request: model.AddVehicleRequest{}, Response: model.Vehicle{}, Status: http.StatusCreated},
+	"GET /drivers/available": {
+		Summary:  "List available drivers",
+		Response: []*model.DriverProfile{},
+		Query: []openapi.Parameter{
+			{Name: "type", In: "query", Description: "Vehicle type", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+		},
+	},
+	"GET /drivers": {
+		Summary:  "Get the profiles of drivers by user ID",
+		Response: []*model.DriverProfile{},
+		Query: []openapi.Parameter{
+			{Name: "user_ids", In: "query", Required: true, Description: "Comma separated user IDs, at most 100", Schema: &openapi.Schema{Type: "string"}},
+		},
+	},
+	"GET /health": {Summary: "Health check", Response: map[string]string{}},
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"truckify/services/driver/internal/model"
 )
 
@@ -96,6 +97,44 @@ func (r *Repository) GetByUserID(userID uuid.UUID) (*model.DriverProfile, error)
 	return driver, nil
 }
 
+// GetByUserIDs returns the drivers with the given user IDs, with their
+// vehicles. Unknown IDs are left out.
+func (r *Repository) GetByUserIDs(userIDs []uuid.UUID) ([]*model.DriverProfile, error) {
+	rows, err := r.db.Query(`
+		SELECT d.id, d.user_id, d.license_number, d.license_state, d.license_expiry, d.license_class,
+			d.years_experience, d.is_available, d.current_location, d.rating, d.total_trips, d.status,
+			d.created_at, d.updated_at, v.vehicle_json
+		FROM drivers d
+		LEFT JOIN LATERAL (
+			SELECT row_to_json(vehicles.*) AS vehicle_json
+			FROM vehicles WHERE vehicles.driver_id = d.id LIMIT 1
+		) v ON true
+		WHERE d.user_id = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drivers []*model.DriverProfile
+	for rows.Next() {
+		d := &model.DriverProfile{}
+		var locationJSON, vehicleJSON sql.NullString
+		if err := rows.Scan(&d.ID, &d.UserID, &d.LicenseNumber, &d.LicenseState, &d.LicenseExpiry,
+			&d.LicenseClass, &d.YearsExperience, &d.IsAvailable, &locationJSON,
+			&d.Rating, &d.TotalTrips, &d.Status, &d.CreatedAt, &d.UpdatedAt, &vehicleJSON); err != nil {
+			return nil, err
+		}
+		if locationJSON.Valid {
+			json.Unmarshal([]byte(locationJSON.String), &d.CurrentLocation)
+		}
+		if vehicleJSON.Valid {
+			json.Unmarshal([]byte(vehicleJSON.String), &d.Vehicle)
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, rows.Err()
+}
+
 func (r *Repository) GetByID(id uuid.UUID) (*model.DriverProfile, error) {
 	driver := &model.DriverProfile{}
 	var locationJSON sql.NullString
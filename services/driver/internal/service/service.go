@@ -22,6 +22,10 @@ func (s *Service) GetDriver(userID uuid.UUID) (*model.DriverProfile, error) {
 	return s.repo.GetByUserID(userID)
 }
 
+func (s *Service) GetDriversByUserIDs(userIDs []uuid.UUID) ([]*model.DriverProfile, error) {
+	return s.repo.GetByUserIDs(userIDs)
+}
+
 func (s *Service) GetDriverByID(id uuid.UUID) (*model.DriverProfile, error) {
 	return s.repo.GetByID(id)
 }
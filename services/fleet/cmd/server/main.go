@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,16 +13,26 @@ import (
 	"truckify/services/fleet/internal/handler"
 	"truckify/services/fleet/internal/repository"
 	"truckify/services/fleet/internal/service"
+	"truckify/services/fleet/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("fleet-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Fleet Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "fleet-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -35,16 +46,32 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	repo := repository.New(db)
 	svc := service.New(repo)
 	h := handler.New(svc)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("fleet-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "fleet-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8005")
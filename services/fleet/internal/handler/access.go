@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/fleet/internal/model"
+	"truckify/services/fleet/internal/repository"
+	"truckify/shared/pkg/authz"
+)
+
+// fleetOwner resolves the operator who owns the fleet and their organisation
+func (h *Handler) fleetOwner(r *http.Request) ([]uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	fleet, err := h.svc.GetFleet(id)
+	if err == repository.ErrNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{fleet.OwnerID, fleet.OrgID}, nil
+}
+
+// vehicleFleetOwner resolves the operator of the fleet the vehicle belongs to
+// and their organisation
+func (h *Handler) vehicleFleetOwner(r *http.Request) ([]uuid.UUID, error) {
+	vehicle, err := h.targetVehicle(r)
+	if err != nil {
+		return nil, err
+	}
+	fleet, err := h.svc.GetFleet(vehicle.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{fleet.OwnerID, fleet.OrgID}, nil
+}
+
+// vehicleOwners resolves the fleet operator and the driver currently assigned
+// to the vehicle
+func (h *Handler) vehicleOwners(r *http.Request) ([]uuid.UUID, error) {
+	owners, err := h.vehicleFleetOwner(r)
+	if err != nil {
+		return nil, err
+	}
+	vehicle, _ := h.targetVehicle(r)
+	if vehicle.CurrentDriverID != nil {
+		owners = append(owners, *vehicle.CurrentDriverID)
+	}
+	return owners, nil
+}
+
+func (h *Handler) targetVehicle(r *http.Request) (*model.FleetVehicle, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	vehicle, err := h.svc.GetVehicle(id)
+	if err == repository.ErrNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	return vehicle, err
+}
@@ -8,42 +8,46 @@ import (
 	"truckify/services/fleet/internal/model"
 	"truckify/services/fleet/internal/repository"
 	"truckify/services/fleet/internal/service"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
 
 type Handler struct {
-	svc *service.Service
-	val *validator.Validator
+	svc   *service.Service
+	val   *validator.Validator
+	authz *authz.Authorizer
 }
 
 func New(svc *service.Service) *Handler {
-	return &Handler{svc: svc, val: validator.New()}
+	return &Handler{svc: svc, val: validator.New(), authz: authz.New(authz.DefaultPolicy())}
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
 	// Vehicle management (more specific routes first)
-	r.HandleFunc("/fleet/vehicles/{id}/assign", h.AssignVehicle).Methods("POST")
-	r.HandleFunc("/fleet/vehicles/{id}/unassign", h.UnassignVehicle).Methods("POST")
-	r.HandleFunc("/fleet/vehicles/{id}", h.GetVehicle).Methods("GET")
-	r.HandleFunc("/fleet/vehicles", h.CreateVehicle).Methods("POST")
-	r.HandleFunc("/fleet/vehicles", h.GetFleetVehicles).Methods("GET")
+	r.HandleFunc("/fleet/vehicles/{id}/assign", h.authz.RequireOwner(authz.FleetManage, h.vehicleFleetOwner, h.AssignVehicle)).Methods("POST")
+	r.HandleFunc("/fleet/vehicles/{id}/unassign", h.authz.RequireOwner(authz.FleetManage, h.vehicleFleetOwner, h.UnassignVehicle)).Methods("POST")
+	r.HandleFunc("/fleet/vehicles/{id}", h.authz.RequireOwner(authz.FleetRead, h.vehicleOwners, h.GetVehicle)).Methods("GET")
+	r.HandleFunc("/fleet/vehicles", h.authz.RequireUnscoped(authz.FleetManage, h.CreateVehicle)).Methods("POST")
+	r.HandleFunc("/fleet/vehicles", h.authz.RequireUnscoped(authz.FleetManage, h.GetFleetVehicles)).Methods("GET")
 
 	// Driver management
-	r.HandleFunc("/fleet/drivers/{id}", h.RemoveDriver).Methods("DELETE")
-	r.HandleFunc("/fleet/drivers", h.AddDriver).Methods("POST")
-	r.HandleFunc("/fleet/drivers", h.GetFleetDrivers).Methods("GET")
+	r.HandleFunc("/fleet/drivers/{id}", h.authz.RequireUnscoped(authz.FleetManage, h.RemoveDriver)).Methods("DELETE")
+	r.HandleFunc("/fleet/drivers", h.authz.RequireUnscoped(authz.FleetManage, h.AddDriver)).Methods("POST")
+	r.HandleFunc("/fleet/drivers", h.authz.RequireUnscoped(authz.FleetManage, h.GetFleetDrivers)).Methods("GET")
 
 	// Fleet management
-	r.HandleFunc("/fleet/{id}", h.GetFleet).Methods("GET")
-	r.HandleFunc("/fleet", h.CreateFleet).Methods("POST")
-	r.HandleFunc("/fleet", h.GetMyFleet).Methods("GET")
+	r.HandleFunc("/fleet/membership", h.authz.RequireUnscoped(authz.FleetRead, h.GetDriverFleet)).Methods("GET")
+	r.HandleFunc("/fleet/{id}", h.authz.RequireOwner(authz.FleetRead, h.fleetOwner, h.GetFleet)).Methods("GET")
+	r.HandleFunc("/fleet", h.authz.RequireUnscoped(authz.FleetManage, h.CreateFleet)).Methods("POST")
+	r.HandleFunc("/fleet", h.authz.RequireUnscoped(authz.FleetManage, h.GetMyFleet)).Methods("GET")
 
 	// Handover
-	r.HandleFunc("/handover/{id}/accept", h.AcceptHandover).Methods("POST")
-	r.HandleFunc("/handover/{id}/reject", h.RejectHandover).Methods("POST")
-	r.HandleFunc("/handover/request", h.RequestHandover).Methods("POST")
-	r.HandleFunc("/handover/pending", h.GetPendingHandovers).Methods("GET")
+	r.HandleFunc("/handover/{id}/accept", h.authz.RequireUnscoped(authz.HandoverManage, h.AcceptHandover)).Methods("POST")
+	r.HandleFunc("/handover/{id}/reject", h.authz.RequireUnscoped(authz.HandoverManage, h.RejectHandover)).Methods("POST")
+	r.HandleFunc("/handover/request", h.authz.RequireUnscoped(authz.HandoverManage, h.RequestHandover)).Methods("POST")
+	r.HandleFunc("/handover/pending", h.authz.RequireUnscoped(authz.HandoverManage, h.GetPendingHandovers)).Methods("GET")
 
 	r.HandleFunc("/health", h.Health).Methods("GET")
 }
@@ -52,6 +56,15 @@ func (h *Handler) getUserID(r *http.Request) (uuid.UUID, error) {
 	return uuid.Parse(r.Header.Get("X-User-ID"))
 }
 
+// getOrgID returns the organisation the caller acts for, whose fleet they
+// manage. Without one it is the caller's personal organisation.
+func (h *Handler) getOrgID(r *http.Request, userID uuid.UUID) uuid.UUID {
+	if principal, ok := authz.PrincipalFrom(r.Context()); ok {
+		return principal.Org()
+	}
+	return userID
+}
+
 func (h *Handler) getRequestID(r *http.Request) string {
 	if id, ok := r.Context().Value("request_id").(string); ok {
 		return id
@@ -73,7 +86,7 @@ func (h *Handler) CreateFleet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.CreateFleet(userID, &req)
+	fleet, err := h.svc.CreateFleet(userID, h.getOrgID(r, userID), &req)
 	if err != nil {
 		response.InternalServerError(w, "failed to create fleet", err.Error(), reqID)
 		return
@@ -89,7 +102,28 @@ func (h *Handler) GetMyFleet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
+	if err == repository.ErrNotFound {
+		response.NotFound(w, "fleet not found", "", reqID)
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, "failed to get fleet", err.Error(), reqID)
+		return
+	}
+	response.Success(w, fleet, reqID)
+}
+
+// GetDriverFleet returns the fleet the caller drives for
+func (h *Handler) GetDriverFleet(w http.ResponseWriter, r *http.Request) {
+	reqID := h.getRequestID(r)
+	userID, err := h.getUserID(r)
+	if err != nil {
+		response.Unauthorized(w, "unauthorized", "", reqID)
+		return
+	}
+
+	fleet, err := h.svc.GetDriverFleet(userID)
 	if err == repository.ErrNotFound {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -129,7 +163,7 @@ func (h *Handler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -157,13 +191,19 @@ func (h *Handler) GetFleetVehicles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	page, err := pagination.Parse(r.URL.Query(), model.VehicleListSpec)
+	if err != nil {
+		response.BadRequest(w, "invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
 	}
 
-	vehicles, err := h.svc.GetFleetVehicles(fleet.ID)
+	vehicles, next, err := h.svc.GetFleetVehicles(fleet.ID, page)
 	if err != nil {
 		response.InternalServerError(w, "failed to get vehicles", err.Error(), reqID)
 		return
@@ -171,7 +211,7 @@ func (h *Handler) GetFleetVehicles(w http.ResponseWriter, r *http.Request) {
 	if vehicles == nil {
 		vehicles = []*model.FleetVehicle{}
 	}
-	response.Success(w, vehicles, reqID)
+	response.List(w, vehicles, next, reqID)
 }
 
 func (h *Handler) GetVehicle(w http.ResponseWriter, r *http.Request) {
@@ -208,7 +248,7 @@ func (h *Handler) AssignVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -246,7 +286,7 @@ func (h *Handler) UnassignVehicle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify ownership
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -273,7 +313,7 @@ func (h *Handler) AddDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -301,13 +341,19 @@ func (h *Handler) GetFleetDrivers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	page, err := pagination.Parse(r.URL.Query(), model.DriverListSpec)
+	if err != nil {
+		response.BadRequest(w, "invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
 	}
 
-	drivers, err := h.svc.GetFleetDrivers(fleet.ID)
+	drivers, next, err := h.svc.GetFleetDrivers(fleet.ID, page)
 	if err != nil {
 		response.InternalServerError(w, "failed to get drivers", err.Error(), reqID)
 		return
@@ -315,7 +361,7 @@ func (h *Handler) GetFleetDrivers(w http.ResponseWriter, r *http.Request) {
 	if drivers == nil {
 		drivers = []*model.FleetDriver{}
 	}
-	response.Success(w, drivers, reqID)
+	response.List(w, drivers, next, reqID)
 }
 
 func (h *Handler) RemoveDriver(w http.ResponseWriter, r *http.Request) {
@@ -332,7 +378,7 @@ func (h *Handler) RemoveDriver(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fleet, err := h.svc.GetMyFleet(userID)
+	fleet, err := h.svc.GetMyFleet(h.getOrgID(r, userID))
 	if err != nil {
 		response.NotFound(w, "fleet not found", "", reqID)
 		return
@@ -371,7 +417,8 @@ func (h *Handler) RequestHandover(w http.ResponseWriter, r *http.Request) {
 		fromDriverID = vehicle.CurrentDriverID
 		// Only current driver or fleet owner can request handover
 		fleet, _ := h.svc.GetFleet(vehicle.FleetID)
-		if *vehicle.CurrentDriverID != userID && fleet.OwnerID != userID {
+		principal, _ := authz.PrincipalFrom(r.Context())
+		if *vehicle.CurrentDriverID != userID && !principal.Owns(fleet.OwnerID) && !principal.Owns(fleet.OrgID) {
 			response.Forbidden(w, "not authorized", "", reqID)
 			return
 		}
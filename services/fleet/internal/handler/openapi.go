@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/fleet/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /fleet":     {Summary: "Create the caller's fleet", Request: model.CreateFleetRequest{}, Response: model.Fleet{}, Status: http.StatusCreated},
+	"GET /fleet":      {Summary: "Get the caller's fleet", Response: model.Fleet{}},
+	"GET /fleet/{id}": {Summary: "Get a fleet", Response: model.Fleet{}},
+
+	"GET /fleet/membership": {Summary: "Get the fleet the caller drives for", Response: model.Fleet{}},
+
+	"POST /fleet/vehicles":               {Summary: "Add a vehicle to the caller's fleet", Request: model.CreateVehicleRequest{}, Response: model.FleetVehicle{}, Status: http.StatusCreated},
+	"GET /fleet/vehicles":                {Summary: "List the caller's fleet vehicles", Response: []*model.FleetVehicle{}, List: &model.VehicleListSpec},
+	"GET /fleet/vehicles/{id}":           {Summary: "Get a vehicle", Response: model.FleetVehicle{}},
+	"POST /fleet/vehicles/{id}/assign":   {Summary: "Assign a vehicle to a driver", Request: model.AssignVehicleRequest{}, Response: map[string]string{}},
+	"POST /fleet/vehicles/{id}/unassign": {Summary: "Take a vehicle off its driver", Response: map[string]string{}},
+
+	"POST /fleet/drivers":        {Summary: "Add a driver to the caller's fleet", Request: model.AddDriverRequest{}, Response: model.FleetDriver{}, Status: http.StatusCreated},
+	"GET /fleet/drivers":         {Summary: "List the caller's fleet drivers", Response: []*model.FleetDriver{}, List: &model.DriverListSpec},
+	"DELETE /fleet/drivers/{id}": {Summary: "Remove a driver from the caller's fleet", Response: map[string]string{}},
+
+	"POST /handover/request":     {Summary: "Request a vehicle handover", Request: model.HandoverRequest{}, Response: model.VehicleHandover{}, Status: http.StatusCreated},
+	"GET /handover/pending":      {Summary: "List handovers waiting for the caller", Response: []*model.VehicleHandover{}},
+	"POST /handover/{id}/accept": {Summary: "Accept a handover", Response: map[string]string{}},
+	"POST /handover/{id}/reject": {Summary: "Reject a handover", Response: map[string]string{}},
+
+	"GET /health": {Summary: "Health check", Response: map[string]string{}},
+}
@@ -4,11 +4,16 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
+// Fleet is an operator's vehicles and drivers. It belongs to the
+// organisation the operator acted for when creating it, whose members can
+// manage it.
 type Fleet struct {
 	ID        uuid.UUID `json:"id"`
 	OwnerID   uuid.UUID `json:"owner_id"`
+	OrgID     uuid.UUID `json:"org_id"`
 	Name      string    `json:"name"`
 	ABN       string    `json:"abn,omitempty"`
 	Status    string    `json:"status"`
@@ -96,3 +101,45 @@ type HandoverRequest struct {
 type AssignVehicleRequest struct {
 	DriverID uuid.UUID `json:"driver_id" validate:"required"`
 }
+
+// VehicleListSpec is what a fleet's vehicles can be filtered and sorted by
+var VehicleListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"status":           {Column: "status"},
+		"type":             {Column: "type"},
+		"year":             {Column: "year", Kind: pagination.Number, Sortable: true},
+		"rego_expiry":      {Column: "rego_expiry", Kind: pagination.Time, Sortable: true},
+		"insurance_expiry": {Column: "insurance_expiry", Kind: pagination.Time, Sortable: true},
+		"created_at":       {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a VehicleListSpec sort field and the
+// vehicle's ID
+func (v *FleetVehicle) SortKey(field string) (interface{}, uuid.UUID) {
+	switch field {
+	case "year":
+		return float64(v.Year), v.ID
+	case "rego_expiry":
+		return v.RegoExpiry, v.ID
+	case "insurance_expiry":
+		return v.InsuranceExpiry, v.ID
+	}
+	return v.CreatedAt, v.ID
+}
+
+// DriverListSpec is what a fleet's drivers can be filtered and sorted by
+var DriverListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"user_id":   {Column: "user_id", Kind: pagination.ID},
+		"joined_at": {Column: "joined_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-joined_at",
+}
+
+// SortKey returns the value of a DriverListSpec sort field and the fleet
+// membership's ID
+func (d *FleetDriver) SortKey(field string) (interface{}, uuid.UUID) {
+	return d.JoinedAt, d.ID
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"truckify/services/fleet/internal/model"
+	"truckify/shared/pkg/pagination"
 )
 
 var (
@@ -25,10 +26,11 @@ func New(db *sql.DB) *Repository {
 }
 
 // Fleet operations
-func (r *Repository) CreateFleet(ownerID uuid.UUID, req *model.CreateFleetRequest) (*model.Fleet, error) {
+func (r *Repository) CreateFleet(ownerID, orgID uuid.UUID, req *model.CreateFleetRequest) (*model.Fleet, error) {
 	fleet := &model.Fleet{
 		ID:        uuid.New(),
 		OwnerID:   ownerID,
+		OrgID:     orgID,
 		Name:      req.Name,
 		ABN:       req.ABN,
 		Status:    "active",
@@ -36,20 +38,22 @@ func (r *Repository) CreateFleet(ownerID uuid.UUID, req *model.CreateFleetReques
 		UpdatedAt: time.Now(),
 	}
 
-	_, err := r.db.Exec(`INSERT INTO fleets (id, owner_id, name, abn, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		fleet.ID, fleet.OwnerID, fleet.Name, fleet.ABN, fleet.Status, fleet.CreatedAt, fleet.UpdatedAt)
+	_, err := r.db.Exec(`INSERT INTO fleets (id, owner_id, org_id, name, abn, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		fleet.ID, fleet.OwnerID, fleet.OrgID, fleet.Name, fleet.ABN, fleet.Status, fleet.CreatedAt, fleet.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return fleet, nil
 }
 
-func (r *Repository) GetFleetByOwner(ownerID uuid.UUID) (*model.Fleet, error) {
+// GetFleetByOrg returns the organisation's fleet, the first it created if
+// there are several
+func (r *Repository) GetFleetByOrg(orgID uuid.UUID) (*model.Fleet, error) {
 	fleet := &model.Fleet{}
-	err := r.db.QueryRow(`SELECT id, owner_id, name, abn, status, created_at, updated_at 
-		FROM fleets WHERE owner_id = $1`, ownerID).Scan(
-		&fleet.ID, &fleet.OwnerID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
+	err := r.db.QueryRow(`SELECT id, owner_id, org_id, name, abn, status, created_at, updated_at 
+		FROM fleets WHERE org_id = $1 ORDER BY created_at LIMIT 1`, orgID).Scan(
+		&fleet.ID, &fleet.OwnerID, &fleet.OrgID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
@@ -58,9 +62,9 @@ func (r *Repository) GetFleetByOwner(ownerID uuid.UUID) (*model.Fleet, error) {
 
 func (r *Repository) GetFleetByID(id uuid.UUID) (*model.Fleet, error) {
 	fleet := &model.Fleet{}
-	err := r.db.QueryRow(`SELECT id, owner_id, name, abn, status, created_at, updated_at 
+	err := r.db.QueryRow(`SELECT id, owner_id, org_id, name, abn, status, created_at, updated_at 
 		FROM fleets WHERE id = $1`, id).Scan(
-		&fleet.ID, &fleet.OwnerID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
+		&fleet.ID, &fleet.OwnerID, &fleet.OrgID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
@@ -99,12 +103,15 @@ func (r *Repository) CreateVehicle(fleetID uuid.UUID, req *model.CreateVehicleRe
 	return v, nil
 }
 
-func (r *Repository) GetFleetVehicles(fleetID uuid.UUID) ([]*model.FleetVehicle, error) {
-	rows, err := r.db.Query(`SELECT id, fleet_id, current_driver_id, type, make, model, year, plate, vin, 
+// GetFleetVehicles returns a page of a fleet's vehicles and the cursor of
+// the next page
+func (r *Repository) GetFleetVehicles(fleetID uuid.UUID, page pagination.Page) ([]*model.FleetVehicle, string, error) {
+	query, args := page.Apply(`SELECT id, fleet_id, current_driver_id, type, make, model, year, plate, vin, 
 		capacity, rego_expiry, insurance_expiry, status, current_location, created_at, updated_at
-		FROM fleet_vehicles WHERE fleet_id = $1 ORDER BY created_at DESC`, fleetID)
+		FROM fleet_vehicles WHERE fleet_id = $1`, []interface{}{fleetID})
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -117,7 +124,7 @@ func (r *Repository) GetFleetVehicles(fleetID uuid.UUID) ([]*model.FleetVehicle,
 		err := rows.Scan(&v.ID, &v.FleetID, &driverID, &v.Type, &v.Make, &v.Model, &v.Year, &v.Plate, &vin,
 			&v.Capacity, &v.RegoExpiry, &v.InsuranceExpiry, &v.Status, &locationJSON, &v.CreatedAt, &v.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		if driverID.Valid {
 			id, _ := uuid.Parse(driverID.String)
@@ -131,7 +138,8 @@ func (r *Repository) GetFleetVehicles(fleetID uuid.UUID) ([]*model.FleetVehicle,
 		}
 		vehicles = append(vehicles, v)
 	}
-	return vehicles, nil
+	vehicles, next := pagination.Cut(page, vehicles, (*model.FleetVehicle).SortKey)
+	return vehicles, next, nil
 }
 
 func (r *Repository) GetVehicle(id uuid.UUID) (*model.FleetVehicle, error) {
@@ -195,11 +203,14 @@ func (r *Repository) AddDriver(fleetID uuid.UUID, req *model.AddDriverRequest) (
 	return fd, nil
 }
 
-func (r *Repository) GetFleetDrivers(fleetID uuid.UUID) ([]*model.FleetDriver, error) {
-	rows, err := r.db.Query(`SELECT id, fleet_id, driver_id, user_id, status, joined_at 
-		FROM fleet_drivers WHERE fleet_id = $1 AND status = 'active' ORDER BY joined_at DESC`, fleetID)
+// GetFleetDrivers returns a page of a fleet's active drivers and the
+// cursor of the next page
+func (r *Repository) GetFleetDrivers(fleetID uuid.UUID, page pagination.Page) ([]*model.FleetDriver, string, error) {
+	query, args := page.Apply(`SELECT id, fleet_id, driver_id, user_id, status, joined_at 
+		FROM fleet_drivers WHERE fleet_id = $1 AND status = 'active'`, []interface{}{fleetID})
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -207,11 +218,12 @@ func (r *Repository) GetFleetDrivers(fleetID uuid.UUID) ([]*model.FleetDriver, e
 	for rows.Next() {
 		d := &model.FleetDriver{}
 		if err := rows.Scan(&d.ID, &d.FleetID, &d.DriverID, &d.UserID, &d.Status, &d.JoinedAt); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		drivers = append(drivers, d)
 	}
-	return drivers, nil
+	drivers, next := pagination.Cut(page, drivers, (*model.FleetDriver).SortKey)
+	return drivers, next, nil
 }
 
 func (r *Repository) RemoveDriver(fleetID, driverID uuid.UUID) error {
@@ -219,12 +231,13 @@ func (r *Repository) RemoveDriver(fleetID, driverID uuid.UUID) error {
 	return err
 }
 
-func (r *Repository) GetDriverFleet(driverID uuid.UUID) (*model.Fleet, error) {
+// GetDriverFleet returns the fleet the driver with the user ID drives for
+func (r *Repository) GetDriverFleet(userID uuid.UUID) (*model.Fleet, error) {
 	fleet := &model.Fleet{}
-	err := r.db.QueryRow(`SELECT f.id, f.owner_id, f.name, f.abn, f.status, f.created_at, f.updated_at 
+	err := r.db.QueryRow(`SELECT f.id, f.owner_id, f.org_id, f.name, f.abn, f.status, f.created_at, f.updated_at 
 		FROM fleets f JOIN fleet_drivers fd ON f.id = fd.fleet_id 
-		WHERE fd.driver_id = $1 AND fd.status = 'active'`, driverID).Scan(
-		&fleet.ID, &fleet.OwnerID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
+		WHERE fd.user_id = $1 AND fd.status = 'active'`, userID).Scan(
+		&fleet.ID, &fleet.OwnerID, &fleet.OrgID, &fleet.Name, &fleet.ABN, &fleet.Status, &fleet.CreatedAt, &fleet.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"truckify/services/fleet/internal/model"
 	"truckify/services/fleet/internal/repository"
+	"truckify/shared/pkg/pagination"
 )
 
 type Service struct {
@@ -14,12 +15,13 @@ func New(repo *repository.Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) CreateFleet(ownerID uuid.UUID, req *model.CreateFleetRequest) (*model.Fleet, error) {
-	return s.repo.CreateFleet(ownerID, req)
+func (s *Service) CreateFleet(ownerID, orgID uuid.UUID, req *model.CreateFleetRequest) (*model.Fleet, error) {
+	return s.repo.CreateFleet(ownerID, orgID, req)
 }
 
-func (s *Service) GetMyFleet(ownerID uuid.UUID) (*model.Fleet, error) {
-	return s.repo.GetFleetByOwner(ownerID)
+// GetMyFleet returns the fleet of the organisation the caller acts for
+func (s *Service) GetMyFleet(orgID uuid.UUID) (*model.Fleet, error) {
+	return s.repo.GetFleetByOrg(orgID)
 }
 
 func (s *Service) GetFleet(id uuid.UUID) (*model.Fleet, error) {
@@ -30,8 +32,8 @@ func (s *Service) CreateVehicle(fleetID uuid.UUID, req *model.CreateVehicleReque
 	return s.repo.CreateVehicle(fleetID, req)
 }
 
-func (s *Service) GetFleetVehicles(fleetID uuid.UUID) ([]*model.FleetVehicle, error) {
-	return s.repo.GetFleetVehicles(fleetID)
+func (s *Service) GetFleetVehicles(fleetID uuid.UUID, page pagination.Page) ([]*model.FleetVehicle, string, error) {
+	return s.repo.GetFleetVehicles(fleetID, page)
 }
 
 func (s *Service) GetVehicle(id uuid.UUID) (*model.FleetVehicle, error) {
@@ -54,16 +56,17 @@ func (s *Service) AddDriver(fleetID uuid.UUID, req *model.AddDriverRequest) (*mo
 	return s.repo.AddDriver(fleetID, req)
 }
 
-func (s *Service) GetFleetDrivers(fleetID uuid.UUID) ([]*model.FleetDriver, error) {
-	return s.repo.GetFleetDrivers(fleetID)
+func (s *Service) GetFleetDrivers(fleetID uuid.UUID, page pagination.Page) ([]*model.FleetDriver, string, error) {
+	return s.repo.GetFleetDrivers(fleetID, page)
 }
 
 func (s *Service) RemoveDriver(fleetID, driverID uuid.UUID) error {
 	return s.repo.RemoveDriver(fleetID, driverID)
 }
 
-func (s *Service) GetDriverFleet(driverID uuid.UUID) (*model.Fleet, error) {
-	return s.repo.GetDriverFleet(driverID)
+// GetDriverFleet returns the fleet the driver with the user ID drives for
+func (s *Service) GetDriverFleet(userID uuid.UUID) (*model.Fleet, error) {
+	return s.repo.GetDriverFleet(userID)
 }
 
 func (s *Service) RequestHandover(req *model.HandoverRequest, fromDriverID *uuid.UUID) (*model.VehicleHandover, error) {
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS vehicle_handovers;
+DROP TABLE IF EXISTS fleet_drivers;
+DROP TABLE IF EXISTS fleet_vehicles;
+DROP TABLE IF EXISTS fleets;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_fleets_org_id;
+ALTER TABLE fleets DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,8 @@
+-- Fleets belong to the organisation the operator acted for when creating
+-- them. Every user's personal organisation shares their ID, so existing
+-- fleets move to their owner's personal organisation.
+ALTER TABLE fleets ADD COLUMN IF NOT EXISTS org_id UUID;
+UPDATE fleets SET org_id = owner_id WHERE org_id IS NULL;
+ALTER TABLE fleets ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_fleets_org_id ON fleets(org_id);
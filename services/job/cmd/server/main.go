@@ -13,16 +13,28 @@ import (
 	"truckify/services/job/internal/handler"
 	"truckify/services/job/internal/repository"
 	"truckify/services/job/internal/service"
+	"truckify/services/job/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/idempotency"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("job-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Job Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "job-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -36,16 +48,52 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
+	// Relay outbox events to NATS
+	if natsURL := config.GetEnv("NATS_URL", ""); natsURL != "" {
+		broker, err := events.NewNATSBroker(natsURL, "job-service", log)
+		if err != nil {
+			log.Fatal("Failed to connect to NATS", "error", err)
+		}
+		defer broker.Close()
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go events.NewRelay(db, broker, log).Run(relayCtx)
+		log.Info("Connected to NATS")
+	}
+
+	// Replay responses to retried requests that carry an Idempotency-Key
+	idempotencyStore := idempotency.NewPostgresStore(db)
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go idempotencyStore.Run(purgeCtx, log)
+
 	repo := repository.New(db)
 	svc := service.New(repo)
 	h := handler.New(svc)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("job-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
+	router.Use(idempotency.Middleware(idempotencyStore, log))
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "job-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/job/internal/model"
+	"truckify/services/job/internal/repository"
+	"truckify/shared/pkg/authz"
+)
+
+// jobShipper resolves the shipper who posted the job and the organisation
+// that owns it as its owners
+func (h *Handler) jobShipper(r *http.Request) ([]uuid.UUID, error) {
+	job, err := h.targetJob(r)
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{job.ShipperID, job.OrgID}, nil
+}
+
+// jobDriver resolves the assigned driver as the owner of the job's deliveries
+func (h *Handler) jobDriver(r *http.Request) ([]uuid.UUID, error) {
+	job, err := h.targetJob(r)
+	if err != nil {
+		return nil, err
+	}
+	if job.DriverID == nil {
+		return nil, nil
+	}
+	return []uuid.UUID{*job.DriverID}, nil
+}
+
+func (h *Handler) targetJob(r *http.Request) (*model.Job, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	job, err := h.svc.GetJob(id)
+	if err == repository.ErrNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	return job, err
+}
@@ -1,48 +1,61 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"truckify/services/job/internal/model"
 	"truckify/services/job/internal/repository"
+	"truckify/services/job/internal/service"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
 
 type ServiceInterface interface {
-	CreateJob(shipperID uuid.UUID, req *model.CreateJobRequest) (*model.Job, error)
+	CreateJob(ctx context.Context, shipperID, orgID uuid.UUID, req *model.CreateJobRequest, actor model.Actor) (*model.Job, error)
 	GetJob(id uuid.UUID) (*model.Job, error)
-	ListJobs(filter model.JobFilter) ([]*model.Job, error)
-	UpdateJob(id uuid.UUID, req *model.UpdateJobRequest) (*model.Job, error)
-	AssignDriver(jobID, driverID uuid.UUID) error
-	UpdateStatus(id uuid.UUID, status string) (*model.Job, error)
+	ListJobs(filter model.JobFilter) ([]*model.Job, string, error)
+	UpdateJob(ctx context.Context, id uuid.UUID, req *model.UpdateJobRequest, actor model.Actor) (*model.Job, error)
+	AssignDriver(ctx context.Context, jobID, driverID uuid.UUID, actor model.Actor) error
+	TransitionStatus(ctx context.Context, id uuid.UUID, status string, actor model.Actor, req *model.TransitionRequest) (*model.Job, error)
+	GetHistory(id uuid.UUID) ([]*model.StatusHistory, error)
 	DeleteJob(id uuid.UUID) error
 }
 
+// maxBatchIDs caps the IDs accepted by one list request
+const maxBatchIDs = 100
+
 type Handler struct {
-	svc ServiceInterface
-	val *validator.Validator
+	svc   ServiceInterface
+	val   *validator.Validator
+	authz *authz.Authorizer
 }
 
 func New(svc ServiceInterface) *Handler {
-	return &Handler{svc: svc, val: validator.New()}
+	return &Handler{svc: svc, val: validator.New(), authz: authz.New(authz.DefaultPolicy())}
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/jobs/all", h.ListAllJobs).Methods("GET")
-	r.HandleFunc("/jobs", h.CreateJob).Methods("POST")
-	r.HandleFunc("/jobs", h.ListJobs).Methods("GET")
-	r.HandleFunc("/jobs/{id}", h.GetJob).Methods("GET")
-	r.HandleFunc("/jobs/{id}", h.UpdateJob).Methods("PUT")
-	r.HandleFunc("/jobs/{id}", h.DeleteJob).Methods("DELETE")
-	r.HandleFunc("/jobs/{id}/assign", h.AssignDriver).Methods("POST")
-	r.HandleFunc("/jobs/{id}/pickup", h.MarkPickedUp).Methods("POST")
-	r.HandleFunc("/jobs/{id}/deliver", h.MarkDelivered).Methods("POST")
-	r.HandleFunc("/jobs/{id}/cancel", h.CancelJob).Methods("POST")
+	r.HandleFunc("/jobs/all", h.authz.RequireUnscoped(authz.JobRead, h.ListAllJobs)).Methods("GET")
+	r.HandleFunc("/jobs", h.authz.RequireUnscoped(authz.JobCreate, h.CreateJob)).Methods("POST")
+	r.HandleFunc("/jobs", h.authz.RequireUnscoped(authz.JobRead, h.ListJobs)).Methods("GET")
+	r.HandleFunc("/jobs/{id}", h.authz.RequireUnscoped(authz.JobRead, h.GetJob)).Methods("GET")
+	r.HandleFunc("/jobs/{id}", h.authz.RequireOwner(authz.JobUpdate, h.jobShipper, h.UpdateJob)).Methods("PUT")
+	r.HandleFunc("/jobs/{id}", h.authz.RequireOwner(authz.JobDelete, h.jobShipper, h.DeleteJob)).Methods("DELETE")
+	r.HandleFunc("/jobs/{id}/assign", h.authz.RequireOwner(authz.JobAssign, h.jobShipper, h.AssignDriver)).Methods("POST")
+	r.HandleFunc("/jobs/{id}/pickup", h.authz.RequireOwner(authz.JobTransition, h.jobDriver, h.MarkPickedUp)).Methods("POST")
+	r.HandleFunc("/jobs/{id}/deliver", h.authz.RequireOwner(authz.JobTransition, h.jobDriver, h.MarkDelivered)).Methods("POST")
+	r.HandleFunc("/jobs/{id}/cancel", h.authz.RequireOwner(authz.JobCancel, h.jobShipper, h.CancelJob)).Methods("POST")
+	r.HandleFunc("/jobs/{id}/fail", h.authz.RequireOwner(authz.JobTransition, h.jobDriver, h.FailJob)).Methods("POST")
+	r.HandleFunc("/jobs/{id}/history", h.authz.RequireUnscoped(authz.JobRead, h.GetHistory)).Methods("GET")
 	r.HandleFunc("/health", h.Health).Methods("GET")
 }
 
@@ -50,6 +63,21 @@ func (h *Handler) getUserID(r *http.Request) (uuid.UUID, error) {
 	return uuid.Parse(r.Header.Get("X-User-ID"))
 }
 
+// getActor identifies the caller for status history entries. A dispatcher
+// acting for a user is the actor, on behalf of that user.
+func (h *Handler) getActor(r *http.Request) model.Actor {
+	actor := model.Actor{Type: r.Header.Get("X-User-Type")}
+	if id, err := h.getUserID(r); err == nil {
+		actor.ID = &id
+	}
+	if actorID, err := uuid.Parse(r.Header.Get("X-Actor-ID")); err == nil {
+		actor.OnBehalfOf = actor.ID
+		actor.ID = &actorID
+		actor.Type = r.Header.Get("X-Actor-Type")
+	}
+	return actor
+}
+
 func (h *Handler) reqID(r *http.Request) string {
 	if id, ok := r.Context().Value("request_id").(string); ok {
 		return id
@@ -78,7 +106,13 @@ func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	job, err := h.svc.CreateJob(userID, &req)
+	// The job belongs to the organisation the shipper is acting for
+	orgID := userID
+	if principal, ok := authz.PrincipalFrom(r.Context()); ok {
+		orgID = principal.Org()
+	}
+
+	job, err := h.svc.CreateJob(r.Context(), userID, orgID, &req, h.getActor(r))
 	if err != nil {
 		response.InternalServerError(w, "create failed", err.Error(), reqID)
 		return
@@ -108,24 +142,42 @@ func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
 	reqID := h.reqID(r)
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	filter := model.JobFilter{
-		Status:      r.URL.Query().Get("status"),
-		VehicleType: r.URL.Query().Get("vehicle_type"),
-		Limit:       limit,
+	page, err := pagination.Parse(r.URL.Query(), model.JobListSpec)
+	if err != nil {
+		response.BadRequest(w, "invalid list parameters", err.Error(), reqID)
+		return
+	}
+	filter := model.JobFilter{Page: page}
+	if raw := r.URL.Query().Get("ids"); raw != "" {
+		parts := strings.Split(raw, ",")
+		if len(parts) > maxBatchIDs {
+			response.BadRequest(w, "too many ids", "at most "+strconv.Itoa(maxBatchIDs), reqID)
+			return
+		}
+		for _, part := range parts {
+			id, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				response.BadRequest(w, "invalid id", part, reqID)
+				return
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
 	}
 
-	jobs, err := h.svc.ListJobs(filter)
+	jobs, next, err := h.svc.ListJobs(filter)
 	if err != nil {
 		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
 		return
 	}
-	response.Success(w, jobs, reqID)
+	response.List(w, jobs, next, reqID)
 }
 
+// ListAllJobs returns the latest jobs in one response for internal reports
 func (h *Handler) ListAllJobs(w http.ResponseWriter, r *http.Request) {
 	reqID := h.reqID(r)
-	jobs, err := h.svc.ListJobs(model.JobFilter{Limit: 1000})
+	page, _ := pagination.Parse(nil, model.JobListSpec)
+	page.Limit = 1000
+	jobs, _, err := h.svc.ListJobs(model.JobFilter{Page: page})
 	if err != nil {
 		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
 		return
@@ -154,11 +206,15 @@ func (h *Handler) UpdateJob(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	job, err := h.svc.UpdateJob(id, &req)
+	job, err := h.svc.UpdateJob(r.Context(), id, &req, h.getActor(r))
 	if err == repository.ErrNotFound {
 		response.NotFound(w, "job not found", "", reqID)
 		return
 	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		response.InvalidTransition(w, "invalid status transition", err.Error(), reqID)
+		return
+	}
 	if err != nil {
 		response.InternalServerError(w, "update failed", err.Error(), reqID)
 		return
@@ -188,7 +244,13 @@ func (h *Handler) AssignDriver(w http.ResponseWriter, r *http.Request) {
 	}
 
 	driverID, _ := uuid.Parse(req.DriverID)
-	if err := h.svc.AssignDriver(jobID, driverID); err != nil {
+	if err := h.svc.AssignDriver(r.Context(), jobID, driverID, h.getActor(r)); err == repository.ErrNotFound {
+		response.NotFound(w, "job not found", "", reqID)
+		return
+	} else if errors.Is(err, service.ErrInvalidTransition) {
+		response.InvalidTransition(w, "invalid status transition", err.Error(), reqID)
+		return
+	} else if err != nil {
 		response.InternalServerError(w, "assign failed", err.Error(), reqID)
 		return
 	}
@@ -218,36 +280,52 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) MarkPickedUp(w http.ResponseWriter, r *http.Request) {
-	reqID := h.reqID(r)
-	id, err := uuid.Parse(mux.Vars(r)["id"])
-	if err != nil {
-		response.BadRequest(w, "invalid id", "", reqID)
-		return
-	}
-	job, err := h.svc.UpdateStatus(id, "in_transit")
-	if err == repository.ErrNotFound {
-		response.NotFound(w, "job not found", "", reqID)
-		return
-	}
-	if err != nil {
-		response.InternalServerError(w, "update failed", err.Error(), reqID)
-		return
-	}
-	response.Success(w, job, reqID)
+	h.transition(w, r, model.StatusInTransit)
 }
 
 func (h *Handler) MarkDelivered(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, model.StatusDelivered)
+}
+
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, model.StatusCancelled)
+}
+
+func (h *Handler) FailJob(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, model.StatusFailed)
+}
+
+// transition applies a status change with an optional reason and location body
+func (h *Handler) transition(w http.ResponseWriter, r *http.Request, status string) {
 	reqID := h.reqID(r)
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
 		response.BadRequest(w, "invalid id", "", reqID)
 		return
 	}
-	job, err := h.svc.UpdateStatus(id, "delivered")
+
+	var req model.TransitionRequest
+	if r.ContentLength > 0 {
+		if h.val != nil {
+			if err := h.val.DecodeAndValidate(r, &req); err != nil {
+				response.BadRequest(w, "validation error", err.Error(), reqID)
+				return
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, "validation error", err.Error(), reqID)
+			return
+		}
+	}
+
+	job, err := h.svc.TransitionStatus(r.Context(), id, status, h.getActor(r), &req)
 	if err == repository.ErrNotFound {
 		response.NotFound(w, "job not found", "", reqID)
 		return
 	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		response.InvalidTransition(w, "invalid status transition", err.Error(), reqID)
+		return
+	}
 	if err != nil {
 		response.InternalServerError(w, "update failed", err.Error(), reqID)
 		return
@@ -255,21 +333,22 @@ func (h *Handler) MarkDelivered(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, job, reqID)
 }
 
-func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	reqID := h.reqID(r)
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
 		response.BadRequest(w, "invalid id", "", reqID)
 		return
 	}
-	job, err := h.svc.UpdateStatus(id, "cancelled")
+
+	history, err := h.svc.GetHistory(id)
 	if err == repository.ErrNotFound {
 		response.NotFound(w, "job not found", "", reqID)
 		return
 	}
 	if err != nil {
-		response.InternalServerError(w, "update failed", err.Error(), reqID)
+		response.InternalServerError(w, "fetch failed", err.Error(), reqID)
 		return
 	}
-	response.Success(w, job, reqID)
+	response.Success(w, history, reqID)
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,15 +13,27 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/job/internal/model"
 	"truckify/services/job/internal/repository"
+	"truckify/services/job/internal/service"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/openapi"
 )
 
 type mockService struct {
-	job  *model.Job
-	jobs []*model.Job
-	err  error
+	job        *model.Job
+	jobs       []*model.Job
+	history    []*model.StatusHistory
+	err        error
+	lastStatus string
+	lastActor  model.Actor
+	lastReq    *model.TransitionRequest
+	lastFilter model.JobFilter
+	lastOrgID  uuid.UUID
+	next       string
+	lastCtx    context.Context
 }
 
-func (m *mockService) CreateJob(shipperID uuid.UUID, req *model.CreateJobRequest) (*model.Job, error) {
+func (m *mockService) CreateJob(ctx context.Context, shipperID, orgID uuid.UUID, req *model.CreateJobRequest, actor model.Actor) (*model.Job, error) {
+	m.lastCtx, m.lastOrgID, m.lastActor = ctx, orgID, actor
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -34,21 +47,22 @@ func (m *mockService) GetJob(id uuid.UUID) (*model.Job, error) {
 	return m.job, nil
 }
 
-func (m *mockService) ListJobs(filter model.JobFilter) ([]*model.Job, error) {
+func (m *mockService) ListJobs(filter model.JobFilter) ([]*model.Job, string, error) {
+	m.lastFilter = filter
 	if m.err != nil {
-		return nil, m.err
+		return nil, "", m.err
 	}
-	return m.jobs, nil
+	return m.jobs, m.next, nil
 }
 
-func (m *mockService) UpdateJob(id uuid.UUID, req *model.UpdateJobRequest) (*model.Job, error) {
+func (m *mockService) UpdateJob(ctx context.Context, id uuid.UUID, req *model.UpdateJobRequest, actor model.Actor) (*model.Job, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
 	return m.job, nil
 }
 
-func (m *mockService) AssignDriver(jobID, driverID uuid.UUID) error {
+func (m *mockService) AssignDriver(ctx context.Context, jobID, driverID uuid.UUID, actor model.Actor) error {
 	return m.err
 }
 
@@ -56,13 +70,21 @@ func (m *mockService) DeleteJob(id uuid.UUID) error {
 	return m.err
 }
 
-func (m *mockService) UpdateStatus(id uuid.UUID, status string) (*model.Job, error) {
+func (m *mockService) TransitionStatus(ctx context.Context, id uuid.UUID, status string, actor model.Actor, req *model.TransitionRequest) (*model.Job, error) {
+	m.lastCtx, m.lastStatus, m.lastActor, m.lastReq = ctx, status, actor, req
 	if m.err != nil {
 		return nil, m.err
 	}
 	return m.job, nil
 }
 
+func (m *mockService) GetHistory(id uuid.UUID) ([]*model.StatusHistory, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.history, nil
+}
+
 func TestJobHealth(t *testing.T) {
 	h := &Handler{svc: &mockService{}, val: nil}
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -92,6 +114,7 @@ func TestCreateJob_Success(t *testing.T) {
 	req := httptest.NewRequest("POST", "/jobs", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-User-ID", uuid.New().String())
+	req = req.WithContext(context.WithValue(req.Context(), "request_id", "req-1"))
 	w := httptest.NewRecorder()
 
 	h.CreateJob(w, req)
@@ -99,6 +122,84 @@ func TestCreateJob_Success(t *testing.T) {
 	if w.Code != http.StatusCreated {
 		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
 	}
+	// The job.created event carries the request ID
+	if id, _ := mock.lastCtx.Value("request_id").(string); id != "req-1" {
+		t.Errorf("expected the request context, got request ID %q", id)
+	}
+}
+
+func TestCreateJob_OwnedByOrganisation(t *testing.T) {
+	userID := uuid.New()
+	mock := &mockService{job: &model.Job{ID: uuid.New()}}
+	h := &Handler{svc: mock, val: nil, authz: authz.New(authz.DefaultPolicy())}
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	body := `{"pickup_city":"Sydney","pickup_state":"NSW","delivery_city":"Melbourne","delivery_state":"VIC","pickup_date":"2026-01-15","delivery_date":"2026-01-16","cargo_type":"general","weight":15000,"vehicle_type":"flatbed","price":2500}`
+	create := func(orgID string) {
+		req := httptest.NewRequest("POST", "/jobs", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", userID.String())
+		req.Header.Set("X-User-Type", "shipper")
+		if orgID != "" {
+			req.Header.Set("X-Org-ID", orgID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Without an organisation the job goes to the shipper's personal one
+	create("")
+	if mock.lastOrgID != userID {
+		t.Errorf("expected personal organisation %s, got %s", userID, mock.lastOrgID)
+	}
+	orgID := uuid.New()
+	create(orgID.String())
+	if mock.lastOrgID != orgID {
+		t.Errorf("expected organisation %s, got %s", orgID, mock.lastOrgID)
+	}
+}
+
+func TestCreateJob_DispatcherActingForShipper(t *testing.T) {
+	shipperID, dispatcherID := uuid.New(), uuid.New()
+	mock := &mockService{job: &model.Job{ID: uuid.New()}}
+	h := &Handler{svc: mock, val: nil, authz: authz.New(authz.DefaultPolicy())}
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	body := `{"pickup_city":"Sydney","pickup_state":"NSW","delivery_city":"Melbourne","delivery_state":"VIC","pickup_date":"2026-01-15","delivery_date":"2026-01-16","cargo_type":"general","weight":15000,"vehicle_type":"flatbed","price":2500}`
+	create := func(permissions string) int {
+		req := httptest.NewRequest("POST", "/jobs", bytes.NewBufferString(body))
+		req.Header.Set("X-User-ID", shipperID.String())
+		req.Header.Set("X-User-Type", "shipper")
+		req.Header.Set("X-Actor-ID", dispatcherID.String())
+		req.Header.Set("X-Actor-Type", "dispatcher")
+		req.Header.Set("X-Permissions", permissions)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := create("job:create"); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	// The history records the dispatcher, on behalf of the shipper
+	if mock.lastActor.ID == nil || *mock.lastActor.ID != dispatcherID || mock.lastActor.Type != "dispatcher" {
+		t.Errorf("unexpected actor %+v", mock.lastActor)
+	}
+	if mock.lastActor.OnBehalfOf == nil || *mock.lastActor.OnBehalfOf != shipperID {
+		t.Errorf("expected on behalf of %s, got %v", shipperID, mock.lastActor.OnBehalfOf)
+	}
+	if mock.lastOrgID != shipperID {
+		t.Errorf("expected the shipper's organisation, got %s", mock.lastOrgID)
+	}
+
+	// Without the post_jobs right the delegation grants nothing here
+	if code := create("bid:accept"); code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", code)
+	}
 }
 
 func TestCreateJob_Unauthorized(t *testing.T) {
@@ -154,10 +255,11 @@ func TestListJobs_Success(t *testing.T) {
 			{ID: uuid.New(), Status: "pending", Price: 1500},
 			{ID: uuid.New(), Status: "assigned", Price: 2000},
 		},
+		next: "next-page",
 	}
 	h := &Handler{svc: mock, val: nil}
 
-	req := httptest.NewRequest("GET", "/jobs?status=pending", nil)
+	req := httptest.NewRequest("GET", "/jobs?status=pending&price[gte]=1000&sort=price", nil)
 	w := httptest.NewRecorder()
 
 	h.ListJobs(w, req)
@@ -171,6 +273,44 @@ func TestListJobs_Success(t *testing.T) {
 	if resp["data"] == nil {
 		t.Error("expected data in response")
 	}
+	if meta := resp["metadata"].(map[string]interface{}); meta["next_cursor"] != "next-page" || meta["has_more"] != true {
+		t.Errorf("expected the next cursor in the metadata, got %v", meta)
+	}
+	if page := mock.lastFilter.Page; len(page.Filters) != 2 || page.Sort.Field != "price" {
+		t.Errorf("expected two filters sorted by price, got %+v", page)
+	}
+}
+
+func TestListJobs_InvalidParameters(t *testing.T) {
+	h := &Handler{svc: &mockService{}, val: nil}
+
+	for _, query := range []string{"sort=notes", "price=free", "limit=-1", "cursor=bogus"} {
+		req := httptest.NewRequest("GET", "/jobs?"+query, nil)
+		w := httptest.NewRecorder()
+		h.ListJobs(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestListJobs_IDs(t *testing.T) {
+	mock := &mockService{jobs: []*model.Job{{ID: uuid.New()}}}
+	h := &Handler{svc: mock, val: nil}
+
+	req := httptest.NewRequest("GET", "/jobs?ids="+uuid.New().String()+","+uuid.New().String(), nil)
+	w := httptest.NewRecorder()
+	h.ListJobs(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/jobs?ids=nope", nil)
+	w = httptest.NewRecorder()
+	h.ListJobs(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid id, got %d", w.Code)
+	}
 }
 
 func TestUpdateJob_Success(t *testing.T) {
@@ -262,5 +402,192 @@ func TestGetJob_InvalidID(t *testing.T) {
 	}
 }
 
+func TestCancelJob_InvalidTransition(t *testing.T) {
+	mock := &mockService{err: &service.TransitionError{From: "delivered", To: "cancelled", Reason: "job is in a terminal state"}}
+	h := &Handler{svc: mock, val: nil}
+
+	req := httptest.NewRequest("POST", "/jobs/"+uuid.New().String()+"/cancel", nil)
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/cancel", h.CancelJob).Methods("POST")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	errBody, _ := resp["error"].(map[string]interface{})
+	if errBody["code"] != "INVALID_STATE_TRANSITION" {
+		t.Errorf("expected INVALID_STATE_TRANSITION, got %v", errBody["code"])
+	}
+}
+
+func TestFailJob_WithReason(t *testing.T) {
+	jobID := uuid.New()
+	userID := uuid.New()
+	mock := &mockService{job: &model.Job{ID: jobID, Status: model.StatusFailed}}
+	h := &Handler{svc: mock, val: nil}
+
+	body := `{"reason":"truck broke down","lat":-33.86,"lng":151.2}`
+	req := httptest.NewRequest("POST", "/jobs/"+jobID.String()+"/fail", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userID.String())
+	req.Header.Set("X-User-Type", "driver")
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/fail", h.FailJob).Methods("POST")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if mock.lastStatus != model.StatusFailed {
+		t.Errorf("expected failed, got %s", mock.lastStatus)
+	}
+	if mock.lastActor.ID == nil || *mock.lastActor.ID != userID || mock.lastActor.Type != "driver" {
+		t.Errorf("unexpected actor %+v", mock.lastActor)
+	}
+	if mock.lastReq == nil || mock.lastReq.Reason != "truck broke down" || mock.lastReq.Lat == nil {
+		t.Errorf("unexpected transition request %+v", mock.lastReq)
+	}
+}
+
+func TestGetHistory_Success(t *testing.T) {
+	jobID := uuid.New()
+	mock := &mockService{
+		history: []*model.StatusHistory{
+			{ID: uuid.New(), JobID: jobID, ToStatus: model.StatusPending},
+			{ID: uuid.New(), JobID: jobID, FromStatus: model.StatusPending, ToStatus: model.StatusAssigned},
+		},
+	}
+	h := &Handler{svc: mock, val: nil}
+
+	req := httptest.NewRequest("GET", "/jobs/"+jobID.String()+"/history", nil)
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/history", h.GetHistory).Methods("GET")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, _ := resp["data"].([]interface{}); len(data) != 2 {
+		t.Errorf("expected 2 history entries, got %v", resp["data"])
+	}
+}
+
+func TestGetHistory_NotFound(t *testing.T) {
+	h := &Handler{svc: &mockService{err: repository.ErrNotFound}, val: nil}
+
+	req := httptest.NewRequest("GET", "/jobs/"+uuid.New().String()+"/history", nil)
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/history", h.GetHistory).Methods("GET")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestRoutes_Authorization(t *testing.T) {
+	jobID := uuid.New()
+	shipperID := uuid.New()
+	driverID := uuid.New()
+	orgID := uuid.New()
+	mock := &mockService{job: &model.Job{ID: jobID, ShipperID: shipperID, OrgID: orgID, DriverID: &driverID, Status: model.StatusAssigned}}
+	h := &Handler{svc: mock, val: nil, authz: authz.New(authz.DefaultPolicy())}
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	tests := []struct {
+		name     string
+		path     string
+		userID   uuid.UUID
+		userType string
+		orgID    uuid.UUID
+		want     int
+	}{
+		{"shipper cancels own job", "/cancel", shipperID, "shipper", uuid.Nil, http.StatusOK},
+		{"other shipper cannot cancel", "/cancel", uuid.New(), "shipper", uuid.Nil, http.StatusForbidden},
+		{"organisation member cancels", "/cancel", uuid.New(), "shipper", orgID, http.StatusOK},
+		{"other organisation cannot cancel", "/cancel", uuid.New(), "shipper", uuid.New(), http.StatusForbidden},
+		{"driver cannot cancel", "/cancel", driverID, "driver", uuid.Nil, http.StatusForbidden},
+		{"assigned driver delivers", "/deliver", driverID, "driver", uuid.Nil, http.StatusOK},
+		{"other driver cannot deliver", "/deliver", uuid.New(), "driver", uuid.Nil, http.StatusForbidden},
+		{"shipper cannot deliver", "/deliver", shipperID, "shipper", uuid.Nil, http.StatusForbidden},
+		{"dispatcher cannot cancel with own token", "/cancel", uuid.New(), "dispatcher", uuid.Nil, http.StatusForbidden},
+		{"admin cancels any job", "/cancel", uuid.New(), "admin", uuid.Nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/jobs/"+jobID.String()+tt.path, nil)
+			req.Header.Set("X-User-ID", tt.userID.String())
+			req.Header.Set("X-User-Type", tt.userType)
+			if tt.orgID != uuid.Nil {
+				req.Header.Set("X-Org-ID", tt.orgID.String())
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	New(&mockService{}).RegisterRoutes(router)
+
+	if err := openapi.Check(router, Routes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	jobID, shipperID := uuid.New(), uuid.New()
+	mock := &mockService{
+		job:     &model.Job{ID: jobID, ShipperID: shipperID, Status: model.StatusPending, CreatedAt: time.Now()},
+		jobs:    []*model.Job{{ID: jobID, ShipperID: shipperID, Status: model.StatusPending}},
+		history: []*model.StatusHistory{{ID: uuid.New(), JobID: jobID, ToStatus: model.StatusPending}},
+	}
+	router := mux.NewRouter()
+	New(mock).RegisterRoutes(router)
+	router.Use(openapi.Middleware(openapi.Build(router, openapi.Info{Title: "job-service"}, Routes), func(r *http.Request, err error) {
+		t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+	}))
+
+	requests := []struct{ method, path, body string }{
+		{"POST", "/jobs", `{"pickup_city":"Sydney","pickup_state":"NSW","delivery_city":"Melbourne","delivery_state":"VIC","pickup_date":"2026-01-15","delivery_date":"2026-01-16","cargo_type":"general","weight":15000,"vehicle_type":"flatbed","price":2500}`},
+		{"GET", "/jobs?sort=price", ""},
+		{"GET", "/jobs/" + jobID.String(), ""},
+		{"GET", "/jobs/not-an-id", ""},
+		{"PUT", "/jobs/" + jobID.String(), `{"price":1200}`},
+		{"POST", "/jobs/" + jobID.String() + "/cancel", ""},
+		{"GET", "/jobs/" + jobID.String() + "/history", ""},
+		{"DELETE", "/jobs/" + jobID.String(), ""},
+		{"GET", "/health", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, bytes.NewBufferString(r.body))
+		req.Header.Set("X-User-ID", shipperID.String())
+		req.Header.Set("X-User-Type", "shipper")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
 // Ensure time import is used
 var _ = time.Now
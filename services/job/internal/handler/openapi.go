@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/job/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"GET /jobs/all": {Summary: "List the latest jobs for internal reports", Response: []*model.Job{}},
+	"POST /jobs":    {Summary: "Post a job", Request: model.CreateJobRequest{}, Response: model.Job{}, Status: http.StatusCreated},
+	"GET /jobs": {
+		Summary:  "List jobs",
+		Response: []*model.Job{},
+		List:     &model.JobListSpec,
+		Query: []openapi.Parameter{
+			{Name: "ids", In: "query", Description: "Comma separated job IDs, at most 100", Schema: &openapi.Schema{Type: "string"}},
+		},
+	},
+	"GET /jobs/{id}":          {Summary: "Get a job", Response: model.Job{}},
+	"PUT /jobs/{id}":          {Summary: "Update a job", Request: model.UpdateJobRequest{}, Response: model.Job{}},
+	"DELETE /jobs/{id}":       {Summary: "Delete a job", Response: map[string]string{}},
+	"POST /jobs/{id}/assign":  {Summary: "Assign a driver", Request: model.AssignDriverRequest{}, Response: map[string]string{}},
+	"POST /jobs/{id}/pickup":  {Summary: "Mark a job picked up", Request: model.TransitionRequest{}, OptionalBody: true, Response: model.Job{}},
+	"POST /jobs/{id}/deliver": {Summary: "Mark a job delivered", Request: model.TransitionRequest{}, OptionalBody: true, Response: model.Job{}},
+	"POST /jobs/{id}/cancel":  {Summary: "Cancel a job", Request: model.TransitionRequest{}, OptionalBody: true, Response: model.Job{}},
+	"POST /jobs/{id}/fail":    {Summary: "Mark a job failed", Request: model.TransitionRequest{}, OptionalBody: true, Response: model.Job{}},
+	"GET /jobs/{id}/history":  {Summary: "List a job's status changes", Response: []*model.StatusHistory{}},
+	"GET /health":             {Summary: "Health check", Response: map[string]string{}},
+}
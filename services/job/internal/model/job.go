@@ -4,13 +4,28 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusAssigned  = "assigned"
+	StatusPickedUp  = "picked_up"
+	StatusInTransit = "in_transit"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// Job is a load posted by a shipper. It belongs to the organisation the
+// shipper acted for when posting it, whose members can manage it.
 type Job struct {
 	ID           uuid.UUID  `json:"id"`
 	ShipperID    uuid.UUID  `json:"shipper_id"`
+	OrgID        uuid.UUID  `json:"org_id"`
 	DriverID     *uuid.UUID `json:"driver_id,omitempty"`
-	Status       string     `json:"status"` // pending, assigned, in_transit, delivered, cancelled
+	Status       string     `json:"status"` // pending, assigned, picked_up, in_transit, delivered, cancelled, failed
 	Pickup       Location   `json:"pickup"`
 	Delivery     Location   `json:"delivery"`
 	PickupDate   time.Time  `json:"pickup_date"`
@@ -51,22 +66,83 @@ type CreateJobRequest struct {
 }
 
 type UpdateJobRequest struct {
-	Status       *string  `json:"status" validate:"omitempty,oneof=pending assigned in_transit delivered cancelled"`
+	Status       *string  `json:"status" validate:"omitempty,oneof=pending assigned picked_up in_transit delivered cancelled failed"`
 	PickupDate   *string  `json:"pickup_date"`
 	DeliveryDate *string  `json:"delivery_date"`
 	Price        *float64 `json:"price"`
 	Notes        *string  `json:"notes"`
 }
 
+// TransitionRequest carries optional context for a status change
+type TransitionRequest struct {
+	Reason string   `json:"reason" validate:"max=500"`
+	Lat    *float64 `json:"lat" validate:"omitempty,min=-90,max=90"`
+	Lng    *float64 `json:"lng" validate:"omitempty,min=-180,max=180"`
+}
+
+// Actor identifies who triggered a status change. OnBehalfOf is set when a
+// dispatcher acted for another user under a delegation.
+type Actor struct {
+	ID         *uuid.UUID
+	Type       string
+	OnBehalfOf *uuid.UUID
+}
+
+// StatusHistory records a single job status transition
+type StatusHistory struct {
+	ID         uuid.UUID  `json:"id"`
+	JobID      uuid.UUID  `json:"job_id"`
+	FromStatus string     `json:"from_status,omitempty"`
+	ToStatus   string     `json:"to_status"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty"`
+	ActorType  string     `json:"actor_type,omitempty"`
+	OnBehalfOf *uuid.UUID `json:"on_behalf_of,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Lat        *float64   `json:"lat,omitempty"`
+	Lng        *float64   `json:"lng,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
 type AssignDriverRequest struct {
 	DriverID string `json:"driver_id" validate:"required,uuid"`
 }
 
 type JobFilter struct {
-	Status      string
-	VehicleType string
-	ShipperID   uuid.UUID
-	DriverID    uuid.UUID
-	Limit       int
-	Offset      int
+	// IDs restricts the list to these jobs
+	IDs       []uuid.UUID
+	ShipperID uuid.UUID
+	DriverID  uuid.UUID
+	// Page holds the filters, sort and position requested by the client
+	Page pagination.Page
+}
+
+// JobListSpec is what clients can filter and sort jobs by
+var JobListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"status":        {Column: "status"},
+		"org_id":        {Column: "org_id", Kind: pagination.ID},
+		"vehicle_type":  {Column: "vehicle_type"},
+		"cargo_type":    {Column: "cargo_type"},
+		"price":         {Column: "price", Kind: pagination.Number, Sortable: true},
+		"weight":        {Column: "weight", Kind: pagination.Number, Sortable: true},
+		"pickup_date":   {Column: "pickup_date", Kind: pagination.Time, Sortable: true},
+		"delivery_date": {Column: "delivery_date", Kind: pagination.Time, Sortable: true},
+		"created_at":    {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a JobListSpec sort field and the job's ID
+func (j *Job) SortKey(field string) (interface{}, uuid.UUID) {
+	switch field {
+	case "price":
+		return j.Price, j.ID
+	case "weight":
+		return j.Weight, j.ID
+	case "pickup_date":
+		return j.PickupDate, j.ID
+	case "delivery_date":
+		return j.DeliveryDate, j.ID
+	}
+	return j.CreatedAt, j.ID
 }
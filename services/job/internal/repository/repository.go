@@ -1,16 +1,26 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"truckify/services/job/internal/model"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/pagination"
 )
 
-var ErrNotFound = errors.New("job not found")
+var (
+	ErrNotFound       = errors.New("job not found")
+	ErrStatusConflict = errors.New("job status changed concurrently")
+)
+
+const eventSource = "job-service"
 
 type Repository struct {
 	db *sql.DB
@@ -20,7 +30,8 @@ func New(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(shipperID uuid.UUID, req *model.CreateJobRequest) (*model.Job, error) {
+// Create posts a job for the shipper, owned by the organisation orgID
+func (r *Repository) Create(ctx context.Context, shipperID, orgID uuid.UUID, req *model.CreateJobRequest, actor model.Actor) (*model.Job, error) {
 	id := uuid.New()
 	now := time.Now()
 	pickupDate, _ := time.Parse("2006-01-02", req.PickupDate)
@@ -32,7 +43,7 @@ func (r *Repository) Create(shipperID uuid.UUID, req *model.CreateJobRequest) (*
 	deliveryJSON, _ := json.Marshal(delivery)
 
 	job := &model.Job{
-		ID: id, ShipperID: shipperID, Status: "pending",
+		ID: id, ShipperID: shipperID, OrgID: orgID, Status: "pending",
 		Pickup: pickup, Delivery: delivery,
 		PickupDate: pickupDate, DeliveryDate: deliveryDate,
 		CargoType: req.CargoType, Weight: req.Weight, VehicleType: req.VehicleType,
@@ -40,15 +51,42 @@ func (r *Repository) Create(shipperID uuid.UUID, req *model.CreateJobRequest) (*
 		CreatedAt: now, UpdatedAt: now,
 	}
 
-	_, err := r.db.Exec(`
-		INSERT INTO jobs (id, shipper_id, status, pickup, delivery, pickup_date, delivery_date,
+	evt, err := events.New(ctx, eventSource, events.TypeJobCreated, 1, job.ID.String(), events.JobCreatedV1{
+		JobID: job.ID, ShipperID: job.ShipperID, Status: job.Status,
+		PickupCity: pickup.City, DeliveryCity: delivery.City,
+		VehicleType: job.VehicleType, Price: job.Price, PickupDate: job.PickupDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO jobs (id, shipper_id, org_id, status, pickup, delivery, pickup_date, delivery_date,
 			cargo_type, weight, vehicle_type, price, distance, notes, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
-		job.ID, job.ShipperID, job.Status, pickupJSON, deliveryJSON, job.PickupDate, job.DeliveryDate,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		job.ID, job.ShipperID, job.OrgID, job.Status, pickupJSON, deliveryJSON, job.PickupDate, job.DeliveryDate,
 		job.CargoType, job.Weight, job.VehicleType, job.Price, job.Distance, job.Notes, job.CreatedAt, job.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := events.WriteOutbox(ctx, tx, evt); err != nil {
+		return nil, err
+	}
+	if err := insertHistory(ctx, tx, &model.StatusHistory{
+		ID: uuid.New(), JobID: job.ID, ToStatus: job.Status,
+		ActorID: actor.ID, ActorType: actor.Type, OnBehalfOf: actor.OnBehalfOf, CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return job, nil
 }
 
@@ -58,10 +96,10 @@ func (r *Repository) GetByID(id uuid.UUID) (*model.Job, error) {
 	var driverID sql.NullString
 
 	err := r.db.QueryRow(`
-		SELECT id, shipper_id, driver_id, status, pickup, delivery, pickup_date, delivery_date,
+		SELECT id, shipper_id, org_id, driver_id, status, pickup, delivery, pickup_date, delivery_date,
 			cargo_type, weight, vehicle_type, price, distance, notes, created_at, updated_at
 		FROM jobs WHERE id = $1`, id).Scan(
-		&job.ID, &job.ShipperID, &driverID, &job.Status, &pickupJSON, &deliveryJSON,
+		&job.ID, &job.ShipperID, &job.OrgID, &driverID, &job.Status, &pickupJSON, &deliveryJSON,
 		&job.PickupDate, &job.DeliveryDate, &job.CargoType, &job.Weight, &job.VehicleType,
 		&job.Price, &job.Distance, &job.Notes, &job.CreatedAt, &job.UpdatedAt)
 	if err == sql.ErrNoRows {
@@ -80,43 +118,35 @@ func (r *Repository) GetByID(id uuid.UUID) (*model.Job, error) {
 	return job, nil
 }
 
-func (r *Repository) List(filter model.JobFilter) ([]*model.Job, error) {
-	query := `SELECT id, shipper_id, driver_id, status, pickup, delivery, pickup_date, delivery_date,
+// List returns a page of jobs and the cursor of the next page
+func (r *Repository) List(filter model.JobFilter) ([]*model.Job, string, error) {
+	query := `SELECT id, shipper_id, org_id, driver_id, status, pickup, delivery, pickup_date, delivery_date,
 		cargo_type, weight, vehicle_type, price, distance, notes, created_at, updated_at FROM jobs WHERE 1=1`
 	args := []interface{}{}
-	argNum := 1
-
-	if filter.Status != "" {
-		query += " AND status = $" + string(rune('0'+argNum))
-		args = append(args, filter.Status)
-		argNum++
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
 	}
-	if filter.VehicleType != "" {
-		query += " AND vehicle_type = $" + string(rune('0'+argNum))
-		args = append(args, filter.VehicleType)
-		argNum++
+
+	if len(filter.IDs) > 0 {
+		query += " AND id = ANY(" + arg(pq.Array(filter.IDs)) + ")"
 	}
 	if filter.ShipperID != uuid.Nil {
-		query += " AND shipper_id = $" + string(rune('0'+argNum))
-		args = append(args, filter.ShipperID)
-		argNum++
+		query += " AND shipper_id = " + arg(filter.ShipperID)
 	}
 	if filter.DriverID != uuid.Nil {
-		query += " AND driver_id = $" + string(rune('0'+argNum))
-		args = append(args, filter.DriverID)
-		argNum++
+		query += " AND driver_id = " + arg(filter.DriverID)
 	}
 
-	query += " ORDER BY created_at DESC"
-	if filter.Limit > 0 {
-		query += " LIMIT " + string(rune('0'+filter.Limit))
-	} else {
-		query += " LIMIT 50"
+	page := filter.Page
+	if len(filter.IDs) > page.Limit {
+		page.Limit = len(filter.IDs)
 	}
+	query, args = page.Apply(query, args)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -125,7 +155,7 @@ func (r *Repository) List(filter model.JobFilter) ([]*model.Job, error) {
 		job := &model.Job{}
 		var pickupJSON, deliveryJSON []byte
 		var driverID sql.NullString
-		rows.Scan(&job.ID, &job.ShipperID, &driverID, &job.Status, &pickupJSON, &deliveryJSON,
+		rows.Scan(&job.ID, &job.ShipperID, &job.OrgID, &driverID, &job.Status, &pickupJSON, &deliveryJSON,
 			&job.PickupDate, &job.DeliveryDate, &job.CargoType, &job.Weight, &job.VehicleType,
 			&job.Price, &job.Distance, &job.Notes, &job.CreatedAt, &job.UpdatedAt)
 		json.Unmarshal(pickupJSON, &job.Pickup)
@@ -136,17 +166,22 @@ func (r *Repository) List(filter model.JobFilter) ([]*model.Job, error) {
 		}
 		jobs = append(jobs, job)
 	}
-	return jobs, nil
+	jobs, next := pagination.Cut(page, jobs, (*model.Job).SortKey)
+	return jobs, next, rows.Err()
 }
 
-func (r *Repository) Update(id uuid.UUID, req *model.UpdateJobRequest) (*model.Job, error) {
+// Update applies the requested changes. When entry is non-nil the status
+// change is only applied if the job is still in entry.FromStatus, and the
+// transition is recorded in the history and outbox.
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, req *model.UpdateJobRequest, entry *model.StatusHistory) (*model.Job, error) {
 	job, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	fromStatus := job.Status
 
-	if req.Status != nil {
-		job.Status = *req.Status
+	if entry != nil {
+		job.Status = entry.ToStatus
 	}
 	if req.PickupDate != nil {
 		job.PickupDate, _ = time.Parse("2006-01-02", *req.PickupDate)
@@ -161,16 +196,56 @@ func (r *Repository) Update(id uuid.UUID, req *model.UpdateJobRequest) (*model.J
 		job.Notes = *req.Notes
 	}
 	job.UpdatedAt = time.Now()
+	if entry != nil {
+		fromStatus = entry.FromStatus
+	}
 
-	_, err = r.db.Exec(`UPDATE jobs SET status=$1, pickup_date=$2, delivery_date=$3, price=$4, notes=$5, updated_at=$6 WHERE id=$7`,
-		job.Status, job.PickupDate, job.DeliveryDate, job.Price, job.Notes, job.UpdatedAt, id)
-	return job, err
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE jobs SET status=$1, pickup_date=$2, delivery_date=$3, price=$4, notes=$5, updated_at=$6 WHERE id=$7 AND status=$8`,
+		job.Status, job.PickupDate, job.DeliveryDate, job.Price, job.Notes, job.UpdatedAt, id, fromStatus)
+	if err != nil {
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrStatusConflict
+	}
+	if entry != nil {
+		if err := recordTransition(ctx, tx, job, entry); err != nil {
+			return nil, err
+		}
+	}
+	return job, tx.Commit()
 }
 
-func (r *Repository) AssignDriver(jobID, driverID uuid.UUID) error {
-	_, err := r.db.Exec(`UPDATE jobs SET driver_id=$1, status='assigned', updated_at=$2 WHERE id=$3`,
-		driverID, time.Now(), jobID)
-	return err
+// AssignDriver assigns a driver to a pending job and records the transition
+func (r *Repository) AssignDriver(ctx context.Context, jobID, driverID uuid.UUID, entry *model.StatusHistory) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	job := &model.Job{ID: jobID, DriverID: &driverID, Status: entry.ToStatus, UpdatedAt: entry.CreatedAt}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE jobs SET driver_id=$1, status=$2, updated_at=$3
+		WHERE id=$4 AND status=$5
+		RETURNING shipper_id, org_id`,
+		driverID, job.Status, job.UpdatedAt, jobID, entry.FromStatus).Scan(&job.ShipperID, &job.OrgID)
+	if err == sql.ErrNoRows {
+		return ErrStatusConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordTransition(ctx, tx, job, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) Delete(id uuid.UUID) error {
@@ -185,10 +260,115 @@ func (r *Repository) Delete(id uuid.UUID) error {
 	return nil
 }
 
-func (r *Repository) UpdateStatus(id uuid.UUID, status string) (*model.Job, error) {
-	_, err := r.db.Exec(`UPDATE jobs SET status=$1, updated_at=$2 WHERE id=$3`, status, time.Now(), id)
+// Transition moves a job from entry.FromStatus to entry.ToStatus and records
+// the change. ErrStatusConflict is returned if the job is no longer in FromStatus.
+func (r *Repository) Transition(ctx context.Context, entry *model.StatusHistory) (*model.Job, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetByID(id)
+	defer tx.Rollback()
+
+	job := &model.Job{ID: entry.JobID, Status: entry.ToStatus, UpdatedAt: entry.CreatedAt}
+	var driverID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE jobs SET status=$1, updated_at=$2
+		WHERE id=$3 AND status=$4
+		RETURNING shipper_id, org_id, driver_id`,
+		entry.ToStatus, entry.CreatedAt, entry.JobID, entry.FromStatus).Scan(&job.ShipperID, &job.OrgID, &driverID)
+	if err == sql.ErrNoRows {
+		return nil, ErrStatusConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	if driverID.Valid {
+		uid, _ := uuid.Parse(driverID.String)
+		job.DriverID = &uid
+	}
+	if err := recordTransition(ctx, tx, job, entry); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(entry.JobID)
+}
+
+// GetHistory returns a job's status transitions, oldest first
+func (r *Repository) GetHistory(jobID uuid.UUID) ([]*model.StatusHistory, error) {
+	rows, err := r.db.Query(`
+		SELECT id, job_id, from_status, to_status, actor_id, actor_type, on_behalf_of, reason, latitude, longitude, created_at
+		FROM job_status_history WHERE job_id = $1 ORDER BY created_at, id`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*model.StatusHistory{}
+	for rows.Next() {
+		h := &model.StatusHistory{}
+		var fromStatus, actorType, reason sql.NullString
+		var actorID, onBehalfOf sql.NullString
+		var lat, lng sql.NullFloat64
+		if err := rows.Scan(&h.ID, &h.JobID, &fromStatus, &h.ToStatus, &actorID, &actorType, &onBehalfOf, &reason, &lat, &lng, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.FromStatus = fromStatus.String
+		h.ActorType = actorType.String
+		h.Reason = reason.String
+		if actorID.Valid {
+			uid, _ := uuid.Parse(actorID.String)
+			h.ActorID = &uid
+		}
+		if onBehalfOf.Valid {
+			uid, _ := uuid.Parse(onBehalfOf.String)
+			h.OnBehalfOf = &uid
+		}
+		if lat.Valid && lng.Valid {
+			h.Lat, h.Lng = &lat.Float64, &lng.Float64
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// recordTransition writes the history entry and the outbox event for a status change
+func recordTransition(ctx context.Context, tx *sql.Tx, job *model.Job, entry *model.StatusHistory) error {
+	if err := insertHistory(ctx, tx, entry); err != nil {
+		return err
+	}
+	return writeStatusChanged(ctx, tx, job, entry.FromStatus)
+}
+
+func insertHistory(ctx context.Context, tx *sql.Tx, h *model.StatusHistory) error {
+	var fromStatus sql.NullString
+	if h.FromStatus != "" {
+		fromStatus = sql.NullString{String: h.FromStatus, Valid: true}
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO job_status_history (id, job_id, from_status, to_status, actor_id, actor_type, on_behalf_of, reason, latitude, longitude, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		h.ID, h.JobID, fromStatus, h.ToStatus, h.ActorID, h.ActorType, h.OnBehalfOf, h.Reason, h.Lat, h.Lng, h.CreatedAt)
+	return err
+}
+
+// writeStatusChanged records a job.status_changed event in the outbox within tx
+func writeStatusChanged(ctx context.Context, tx *sql.Tx, job *model.Job, fromStatus string) error {
+	data := events.JobStatusChangedV2{
+		JobID:      job.ID,
+		ShipperID:  job.ShipperID,
+		DriverID:   job.DriverID,
+		FromStatus: fromStatus,
+		ToStatus:   job.Status,
+		ChangedAt:  job.UpdatedAt,
+	}
+	if job.OrgID != uuid.Nil {
+		data.OrgID = &job.OrgID
+	}
+	evt, err := events.New(ctx, eventSource, events.TypeJobStatusChanged, 2, job.ID.String(), data)
+	if err != nil {
+		return err
+	}
+	return events.WriteOutbox(ctx, tx, evt)
 }
@@ -0,0 +1,11 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var jobsCreated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "truckify_jobs_created_total",
+	Help: "Jobs posted by shippers.",
+})
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"truckify/services/job/internal/model"
 	"truckify/services/job/internal/repository"
@@ -14,30 +17,112 @@ func New(repo *repository.Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) CreateJob(shipperID uuid.UUID, req *model.CreateJobRequest) (*model.Job, error) {
-	return s.repo.Create(shipperID, req)
+func (s *Service) CreateJob(ctx context.Context, shipperID, orgID uuid.UUID, req *model.CreateJobRequest, actor model.Actor) (*model.Job, error) {
+	job, err := s.repo.Create(ctx, shipperID, orgID, req, actor)
+	if err != nil {
+		return nil, err
+	}
+	jobsCreated.Inc()
+	return job, nil
 }
 
 func (s *Service) GetJob(id uuid.UUID) (*model.Job, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *Service) ListJobs(filter model.JobFilter) ([]*model.Job, error) {
+func (s *Service) ListJobs(filter model.JobFilter) ([]*model.Job, string, error) {
 	return s.repo.List(filter)
 }
 
-func (s *Service) UpdateJob(id uuid.UUID, req *model.UpdateJobRequest) (*model.Job, error) {
-	return s.repo.Update(id, req)
+func (s *Service) UpdateJob(ctx context.Context, id uuid.UUID, req *model.UpdateJobRequest, actor model.Actor) (*model.Job, error) {
+	job, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *model.StatusHistory
+	if req.Status != nil && *req.Status != job.Status {
+		if err := CanTransition(job, *req.Status); err != nil {
+			return nil, err
+		}
+		entry = newEntry(job, *req.Status, actor, nil)
+	}
+
+	updated, err := s.repo.Update(ctx, id, req, entry)
+	if err == repository.ErrStatusConflict {
+		to := job.Status
+		if entry != nil {
+			to = entry.ToStatus
+		}
+		return nil, &TransitionError{From: job.Status, To: to, Reason: "job was modified concurrently"}
+	}
+	return updated, err
 }
 
-func (s *Service) AssignDriver(jobID, driverID uuid.UUID) error {
-	return s.repo.AssignDriver(jobID, driverID)
+func (s *Service) AssignDriver(ctx context.Context, jobID, driverID uuid.UUID, actor model.Actor) error {
+	job, err := s.repo.GetByID(jobID)
+	if err != nil {
+		return err
+	}
+
+	candidate := *job
+	candidate.DriverID = &driverID
+	if err := CanTransition(&candidate, model.StatusAssigned); err != nil {
+		return err
+	}
+
+	entry := newEntry(job, model.StatusAssigned, actor, nil)
+	if err := s.repo.AssignDriver(ctx, jobID, driverID, entry); err == repository.ErrStatusConflict {
+		return &TransitionError{From: job.Status, To: model.StatusAssigned, Reason: "job was modified concurrently"}
+	} else if err != nil {
+		return err
+	}
+	return nil
 }
 
 func (s *Service) DeleteJob(id uuid.UUID) error {
 	return s.repo.Delete(id)
 }
 
-func (s *Service) UpdateStatus(id uuid.UUID, status string) (*model.Job, error) {
-	return s.repo.UpdateStatus(id, status)
+// TransitionStatus moves a job to a new status if the state machine allows it
+func (s *Service) TransitionStatus(ctx context.Context, id uuid.UUID, status string, actor model.Actor, req *model.TransitionRequest) (*model.Job, error) {
+	job, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanTransition(job, status); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Transition(ctx, newEntry(job, status, actor, req))
+	if err == repository.ErrStatusConflict {
+		return nil, &TransitionError{From: job.Status, To: status, Reason: "job was modified concurrently"}
+	}
+	return updated, err
+}
+
+// GetHistory returns the status history of a job
+func (s *Service) GetHistory(id uuid.UUID) ([]*model.StatusHistory, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetHistory(id)
+}
+
+func newEntry(job *model.Job, to string, actor model.Actor, req *model.TransitionRequest) *model.StatusHistory {
+	entry := &model.StatusHistory{
+		ID:         uuid.New(),
+		JobID:      job.ID,
+		FromStatus: job.Status,
+		ToStatus:   to,
+		ActorID:    actor.ID,
+		ActorType:  actor.Type,
+		OnBehalfOf: actor.OnBehalfOf,
+		CreatedAt:  time.Now(),
+	}
+	if req != nil {
+		entry.Reason = req.Reason
+		entry.Lat, entry.Lng = req.Lat, req.Lng
+	}
+	return entry
 }
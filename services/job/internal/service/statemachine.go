@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"truckify/services/job/internal/model"
+)
+
+// ErrInvalidTransition is matched by every TransitionError
+var ErrInvalidTransition = errors.New("invalid job status transition")
+
+// TransitionError describes a rejected status change
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("cannot move job from %s to %s: %s", e.From, e.To, e.Reason)
+	}
+	return fmt.Sprintf("cannot move job from %s to %s", e.From, e.To)
+}
+
+// Is lets errors.Is(err, ErrInvalidTransition) match any TransitionError
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// guard checks a precondition on the job before a transition is allowed
+type guard func(job *model.Job) string
+
+func driverAssigned(job *model.Job) string {
+	if job.DriverID == nil {
+		return "driver must be assigned"
+	}
+	return ""
+}
+
+// transitions lists the allowed next states for each status and their guards.
+// delivered, cancelled and failed are terminal.
+var transitions = map[string]map[string][]guard{
+	model.StatusPending: {
+		model.StatusAssigned:  {driverAssigned},
+		model.StatusCancelled: nil,
+	},
+	model.StatusAssigned: {
+		model.StatusPickedUp:  {driverAssigned},
+		model.StatusInTransit: {driverAssigned},
+		model.StatusCancelled: nil,
+	},
+	model.StatusPickedUp: {
+		model.StatusInTransit: nil,
+		model.StatusDelivered: nil,
+		model.StatusFailed:    nil,
+	},
+	model.StatusInTransit: {
+		model.StatusDelivered: nil,
+		model.StatusFailed:    nil,
+	},
+}
+
+// CanTransition reports whether the job may move to the given status
+func CanTransition(job *model.Job, to string) error {
+	next, ok := transitions[job.Status]
+	if !ok {
+		return &TransitionError{From: job.Status, To: to, Reason: "job is in a terminal state"}
+	}
+	guards, ok := next[to]
+	if !ok {
+		return &TransitionError{From: job.Status, To: to}
+	}
+	for _, g := range guards {
+		if reason := g(job); reason != "" {
+			return &TransitionError{From: job.Status, To: to, Reason: reason}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"truckify/services/job/internal/model"
+)
+
+func TestCanTransition(t *testing.T) {
+	driverID := uuid.New()
+
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		hasDriver bool
+		wantErr   bool
+	}{
+		{"pending to assigned", model.StatusPending, model.StatusAssigned, true, false},
+		{"pending to assigned without driver", model.StatusPending, model.StatusAssigned, false, true},
+		{"pending to cancelled", model.StatusPending, model.StatusCancelled, false, false},
+		{"pending to in_transit", model.StatusPending, model.StatusInTransit, true, true},
+		{"pending to delivered", model.StatusPending, model.StatusDelivered, true, true},
+		{"assigned to picked_up", model.StatusAssigned, model.StatusPickedUp, true, false},
+		{"assigned to in_transit", model.StatusAssigned, model.StatusInTransit, true, false},
+		{"assigned to in_transit without driver", model.StatusAssigned, model.StatusInTransit, false, true},
+		{"assigned to cancelled", model.StatusAssigned, model.StatusCancelled, true, false},
+		{"assigned to delivered", model.StatusAssigned, model.StatusDelivered, true, true},
+		{"picked_up to in_transit", model.StatusPickedUp, model.StatusInTransit, true, false},
+		{"picked_up to delivered", model.StatusPickedUp, model.StatusDelivered, true, false},
+		{"picked_up to failed", model.StatusPickedUp, model.StatusFailed, true, false},
+		{"picked_up to cancelled", model.StatusPickedUp, model.StatusCancelled, true, true},
+		{"in_transit to delivered", model.StatusInTransit, model.StatusDelivered, true, false},
+		{"in_transit to failed", model.StatusInTransit, model.StatusFailed, true, false},
+		{"in_transit to cancelled", model.StatusInTransit, model.StatusCancelled, true, true},
+		{"in_transit to pending", model.StatusInTransit, model.StatusPending, true, true},
+		{"delivered is terminal", model.StatusDelivered, model.StatusInTransit, true, true},
+		{"cancelled is terminal", model.StatusCancelled, model.StatusPending, false, true},
+		{"failed is terminal", model.StatusFailed, model.StatusInTransit, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &model.Job{ID: uuid.New(), Status: tt.from}
+			if tt.hasDriver {
+				job.DriverID = &driverID
+			}
+
+			err := CanTransition(job, tt.to)
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected error moving %s to %s", tt.from, tt.to)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("expected ErrInvalidTransition, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTransitionErrorMessage(t *testing.T) {
+	err := &TransitionError{From: model.StatusDelivered, To: model.StatusCancelled, Reason: "job is in a terminal state"}
+	want := "cannot move job from delivered to cancelled: job is in a terminal state"
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+-- Job status transition history
+CREATE TABLE IF NOT EXISTS job_status_history (
+    id UUID PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    actor_id UUID,
+    actor_type VARCHAR(50),
+    reason TEXT,
+    latitude DECIMAL(10,7),
+    longitude DECIMAL(10,7),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_status_history_job ON job_status_history(job_id, created_at);
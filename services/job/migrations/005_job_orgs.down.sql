@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_jobs_org_id;
+ALTER TABLE jobs DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,8 @@
+-- Jobs belong to the organisation the shipper acted for when posting them.
+-- Every user's personal organisation shares their ID, so existing jobs move
+-- to their shipper's personal organisation.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS org_id UUID;
+UPDATE jobs SET org_id = shipper_id WHERE org_id IS NULL;
+ALTER TABLE jobs ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_org_id ON jobs(org_id);
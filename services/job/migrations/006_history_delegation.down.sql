@@ -0,0 +1 @@
+ALTER TABLE job_status_history DROP COLUMN IF EXISTS on_behalf_of;
@@ -0,0 +1,3 @@
+-- The user a dispatcher acted for under a delegation. actor_id is then the
+-- dispatcher.
+ALTER TABLE job_status_history ADD COLUMN IF NOT EXISTS on_behalf_of UUID;
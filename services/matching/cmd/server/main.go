@@ -13,16 +13,26 @@ import (
 	"truckify/services/matching/internal/handler"
 	"truckify/services/matching/internal/repository"
 	"truckify/services/matching/internal/service"
+	"truckify/services/matching/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("matching-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Matching Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "matching-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -36,6 +46,17 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	driverSvcURL := config.GetEnv("DRIVER_SERVICE_URL", "http://localhost:8004")
 	jobSvcURL := config.GetEnv("JOB_SERVICE_URL", "http://localhost:8006")
 
@@ -45,10 +66,15 @@ func main() {
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("matching-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "matching-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -13,10 +14,10 @@ import (
 )
 
 type ServiceInterface interface {
-	FindMatches(req *model.MatchRequest) (*model.MatchResponse, error)
+	FindMatches(ctx context.Context, req *model.MatchRequest) (*model.MatchResponse, error)
 	GetMatchesForJob(jobID uuid.UUID) ([]*model.Match, error)
 	GetPendingMatches(driverID uuid.UUID) ([]*model.Match, error)
-	AcceptMatch(matchID uuid.UUID) error
+	AcceptMatch(ctx context.Context, matchID uuid.UUID) error
 	RejectMatch(matchID uuid.UUID) error
 }
 
@@ -61,7 +62,7 @@ func (h *Handler) FindMatches(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := h.svc.FindMatches(&req)
+	result, err := h.svc.FindMatches(r.Context(), &req)
 	if err != nil {
 		response.InternalServerError(w, "matching failed", err.Error(), reqID)
 		return
@@ -113,7 +114,7 @@ func (h *Handler) AcceptMatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.AcceptMatch(matchID); err != nil {
+	if err := h.svc.AcceptMatch(r.Context(), matchID); err != nil {
 		if err == repository.ErrNotFound {
 			response.NotFound(w, "match not found", "", reqID)
 			return
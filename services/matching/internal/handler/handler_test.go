@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/matching/internal/model"
 	"truckify/services/matching/internal/repository"
+	"truckify/shared/pkg/openapi"
 )
 
 type mockService struct {
@@ -19,7 +21,7 @@ type mockService struct {
 	err       error
 }
 
-func (m *mockService) FindMatches(req *model.MatchRequest) (*model.MatchResponse, error) {
+func (m *mockService) FindMatches(ctx context.Context, req *model.MatchRequest) (*model.MatchResponse, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -40,7 +42,7 @@ func (m *mockService) GetPendingMatches(driverID uuid.UUID) ([]*model.Match, err
 	return m.matches, nil
 }
 
-func (m *mockService) AcceptMatch(matchID uuid.UUID) error {
+func (m *mockService) AcceptMatch(ctx context.Context, matchID uuid.UUID) error {
 	return m.err
 }
 
@@ -207,3 +209,12 @@ func TestFindMatches_InvalidJSON(t *testing.T) {
 		t.Errorf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	New(&mockService{}).RegisterRoutes(router)
+
+	if err := openapi.Check(router, Routes); err != nil {
+		t.Fatal(err)
+	}
+}
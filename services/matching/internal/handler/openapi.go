@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"truckify/services/matching/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /match":               {Summary: "Find drivers for a job", Request: model.MatchRequest{}, Response: model.MatchResponse{}},
+	"GET /matches/job/{jobId}":  {Summary: "List a job's matches", Response: []*model.Match{}},
+	"GET /matches/pending":      {Summary: "List the caller's pending matches", Response: []*model.Match{}},
+	"POST /matches/{id}/accept": {Summary: "Accept a match", Response: map[string]string{}},
+	"POST /matches/{id}/reject": {Summary: "Reject a match", Response: map[string]string{}},
+	"GET /health":               {Summary: "Health check", Response: map[string]string{}},
+}
@@ -1,29 +1,35 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"truckify/services/matching/internal/model"
 	"truckify/services/matching/internal/repository"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/tracing"
 )
 
 type Service struct {
 	repo          *repository.Repository
 	driverSvcURL  string
 	jobSvcURL     string
+	client        *http.Client
 }
 
 func New(repo *repository.Repository, driverSvcURL, jobSvcURL string) *Service {
-	return &Service{repo: repo, driverSvcURL: driverSvcURL, jobSvcURL: jobSvcURL}
+	return &Service{repo: repo, driverSvcURL: driverSvcURL, jobSvcURL: jobSvcURL, client: tracing.NewClient(10 * time.Second)}
 }
 
 // FindMatches finds available drivers for a job
-func (s *Service) FindMatches(req *model.MatchRequest) (*model.MatchResponse, error) {
+func (s *Service) FindMatches(ctx context.Context, req *model.MatchRequest) (*model.MatchResponse, error) {
 	if req.MaxDistance <= 0 {
 		req.MaxDistance = 100 // default 100km
 	}
@@ -32,7 +38,7 @@ func (s *Service) FindMatches(req *model.MatchRequest) (*model.MatchResponse, er
 	}
 
 	// Get available drivers from driver service
-	drivers, err := s.getAvailableDrivers(req.VehicleType)
+	drivers, err := s.getAvailableDrivers(ctx, req.VehicleType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get drivers: %w", err)
 	}
@@ -92,7 +98,7 @@ func (s *Service) GetPendingMatches(driverID uuid.UUID) ([]*model.Match, error)
 	return s.repo.GetPendingForDriver(driverID)
 }
 
-func (s *Service) AcceptMatch(matchID uuid.UUID) error {
+func (s *Service) AcceptMatch(ctx context.Context, matchID uuid.UUID) error {
 	match, err := s.repo.GetByID(matchID)
 	if err != nil {
 		return err
@@ -104,7 +110,7 @@ func (s *Service) AcceptMatch(matchID uuid.UUID) error {
 	}
 
 	// Assign driver to job via job service
-	return s.assignDriverToJob(match.JobID, match.DriverID)
+	return s.assignDriverToJob(ctx, match.JobID, match.DriverID)
 }
 
 func (s *Service) RejectMatch(matchID uuid.UUID) error {
@@ -116,9 +122,13 @@ func (s *Service) ExpireMatches() (int64, error) {
 }
 
 // Helper: get available drivers from driver service
-func (s *Service) getAvailableDrivers(vehicleType string) ([]driverInfo, error) {
+func (s *Service) getAvailableDrivers(ctx context.Context, vehicleType string) ([]driverInfo, error) {
 	url := fmt.Sprintf("%s/drivers/available?type=%s", s.driverSvcURL, vehicleType)
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -160,16 +170,25 @@ func (d *driverInfo) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// Helper: assign driver to job
-func (s *Service) assignDriverToJob(jobID, driverID uuid.UUID) error {
+// Helper: assign driver to job. The driver has accepted the shipper's offer,
+// so the matching service makes the assignment for the shipper, limited to
+// assigning.
+func (s *Service) assignDriverToJob(ctx context.Context, jobID, driverID uuid.UUID) error {
+	shipperID, err := s.getJobShipper(ctx, jobID, driverID)
+	if err != nil {
+		return err
+	}
+
 	url := fmt.Sprintf("%s/jobs/%s/assign", s.jobSvcURL, jobID)
 	body := fmt.Sprintf(`{"driver_id":"%s"}`, driverID)
-	req, _ := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Body = http.NoBody
-	
-	client := &http.Client{}
-	resp, err := client.Post(url, "application/json", stringReader(body))
+	authz.Principal{UserID: shipperID, Role: authz.RoleShipper, Permissions: []authz.Permission{authz.JobAssign}}.Forward(req)
+
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -181,22 +200,33 @@ func (s *Service) assignDriverToJob(jobID, driverID uuid.UUID) error {
 	return nil
 }
 
-func stringReader(s string) *stringReaderImpl {
-	return &stringReaderImpl{s: s, i: 0}
-}
+// Helper: get the shipper who posted a job, read as the driver
+func (s *Service) getJobShipper(ctx context.Context, jobID, driverID uuid.UUID) (uuid.UUID, error) {
+	url := fmt.Sprintf("%s/jobs/%s", s.jobSvcURL, jobID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	authz.Principal{UserID: driverID, Role: authz.RoleDriver, Permissions: []authz.Permission{authz.JobRead}}.Forward(req)
 
-type stringReaderImpl struct {
-	s string
-	i int
-}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer resp.Body.Close()
 
-func (r *stringReaderImpl) Read(p []byte) (n int, err error) {
-	if r.i >= len(r.s) {
-		return 0, nil
+	if resp.StatusCode >= 400 {
+		return uuid.Nil, fmt.Errorf("failed to get job: status %d", resp.StatusCode)
+	}
+	var result struct {
+		Data struct {
+			ShipperID uuid.UUID `json:"shipper_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return uuid.Nil, err
 	}
-	n = copy(p, r.s[r.i:])
-	r.i += n
-	return n, nil
+	return result.Data.ShipperID, nil
 }
 
 // Haversine formula for distance between two coordinates
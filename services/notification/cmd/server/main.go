@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,17 +12,29 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/notification/internal/handler"
 	"truckify/services/notification/internal/service"
+	"truckify/services/notification/internal/webhook"
 	"truckify/services/notification/internal/websocket"
+	"truckify/services/notification/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
+	"truckify/shared/pkg/events"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("notification-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Notification Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "notification-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -35,14 +48,65 @@ func main() {
 	}
 	defer database.ClosePostgresDB(db)
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	hub := websocket.NewHub()
 	go hub.Run()
 
+	webhookConfig := webhook.DefaultConfig()
+	webhookConfig.AllowPrivate = config.GetEnvBool("WEBHOOK_ALLOW_PRIVATE", false)
+	webhooks := webhook.New(webhook.NewPostgresStore(db), log, webhookConfig)
+	if fleetURL := config.GetEnv("FLEET_SERVICE_URL", ""); fleetURL != "" {
+		webhooks.SetFleets(webhook.NewFleetClient(fleetURL))
+	}
+
+	// Forward job status and driver location events to topic subscribers.
+	// Every replica has its own ephemeral subscriptions so it sees every
+	// event. Webhook deliveries are queued once per event, so those
+	// subscriptions share one durable queue group across replicas.
+	topics := websocket.NewTopics()
+	if natsURL := config.GetEnv("NATS_URL", ""); natsURL != "" {
+		broker, err := events.NewNATSFanoutBroker(natsURL, "notification-service", log)
+		if err != nil {
+			log.Fatal("Failed to connect to NATS", "error", err)
+		}
+		defer broker.Close()
+		if err := topics.Forward(broker); err != nil {
+			log.Fatal("Failed to subscribe to events", "error", err)
+		}
+
+		webhookBroker, err := events.NewNATSBroker(natsURL, "notification-service", log)
+		if err != nil {
+			log.Fatal("Failed to connect to NATS", "error", err)
+		}
+		defer webhookBroker.Close()
+		if err := webhooks.Subscribe(webhookBroker); err != nil {
+			log.Fatal("Failed to subscribe to events", "error", err)
+		}
+		log.Info("Connected to NATS")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go webhooks.Run(ctx)
+
 	svc := service.New(log, db)
-	h := handler.New(svc, hub)
+	h := handler.New(svc, webhooks, hub, topics)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("notification-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
@@ -51,7 +115,10 @@ func main() {
 		w.Write([]byte(`{"status":"healthy"}`))
 	}).Methods(http.MethodGet)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	h.RegisterWebhookRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "notification-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8014")
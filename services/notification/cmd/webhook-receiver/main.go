@@ -0,0 +1,43 @@
+// Command webhook-receiver is a local endpoint for trying out webhooks. It
+// verifies each delivery's signature with WEBHOOK_SECRET and logs what it
+// receives; answering with RESPONSE_STATUS lets retries be watched.
+//
+// Register it with a notification service started with
+// WEBHOOK_ALLOW_PRIVATE=true, since it listens on a private address.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"truckify/services/notification/internal/webhook"
+	"truckify/shared/pkg/config"
+	"truckify/shared/pkg/logger"
+)
+
+func main() {
+	log := logger.New("webhook-receiver", config.GetEnv("LOG_LEVEL", "info"))
+
+	secret := config.GetEnv("WEBHOOK_SECRET", "")
+	if secret == "" {
+		log.Fatal("WEBHOOK_SECRET is required")
+	}
+
+	receiver := webhook.NewReceiver(secret)
+	receiver.RespondWith(config.GetEnvInt("RESPONSE_STATUS", http.StatusNoContent))
+	receiver.OnReceive = func(r webhook.Received) {
+		log.Info("Webhook received",
+			"event", r.Payload.Type,
+			"event_id", r.Payload.ID,
+			"delivery_id", r.Header.Get(webhook.DeliveryHeader),
+			"data", string(r.Payload.Data))
+	}
+
+	port := config.GetEnv("PORT", "9099")
+	server := &http.Server{Addr: fmt.Sprintf(":%s", port), Handler: receiver, ReadTimeout: 15 * time.Second}
+	log.Info("Webhook receiver listening", "port", port)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal("Server failed", "error", err)
+	}
+}
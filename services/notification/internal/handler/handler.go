@@ -2,13 +2,17 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"truckify/services/notification/internal/model"
 	"truckify/services/notification/internal/service"
+	"truckify/services/notification/internal/webhook"
 	ws "truckify/services/notification/internal/websocket"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -19,23 +23,35 @@ var upgrader = websocket.Upgrader{
 
 type Handler struct {
 	service   *service.Service
+	webhooks  *webhook.Service
 	validator *validator.Validator
+	authz     *authz.Authorizer
 	hub       *ws.Hub
+	topics    *ws.Topics
 }
 
-func New(svc *service.Service, hub *ws.Hub) *Handler {
-	return &Handler{service: svc, validator: validator.New(), hub: hub}
+func New(svc *service.Service, webhooks *webhook.Service, hub *ws.Hub, topics *ws.Topics) *Handler {
+	return &Handler{
+		service:   svc,
+		webhooks:  webhooks,
+		validator: validator.New(),
+		authz:     authz.New(authz.DefaultPolicy()),
+		hub:       hub,
+		topics:    topics,
+	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/notifications/send", h.SendNotification).Methods(http.MethodPost)
 	router.HandleFunc("/notifications/user/{id}", h.GetUserNotifications).Methods(http.MethodGet)
 	router.HandleFunc("/ws", h.HandleWebSocket)
-	// Messaging
-	router.HandleFunc("/messages/conversations", h.GetConversations).Methods(http.MethodGet)
-	router.HandleFunc("/messages/conversations/job/{jobId}", h.GetOrCreateConversation).Methods(http.MethodPost)
-	router.HandleFunc("/messages/conversations/{id}", h.GetMessages).Methods(http.MethodGet)
-	router.HandleFunc("/messages/conversations/{id}", h.SendMessage).Methods(http.MethodPost)
+	// Internal topic feed for the gateway; not routed from outside
+	router.HandleFunc("/ws/topics", h.HandleTopicWebSocket)
+	// Messaging, limited to the conversations the caller takes part in
+	router.HandleFunc("/messages/conversations", h.authz.RequireUnscoped(authz.MessageSend, h.GetConversations)).Methods(http.MethodGet)
+	router.HandleFunc("/messages/conversations/job/{jobId}", h.authz.RequireUnscoped(authz.MessageSend, h.GetOrCreateConversation)).Methods(http.MethodPost)
+	router.HandleFunc("/messages/conversations/{id}", h.authz.RequireUnscoped(authz.MessageSend, h.GetMessages)).Methods(http.MethodGet)
+	router.HandleFunc("/messages/conversations/{id}", h.authz.RequireUnscoped(authz.MessageSend, h.SendMessage)).Methods(http.MethodPost)
 }
 
 func (h *Handler) SendNotification(w http.ResponseWriter, r *http.Request) {
@@ -117,6 +133,51 @@ func (h *Handler) readPump(c *ws.Client) {
 	}
 }
 
+// HandleTopicWebSocket streams topic messages. The client sends
+// {"type":"subscribe","topic":"job:<id>"} or "unsubscribe" to choose topics.
+func (h *Handler) HandleTopicWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	// Topic connections outlive the server's request timeouts
+	conn.SetReadDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Time{})
+
+	client := ws.NewTopicClient(conn)
+	go h.writeTopics(client)
+
+	defer func() {
+		h.topics.Leave(client)
+		conn.Close()
+	}()
+	for {
+		var req struct {
+			Type  string `json:"type"`
+			Topic string `json:"topic"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		switch req.Type {
+		case "subscribe":
+			h.topics.Subscribe(client, req.Topic)
+		case "unsubscribe":
+			h.topics.Unsubscribe(client, req.Topic)
+		}
+	}
+}
+
+func (h *Handler) writeTopics(c *ws.TopicClient) {
+	defer c.Conn.Close()
+	for msg := range c.Send {
+		if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			return
+		}
+	}
+}
+
 func (h *Handler) GetHub() *ws.Hub {
 	return h.hub
 }
@@ -132,7 +193,12 @@ func (h *Handler) GetConversations(w http.ResponseWriter, r *http.Request) {
 		response.Unauthorized(w, "unauthorized", "", reqID)
 		return
 	}
-	convs, err := h.service.GetUserConversations(userID)
+	page, err := pagination.Parse(r.URL.Query(), model.ConversationListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), reqID)
+		return
+	}
+	convs, next, err := h.service.GetUserConversations(userID, page)
 	if err != nil {
 		response.InternalServerError(w, "Failed to get conversations", "", reqID)
 		return
@@ -140,7 +206,7 @@ func (h *Handler) GetConversations(w http.ResponseWriter, r *http.Request) {
 	if convs == nil {
 		convs = []model.Conversation{}
 	}
-	response.Success(w, convs, reqID)
+	response.List(w, convs, next, reqID)
 }
 
 func (h *Handler) GetOrCreateConversation(w http.ResponseWriter, r *http.Request) {
@@ -238,7 +304,13 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	msg, err := h.service.SendMessage(convID, userID, req.Content)
+	// A dispatcher acting for the sender is recorded alongside them
+	var sentBy *uuid.UUID
+	if principal, _ := authz.PrincipalFrom(r.Context()); principal.Acting() {
+		sentBy = &principal.ActorID
+	}
+
+	msg, err := h.service.SendMessage(convID, userID, sentBy, req.Content)
 	if err != nil {
 		response.InternalServerError(w, "Failed to send message", "", reqID)
 		return
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/notification/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes and the
+// server's health check
+var Routes = openapi.Routes{
+	"POST /notifications/send":     {Summary: "Send a notification", Request: model.SendNotificationRequest{}, Response: model.Notification{}, Status: http.StatusCreated},
+	"GET /notifications/user/{id}": {Summary: "List a user's notifications", Response: []model.Notification{}},
+	"GET /ws": {
+		Summary:     "Receive notifications",
+		Description: "WebSocket upgrade. Notifications and messages are pushed as JSON frames.",
+		Status:      http.StatusSwitchingProtocols,
+		Query: []openapi.Parameter{
+			{Name: "user_id", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+		},
+	},
+	"GET /ws/topics": {
+		Summary:     "Subscribe to event topics",
+		Description: "WebSocket upgrade for the gateway's subscriptions; not routed from outside.",
+		Status:      http.StatusSwitchingProtocols,
+	},
+
+	"GET /messages/conversations": {Summary: "List the caller's conversations", Response: []model.Conversation{}, List: &model.ConversationListSpec},
+	"POST /messages/conversations/job/{jobId}": {
+		Summary: "Open the conversation about a job",
+		Request: struct {
+			ShipperID string `json:"shipper_id"`
+			DriverID  string `json:"driver_id"`
+		}{},
+		Response: model.Conversation{},
+	},
+	"GET /messages/conversations/{id}": {Summary: "List a conversation's messages", Response: []model.Message{}},
+	"POST /messages/conversations/{id}": {
+		Summary: "Send a message",
+		Request: struct {
+			Content string `json:"content" validate:"required,max=2000"`
+		}{},
+		Response: model.Message{},
+		Status:   http.StatusCreated,
+	},
+
+	"POST /webhooks": {
+		Summary:     "Register a webhook endpoint",
+		Description: "The response holds the secret payloads are signed with, which is not shown again.",
+		Request:     model.CreateWebhookRequest{},
+		Response:    model.CreatedWebhookEndpoint{},
+		Status:      http.StatusCreated,
+	},
+	"GET /webhooks":         {Summary: "List the caller's webhook endpoints", Response: []model.WebhookEndpoint{}},
+	"GET /webhooks/{id}":    {Summary: "Get a webhook endpoint", Response: model.WebhookEndpoint{}},
+	"PUT /webhooks/{id}":    {Summary: "Update a webhook endpoint", Description: "Setting active to true re-enables an endpoint that was disabled after failing.", Request: model.UpdateWebhookRequest{}, Response: model.WebhookEndpoint{}},
+	"DELETE /webhooks/{id}": {Summary: "Delete a webhook endpoint", Status: http.StatusNoContent},
+	"POST /webhooks/{id}/ping": {
+		Summary:     "Send a test event",
+		Description: "Sends webhook.ping to the endpoint straight away and returns the delivery.",
+		Response:    model.WebhookDelivery{},
+	},
+	"GET /webhooks/{id}/deliveries": {
+		Summary:     "List an endpoint's deliveries",
+		Description: "Filter on status=dead for the dead-letter queue.",
+		Response:    []model.WebhookDelivery{},
+		List:        &model.WebhookDeliveryListSpec,
+	},
+	"POST /webhooks/{id}/deliveries/{deliveryId}/redeliver": {Summary: "Send a delivery again", Response: model.WebhookDelivery{}, Status: http.StatusCreated},
+
+	"GET /health": {Summary: "Health check", Response: map[string]string{}, Raw: true},
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/notification/internal/model"
+	"truckify/services/notification/internal/webhook"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/response"
+)
+
+// RegisterWebhookRoutes registers webhook endpoint management and delivery
+// logs. Every route acts on the caller's own endpoints.
+func (h *Handler) RegisterWebhookRoutes(router *mux.Router) {
+	manage := func(next http.HandlerFunc) http.HandlerFunc {
+		return h.authz.RequireUnscoped(authz.WebhookManage, next)
+	}
+	router.HandleFunc("/webhooks", manage(h.CreateWebhook)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks", manage(h.ListWebhooks)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}", manage(h.GetWebhook)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}", manage(h.UpdateWebhook)).Methods(http.MethodPut)
+	router.HandleFunc("/webhooks/{id}", manage(h.DeleteWebhook)).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/{id}/ping", manage(h.PingWebhook)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}/deliveries", manage(h.ListWebhookDeliveries)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}/deliveries/{deliveryId}/redeliver", manage(h.RedeliverWebhook)).Methods(http.MethodPost)
+}
+
+// orgOwner is the organisation role of the users who register endpoints for
+// the organisation
+const orgOwner = "owner"
+
+// CreateWebhook registers an endpoint for the caller. Owners acting for an
+// organisation register it for the organisation too, so it also receives
+// the events of the organisation's jobs and fleet. The response holds the
+// signing secret, which is not shown again.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+
+	var req model.CreateWebhookRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), reqID)
+		return
+	}
+
+	// A personal organisation shares its user's ID, whose events the
+	// endpoint receives anyway
+	orgID := uuid.Nil
+	if principal.OrgRole == orgOwner && principal.OrgID != principal.UserID {
+		orgID = principal.OrgID
+	}
+
+	endpoint, err := h.webhooks.CreateEndpoint(r.Context(), principal.UserID, orgID, &req)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Created(w, endpoint, reqID)
+}
+
+// ListWebhooks returns the caller's endpoints
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+
+	endpoints, err := h.webhooks.ListEndpoints(r.Context(), principal.UserID)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Success(w, endpoints, reqID)
+}
+
+// GetWebhook returns one of the caller's endpoints
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+
+	endpoint, err := h.webhooks.GetEndpoint(r.Context(), principal.UserID, id)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Success(w, endpoint, reqID)
+}
+
+// UpdateWebhook changes one of the caller's endpoints
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+
+	var req model.UpdateWebhookRequest
+	if err := h.validator.DecodeAndValidate(r, &req); err != nil {
+		response.BadRequest(w, "Invalid request", err.Error(), reqID)
+		return
+	}
+
+	endpoint, err := h.webhooks.UpdateEndpoint(r.Context(), principal.UserID, id, &req)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Success(w, endpoint, reqID)
+}
+
+// DeleteWebhook removes one of the caller's endpoints
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+
+	if err := h.webhooks.DeleteEndpoint(r.Context(), principal.UserID, id); err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.NoContent(w, reqID)
+}
+
+// PingWebhook sends a test event to one of the caller's endpoints and
+// returns how the delivery went
+func (h *Handler) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+
+	delivery, err := h.webhooks.Ping(r.Context(), principal.UserID, id)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Success(w, delivery, reqID)
+}
+
+// ListWebhookDeliveries returns a page of an endpoint's delivery log.
+// Filtering on status=dead lists its dead-letter queue.
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+	page, err := pagination.Parse(r.URL.Query(), model.WebhookDeliveryListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), reqID)
+		return
+	}
+
+	deliveries, next, err := h.webhooks.ListDeliveries(r.Context(), principal.UserID, id, page)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.List(w, deliveries, next, reqID)
+}
+
+// RedeliverWebhook queues a delivery to be sent again
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	reqID, _ := r.Context().Value("request_id").(string)
+	principal, _ := authz.PrincipalFrom(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID", "", reqID)
+		return
+	}
+	deliveryID, err := uuid.Parse(mux.Vars(r)["deliveryId"])
+	if err != nil {
+		response.BadRequest(w, "Invalid delivery ID", "", reqID)
+		return
+	}
+
+	delivery, err := h.webhooks.Redeliver(r.Context(), principal.UserID, id, deliveryID)
+	if err != nil {
+		h.webhookError(w, err, reqID)
+		return
+	}
+	response.Created(w, delivery, reqID)
+}
+
+func (h *Handler) webhookError(w http.ResponseWriter, err error, reqID string) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidURL):
+		response.BadRequest(w, "Invalid webhook URL", err.Error(), reqID)
+	case errors.Is(err, webhook.ErrEndpointNotFound):
+		response.NotFound(w, "Webhook not found", "", reqID)
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		response.NotFound(w, "Delivery not found", "", reqID)
+	case errors.Is(err, webhook.ErrEndpointDisabled):
+		response.Conflict(w, "Webhook is disabled", "Set active to true to turn it back on", reqID)
+	default:
+		response.InternalServerError(w, "Webhook request failed", "", reqID)
+	}
+}
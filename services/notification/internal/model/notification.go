@@ -3,6 +3,7 @@ package model
 import (
 	"time"
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
 type NotificationType string
@@ -50,6 +51,26 @@ type Conversation struct {
 	UnreadCount   int      `json:"unread_count,omitempty"`
 }
 
+// ConversationListSpec is what a user's conversations can be filtered and
+// sorted by; the most recently active come first
+var ConversationListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"job_id":     {Column: "job_id", Kind: pagination.ID},
+		"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+		"updated_at": {Column: "updated_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-updated_at",
+}
+
+// SortKey returns the value of a ConversationListSpec sort field and the
+// conversation's ID
+func (c Conversation) SortKey(field string) (interface{}, uuid.UUID) {
+	if field == "created_at" {
+		return c.CreatedAt, c.ID
+	}
+	return c.UpdatedAt, c.ID
+}
+
 type Message struct {
 	ID             uuid.UUID  `json:"id" db:"id"`
 	ConversationID uuid.UUID  `json:"conversation_id" db:"conversation_id"`
@@ -57,6 +78,8 @@ type Message struct {
 	Content        string     `json:"content" db:"content"`
 	ReadAt         *time.Time `json:"read_at,omitempty" db:"read_at"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	// SentBy is the dispatcher who sent the message for the sender, if any
+	SentBy *uuid.UUID `json:"sent_by,omitempty" db:"sent_by"`
 }
 
 type SendMessageRequest struct {
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
+)
+
+// Webhook event types an endpoint can subscribe to
+const (
+	WebhookJobStatusChanged = "job.status_changed"
+	WebhookJobDelivered     = "job.delivered"
+	WebhookBidAccepted      = "bid.accepted"
+	WebhookPaymentCompleted = "payment.completed"
+	// WebhookPing is only sent on request, to test an endpoint
+	WebhookPing = "webhook.ping"
+)
+
+// WebhookEventTypes lists the event types an endpoint can subscribe to
+var WebhookEventTypes = []string{WebhookJobStatusChanged, WebhookJobDelivered, WebhookBidAccepted, WebhookPaymentCompleted}
+
+// Delivery statuses. Dead deliveries ran out of attempts and wait in the
+// dead-letter queue until they are redelivered by hand.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookEndpoint is a URL that receives events for the user who registered
+// it and, if they registered it as its owner, for their organisation
+type WebhookEndpoint struct {
+	ID                  uuid.UUID  `json:"id"`
+	UserID              uuid.UUID  `json:"user_id"`
+	OrgID               *uuid.UUID `json:"org_id,omitempty"`
+	URL                 string     `json:"url"`
+	Description         string     `json:"description"`
+	Secret              string     `json:"-"`
+	EventTypes          []string   `json:"event_types"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	FailingSince        *time.Time `json:"failing_since,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Subscribed reports whether the endpoint receives an event type
+func (e *WebhookEndpoint) Subscribed(eventType string) bool {
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedWebhookEndpoint is returned once, when an endpoint is registered,
+// and is the only time its signing secret is shown
+type CreatedWebhookEndpoint struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to an endpoint
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	RedeliveryOf   *uuid.UUID      `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// SortKey returns the value of a WebhookDeliveryListSpec sort field and the
+// delivery's ID
+func (d WebhookDelivery) SortKey(field string) (interface{}, uuid.UUID) {
+	return d.CreatedAt, d.ID
+}
+
+// WebhookDeliveryListSpec is what an endpoint's delivery log can be filtered
+// and sorted by; the newest come first
+var WebhookDeliveryListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"status":     {Column: "status", Kind: pagination.String},
+		"event_type": {Column: "event_type", Kind: pagination.String},
+		"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// WebhookPayload is the body posted to an endpoint
+type WebhookPayload struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CreateWebhookRequest registers an endpoint
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	Description string   `json:"description" validate:"max=255"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,oneof=job.status_changed job.delivered bid.accepted payment.completed"`
+}
+
+// UpdateWebhookRequest changes an endpoint. Setting active re-enables an
+// endpoint that was disabled after failing.
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Description *string  `json:"description" validate:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types" validate:"omitempty,min=1,dive,oneof=job.status_changed job.delivered bid.accepted payment.completed"`
+	Active      *bool    `json:"active"`
+}
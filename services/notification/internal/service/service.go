@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"truckify/services/notification/internal/model"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 )
 
 type Service struct {
@@ -76,10 +77,13 @@ func (s *Service) GetOrCreateConversation(jobID, shipperID, driverID uuid.UUID)
 	return &conv, nil
 }
 
-func (s *Service) GetUserConversations(userID uuid.UUID) ([]model.Conversation, error) {
-	rows, err := s.db.Query(`SELECT id, job_id, shipper_id, driver_id, created_at, updated_at FROM conversations WHERE shipper_id = $1 OR driver_id = $1 ORDER BY updated_at DESC`, userID)
+// GetUserConversations returns a page of a user's conversations and the
+// cursor of the next page
+func (s *Service) GetUserConversations(userID uuid.UUID, page pagination.Page) ([]model.Conversation, string, error) {
+	query, args := page.Apply(`SELECT id, job_id, shipper_id, driver_id, created_at, updated_at FROM conversations WHERE (shipper_id = $1 OR driver_id = $1)`, []interface{}{userID})
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -87,12 +91,12 @@ func (s *Service) GetUserConversations(userID uuid.UUID) ([]model.Conversation,
 	for rows.Next() {
 		var c model.Conversation
 		if err := rows.Scan(&c.ID, &c.JobID, &c.ShipperID, &c.DriverID, &c.CreatedAt, &c.UpdatedAt); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		// Get last message
 		var msg model.Message
-		err := s.db.QueryRow(`SELECT id, conversation_id, sender_id, content, read_at, created_at FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC LIMIT 1`, c.ID).
-			Scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.Content, &msg.ReadAt, &msg.CreatedAt)
+		err := s.db.QueryRow(`SELECT id, conversation_id, sender_id, sent_by, content, read_at, created_at FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC LIMIT 1`, c.ID).
+			Scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.SentBy, &msg.Content, &msg.ReadAt, &msg.CreatedAt)
 		if err == nil {
 			c.LastMessage = &msg
 		}
@@ -100,14 +104,15 @@ func (s *Service) GetUserConversations(userID uuid.UUID) ([]model.Conversation,
 		s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE conversation_id = $1 AND sender_id != $2 AND read_at IS NULL`, c.ID, userID).Scan(&c.UnreadCount)
 		convs = append(convs, c)
 	}
-	return convs, nil
+	convs, next := pagination.Cut(page, convs, model.Conversation.SortKey)
+	return convs, next, nil
 }
 
 func (s *Service) GetMessages(conversationID, userID uuid.UUID) ([]model.Message, error) {
 	// Mark messages as read
 	s.db.Exec(`UPDATE messages SET read_at = $1 WHERE conversation_id = $2 AND sender_id != $3 AND read_at IS NULL`, time.Now(), conversationID, userID)
 
-	rows, err := s.db.Query(`SELECT id, conversation_id, sender_id, content, read_at, created_at FROM messages WHERE conversation_id = $1 ORDER BY created_at ASC`, conversationID)
+	rows, err := s.db.Query(`SELECT id, conversation_id, sender_id, sent_by, content, read_at, created_at FROM messages WHERE conversation_id = $1 ORDER BY created_at ASC`, conversationID)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +121,7 @@ func (s *Service) GetMessages(conversationID, userID uuid.UUID) ([]model.Message
 	var msgs []model.Message
 	for rows.Next() {
 		var m model.Message
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.ReadAt, &m.CreatedAt); err != nil {
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.SentBy, &m.Content, &m.ReadAt, &m.CreatedAt); err != nil {
 			return nil, err
 		}
 		msgs = append(msgs, m)
@@ -124,16 +129,19 @@ func (s *Service) GetMessages(conversationID, userID uuid.UUID) ([]model.Message
 	return msgs, nil
 }
 
-func (s *Service) SendMessage(conversationID, senderID uuid.UUID, content string) (*model.Message, error) {
+// SendMessage stores a message from senderID. sentBy is the dispatcher
+// sending it for them, or nil.
+func (s *Service) SendMessage(conversationID, senderID uuid.UUID, sentBy *uuid.UUID, content string) (*model.Message, error) {
 	msg := &model.Message{
 		ID:             uuid.New(),
 		ConversationID: conversationID,
 		SenderID:       senderID,
+		SentBy:         sentBy,
 		Content:        content,
 		CreatedAt:      time.Now(),
 	}
-	_, err := s.db.Exec(`INSERT INTO messages (id, conversation_id, sender_id, content, created_at) VALUES ($1, $2, $3, $4, $5)`,
-		msg.ID, msg.ConversationID, msg.SenderID, msg.Content, msg.CreatedAt)
+	_, err := s.db.Exec(`INSERT INTO messages (id, conversation_id, sender_id, sent_by, content, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		msg.ID, msg.ConversationID, msg.SenderID, msg.SentBy, msg.Content, msg.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/tracing"
+)
+
+// Fleets finds who runs the fleet a driver drives for
+type Fleets interface {
+	// DriverFleetOwners returns the operator and organisation of the
+	// driver's fleet, or nothing if they drive for none
+	DriverFleetOwners(ctx context.Context, driverID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// FleetClient looks drivers' fleets up in the fleet service
+type FleetClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewFleetClient creates a client for the fleet service at baseURL
+func NewFleetClient(baseURL string) *FleetClient {
+	return &FleetClient{baseURL: baseURL, client: tracing.NewClient(5 * time.Second)}
+}
+
+// DriverFleetOwners asks the fleet service, as the driver, which fleet they
+// drive for
+func (c *FleetClient) DriverFleetOwners(ctx context.Context, driverID uuid.UUID) ([]uuid.UUID, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/fleet/membership", nil)
+	if err != nil {
+		return nil, err
+	}
+	authz.Principal{UserID: driverID, Role: authz.RoleDriver, Permissions: []authz.Permission{authz.FleetRead}}.Forward(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		// Not a driver, or not in a fleet
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("fleet service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			OwnerID uuid.UUID `json:"owner_id"`
+			OrgID   uuid.UUID `json:"org_id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{result.Data.OwnerID, result.Data.OrgID}, nil
+}
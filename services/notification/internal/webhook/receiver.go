@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"truckify/services/notification/internal/model"
+)
+
+// Received is a delivery taken in by a Receiver
+type Received struct {
+	Header  http.Header
+	Payload model.WebhookPayload
+}
+
+// Receiver is an HTTP endpoint that checks webhook signatures and keeps the
+// payloads it receives. It stands in for an integrator's endpoint in tests
+// and in cmd/webhook-receiver.
+type Receiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	received []Received
+	// OnReceive, when set, is called with each verified delivery
+	OnReceive func(Received)
+}
+
+// NewReceiver creates a receiver that accepts deliveries signed with secret
+func NewReceiver(secret string) *Receiver {
+	return &Receiver{secret: secret, status: http.StatusNoContent}
+}
+
+// SetSecret changes the secret deliveries must be signed with
+func (rc *Receiver) SetSecret(secret string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.secret = secret
+}
+
+// RespondWith sets the status verified deliveries are answered with, to
+// act out a failing endpoint
+func (rc *Receiver) RespondWith(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+// Received returns the verified deliveries in the order they arrived
+func (rc *Receiver) Received() []Received {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Received(nil), rc.received...)
+}
+
+// ServeHTTP verifies and records a delivery. Deliveries with a bad
+// signature are answered with 401 and not recorded.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "unreadable body", http.StatusBadRequest)
+		return
+	}
+
+	rc.mu.Lock()
+	secret, status := rc.secret, rc.status
+	rc.mu.Unlock()
+
+	if err := Verify(secret, r.Header.Get(SignatureHeader), body, 5*time.Minute, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var payload model.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	received := Received{Header: r.Header.Clone(), Payload: payload}
+	rc.mu.Lock()
+	rc.received = append(rc.received, received)
+	rc.mu.Unlock()
+	if rc.OnReceive != nil {
+		rc.OnReceive(received)
+	}
+	w.WriteHeader(status)
+}
@@ -0,0 +1,612 @@
+// Package webhook sends events to endpoints that integrators register.
+//
+// Every event is stored as a delivery per subscribed endpoint and posted
+// with an HMAC-SHA256 signature. Failed deliveries are retried with
+// exponential backoff and dead-lettered once they run out of attempts;
+// endpoints that keep failing are disabled until their owner turns them
+// back on.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/services/notification/internal/model"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/safehttp"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid webhook URL")
+	ErrEndpointDisabled = errors.New("webhook endpoint is disabled")
+)
+
+// secretPrefix starts every signing secret
+const secretPrefix = "whsec_"
+
+// responseBodyLimit caps how much of an endpoint's response is logged
+const responseBodyLimit = 1024
+
+// Config tunes delivery
+type Config struct {
+	// MaxAttempts is how many times a delivery is tried before it is
+	// dead-lettered
+	MaxAttempts int
+	// BaseBackoff is the wait after the first failed attempt; it doubles
+	// with every further attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// An endpoint is disabled once DisableAfterFailures attempts in a row
+	// have failed over at least DisableAfter
+	DisableAfterFailures int
+	DisableAfter         time.Duration
+	// Timeout bounds each attempt
+	Timeout time.Duration
+	// AllowPrivate allows plain HTTP and private, loopback and link-local
+	// addresses, for local testing only
+	AllowPrivate bool
+}
+
+// DefaultConfig retries a delivery for about four hours and disables an
+// endpoint that has failed for a day
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:          10,
+		BaseBackoff:          30 * time.Second,
+		MaxBackoff:           2 * time.Hour,
+		DisableAfterFailures: 10,
+		DisableAfter:         24 * time.Hour,
+		Timeout:              10 * time.Second,
+	}
+}
+
+// Service manages webhook endpoints and delivers events to them
+type Service struct {
+	store     Store
+	fleets    Fleets
+	logger    *logger.Logger
+	config    Config
+	client    *http.Client
+	now       func() time.Time
+	batchSize int
+	interval  time.Duration
+}
+
+// New creates a new webhook service
+func New(store Store, log *logger.Logger, config Config) *Service {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivate {
+		dialer.Control = denyPrivate
+	}
+	return &Service{
+		store:  store,
+		logger: log,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, MaxIdleConnsPerHost: 2},
+			// A redirect is answered as a failure rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		now:       time.Now,
+		batchSize: 20,
+		interval:  time.Second,
+	}
+}
+
+// SetFleets lets events concerning a driver reach the operator and
+// organisation of the driver's fleet
+func (s *Service) SetFleets(fleets Fleets) {
+	s.fleets = fleets
+}
+
+// CreateEndpoint registers an endpoint for the user and, unless orgID is
+// nil, for the organisation they own
+func (s *Service) CreateEndpoint(ctx context.Context, userID, orgID uuid.UUID, req *model.CreateWebhookRequest) (*model.CreatedWebhookEndpoint, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	e := model.WebhookEndpoint{
+		ID:          uuid.New(),
+		UserID:      userID,
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		EventTypes:  uniqueTypes(req.EventTypes),
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if orgID != uuid.Nil {
+		e.OrgID = &orgID
+	}
+	if err := s.store.CreateEndpoint(ctx, &e); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook endpoint created", "user_id", userID, "endpoint_id", e.ID)
+	return &model.CreatedWebhookEndpoint{WebhookEndpoint: e, Secret: secret}, nil
+}
+
+// ListEndpoints returns the user's endpoints
+func (s *Service) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]model.WebhookEndpoint, error) {
+	endpoints, err := s.store.ListEndpoints(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints == nil {
+		endpoints = []model.WebhookEndpoint{}
+	}
+	return endpoints, nil
+}
+
+// GetEndpoint returns one of the user's endpoints
+func (s *Service) GetEndpoint(ctx context.Context, userID, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	e, err := s.store.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.UserID != userID {
+		return nil, ErrEndpointNotFound
+	}
+	return e, nil
+}
+
+// UpdateEndpoint changes one of the user's endpoints. Turning an endpoint
+// back on clears its failure streak; its dead-lettered deliveries stay
+// where they are until they are redelivered.
+func (s *Service) UpdateEndpoint(ctx context.Context, userID, id uuid.UUID, req *model.UpdateWebhookRequest) (*model.WebhookEndpoint, error) {
+	e, err := s.GetEndpoint(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := s.checkURL(*req.URL); err != nil {
+			return nil, err
+		}
+		e.URL = *req.URL
+	}
+	if req.Description != nil {
+		e.Description = *req.Description
+	}
+	if len(req.EventTypes) > 0 {
+		e.EventTypes = uniqueTypes(req.EventTypes)
+	}
+	if req.Active != nil {
+		if *req.Active && !e.Active {
+			e.ConsecutiveFailures = 0
+			e.FailingSince = nil
+			e.DisabledAt = nil
+			e.DisabledReason = ""
+		}
+		e.Active = *req.Active
+	}
+	e.UpdatedAt = s.now()
+
+	if err := s.store.UpdateEndpoint(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DeleteEndpoint removes one of the user's endpoints with its delivery log
+func (s *Service) DeleteEndpoint(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := s.GetEndpoint(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.store.DeleteEndpoint(ctx, id); err != nil {
+		return err
+	}
+	s.logger.Info("Webhook endpoint deleted", "user_id", userID, "endpoint_id", id)
+	return nil
+}
+
+// ListDeliveries returns a page of the delivery log of one of the user's
+// endpoints and the cursor of the next page
+func (s *Service) ListDeliveries(ctx context.Context, userID, endpointID uuid.UUID, page pagination.Page) ([]model.WebhookDelivery, string, error) {
+	if _, err := s.GetEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, "", err
+	}
+	deliveries, next, err := s.store.ListDeliveries(ctx, endpointID, page)
+	if err != nil {
+		return nil, "", err
+	}
+	if deliveries == nil {
+		deliveries = []model.WebhookDelivery{}
+	}
+	return deliveries, next, nil
+}
+
+// Redeliver queues a delivery of one of the user's endpoints to be sent
+// again, whatever became of it. The original stays in the log.
+func (s *Service) Redeliver(ctx context.Context, userID, endpointID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	e, err := s.GetEndpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Active {
+		return nil, ErrEndpointDisabled
+	}
+	orig, err := s.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if orig.EndpointID != e.ID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	d := s.newDelivery(e.ID, orig.EventID, orig.EventType, orig.Payload)
+	d.RedeliveryOf = &orig.ID
+	if _, err := s.store.CreateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Webhook redelivery queued", "endpoint_id", e.ID, "delivery_id", d.ID, "redelivery_of", orig.ID)
+	return d, nil
+}
+
+// Ping sends a webhook.ping event to one of the user's endpoints straight
+// away and returns the logged delivery, so the endpoint can be tested
+func (s *Service) Ping(ctx context.Context, userID, endpointID uuid.UUID) (*model.WebhookDelivery, error) {
+	e, err := s.GetEndpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Active {
+		return nil, ErrEndpointDisabled
+	}
+
+	data, err := json.Marshal(map[string]uuid.UUID{"endpoint_id": e.ID})
+	if err != nil {
+		return nil, err
+	}
+	eventID := uuid.New()
+	payload, err := json.Marshal(model.WebhookPayload{ID: eventID, Type: model.WebhookPing, CreatedAt: s.now().UTC(), Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	// The delivery is stored as claimed so the worker leaves it to us
+	d := s.newDelivery(e.ID, eventID, model.WebhookPing, payload)
+	lease := s.now().Add(s.config.Timeout * 2)
+	d.NextAttemptAt = &lease
+	if _, err := s.store.CreateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	s.attempt(ctx, e, d)
+	return d, nil
+}
+
+// Subscribe queues deliveries for the events endpoints can receive
+func (s *Service) Subscribe(sub events.Subscriber) error {
+	for _, eventType := range []string{events.TypeJobStatusChanged, events.TypeBidAccepted, events.TypePaymentCompleted} {
+		if _, err := sub.Subscribe(eventType, s.Enqueue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue stores a delivery of the event for every endpoint of the users and
+// organisations it concerns that is subscribed to it. Events seen before are
+// not queued again, so the broker may deliver an event more than once.
+func (s *Service) Enqueue(ctx context.Context, evt *events.Event) error {
+	// Events concerning a driver also concern their fleet
+	var owners, drivers []uuid.UUID
+	var types []string
+	switch evt.Type {
+	case events.TypeJobStatusChanged:
+		// Version 2 adds the job's organisation to version 1, which decodes
+		// the same way without one
+		var data events.JobStatusChangedV2
+		if err := json.Unmarshal(evt.Data, &data); err != nil {
+			return err
+		}
+		owners = []uuid.UUID{data.ShipperID}
+		if data.OrgID != nil {
+			owners = append(owners, *data.OrgID)
+		}
+		if data.DriverID != nil {
+			drivers = []uuid.UUID{*data.DriverID}
+		}
+		types = []string{model.WebhookJobStatusChanged}
+		if data.ToStatus == "delivered" {
+			types = append(types, model.WebhookJobDelivered)
+		}
+	case events.TypeBidAccepted:
+		var data events.BidAcceptedV1
+		if err := json.Unmarshal(evt.Data, &data); err != nil {
+			return err
+		}
+		drivers = []uuid.UUID{data.DriverID}
+		types = []string{model.WebhookBidAccepted}
+	case events.TypePaymentCompleted:
+		var data events.PaymentV1
+		if err := json.Unmarshal(evt.Data, &data); err != nil {
+			return err
+		}
+		owners = []uuid.UUID{data.PayerID}
+		drivers = []uuid.UUID{data.PayeeID}
+		types = []string{model.WebhookPaymentCompleted}
+	default:
+		return nil
+	}
+	for _, driverID := range drivers {
+		owners = append(owners, driverID)
+		if s.fleets == nil {
+			continue
+		}
+		// A failed lookup fails the event, which the broker delivers again
+		fleet, err := s.fleets.DriverFleetOwners(ctx, driverID)
+		if err != nil {
+			return fmt.Errorf("failed to find the fleet of driver %s: %w", driverID, err)
+		}
+		owners = append(owners, fleet...)
+	}
+
+	for _, eventType := range types {
+		endpoints, err := s.store.SubscribedEndpoints(ctx, owners, eventType)
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		payload, err := json.Marshal(model.WebhookPayload{ID: evt.ID, Type: eventType, CreatedAt: evt.OccurredAt, Data: evt.Data})
+		if err != nil {
+			return err
+		}
+		for _, e := range endpoints {
+			if _, err := s.store.CreateDelivery(ctx, s.newDelivery(e.ID, evt.ID, eventType, payload)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run sends due deliveries on every tick until the context is cancelled
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := s.DeliverDue(ctx)
+				if err != nil {
+					s.logger.Error("Webhook delivery failed", "error", err)
+					break
+				}
+				if n < s.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DeliverDue attempts one batch of due deliveries and returns how many were
+// attempted. Claimed deliveries are held long enough for every attempt in
+// the batch to time out, so several replicas can deliver concurrently.
+func (s *Service) DeliverDue(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.store.ClaimDue(ctx, now, now.Add(s.config.Timeout*2), s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoints := map[uuid.UUID]*model.WebhookEndpoint{}
+	var wg sync.WaitGroup
+	for i := range due {
+		d := &due[i]
+		e, ok := endpoints[d.EndpointID]
+		if !ok {
+			if e, err = s.store.GetEndpoint(ctx, d.EndpointID); err != nil {
+				// The lease runs out and the delivery is claimed again
+				s.logger.Error("Failed to load webhook endpoint", "endpoint_id", d.EndpointID, "error", err)
+				continue
+			}
+			endpoints[d.EndpointID] = e
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.attempt(ctx, e, d)
+		}()
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// attempt posts a delivery and records the outcome on the delivery and its
+// endpoint
+func (s *Service) attempt(ctx context.Context, e *model.WebhookEndpoint, d *model.WebhookDelivery) {
+	status, body, err := s.post(ctx, e, d)
+
+	now := s.now()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.UpdatedAt = now
+	d.ResponseStatus = status
+	d.ResponseBody = body
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	ok := err == nil && status >= 200 && status < 300
+	switch {
+	case ok:
+		d.Status = model.DeliverySucceeded
+		d.NextAttemptAt = nil
+	case d.Attempts >= s.config.MaxAttempts:
+		d.Status = model.DeliveryDead
+		d.NextAttemptAt = nil
+		s.logger.Warn("Webhook delivery dead-lettered", "endpoint_id", e.ID, "delivery_id", d.ID, "attempts", d.Attempts)
+	default:
+		next := now.Add(s.backoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+	if err := s.store.UpdateDelivery(ctx, d); err != nil {
+		s.logger.Error("Failed to record webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+
+	if ok {
+		if err := s.store.EndpointSucceeded(ctx, e.ID); err != nil {
+			s.logger.Error("Failed to record webhook endpoint success", "endpoint_id", e.ID, "error", err)
+		}
+		return
+	}
+	s.endpointFailed(ctx, e, now)
+}
+
+// endpointFailed extends the endpoint's failure streak and disables it once
+// the streak is both long enough and old enough
+func (s *Service) endpointFailed(ctx context.Context, e *model.WebhookEndpoint, at time.Time) {
+	failures, since, err := s.store.EndpointFailed(ctx, e.ID, at)
+	if err != nil {
+		s.logger.Error("Failed to record webhook endpoint failure", "endpoint_id", e.ID, "error", err)
+		return
+	}
+	if failures < s.config.DisableAfterFailures || at.Sub(since) < s.config.DisableAfter {
+		return
+	}
+
+	reason := fmt.Sprintf("%d consecutive failed deliveries since %s", failures, since.UTC().Format(time.RFC3339))
+	if err := s.store.DisableEndpoint(ctx, e.ID, reason, at); err != nil {
+		s.logger.Error("Failed to disable webhook endpoint", "endpoint_id", e.ID, "error", err)
+		return
+	}
+	s.logger.Warn("Webhook endpoint disabled", "endpoint_id", e.ID, "user_id", e.UserID, "reason", reason)
+}
+
+// post sends a delivery and returns the response status and the start of
+// the response body
+func (s *Service) post(ctx context.Context, e *model.WebhookEndpoint, d *model.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Truckify-Webhooks/1.0")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID.String())
+	req.Header.Set(SignatureHeader, Sign(e.Secret, time.Now(), d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	return resp.StatusCode, string(body), nil
+}
+
+// backoff is the wait after the given number of failed attempts
+func (s *Service) backoff(attempts int) time.Duration {
+	wait := s.config.BaseBackoff
+	for i := 1; i < attempts && wait < s.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > s.config.MaxBackoff {
+		wait = s.config.MaxBackoff
+	}
+	return wait
+}
+
+func (s *Service) newDelivery(endpointID, eventID uuid.UUID, eventType string, payload []byte) *model.WebhookDelivery {
+	now := s.now()
+	return &model.WebhookDelivery{
+		ID:            uuid.New(),
+		EndpointID:    endpointID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// checkURL accepts absolute HTTPS URLs. Plain HTTP and private addresses
+// are only allowed when the service is configured for local testing; the
+// dialer also refuses private addresses a host name resolves to.
+func (s *Service) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if s.config.AllowPrivate {
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return ErrInvalidURL
+		}
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: must use https", ErrInvalidURL)
+	}
+	if safehttp.PrivateHost(u.Hostname()) {
+		return fmt.Errorf("%w: private address", ErrInvalidURL)
+	}
+	return nil
+}
+
+// denyPrivate refuses connections to private addresses, so endpoints cannot
+// reach the platform's own network
+func denyPrivate(network, address string, c syscall.RawConn) error {
+	if err := safehttp.DenyPrivate(network, address, c); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	return nil
+}
+
+func uniqueTypes(types []string) []string {
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		dup := false
+		for _, r := range result {
+			dup = dup || r == t
+		}
+		if !dup {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// generateSecret returns a new random signing secret
+func generateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
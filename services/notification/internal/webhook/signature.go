@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Truckify-Signature"
+	EventHeader     = "X-Truckify-Event"
+	DeliveryHeader  = "X-Truckify-Delivery"
+)
+
+// ErrInvalidSignature is returned by Verify for payloads that were not
+// signed with the secret, or were signed too long ago
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header of a payload sent at t. The signature
+// is the hex HMAC-SHA256, keyed with the endpoint's secret, of the Unix
+// timestamp, a dot and the body: "t=<timestamp>,v1=<signature>".
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, signature(secret, ts, body))
+}
+
+// Verify checks a signature header made by Sign. Signatures older than
+// tolerance are rejected so captured requests cannot be replayed; a zero
+// tolerance skips the check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+
+	want := signature(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"truckify/services/notification/internal/model"
+	"truckify/shared/pkg/pagination"
+)
+
+var (
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Store keeps webhook endpoints and their deliveries
+type Store interface {
+	CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*model.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context, userID uuid.UUID) ([]model.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	// SubscribedEndpoints returns the active endpoints of any of the users
+	// or organisations that receive an event type
+	SubscribedEndpoints(ctx context.Context, ownerIDs []uuid.UUID, eventType string) ([]model.WebhookEndpoint, error)
+	// EndpointSucceeded clears an endpoint's failure streak
+	EndpointSucceeded(ctx context.Context, id uuid.UUID) error
+	// EndpointFailed extends an endpoint's failure streak and returns its
+	// length and when it started
+	EndpointFailed(ctx context.Context, id uuid.UUID, at time.Time) (int, time.Time, error)
+	// DisableEndpoint turns an endpoint off and dead-letters its pending
+	// deliveries
+	DisableEndpoint(ctx context.Context, id uuid.UUID, reason string, at time.Time) error
+
+	// CreateDelivery stores a delivery. It returns false, without error, if
+	// the event was already queued for the endpoint.
+	CreateDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, page pagination.Page) ([]model.WebhookDelivery, string, error)
+	UpdateDelivery(ctx context.Context, d *model.WebhookDelivery) error
+	// ClaimDue returns up to limit pending deliveries of active endpoints
+	// that are due at now, and holds them until leaseUntil so that other
+	// replicas leave them alone while they are attempted
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error)
+}
+
+// PostgresStore is the Store backed by the service's database
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new Postgres store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const endpointColumns = `id, user_id, org_id, url, description, secret, event_types, active, consecutive_failures,
+	failing_since, disabled_at, disabled_reason, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEndpoint(row scanner) (*model.WebhookEndpoint, error) {
+	var e model.WebhookEndpoint
+	var orgID uuid.NullUUID
+	var reason sql.NullString
+	err := row.Scan(&e.ID, &e.UserID, &orgID, &e.URL, &e.Description, &e.Secret, pq.Array(&e.EventTypes), &e.Active,
+		&e.ConsecutiveFailures, &e.FailingSince, &e.DisabledAt, &reason, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if orgID.Valid {
+		e.OrgID = &orgID.UUID
+	}
+	e.DisabledReason = reason.String
+	return &e, nil
+}
+
+func (s *PostgresStore) CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_endpoints (id, user_id, org_id, url, description, secret, event_types, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		e.ID, e.UserID, e.OrgID, e.URL, e.Description, e.Secret, pq.Array(e.EventTypes), e.Active, e.CreatedAt, e.UpdatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetEndpoint(ctx context.Context, id uuid.UUID) (*model.WebhookEndpoint, error) {
+	e, err := scanEndpoint(s.db.QueryRowContext(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrEndpointNotFound
+	}
+	return e, err
+}
+
+func (s *PostgresStore) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]model.WebhookEndpoint, error) {
+	return s.queryEndpoints(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at`, userID)
+}
+
+func (s *PostgresStore) SubscribedEndpoints(ctx context.Context, ownerIDs []uuid.UUID, eventType string) ([]model.WebhookEndpoint, error) {
+	ids := make([]string, len(ownerIDs))
+	for i, id := range ownerIDs {
+		ids[i] = id.String()
+	}
+	return s.queryEndpoints(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints
+		WHERE (user_id = ANY($1::uuid[]) OR org_id = ANY($1::uuid[])) AND active AND $2 = ANY(event_types)`, pq.Array(ids), eventType)
+}
+
+func (s *PostgresStore) queryEndpoints(ctx context.Context, query string, args ...interface{}) ([]model.WebhookEndpoint, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []model.WebhookEndpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *e)
+	}
+	return endpoints, rows.Err()
+}
+
+func (s *PostgresStore) UpdateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_endpoints SET url = $1, description = $2, event_types = $3, active = $4,
+			consecutive_failures = $5, failing_since = $6, disabled_at = $7, disabled_reason = $8, updated_at = $9
+		WHERE id = $10`,
+		e.URL, e.Description, pq.Array(e.EventTypes), e.Active, e.ConsecutiveFailures, e.FailingSince,
+		e.DisabledAt, nullString(e.DisabledReason), e.UpdatedAt, e.ID)
+	return affected(res, err, ErrEndpointNotFound)
+}
+
+func (s *PostgresStore) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	return affected(res, err, ErrEndpointNotFound)
+}
+
+func (s *PostgresStore) EndpointSucceeded(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_endpoints SET consecutive_failures = 0, failing_since = NULL
+		WHERE id = $1 AND consecutive_failures > 0`, id)
+	return err
+}
+
+func (s *PostgresStore) EndpointFailed(ctx context.Context, id uuid.UUID, at time.Time) (int, time.Time, error) {
+	var failures int
+	var since time.Time
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE webhook_endpoints SET consecutive_failures = consecutive_failures + 1, failing_since = COALESCE(failing_since, $1)
+		WHERE id = $2
+		RETURNING consecutive_failures, failing_since`, at, id).Scan(&failures, &since)
+	if err == sql.ErrNoRows {
+		return 0, time.Time{}, ErrEndpointNotFound
+	}
+	return failures, since, err
+}
+
+func (s *PostgresStore) DisableEndpoint(ctx context.Context, id uuid.UUID, reason string, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE webhook_endpoints SET active = FALSE, disabled_at = $1, disabled_reason = $2, updated_at = $1
+		WHERE id = $3`, at, reason, id)
+	if err := affected(res, err, ErrEndpointNotFound); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, next_attempt_at = NULL, error = $2, updated_at = $3
+		WHERE endpoint_id = $4 AND status = $5`,
+		model.DeliveryDead, "endpoint disabled: "+reason, at, id, model.DeliveryPending); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_attempt_at, response_status, response_body, error, redelivery_of, created_at, updated_at`
+
+func scanDelivery(row scanner) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var payload []byte
+	var status sql.NullInt64
+	var body, errText sql.NullString
+	err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &status, &body, &errText, &d.RedeliveryOf, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	d.ResponseStatus = int(status.Int64)
+	d.ResponseBody = body.String
+	d.Error = errText.String
+	return &d, nil
+}
+
+func (s *PostgresStore) CreateDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+			redelivery_of, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (endpoint_id, event_id, event_type) WHERE redelivery_of IS NULL DO NOTHING`,
+		d.ID, d.EndpointID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.Attempts, d.NextAttemptAt,
+		d.RedeliveryOf, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *PostgresStore) GetDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+func (s *PostgresStore) ListDeliveries(ctx context.Context, endpointID uuid.UUID, page pagination.Page) ([]model.WebhookDelivery, string, error) {
+	query, args := page.Apply(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE endpoint_id = $1`, []interface{}{endpointID})
+	deliveries, err := s.queryDeliveries(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	deliveries, next := pagination.Cut(page, deliveries, model.WebhookDelivery.SortKey)
+	return deliveries, next, nil
+}
+
+func (s *PostgresStore) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]model.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *PostgresStore) UpdateDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	var status interface{}
+	if d.ResponseStatus != 0 {
+		status = d.ResponseStatus
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+			response_status = $5, response_body = $6, error = $7, updated_at = $8
+		WHERE id = $9`,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastAttemptAt, status, nullString(d.ResponseBody),
+		nullString(d.Error), d.UpdatedAt, d.ID)
+	return affected(res, err, ErrDeliveryNotFound)
+}
+
+func (s *PostgresStore) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error) {
+	return s.queryDeliveries(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status = $2 AND d.next_attempt_at <= $3 AND e.active
+			ORDER BY d.next_attempt_at
+			LIMIT $4
+			FOR UPDATE OF d SKIP LOCKED)
+		RETURNING `+deliveryColumns, leaseUntil, model.DeliveryPending, now, limit)
+}
+
+func affected(res sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
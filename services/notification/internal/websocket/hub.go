@@ -51,6 +51,7 @@ func (h *Hub) Run() {
 			}
 			h.clients[client.UserID][client] = true
 			h.mu.Unlock()
+			connections.Inc()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
@@ -58,6 +59,7 @@ func (h *Hub) Run() {
 				if _, ok := clients[client]; ok {
 					delete(clients, client)
 					close(client.Send)
+					connections.Dec()
 					if len(clients) == 0 {
 						delete(h.clients, client.UserID)
 					}
@@ -74,6 +76,7 @@ func (h *Hub) Run() {
 					default:
 						close(client.Send)
 						delete(clients, client)
+						connections.Dec()
 					}
 				}
 			}
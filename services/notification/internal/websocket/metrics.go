@@ -0,0 +1,11 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var connections = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "truckify_websocket_connections",
+	Help: "Open WebSocket connections in the notification hub.",
+})
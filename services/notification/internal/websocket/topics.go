@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"truckify/shared/pkg/events"
+)
+
+// Topics fans events out to internal subscribers, such as the gateway's
+// GraphQL subscriptions. Topics are "job:<job id>" for job status changes
+// and "driver:<driver user id>" for driver locations.
+type Topics struct {
+	subscribers map[string]map[*TopicClient]bool
+	mu          sync.Mutex
+}
+
+// TopicClient is a connection subscribed to any number of topics
+type TopicClient struct {
+	Conn   *websocket.Conn
+	Send   chan []byte
+	topics map[string]bool
+}
+
+// TopicMessage is sent to the subscribers of a topic
+type TopicMessage struct {
+	Type    string      `json:"type"`
+	Topic   string      `json:"topic"`
+	Payload interface{} `json:"payload"`
+}
+
+func NewTopics() *Topics {
+	return &Topics{subscribers: make(map[string]map[*TopicClient]bool)}
+}
+
+func NewTopicClient(conn *websocket.Conn) *TopicClient {
+	return &TopicClient{Conn: conn, Send: make(chan []byte, 256), topics: make(map[string]bool)}
+}
+
+// Subscribe adds a client to a topic
+func (t *Topics) Subscribe(client *TopicClient, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if client.topics == nil {
+		return // already left
+	}
+	if t.subscribers[topic] == nil {
+		t.subscribers[topic] = make(map[*TopicClient]bool)
+	}
+	t.subscribers[topic][client] = true
+	client.topics[topic] = true
+}
+
+// Unsubscribe removes a client from a topic
+func (t *Topics) Unsubscribe(client *TopicClient, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(client, topic)
+}
+
+// Leave removes a client from all its topics and closes its Send channel
+func (t *Topics) Leave(client *TopicClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leave(client)
+}
+
+// Publish sends a message to every subscriber of a topic. Subscribers that
+// cannot keep up are dropped.
+func (t *Topics) Publish(topic, msgType string, payload interface{}) {
+	data, err := json.Marshal(TopicMessage{Type: msgType, Topic: topic, Payload: payload})
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for client := range t.subscribers[topic] {
+		select {
+		case client.Send <- data:
+		default:
+			t.leave(client)
+		}
+	}
+}
+
+// Forward publishes job status changes and driver locations from the event
+// broker to their topics
+func (t *Topics) Forward(sub events.Subscriber) error {
+	_, err := sub.Subscribe(events.TypeJobStatusChanged, func(ctx context.Context, evt *events.Event) error {
+		var data events.JobStatusChangedV1
+		if err := evt.Decode(&data); err != nil {
+			return err
+		}
+		t.Publish("job:"+data.JobID.String(), evt.Type, evt.Data)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = sub.Subscribe(events.TypeTrackingLocationUpdated, func(ctx context.Context, evt *events.Event) error {
+		var data events.TrackingLocationUpdatedV1
+		if err := evt.Decode(&data); err != nil {
+			return err
+		}
+		t.Publish("driver:"+data.DriverID.String(), evt.Type, evt.Data)
+		return nil
+	})
+	return err
+}
+
+func (t *Topics) remove(client *TopicClient, topic string) {
+	if subscribers, ok := t.subscribers[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(t.subscribers, topic)
+		}
+	}
+	delete(client.topics, topic)
+}
+
+func (t *Topics) leave(client *TopicClient) {
+	if client.topics == nil {
+		return
+	}
+	for topic := range client.topics {
+		t.remove(client, topic)
+	}
+	client.topics = nil
+	close(client.Send)
+}
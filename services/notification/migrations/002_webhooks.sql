@@ -0,0 +1,45 @@
+-- Webhook endpoints receive signed event notifications for the user who
+-- registered them. The secret signs every payload, so it is kept as is.
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    secret VARCHAR(100) NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    failing_since TIMESTAMP,
+    disabled_at TIMESTAMP,
+    disabled_reason VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_user ON webhook_endpoints(user_id);
+
+-- One row per event per endpoint. Pending rows are retried with backoff
+-- until they succeed or run out of attempts and are dead-lettered.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- An event reaches an endpoint once per type, however often the broker
+-- delivers it
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries(endpoint_id, event_id, event_type) WHERE redelivery_of IS NULL;
+CREATE INDEX idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+-- The dispatcher who sent a message for its sender under a delegation
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sent_by UUID;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_webhook_endpoints_org;
+ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS org_id;
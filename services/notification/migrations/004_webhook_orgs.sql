@@ -0,0 +1,5 @@
+-- Endpoints an organisation's owner registers for it also receive the
+-- events of the organisation's jobs and fleet
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS org_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_org ON webhook_endpoints(org_id) WHERE org_id IS NOT NULL;
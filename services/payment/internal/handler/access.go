@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"truckify/services/payment/internal/model"
+	"truckify/services/payment/internal/service"
+	"truckify/shared/pkg/authz"
+)
+
+// paymentParties resolves the payer, the payer's organisation and the payee
+// as owners of the payment
+func (h *Handler) paymentParties(r *http.Request) ([]uuid.UUID, error) {
+	p, err := h.targetPayment(r)
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{p.PayerID, p.PayerOrgID, p.PayeeID}, nil
+}
+
+// paymentPayer resolves the payer and their organisation, who alone may
+// process the payment
+func (h *Handler) paymentPayer(r *http.Request) ([]uuid.UUID, error) {
+	p, err := h.targetPayment(r)
+	if err != nil {
+		return nil, err
+	}
+	return []uuid.UUID{p.PayerID, p.PayerOrgID}, nil
+}
+
+func (h *Handler) targetPayment(r *http.Request) (*model.Payment, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	p, err := h.service.GetPayment(r.Context(), id)
+	if err == service.ErrPaymentNotFound {
+		return nil, authz.ErrResourceNotFound
+	}
+	return p, err
+}
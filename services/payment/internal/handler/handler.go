@@ -11,6 +11,7 @@ import (
 	"truckify/services/payment/internal/model"
 	"truckify/services/payment/internal/service"
 	stripeClient "truckify/services/payment/internal/stripe"
+	"truckify/shared/pkg/authz"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -35,20 +36,21 @@ type Handler struct {
 	service   ServiceInterface
 	validator *validator.Validator
 	stripe    *stripeClient.Client
+	authz     *authz.Authorizer
 }
 
 func New(svc ServiceInterface) *Handler {
-	return &Handler{service: svc, validator: validator.New(), stripe: stripeClient.New()}
+	return &Handler{service: svc, validator: validator.New(), stripe: stripeClient.New(), authz: authz.New(authz.DefaultPolicy())}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/payments", h.CreatePayment).Methods(http.MethodPost)
-	router.HandleFunc("/payments/{id}", h.GetPayment).Methods(http.MethodGet)
-	router.HandleFunc("/payments/{id}/process", h.ProcessPayment).Methods(http.MethodPost)
-	router.HandleFunc("/payments/{id}/refund", h.RefundPayment).Methods(http.MethodPost)
+	router.HandleFunc("/payments", h.authz.RequireUnscoped(authz.PaymentCreate, h.CreatePayment)).Methods(http.MethodPost)
+	router.HandleFunc("/payments/{id}", h.authz.RequireOwner(authz.PaymentRead, h.paymentParties, h.GetPayment)).Methods(http.MethodGet)
+	router.HandleFunc("/payments/{id}/process", h.authz.RequireOwner(authz.PaymentProcess, h.paymentPayer, h.ProcessPayment)).Methods(http.MethodPost)
+	router.HandleFunc("/payments/{id}/refund", h.authz.RequireUnscoped(authz.PaymentRefund, h.RefundPayment)).Methods(http.MethodPost)
 	
 	// Stripe endpoints
-	router.HandleFunc("/checkout", h.CreateCheckout).Methods(http.MethodPost)
+	router.HandleFunc("/checkout", h.authz.RequireUnscoped(authz.PaymentCreate, h.CreateCheckout)).Methods(http.MethodPost)
 	router.HandleFunc("/checkout/subscription", h.CreateSubscriptionCheckout).Methods(http.MethodPost)
 	router.HandleFunc("/webhook/stripe", h.StripeWebhook).Methods(http.MethodPost)
 	
@@ -72,6 +74,14 @@ func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 		response.BadRequest(w, "Invalid request", err.Error(), reqID)
 		return
 	}
+	principal, _ := authz.PrincipalFrom(r.Context())
+	if !h.authz.Can(principal, authz.PaymentCreate, req.PayerID) {
+		response.Forbidden(w, "Not authorized to create payments for this payer", "", reqID)
+		return
+	}
+	if req.PayerID == principal.UserID {
+		req.PayerOrgID = principal.Org()
+	}
 	p, err := h.service.CreatePayment(r.Context(), req)
 	if err != nil {
 		response.InternalServerError(w, "Failed to create payment", "", reqID)
@@ -257,7 +267,11 @@ func (h *Handler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get job details from job service
-	jobResp, err := http.Get(fmt.Sprintf("http://job-service:8006/jobs/%s", req.JobID))
+	jobReq, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, fmt.Sprintf("http://job-service:8006/jobs/%s", req.JobID), nil)
+	if principal, ok := authz.PrincipalFrom(r.Context()); ok {
+		principal.Forward(jobReq)
+	}
+	jobResp, err := http.DefaultClient.Do(jobReq)
 	if err != nil || jobResp.StatusCode != 200 {
 		response.BadRequest(w, "Job not found", "", reqID)
 		return
@@ -266,16 +280,19 @@ func (h *Handler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
 
 	var jobData struct {
 		Data struct {
-			ID        string  `json:"id"`
-			ShipperID string  `json:"shipper_id"`
-			DriverID  string  `json:"driver_id"`
-			Price     float64 `json:"price"`
-			CargoType string  `json:"cargo_type"`
+			ID        string    `json:"id"`
+			ShipperID uuid.UUID `json:"shipper_id"`
+			OrgID     uuid.UUID `json:"org_id"`
+			DriverID  string    `json:"driver_id"`
+			Price     float64   `json:"price"`
+			CargoType string    `json:"cargo_type"`
 		} `json:"data"`
 	}
 	json.NewDecoder(jobResp.Body).Decode(&jobData)
 
-	if jobData.Data.ShipperID != userID.String() {
+	// The shipper or a member of the organisation that owns the job pays
+	principal, _ := authz.PrincipalFrom(r.Context())
+	if jobData.Data.ShipperID != userID && !(jobData.Data.OrgID != uuid.Nil && principal.Owns(jobData.Data.OrgID)) {
 		response.Forbidden(w, "Not authorized to pay for this job", "", reqID)
 		return
 	}
@@ -286,6 +303,7 @@ func (h *Handler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
 	sess, err := h.stripe.CreateCheckoutSession(stripeClient.CheckoutParams{
 		JobID:       req.JobID.String(),
 		PayerID:     userID.String(),
+		PayerOrgID:  jobData.Data.OrgID.String(),
 		PayeeID:     jobData.Data.DriverID,
 		Amount:      int64(jobData.Data.Price * 100),
 		PlatformFee: int64(calc.PlatformFee * 100),
@@ -351,12 +369,14 @@ func (h *Handler) StripeWebhook(w http.ResponseWriter, r *http.Request) {
 			// Job payment
 			jobID, _ := uuid.Parse(event.JobID)
 			payerID, _ := uuid.Parse(event.PayerID)
+			payerOrgID, _ := uuid.Parse(event.PayerOrgID)
 			payeeID, _ := uuid.Parse(event.PayeeID)
 			h.service.CreatePayment(r.Context(), model.CreatePaymentRequest{
-				JobID:   jobID,
-				PayerID: payerID,
-				PayeeID: payeeID,
-				Amount:  float64(event.Amount) / 100,
+				JobID:      jobID,
+				PayerID:    payerID,
+				PayeeID:    payeeID,
+				Amount:     float64(event.Amount) / 100,
+				PayerOrgID: payerOrgID,
 			})
 			http.Post(fmt.Sprintf("http://job-service:8006/jobs/%s/paid", event.JobID), "application/json", nil)
 		} else if event.UserID != "" && event.TierID != "" {
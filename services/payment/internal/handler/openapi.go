@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"truckify/services/payment/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// checkoutSession is the Stripe session a checkout redirects to
+type checkoutSession struct {
+	CheckoutURL string `json:"checkout_url"`
+	SessionID   string `json:"session_id"`
+}
+
+// Routes documents the endpoints registered by RegisterRoutes and the
+// server's health check
+var Routes = openapi.Routes{
+	"POST /payments":              {Summary: "Create a payment", Request: model.CreatePaymentRequest{}, Response: model.Payment{}, Status: http.StatusCreated},
+	"GET /payments/{id}":          {Summary: "Get a payment", Response: model.Payment{}},
+	"POST /payments/{id}/process": {Summary: "Process a pending payment", Response: model.Payment{}},
+	"POST /payments/{id}/refund":  {Summary: "Refund a payment", Response: model.Payment{}},
+
+	"POST /checkout":              {Summary: "Start a Stripe checkout for a job", Request: model.CheckoutRequest{}, Response: checkoutSession{}},
+	"POST /checkout/subscription": {Summary: "Start a Stripe checkout for a subscription", Request: model.SubscriptionCheckoutRequest{}, Response: checkoutSession{}},
+	"POST /webhook/stripe": {
+		Summary:     "Receive Stripe events",
+		Description: "Called by Stripe with a signed event. Replies with an empty body.",
+		Raw:         true,
+	},
+
+	"GET /pricing/tiers":            {Summary: "List subscription tiers", Response: []model.SubscriptionTier{}},
+	"GET /pricing/commission-tiers": {Summary: "List commission discounts by job value", Response: []model.CommissionTier{}},
+	"POST /pricing/calculate": {
+		Summary: "Calculate the fees on a job",
+		Request: struct {
+			DriverID  uuid.UUID `json:"driver_id" validate:"required"`
+			JobAmount float64   `json:"job_amount" validate:"required,gt=0"`
+		}{},
+		Response: model.FeeCalculation{},
+	},
+	"GET /subscription": {
+		Summary:     "Get the caller's subscription",
+		Description: `Returns {"tier":"free","status":"none"} when the caller has no subscription.`,
+	},
+	"POST /subscription":   {Summary: "Subscribe to a tier", Request: model.SubscribeRequest{}, Response: model.Subscription{}, Status: http.StatusCreated},
+	"DELETE /subscription": {Summary: "Cancel the caller's subscription", Response: map[string]string{}},
+
+	"GET /health": {Summary: "Health check", Response: map[string]string{}, Raw: true},
+}
@@ -14,10 +14,13 @@ const (
 	StatusRefunded  PaymentStatus = "refunded"
 )
 
+// Payment is a shipper's payment for a job. The organisation the payer
+// acted for owns it alongside the payer.
 type Payment struct {
 	ID          uuid.UUID     `json:"id" db:"id"`
 	JobID       uuid.UUID     `json:"job_id" db:"job_id"`
 	PayerID     uuid.UUID     `json:"payer_id" db:"payer_id"`
+	PayerOrgID  uuid.UUID     `json:"payer_org_id" db:"payer_org_id"`
 	PayeeID     uuid.UUID     `json:"payee_id" db:"payee_id"`
 	Amount      float64       `json:"amount" db:"amount"`
 	PlatformFee float64       `json:"platform_fee" db:"platform_fee"`
@@ -32,6 +35,9 @@ type CreatePaymentRequest struct {
 	PayerID uuid.UUID `json:"payer_id" validate:"required"`
 	PayeeID uuid.UUID `json:"payee_id" validate:"required"`
 	Amount  float64   `json:"amount" validate:"required,gt=0"`
+	// PayerOrgID is the organisation the payer acts for, taken from the
+	// caller rather than the body. It defaults to the payer's personal one.
+	PayerOrgID uuid.UUID `json:"-"`
 }
 
 type SubscriptionTier struct {
@@ -9,16 +9,25 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"truckify/services/payment/internal/model"
+	"truckify/shared/pkg/events"
 )
 
+const eventSource = "payment-service"
+
+// statusEvents maps payment statuses to the event emitted on entering them
+var statusEvents = map[model.PaymentStatus]string{
+	model.StatusCompleted: events.TypePaymentCompleted,
+	model.StatusRefunded:  events.TypePaymentRefunded,
+}
+
 type Repository struct{ db *sqlx.DB }
 
 func New(db *sqlx.DB) *Repository { return &Repository{db: db} }
 
 func (r *Repository) Create(ctx context.Context, p *model.Payment) error {
-	query := `INSERT INTO payments (id, job_id, payer_id, payee_id, amount, platform_fee, driver_payout, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	_, err := r.db.ExecContext(ctx, query, p.ID, p.JobID, p.PayerID, p.PayeeID, p.Amount, p.PlatformFee, p.DriverPayout, p.Status, p.CreatedAt, p.UpdatedAt)
+	query := `INSERT INTO payments (id, job_id, payer_id, payer_org_id, payee_id, amount, platform_fee, driver_payout, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := r.db.ExecContext(ctx, query, p.ID, p.JobID, p.PayerID, p.PayerOrgID, p.PayeeID, p.Amount, p.PlatformFee, p.DriverPayout, p.Status, p.CreatedAt, p.UpdatedAt)
 	return err
 }
 
@@ -32,8 +41,31 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*model.Payment,
 }
 
 func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status model.PaymentStatus) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", status, time.Now(), id)
-	return err
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var p model.Payment
+	err = tx.GetContext(ctx, &p, "UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3 RETURNING *", status, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	if eventType, ok := statusEvents[status]; ok {
+		evt, err := events.New(ctx, eventSource, eventType, 1, p.ID.String(), events.PaymentV1{
+			PaymentID: p.ID, JobID: p.JobID, PayerID: p.PayerID, PayeeID: p.PayeeID,
+			Amount: p.Amount, PlatformFee: p.PlatformFee, DriverPayout: p.DriverPayout, Status: string(p.Status),
+		})
+		if err != nil {
+			return err
+		}
+		if err := events.WriteOutbox(ctx, tx, evt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*model.Payment, error) {
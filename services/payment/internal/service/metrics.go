@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	paymentsCompleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "truckify_payments_completed_total",
+		Help: "Payments processed to completion.",
+	})
+
+	paymentVolume = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "truckify_payments_completed_amount_total",
+		Help: "Sum of completed payment amounts.",
+	})
+)
@@ -25,11 +25,16 @@ func New(repo *repository.Repository) *Service { return &Service{repo: repo} }
 func (s *Service) CreatePayment(ctx context.Context, req model.CreatePaymentRequest) (*model.Payment, error) {
 	// Calculate fees based on payee's subscription
 	calc, _ := s.CalculateFees(ctx, req.PayeeID, req.Amount)
-	
+	payerOrgID := req.PayerOrgID
+	if payerOrgID == uuid.Nil {
+		payerOrgID = req.PayerID
+	}
+
 	p := &model.Payment{
 		ID:           uuid.New(),
 		JobID:        req.JobID,
 		PayerID:      req.PayerID,
+		PayerOrgID:   payerOrgID,
 		PayeeID:      req.PayeeID,
 		Amount:       req.Amount,
 		PlatformFee:  calc.PlatformFee,
@@ -67,6 +72,8 @@ func (s *Service) ProcessPayment(ctx context.Context, id uuid.UUID) (*model.Paym
 		return nil, err
 	}
 	p.Status = model.StatusCompleted
+	paymentsCompleted.Inc()
+	paymentVolume.Add(p.Amount)
 	return p, nil
 }
 
@@ -27,6 +27,7 @@ func New() *Client {
 type CheckoutParams struct {
 	JobID        string
 	PayerID      string
+	PayerOrgID   string
 	PayeeID      string
 	Amount       int64 // cents
 	PlatformFee  int64 // cents
@@ -57,6 +58,7 @@ func (c *Client) CreateCheckoutSession(params CheckoutParams) (*stripe.CheckoutS
 		Metadata: map[string]string{
 			"job_id":       params.JobID,
 			"payer_id":     params.PayerID,
+			"payer_org_id": params.PayerOrgID,
 			"payee_id":     params.PayeeID,
 			"platform_fee": fmt.Sprintf("%d", params.PlatformFee),
 		},
@@ -132,6 +134,7 @@ type WebhookEvent struct {
 	SubscriptionID string
 	JobID          string
 	PayerID        string
+	PayerOrgID     string
 	PayeeID        string
 	UserID         string
 	TierID         string
@@ -164,6 +167,7 @@ func (c *Client) ParseWebhook(r *http.Request) (*WebhookEvent, error) {
 		we.Amount = sess.AmountTotal
 		we.JobID = sess.Metadata["job_id"]
 		we.PayerID = sess.Metadata["payer_id"]
+		we.PayerOrgID = sess.Metadata["payer_org_id"]
 		we.PayeeID = sess.Metadata["payee_id"]
 		we.UserID = sess.Metadata["user_id"]
 		we.TierID = sess.Metadata["tier_id"]
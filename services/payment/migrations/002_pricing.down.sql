@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS platform_settings;
+DROP TABLE IF EXISTS commission_tiers;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS subscription_tiers;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_payer_org_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS payer_org_id;
@@ -0,0 +1,8 @@
+-- Payments belong to the organisation the payer acted for. Every user's
+-- personal organisation shares their ID, so existing payments move to their
+-- payer's personal organisation.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS payer_org_id UUID;
+UPDATE payments SET payer_org_id = payer_id WHERE payer_org_id IS NULL;
+ALTER TABLE payments ALTER COLUMN payer_org_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_payer_org_id ON payments(payer_org_id);
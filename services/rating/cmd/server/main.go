@@ -13,10 +13,14 @@ import (
 	"truckify/services/rating/internal/handler"
 	"truckify/services/rating/internal/repository"
 	"truckify/services/rating/internal/service"
+	"truckify/services/rating/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
@@ -25,6 +29,12 @@ func main() {
 	log := logger.New("rating-service", logLevel)
 	log.Info("Starting Rating Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "rating-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database connection
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
@@ -40,6 +50,17 @@ func main() {
 	defer database.ClosePostgresDB(db)
 	log.Info("Connected to PostgreSQL")
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	// Initialize repository
 	repo := repository.New(db)
 
@@ -54,12 +75,17 @@ func main() {
 
 	// Apply middlewares
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("rating-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.SecurityHeaders)
 
 	// Register routes
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "rating-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	// Wrap router with CORS
 	corsHandler := middleware.CORS([]string{"*"})(router)
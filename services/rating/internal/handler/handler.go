@@ -9,6 +9,7 @@ import (
 	"truckify/services/rating/internal/model"
 	"truckify/services/rating/internal/service"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
 )
@@ -16,7 +17,7 @@ import (
 // ServiceInterface defines the interface for rating service operations
 type ServiceInterface interface {
 	CreateRating(ctx context.Context, raterID uuid.UUID, req *model.CreateRatingRequest) (*model.RatingResponse, error)
-	GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]model.RatingResponse, error)
+	GetRatingsByUser(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.RatingResponse, string, error)
 	GetRatingsByJob(ctx context.Context, jobID uuid.UUID) ([]model.RatingResponse, error)
 	GetUserRatingStats(ctx context.Context, userID uuid.UUID) (*model.UserRatingStats, error)
 }
@@ -88,15 +89,21 @@ func (h *Handler) GetRatingsByUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ratings, err := h.service.GetRatingsByUser(r.Context(), userID)
+	page, err := pagination.Parse(r.URL.Query(), model.RatingListSpec)
+	if err != nil {
+		response.BadRequest(w, "Invalid list parameters", err.Error(), requestID)
+		return
+	}
+
+	ratings, next, err := h.service.GetRatingsByUser(r.Context(), userID, page)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
 	}
 
-	response.Success(w, map[string]interface{}{
+	response.List(w, map[string]interface{}{
 		"ratings": ratings,
-	}, requestID)
+	}, next, requestID)
 }
 
 // GetRatingsByJob handles getting ratings for a job
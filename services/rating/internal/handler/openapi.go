@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/rating/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// ratingList wraps the ratings returned by the list endpoints
+type ratingList struct {
+	Ratings []model.RatingResponse `json:"ratings"`
+}
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /ratings":          {Summary: "Rate the other party on a job", Request: model.CreateRatingRequest{}, Response: model.RatingResponse{}, Status: http.StatusCreated},
+	"GET /ratings/user/{id}": {Summary: "List the ratings a user has received", Response: ratingList{}, List: &model.RatingListSpec},
+	"GET /ratings/job/{id}":  {Summary: "List the ratings on a job", Response: ratingList{}},
+	"GET /health":            {Summary: "Health check", Response: map[string]interface{}{}},
+}
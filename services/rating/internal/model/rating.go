@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"truckify/shared/pkg/pagination"
 )
 
 // Rating represents a rating in the system
@@ -17,6 +18,23 @@ type Rating struct {
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
 
+// RatingListSpec is what a user's ratings can be filtered and sorted by
+var RatingListSpec = pagination.Spec{
+	Fields: map[string]pagination.Field{
+		"rating":     {Column: "rating", Kind: pagination.Number, Sortable: true},
+		"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+// SortKey returns the value of a RatingListSpec sort field and the rating's ID
+func (r Rating) SortKey(field string) (interface{}, uuid.UUID) {
+	if field == "rating" {
+		return float64(r.Rating), r.ID
+	}
+	return r.CreatedAt, r.ID
+}
+
 // CreateRatingRequest represents a request to create a rating
 type CreateRatingRequest struct {
 	JobID   uuid.UUID `json:"job_id" validate:"required"`
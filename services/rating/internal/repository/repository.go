@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"truckify/services/rating/internal/model"
+	"truckify/shared/pkg/pagination"
 )
 
 var (
@@ -44,17 +45,17 @@ func (r *Repository) CreateRating(ctx context.Context, rating *model.Rating) err
 	return nil
 }
 
-// GetRatingsByUser gets all ratings for a specific user
-func (r *Repository) GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]model.Rating, error) {
-	query := `
+// GetRatingsByUser gets a page of the ratings for a specific user and the
+// cursor of the next page
+func (r *Repository) GetRatingsByUser(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.Rating, string, error) {
+	query, args := page.Apply(`
 		SELECT id, job_id, rater_id, ratee_id, rating, comment, created_at
 		FROM ratings
-		WHERE ratee_id = $1
-		ORDER BY created_at DESC`
+		WHERE ratee_id = $1`, []interface{}{userID})
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -66,12 +67,13 @@ func (r *Repository) GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]
 			&rating.Rating, &rating.Comment, &rating.CreatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		ratings = append(ratings, rating)
 	}
 
-	return ratings, rows.Err()
+	ratings, next := pagination.Cut(page, ratings, model.Rating.SortKey)
+	return ratings, next, rows.Err()
 }
 
 // GetRatingsByJob gets all ratings for a specific job
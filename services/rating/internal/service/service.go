@@ -9,6 +9,7 @@ import (
 	"truckify/services/rating/internal/model"
 	"truckify/services/rating/internal/repository"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/pagination"
 )
 
 var (
@@ -21,7 +22,7 @@ var (
 // RepositoryInterface defines the interface for rating repository operations
 type RepositoryInterface interface {
 	CreateRating(ctx context.Context, rating *model.Rating) error
-	GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]model.Rating, error)
+	GetRatingsByUser(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.Rating, string, error)
 	GetRatingsByJob(ctx context.Context, jobID uuid.UUID) ([]model.Rating, error)
 	GetUserRatingStats(ctx context.Context, userID uuid.UUID) (*model.UserRatingStats, error)
 	CheckRatingExists(ctx context.Context, jobID, raterID uuid.UUID) (bool, error)
@@ -83,12 +84,13 @@ func (s *Service) CreateRating(ctx context.Context, raterID uuid.UUID, req *mode
 	return rating.ToRatingResponse(), nil
 }
 
-// GetRatingsByUser gets all ratings for a specific user
-func (s *Service) GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]model.RatingResponse, error) {
-	ratings, err := s.repo.GetRatingsByUser(ctx, userID)
+// GetRatingsByUser gets a page of the ratings for a specific user and the
+// cursor of the next page
+func (s *Service) GetRatingsByUser(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]model.RatingResponse, string, error) {
+	ratings, next, err := s.repo.GetRatingsByUser(ctx, userID, page)
 	if err != nil {
 		s.logger.Error("Failed to get ratings by user", "error", err, "user_id", userID)
-		return nil, err
+		return nil, "", err
 	}
 
 	responses := make([]model.RatingResponse, len(ratings))
@@ -96,7 +98,7 @@ func (s *Service) GetRatingsByUser(ctx context.Context, userID uuid.UUID) ([]mod
 		responses[i] = *rating.ToRatingResponse()
 	}
 
-	return responses, nil
+	return responses, next, nil
 }
 
 // GetRatingsByJob gets all ratings for a specific job
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,18 +14,30 @@ import (
 	"truckify/services/route/internal/service"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
 	log := logger.New("route-service", config.GetEnv("LOG_LEVEL", "info"))
 	log.Info("Starting Route Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "route-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	svc := service.New()
 	h := handler.New(svc)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("route-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 
@@ -33,7 +46,9 @@ func main() {
 		w.Write([]byte(`{"status":"healthy"}`))
 	}).Methods(http.MethodGet)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "route-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "*"})(router)
 
 	port := config.GetEnv("PORT", "8009")
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"truckify/services/route/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes and the
+// server's health check
+var Routes = openapi.Routes{
+	"POST /route":          {Summary: "Estimate a route's distance, duration and costs", Request: model.RouteRequest{}, Response: model.RouteResponse{}},
+	"POST /route/optimize": {Summary: "Order stops for the shortest route", Request: model.OptimizeRequest{}, Response: model.OptimizeResponse{}},
+	"GET /health":          {Summary: "Health check", Response: map[string]string{}, Raw: true},
+}
@@ -14,10 +14,16 @@ import (
 	"truckify/services/tracking/internal/handler"
 	"truckify/services/tracking/internal/repository"
 	"truckify/services/tracking/internal/service"
+	"truckify/services/tracking/migrations"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
+	"truckify/shared/pkg/events"
+	"truckify/shared/pkg/idempotency"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
@@ -26,6 +32,12 @@ func main() {
 	log := logger.New("tracking-service", logLevel)
 	log.Info("Starting Tracking Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "tracking-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database connection
 	sqlDB, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
@@ -39,7 +51,37 @@ func main() {
 		log.Fatal("Failed to connect to database", "error", err)
 	}
 	defer database.ClosePostgresDB(sqlDB)
+
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), sqlDB, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), sqlDB, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
 	
+	// Relay outbox events to NATS
+	if natsURL := config.GetEnv("NATS_URL", ""); natsURL != "" {
+		broker, err := events.NewNATSBroker(natsURL, "tracking-service", log)
+		if err != nil {
+			log.Fatal("Failed to connect to NATS", "error", err)
+		}
+		defer broker.Close()
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go events.NewRelay(sqlDB, broker, log).Run(relayCtx)
+		log.Info("Connected to NATS")
+	}
+
+	// Replay responses to retried requests that carry an Idempotency-Key
+	idempotencyStore := idempotency.NewPostgresStore(sqlDB)
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go idempotencyStore.Run(purgeCtx, log)
+
 	// Wrap with sqlx
 	db := sqlx.NewDb(sqlDB, "postgres")
 	log.Info("Connected to PostgreSQL")
@@ -48,7 +90,9 @@ func main() {
 	repo := repository.New(db)
 
 	// Initialize service
-	svc := service.New(repo, log)
+	jobSvcURL := config.GetEnv("JOB_SERVICE_URL", "http://localhost:8006")
+	fleetSvcURL := config.GetEnv("FLEET_SERVICE_URL", "http://localhost:8005")
+	svc := service.New(repo, jobSvcURL, fleetSvcURL, log)
 
 	// Initialize handler
 	h := handler.New(svc, log)
@@ -58,12 +102,18 @@ func main() {
 
 	// Apply middlewares
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("tracking-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.SecurityHeaders)
+	router.Use(idempotency.Middleware(idempotencyStore, log))
 
 	// Register routes
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "tracking-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	// Wrap router with CORS
 	corsHandler := middleware.CORS([]string{"*"})(router)
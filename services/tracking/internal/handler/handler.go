@@ -8,6 +8,7 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/tracking/internal/model"
 	"truckify/services/tracking/internal/repository"
+	"truckify/shared/pkg/authz"
 	"truckify/shared/pkg/logger"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
@@ -18,6 +19,7 @@ type ServiceInterface interface {
 	UpdateLocation(ctx context.Context, req *model.LocationUpdateRequest) error
 	GetJobTrackingHistory(ctx context.Context, jobID uuid.UUID) ([]model.TrackingEvent, error)
 	GetDriverCurrentLocation(ctx context.Context, driverID uuid.UUID) (*model.CurrentLocationResponse, error)
+	GetDriverOwners(ctx context.Context, driverID uuid.UUID) ([]uuid.UUID, error)
 	GetStops(ctx context.Context, jobID uuid.UUID) ([]model.Stop, error)
 }
 
@@ -25,6 +27,7 @@ type ServiceInterface interface {
 type Handler struct {
 	service   ServiceInterface
 	validator *validator.Validator
+	authz     *authz.Authorizer
 	logger    *logger.Logger
 }
 
@@ -33,6 +36,7 @@ func New(service ServiceInterface, logger *logger.Logger) *Handler {
 	return &Handler{
 		service:   service,
 		validator: validator.New(),
+		authz:     authz.New(authz.DefaultPolicy()),
 		logger:    logger,
 	}
 }
@@ -42,7 +46,7 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/tracking/update", h.UpdateLocation).Methods(http.MethodPost)
 	router.HandleFunc("/tracking/job/{id}", h.GetJobTrackingHistory).Methods(http.MethodGet)
 	router.HandleFunc("/tracking/job/{id}/stops", h.GetStops).Methods(http.MethodGet)
-	router.HandleFunc("/tracking/driver/{id}/current", h.GetDriverCurrentLocation).Methods(http.MethodGet)
+	router.HandleFunc("/tracking/driver/{id}/current", h.authz.RequireOwner(authz.LocationRead, h.driverOwners, h.GetDriverCurrentLocation)).Methods(http.MethodGet)
 	router.HandleFunc("/health", h.Health).Methods(http.MethodGet)
 }
 
@@ -111,6 +115,15 @@ func (h *Handler) GetDriverCurrentLocation(w http.ResponseWriter, r *http.Reques
 	response.Success(w, location, requestID)
 }
 
+// driverOwners resolves who may see the location of the driver in the path
+func (h *Handler) driverOwners(r *http.Request) ([]uuid.UUID, error) {
+	driverID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, authz.ErrInvalidResourceID
+	}
+	return h.service.GetDriverOwners(r.Context(), driverID)
+}
+
 // Health handles health check requests
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	requestID, _ := r.Context().Value("request_id").(string)
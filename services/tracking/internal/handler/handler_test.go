@@ -9,10 +9,13 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"truckify/services/tracking/internal/model"
+	"truckify/services/tracking/internal/repository"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/openapi"
 )
 
 // MockService is a mock implementation of ServiceInterface
@@ -38,6 +41,14 @@ func (m *MockService) GetDriverCurrentLocation(ctx context.Context, driverID uui
 	return args.Get(0).(*model.CurrentLocationResponse), args.Error(1)
 }
 
+func (m *MockService) GetDriverOwners(ctx context.Context, driverID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, driverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 func (m *MockService) GetStops(ctx context.Context, jobID uuid.UUID) ([]model.Stop, error) {
 	args := m.Called(ctx, jobID)
 	if args.Get(0) == nil {
@@ -104,4 +115,57 @@ func TestHandler_Health(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "healthy", response["data"].(map[string]interface{})["status"])
 	assert.Equal(t, "tracking-service", response["data"].(map[string]interface{})["service"])
-}
\ No newline at end of file
+}
+func TestHandler_GetDriverCurrentLocation_Access(t *testing.T) {
+	driverID := uuid.New()
+	shipperID := uuid.New()
+	location := &model.CurrentLocationResponse{DriverID: driverID, Latitude: -33.8688, Longitude: 151.2093}
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		userType string
+		owners   []uuid.UUID
+		found    bool
+		want     int
+	}{
+		{"driver sees themselves", driverID, "driver", []uuid.UUID{driverID}, true, http.StatusOK},
+		{"shipper on the driver's job", shipperID, "shipper", []uuid.UUID{driverID, shipperID}, true, http.StatusOK},
+		{"shipper without a job with the driver", shipperID, "shipper", []uuid.UUID{driverID}, true, http.StatusForbidden},
+		{"driver with no positions yet", driverID, "driver", []uuid.UUID{driverID}, false, http.StatusNotFound},
+		{"admin sees any driver", uuid.New(), "admin", nil, true, http.StatusOK},
+		{"dispatcher cannot", uuid.New(), "dispatcher", nil, true, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			if tt.owners != nil {
+				mockService.On("GetDriverOwners", mock.Anything, driverID).Return(tt.owners, nil)
+			}
+			if tt.found {
+				mockService.On("GetDriverCurrentLocation", mock.Anything, driverID).Return(location, nil).Maybe()
+			} else {
+				mockService.On("GetDriverCurrentLocation", mock.Anything, driverID).Return(nil, repository.ErrTrackingEventNotFound).Maybe()
+			}
+			router := mux.NewRouter()
+			New(mockService, logger.New("test", "error")).RegisterRoutes(router)
+
+			req := httptest.NewRequest(http.MethodGet, "/tracking/driver/"+driverID.String()+"/current", nil)
+			req.Header.Set("X-User-ID", tt.userID.String())
+			req.Header.Set("X-User-Type", tt.userType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	router := mux.NewRouter()
+	New(new(MockService), logger.New("test", "error")).RegisterRoutes(router)
+
+	assert.NoError(t, openapi.Check(router, Routes))
+}
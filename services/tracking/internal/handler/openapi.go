@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"truckify/services/tracking/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /tracking/update":             {Summary: "Record a driver's location", Request: model.LocationUpdateRequest{}, Response: map[string]string{}},
+	"GET /tracking/job/{id}":            {Summary: "List a job's tracking events", Response: []model.TrackingEvent{}},
+	"GET /tracking/job/{id}/stops":      {Summary: "List the stops made on a job", Response: []model.Stop{}},
+	"GET /tracking/driver/{id}/current": {Summary: "Get a driver's last known location", Response: model.CurrentLocationResponse{}},
+	"GET /health":                       {Summary: "Health check", Response: map[string]interface{}{}},
+}
@@ -10,8 +10,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"truckify/services/tracking/internal/model"
+	"truckify/shared/pkg/events"
 )
 
+const eventSource = "tracking-service"
+
 var (
 	ErrTrackingEventNotFound = errors.New("tracking event not found")
 )
@@ -26,13 +29,33 @@ func New(db *sqlx.DB) *Repository {
 	return &Repository{db: db}
 }
 
-// CreateTrackingEvent creates a new tracking event
+// CreateTrackingEvent creates a new tracking event and records it in the outbox
 func (r *Repository) CreateTrackingEvent(ctx context.Context, event *model.TrackingEvent) error {
+	evt, err := events.New(ctx, eventSource, events.TypeTrackingLocationUpdated, 1, event.JobID.String(), events.TrackingLocationUpdatedV1{
+		JobID:      event.JobID,
+		DriverID:   event.DriverID,
+		Latitude:   event.Latitude,
+		Longitude:  event.Longitude,
+		Speed:      event.Speed,
+		Heading:    event.Heading,
+		EventType:  string(event.EventType),
+		RecordedAt: event.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO tracking_events (id, job_id, driver_id, latitude, longitude, speed, heading, timestamp, event_type, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		event.ID,
 		event.JobID,
 		event.DriverID,
@@ -44,7 +67,13 @@ func (r *Repository) CreateTrackingEvent(ctx context.Context, event *model.Track
 		event.EventType,
 		event.CreatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := events.WriteOutbox(ctx, tx, evt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetJobTrackingHistory gets tracking history for a job
@@ -2,14 +2,27 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 	"truckify/services/tracking/internal/model"
 	"truckify/services/tracking/internal/repository"
+	"truckify/shared/pkg/authz"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/tracing"
 )
 
+// activeJobStatuses are the job statuses in which a driver is on the job
+var activeJobStatuses = map[string]bool{
+	"assigned":   true,
+	"picked_up":  true,
+	"in_transit": true,
+}
+
 // RepositoryInterface defines the interface for tracking repository operations
 type RepositoryInterface interface {
 	CreateTrackingEvent(ctx context.Context, event *model.TrackingEvent) error
@@ -21,15 +34,22 @@ type RepositoryInterface interface {
 
 // Service handles tracking business logic
 type Service struct {
-	repo   RepositoryInterface
-	logger *logger.Logger
+	repo        RepositoryInterface
+	jobSvcURL   string
+	fleetSvcURL string
+	client      *http.Client
+	logger      *logger.Logger
 }
 
-// New creates a new service instance
-func New(repo RepositoryInterface, logger *logger.Logger) *Service {
+// New creates a new service instance. The job and fleet services are asked
+// who may see a driver's location.
+func New(repo RepositoryInterface, jobSvcURL, fleetSvcURL string, logger *logger.Logger) *Service {
 	return &Service{
-		repo:   repo,
-		logger: logger,
+		repo:        repo,
+		jobSvcURL:   jobSvcURL,
+		fleetSvcURL: fleetSvcURL,
+		client:      tracing.NewClient(5 * time.Second),
+		logger:      logger,
 	}
 }
 
@@ -91,6 +111,108 @@ func (s *Service) GetDriverCurrentLocation(ctx context.Context, driverID uuid.UU
 	return response, nil
 }
 
+// GetDriverOwners returns who may see a driver's location: the driver, the
+// caller when the driver is in the caller's fleet, and the shipper and
+// organisation of the job the driver last reported a position for, while
+// the driver is on it. The caller's identity is forwarded from the context.
+func (s *Service) GetDriverOwners(ctx context.Context, driverID uuid.UUID) ([]uuid.UUID, error) {
+	owners := []uuid.UUID{driverID}
+	p, ok := authz.PrincipalFrom(ctx)
+	if !ok || p.UserID == driverID {
+		return owners, nil
+	}
+
+	if p.Role == authz.RoleFleetOperator {
+		inFleet, err := s.inCallerFleet(ctx, p, driverID)
+		if err != nil {
+			return nil, err
+		}
+		if inFleet {
+			owners = append(owners, p.Org())
+		}
+		return owners, nil
+	}
+
+	event, err := s.repo.GetDriverCurrentLocation(ctx, driverID)
+	if err == repository.ErrTrackingEventNotFound {
+		return owners, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	jobOwners, err := s.activeJobOwners(ctx, p, event.JobID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	return append(owners, jobOwners...), nil
+}
+
+// inCallerFleet asks the fleet service whether the driver is an active
+// member of the caller's fleet
+func (s *Service) inCallerFleet(ctx context.Context, p authz.Principal, driverID uuid.UUID) (bool, error) {
+	query := url.Values{"user_id": {driverID.String()}, "limit": {"1"}}
+	var drivers []struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	found, err := s.get(ctx, p, s.fleetSvcURL+"/fleet/drivers?"+query.Encode(), &drivers)
+	if err != nil || !found {
+		return false, err
+	}
+	return len(drivers) > 0 && drivers[0].UserID == driverID, nil
+}
+
+// activeJobOwners looks up the shipper and organisation of a job in the job
+// service, if the driver is assigned to it and it is not yet finished
+func (s *Service) activeJobOwners(ctx context.Context, p authz.Principal, jobID, driverID uuid.UUID) ([]uuid.UUID, error) {
+	var job struct {
+		ShipperID uuid.UUID  `json:"shipper_id"`
+		OrgID     uuid.UUID  `json:"org_id"`
+		DriverID  *uuid.UUID `json:"driver_id"`
+		Status    string     `json:"status"`
+	}
+	found, err := s.get(ctx, p, fmt.Sprintf("%s/jobs/%s", s.jobSvcURL, jobID), &job)
+	if err != nil || !found {
+		return nil, err
+	}
+	if job.DriverID == nil || *job.DriverID != driverID || !activeJobStatuses[job.Status] {
+		return nil, nil
+	}
+	return []uuid.UUID{job.ShipperID, job.OrgID}, nil
+}
+
+// get fetches a resource from another service as the principal and decodes
+// the data of its response into out. It reports false when the resource is
+// missing or hidden from the principal.
+func (s *Service) get(ctx context.Context, p authz.Principal, rawURL string, out interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	p.Forward(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	result := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // GetStops gets all detected stops for a job (5+ min stationary)
 func (s *Service) GetStops(ctx context.Context, jobID uuid.UUID) ([]model.Stop, error) {
 	return s.repo.GetStops(ctx, jobID)
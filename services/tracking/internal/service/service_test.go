@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/services/tracking/internal/model"
+	"truckify/services/tracking/internal/repository"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/logger"
+)
+
+// fakeRepo returns the last position of one driver
+type fakeRepo struct {
+	RepositoryInterface
+	last *model.TrackingEvent
+}
+
+func (r *fakeRepo) GetDriverCurrentLocation(ctx context.Context, driverID uuid.UUID) (*model.TrackingEvent, error) {
+	if r.last == nil || r.last.DriverID != driverID {
+		return nil, repository.ErrTrackingEventNotFound
+	}
+	return r.last, nil
+}
+
+func TestGetDriverOwners(t *testing.T) {
+	driverID := uuid.New()
+	shipperID := uuid.New()
+	orgID := uuid.New()
+	operatorID := uuid.New()
+	jobID := uuid.New()
+
+	jobStatus := "in_transit"
+	jobs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/jobs/"+jobID.String(), r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"shipper_id": shipperID, "org_id": orgID, "driver_id": driverID, "status": jobStatus,
+		}})
+	}))
+	defer jobs.Close()
+
+	fleets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the operator's fleet has the driver
+		drivers := []map[string]interface{}{}
+		if r.Header.Get("X-User-ID") == operatorID.String() && r.URL.Query().Get("user_id") == driverID.String() {
+			drivers = append(drivers, map[string]interface{}{"user_id": driverID})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": drivers})
+	}))
+	defer fleets.Close()
+
+	repo := &fakeRepo{last: &model.TrackingEvent{JobID: jobID, DriverID: driverID, Timestamp: time.Now()}}
+	svc := New(repo, jobs.URL, fleets.URL, logger.New("test", "error"))
+
+	ownersFor := func(p authz.Principal) []uuid.UUID {
+		owners, err := svc.GetDriverOwners(authz.WithPrincipal(context.Background(), p), driverID)
+		require.NoError(t, err)
+		return owners
+	}
+
+	// The driver themselves
+	assert.Equal(t, []uuid.UUID{driverID}, ownersFor(authz.Principal{UserID: driverID, Role: authz.RoleDriver}))
+
+	// The shipper of the job the driver is on
+	assert.Equal(t, []uuid.UUID{driverID, shipperID, orgID}, ownersFor(authz.Principal{UserID: shipperID, Role: authz.RoleShipper}))
+
+	// The operator of the driver's fleet, but not of another fleet
+	assert.Equal(t, []uuid.UUID{driverID, operatorID}, ownersFor(authz.Principal{UserID: operatorID, Role: authz.RoleFleetOperator}))
+	assert.Equal(t, []uuid.UUID{driverID}, ownersFor(authz.Principal{UserID: uuid.New(), Role: authz.RoleFleetOperator}))
+
+	// Nobody else once the job is delivered
+	jobStatus = "delivered"
+	assert.Equal(t, []uuid.UUID{driverID}, ownersFor(authz.Principal{UserID: shipperID, Role: authz.RoleShipper}))
+}
@@ -0,0 +1,16 @@
+-- Transactional outbox drained by the event relay
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id UUID PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    request_id VARCHAR(100),
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(occurred_at) WHERE published_at IS NULL;
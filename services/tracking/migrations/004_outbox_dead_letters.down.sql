@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_event_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(occurred_at) WHERE published_at IS NULL;
+
+ALTER TABLE event_outbox DROP COLUMN IF EXISTS dead_at;
@@ -13,10 +13,15 @@ import (
 	"truckify/services/user/internal/handler"
 	"truckify/services/user/internal/repository"
 	"truckify/services/user/internal/service"
+	"truckify/services/user/migrations"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/config"
 	"truckify/shared/pkg/database"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/metrics"
 	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/openapi"
+	"truckify/shared/pkg/tracing"
 )
 
 func main() {
@@ -24,6 +29,12 @@ func main() {
 	log := logger.New("user-service", logLevel)
 	log.Info("Starting User Service")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "user-service")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := database.NewPostgresDB(database.PostgresConfig{
 		Host:     config.GetEnv("DB_HOST", "localhost"),
 		Port:     config.GetEnvInt("DB_PORT", 5432),
@@ -38,17 +49,34 @@ func main() {
 	defer database.ClosePostgresDB(db)
 	log.Info("Connected to PostgreSQL")
 
+	// `migrate` manages the schema; serving requires it to be up to date
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.MigrateCommand(context.Background(), db, migrations.FS, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(context.Background(), db, migrations.FS, config.GetEnvBool("DB_AUTO_MIGRATE", false)); err != nil {
+		log.Fatal("Database schema is not up to date, run migrate up", "error", err)
+	}
+
 	repo := repository.New(db)
 	svc := service.New(repo, log)
+	svc.SetAuditRecorder(audit.NewClient(config.GetEnv("AUDIT_SERVICE_URL", "http://localhost:8018"), "user-service"))
 	h := handler.New(svc, log)
 
 	router := mux.NewRouter()
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.Deadline)
+	router.Use(metrics.Middleware("user-service"))
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logger(log))
 	router.Use(middleware.SecurityHeaders)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 	h.RegisterRoutes(router)
+	router.Handle(openapi.Path, openapi.Build(router, openapi.Info{Title: "user-service", Version: "1.0.0"}, handler.Routes)).Methods(http.MethodGet)
 
 	// Wrap router with CORS
 	corsHandler := middleware.CORS([]string{"http://localhost:5173", "http://localhost:3000", "*"})(router)
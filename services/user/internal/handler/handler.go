@@ -13,6 +13,7 @@ import (
 	"github.com/gorilla/mux"
 	"truckify/services/user/internal/model"
 	"truckify/services/user/internal/service"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/logger"
 	"truckify/shared/pkg/response"
 	"truckify/shared/pkg/validator"
@@ -357,7 +358,11 @@ func (h *Handler) VerifyDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.VerifyDocument(r.Context(), id, req.Status); err != nil {
+	if err := h.service.VerifyDocument(audit.RequestContext(r), id, req.Status); err != nil {
+		if err == service.ErrDocumentNotFound {
+			response.NotFound(w, "Document not found", "", requestID)
+			return
+		}
 		response.InternalServerError(w, "Failed to update document", "", requestID)
 		return
 	}
@@ -15,7 +15,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"truckify/services/user/internal/handler"
 	"truckify/services/user/internal/model"
+	"truckify/services/user/internal/service"
 	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/openapi"
 )
 
 type MockService struct {
@@ -285,3 +287,35 @@ func TestHealth_Success(t *testing.T) {
 	data := response["data"].(map[string]interface{})
 	assert.Equal(t, "healthy", data["status"])
 }
+
+func TestVerifyDocument(t *testing.T) {
+	h, mockService := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	docID, missingID := uuid.New(), uuid.New()
+	mockService.On("VerifyDocument", mock.Anything, docID, "verified").Return(nil)
+	mockService.On("VerifyDocument", mock.Anything, missingID, "verified").Return(service.ErrDocumentNotFound)
+
+	verify := func(id uuid.UUID, userType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/documents/"+id.String()+"/verify", bytes.NewBufferString(`{"status":"verified"}`))
+		req.Header.Set("X-User-ID", uuid.New().String())
+		req.Header.Set("X-User-Type", userType)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusForbidden, verify(docID, "driver").Code)
+	assert.Equal(t, http.StatusOK, verify(docID, "admin").Code)
+	assert.Equal(t, http.StatusNotFound, verify(missingID, "admin").Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	h, _ := setupTestHandler()
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	assert.NoError(t, openapi.Check(router, handler.Routes))
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"truckify/services/user/internal/model"
+	"truckify/shared/pkg/openapi"
+)
+
+// uploadForm is the form UploadDocument reads
+type uploadForm struct {
+	File string `json:"file" validate:"required"`
+	model.UploadDocumentRequest
+}
+
+// Routes documents the endpoints registered by RegisterRoutes
+var Routes = openapi.Routes{
+	"POST /profile":   {Summary: "Create the caller's profile", Request: model.CreateProfileRequest{}, Response: model.UserProfile{}, Status: http.StatusCreated},
+	"GET /profile":    {Summary: "Get the caller's profile", Response: model.UserProfile{}},
+	"PUT /profile":    {Summary: "Update the caller's profile", Request: model.UpdateProfileRequest{}, Response: model.UserProfile{}},
+	"DELETE /profile": {Summary: "Delete the caller's profile", Response: map[string]string{}},
+
+	"POST /documents":            {Summary: "Upload a document", Request: uploadForm{}, Multipart: true, Response: model.Document{}, Status: http.StatusCreated},
+	"GET /documents":             {Summary: "List the caller's documents", Response: []model.Document{}},
+	"GET /documents/job/{jobId}": {Summary: "List a job's documents", Response: []model.Document{}},
+	"GET /documents/{id}": {
+		Summary:     "Get a document",
+		Description: "With download=true the data holds the document and its base64 content.",
+		Query: []openapi.Parameter{
+			{Name: "download", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+	},
+	"DELETE /documents/{id}": {Summary: "Delete a document", Response: map[string]string{}},
+	"POST /documents/{id}/verify": {
+		Summary: "Verify or reject a document",
+		Request: struct {
+			Status string `json:"status" validate:"required,oneof=verified rejected"`
+		}{},
+		Response: map[string]string{},
+	},
+	"GET /health": {Summary: "Health check", Response: map[string]interface{}{}},
+}
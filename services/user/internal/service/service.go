@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"truckify/services/user/internal/model"
 	"truckify/services/user/internal/repository"
+	"truckify/shared/pkg/audit"
 	"truckify/shared/pkg/logger"
 )
 
@@ -25,6 +27,7 @@ type RepositoryInterface interface {
 
 type Service struct {
 	repo   RepositoryInterface
+	audit  audit.Recorder
 	logger *logger.Logger
 }
 
@@ -32,6 +35,11 @@ func New(repo RepositoryInterface, logger *logger.Logger) *Service {
 	return &Service{repo: repo, logger: logger}
 }
 
+// SetAuditRecorder sets where administrative actions are recorded
+func (s *Service) SetAuditRecorder(recorder audit.Recorder) {
+	s.audit = recorder
+}
+
 func (s *Service) CreateProfile(ctx context.Context, userID uuid.UUID, req *model.CreateProfileRequest) (*model.UserProfile, error) {
 	profile := &model.UserProfile{
 		ID:        uuid.New(),
@@ -74,8 +82,9 @@ func (s *Service) DeleteProfile(ctx context.Context, userID uuid.UUID) error {
 }
 
 var (
-	ErrProfileNotFound = repository.ErrProfileNotFound
-	ErrProfileExists   = repository.ErrProfileExists
+	ErrProfileNotFound  = repository.ErrProfileNotFound
+	ErrProfileExists    = repository.ErrProfileExists
+	ErrDocumentNotFound = errors.New("document not found")
 )
 
 // Document methods
@@ -110,6 +119,31 @@ func (s *Service) DeleteDocument(ctx context.Context, id, userID uuid.UUID) erro
 	return s.repo.DeleteDocument(ctx, id)
 }
 
+// VerifyDocument sets a document's review status and records the review in
+// the audit log (admin only)
 func (s *Service) VerifyDocument(ctx context.Context, id uuid.UUID, status string) error {
-	return s.repo.UpdateDocumentStatus(ctx, id, status)
+	doc, err := s.repo.GetDocument(ctx, id)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return ErrDocumentNotFound
+	}
+	if err := s.repo.UpdateDocumentStatus(ctx, id, status); err != nil {
+		return err
+	}
+
+	if s.audit != nil {
+		changes, _ := audit.Diff(map[string]string{"status": doc.Status}, map[string]string{"status": status})
+		err := s.audit.Record(ctx, audit.Entry{
+			Action:       audit.ActionDocumentVerified,
+			ResourceType: "document",
+			ResourceID:   id.String(),
+			Changes:      changes,
+		})
+		if err != nil {
+			s.logger.Error("Failed to record audit entry", "action", audit.ActionDocumentVerified, "resource_id", id, "error", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,197 @@
+// Package audit records administrative actions in the audit log service.
+//
+// Services describe what changed in an Entry and record it with a Recorder;
+// who made the request, from where and under which request ID is taken from
+// the context. Handlers pass RequestContext(r) to the code that records, so
+// the caller's identity and address reach the entry.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/authz"
+	"truckify/shared/pkg/middleware"
+	"truckify/shared/pkg/tracing"
+)
+
+// Actions recorded by Truckify services
+const (
+	ActionUserStatusChanged = "user.status_changed"
+	ActionDocumentVerified  = "document.verified"
+	ActionPolicyVerified    = "insurance_policy.verified"
+	ActionConfigSaved       = "config.saved"
+	ActionConfigRestored    = "config.restored"
+	ActionMessageDecrypted  = "message.decrypted"
+)
+
+// Entry is one audited action
+type Entry struct {
+	Service      string `json:"service" validate:"max=50"`
+	Action       string `json:"action" validate:"required,max=100"`
+	ResourceType string `json:"resource_type" validate:"required,max=50"`
+	ResourceID   string `json:"resource_id" validate:"max=255"`
+	// ActorID is who made the request, the dispatcher when one acts for a
+	// user, who is then OnBehalfOf. It is nil for actions the system takes.
+	ActorID    *uuid.UUID `json:"actor_id,omitempty"`
+	ActorType  string     `json:"actor_type,omitempty" validate:"max=20"`
+	OnBehalfOf *uuid.UUID `json:"on_behalf_of,omitempty"`
+	// Changes holds the fields the action changed
+	Changes map[string]Change `json:"changes,omitempty"`
+	// IPAddress and RequestID come from the request, so the audit service
+	// cuts them down to what it stores rather than refuse the entry
+	IPAddress  string    `json:"ip_address,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Change is the value of a field before and after an action. Before is
+// empty for fields the action added, and After for fields it removed.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Recorder records audit entries
+type Recorder interface {
+	Record(ctx context.Context, e Entry) error
+}
+
+// Diff returns the fields whose values differ between two values that
+// marshal to JSON objects. A nil before or after stands for a resource that
+// was created or deleted.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for key, value := range b {
+		if !bytes.Equal(value, a[key]) {
+			changes[key] = Change{Before: value, After: a[key]}
+		}
+	}
+	for key, value := range a {
+		if _, ok := b[key]; !ok {
+			changes[key] = Change{After: value}
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the compacted JSON value of each field of v
+func fields(v interface{}) (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage)
+	if v == nil {
+		return out, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audited value: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("audited value is not an object: %w", err)
+	}
+	for key, value := range raw {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, value); err != nil {
+			return nil, err
+		}
+		out[key] = buf.Bytes()
+	}
+	return out, nil
+}
+
+type ipKey struct{}
+
+// RequestContext returns the request's context carrying the caller and
+// their address. The caller is the principal the authorizer stored, or is
+// read from the gateway's identity headers.
+func RequestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if _, ok := authz.PrincipalFrom(ctx); !ok {
+		if p, err := authz.FromRequest(r); err == nil {
+			ctx = authz.WithPrincipal(ctx, p)
+		}
+	}
+	return context.WithValue(ctx, ipKey{}, middleware.ForwardedClientIP(r))
+}
+
+// complete fills in what the entry leaves out from the context
+func complete(ctx context.Context, service string, e *Entry) {
+	if e.Service == "" {
+		e.Service = service
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+	if e.RequestID == "" {
+		e.RequestID, _ = ctx.Value("request_id").(string)
+	}
+	if e.IPAddress == "" {
+		e.IPAddress, _ = ctx.Value(ipKey{}).(string)
+	}
+	if p, ok := authz.PrincipalFrom(ctx); ok && e.ActorID == nil {
+		if p.Acting() {
+			actorID, userID := p.ActorID, p.UserID
+			e.ActorID, e.ActorType, e.OnBehalfOf = &actorID, string(p.ActorRole), &userID
+		} else {
+			userID := p.UserID
+			e.ActorID, e.ActorType = &userID, string(p.Role)
+		}
+	}
+}
+
+// Client records entries in the audit log service
+type Client struct {
+	baseURL string
+	service string
+	client  *http.Client
+}
+
+// NewClient creates a client that records entries for the named service
+func NewClient(baseURL, service string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		service: service,
+		client:  tracing.NewClient(5 * time.Second),
+	}
+}
+
+// Record completes the entry from the context and appends it to the log
+func (c *Client) Record(ctx context.Context, e Entry) error {
+	complete(ctx, c.service, &e)
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/audit/entries", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("audit service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
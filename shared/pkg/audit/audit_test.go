@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	type policy struct {
+		Status     string     `json:"status"`
+		VerifiedBy *uuid.UUID `json:"verified_by,omitempty"`
+		Premium    float64    `json:"premium"`
+	}
+	adminID := uuid.New()
+
+	changes, err := Diff(policy{Status: "pending", Premium: 120}, policy{Status: "active", VerifiedBy: &adminID, Premium: 120})
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.JSONEq(t, `"pending"`, string(changes["status"].Before))
+	assert.JSONEq(t, `"active"`, string(changes["status"].After))
+	assert.Nil(t, changes["verified_by"].Before)
+	assert.JSONEq(t, `"`+adminID.String()+`"`, string(changes["verified_by"].After))
+
+	// A created resource changes every field
+	changes, err = Diff(nil, map[string]string{"status": "active"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Change{"status": {After: json.RawMessage(`"active"`)}}, changes)
+
+	_, err = Diff("active", nil)
+	assert.Error(t, err)
+}
+
+func TestRequestContext(t *testing.T) {
+	adminID := uuid.New()
+	req := httptest.NewRequest(http.MethodPut, "/admin/users/1/status", nil)
+	req.Header.Set("X-User-ID", adminID.String())
+	req.Header.Set("X-User-Type", "admin")
+	// The gateway's address for the client counts, not what the client claims
+	req.Header.Set("X-Forwarded-For", "192.0.2.66, 10.0.0.2")
+	req.Header.Set("X-Client-IP", "203.0.113.7")
+	req = req.WithContext(context.WithValue(req.Context(), "request_id", "req-123"))
+
+	rec := NewMemory("auth-service")
+	require.NoError(t, rec.Record(RequestContext(req), Entry{Action: ActionUserStatusChanged, ResourceType: "user", ResourceID: "1"}))
+
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, "auth-service", e.Service)
+	assert.Equal(t, &adminID, e.ActorID)
+	assert.Equal(t, "admin", e.ActorType)
+	assert.Nil(t, e.OnBehalfOf)
+	assert.Equal(t, "203.0.113.7", e.IPAddress)
+	assert.Equal(t, "req-123", e.RequestID)
+	assert.False(t, e.OccurredAt.IsZero())
+}
+
+func TestRequestContext_ActingDispatcher(t *testing.T) {
+	shipperID, dispatcherID := uuid.New(), uuid.New()
+	req := httptest.NewRequest(http.MethodPost, "/jobs", nil)
+	req.Header.Set("X-User-ID", shipperID.String())
+	req.Header.Set("X-User-Type", "shipper")
+	req.Header.Set("X-Actor-ID", dispatcherID.String())
+	req.Header.Set("X-Actor-Type", "dispatcher")
+	req.RemoteAddr = "198.51.100.4:51234"
+
+	rec := NewMemory("job-service")
+	require.NoError(t, rec.Record(RequestContext(req), Entry{Action: "job.updated", ResourceType: "job"}))
+
+	e := rec.Entries()[0]
+	assert.Equal(t, &dispatcherID, e.ActorID)
+	assert.Equal(t, "dispatcher", e.ActorType)
+	assert.Equal(t, &shipperID, e.OnBehalfOf)
+	assert.Equal(t, "198.51.100.4", e.IPAddress)
+}
+
+func TestClientRecord(t *testing.T) {
+	var got Entry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/audit/entries", r.URL.Path)
+		assert.Equal(t, "req-123", r.Header.Get("X-Request-ID"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Action == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "admin-service")
+	ctx := context.WithValue(context.Background(), "request_id", "req-123")
+
+	require.NoError(t, client.Record(ctx, Entry{Action: ActionConfigSaved, ResourceType: "config"}))
+	assert.Equal(t, "admin-service", got.Service)
+	assert.Equal(t, "req-123", got.RequestID)
+	assert.Nil(t, got.ActorID)
+
+	assert.EqualError(t, client.Record(ctx, Entry{ResourceType: "config"}), "audit service returned status 400")
+}
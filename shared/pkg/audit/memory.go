@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps recorded entries in memory. It is intended for tests and
+// local development.
+type Memory struct {
+	mu      sync.RWMutex
+	service string
+	entries []Entry
+	// Err, when set, is returned by Record instead of keeping the entry
+	Err error
+}
+
+// NewMemory creates an in-memory recorder for the named service
+func NewMemory(service string) *Memory {
+	return &Memory{service: service}
+}
+
+// Record completes the entry from the context and keeps it
+func (m *Memory) Record(ctx context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	complete(ctx, m.service, &e)
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+// Entries returns every entry recorded so far, in order
+func (m *Memory) Entries() []Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Entry, len(m.entries))
+	copy(out, m.entries)
+	return out
+}
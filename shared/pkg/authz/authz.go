@@ -0,0 +1,170 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Role is the user type a principal acts as. It matches the user_type claim
+// the API gateway forwards in the X-User-Type header.
+type Role string
+
+const (
+	RoleShipper       Role = "shipper"
+	RoleDriver        Role = "driver"
+	RoleFleetOperator Role = "fleet_operator"
+	RoleDispatcher    Role = "dispatcher"
+	RoleAdmin         Role = "admin"
+	RoleSupport       Role = "support"
+)
+
+// Roles lists every known role
+var Roles = []Role{RoleShipper, RoleDriver, RoleFleetOperator, RoleDispatcher, RoleAdmin, RoleSupport}
+
+// Valid reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrUnauthenticated   = errors.New("missing or invalid user identity")
+	ErrForbidden         = errors.New("insufficient permissions")
+	ErrResourceNotFound  = errors.New("resource not found")
+	ErrInvalidResourceID = errors.New("invalid resource id")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID uuid.UUID
+	Role   Role
+	// Permissions limits an API client to some of the permissions of its
+	// user's role. It is nil for users, who are not limited.
+	Permissions []Permission
+	// OrgID is the organisation the caller acts for, whose resources the
+	// caller owns alongside their own. It is uuid.Nil when not known.
+	OrgID uuid.UUID
+	// OrgRole is the caller's role within OrgID
+	OrgRole string
+	// ActorID and ActorRole are set when a dispatcher acts for UserID under
+	// a delegation. The principal is then authorized as UserID, limited to
+	// the delegated Permissions, and ActorID is who really made the request.
+	ActorID   uuid.UUID
+	ActorRole Role
+}
+
+// Acting reports whether a dispatcher is acting for the principal's user
+func (p Principal) Acting() bool {
+	return p.ActorID != uuid.Nil
+}
+
+// Owns reports whether the principal owns a resource owned by owner, either
+// directly or through its organisation
+func (p Principal) Owns(owner uuid.UUID) bool {
+	return owner == p.UserID || (p.OrgID != uuid.Nil && owner == p.OrgID)
+}
+
+// Org returns the organisation that resources the principal creates belong
+// to. Without one, that is the user's personal organisation, which shares
+// the user's ID.
+func (p Principal) Org() uuid.UUID {
+	if p.OrgID != uuid.Nil {
+		return p.OrgID
+	}
+	return p.UserID
+}
+
+// Granted reports whether the principal is not limited from using the
+// permission. Whether its role holds the permission is up to the policy.
+func (p Principal) Granted(perm Permission) bool {
+	if p.Permissions == nil {
+		return true
+	}
+	for _, granted := range p.Permissions {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// FromRequest reads the principal from the identity headers set by the API
+// gateway. The X-Permissions header, when present, lists the permissions
+// an API client or acting dispatcher was granted, separated by spaces.
+// X-Org-ID and X-Org-Role name the organisation the caller acts for, and
+// X-Actor-ID and X-Actor-Type the dispatcher acting for the user.
+func FromRequest(r *http.Request) (Principal, error) {
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	role := Role(r.Header.Get("X-User-Type"))
+	if !role.Valid() {
+		return Principal{}, ErrUnauthenticated
+	}
+	p := Principal{UserID: userID, Role: role}
+	if orgID := r.Header.Get("X-Org-ID"); orgID != "" {
+		if p.OrgID, err = uuid.Parse(orgID); err != nil {
+			return Principal{}, ErrUnauthenticated
+		}
+		p.OrgRole = r.Header.Get("X-Org-Role")
+	}
+	if actorID := r.Header.Get("X-Actor-ID"); actorID != "" {
+		if p.ActorID, err = uuid.Parse(actorID); err != nil {
+			return Principal{}, ErrUnauthenticated
+		}
+		if p.ActorRole = Role(r.Header.Get("X-Actor-Type")); !p.ActorRole.Valid() {
+			return Principal{}, ErrUnauthenticated
+		}
+	}
+	if values, ok := r.Header["X-Permissions"]; ok {
+		p.Permissions = []Permission{}
+		for _, perm := range strings.Fields(strings.Join(values, " ")) {
+			p.Permissions = append(p.Permissions, Permission(perm))
+		}
+	}
+	return p, nil
+}
+
+// Forward sets the identity headers on an outgoing service-to-service request
+// so the downstream service authorizes it as the same principal.
+func (p Principal) Forward(req *http.Request) {
+	req.Header.Set("X-User-ID", p.UserID.String())
+	req.Header.Set("X-User-Type", string(p.Role))
+	if p.OrgID != uuid.Nil {
+		req.Header.Set("X-Org-ID", p.OrgID.String())
+		req.Header.Set("X-Org-Role", p.OrgRole)
+	}
+	if p.Acting() {
+		req.Header.Set("X-Actor-ID", p.ActorID.String())
+		req.Header.Set("X-Actor-Type", string(p.ActorRole))
+	}
+	if p.Permissions != nil {
+		perms := make([]string, len(p.Permissions))
+		for i, perm := range p.Permissions {
+			perms[i] = string(perm)
+		}
+		req.Header.Set("X-Permissions", strings.Join(perms, " "))
+	}
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx by the authorizer
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
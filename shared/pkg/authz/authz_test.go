@@ -0,0 +1,331 @@
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicyMatrix(t *testing.T) {
+	policy := DefaultPolicy()
+
+	tests := []struct {
+		role Role
+		perm Permission
+		want Scope
+	}{
+		// Jobs
+		{RoleShipper, JobCreate, ScopeAny},
+		{RoleShipper, JobUpdate, ScopeOwn},
+		{RoleShipper, JobCancel, ScopeOwn},
+		{RoleShipper, JobTransition, ScopeNone},
+		{RoleDriver, JobCreate, ScopeNone},
+		{RoleDriver, JobTransition, ScopeOwn},
+		{RoleDriver, JobCancel, ScopeNone},
+		{RoleDriver, JobAssign, ScopeNone},
+		{RoleFleetOperator, JobUpdate, ScopeNone},
+		{RoleDispatcher, JobAssign, ScopeNone},
+		{RoleDispatcher, JobCancel, ScopeNone},
+		{RoleDispatcher, JobDelete, ScopeNone},
+		{RoleSupport, JobRead, ScopeAny},
+		{RoleSupport, JobUpdate, ScopeNone},
+		{RoleAdmin, JobDelete, ScopeAny},
+
+		// Bids
+		{RoleDriver, BidCreate, ScopeAny},
+		{RoleDriver, BidUpdate, ScopeOwn},
+		{RoleDriver, BidAccept, ScopeNone},
+		{RoleShipper, BidCreate, ScopeNone},
+		{RoleShipper, BidAccept, ScopeOwn},
+		{RoleDispatcher, BidAccept, ScopeNone},
+		{RoleSupport, BidAccept, ScopeNone},
+
+		// Fleets
+		{RoleFleetOperator, FleetManage, ScopeOwn},
+		{RoleFleetOperator, FleetRead, ScopeOwn},
+		{RoleDriver, FleetManage, ScopeNone},
+		{RoleShipper, FleetRead, ScopeNone},
+		{RoleDispatcher, FleetRead, ScopeAny},
+
+		// Driver locations
+		{RoleDriver, LocationRead, ScopeOwn},
+		{RoleFleetOperator, LocationRead, ScopeOwn},
+		{RoleShipper, LocationRead, ScopeOwn},
+		{RoleDispatcher, LocationRead, ScopeNone},
+		{RoleSupport, LocationRead, ScopeNone},
+		{RoleAdmin, LocationRead, ScopeAny},
+
+		// Insurance
+		{RoleDriver, ClaimCreate, ScopeOwn},
+		{RoleDriver, ClaimReview, ScopeNone},
+		{RoleShipper, PolicyVerify, ScopeNone},
+		{RoleSupport, ClaimReview, ScopeAny},
+		{RoleSupport, PolicyVerify, ScopeAny},
+		{RoleDispatcher, ClaimRead, ScopeNone},
+
+		// Payments
+		{RoleShipper, PaymentProcess, ScopeOwn},
+		{RoleShipper, PaymentRefund, ScopeNone},
+		{RoleDriver, PaymentRead, ScopeOwn},
+		{RoleDriver, PaymentCreate, ScopeNone},
+		{RoleSupport, PaymentRefund, ScopeAny},
+		{RoleSupport, PaymentProcess, ScopeNone},
+
+		// Users
+		{RoleAdmin, UserManage, ScopeAny},
+		{RoleSupport, UserRead, ScopeAny},
+		{RoleSupport, UserManage, ScopeNone},
+		{RoleDispatcher, UserRead, ScopeNone},
+
+		// Webhooks
+		{RoleShipper, WebhookManage, ScopeOwn},
+		{RoleDriver, WebhookManage, ScopeOwn},
+		{RoleSupport, WebhookManage, ScopeNone},
+
+		// Messaging
+		{RoleShipper, MessageSend, ScopeOwn},
+		{RoleDispatcher, MessageSend, ScopeOwn},
+		{RoleSupport, MessageSend, ScopeNone},
+
+		// Unknown roles hold nothing
+		{Role("superuser"), JobRead, ScopeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.perm), func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Scope(tt.role, tt.perm))
+		})
+	}
+}
+
+func TestDefaultPolicyCoversAllRoles(t *testing.T) {
+	policy := DefaultPolicy()
+	for _, role := range Roles {
+		assert.NotEmpty(t, policy[role], role)
+	}
+}
+
+func TestCan(t *testing.T) {
+	a := New(DefaultPolicy())
+	owner, other, org := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		p      Principal
+		perm   Permission
+		owners []uuid.UUID
+		want   bool
+	}{
+		{"owner with own scope", Principal{UserID: owner, Role: RoleShipper}, JobUpdate, []uuid.UUID{owner}, true},
+		{"non-owner with own scope", Principal{UserID: other, Role: RoleShipper}, JobUpdate, []uuid.UUID{owner}, false},
+		{"one of several owners", Principal{UserID: owner, Role: RoleDriver}, PaymentRead, []uuid.UUID{other, owner}, true},
+		{"own scope without owners", Principal{UserID: owner, Role: RoleShipper}, JobUpdate, nil, false},
+		{"any scope", Principal{UserID: other, Role: RoleAdmin}, JobUpdate, []uuid.UUID{owner}, true},
+		{"no scope", Principal{UserID: owner, Role: RoleDriver}, JobUpdate, []uuid.UUID{owner}, false},
+		{"granted to API client", Principal{UserID: owner, Role: RoleShipper, Permissions: []Permission{JobUpdate}}, JobUpdate, []uuid.UUID{owner}, true},
+		{"not granted to API client", Principal{UserID: other, Role: RoleAdmin, Permissions: []Permission{JobRead}}, JobUpdate, []uuid.UUID{owner}, false},
+		{"member of owning org", Principal{UserID: other, Role: RoleShipper, OrgID: org}, JobUpdate, []uuid.UUID{owner, org}, true},
+		{"member of another org", Principal{UserID: other, Role: RoleShipper, OrgID: uuid.New()}, JobUpdate, []uuid.UUID{owner, org}, false},
+		{"no org never matches", Principal{UserID: other, Role: RoleShipper}, JobUpdate, []uuid.UUID{uuid.Nil}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, a.Can(tt.p, tt.perm, tt.owners...))
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	userID := uuid.New()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User-ID", userID.String())
+	req.Header.Set("X-User-Type", "dispatcher")
+	p, err := FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, Principal{UserID: userID, Role: RoleDispatcher}, p)
+	assert.Equal(t, userID, p.Org())
+
+	orgID := uuid.New()
+	req.Header.Set("X-Org-ID", orgID.String())
+	req.Header.Set("X-Org-Role", "admin")
+	p, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, orgID, p.OrgID)
+	assert.Equal(t, "admin", p.OrgRole)
+	assert.Equal(t, orgID, p.Org())
+
+	req.Header.Set("X-Org-ID", "not-a-uuid")
+	_, err = FromRequest(req)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	req.Header.Del("X-Org-ID")
+
+	actorID := uuid.New()
+	req.Header.Set("X-Actor-ID", actorID.String())
+	req.Header.Set("X-Actor-Type", "dispatcher")
+	p, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.True(t, p.Acting())
+	assert.Equal(t, actorID, p.ActorID)
+	assert.Equal(t, RoleDispatcher, p.ActorRole)
+
+	req.Header.Set("X-Actor-Type", "root")
+	_, err = FromRequest(req)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	req.Header.Del("X-Actor-ID")
+	req.Header.Del("X-Actor-Type")
+
+	req.Header.Set("X-User-Type", "root")
+	_, err = FromRequest(req)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	req.Header.Set("X-User-Type", "driver")
+	req.Header.Set("X-User-ID", "not-a-uuid")
+	_, err = FromRequest(req)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestRequireOwner(t *testing.T) {
+	a := New(DefaultPolicy())
+	owner, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		userID   string
+		role     Role
+		perm     Permission
+		resolver Resolver
+		want     int
+	}{
+		{"unauthenticated", "", RoleShipper, JobUpdate, nil, http.StatusUnauthorized},
+		{"role without permission", owner.String(), RoleDriver, JobCancel, nil, http.StatusForbidden},
+		{"any scope skips resolver", other.String(), RoleAdmin, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return nil, errors.New("should not be called")
+		}, http.StatusOK},
+		{"owner", owner.String(), RoleShipper, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return []uuid.UUID{owner}, nil
+		}, http.StatusOK},
+		{"not owner", other.String(), RoleShipper, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return []uuid.UUID{owner}, nil
+		}, http.StatusForbidden},
+		{"resource not found", owner.String(), RoleShipper, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return nil, ErrResourceNotFound
+		}, http.StatusNotFound},
+		{"invalid resource id", owner.String(), RoleShipper, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return nil, ErrInvalidResourceID
+		}, http.StatusBadRequest},
+		{"resolver failure", owner.String(), RoleShipper, JobCancel, func(r *http.Request) ([]uuid.UUID, error) {
+			return nil, errors.New("db down")
+		}, http.StatusInternalServerError},
+		{"own scope without resolver", owner.String(), RoleFleetOperator, FleetRead, nil, http.StatusForbidden},
+		{"own scope on the caller", owner.String(), RoleFleetOperator, FleetRead, caller, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Principal
+			h := a.RequireOwner(tt.perm, tt.resolver, func(w http.ResponseWriter, r *http.Request) {
+				got, _ = PrincipalFrom(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("POST", "/", nil)
+			req.Header.Set("X-User-ID", tt.userID)
+			req.Header.Set("X-User-Type", string(tt.role))
+			w := httptest.NewRecorder()
+			h(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, tt.role, got.Role)
+			}
+		})
+	}
+}
+
+func TestForward(t *testing.T) {
+	p := Principal{UserID: uuid.New(), Role: RoleShipper}
+	req := httptest.NewRequest("GET", "/", nil)
+	p.Forward(req)
+
+	got, err := FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+	assert.NotContains(t, req.Header, "X-Permissions")
+
+	member := Principal{UserID: uuid.New(), Role: RoleShipper, OrgID: uuid.New(), OrgRole: "member"}
+	req = httptest.NewRequest("GET", "/", nil)
+	member.Forward(req)
+	got, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, member, got)
+
+	client := Principal{UserID: uuid.New(), Role: RoleShipper, Permissions: []Permission{JobCreate, JobRead}}
+	req = httptest.NewRequest("GET", "/", nil)
+	client.Forward(req)
+
+	got, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, client, got)
+
+	acting := Principal{UserID: uuid.New(), Role: RoleShipper, Permissions: []Permission{JobCreate}, ActorID: uuid.New(), ActorRole: RoleDispatcher}
+	req = httptest.NewRequest("GET", "/", nil)
+	acting.Forward(req)
+
+	got, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, acting, got)
+}
+
+func TestRequireOwner_APIClient(t *testing.T) {
+	a := New(DefaultPolicy())
+	h := a.RequireUnscoped(JobCreate, func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-User-ID", uuid.New().String())
+	req.Header.Set("X-User-Type", string(RoleShipper))
+	req.Header.Set("X-Permissions", "job:read")
+	w := httptest.NewRecorder()
+	h(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// An empty grant allows nothing
+	req.Header.Set("X-Permissions", "")
+	w = httptest.NewRecorder()
+	h(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req.Header.Set("X-Permissions", "job:read job:create")
+	w = httptest.NewRecorder()
+	h(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPolicyDelegate(t *testing.T) {
+	policy := DefaultPolicy()
+
+	perms := policy.Delegate(RoleShipper, []Right{RightPostJobs, RightAcceptBids})
+	assert.Equal(t, []Permission{JobCreate, JobRead, JobUpdate, JobCancel, BidRead, BidAccept}, perms)
+
+	// A fleet operator cannot post jobs, so neither can their dispatcher
+	perms = policy.Delegate(RoleFleetOperator, []Right{RightPostJobs, RightAssignDrivers})
+	assert.Equal(t, []Permission{JobRead, FleetRead, HandoverManage}, perms)
+
+	assert.Empty(t, policy.Delegate(RoleShipper, []Right{"drive_trucks"}))
+	assert.False(t, Right("drive_trucks").Valid())
+	assert.True(t, RightMessage.Valid())
+}
+
+func TestPermissionsCoverPolicy(t *testing.T) {
+	for role, perms := range DefaultPolicy() {
+		for perm := range perms {
+			assert.Contains(t, Permissions, perm, "%s holds an unlisted permission", role)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package authz
+
+// Right is a power a shipper or fleet operator delegates to a dispatcher.
+// A dispatcher acting for them is granted the permissions of each right
+// they were delegated.
+type Right string
+
+const (
+	RightPostJobs      Right = "post_jobs"
+	RightAcceptBids    Right = "accept_bids"
+	RightAssignDrivers Right = "assign_drivers"
+	RightMessage       Right = "message"
+)
+
+// Rights lists every right that can be delegated
+var Rights = []Right{RightPostJobs, RightAcceptBids, RightAssignDrivers, RightMessage}
+
+// rightPermissions maps each right to the permissions it grants
+var rightPermissions = map[Right][]Permission{
+	RightPostJobs:      {JobCreate, JobRead, JobUpdate, JobCancel},
+	RightAcceptBids:    {JobRead, BidRead, BidAccept},
+	RightAssignDrivers: {JobRead, JobAssign, FleetRead, HandoverManage},
+	RightMessage:       {MessageSend},
+}
+
+// Valid reports whether the right is one of the known rights
+func (r Right) Valid() bool {
+	_, ok := rightPermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions the right grants
+func (r Right) Permissions() []Permission {
+	return rightPermissions[r]
+}
+
+// Delegate returns the permissions a dispatcher acting for a user of the
+// role is granted by rights. Permissions the role does not hold itself are
+// left out, so a dispatcher can never do more than the user they act for.
+func (p Policy) Delegate(role Role, rights []Right) []Permission {
+	perms := []Permission{}
+	seen := map[Permission]bool{}
+	for _, right := range rights {
+		for _, perm := range right.Permissions() {
+			if seen[perm] || p.Scope(role, perm) == ScopeNone {
+				continue
+			}
+			seen[perm] = true
+			perms = append(perms, perm)
+		}
+	}
+	return perms
+}
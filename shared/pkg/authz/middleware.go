@@ -0,0 +1,110 @@
+package authz
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"truckify/shared/pkg/response"
+)
+
+// Resolver returns the users and organisations who own the resource a
+// request targets. It should return ErrResourceNotFound or
+// ErrInvalidResourceID when the resource cannot be identified.
+type Resolver func(r *http.Request) ([]uuid.UUID, error)
+
+// Authorizer enforces a policy on HTTP handlers
+type Authorizer struct {
+	policy Policy
+}
+
+// New creates an authorizer for the given policy
+func New(policy Policy) *Authorizer {
+	return &Authorizer{policy: policy}
+}
+
+// Can reports whether the principal holds the permission on a resource
+// owned by any of the given users or organisations
+func (a *Authorizer) Can(p Principal, perm Permission, owners ...uuid.UUID) bool {
+	if !p.Granted(perm) {
+		return false
+	}
+	switch a.policy.Scope(p.Role, perm) {
+	case ScopeAny:
+		return true
+	case ScopeOwn:
+		for _, owner := range owners {
+			if p.Owns(owner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireUnscoped allows the request through if the caller's role holds the
+// permission at all. Use it only for routes that act on the caller's own
+// data, such as "my fleet", where the handler scopes the query to the
+// caller or checks ownership itself. Routes on a resource named in the path
+// should use RequireOwner.
+func (a *Authorizer) RequireUnscoped(perm Permission, next http.HandlerFunc) http.HandlerFunc {
+	return a.RequireOwner(perm, caller, next)
+}
+
+// caller resolves to the principal itself, for routes whose handler scopes
+// the query to the caller
+func caller(r *http.Request) ([]uuid.UUID, error) {
+	p, _ := PrincipalFrom(r.Context())
+	return []uuid.UUID{p.Org()}, nil
+}
+
+// RequireOwner allows the request through if the caller holds the permission
+// on every resource, or holds it on their own resources and is one of the
+// owners returned by the resolver. Without a resolver, ownership cannot be
+// checked and only callers holding the permission on every resource pass.
+func (a *Authorizer) RequireOwner(perm Permission, owners Resolver, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqID, _ := r.Context().Value("request_id").(string)
+
+		p, err := FromRequest(r)
+		if err != nil {
+			response.Unauthorized(w, "unauthorized", "", reqID)
+			return
+		}
+		r = r.WithContext(WithPrincipal(r.Context(), p))
+
+		if !p.Granted(perm) {
+			response.Forbidden(w, "Permission not granted to this API client", string(perm), reqID)
+			return
+		}
+
+		switch a.policy.Scope(p.Role, perm) {
+		case ScopeAny:
+		case ScopeOwn:
+			if owners == nil {
+				response.Forbidden(w, ErrForbidden.Error(), string(perm), reqID)
+				return
+			}
+			ids, err := owners(r)
+			switch {
+			case err == ErrResourceNotFound:
+				response.NotFound(w, "resource not found", "", reqID)
+				return
+			case err == ErrInvalidResourceID:
+				response.BadRequest(w, "invalid id", "", reqID)
+				return
+			case err != nil:
+				response.InternalServerError(w, "authorization failed", err.Error(), reqID)
+				return
+			}
+			if !a.Can(p, perm, ids...) {
+				response.Forbidden(w, ErrForbidden.Error(), string(perm), reqID)
+				return
+			}
+		default:
+			response.Forbidden(w, ErrForbidden.Error(), string(perm), reqID)
+			return
+		}
+
+		next(w, r)
+	}
+}
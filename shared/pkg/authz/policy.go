@@ -0,0 +1,232 @@
+package authz
+
+// Permission names an action on a resource type
+type Permission string
+
+const (
+	JobCreate     Permission = "job:create"
+	JobRead       Permission = "job:read"
+	JobUpdate     Permission = "job:update"
+	JobDelete     Permission = "job:delete"
+	JobAssign     Permission = "job:assign"
+	JobTransition Permission = "job:transition"
+	JobCancel     Permission = "job:cancel"
+
+	BidCreate Permission = "bid:create"
+	BidRead   Permission = "bid:read"
+	BidUpdate Permission = "bid:update"
+	BidAccept Permission = "bid:accept"
+
+	FleetManage    Permission = "fleet:manage"
+	FleetRead      Permission = "fleet:read"
+	HandoverManage Permission = "handover:manage"
+
+	LocationRead Permission = "location:read"
+
+	PolicyCreate Permission = "insurance_policy:create"
+	PolicyRead   Permission = "insurance_policy:read"
+	PolicyVerify Permission = "insurance_policy:verify"
+	ClaimCreate  Permission = "insurance_claim:create"
+	ClaimRead    Permission = "insurance_claim:read"
+	ClaimUpdate  Permission = "insurance_claim:update"
+	ClaimReview  Permission = "insurance_claim:review"
+
+	PaymentCreate  Permission = "payment:create"
+	PaymentRead    Permission = "payment:read"
+	PaymentProcess Permission = "payment:process"
+	PaymentRefund  Permission = "payment:refund"
+
+	UserRead   Permission = "user:read"
+	UserManage Permission = "user:manage"
+
+	WebhookManage Permission = "webhook:manage"
+
+	MessageSend Permission = "message:send"
+
+	AuditRead Permission = "audit:read"
+)
+
+// Permissions lists every permission
+var Permissions = []Permission{
+	JobCreate, JobRead, JobUpdate, JobDelete, JobAssign, JobTransition, JobCancel,
+	BidCreate, BidRead, BidUpdate, BidAccept,
+	FleetManage, FleetRead, HandoverManage,
+	LocationRead,
+	PolicyCreate, PolicyRead, PolicyVerify, ClaimCreate, ClaimRead, ClaimUpdate, ClaimReview,
+	PaymentCreate, PaymentRead, PaymentProcess, PaymentRefund,
+	UserRead, UserManage,
+	WebhookManage,
+	MessageSend,
+	AuditRead,
+}
+
+// Scope is how far a permission reaches
+type Scope int
+
+const (
+	// ScopeNone denies the permission
+	ScopeNone Scope = iota
+	// ScopeOwn allows the permission on resources the principal owns
+	ScopeOwn
+	// ScopeAny allows the permission on every resource
+	ScopeAny
+)
+
+// Policy maps each role to the permissions it holds
+type Policy map[Role]map[Permission]Scope
+
+// Scope returns how far the role holds the permission
+func (p Policy) Scope(role Role, perm Permission) Scope {
+	return p[role][perm]
+}
+
+// DefaultPolicy is the platform access policy. Ownership for ScopeOwn is
+// decided by each service's resolvers: a job is owned by its shipper and
+// their organisation (or its driver for transitions), a bid by its driver, a
+// fleet by its operator and their organisation, a payment by its payer, the
+// payer's organisation and its payee, and insurance policies and claims by the policy
+// holder, webhook endpoints by the user who registered them,
+// conversations by their participants, and a driver's location by the
+// driver, their fleet operator and the shipper of the job they are on.
+func DefaultPolicy() Policy {
+	return Policy{
+		RoleShipper: {
+			JobCreate: ScopeAny,
+			JobRead:   ScopeAny,
+			JobUpdate: ScopeOwn,
+			JobDelete: ScopeOwn,
+			JobAssign: ScopeOwn,
+			JobCancel: ScopeOwn,
+
+			BidRead:   ScopeAny,
+			BidAccept: ScopeOwn,
+
+			LocationRead: ScopeOwn,
+
+			PolicyCreate: ScopeAny,
+			PolicyRead:   ScopeOwn,
+			ClaimCreate:  ScopeOwn,
+			ClaimRead:    ScopeOwn,
+			ClaimUpdate:  ScopeOwn,
+
+			PaymentCreate:  ScopeOwn,
+			PaymentRead:    ScopeOwn,
+			PaymentProcess: ScopeOwn,
+
+			WebhookManage: ScopeOwn,
+
+			MessageSend: ScopeOwn,
+		},
+		RoleDriver: {
+			JobRead:       ScopeAny,
+			JobTransition: ScopeOwn,
+
+			BidCreate: ScopeAny,
+			BidRead:   ScopeAny,
+			BidUpdate: ScopeOwn,
+
+			FleetRead:      ScopeOwn,
+			HandoverManage: ScopeAny,
+
+			LocationRead: ScopeOwn,
+
+			PolicyCreate: ScopeAny,
+			PolicyRead:   ScopeOwn,
+			ClaimCreate:  ScopeOwn,
+			ClaimRead:    ScopeOwn,
+			ClaimUpdate:  ScopeOwn,
+
+			PaymentRead: ScopeOwn,
+
+			WebhookManage: ScopeOwn,
+
+			MessageSend: ScopeOwn,
+		},
+		RoleFleetOperator: {
+			JobRead: ScopeAny,
+
+			BidRead: ScopeAny,
+
+			FleetManage:    ScopeOwn,
+			FleetRead:      ScopeOwn,
+			HandoverManage: ScopeAny,
+
+			LocationRead: ScopeOwn,
+
+			PolicyCreate: ScopeAny,
+			PolicyRead:   ScopeOwn,
+			ClaimCreate:  ScopeOwn,
+			ClaimRead:    ScopeOwn,
+			ClaimUpdate:  ScopeOwn,
+
+			PaymentRead: ScopeOwn,
+
+			WebhookManage: ScopeOwn,
+
+			MessageSend: ScopeOwn,
+		},
+		// A dispatcher's own token only reads. Everything else they do for a
+		// shipper or fleet operator goes through an acting token, authorized
+		// as the user who delegated to them.
+		RoleDispatcher: {
+			JobRead: ScopeAny,
+
+			BidRead: ScopeAny,
+
+			FleetRead: ScopeAny,
+
+			WebhookManage: ScopeOwn,
+
+			MessageSend: ScopeOwn,
+		},
+		RoleSupport: {
+			JobRead: ScopeAny,
+
+			BidRead: ScopeAny,
+
+			FleetRead: ScopeAny,
+
+			PolicyRead:   ScopeAny,
+			PolicyVerify: ScopeAny,
+			ClaimRead:    ScopeAny,
+			ClaimReview:  ScopeAny,
+
+			PaymentRead:   ScopeAny,
+			PaymentRefund: ScopeAny,
+
+			UserRead: ScopeAny,
+		},
+		RoleAdmin: {
+			JobCreate:     ScopeAny,
+			JobRead:       ScopeAny,
+			JobUpdate:     ScopeAny,
+			JobDelete:     ScopeAny,
+			JobAssign:     ScopeAny,
+			JobTransition: ScopeAny,
+			JobCancel:     ScopeAny,
+
+			BidRead:   ScopeAny,
+			BidAccept: ScopeAny,
+
+			FleetRead: ScopeAny,
+
+			LocationRead: ScopeAny,
+
+			PolicyRead:   ScopeAny,
+			PolicyVerify: ScopeAny,
+			ClaimRead:    ScopeAny,
+			ClaimUpdate:  ScopeAny,
+			ClaimReview:  ScopeAny,
+
+			PaymentCreate:  ScopeAny,
+			PaymentRead:    ScopeAny,
+			PaymentProcess: ScopeAny,
+			PaymentRefund:  ScopeAny,
+
+			UserRead:   ScopeAny,
+			UserManage: ScopeAny,
+
+			AuditRead: ScopeAny,
+		},
+	}
+}
@@ -0,0 +1,454 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Migration errors
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind")
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	ErrIrreversible     = errors.New("migration has no down script")
+)
+
+// migrationLockID is the Postgres advisory lock held while migrating, so
+// replicas starting together apply each migration once
+const migrationLockID int64 = 0x7472756b6d6967 // "trukmig"
+
+// migrationFile matches 001_create_jobs.sql and 001_create_jobs.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(\.down)?\.sql$`)
+
+// Migration is one versioned schema change. Up is read from
+// NNN_name.sql and Down, if there is one, from NNN_name.down.sql.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// String returns the migration's file name without extension
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LoadMigrations reads the migrations in the root of fsys, ordered by
+// version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	downs := map[int]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 001_description.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] != "" {
+			downs[version] = string(data)
+			continue
+		}
+		if existing, ok := byVersion[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share a version", existing, entry.Name())
+		}
+		sum := sha256.Sum256(data)
+		byVersion[version] = &Migration{
+			Version:  version,
+			Name:     match[2],
+			Up:       string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, down := range downs {
+		m, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("down migration %03d has no up migration", version)
+		}
+		m.Down = down
+	}
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies a service's migrations to its database and records them
+// in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the migrations in fsys
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := m.apply(ctx, conn, mig.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+					mig.Version, mig.Name, mig.Checksum, time.Now())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and
+// returns the ones reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("migration %s: %w", mig, ErrIrreversible)
+			}
+			err := m.apply(ctx, conn, mig.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Baseline records the migrations up to version as applied without running
+// them, for databases whose schema was created before migrations were
+// tracked
+func (m *Migrator) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			_, err := conn.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+				mig.Version, mig.Name, mig.Checksum, time.Now())
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		row, ok := applied[mig.Version]
+		status = append(status, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: row.appliedAt})
+	}
+	return status, nil
+}
+
+// Check returns ErrSchemaBehind if any migration is pending, and
+// ErrChecksumMismatch if an applied migration has since been edited.
+// Migrations in the database that this build does not know about are
+// allowed, so an older release keeps running while a newer one rolls out.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	if err := m.compare(applied); err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig.String())
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending %s", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// withLock runs fn on a connection holding the migration lock, after
+// making sure the schema_migrations table exists
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// apply runs a migration script and its bookkeeping in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, record func(*sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// verify reads the applied migrations and checks their checksums
+func (m *Migrator) verify(ctx context.Context, q queryer) (map[int]appliedMigration, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return applied, m.compare(applied)
+}
+
+// compare checks applied migrations against the files they came from
+func (m *Migrator) compare(applied map[int]appliedMigration) error {
+	for _, mig := range m.migrations {
+		if row, ok := applied[mig.Version]; ok && row.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+		}
+	}
+	return nil
+}
+
+// applied reads schema_migrations. A missing table means nothing has been
+// applied.
+func (m *Migrator) applied(ctx context.Context, q queryer) (map[int]appliedMigration, error) {
+	applied := map[int]appliedMigration{}
+
+	rows, err := q.QueryContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if rows.Next() {
+		err = rows.Scan(&exists)
+	}
+	rows.Close()
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err = q.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			version int
+			row     appliedMigration
+		)
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
+
+// EnsureSchema checks that the database schema is current before a service
+// starts serving. With autoMigrate, pending migrations are applied first.
+func EnsureSchema(ctx context.Context, db *sql.DB, fsys fs.FS, autoMigrate bool) error {
+	migrator, err := NewMigrator(db, fsys)
+	if err != nil {
+		return err
+	}
+	if autoMigrate {
+		if _, err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+	return migrator.Check(ctx)
+}
+
+// MigrateCommand runs the migrate subcommand of a service binary:
+//
+//	migrate [up]              apply pending migrations
+//	migrate down [N]          revert the last N migrations (default 1)
+//	migrate status            list migrations
+//	migrate baseline VERSION  mark migrations up to VERSION as applied
+func MigrateCommand(ctx context.Context, db *sql.DB, fsys fs.FS, args []string, out io.Writer) error {
+	migrator, err := NewMigrator(db, fsys)
+	if err != nil {
+		return err
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	number := func(def int) (int, error) {
+		if len(args) == 0 {
+			if def < 0 {
+				return 0, fmt.Errorf("migrate %s needs a version", cmd)
+			}
+			return def, nil
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("migrate %s: invalid number %q", cmd, args[0])
+		}
+		return n, nil
+	}
+	report := func(verb string, migrations []Migration, err error) error {
+		for _, mig := range migrations {
+			fmt.Fprintf(out, "%s %s\n", verb, mig)
+		}
+		if err == nil && len(migrations) == 0 {
+			fmt.Fprintln(out, "nothing to do")
+		}
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		done, err := migrator.Up(ctx)
+		return report("applied", done, err)
+	case "down":
+		steps, err := number(1)
+		if err != nil {
+			return err
+		}
+		done, err := migrator.Down(ctx, steps)
+		return report("reverted", done, err)
+	case "baseline":
+		version, err := number(-1)
+		if err != nil {
+			return err
+		}
+		done, err := migrator.Baseline(ctx, version)
+		return report("baselined", done, err)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", s.Migration, applied)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down, status or baseline)", cmd)
+	}
+}
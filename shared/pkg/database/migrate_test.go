@@ -0,0 +1,145 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"001_create_jobs.sql":      {Data: []byte("CREATE TABLE jobs (id UUID PRIMARY KEY);")},
+	"001_create_jobs.down.sql": {Data: []byte("DROP TABLE jobs;")},
+	"002_add_status.sql":       {Data: []byte("ALTER TABLE jobs ADD COLUMN status TEXT;")},
+	"migrations.go":            {Data: []byte("package migrations")},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "create_jobs", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE jobs;", migrations[0].Down)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Equal(t, "002_add_status", migrations[1].String())
+	assert.Empty(t, migrations[1].Down)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	_, err := LoadMigrations(fstest.MapFS{"create_jobs.sql": {}})
+	assert.Error(t, err)
+
+	_, err = LoadMigrations(fstest.MapFS{"001_a.sql": {}, "0001_b.sql": {}})
+	assert.ErrorContains(t, err, "share a version")
+
+	_, err = LoadMigrations(fstest.MapFS{"003_orphan.down.sql": {}})
+	assert.ErrorContains(t, err, "no up migration")
+}
+
+// expectApplied expects the query for applied migrations to return versions
+func expectApplied(mock sqlmock.Sqlmock, migrations []Migration, versions ...int) {
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	rows := sqlmock.NewRows([]string{"version", "checksum", "applied_at"})
+	for _, v := range versions {
+		rows.AddRow(v, migrations[v-1].Checksum, time.Now())
+	}
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations`).WillReturnRows(rows)
+}
+
+func TestMigratorUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectApplied(mock, m.migrations, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE jobs ADD COLUMN status TEXT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).
+		WithArgs(2, "add_status", m.migrations[1].Checksum, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, 2, done[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorDownIrreversible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectApplied(mock, m.migrations, 1, 2)
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = m.Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorCheck(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+
+	// Nothing applied yet
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	err = m.Check(context.Background())
+	assert.ErrorIs(t, err, ErrSchemaBehind)
+	assert.ErrorContains(t, err, "001_create_jobs, 002_add_status")
+
+	// Up to date
+	expectApplied(mock, m.migrations, 1, 2)
+	assert.NoError(t, m.Check(context.Background()))
+
+	// An applied migration was edited afterwards
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version, checksum, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, "0000", time.Now()).
+			AddRow(2, m.migrations[1].Checksum, time.Now()).
+			AddRow(3, "from-a-newer-release", time.Now()))
+	assert.ErrorIs(t, m.Check(context.Background()), ErrChecksumMismatch)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateCommandStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	expectApplied(mock, m.migrations, 1)
+
+	var out bytes.Buffer
+	require.NoError(t, MigrateCommand(context.Background(), db, testMigrations, []string{"status"}, &out))
+	assert.Contains(t, out.String(), "001_create_jobs")
+	assert.Regexp(t, `002_add_status +pending`, out.String())
+
+	assert.Error(t, MigrateCommand(context.Background(), db, testMigrations, []string{"sideways"}, &out))
+}
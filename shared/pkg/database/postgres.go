@@ -3,10 +3,15 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"truckify/shared/pkg/metrics"
 )
 
 // PostgresConfig holds PostgreSQL configuration
@@ -35,7 +40,17 @@ func NewPostgresDB(config PostgresConfig) (*sql.DB, error) {
 		config.SSLMode,
 	)
 
-	db, err := sql.Open("postgres", dsn)
+	// Queries made within a traced request get a span; others are not traced
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(attribute.String("db.system", "postgresql")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -73,6 +88,9 @@ func NewPostgresDB(config PostgresConfig) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// Export connection pool stats
+	metrics.RegisterDB(config.Database, db)
+
 	return db, nil
 }
 
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBrokerClosed = errors.New("event broker is closed")
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+// Event is the envelope every domain event travels in
+type Event struct {
+	ID          uuid.UUID       `json:"id"`
+	Type        string          `json:"type"`
+	Version     int             `json:"version"`
+	Source      string          `json:"source"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	RequestID   string          `json:"request_id,omitempty"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Handler processes a delivered event
+type Handler func(ctx context.Context, evt *Event) error
+
+// Publisher publishes events to a broker
+type Publisher interface {
+	Publish(ctx context.Context, evt *Event) error
+}
+
+// Subscription represents an active subscription
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Subscriber registers handlers for event type patterns.
+// Patterns use dot-separated tokens where "*" matches a single token
+// and ">" matches all remaining tokens, e.g. "job.*" or "payment.>".
+type Subscriber interface {
+	Subscribe(pattern string, handler Handler) (Subscription, error)
+}
+
+// Broker is both a publisher and a subscriber
+type Broker interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+// New creates an event with the given payload marshalled as its data
+func New(ctx context.Context, source, eventType string, version int, aggregateID string, payload interface{}) (*Event, error) {
+	if eventType == "" || version < 1 {
+		return nil, ErrInvalidEvent
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	evt := &Event{
+		ID:          uuid.New(),
+		Type:        eventType,
+		Version:     version,
+		Source:      source,
+		AggregateID: aggregateID,
+		OccurredAt:  time.Now().UTC(),
+		Data:        data,
+	}
+	if ctx != nil {
+		if requestID, ok := ctx.Value("request_id").(string); ok {
+			evt.RequestID = requestID
+		}
+	}
+	return evt, nil
+}
+
+// Decode unmarshals the event data into the given payload
+func (e *Event) Decode(payload interface{}) error {
+	return json.Unmarshal(e.Data, payload)
+}
+
+// Subject returns the broker subject the event is published on
+func (e *Event) Subject() string {
+	return SubjectPrefix + e.Type
+}
+
+// SubjectPrefix namespaces all Truckify events on the broker
+const SubjectPrefix = "truckify.events."
+
+// Match reports whether an event type matches a subscription pattern
+func Match(pattern, eventType string) bool {
+	if pattern == "" {
+		return false
+	}
+	patternTokens := strings.Split(pattern, ".")
+	typeTokens := strings.Split(eventType, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(typeTokens) > i
+		}
+		if i >= len(typeTokens) {
+			return false
+		}
+		if token != "*" && token != typeTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(typeTokens)
+}
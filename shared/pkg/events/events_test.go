@@ -0,0 +1,288 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/shared/pkg/logger"
+)
+
+func TestNew(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "request_id", "req-123")
+	jobID := uuid.New()
+
+	evt, err := New(ctx, "job-service", TypeJobStatusChanged, 1, jobID.String(), JobStatusChangedV1{
+		JobID:      jobID,
+		FromStatus: "pending",
+		ToStatus:   "assigned",
+	})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, uuid.Nil, evt.ID)
+	assert.Equal(t, "req-123", evt.RequestID)
+	assert.Equal(t, "truckify.events.job.status_changed", evt.Subject())
+
+	var payload JobStatusChangedV1
+	require.NoError(t, evt.Decode(&payload))
+	assert.Equal(t, jobID, payload.JobID)
+	assert.Equal(t, "assigned", payload.ToStatus)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(context.Background(), "job-service", "", 1, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	_, err = New(context.Background(), "job-service", TypeJobCreated, 0, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		eventType string
+		want      bool
+	}{
+		{"job.created", "job.created", true},
+		{"job.created", "job.status_changed", false},
+		{"job.*", "job.created", true},
+		{"job.*", "bid.placed", false},
+		{"*.accepted", "bid.accepted", true},
+		{">", "payment.completed", true},
+		{"payment.>", "payment.completed", true},
+		{"payment.>", "payment", false},
+		{"job", "job.created", false},
+		{"", "job.created", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.eventType, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(tt.pattern, tt.eventType))
+		})
+	}
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	var jobEvents, allEvents []string
+	_, err := broker.Subscribe("job.*", func(ctx context.Context, evt *Event) error {
+		jobEvents = append(jobEvents, evt.Type)
+		return nil
+	})
+	require.NoError(t, err)
+	all, err := broker.Subscribe(">", func(ctx context.Context, evt *Event) error {
+		allEvents = append(allEvents, evt.Type)
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	jobEvt, _ := New(ctx, "job-service", TypeJobCreated, 1, "1", JobCreatedV1{})
+	bidEvt, _ := New(ctx, "bidding-service", TypeBidAccepted, 1, "2", BidAcceptedV1{})
+
+	require.NoError(t, broker.Publish(ctx, jobEvt))
+	require.NoError(t, broker.Publish(ctx, bidEvt))
+
+	assert.Equal(t, []string{TypeJobCreated}, jobEvents)
+	assert.Equal(t, []string{TypeJobCreated, TypeBidAccepted}, allEvents)
+	assert.Len(t, broker.Published(), 2)
+
+	require.NoError(t, all.Unsubscribe())
+	require.NoError(t, broker.Publish(ctx, bidEvt))
+	assert.Len(t, allEvents, 2)
+}
+
+func TestMemoryBrokerHandlerError(t *testing.T) {
+	broker := NewMemoryBroker()
+	handlerErr := errors.New("boom")
+	broker.Subscribe(">", func(ctx context.Context, evt *Event) error { return handlerErr })
+
+	evt, _ := New(context.Background(), "test", TypeBidPlaced, 1, "1", BidPlacedV1{})
+	assert.ErrorIs(t, broker.Publish(context.Background(), evt), handlerErr)
+
+	broker.Close()
+	assert.ErrorIs(t, broker.Publish(context.Background(), evt), ErrBrokerClosed)
+}
+
+// fakeMsg records how a delivery was acknowledged
+type fakeMsg struct{ acked, naked, termed bool }
+
+func (m *fakeMsg) Ack(...nats.AckOpt) error { m.acked = true; return nil }
+
+func (m *fakeMsg) NakWithDelay(time.Duration, ...nats.AckOpt) error { m.naked = true; return nil }
+
+func (m *fakeMsg) Term(...nats.AckOpt) error { m.termed = true; return nil }
+
+func TestNATSBrokerDeliver(t *testing.T) {
+	b := &NATSBroker{queue: "test", log: logger.New("test", "error")}
+	evt, _ := New(context.Background(), "test", TypeBidPlaced, 1, "1", BidPlacedV1{})
+	data, _ := json.Marshal(evt)
+
+	// Handled events are acknowledged
+	msg := &fakeMsg{}
+	b.deliver(msg, data, func(ctx context.Context, got *Event) error {
+		assert.Equal(t, evt.ID, got.ID)
+		return nil
+	})
+	assert.Equal(t, &fakeMsg{acked: true}, msg)
+
+	// Failed ones are redelivered
+	msg = &fakeMsg{}
+	b.deliver(msg, data, func(ctx context.Context, evt *Event) error { return errors.New("boom") })
+	assert.Equal(t, &fakeMsg{naked: true}, msg)
+
+	// Malformed ones are dropped without reaching the handler
+	msg = &fakeMsg{}
+	b.deliver(msg, []byte("{"), func(ctx context.Context, evt *Event) error {
+		t.Fatal("handler called with a malformed event")
+		return nil
+	})
+	assert.Equal(t, &fakeMsg{termed: true}, msg)
+}
+
+func TestConsumerName(t *testing.T) {
+	assert.Equal(t, "notification-service_job_status_changed", consumerName("notification-service", "job.status_changed"))
+	assert.Equal(t, "bidding-service_job_any", consumerName("bidding-service", "job.*"))
+	assert.Equal(t, "audit_payment_all", consumerName("audit", "payment.>"))
+}
+
+func TestWriteOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	evt, _ := New(context.Background(), "payment-service", TypePaymentCompleted, 1, "p1", PaymentV1{Status: "completed"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO event_outbox").
+		WithArgs(evt.ID, evt.Type, evt.Version, evt.Source, evt.AggregateID, evt.RequestID, []byte(evt.Data), evt.OccurredAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, WriteOutbox(context.Background(), tx, evt, nil))
+	require.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var outboxColumns = []string{"id", "event_type", "version", "source", "aggregate_id", "request_id", "payload", "occurred_at", "attempts"}
+
+func TestRelayDrain(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	broker := NewMemoryBroker()
+	relay := NewRelay(db, broker, logger.New("test", "error"))
+
+	first, second := uuid.New(), uuid.New()
+	payload, _ := json.Marshal(BidPlacedV1{Amount: 100})
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM event_outbox").
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(first, TypeBidPlaced, 1, "bidding-service", "b1", "req-1", payload, now, 0).
+			AddRow(second, TypeBidAccepted, 1, "bidding-service", "b1", nil, payload, now, 0))
+	mock.ExpectExec("UPDATE event_outbox SET published_at").WithArgs(sqlmock.AnyArg(), first).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE event_outbox SET published_at").WithArgs(sqlmock.AnyArg(), second).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	published := broker.Published()
+	require.Len(t, published, 2)
+	assert.Equal(t, TypeBidPlaced, published[0].Type)
+	assert.Equal(t, "req-1", published[0].RequestID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayDrainStopsOnPublishFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	broker := NewMemoryBroker()
+	broker.Subscribe(">", func(ctx context.Context, evt *Event) error { return errors.New("downstream unavailable") })
+	relay := NewRelay(db, broker, logger.New("test", "error"))
+
+	first, second := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM event_outbox").
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(first, TypeBidPlaced, 1, "bidding-service", "b1", nil, []byte(`{}`), time.Now(), 0).
+			AddRow(second, TypeBidPlaced, 1, "bidding-service", "b2", nil, []byte(`{}`), time.Now(), 0))
+	mock.ExpectExec("UPDATE event_outbox SET attempts").WithArgs("downstream unavailable", first).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayDrainSkipsDeadEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	poison, next := uuid.New(), uuid.New()
+	broker := NewMemoryBroker()
+	broker.Subscribe(">", func(ctx context.Context, evt *Event) error {
+		if evt.ID == poison {
+			return errors.New("message too large")
+		}
+		return nil
+	})
+	relay := NewRelay(db, broker, logger.New("test", "error"))
+
+	// The event failing its last attempt is set aside and the one behind it
+	// is published
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM event_outbox WHERE published_at IS NULL AND dead_at IS NULL").
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(poison, TypeBidPlaced, 1, "bidding-service", "b1", nil, []byte(`{}`), time.Now(), maxOutboxAttempts-1).
+			AddRow(next, TypeBidPlaced, 1, "bidding-service", "b2", nil, []byte(`{}`), time.Now(), 0))
+	mock.ExpectExec("UPDATE event_outbox SET attempts = attempts \\+ 1, last_error = \\$1, dead_at = \\$2").
+		WithArgs("message too large", sqlmock.AnyArg(), poison).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE event_outbox SET published_at").WithArgs(sqlmock.AnyArg(), next).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchema(t *testing.T) {
+	for _, eventType := range []string{TypeJobCreated, TypeJobStatusChanged, TypeBidPlaced, TypeBidAccepted,
+		TypePaymentCompleted, TypePaymentRefunded, TypeTrackingLocationUpdated} {
+		data, err := Schema(eventType, 1)
+		require.NoError(t, err, eventType)
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &doc), eventType)
+		assert.Equal(t, eventType+" v1", doc["title"])
+	}
+
+	data, err := Schema(TypeJobStatusChanged, 2)
+	require.NoError(t, err)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "job.status_changed v2", doc["title"])
+	assert.Contains(t, doc["properties"], "org_id")
+
+	_, err = Schema(TypeJobCreated, 99)
+	assert.Error(t, err)
+}
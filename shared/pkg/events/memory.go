@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker is an in-process broker that delivers events synchronously.
+// It is intended for tests and local development.
+type MemoryBroker struct {
+	mu        sync.RWMutex
+	subs      map[int]*memorySubscription
+	nextID    int
+	published []*Event
+	closed    bool
+}
+
+type memorySubscription struct {
+	broker  *MemoryBroker
+	id      int
+	pattern string
+	handler Handler
+}
+
+// NewMemoryBroker creates a new in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[int]*memorySubscription)}
+}
+
+// Publish delivers the event to every matching subscriber and returns the first handler error
+func (b *MemoryBroker) Publish(ctx context.Context, evt *Event) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBrokerClosed
+	}
+	b.published = append(b.published, evt)
+	var handlers []Handler
+	for id := 0; id < b.nextID; id++ {
+		if sub, ok := b.subs[id]; ok && Match(sub.pattern, evt.Type) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.Unlock()
+
+	var firstErr error
+	for _, h := range handlers {
+		if err := h(ctx, evt); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Subscribe registers a handler for events matching the pattern
+func (b *MemoryBroker) Subscribe(pattern string, handler Handler) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	sub := &memorySubscription{broker: b, id: b.nextID, pattern: pattern, handler: handler}
+	b.subs[sub.id] = sub
+	b.nextID++
+	return sub, nil
+}
+
+// Published returns every event published so far, in order
+func (b *MemoryBroker) Published() []*Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]*Event, len(b.published))
+	copy(out, b.published)
+	return out
+}
+
+// Close stops the broker and drops all subscriptions
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.subs = make(map[int]*memorySubscription)
+	return nil
+}
+
+// Unsubscribe removes the subscription from its broker
+func (s *memorySubscription) Unsubscribe() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	delete(s.broker.subs, s.id)
+	return nil
+}
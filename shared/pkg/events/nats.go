@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"truckify/shared/pkg/logger"
+)
+
+const (
+	// StreamName is the JetStream stream every event is stored in
+	StreamName = "TRUCKIFY_EVENTS"
+	// streamMaxAge is how long events wait for subscribers that are behind.
+	// Subscriptions idle for as long are removed.
+	streamMaxAge = 24 * time.Hour
+	// redeliverDelay is how long an event whose handler failed waits before
+	// it is delivered again
+	redeliverDelay = 5 * time.Second
+	// maxDeliver caps the deliveries of an event whose handler keeps failing
+	maxDeliver = 10
+)
+
+// NATSBroker publishes and subscribes to events over NATS JetStream, so that
+// events are redelivered until a subscriber handles them
+type NATSBroker struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	queue  string
+	fanout bool
+	log    *logger.Logger
+}
+
+// NewNATSBroker connects to NATS and creates the event stream if it does not
+// exist. Subscribers sharing the same queue group name load-balance
+// deliveries, so pass the service name to get one delivery per service rather
+// than one per replica.
+func NewNATSBroker(url, queue string, log *logger.Logger) (*NATSBroker, error) {
+	return connectNATS(url, queue, false, log)
+}
+
+// NewNATSFanoutBroker connects to NATS for subscribers that need every event
+// on every replica, such as those forwarding events to websocket clients.
+// Its subscriptions are ephemeral: they start with the events published
+// after them and go away with the replica, so replicas come and go without
+// leaving consumers behind. name only labels the connection.
+func NewNATSFanoutBroker(url, name string, log *logger.Logger) (*NATSBroker, error) {
+	return connectNATS(url, name, true, log)
+}
+
+func connectNATS(url, queue string, fanout bool, log *logger.Logger) (*NATSBroker, error) {
+	conn, err := nats.Connect(url,
+		nats.Name(queue),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     StreamName,
+		Subjects: []string{SubjectPrefix + ">"},
+		MaxAge:   streamMaxAge,
+	})
+	if err != nil && !errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create event stream: %w", err)
+	}
+	return &NATSBroker{conn: conn, js: js, queue: queue, fanout: fanout, log: log}, nil
+}
+
+// Publish stores the event in the stream and waits for the server to
+// acknowledge it. Events published again, as the relay does after a
+// failure, are only stored once.
+func (b *NATSBroker) Publish(ctx context.Context, evt *Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = b.js.Publish(evt.Subject(), data, nats.Context(ctx), nats.MsgId(evt.ID.String()))
+	return err
+}
+
+// Subscribe registers a durable queue subscription for events matching the
+// pattern, or an ephemeral one on a fanout broker. A new subscription starts
+// with the events published after it.
+func (b *NATSBroker) Subscribe(pattern string, handler Handler) (Subscription, error) {
+	cb := func(msg *nats.Msg) {
+		b.deliver(msg, msg.Data, handler)
+	}
+	opts := []nats.SubOpt{nats.ManualAck(), nats.DeliverNew(), nats.MaxDeliver(maxDeliver)}
+
+	var sub *nats.Subscription
+	var err error
+	if b.fanout {
+		// The server removes the consumer shortly after the replica
+		// disconnects, however it went away
+		sub, err = b.js.Subscribe(SubjectPrefix+pattern, cb, opts...)
+	} else {
+		sub, err = b.js.QueueSubscribe(SubjectPrefix+pattern, b.queue, cb,
+			append(opts, nats.Durable(consumerName(b.queue, pattern)), nats.InactiveThreshold(streamMaxAge))...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Close drains pending messages and closes the connection
+func (b *NATSBroker) Close() error {
+	return b.conn.Drain()
+}
+
+// acknowledger acknowledges the delivery of a JetStream message
+type acknowledger interface {
+	Ack(opts ...nats.AckOpt) error
+	NakWithDelay(delay time.Duration, opts ...nats.AckOpt) error
+	Term(opts ...nats.AckOpt) error
+}
+
+// deliver hands the event in data to the handler. The event is acknowledged
+// once handled; if the handler fails it is redelivered after redeliverDelay.
+func (b *NATSBroker) deliver(msg acknowledger, data []byte, handler Handler) {
+	var evt Event
+	if err := json.Unmarshal(data, &evt); err != nil {
+		// Redelivering it would fail the same way
+		b.log.Error("Dropping malformed event", "queue", b.queue, "error", err)
+		msg.Term()
+		return
+	}
+	if err := handler(context.Background(), &evt); err != nil {
+		b.log.Error("Event handler failed, redelivering", "queue", b.queue, "event_id", evt.ID,
+			"type", evt.Type, "request_id", evt.RequestID, "error", err)
+		msg.NakWithDelay(redeliverDelay)
+		return
+	}
+	msg.Ack()
+}
+
+// consumerName names the durable consumer of a queue group's subscription to
+// pattern. Consumer names cannot contain the dots and wildcards of subjects.
+func consumerName(queue, pattern string) string {
+	return queue + "_" + strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(pattern)
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"truckify/shared/pkg/logger"
+)
+
+// Execer is satisfied by *sql.DB, *sql.Tx, *sqlx.DB and *sqlx.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// WriteOutbox stores events in the event_outbox table. Pass the transaction
+// used for the domain write so the events commit or roll back with it.
+func WriteOutbox(ctx context.Context, exec Execer, evts ...*Event) error {
+	for _, evt := range evts {
+		if evt == nil {
+			continue
+		}
+		_, err := exec.ExecContext(ctx, `
+			INSERT INTO event_outbox (id, event_type, version, source, aggregate_id, request_id, payload, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			evt.ID, evt.Type, evt.Version, evt.Source, evt.AggregateID, evt.RequestID, []byte(evt.Data), evt.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("failed to write outbox event %s: %w", evt.Type, err)
+		}
+	}
+	return nil
+}
+
+// maxOutboxAttempts caps the publish attempts of an event before the relay
+// sets it aside as dead
+const maxOutboxAttempts = 10
+
+// outboxRow is an unpublished event and how many times publishing it failed
+type outboxRow struct {
+	evt      *Event
+	attempts int
+}
+
+// Relay drains the outbox table and publishes pending events
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	logger    *logger.Logger
+	batchSize int
+	interval  time.Duration
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(db *sql.DB, publisher Publisher, log *logger.Logger) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		logger:    log,
+		batchSize: 100,
+		interval:  time.Second,
+	}
+}
+
+// Run drains the outbox on every tick until the context is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := r.Drain(ctx)
+				if err != nil {
+					r.logger.Error("Outbox relay failed", "error", err)
+					break
+				}
+				if n < r.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Drain publishes one batch of pending events in order and returns how many
+// were published. Rows are locked with SKIP LOCKED so several replicas can
+// relay concurrently; publishing stops at the first failure to keep ordering.
+// An event that fails maxOutboxAttempts times is marked dead and skipped, so
+// it cannot hold up the events behind it.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, event_type, version, source, aggregate_id, request_id, payload, occurred_at, attempts
+		FROM event_outbox
+		WHERE published_at IS NULL AND dead_at IS NULL
+		ORDER BY occurred_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var pending []outboxRow
+	for rows.Next() {
+		row := outboxRow{evt: &Event{}}
+		evt := row.evt
+		var payload []byte
+		var requestID sql.NullString
+		if err := rows.Scan(&evt.ID, &evt.Type, &evt.Version, &evt.Source, &evt.AggregateID, &requestID, &payload, &evt.OccurredAt, &row.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		evt.RequestID = requestID.String
+		evt.Data = payload
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, row := range pending {
+		evt := row.evt
+		if err := r.publisher.Publish(ctx, evt); err != nil {
+			if row.attempts+1 >= maxOutboxAttempts {
+				if _, uerr := tx.ExecContext(ctx, `UPDATE event_outbox SET attempts = attempts + 1, last_error = $1, dead_at = $2 WHERE id = $3`,
+					err.Error(), time.Now(), evt.ID); uerr != nil {
+					return published, uerr
+				}
+				r.logger.Error("Giving up on outbox event", "event_id", evt.ID, "type", evt.Type,
+					"attempts", row.attempts+1, "error", err)
+				continue
+			}
+			if _, uerr := tx.ExecContext(ctx, `UPDATE event_outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2`,
+				err.Error(), evt.ID); uerr != nil {
+				return published, uerr
+			}
+			r.logger.Warn("Failed to publish outbox event", "event_id", evt.ID, "type", evt.Type, "error", err)
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE event_outbox SET published_at = $1 WHERE id = $2`, time.Now(), evt.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
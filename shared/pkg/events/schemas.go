@@ -0,0 +1,106 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types emitted by Truckify services
+const (
+	TypeJobCreated              = "job.created"
+	TypeJobStatusChanged        = "job.status_changed"
+	TypeBidPlaced               = "bid.placed"
+	TypeBidAccepted             = "bid.accepted"
+	TypePaymentCompleted        = "payment.completed"
+	TypePaymentRefunded         = "payment.refunded"
+	TypeTrackingLocationUpdated = "tracking.location_updated"
+)
+
+// JobCreatedV1 is the payload of job.created version 1
+type JobCreatedV1 struct {
+	JobID        uuid.UUID `json:"job_id"`
+	ShipperID    uuid.UUID `json:"shipper_id"`
+	Status       string    `json:"status"`
+	PickupCity   string    `json:"pickup_city"`
+	DeliveryCity string    `json:"delivery_city"`
+	VehicleType  string    `json:"vehicle_type"`
+	Price        float64   `json:"price"`
+	PickupDate   time.Time `json:"pickup_date"`
+}
+
+// JobStatusChangedV1 is the payload of job.status_changed version 1
+type JobStatusChangedV1 struct {
+	JobID      uuid.UUID  `json:"job_id"`
+	ShipperID  uuid.UUID  `json:"shipper_id"`
+	DriverID   *uuid.UUID `json:"driver_id,omitempty"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+// JobStatusChangedV2 is the payload of job.status_changed version 2. It adds
+// the organisation that owns the job, when one does.
+type JobStatusChangedV2 struct {
+	JobID      uuid.UUID  `json:"job_id"`
+	ShipperID  uuid.UUID  `json:"shipper_id"`
+	OrgID      *uuid.UUID `json:"org_id,omitempty"`
+	DriverID   *uuid.UUID `json:"driver_id,omitempty"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+// BidPlacedV1 is the payload of bid.placed version 1
+type BidPlacedV1 struct {
+	BidID    uuid.UUID `json:"bid_id"`
+	JobID    uuid.UUID `json:"job_id"`
+	DriverID uuid.UUID `json:"driver_id"`
+	Amount   float64   `json:"amount"`
+}
+
+// BidAcceptedV1 is the payload of bid.accepted version 1
+type BidAcceptedV1 struct {
+	BidID    uuid.UUID `json:"bid_id"`
+	JobID    uuid.UUID `json:"job_id"`
+	DriverID uuid.UUID `json:"driver_id"`
+	Amount   float64   `json:"amount"`
+}
+
+// PaymentV1 is the payload of payment.completed and payment.refunded version 1
+type PaymentV1 struct {
+	PaymentID    uuid.UUID `json:"payment_id"`
+	JobID        uuid.UUID `json:"job_id"`
+	PayerID      uuid.UUID `json:"payer_id"`
+	PayeeID      uuid.UUID `json:"payee_id"`
+	Amount       float64   `json:"amount"`
+	PlatformFee  float64   `json:"platform_fee"`
+	DriverPayout float64   `json:"driver_payout"`
+	Status       string    `json:"status"`
+}
+
+// TrackingLocationUpdatedV1 is the payload of tracking.location_updated version 1
+type TrackingLocationUpdatedV1 struct {
+	JobID      uuid.UUID `json:"job_id"`
+	DriverID   uuid.UUID `json:"driver_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Speed      float64   `json:"speed"`
+	Heading    float64   `json:"heading"`
+	EventType  string    `json:"event_type"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// Schema returns the JSON schema document for an event type and version
+func Schema(eventType string, version int) ([]byte, error) {
+	data, err := schemaFS.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", eventType, version))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s v%d", eventType, version)
+	}
+	return data, nil
+}
@@ -0,0 +1,225 @@
+// Package idempotency makes retried POST, PUT, PATCH and DELETE requests
+// safe. A client sends the same Idempotency-Key header with every attempt of
+// a request; the first attempt runs and its response is stored, and later
+// attempts get the stored response instead of running again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"truckify/shared/pkg/logger"
+	"truckify/shared/pkg/response"
+)
+
+// Header is the request header carrying the idempotency key
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// TTL is how long a completed response is replayed
+	TTL = 24 * time.Hour
+	// LockTimeout is how long a request may hold its key before another
+	// attempt may take it over, in case the first one died mid-request
+	LockTimeout = time.Minute
+	// maxKeyLength bounds client supplied keys
+	maxKeyLength = 255
+	// maxBodyBytes bounds the request bodies read for the fingerprint and
+	// the responses stored for replay
+	maxBodyBytes = 1 << 20
+)
+
+// Record is a request stored under an idempotency key and, once it has
+// finished, its response
+type Record struct {
+	Key         string
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// Store keeps idempotency records
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns
+	// nil if the caller now holds the key, or the record already stored
+	// under it.
+	Begin(ctx context.Context, key, fingerprint string) (*Record, error)
+	// Complete stores the response for a key held by the caller
+	Complete(ctx context.Context, rec *Record) error
+	// Release gives up a key held by the caller without storing a
+	// response, so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Middleware returns middleware that applies idempotency keys to mutating
+// requests that carry one. Keys are scoped to the caller, so two users
+// cannot see each other's responses by choosing the same key.
+//
+// Responses with a 5xx status are not stored: the key is released and the
+// client can retry. A retry whose method, path or body differs from the
+// original gets 422, and one that arrives while the original is still
+// running gets 409.
+func Middleware(store Store, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestID, _ := r.Context().Value("request_id").(string)
+			if len(key) > maxKeyLength {
+				response.BadRequest(w, "Invalid Idempotency-Key", "keys are at most 255 characters", requestID)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+			if err != nil {
+				response.BadRequest(w, "Failed to read request body", "", requestID)
+				return
+			}
+			if len(body) > maxBodyBytes {
+				response.Error(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body too large for an idempotent request", "", requestID)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key = r.Header.Get("X-User-ID") + ":" + key
+			fingerprint := fingerprint(r, body)
+
+			ctx := r.Context()
+			existing, err := store.Begin(ctx, key, fingerprint)
+			if err != nil {
+				log.WithContext(ctx).Error("Failed to claim idempotency key", "error", err)
+				response.ServiceUnavailable(w, "Service unavailable", "", requestID)
+				return
+			}
+
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != fingerprint:
+					response.Error(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+						"Idempotency-Key was already used for a different request", "", requestID)
+				case !existing.Completed:
+					w.Header().Set("Retry-After", "1")
+					response.Error(w, http.StatusConflict, "IDEMPOTENCY_KEY_IN_USE",
+						"A request with this Idempotency-Key is still being processed", "", requestID)
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// The handler failed or panicked; let the client try again
+				if err := store.Release(context.WithoutCancel(ctx), key); err != nil {
+					log.WithContext(ctx).Error("Failed to release idempotency key", "error", err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError || rec.overflow {
+				return
+			}
+			err = store.Complete(context.WithoutCancel(ctx), &Record{
+				Key:         key,
+				Fingerprint: fingerprint,
+				Completed:   true,
+				StatusCode:  rec.status,
+				Header:      storedHeader(w.Header()),
+				Body:        rec.body.Bytes(),
+			})
+			if err != nil {
+				log.WithContext(ctx).Error("Failed to store idempotent response", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// mutating reports whether a method changes state
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies a request by its method, path and body
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// storedHeader returns the response headers worth replaying
+func storedHeader(h http.Header) http.Header {
+	stored := h.Clone()
+	for _, name := range []string{"Date", "Set-Cookie", "X-Request-ID", "Content-Length"} {
+		stored.Del(name)
+	}
+	return stored
+}
+
+// replay writes a stored response
+func replay(w http.ResponseWriter, rec *Record) {
+	for name, values := range rec.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	w.Write(rec.Body)
+}
+
+// recorder passes a response through while keeping a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.overflow {
+		if r.body.Len()+len(b) > maxBodyBytes {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
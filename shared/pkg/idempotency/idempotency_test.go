@@ -0,0 +1,174 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/shared/pkg/logger"
+)
+
+// countingHandler creates a resource and counts how often it ran
+func countingHandler(calls *atomic.Int32, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/jobs/1")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `}`))
+	})
+}
+
+func newRequest(method, path, key, userID, body string) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(Header, key)
+	}
+	r.Header.Set("X-User-ID", userID)
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestReplaysCompletedRequest(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), logger.New("test", "error"))(countingHandler(&calls, http.StatusCreated))
+
+	first := serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{"a":1}`))
+	second := serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{"a":1}`))
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "/jobs/1", second.Header().Get("Location"))
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+}
+
+func TestKeysAreScopedToUser(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), logger.New("test", "error"))(countingHandler(&calls, http.StatusCreated))
+
+	serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{}`))
+	w := serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-2", `{}`))
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Empty(t, w.Header().Get(ReplayedHeader))
+}
+
+func TestRejectsKeyReuseWithDifferentRequest(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), logger.New("test", "error"))(countingHandler(&calls, http.StatusCreated))
+
+	serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{"a":1}`))
+
+	w := serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{"a":2}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "IDEMPOTENCY_KEY_REUSED")
+
+	w = serve(h, newRequest(http.MethodPost, "/bids", "key-1", "user-1", `{"a":1}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestConcurrentDuplicateGetsConflict(t *testing.T) {
+	store := NewMemoryStore()
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Middleware(store, logger.New("test", "error"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(h, newRequest(http.MethodPost, "/payments", "key-1", "user-1", `{}`)) }()
+	<-started
+
+	w := serve(h, newRequest(http.MethodPost, "/payments", "key-1", "user-1", `{}`))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestServerErrorsAreNotStored(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), logger.New("test", "error"))(countingHandler(&calls, http.StatusServiceUnavailable))
+
+	serve(h, newRequest(http.MethodPost, "/payments", "key-1", "user-1", `{}`))
+	serve(h, newRequest(http.MethodPost, "/payments", "key-1", "user-1", `{}`))
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestPanicReleasesKey(t *testing.T) {
+	store := NewMemoryStore()
+	h := Middleware(store, logger.New("test", "error"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	assert.Panics(t, func() { serve(h, newRequest(http.MethodPost, "/jobs", "key-1", "user-1", `{}`)) })
+
+	rec, err := store.Begin(context.Background(), "user-1:key-1", "anything")
+	require.NoError(t, err)
+	assert.Nil(t, rec, "key should be free after a panic")
+}
+
+func TestIgnoresSafeMethodsAndMissingKey(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), logger.New("test", "error"))(countingHandler(&calls, http.StatusOK))
+
+	serve(h, newRequest(http.MethodGet, "/jobs", "key-1", "user-1", ""))
+	serve(h, newRequest(http.MethodGet, "/jobs", "key-1", "user-1", ""))
+	serve(h, newRequest(http.MethodPost, "/jobs", "", "user-1", `{}`))
+	serve(h, newRequest(http.MethodPost, "/jobs", "", "user-1", `{}`))
+
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestPostgresStoreBegin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewPostgresStore(db)
+	store.now = func() time.Time { return now }
+
+	// Claimed
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("user-1:key-1", "fp", now, now.Add(TTL), now.Add(-LockTimeout)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("user-1:key-1"))
+	rec, err := store.Begin(context.Background(), "user-1:key-1", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	// Already completed
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT fingerprint, status_code, headers, body, completed_at").
+		WithArgs("user-1:key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "headers", "body", "completed_at"}).
+			AddRow("fp", 201, []byte(`{"Location":["/jobs/1"]}`), []byte(`{}`), now))
+	rec, err = store.Begin(context.Background(), "user-1:key-1", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.True(t, rec.Completed)
+	assert.Equal(t, 201, rec.StatusCode)
+	assert.Equal(t, "/jobs/1", rec.Header.Get("Location"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps idempotency records in process. Expired records are
+// only replaced, never removed, so it is meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+	now     func() time.Time
+}
+
+type memoryRecord struct {
+	Record
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord), now: time.Now}
+}
+
+// Begin claims the key unless it holds a live record
+func (s *MemoryStore) Begin(ctx context.Context, key, fingerprint string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if existing, ok := s.records[key]; ok {
+		abandoned := !existing.Completed && now.Sub(existing.createdAt) > LockTimeout
+		if now.Before(existing.expiresAt) && !abandoned {
+			rec := existing.Record
+			return &rec, nil
+		}
+	}
+
+	s.records[key] = &memoryRecord{
+		Record:    Record{Key: key, Fingerprint: fingerprint},
+		createdAt: now,
+		expiresAt: now.Add(TTL),
+	}
+	return nil, nil
+}
+
+// Complete stores the response
+func (s *MemoryStore) Complete(ctx context.Context, rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[rec.Key]; ok && existing.Fingerprint == rec.Fingerprint {
+		existing.Record = *rec
+		existing.Completed = true
+	}
+	return nil
+}
+
+// Release deletes an unfinished claim
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[key]; ok && !existing.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"truckify/shared/pkg/logger"
+)
+
+// PostgresStore keeps idempotency records in the idempotency_keys table
+type PostgresStore struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewPostgresStore creates a store on a service database
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db, now: time.Now}
+}
+
+// Begin inserts a claim for the key. An expired record, or a claim older
+// than LockTimeout that never completed, is taken over.
+func (s *PostgresStore) Begin(ctx context.Context, key, fingerprint string) (*Record, error) {
+	now := s.now()
+	var claimed string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at,
+			status_code = NULL, headers = NULL, body = NULL, completed_at = NULL
+		WHERE idempotency_keys.expires_at < $3
+			OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < $5)
+		RETURNING key`,
+		key, fingerprint, now, now.Add(TTL), now.Add(-LockTimeout)).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	// Someone else holds the key
+	rec := &Record{Key: key}
+	var (
+		statusCode  sql.NullInt64
+		headers     []byte
+		completedAt sql.NullTime
+	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, headers, body, completed_at
+		FROM idempotency_keys WHERE key = $1`, key).
+		Scan(&rec.Fingerprint, &statusCode, &headers, &rec.Body, &completedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+
+	rec.Completed = completedAt.Valid
+	rec.StatusCode = int(statusCode.Int64)
+	if len(headers) > 0 {
+		rec.Header = http.Header{}
+		if err := json.Unmarshal(headers, &rec.Header); err != nil {
+			return nil, fmt.Errorf("failed to decode stored headers: %w", err)
+		}
+	}
+	return rec, nil
+}
+
+// Complete stores the response
+func (s *PostgresStore) Complete(ctx context.Context, rec *Record) error {
+	headers, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $2, headers = $3, body = $4, completed_at = $5
+		WHERE key = $1 AND fingerprint = $6`,
+		rec.Key, rec.StatusCode, headers, rec.Body, s.now(), rec.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release deletes an unfinished claim
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND completed_at IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes expired records
+func (s *PostgresStore) Purge(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Run purges expired records every hour until the context is cancelled
+func (s *PostgresStore) Run(ctx context.Context, log *logger.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Purge(ctx); err != nil {
+				log.Warn("Failed to purge idempotency keys", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Denylist records revoked token IDs until the tokens would have expired anyway
+type Denylist interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// RedisDenylist stores revoked token IDs in Redis
+type RedisDenylist struct {
+	redis *redis.Client
+}
+
+// NewRedisDenylist creates a denylist backed by Redis
+func NewRedisDenylist(redisClient *redis.Client) *RedisDenylist {
+	return &RedisDenylist{redis: redisClient}
+}
+
+func denylistKey(jti string) string {
+	return "jwt:revoked:" + jti
+}
+
+// Revoke denies the token ID until expiresAt. Tokens that have already
+// expired are rejected by validation, so nothing is stored for them.
+func (d *RedisDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.redis.Set(ctx, denylistKey(jti), 1, ttl).Err()
+}
+
+// IsRevoked reports whether the token ID has been revoked
+func (d *RedisDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.redis.Exists(ctx, denylistKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
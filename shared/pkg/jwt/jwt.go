@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,16 +10,58 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrExpiredToken   = errors.New("token has expired")
+	ErrWrongTokenType = errors.New("wrong token type")
+)
+
+// Token types carried in the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeMFA stands for a login that has passed its password check
+	// and still needs a second factor. It grants no API access.
+	TokenTypeMFA = "mfa"
 )
 
 // Claims represents JWT claims
 type Claims struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	UserType  string `json:"user_type"`
+	TokenType string `json:"typ"`
+	SessionID string `json:"sid,omitempty"`
+	// ClientID, Scope and RateLimit are set on tokens issued to API
+	// clients: the API key the token was issued for, the space separated
+	// permissions it carries and the key's requests per minute
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	RateLimit int    `json:"rate_limit,omitempty"`
+	// OrgID and OrgRole name the organisation a user's session acts for and
+	// the user's role in it
+	OrgID   string `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	// Actor is set on acting-as tokens, which a dispatcher uses to act for
+	// the token's subject under the delegation named by DelegationID. Scope
+	// then lists the permissions delegated.
+	Actor        *Actor `json:"act,omitempty"`
+	DelegationID string `json:"delegation_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor is the user acting for the subject of an acting-as token
+type Actor struct {
 	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
 	UserType string `json:"user_type"`
-	jwt.RegisteredClaims
+}
+
+// Scopes returns the permissions of a client or acting-as token, or nil for
+// a user's own token
+func (c *Claims) Scopes() []string {
+	if c.ClientID == "" && c.DelegationID == "" {
+		return nil
+	}
+	return strings.Fields(c.Scope)
 }
 
 // TokenPair represents access and refresh tokens
@@ -26,91 +69,239 @@ type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int64  `json:"expires_in"`
+
+	// Token IDs and expiries, kept server-side for session tracking
+	AccessTokenID    string    `json:"-"`
+	RefreshTokenID   string    `json:"-"`
+	AccessExpiresAt  time.Time `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
+}
+
+// ClientToken is an access token issued to an API client or an acting-as
+// token. Neither has a refresh token; the client asks for a new one with its
+// key and the dispatcher with their own session.
+type ClientToken struct {
+	AccessToken string
+	ExpiresIn   int64
+	ID          string
+	ExpiresAt   time.Time
 }
 
 // JWTManager manages JWT tokens
 type JWTManager struct {
-	secretKey        string
-	accessTokenTTL   time.Duration
-	refreshTokenTTL  time.Duration
+	secretKey       string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	keys            *KeySet
+	verifier        KeySource
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a JWT manager that signs and verifies with a shared
+// HS256 secret
 func NewJWTManager(secretKey string, accessTokenTTL, refreshTokenTTL time.Duration) *JWTManager {
+	keys := NewKeySet(NewHMACKey("", secretKey))
 	return &JWTManager{
 		secretKey:       secretKey,
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
+		keys:            keys,
+		verifier:        keys,
+	}
+}
+
+// NewKeyManager creates a JWT manager that signs with the current key of the
+// set and verifies with any of its keys, identified by the kid header
+func NewKeyManager(keys *KeySet, accessTokenTTL, refreshTokenTTL time.Duration) *JWTManager {
+	return &JWTManager{
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		keys:            keys,
+		verifier:        keys,
+	}
+}
+
+// NewVerifier creates a JWT manager that only validates tokens, looking up
+// keys in source. Generating tokens fails with ErrNoSigningKey.
+func NewVerifier(source KeySource) *JWTManager {
+	return &JWTManager{verifier: source}
+}
+
+// JWKS returns the public keys of the manager's key set
+func (m *JWTManager) JWKS() *JWKS {
+	if m.keys == nil {
+		return &JWKS{Keys: []JWK{}}
 	}
+	return m.keys.JWKS()
 }
 
 // GenerateTokenPair generates an access and refresh token pair
 func (m *JWTManager) GenerateTokenPair(userID, email, userType string) (*TokenPair, error) {
-	accessToken, err := m.GenerateAccessToken(userID, email, userType)
+	return m.GenerateSessionTokenPair("", userID, email, userType)
+}
+
+// GenerateSessionTokenPair generates an access and refresh token pair bound
+// to a server-side session through the sid claim
+func (m *JWTManager) GenerateSessionTokenPair(sessionID, userID, email, userType string) (*TokenPair, error) {
+	return m.GenerateOrgTokenPair(sessionID, userID, email, userType, "", "")
+}
+
+// GenerateOrgTokenPair generates a session token pair that also carries the
+// organisation the session acts for and the user's role in it
+func (m *JWTManager) GenerateOrgTokenPair(sessionID, userID, email, userType, orgID, orgRole string) (*TokenPair, error) {
+	now := time.Now()
+	pair := &TokenPair{
+		ExpiresIn:        int64(m.accessTokenTTL.Seconds()),
+		AccessTokenID:    uuid.New().String(),
+		RefreshTokenID:   uuid.New().String(),
+		AccessExpiresAt:  now.Add(m.accessTokenTTL),
+		RefreshExpiresAt: now.Add(m.refreshTokenTTL),
+	}
+
+	access := m.newClaims(TokenTypeAccess, pair.AccessTokenID, sessionID, userID, email, userType, now, pair.AccessExpiresAt)
+	access.OrgID, access.OrgRole = orgID, orgRole
+	refresh := m.newClaims(TokenTypeRefresh, pair.RefreshTokenID, sessionID, userID, email, userType, now, pair.RefreshExpiresAt)
+	refresh.OrgID, refresh.OrgRole = orgID, orgRole
+
+	var err error
+	pair.AccessToken, err = m.sign(access)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := m.GenerateRefreshToken(userID, email, userType)
+	pair.RefreshToken, err = m.sign(refresh)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(m.accessTokenTTL.Seconds()),
-	}, nil
+	return pair, nil
 }
 
-// GenerateAccessToken generates an access token
-func (m *JWTManager) GenerateAccessToken(userID, email, userType string) (string, error) {
+// GenerateClientToken generates an access token for an API client acting
+// for the user who owns its key
+func (m *JWTManager) GenerateClientToken(clientID, userID, email, userType string, scopes []string, rateLimit int) (*ClientToken, error) {
 	now := time.Now()
-	claims := Claims{
-		UserID:   userID,
-		Email:    email,
-		UserType: userType,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			ID:        uuid.New().String(),
-			Issuer:    "truckify",
-		},
+	token := &ClientToken{
+		ExpiresIn: int64(m.accessTokenTTL.Seconds()),
+		ID:        uuid.New().String(),
+		ExpiresAt: now.Add(m.accessTokenTTL),
+	}
+
+	claims := m.newClaims(TokenTypeAccess, token.ID, "", userID, email, userType, now, token.ExpiresAt)
+	claims.ClientID = clientID
+	claims.Scope = strings.Join(scopes, " ")
+	claims.RateLimit = rateLimit
+
+	var err error
+	token.AccessToken, err = m.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// GenerateActingToken generates an access token for a dispatcher acting for
+// the user it names, limited to the delegated scopes. It expires after ttl
+// or the access token TTL, whichever is sooner.
+func (m *JWTManager) GenerateActingToken(delegationID, userID, email, userType string, actor Actor, scopes []string, ttl time.Duration) (*ClientToken, error) {
+	if ttl <= 0 || ttl > m.accessTokenTTL {
+		ttl = m.accessTokenTTL
+	}
+	now := time.Now()
+	token := &ClientToken{
+		ExpiresIn: int64(ttl.Seconds()),
+		ID:        uuid.New().String(),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	claims := m.newClaims(TokenTypeAccess, token.ID, "", userID, email, userType, now, token.ExpiresAt)
+	claims.Actor = &actor
+	claims.DelegationID = delegationID
+	claims.Scope = strings.Join(scopes, " ")
+
+	var err error
+	token.AccessToken, err = m.sign(claims)
+	if err != nil {
+		return nil, err
 	}
+	return token, nil
+}
+
+// GenerateMFAToken generates a token for a login waiting on its second
+// factor. Its ID names the challenge the auth service keeps for it.
+func (m *JWTManager) GenerateMFAToken(challengeID, userID, email, userType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return m.sign(m.newClaims(TokenTypeMFA, challengeID, "", userID, email, userType, now, now.Add(ttl)))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+// AccessTokenTTL returns how long access tokens are valid
+func (m *JWTManager) AccessTokenTTL() time.Duration {
+	return m.accessTokenTTL
+}
+
+// GenerateAccessToken generates an access token
+func (m *JWTManager) GenerateAccessToken(userID, email, userType string) (string, error) {
+	now := time.Now()
+	return m.sign(m.newClaims(TokenTypeAccess, uuid.New().String(), "", userID, email, userType, now, now.Add(m.accessTokenTTL)))
 }
 
 // GenerateRefreshToken generates a refresh token
 func (m *JWTManager) GenerateRefreshToken(userID, email, userType string) (string, error) {
 	now := time.Now()
-	claims := Claims{
-		UserID:   userID,
-		Email:    email,
-		UserType: userType,
+	return m.sign(m.newClaims(TokenTypeRefresh, uuid.New().String(), "", userID, email, userType, now, now.Add(m.refreshTokenTTL)))
+}
+
+func (m *JWTManager) newClaims(tokenType, id, sessionID, userID, email, userType string, issuedAt, expiresAt time.Time) Claims {
+	return Claims{
+		UserID:    userID,
+		Email:     email,
+		UserType:  userType,
+		TokenType: tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.refreshTokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			ID:        id,
 			Issuer:    "truckify",
 		},
 	}
+}
+
+func (m *JWTManager) sign(claims Claims) (string, error) {
+	if m.keys == nil {
+		return "", ErrNoSigningKey
+	}
+	key, err := m.keys.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signingMaterial())
+}
+
+// keyFunc returns the verification key named by the token's kid header.
+// Tokens without a kid are checked against the key with an empty ID, which
+// is how HS256 secrets are registered.
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := m.verifier.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrInvalidToken
+	}
+	return key.verificationMaterial(), nil
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(m.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc,
+		jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA}))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -127,9 +318,35 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// ValidateAccessToken validates a token and checks that it is an access token
+func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return m.validateType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a token and checks that it is a refresh token
+func (m *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return m.validateType(tokenString, TokenTypeRefresh)
+}
+
+// ValidateMFAToken validates a token and checks that it is an MFA token
+func (m *JWTManager) ValidateMFAToken(tokenString string) (*Claims, error) {
+	return m.validateType(tokenString, TokenTypeMFA)
+}
+
+func (m *JWTManager) validateType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
 // RefreshAccessToken generates a new access token from a refresh token
 func (m *JWTManager) RefreshAccessToken(refreshToken string) (string, error) {
-	claims, err := m.ValidateToken(refreshToken)
+	claims, err := m.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return "", err
 	}
@@ -237,3 +237,111 @@ func TestTokenExpiry(t *testing.T) {
 	assert.Nil(t, claims)
 	assert.ErrorIs(t, err, ErrExpiredToken)
 }
+
+func TestTypedTokens(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+
+	tokenPair, err := manager.GenerateSessionTokenPair("session-1", "user-1", "test@example.com", "driver")
+	require.NoError(t, err)
+
+	access, err := manager.ValidateAccessToken(tokenPair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeAccess, access.TokenType)
+	assert.Equal(t, "session-1", access.SessionID)
+	assert.Equal(t, tokenPair.AccessTokenID, access.ID)
+
+	refresh, err := manager.ValidateRefreshToken(tokenPair.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeRefresh, refresh.TokenType)
+	assert.Equal(t, "session-1", refresh.SessionID)
+	assert.Equal(t, tokenPair.RefreshTokenID, refresh.ID)
+
+	// An access token cannot be used to refresh, nor a refresh token to call APIs
+	_, err = manager.ValidateRefreshToken(tokenPair.AccessToken)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = manager.ValidateAccessToken(tokenPair.RefreshToken)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = manager.RefreshAccessToken(tokenPair.AccessToken)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+
+	// An MFA token is neither
+	mfa, err := manager.GenerateMFAToken("challenge-1", "user-1", "test@example.com", "driver", 5*time.Minute)
+	require.NoError(t, err)
+	claims, err := manager.ValidateMFAToken(mfa)
+	require.NoError(t, err)
+	assert.Equal(t, "challenge-1", claims.ID)
+	_, err = manager.ValidateAccessToken(mfa)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = manager.ValidateMFAToken(tokenPair.AccessToken)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+}
+
+func TestGenerateClientToken(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+
+	token, err := manager.GenerateClientToken("key-1", "user-1", "tms@example.com", "shipper", []string{"job:create", "job:read"}, 600)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), token.ExpiresIn)
+
+	claims, err := manager.ValidateAccessToken(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", claims.ClientID)
+	assert.Equal(t, "user-1", claims.UserID)
+	assert.Equal(t, token.ID, claims.ID)
+	assert.Equal(t, []string{"job:create", "job:read"}, claims.Scopes())
+	assert.Equal(t, 600, claims.RateLimit)
+
+	// A user's token carries no scopes, so nothing is restricted
+	user, err := manager.GenerateAccessToken("user-1", "tms@example.com", "shipper")
+	require.NoError(t, err)
+	claims, err = manager.ValidateAccessToken(user)
+	require.NoError(t, err)
+	assert.Nil(t, claims.Scopes())
+}
+
+func TestGenerateOrgTokenPair(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+
+	pair, err := manager.GenerateOrgTokenPair("session-1", "user-1", "ops@example.com", "shipper", "org-1", "admin")
+	require.NoError(t, err)
+
+	for _, token := range []string{pair.AccessToken, pair.RefreshToken} {
+		claims, err := manager.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "org-1", claims.OrgID)
+		assert.Equal(t, "admin", claims.OrgRole)
+		assert.Equal(t, "session-1", claims.SessionID)
+	}
+
+	// Session tokens without an organisation leave the claims out
+	pair, err = manager.GenerateSessionTokenPair("session-1", "user-1", "ops@example.com", "shipper")
+	require.NoError(t, err)
+	claims, err := manager.ValidateAccessToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Empty(t, claims.OrgID)
+}
+
+func TestGenerateActingToken(t *testing.T) {
+	manager := NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour)
+
+	actor := Actor{UserID: "dispatcher-1", UserType: "dispatcher"}
+	token, err := manager.GenerateActingToken("delegation-1", "user-1", "ops@example.com", "shipper", actor, []string{"job:create"}, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), token.ExpiresIn)
+
+	claims, err := manager.ValidateAccessToken(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+	assert.Equal(t, "shipper", claims.UserType)
+	assert.Equal(t, &actor, claims.Actor)
+	assert.Equal(t, "delegation-1", claims.DelegationID)
+	assert.Equal(t, []string{"job:create"}, claims.Scopes())
+
+	// A delegation about to expire cuts the token short
+	token, err = manager.GenerateActingToken("delegation-1", "user-1", "ops@example.com", "shipper", actor, nil, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), token.ExpiresIn)
+	claims, err = manager.ValidateAccessToken(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, claims.Scopes())
+}
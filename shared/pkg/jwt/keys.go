@@ -0,0 +1,289 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a signing or verification key identified by its kid
+type Key struct {
+	ID        string
+	Algorithm string
+	// Private is the signing key for RS256 and EdDSA; nil for verification-only keys
+	Private crypto.Signer
+	// Public verifies RS256 and EdDSA signatures
+	Public crypto.PublicKey
+	// Secret signs and verifies HS256 tokens. It is never published.
+	Secret []byte
+	// NotBefore is when the key starts signing. Keys are published and
+	// accepted for verification before then, so verifiers learn a new key
+	// ahead of its first use.
+	NotBefore time.Time
+	// ExpiresAt is when the key stops being accepted; zero means never
+	ExpiresAt time.Time
+	// VerifyOnly keeps a key with signing material from signing, for
+	// accepting tokens issued under a retired key
+	VerifyOnly bool
+}
+
+// NewHMACKey creates an HS256 key
+func NewHMACKey(id, secret string) *Key {
+	return &Key{ID: id, Algorithm: AlgorithmHS256, Secret: []byte(secret)}
+}
+
+// NewAsymmetricKey creates an RS256 or EdDSA key from a private or public
+// key, choosing the algorithm from the key type
+func NewAsymmetricKey(id string, key interface{}) (*Key, error) {
+	k := &Key{ID: id}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Algorithm, k.Private, k.Public = AlgorithmRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Algorithm, k.Public = AlgorithmRS256, key
+	case ed25519.PrivateKey:
+		k.Algorithm, k.Private, k.Public = AlgorithmEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Algorithm, k.Public = AlgorithmEdDSA, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return k, nil
+}
+
+func (k *Key) canSign() bool {
+	if k.VerifyOnly {
+		return false
+	}
+	if k.Algorithm == AlgorithmHS256 {
+		return len(k.Secret) > 0
+	}
+	return k.Private != nil
+}
+
+func (k *Key) expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// signingMaterial returns the key the JWT library signs with
+func (k *Key) signingMaterial() interface{} {
+	if k.Algorithm == AlgorithmHS256 {
+		return k.Secret
+	}
+	return k.Private
+}
+
+// verificationMaterial returns the key the JWT library verifies with
+func (k *Key) verificationMaterial() interface{} {
+	if k.Algorithm == AlgorithmHS256 {
+		return k.Secret
+	}
+	return k.Public
+}
+
+// KeySource looks up verification keys by kid
+type KeySource interface {
+	VerificationKey(kid string) (*Key, error)
+}
+
+// KeySet holds the keys a service signs and verifies with. Several keys can
+// be active at once; the one that started signing most recently is used for
+// new tokens, so rotation is scheduled by adding a key with a future NotBefore.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+// NewKeySet creates a key set
+func NewKeySet(keys ...*Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// Add adds a key to the set, replacing any key with the same ID
+func (s *KeySet) Add(key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, k := range s.keys {
+		if k.ID == key.ID {
+			s.keys[i] = key
+			return
+		}
+	}
+	s.keys = append(s.keys, key)
+}
+
+// SigningKey returns the key to sign new tokens with at the given time
+func (s *KeySet) SigningKey(now time.Time) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var current *Key
+	for _, k := range s.keys {
+		if !k.canSign() || now.Before(k.NotBefore) || k.expired(now) {
+			continue
+		}
+		if current == nil || k.NotBefore.After(current.NotBefore) ||
+			(k.NotBefore.Equal(current.NotBefore) && k.ID > current.ID) {
+			current = k
+		}
+	}
+	if current == nil {
+		return nil, ErrNoSigningKey
+	}
+	return current, nil
+}
+
+// VerificationKey returns the unexpired key with the given ID
+func (s *KeySet) VerificationKey(kid string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, k := range s.keys {
+		if k.ID == kid && !k.expired(now) {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS returns the public keys that verifiers should accept
+func (s *KeySet) JWKS() *JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	set := &JWKS{Keys: []JWK{}}
+	for _, k := range s.keys {
+		if k.Algorithm == AlgorithmHS256 || k.expired(now) {
+			continue
+		}
+		jwk, err := publicJWK(k)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// keyManifest lists the key files in a key directory with their schedule
+type keyManifest struct {
+	Keys []struct {
+		ID        string    `json:"kid"`
+		File      string    `json:"file"`
+		NotBefore time.Time `json:"not_before"`
+		ExpiresAt time.Time `json:"expires_at"`
+	} `json:"keys"`
+}
+
+// LoadKeyDir loads PEM keys from a directory. If the directory contains a
+// keys.json manifest, it names each key file with its kid and schedule:
+//
+//	{"keys": [{"kid": "2026-10", "file": "2026-10.pem", "not_before": "2026-10-01T00:00:00Z"}]}
+//
+// Otherwise every *.pem file is loaded with its file name as the kid, and
+// the last name in sort order signs.
+func LoadKeyDir(dir string) (*KeySet, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "keys.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return loadPEMDir(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest keyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse key manifest: %w", err)
+	}
+
+	set := NewKeySet()
+	for _, entry := range manifest.Keys {
+		key, err := LoadKeyFile(filepath.Join(dir, entry.File), entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		key.NotBefore = entry.NotBefore
+		key.ExpiresAt = entry.ExpiresAt
+		set.Add(key)
+	}
+	return set, nil
+}
+
+func loadPEMDir(dir string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	set := NewKeySet()
+	for _, file := range files {
+		key, err := LoadKeyFile(file, strings.TrimSuffix(filepath.Base(file), ".pem"))
+		if err != nil {
+			return nil, err
+		}
+		set.Add(key)
+	}
+	return set, nil
+}
+
+// LoadKeyFile loads an RSA or Ed25519 key from a PEM file. Private keys may
+// be PKCS#8 or PKCS#1; public keys are loaded as verification-only keys.
+func LoadKeyFile(path, kid string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key, err := NewAsymmetricKey(kid, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
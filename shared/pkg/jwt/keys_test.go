@@ -0,0 +1,221 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := NewAsymmetricKey(kid, private)
+	require.NoError(t, err)
+	return key
+}
+
+func newEd25519Key(t *testing.T, kid string) *Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := NewAsymmetricKey(kid, private)
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeyManagerAlgorithms(t *testing.T) {
+	tests := []struct {
+		name string
+		key  func(t *testing.T) *Key
+		alg  string
+	}{
+		{"RS256", func(t *testing.T) *Key { return newRSAKey(t, "rsa-1") }, AlgorithmRS256},
+		{"EdDSA", func(t *testing.T) *Key { return newEd25519Key(t, "ed-1") }, AlgorithmEdDSA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key(t)
+			manager := NewKeyManager(NewKeySet(key), 15*time.Minute, 7*24*time.Hour)
+
+			token, err := manager.GenerateAccessToken("user-1", "test@example.com", "driver")
+			require.NoError(t, err)
+
+			parsed, _, err := gojwt.NewParser().ParseUnverified(token, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, parsed.Method.Alg())
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+
+			claims, err := manager.ValidateAccessToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.UserID)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Now()
+	old := newEd25519Key(t, "old")
+	old.NotBefore = now.Add(-48 * time.Hour)
+	next := newEd25519Key(t, "next")
+	next.NotBefore = now.Add(time.Hour)
+	current := newEd25519Key(t, "current")
+	current.NotBefore = now.Add(-time.Hour)
+
+	keys := NewKeySet(old, next, current)
+
+	key, err := keys.SigningKey(now)
+	require.NoError(t, err)
+	assert.Equal(t, "current", key.ID)
+
+	// The scheduled key takes over once its start time passes
+	key, err = keys.SigningKey(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "next", key.ID)
+
+	// Tokens signed with the previous key still verify
+	manager := NewKeyManager(NewKeySet(old), 15*time.Minute, time.Hour)
+	token, err := manager.GenerateAccessToken("user-1", "test@example.com", "driver")
+	require.NoError(t, err)
+	_, err = NewKeyManager(keys, 15*time.Minute, time.Hour).ValidateAccessToken(token)
+	assert.NoError(t, err)
+
+	// Expired keys neither sign nor verify
+	old.ExpiresAt = now.Add(-time.Minute)
+	_, err = NewKeyManager(keys, 15*time.Minute, time.Hour).ValidateAccessToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifyOnlyKeys(t *testing.T) {
+	legacy := NewHMACKey("", "legacy-secret")
+	legacy.VerifyOnly = true
+	keys := NewKeySet(legacy, newRSAKey(t, "rsa-1"))
+
+	key, err := keys.SigningKey(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "rsa-1", key.ID)
+
+	// Tokens from the shared-secret era are still accepted
+	token, err := NewJWTManager("legacy-secret", 15*time.Minute, time.Hour).GenerateAccessToken("user-1", "a@b.c", "driver")
+	require.NoError(t, err)
+	_, err = NewKeyManager(keys, 15*time.Minute, time.Hour).ValidateAccessToken(token)
+	assert.NoError(t, err)
+
+	// HMAC secrets are never published
+	assert.Len(t, keys.JWKS().Keys, 1)
+}
+
+func TestAlgorithmMismatchRejected(t *testing.T) {
+	key := newRSAKey(t, "rsa-1")
+	keys := NewKeySet(key)
+
+	// An HS256 token claiming the RSA kid must not verify
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, Claims{UserID: "user-1", TokenType: TokenTypeAccess})
+	token.Header["kid"] = "rsa-1"
+	signed, err := token.SignedString([]byte("anything"))
+	require.NoError(t, err)
+
+	_, err = NewKeyManager(keys, time.Minute, time.Hour).ValidateToken(signed)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifierCannotSign(t *testing.T) {
+	verifier := NewVerifier(NewKeySet(newEd25519Key(t, "ed-1")))
+	_, err := verifier.GenerateAccessToken("user-1", "test@example.com", "driver")
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	edKey := newEd25519Key(t, "ed-1")
+	published := NewKeySet(rsaKey)
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(published.JWKS())
+	}))
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL, time.Hour)
+	remote.minRefresh = 0
+	verifier := NewVerifier(remote)
+
+	token, err := NewKeyManager(NewKeySet(rsaKey), time.Minute, time.Hour).GenerateAccessToken("user-1", "a@b.c", "driver")
+	require.NoError(t, err)
+	claims, err := verifier.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+
+	// Cached keys are reused
+	_, err = verifier.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// A new kid triggers a refetch
+	published.Add(edKey)
+	token, err = NewKeyManager(NewKeySet(edKey), time.Minute, time.Hour).GenerateAccessToken("user-2", "a@b.c", "driver")
+	require.NoError(t, err)
+	claims, err = verifier.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-2", claims.UserID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestLoadKeyDir(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "2026-01.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate))
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "2026-02.pem"), "PRIVATE KEY", der)
+
+	// Without a manifest the last file name signs
+	keys, err := LoadKeyDir(dir)
+	require.NoError(t, err)
+	key, err := keys.SigningKey(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02", key.ID)
+	assert.Equal(t, AlgorithmEdDSA, key.Algorithm)
+	assert.Len(t, keys.JWKS().Keys, 2)
+
+	// A manifest schedules the keys
+	manifest := `{"keys": [
+		{"kid": "rsa", "file": "2026-01.pem", "not_before": "2020-01-01T00:00:00Z"},
+		{"kid": "ed", "file": "2026-02.pem", "not_before": "2999-01-01T00:00:00Z"}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keys.json"), []byte(manifest), 0o600))
+
+	keys, err = LoadKeyDir(dir)
+	require.NoError(t, err)
+	key, err = keys.SigningKey(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "rsa", key.ID)
+	assert.Equal(t, AlgorithmRS256, key.Algorithm)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
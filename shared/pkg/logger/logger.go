@@ -4,6 +4,8 @@ import (
 	"context"
 	"log/slog"
 	"os"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Logger struct {
@@ -37,13 +39,26 @@ func New(serviceName string, level string) *Logger {
 	return &Logger{Logger: logger}
 }
 
-// WithContext adds context values to the logger
+// WithContext adds context values to the logger: the request ID and, inside
+// a traced request, the trace and span IDs
 func (l *Logger) WithContext(ctx context.Context) *Logger {
+	var args []any
+
 	// Extract request ID from context if it exists
 	if requestID, ok := ctx.Value("request_id").(string); ok {
-		return &Logger{Logger: l.Logger.With(slog.String("request_id", requestID))}
+		args = append(args, slog.String("request_id", requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		args = append(args,
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
 	}
-	return l
+
+	if len(args) == 0 {
+		return l
+	}
+	return &Logger{Logger: l.Logger.With(args...)}
 }
 
 // WithFields adds additional fields to the logger
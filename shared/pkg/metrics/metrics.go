@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"truckify/shared/pkg/middleware"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths
+// cannot blow up label cardinality
+const unmatchedRoute = "unmatched"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by service, method, route template and status.",
+	}, []string{"service", "method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency, by service, method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method", "route", "status"})
+)
+
+type routeKey struct{}
+
+type routeLabel struct {
+	name string
+}
+
+// Middleware records request counts and latencies for a service. Requests
+// are labelled by their gorilla/mux path template rather than the raw path.
+func Middleware(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			label := &routeLabel{}
+			wrapped := middleware.NewStatusWriter(w)
+
+			next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), routeKey{}, label)))
+
+			route := label.name
+			if route == "" {
+				route = routeTemplate(r)
+			}
+			status := strconv.Itoa(wrapped.Status())
+			requestsTotal.WithLabelValues(service, r.Method, route, status).Inc()
+			requestDuration.WithLabelValues(service, r.Method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// SetRoute overrides the route label for the current request, for handlers
+// that route requests themselves
+func SetRoute(ctx context.Context, route string) {
+	if label, ok := ctx.Value(routeKey{}).(*routeLabel); ok {
+		label.name = route
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return unmatchedRoute
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports connection pool statistics for a database
+func RegisterDB(name string, db *sql.DB) {
+	Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Register adds a collector to the default registry. A collector that is
+// already registered is left in place.
+func Register(c prometheus.Collector) {
+	if err := prometheus.Register(c); err != nil {
+		var exists prometheus.AlreadyRegisteredError
+		if !errors.As(err, &exists) {
+			panic(err)
+		}
+	}
+}
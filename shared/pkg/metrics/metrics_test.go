@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareLabelsByRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware("test-service"))
+	router.HandleFunc("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"a", "b", "c"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil))
+	}
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(requestsTotal.WithLabelValues("test-service", "GET", "/jobs/{id}", "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(requestsTotal.WithLabelValues("test-service", "GET", "/jobs/a", "404")))
+}
+
+func TestSetRouteOverridesLabel(t *testing.T) {
+	h := Middleware("test-gateway")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r.Context(), "/api/v1/jobs")
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/jobs/123", nil))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("test-gateway", "POST", "/api/v1/jobs", "200")))
+}
+
+func TestUnmatchedRoute(t *testing.T) {
+	h := Middleware("test-unmatched")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/random/path", nil))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("test-unmatched", "GET", unmatchedRoute, "200")))
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,7 +35,7 @@ func Logger(log *logger.Logger) func(http.Handler) http.Handler {
 			start := time.Now()
 
 			// Create a response writer wrapper to capture status code
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			wrapped := NewStatusWriter(w)
 
 			next.ServeHTTP(wrapped, r)
 
@@ -41,7 +44,7 @@ func Logger(log *logger.Logger) func(http.Handler) http.Handler {
 			log.WithContext(r.Context()).Info("HTTP request",
 				"method", r.Method,
 				"path", r.URL.Path,
-				"status", wrapped.statusCode,
+				"status", wrapped.Status(),
 				"duration_ms", duration.Milliseconds(),
 				"remote_addr", r.RemoteAddr,
 				"user_agent", r.UserAgent(),
@@ -50,15 +53,54 @@ func Logger(log *logger.Logger) func(http.Handler) http.Handler {
 	}
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
-type responseWriter struct {
+// StatusWriter wraps http.ResponseWriter to capture the status code. It
+// passes flushes and hijacks through, so it can wrap streaming and WebSocket
+// handlers.
+type StatusWriter struct {
 	http.ResponseWriter
-	statusCode int
+	status      int
+	wroteHeader bool
 }
 
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+// NewStatusWriter wraps w
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code written, 200 if none was set explicitly
+func (w *StatusWriter) Status() int {
+	return w.status
+}
+
+func (w *StatusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming responses
+func (w *StatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports WebSocket upgrades
+func (w *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 // CORS adds CORS headers to responses
@@ -83,7 +125,7 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 					w.Header().Set("Access-Control-Allow-Origin", "*")
 				}
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-Request-ID, X-User-ID, X-User-Type")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Idempotency-Key, X-Request-ID, X-User-ID, X-User-Type")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				w.Header().Set("Access-Control-Max-Age", "3600")
 			}
@@ -134,3 +176,23 @@ func SecurityHeaders(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// TimeoutHeader carries the time the caller will wait for a response, in
+// milliseconds. The API gateway sets it from the route timeout.
+const TimeoutHeader = "X-Request-Timeout"
+
+// Deadline bounds the request context by the caller's timeout, so work the
+// caller has already given up on is cancelled
+func Deadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+		if err != nil || ms <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -3,67 +3,297 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/redis/go-redis/v9"
 	"truckify/shared/pkg/response"
 )
 
-// RateLimiter implements rate limiting using Redis
+var (
+	rateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_decisions_total",
+		Help: "Rate limiter decisions by policy: allowed or limited.",
+	}, []string{"policy", "result"})
+
+	rateLimitFallbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rate_limit_fallback_total",
+		Help: "Rate limiter decisions made in process because Redis was unavailable.",
+	})
+)
+
+// Rate limiting algorithms
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills at
+	// Limit per Window
+	TokenBucket = "token_bucket"
+	// SlidingLog allows at most Limit requests in any Window
+	SlidingLog = "sliding_log"
+)
+
+// redisRetryDelay is how long the limiter stays on its in-process fallback
+// after a Redis error before trying Redis again
+const redisRetryDelay = time.Second
+
+// Quota is a request allowance
+type Quota struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+}
+
+// Policy is a named set of quotas. Callers get the quota for their user
+// type, or Default.
+type Policy struct {
+	Name      string
+	Default   Quota
+	UserTypes map[string]Quota
+}
+
+// quota returns the quota that applies to a user type
+func (p Policy) quota(userType string) Quota {
+	if q, ok := p.UserTypes[userType]; ok {
+		return q
+	}
+	return p.Default
+}
+
+// decision is the outcome of checking a request against a quota
+type decision struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// reset is when the quota is next replenished, and retryAfter how long
+	// a limited caller has to wait
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// RateLimiter limits requests per caller. Counters live in Redis so every
+// gateway instance shares them; while Redis is unavailable each instance
+// falls back to counting in process.
 type RateLimiter struct {
-	redis      *redis.Client
-	maxRequest int
-	window     time.Duration
+	redis    *redis.Client
+	memory   *memoryStore
+	policy   Policy
+	proxies  TrustedProxies
+	now      func() time.Time
+	redisOff atomic.Int64
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a rate limiter whose default policy is a token
+// bucket of maxRequests per window. redisClient may be nil to count in
+// process only.
 func NewRateLimiter(redisClient *redis.Client, maxRequests int, window time.Duration) *RateLimiter {
 	return &RateLimiter{
-		redis:      redisClient,
-		maxRequest: maxRequests,
-		window:     window,
+		redis:  redisClient,
+		memory: newMemoryStore(),
+		policy: Policy{
+			Name:    "default",
+			Default: Quota{Algorithm: TokenBucket, Limit: maxRequests, Window: window},
+		},
+		now: time.Now,
 	}
 }
 
-// Limit applies rate limiting
+// SetTrustedProxies sets the proxies whose X-Forwarded-For header is
+// believed when working out the client IP
+func (rl *RateLimiter) SetTrustedProxies(proxies TrustedProxies) {
+	rl.proxies = proxies
+}
+
+// Limit applies the default policy
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get client identifier (IP address or user ID from context)
-		identifier := r.RemoteAddr
-		if userID, ok := r.Context().Value("user_id").(string); ok && userID != "" {
-			identifier = userID
+	return rl.Apply(rl.policy)(next)
+}
+
+// Apply returns middleware enforcing a policy. It must run after
+// authentication so that signed-in callers are limited per user, and API
+// clients per key.
+func (rl *RateLimiter) Apply(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userType, _ := r.Context().Value("user_type").(string)
+			quota := policy.quota(userType)
+			// An API key with its own limit gets it in place of the policy's
+			if perMinute, ok := r.Context().Value("api_key_rate_limit").(int); ok && perMinute > 0 {
+				quota = Quota{Algorithm: TokenBucket, Limit: perMinute, Window: time.Minute}
+			}
+			key := fmt.Sprintf("rate_limit:%s:%s:%s", policy.Name, quota.Algorithm, rl.identity(r))
+
+			d := rl.allow(r.Context(), key, quota)
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(d.reset)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", quota.Limit, seconds(quota.Window)))
+
+			if !d.allowed {
+				rateLimitDecisions.WithLabelValues(policy.Name, "limited").Inc()
+				h.Set("Retry-After", strconv.Itoa(seconds(d.retryAfter)))
+				requestID, _ := r.Context().Value("request_id").(string)
+				response.Error(w, http.StatusTooManyRequests, "RATE_LIMIT_EXCEEDED",
+					"Too many requests", "Please try again later", requestID)
+				return
+			}
+
+			rateLimitDecisions.WithLabelValues(policy.Name, "allowed").Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow checks a request against Redis, or the in-process store while
+// Redis is unavailable
+func (rl *RateLimiter) allow(ctx context.Context, key string, q Quota) decision {
+	now := rl.now()
+	if rl.redis != nil && now.UnixNano() >= rl.redisOff.Load() {
+		d, err := redisAllow(ctx, rl.redis, key, q, now)
+		if err == nil {
+			return d
+		}
+		if ctx.Err() == nil {
+			rl.redisOff.Store(now.Add(redisRetryDelay).UnixNano())
 		}
+	}
+	if rl.redis != nil {
+		rateLimitFallbacks.Inc()
+	}
+	return rl.memory.allow(key, q, now)
+}
+
+// identity returns the caller a request is counted against: the API key,
+// else the authenticated user, else the client IP. Keys are counted apart
+// from their owner and from each other.
+func (rl *RateLimiter) identity(r *http.Request) string {
+	if keyID, ok := r.Context().Value("api_key_id").(string); ok && keyID != "" {
+		return "key:" + keyID
+	}
+	if userID, ok := r.Context().Value("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + rl.proxies.ClientIP(r)
+}
 
-		key := fmt.Sprintf("rate_limit:%s", identifier)
+// seconds rounds a duration up to whole seconds for a header
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
 
-		ctx := context.Background()
+// TrustedProxies are the networks of proxies in front of the gateway, such
+// as the load balancer
+type TrustedProxies []netip.Prefix
 
-		// Increment counter
-		count, err := rl.redis.Incr(ctx, key).Result()
+// ParseTrustedProxies parses a list of CIDRs or single addresses
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
 		if err != nil {
-			// If Redis fails, allow the request (fail open)
-			next.ServeHTTP(w, r)
-			return
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
 		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
 
-		// Set expiration on first request
-		if count == 1 {
-			rl.redis.Expire(ctx, key, rl.window)
+// contains reports whether addr belongs to a trusted proxy
+func (p TrustedProxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
 		}
+	}
+	return false
+}
 
-		// Check if limit exceeded
-		if count > int64(rl.maxRequest) {
-			requestID, _ := r.Context().Value("request_id").(string)
-			response.Error(w, http.StatusTooManyRequests, "RATE_LIMIT_EXCEEDED",
-				"Too many requests", "Please try again later", requestID)
-			return
+// ClientIP returns the address of the client that made a request. When the
+// connection comes from a trusted proxy, X-Forwarded-For is read from the
+// right, skipping trusted proxies, so a client cannot choose its own
+// address by sending the header itself.
+func (p TrustedProxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	addr = addr.Unmap()
+
+	if !p.contains(addr) {
+		return addr.String()
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !p.contains(addr) {
+			break
 		}
+	}
+	return addr.String()
+}
 
-		// Set rate limit headers
-		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", rl.maxRequest))
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", rl.maxRequest-int(count)))
+// ClientIPHeader carries the client address the API gateway worked out with
+// its trusted proxies to the services behind it. The gateway drops any
+// value a client sends.
+const ClientIPHeader = "X-Client-IP"
 
-		next.ServeHTTP(w, r)
-	})
+// ClientIP stores the address of the client in the request context under
+// "client_ip", for the gateway to pass on in ClientIPHeader
+func ClientIP(proxies TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "client_ip", proxies.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ForwardedClientIP returns the client address the API gateway passed in
+// ClientIPHeader, or the address of the connection when the request did not
+// come through the gateway. Services use it rather than X-Forwarded-For,
+// whose leftmost hops are chosen by the client.
+func ForwardedClientIP(r *http.Request) string {
+	if addr, err := netip.ParseAddr(r.Header.Get(ClientIPHeader)); err == nil {
+		return addr.WithZone("").String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().WithZone("").String()
+	}
+	return host
 }
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills the bucket for the time since it was last used,
+// then takes a token if one is left. It returns whether the request is
+// allowed and the tokens remaining.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(capacity, tokens + (now - ts) * capacity / window)
+  ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingLogScript drops requests older than the window from the log, then
+// records this one if the log has room. It returns whether the request is
+// allowed, the requests in the window and the time of the oldest.
+var slidingLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+
+redis.call('PEXPIRE', KEYS[1], window)
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2] or tostring(now)}
+`)
+
+// redisAllow checks a request against a quota stored in Redis. Times are
+// passed in milliseconds.
+func redisAllow(ctx context.Context, client *redis.Client, key string, q Quota, now time.Time) (decision, error) {
+	nowMs := now.UnixMilli()
+	windowMs := q.Window.Milliseconds()
+
+	switch q.Algorithm {
+	case SlidingLog:
+		member := strconv.FormatInt(nowMs, 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+		res, err := slidingLogScript.Run(ctx, client, []string{key}, q.Limit, windowMs, nowMs, member).Slice()
+		if err != nil {
+			return decision{}, err
+		}
+		if len(res) != 3 {
+			return decision{}, fmt.Errorf("unexpected sliding log result %v", res)
+		}
+		allowed, _ := res[0].(int64)
+		count, _ := res[1].(int64)
+		oldest, err := strconv.ParseFloat(fmt.Sprint(res[2]), 64)
+		if err != nil {
+			return decision{}, err
+		}
+		expires := time.Duration(int64(oldest)+windowMs-nowMs) * time.Millisecond
+		return slidingLogDecision(q, allowed == 1, int(count), expires), nil
+
+	default:
+		res, err := tokenBucketScript.Run(ctx, client, []string{key}, q.Limit, windowMs, nowMs).Slice()
+		if err != nil {
+			return decision{}, err
+		}
+		if len(res) != 2 {
+			return decision{}, fmt.Errorf("unexpected token bucket result %v", res)
+		}
+		allowed, _ := res[0].(int64)
+		tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+		if err != nil {
+			return decision{}, err
+		}
+		return tokenBucketDecision(q, allowed == 1, tokens), nil
+	}
+}
+
+// tokenBucketDecision describes a token bucket left with tokens
+func tokenBucketDecision(q Quota, allowed bool, tokens float64) decision {
+	perToken := q.Window / time.Duration(q.Limit)
+	d := decision{
+		allowed:   allowed,
+		limit:     q.Limit,
+		remaining: int(math.Floor(tokens)),
+		reset:     time.Duration((float64(q.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		d.retryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return d
+}
+
+// slidingLogDecision describes a log holding count requests whose oldest
+// leaves the window after expires
+func slidingLogDecision(q Quota, allowed bool, count int, expires time.Duration) decision {
+	d := decision{
+		allowed:   allowed,
+		limit:     q.Limit,
+		remaining: q.Limit - count,
+		reset:     expires,
+	}
+	if !allowed {
+		d.retryAfter = expires
+	}
+	return d
+}
+
+// memorySweepInterval is how often idle entries are dropped from the
+// in-process store
+const memorySweepInterval = time.Minute
+
+// memoryStore runs the same algorithms as the Redis scripts in process. It
+// is the fallback while Redis is unavailable, so limits are per gateway
+// instance rather than shared.
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	tokens  float64
+	last    time.Time
+	log     []time.Time
+	expires time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// allow checks a request against a quota
+func (s *memoryStore) allow(key string, q Quota, now time.Time) decision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &memoryEntry{tokens: float64(q.Limit), last: now}
+		s.entries[key] = e
+	}
+	e.expires = now.Add(q.Window)
+
+	if q.Algorithm == SlidingLog {
+		cutoff := now.Add(-q.Window)
+		kept := e.log[:0]
+		for _, t := range e.log {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		e.log = kept
+
+		allowed := len(e.log) < q.Limit
+		if allowed {
+			e.log = append(e.log, now)
+		}
+		return slidingLogDecision(q, allowed, len(e.log), e.log[0].Add(q.Window).Sub(now))
+	}
+
+	if elapsed := now.Sub(e.last); elapsed > 0 {
+		e.tokens = math.Min(float64(q.Limit), e.tokens+float64(elapsed)*float64(q.Limit)/float64(q.Window))
+		e.last = now
+	}
+	allowed := e.tokens >= 1
+	if allowed {
+		e.tokens--
+	}
+	return tokenBucketDecision(q, allowed, e.tokens)
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLimiter returns a limiter backed by miniredis with a clock the
+// test controls
+func newTestLimiter(t *testing.T) (*RateLimiter, *miniredis.Miniredis, *time.Time) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Unix(1_700_000_000, 0)
+	rl := NewRateLimiter(client, 3, time.Minute)
+	rl.now = func() time.Time { return now }
+	return rl, mr, &now
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func withUser(r *http.Request, userID, userType string) *http.Request {
+	ctx := context.WithValue(r.Context(), "user_id", userID)
+	ctx = context.WithValue(ctx, "user_type", userType)
+	return r.WithContext(ctx)
+}
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestTokenBucketAllowsBurstThenRefills(t *testing.T) {
+	rl, mr, now := newTestLimiter(t)
+	h := rl.Limit(ok)
+
+	for i := 2; i >= 0; i-- {
+		w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(i), w.Header().Get("RateLimit-Remaining"))
+	}
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "20", w.Header().Get("Retry-After"))
+	assert.Equal(t, "3;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, []string{"rate_limit:default:token_bucket:ip:192.0.2.1"}, mr.Keys())
+
+	// One token comes back every 20 seconds
+	*now = now.Add(20 * time.Second)
+	assert.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+}
+
+func TestSlidingLog(t *testing.T) {
+	rl, _, now := newTestLimiter(t)
+	h := rl.Apply(Policy{Name: "login", Default: Quota{Algorithm: SlidingLog, Limit: 2, Window: time.Minute}})(ok)
+
+	assert.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodPost, "/", nil)).Code)
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodPost, "/", nil)).Code)
+
+	w := serve(h, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// The first request leaves the window
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodPost, "/", nil)).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, httptest.NewRequest(http.MethodPost, "/", nil)).Code)
+}
+
+func TestLimitsPerUserAndUserType(t *testing.T) {
+	rl, _, _ := newTestLimiter(t)
+	h := rl.Apply(Policy{
+		Name:      "tracking",
+		Default:   Quota{Algorithm: TokenBucket, Limit: 1, Window: time.Minute},
+		UserTypes: map[string]Quota{"driver": {Algorithm: TokenBucket, Limit: 5, Window: time.Minute}},
+	})(ok)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.Equal(t, http.StatusOK, serve(h, withUser(r, "shipper-1", "shipper")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, withUser(r, "shipper-1", "shipper")).Code)
+	// Same address, different user
+	assert.Equal(t, http.StatusOK, serve(h, withUser(r, "shipper-2", "shipper")).Code)
+
+	w := serve(h, withUser(r, "driver-1", "driver"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestLimitsPerAPIKey(t *testing.T) {
+	rl, mr, _ := newTestLimiter(t)
+	h := rl.Limit(ok)
+
+	withKey := func(keyID string, perMinute int) *http.Request {
+		r := withUser(httptest.NewRequest(http.MethodPost, "/", nil), "shipper-1", "shipper")
+		ctx := context.WithValue(r.Context(), "api_key_id", keyID)
+		ctx = context.WithValue(ctx, "api_key_rate_limit", perMinute)
+		return r.WithContext(ctx)
+	}
+
+	w := serve(h, withKey("key-1", 1))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, withKey("key-1", 1)).Code)
+
+	// Another key of the same user has its own allowance, and a key
+	// without a limit of its own gets the policy's
+	assert.Equal(t, http.StatusOK, serve(h, withKey("key-2", 1)).Code)
+	assert.Equal(t, "3", serve(h, withKey("key-3", 0)).Header().Get("RateLimit-Limit"))
+	assert.Contains(t, mr.Keys(), "rate_limit:default:token_bucket:key:key-1")
+}
+
+func TestFallsBackWhenRedisIsDown(t *testing.T) {
+	rl, mr, _ := newTestLimiter(t)
+	h := rl.Limit(ok)
+
+	mr.Close()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Code)
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct client ignores header", "203.0.113.7:5555", []string{"1.2.3.4"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"spoofed hops are skipped", "10.1.2.3:443", []string{"1.2.3.4, 198.51.100.9, 192.168.1.1"}, "198.51.100.9"},
+		{"multiple headers", "10.1.2.3:443", []string{"1.2.3.4", "198.51.100.9"}, "198.51.100.9"},
+		{"only proxies", "10.1.2.3:443", []string{"10.9.9.9"}, "10.9.9.9"},
+		{"no header", "10.1.2.3:443", nil, "10.1.2.3"},
+		{"garbage stops the walk", "10.1.2.3:443", []string{"198.51.100.9, nonsense"}, "10.1.2.3"},
+		{"ipv6", "[2001:db8::1]:443", nil, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, tt.want, proxies.ClientIP(r))
+		})
+	}
+}
+
+func TestForwardedClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.1.2.3:443"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	assert.Equal(t, "10.1.2.3", ForwardedClientIP(r))
+
+	r.Header.Set(ClientIPHeader, "198.51.100.9")
+	assert.Equal(t, "198.51.100.9", ForwardedClientIP(r))
+
+	// Anything but an address is ignored
+	r.Header.Set(ClientIPHeader, strings.Repeat("1", 200))
+	assert.Equal(t, "10.1.2.3", ForwardedClientIP(r))
+}
+
+func TestParseTrustedProxies_Invalid(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,407 @@
+// Package openapi builds the OpenAPI 3 document of a service from the routes
+// registered on its router and the model structs of its handlers, and
+// checks requests and responses against it.
+//
+// A service describes its endpoints in a Routes map keyed by method and
+// path template:
+//
+//	var Routes = openapi.Routes{
+//		"POST /jobs":     {Summary: "Create a job", Request: model.CreateJobRequest{}, Response: model.Job{}, Status: http.StatusCreated},
+//		"GET /jobs/{id}": {Summary: "Get a job", Response: model.Job{}},
+//	}
+//
+// Build walks the router, so every registered route is in the document
+// even before it is described; Check reports routes and descriptions that
+// do not match up.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"truckify/shared/pkg/pagination"
+)
+
+// Version is the OpenAPI version of the documents
+const Version = "3.0.3"
+
+// Path is where services serve their document
+const Path = "/openapi.json"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+// Operation is one method of a path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the security schemes of a document
+type Components struct {
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	// Name and In locate an apiKey scheme's key, such as a header
+	Name string `json:"name,omitempty"`
+	In   string `json:"in,omitempty"`
+}
+
+// ServeHTTP serves the document as JSON
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// Operation returns the operation for a method and path template, or nil
+func (d *Document) Operation(method, path string) *Operation {
+	if item := d.Paths[path]; item != nil {
+		return (*item)[strings.ToLower(method)]
+	}
+	return nil
+}
+
+// Route describes an endpoint
+type Route struct {
+	Summary     string
+	Description string
+	// Request is a value of the JSON body type, or nil for no body
+	Request interface{}
+	// OptionalBody marks a Request body that may be left out
+	OptionalBody bool
+	// Multipart marks a Request sent as multipart/form-data rather than JSON
+	Multipart bool
+	// Form marks a Request sent as application/x-www-form-urlencoded
+	Form bool
+	// Response is a value of the type returned as data on success, or nil
+	// when the data is not described
+	Response interface{}
+	// Raw marks a Response sent as is rather than in the response envelope
+	Raw bool
+	// Status is the success status, 200 if zero
+	Status int
+	// List documents the pagination, filter and sort parameters of a list
+	List *pagination.Spec
+	// Query documents other query parameters
+	Query []Parameter
+}
+
+// Routes describes the endpoints of a service by "METHOD /path/{param}"
+type Routes map[string]Route
+
+// Build returns the document of the routes registered on router
+func Build(router *mux.Router, info Info, routes Routes) *Document {
+	doc := &Document{OpenAPI: Version, Info: info, Paths: map[string]*PathItem{}}
+	for _, ep := range endpoints(router) {
+		item := doc.Paths[ep.path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[ep.path] = item
+		}
+		(*item)[strings.ToLower(ep.method)] = routes[ep.key()].operation(ep.path, info.Title)
+	}
+	return doc
+}
+
+// Check reports routes registered on router without a description, and
+// descriptions of routes that are not registered
+func Check(router *mux.Router, routes Routes) error {
+	registered := map[string]bool{}
+	var problems []string
+	for _, ep := range endpoints(router) {
+		registered[ep.key()] = true
+		if _, ok := routes[ep.key()]; !ok {
+			problems = append(problems, "undocumented route "+ep.key())
+		}
+	}
+	for key := range routes {
+		if !registered[key] {
+			problems = append(problems, "documented route "+key+" is not registered")
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+}
+
+// endpoint is a method and normalised path template of a router
+type endpoint struct {
+	method, path string
+}
+
+func (e endpoint) key() string {
+	return e.method + " " + e.path
+}
+
+// endpoints lists the routes of router. Routes without methods, such as
+// WebSocket upgrades, are listed as GET. The metrics and document routes
+// are left out.
+func endpoints(router *mux.Router) []endpoint {
+	var eps []endpoint
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+		path := Template(tmpl)
+		if path == "/metrics" || path == Path {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, m := range methods {
+			eps = append(eps, endpoint{method: strings.ToUpper(m), path: path})
+		}
+		return nil
+	})
+	return eps
+}
+
+// Template turns a mux path template into an OpenAPI one by dropping the
+// patterns of its variables, so /jobs/{id:[0-9]+} becomes /jobs/{id}
+func Template(tmpl string) string {
+	var b strings.Builder
+	depth := 0
+	skipping := false
+	for _, c := range tmpl {
+		switch {
+		case c == '{':
+			depth++
+			if depth == 1 {
+				skipping = false
+			}
+		case c == '}':
+			depth--
+			if depth == 0 {
+				skipping = false
+				b.WriteRune(c)
+				continue
+			}
+		case c == ':' && depth == 1:
+			skipping = true
+		}
+		if !skipping {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// pathParams returns the variable names of a path template
+func pathParams(path string) []string {
+	var names []string
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
+
+func (r Route) operation(path, tag string) *Operation {
+	op := &Operation{
+		Summary:     r.Summary,
+		Description: r.Description,
+		Tags:        []string{tag},
+		Responses:   map[string]*Response{},
+	}
+	for _, name := range pathParams(path) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	if r.List != nil {
+		op.Parameters = append(op.Parameters, listParams(r.List)...)
+	}
+	op.Parameters = append(op.Parameters, r.Query...)
+
+	if r.Request != nil {
+		content := jsonContent(SchemaOf(r.Request, true))
+		if r.Multipart {
+			content = map[string]MediaType{"multipart/form-data": content["application/json"]}
+		} else if r.Form {
+			content = map[string]MediaType{"application/x-www-form-urlencoded": content["application/json"]}
+		}
+		op.RequestBody = &RequestBody{Required: !r.OptionalBody, Content: content}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if status != http.StatusNoContent && status != http.StatusSwitchingProtocols {
+		data := &Schema{}
+		if r.Response != nil {
+			data = SchemaOf(r.Response, false)
+		}
+		if r.Raw {
+			success.Content = jsonContent(data)
+		} else {
+			success.Content = jsonContent(envelope(data, r.List != nil))
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &Response{Description: "Error", Content: jsonContent(envelope(&Schema{}, false))}
+	return op
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// envelope is the schema of the response envelope around data
+func envelope(data *Schema, list bool) *Schema {
+	metadata := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"timestamp":   {Type: "string"},
+			"request_id":  {Type: "string"},
+			"next_cursor": {Type: "string"},
+			"has_more":    {Type: "boolean"},
+		},
+		Required: []string{"timestamp", "request_id"},
+	}
+	if list {
+		metadata.Required = append(metadata.Required, "has_more")
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"data":    data,
+			"error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code":    {Type: "string"},
+					"message": {Type: "string"},
+					"details": {Type: "string"},
+				},
+				Required: []string{"code", "message"},
+			},
+			"metadata": metadata,
+		},
+		Required:             []string{"success", "metadata"},
+		AdditionalProperties: false,
+	}
+}
+
+// listParams documents the query parameters of a pagination spec
+func listParams(spec *pagination.Spec) []Parameter {
+	params := []Parameter{
+		{Name: "limit", In: "query", Description: fmt.Sprintf("Page size, at most %d", pagination.MaxLimit),
+			Schema: &Schema{Type: "integer", Minimum: ptr(1.0), Default: pagination.DefaultLimit}},
+		{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: &Schema{Type: "string"}},
+	}
+
+	names := make([]string, 0, len(spec.Fields))
+	for name := range spec.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sorts []interface{}
+	for _, name := range names {
+		if spec.Fields[name].Sortable {
+			sorts = append(sorts, name, "-"+name)
+		}
+	}
+	if len(sorts) > 0 {
+		sortParam := Parameter{Name: "sort", In: "query", Description: "Sort field, descending with a leading -",
+			Schema: &Schema{Type: "string", Enum: sorts}}
+		if spec.DefaultSort != "" {
+			sortParam.Schema.Default = spec.DefaultSort
+		}
+		params = append(params, sortParam)
+	}
+
+	for _, name := range names {
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "query",
+			Description: "Filter; also " + name + "[op] with op eq, ne, gt, gte, lt, lte or in",
+			Schema:      kindSchema(spec.Fields[name].Kind),
+		})
+	}
+	return params
+}
+
+func kindSchema(kind pagination.Kind) *Schema {
+	switch kind {
+	case pagination.Number:
+		return &Schema{Type: "number"}
+	case pagination.Time:
+		return &Schema{Type: "string", Format: "date-time"}
+	case pagination.ID:
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+	return &Schema{Type: "string"}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"truckify/shared/pkg/pagination"
+	"truckify/shared/pkg/response"
+)
+
+type base struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type job struct {
+	base
+	Status   string     `json:"status"`
+	DriverID *uuid.UUID `json:"driver_id"`
+	Notes    string     `json:"notes,omitempty"`
+	Internal string     `json:"-"`
+}
+
+type createJob struct {
+	CargoType   string   `json:"cargo_type" validate:"required,max=50"`
+	Weight      float64  `json:"weight" validate:"required,gt=0"`
+	VehicleType string   `json:"vehicle_type" validate:"required,oneof=flatbed tanker"`
+	Contact     string   `json:"contact" validate:"omitempty,email"`
+	Tags        []string `json:"tags" validate:"max=3,dive,min=1"`
+}
+
+var testRoutes = Routes{
+	"GET /jobs":         {Summary: "List jobs", Response: []job{}, List: &pagination.Spec{Fields: map[string]pagination.Field{"created_at": {Column: "created_at", Kind: pagination.Time, Sortable: true}}, DefaultSort: "-created_at"}},
+	"POST /jobs":        {Summary: "Create a job", Request: createJob{}, Response: job{}, Status: http.StatusCreated},
+	"GET /jobs/{id}":    {Summary: "Get a job", Response: job{}},
+	"DELETE /jobs/{id}": {Summary: "Delete a job", Status: http.StatusNoContent},
+}
+
+func newRouter(get http.HandlerFunc) *mux.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router := mux.NewRouter()
+	router.Handle("/metrics", http.HandlerFunc(ok))
+	router.HandleFunc("/jobs", ok).Methods(http.MethodGet)
+	router.HandleFunc("/jobs", ok).Methods(http.MethodPost)
+	router.HandleFunc("/jobs/{id:[0-9a-f-]+}", get).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{id}", ok).Methods(http.MethodDelete)
+	return router
+}
+
+func TestBuild(t *testing.T) {
+	doc := Build(newRouter(nil), Info{Title: "job-service", Version: "1.0.0"}, testRoutes)
+
+	assert.Len(t, doc.Paths, 2, "metrics is left out")
+	get := doc.Operation(http.MethodGet, "/jobs/{id}")
+	require.NotNil(t, get)
+	assert.Equal(t, "Get a job", get.Summary)
+	assert.Equal(t, []string{"job-service"}, get.Tags)
+	assert.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, get.Parameters)
+
+	list := doc.Operation(http.MethodGet, "/jobs")
+	var names []string
+	for _, p := range list.Parameters {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"limit", "cursor", "sort", "created_at"}, names)
+	assert.Contains(t, list.Responses["200"].Content["application/json"].Schema.Properties["metadata"].Required, "has_more")
+
+	create := doc.Operation(http.MethodPost, "/jobs")
+	body := create.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"cargo_type", "weight", "vehicle_type"}, body.Required)
+	assert.Equal(t, 50, *body.Properties["cargo_type"].MaxLength)
+	assert.Equal(t, 0.0, *body.Properties["weight"].Minimum)
+	assert.True(t, body.Properties["weight"].ExclusiveMinimum)
+	assert.Equal(t, []interface{}{"flatbed", "tanker"}, body.Properties["vehicle_type"].Enum)
+	assert.Equal(t, "email", body.Properties["contact"].Format)
+	assert.Equal(t, 3, *body.Properties["tags"].MaxItems)
+	assert.Nil(t, body.Properties["tags"].Items.MinLength, "rules after dive are for the items")
+	assert.Contains(t, create.Responses, "201")
+
+	assert.Nil(t, doc.Operation(http.MethodDelete, "/jobs/{id}").Responses["204"].Content)
+
+	// The document is JSON
+	w := httptest.NewRecorder()
+	doc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	var served map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, Version, served["openapi"])
+}
+
+func TestSchemaOfResponse(t *testing.T) {
+	s := SchemaOf(job{}, false)
+
+	assert.Equal(t, []string{"id", "created_at", "status", "driver_id"}, s.Required)
+	assert.Equal(t, false, s.AdditionalProperties)
+	assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, s.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["created_at"])
+	assert.True(t, s.Properties["driver_id"].Nullable)
+	assert.NotContains(t, s.Properties, "Internal")
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(newRouter(nil), testRoutes))
+
+	routes := Routes{"GET /jobs": {}, "POST /jobs": {}, "GET /jobs/{id}": {}, "PUT /jobs/{id}": {}}
+	err := Check(newRouter(nil), routes)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undocumented route DELETE /jobs/{id}")
+	assert.Contains(t, err.Error(), "documented route PUT /jobs/{id} is not registered")
+}
+
+func TestValidate(t *testing.T) {
+	s := SchemaOf(createJob{}, true)
+
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"cargo_type":"steel","weight":2,"vehicle_type":"flatbed","extra":true}`), &v))
+	assert.NoError(t, Validate(s, v), "requests may carry other properties")
+
+	require.NoError(t, json.Unmarshal([]byte(`{"cargo_type":1,"weight":0,"vehicle_type":"ute","contact":"nobody"}`), &v))
+	err := Validate(s, v)
+	require.Error(t, err)
+	for _, want := range []string{"$.cargo_type: is number", "$.weight: 0 is below", "$.vehicle_type: ute is not one of", `$.contact: "nobody" is not a valid email`} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var problems []string
+	report := func(r *http.Request, err error) { problems = append(problems, err.Error()) }
+
+	serve := func(get http.HandlerFunc, method, path, body string) {
+		router := newRouter(get)
+		router.Use(Middleware(Build(router, Info{Title: "job-service"}, testRoutes), report))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, strings.NewReader(body)))
+	}
+
+	id := uuid.New()
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		response.Success(w, job{base: base{ID: id}, Status: "open"}, "")
+	}, http.MethodGet, "/jobs/"+id.String(), "")
+	assert.Empty(t, problems)
+
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		response.Success(w, map[string]interface{}{"id": "not-a-uuid", "status": 3}, "")
+	}, http.MethodGet, "/jobs/"+id.String(), "")
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], `$.data.id: "not-a-uuid" is not a valid uuid`)
+	assert.Contains(t, problems[0], `$.data: missing property "created_at"`)
+	assert.Contains(t, problems[0], "$.data.status: is number, want string")
+
+	problems = nil
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		response.NotFound(w, "Job not found", "", "")
+	}, http.MethodGet, "/jobs/"+id.String(), "")
+	assert.Empty(t, problems, "errors match the default response")
+
+	serve(nil, http.MethodPost, "/jobs", `{"weight":1}`)
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0], `request: $: missing property "cargo_type"`)
+	assert.Contains(t, problems[1], "status 200 is not in the document")
+}
@@ -0,0 +1,270 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is a JSON schema as used by OpenAPI 3.0
+type Schema struct {
+	Type             string             `json:"type,omitempty"`
+	Format           string             `json:"format,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	Nullable         bool               `json:"nullable,omitempty"`
+	Enum             []interface{}      `json:"enum,omitempty"`
+	Default          interface{}        `json:"default,omitempty"`
+	Minimum          *float64           `json:"minimum,omitempty"`
+	Maximum          *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int               `json:"minLength,omitempty"`
+	MaxLength        *int               `json:"maxLength,omitempty"`
+	MinItems         *int               `json:"minItems,omitempty"`
+	MaxItems         *int               `json:"maxItems,omitempty"`
+	Items            *Schema            `json:"items,omitempty"`
+	Properties       map[string]*Schema `json:"properties,omitempty"`
+	Required         []string           `json:"required,omitempty"`
+	// AdditionalProperties is false for a struct, or the *Schema of a
+	// map's values
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaOf returns the schema of the JSON encoding of v's type.
+//
+// Property names come from json tags. For a request, the validator tags
+// set which properties are required and their bounds, enums and formats.
+// For a response every property without omitempty is required, and struct
+// objects do not allow other properties, so that a handler returning
+// something else is caught.
+func SchemaOf(v interface{}, request bool) *Schema {
+	g := generator{request: request, seen: map[reflect.Type]bool{}}
+	return g.schema(reflect.TypeOf(v))
+}
+
+type generator struct {
+	request bool
+	// seen holds the structs being generated, to stop at recursive types
+	seen map[reflect.Type]bool
+}
+
+func (g generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := g.schema(t.Elem())
+		s.Nullable = true
+		return s
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if g.seen[t] {
+			return &Schema{Type: "object"}
+		}
+		g.seen[t] = true
+		defer delete(g.seen, t)
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		if !g.request {
+			s.AdditionalProperties = false
+		}
+		g.fields(t, s)
+		return s
+	}
+	// Interfaces and anything else can hold any value
+	return &Schema{}
+}
+
+// fields adds the properties of struct type t to s, flattening embedded
+// structs as encoding/json does
+func (g generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(f.Type)
+		if hasOption(opts, "string") {
+			prop = &Schema{Type: "string", Nullable: prop.Nullable}
+		}
+		required := !g.request && !hasOption(opts, "omitempty")
+		if g.request {
+			required = constrain(prop, f.Tag.Get("validate"))
+		}
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// constrain applies validator tags to a property's schema and reports
+// whether the property is required. Rules after dive apply to the
+// elements of a list and are left out.
+func constrain(s *Schema, tag string) bool {
+	required := false
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "url", "uri":
+			s.Format = "uri"
+		case "datetime":
+			s.Format = "date-time"
+		case "latitude":
+			bound(s, "-90", false, false)
+			bound(s, "90", true, false)
+		case "longitude":
+			bound(s, "-180", false, false)
+			bound(s, "180", true, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(s.Type, v))
+			}
+			// An empty string is skipped by omitempty
+			if s.Type == "string" && !s.Nullable && slices.Contains(rules, "omitempty") {
+				s.Enum = append(s.Enum, "")
+			}
+		case "min", "gte":
+			bound(s, param, false, false)
+		case "max", "lte":
+			bound(s, param, true, false)
+		case "gt":
+			bound(s, param, false, true)
+		case "lt":
+			bound(s, param, true, true)
+		case "len":
+			bound(s, param, false, false)
+			bound(s, param, true, false)
+		}
+	}
+	return required
+}
+
+// bound sets a lower or upper limit: a length for strings, a size for
+// arrays and a value for numbers
+func bound(s *Schema, param string, upper, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	// Lengths and sizes are whole, so an exclusive bound is one further in
+	size := int(n)
+	if exclusive && upper {
+		size--
+	} else if exclusive {
+		size++
+	}
+	switch s.Type {
+	case "string":
+		if upper {
+			s.MaxLength = &size
+		} else {
+			s.MinLength = &size
+		}
+	case "array":
+		if upper {
+			s.MaxItems = &size
+		} else {
+			s.MinItems = &size
+		}
+	case "number", "integer":
+		if upper {
+			s.Maximum, s.ExclusiveMaximum = &n, exclusive
+		} else {
+			s.Minimum, s.ExclusiveMinimum = &n, exclusive
+		}
+	}
+}
+
+func enumValue(typ, v string) interface{} {
+	if typ == "number" || typ == "integer" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
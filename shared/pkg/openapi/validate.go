@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Validate checks a decoded JSON value against a schema and returns every
+// mismatch found
+func Validate(s *Schema, v interface{}) error {
+	var problems []string
+	validate(s, v, "$", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func validate(s *Schema, v interface{}, at string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+	if s == nil {
+		return
+	}
+	if v == nil {
+		if s.Type != "" && !s.Nullable {
+			fail("is null, want %s", s.Type)
+		}
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e interface{}) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		fail("%v is not one of %v", v, s.Enum)
+	}
+
+	switch s.Type {
+	case "":
+		return
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("is %s, want boolean", kindOf(v))
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("is %s, want %s", kindOf(v), s.Type)
+			return
+		}
+		if s.Type == "integer" && n != float64(int64(n)) {
+			fail("%v is not an integer", n)
+		}
+		if s.Minimum != nil && (n < *s.Minimum || s.ExclusiveMinimum && n == *s.Minimum) {
+			fail("%v is below the minimum %v", n, *s.Minimum)
+		}
+		if s.Maximum != nil && (n > *s.Maximum || s.ExclusiveMaximum && n == *s.Maximum) {
+			fail("%v is above the maximum %v", n, *s.Maximum)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("is %s, want string", kindOf(v))
+			return
+		}
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("is shorter than %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("is longer than %d", *s.MaxLength)
+		}
+		if str != "" && !validFormat(s.Format, str) {
+			fail("%q is not a valid %s", str, s.Format)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			fail("is %s, want array", kindOf(v))
+			return
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("has fewer than %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("has more than %d items", *s.MaxItems)
+		}
+		for i, item := range items {
+			validate(s.Items, item, at+"["+strconv.Itoa(i)+"]", problems)
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("is %s, want object", kindOf(v))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing property %q", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				validate(prop, obj[name], at+"."+name, problems)
+				continue
+			}
+			switch extra := s.AdditionalProperties.(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", name)
+				}
+			case *Schema:
+				validate(extra, obj[name], at+"."+name, problems)
+			}
+		}
+	}
+}
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "uuid":
+		_, err := uuid.Parse(s)
+		return err == nil
+	case "email":
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	}
+	return true
+}
+
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Middleware checks the JSON bodies of requests and responses against doc
+// and passes mismatches to report. It is meant for tests: requests are
+// served as they would be without it. Routes must be served by a mux
+// router for their operation to be found.
+func Middleware(doc *Document, report func(r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tmpl, _ := route.GetPathTemplate()
+			op := doc.Operation(r.Method, Template(tmpl))
+			if op == nil {
+				report(r, fmt.Errorf("%s %s is not in the document", r.Method, Template(tmpl)))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if op.RequestBody != nil && r.Body != nil {
+				body, _ := io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				if len(body) > 0 || op.RequestBody.Required {
+					if err := validateBody(op.RequestBody.Content, body); err != nil {
+						report(r, fmt.Errorf("request: %w", err))
+					}
+				}
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			res := op.Responses[strconv.Itoa(rec.status)]
+			if res == nil && rec.status >= http.StatusBadRequest {
+				res = op.Responses["default"]
+			}
+			if res == nil {
+				report(r, fmt.Errorf("response: status %d is not in the document", rec.status))
+				return
+			}
+			if err := validateBody(res.Content, rec.body.Bytes()); err != nil {
+				report(r, fmt.Errorf("response %d: %w", rec.status, err))
+			}
+		})
+	}
+}
+
+// validateBody checks a body against the JSON schema of content. Bodies
+// without a JSON schema are not checked.
+func validateBody(content map[string]MediaType, body []byte) error {
+	media, ok := content["application/json"]
+	if !ok {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(media.Schema, v)
+}
+
+// recorder keeps a copy of the response
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,323 @@
+// Package pagination implements the query conventions shared by list
+// endpoints: keyset cursors, filters and sorting.
+//
+// A list request may carry
+//
+//	limit=20               page size, at most MaxLimit
+//	cursor=<next_cursor>   continue after the previous page
+//	sort=-created_at       sort field, descending with a leading "-"
+//	status=open            filter, equal to
+//	price[gte]=100         filter with an operator: eq, ne, gt, gte, lt, lte or in
+//	status[in]=open,bidding
+//
+// Only the fields an endpoint declares in its Spec can be filtered or
+// sorted; other query parameters are left to the handler. Rows are ordered
+// by the sort field and then by ID, so cursors stay stable while rows are
+// added.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLimit is the page size when a request has no limit
+	DefaultLimit = 20
+	// MaxLimit caps the page size
+	MaxLimit = 100
+)
+
+// ErrInvalid is wrapped by the errors Parse returns for a malformed query
+var ErrInvalid = errors.New("invalid list parameters")
+
+// Kind is the type of a field's values
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Time
+	ID
+)
+
+// Filter operators
+const (
+	Eq  = "eq"
+	Ne  = "ne"
+	Gt  = "gt"
+	Gte = "gte"
+	Lt  = "lt"
+	Lte = "lte"
+	In  = "in"
+)
+
+var comparisons = map[string]string{Eq: "=", Ne: "<>", Gt: ">", Gte: ">=", Lt: "<", Lte: "<="}
+
+// Field is a column a list can be filtered or sorted by
+type Field struct {
+	Column string
+	Kind   Kind
+	// Sortable allows ordering by the field. The column must not be null.
+	Sortable bool
+}
+
+// Spec declares the fields of a list endpoint
+type Spec struct {
+	Fields map[string]Field
+	// DefaultSort is the sort of a request without one, such as
+	// "-created_at". Without it rows are ordered by ID.
+	DefaultSort string
+	// IDColumn breaks ties between rows with the same sort value; "id" if
+	// empty
+	IDColumn string
+}
+
+// Sort orders a list by one field
+type Sort struct {
+	Field  string
+	Column string
+	Kind   Kind
+	Desc   bool
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Filter is a condition on one field. Values holds one value, or several
+// for In.
+type Filter struct {
+	Field  string
+	Column string
+	Op     string
+	Values []interface{}
+}
+
+// Page is a parsed list request
+type Page struct {
+	Limit   int
+	Sort    Sort
+	Filters []Filter
+
+	idColumn string
+	// after is the position of the last row of the previous page
+	after *position
+}
+
+type position struct {
+	value interface{}
+	id    uuid.UUID
+}
+
+// cursor is the JSON inside an opaque cursor
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    string `json:"id"`
+}
+
+// Parse reads a list request from a query string
+func Parse(q url.Values, spec Spec) (Page, error) {
+	p := Page{Limit: DefaultLimit, idColumn: spec.IDColumn}
+
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return Page{}, fmt.Errorf("%w: limit must be a positive number", ErrInvalid)
+		}
+		p.Limit = min(n, MaxLimit)
+	}
+
+	order := q.Get("sort")
+	if order == "" {
+		order = spec.DefaultSort
+	}
+	if order != "" {
+		s := Sort{Field: strings.TrimPrefix(order, "-"), Desc: strings.HasPrefix(order, "-")}
+		field, ok := spec.Fields[s.Field]
+		if !ok || !field.Sortable {
+			return Page{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalid, s.Field)
+		}
+		s.Column, s.Kind = field.Column, field.Kind
+		p.Sort = s
+	}
+
+	// In a fixed order, so the same request always builds the same query
+	for _, key := range slices.Sorted(maps.Keys(q)) {
+		values := q[key]
+		name, op := key, Eq
+		if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+			name, op = key[:i], key[i+1:len(key)-1]
+		}
+		field, ok := spec.Fields[name]
+		if !ok {
+			continue
+		}
+		if _, ok := comparisons[op]; !ok && op != In {
+			return Page{}, fmt.Errorf("%w: unknown operator %q for %s", ErrInvalid, op, name)
+		}
+		for _, raw := range values {
+			parts := []string{raw}
+			if op == In {
+				parts = strings.Split(raw, ",")
+			}
+			f := Filter{Field: name, Column: field.Column, Op: op}
+			for _, part := range parts {
+				v, err := parseValue(field.Kind, strings.TrimSpace(part))
+				if err != nil {
+					return Page{}, fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+				}
+				f.Values = append(f.Values, v)
+			}
+			p.Filters = append(p.Filters, f)
+		}
+	}
+
+	if raw := q.Get("cursor"); raw != "" {
+		after, err := p.decode(raw)
+		if err != nil {
+			return Page{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		p.after = after
+	}
+	return p, nil
+}
+
+// Apply appends the page's filters, cursor position, order and limit to
+// query, which must end in a WHERE clause, and returns it with its
+// arguments. One row more than the limit is fetched so that Cut can tell
+// whether another page follows.
+func (p Page) Apply(query string, args []interface{}) (string, []interface{}) {
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var b strings.Builder
+	b.WriteString(query)
+	for _, f := range p.Filters {
+		if f.Op == In {
+			placeholders := make([]string, len(f.Values))
+			for i, v := range f.Values {
+				placeholders[i] = arg(v)
+			}
+			fmt.Fprintf(&b, " AND %s IN (%s)", f.Column, strings.Join(placeholders, ", "))
+			continue
+		}
+		fmt.Fprintf(&b, " AND %s %s %s", f.Column, comparisons[f.Op], arg(f.Values[0]))
+	}
+
+	id := p.idColumn
+	if id == "" {
+		id = "id"
+	}
+	dir, cmp := "ASC", ">"
+	if p.Sort.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if p.after != nil {
+		if p.Sort.Column == "" {
+			fmt.Fprintf(&b, " AND %s %s %s", id, cmp, arg(p.after.id))
+		} else {
+			fmt.Fprintf(&b, " AND (%s, %s) %s (%s, %s)", p.Sort.Column, id, cmp, arg(p.after.value), arg(p.after.id))
+		}
+	}
+
+	b.WriteString(" ORDER BY ")
+	if p.Sort.Column != "" {
+		fmt.Fprintf(&b, "%s %s, ", p.Sort.Column, dir)
+	}
+	fmt.Fprintf(&b, "%s %s LIMIT %s", id, dir, arg(p.limit()+1))
+	return b.String(), args
+}
+
+// Cut trims rows fetched with Apply to the page size and returns the
+// cursor of the next page, or "" on the last page. key returns a row's
+// value of the named sort field and its ID.
+func Cut[T any](p Page, rows []T, key func(row T, field string) (interface{}, uuid.UUID)) ([]T, string) {
+	if len(rows) <= p.limit() {
+		return rows, ""
+	}
+	rows = rows[:p.limit()]
+	value, id := key(rows[len(rows)-1], p.Sort.Field)
+	c := cursor{Sort: p.Sort.String(), ID: id.String()}
+	if p.Sort.Field != "" {
+		c.Value = formatValue(value)
+	}
+	data, _ := json.Marshal(c)
+	return rows, base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (p Page) limit() int {
+	if p.Limit < 1 {
+		return DefaultLimit
+	}
+	return p.Limit
+}
+
+// decode reads a cursor, which must come from a page with the same sort
+func (p Page) decode(raw string) (*position, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	if c.Sort != p.Sort.String() {
+		return nil, errors.New("cursor is for a different sort")
+	}
+	id, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	pos := &position{id: id}
+	if p.Sort.Field != "" {
+		if pos.value, err = parseValue(p.Sort.Kind, c.Value); err != nil {
+			return nil, errors.New("malformed cursor")
+		}
+	}
+	return pos, nil
+}
+
+func parseValue(kind Kind, raw string) (interface{}, error) {
+	switch kind {
+	case Number:
+		return strconv.ParseFloat(raw, 64)
+	case Time:
+		if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", raw)
+	case ID:
+		return uuid.Parse(raw)
+	}
+	return raw, nil
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
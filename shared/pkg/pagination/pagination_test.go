@@ -0,0 +1,113 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var jobSpec = Spec{
+	Fields: map[string]Field{
+		"status":     {Column: "status"},
+		"price":      {Column: "price", Kind: Number, Sortable: true},
+		"created_at": {Column: "created_at", Kind: Time, Sortable: true},
+	},
+	DefaultSort: "-created_at",
+}
+
+type row struct {
+	ID        uuid.UUID
+	Price     float64
+	CreatedAt time.Time
+}
+
+func rowKey(r row, field string) (interface{}, uuid.UUID) {
+	if field == "price" {
+		return r.Price, r.ID
+	}
+	return r.CreatedAt, r.ID
+}
+
+func parse(t *testing.T, query string) Page {
+	t.Helper()
+	q, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	p, err := Parse(q, jobSpec)
+	require.NoError(t, err)
+	return p
+}
+
+func TestApply(t *testing.T) {
+	p := parse(t, "status[in]=open,bidding&price[gte]=100&limit=5&other=ignored")
+
+	query, args := p.Apply("SELECT * FROM jobs WHERE shipper_id = $1", []interface{}{"s-1"})
+	assert.Equal(t, "SELECT * FROM jobs WHERE shipper_id = $1 AND price >= $2 AND status IN ($3, $4) ORDER BY created_at DESC, id DESC LIMIT $5", query)
+	assert.Equal(t, []interface{}{"s-1", 100.0, "open", "bidding", 6}, args)
+}
+
+func TestCursorContinuesAfterLastRow(t *testing.T) {
+	p := parse(t, "sort=price&limit=2")
+	rows := []row{
+		{ID: uuid.New(), Price: 10},
+		{ID: uuid.New(), Price: 20},
+		{ID: uuid.New(), Price: 20},
+	}
+
+	page, next := Cut(p, rows, rowKey)
+	require.Len(t, page, 2)
+	require.NotEmpty(t, next)
+
+	p = parse(t, "sort=price&limit=2&cursor="+next)
+	query, args := p.Apply("SELECT * FROM jobs WHERE TRUE", nil)
+	assert.Equal(t, "SELECT * FROM jobs WHERE TRUE AND (price, id) > ($1, $2) ORDER BY price ASC, id ASC LIMIT $3", query)
+	assert.Equal(t, []interface{}{20.0, rows[1].ID, 3}, args)
+
+	// The last page has no cursor
+	page, next = Cut(p, rows[2:], rowKey)
+	assert.Len(t, page, 1)
+	assert.Empty(t, next)
+}
+
+func TestTimeCursorKeepsPrecision(t *testing.T) {
+	p := parse(t, "")
+	created := time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	rows := make([]row, DefaultLimit+1)
+	for i := range rows {
+		rows[i] = row{ID: uuid.New(), CreatedAt: created}
+	}
+
+	_, next := Cut(p, rows, rowKey)
+	p = parse(t, "cursor="+next)
+	_, args := p.Apply("SELECT * FROM jobs WHERE TRUE", nil)
+	assert.Equal(t, created, args[0])
+}
+
+func TestParseRejects(t *testing.T) {
+	_, next := Cut(parse(t, "sort=price&limit=1"), []row{{ID: uuid.New()}, {ID: uuid.New()}}, rowKey)
+
+	tests := map[string]string{
+		"bad limit":           "limit=0",
+		"unknown sort":        "sort=-status",
+		"unknown operator":    "price[like]=1",
+		"bad value":           "price=cheap",
+		"malformed cursor":    "cursor=not-a-cursor",
+		"cursor of a sort":    "cursor=" + next,
+		"bad time in a range": "created_at[gt]=yesterday",
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, _ := url.ParseQuery(query)
+			_, err := Parse(q, jobSpec)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestLimitIsCapped(t *testing.T) {
+	assert.Equal(t, MaxLimit, parse(t, "limit=5000").Limit)
+	assert.Equal(t, DefaultLimit, parse(t, "").Limit)
+}
@@ -27,6 +27,9 @@ type ErrorInfo struct {
 type Metadata struct {
 	Timestamp string `json:"timestamp"`
 	RequestID string `json:"request_id"`
+	// NextCursor and HasMore are set on pages of a list
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    *bool  `json:"has_more,omitempty"`
 }
 
 // Success sends a successful response
@@ -41,6 +44,22 @@ func Success(w http.ResponseWriter, data interface{}, requestID string) {
 	})
 }
 
+// List sends a page of a list. nextCursor continues the list and is empty
+// on the last page.
+func List(w http.ResponseWriter, data interface{}, nextCursor, requestID string) {
+	hasMore := nextCursor != ""
+	Send(w, http.StatusOK, Response{
+		Success: true,
+		Data:    data,
+		Metadata: Metadata{
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+			RequestID:  getRequestID(requestID),
+			NextCursor: nextCursor,
+			HasMore:    &hasMore,
+		},
+	})
+}
+
 // Created sends a 201 Created response
 func Created(w http.ResponseWriter, data interface{}, requestID string) {
 	Send(w, http.StatusCreated, Response{
@@ -100,6 +119,11 @@ func Conflict(w http.ResponseWriter, message, details, requestID string) {
 	Error(w, http.StatusConflict, "CONFLICT", message, details, requestID)
 }
 
+// InvalidTransition sends a 409 Conflict response for a rejected state change
+func InvalidTransition(w http.ResponseWriter, message, details, requestID string) {
+	Error(w, http.StatusConflict, "INVALID_STATE_TRANSITION", message, details, requestID)
+}
+
 // InternalServerError sends a 500 Internal Server Error response
 func InternalServerError(w http.ResponseWriter, message, details, requestID string) {
 	Error(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", message, details, requestID)
@@ -48,6 +48,26 @@ func TestCreated(t *testing.T) {
 	assert.Equal(t, requestID, resp.Metadata.RequestID)
 }
 
+func TestList(t *testing.T) {
+	w := httptest.NewRecorder()
+	List(w, []string{"a", "b"}, "next", "test-request-id")
+
+	var resp struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "next", resp.Metadata["next_cursor"])
+	assert.Equal(t, true, resp.Metadata["has_more"])
+
+	// The last page says so rather than leaving has_more out
+	w = httptest.NewRecorder()
+	List(w, []string{}, "", "test-request-id")
+	resp.Metadata = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.NotContains(t, resp.Metadata, "next_cursor")
+	assert.Equal(t, false, resp.Metadata["has_more"])
+}
+
 func TestNoContent(t *testing.T) {
 	w := httptest.NewRecorder()
 	requestID := "test-request-id"
@@ -162,6 +182,23 @@ func TestConflict(t *testing.T) {
 	assert.Equal(t, "CONFLICT", resp.Error.Code)
 }
 
+func TestInvalidTransition(t *testing.T) {
+	w := httptest.NewRecorder()
+	requestID := "test-request-id"
+
+	InvalidTransition(w, "Invalid status transition", "cannot move job from cancelled to delivered", requestID)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var resp Response
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+
+	assert.False(t, resp.Success)
+	assert.Equal(t, "INVALID_STATE_TRANSITION", resp.Error.Code)
+	assert.Equal(t, "cannot move job from cancelled to delivered", resp.Error.Details)
+}
+
 func TestInternalServerError(t *testing.T) {
 	w := httptest.NewRecorder()
 	requestID := "test-request-id"
@@ -0,0 +1,55 @@
+// Package safehttp makes requests to URLs that users supply, such as webhook
+// endpoints and identity providers, without letting them reach the
+// platform's own network.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a URL names, or a host name resolves
+// to, a private address
+var ErrPrivateAddress = errors.New("private address")
+
+// NewClient returns a client that refuses to connect to private addresses.
+// Every connection is checked after the host name is resolved, so redirects
+// and DNS answers pointing inside the network are refused too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: DenyPrivate}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+	}
+}
+
+// DenyPrivate is a net.Dialer Control function that refuses connections to
+// private addresses
+func DenyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || PrivateIP(ip) {
+		return fmt.Errorf("%w %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// PrivateHost reports whether a URL's host name is localhost or a private
+// address. Other host names are checked when they are dialled.
+func PrivateHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && PrivateIP(ip))
+}
+
+// PrivateIP reports whether ip is a private, loopback, link-local,
+// multicast or unspecified address
+func PrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
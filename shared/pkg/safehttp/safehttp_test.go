@@ -0,0 +1,38 @@
+package safehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrivateHost(t *testing.T) {
+	for host, private := range map[string]bool{
+		"example.com":     false,
+		"93.184.216.34":   false,
+		"localhost":       true,
+		"127.0.0.1":       true,
+		"10.0.0.5":        true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"::1":             true,
+		"fe80::1":         true,
+		"0.0.0.0":         true,
+	} {
+		if got := PrivateHost(host); got != private {
+			t.Errorf("PrivateHost(%s) = %v, want %v", host, got, private)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("expected ErrPrivateAddress, got %v", err)
+	}
+}
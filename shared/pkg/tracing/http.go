@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"truckify/shared/pkg/middleware"
+)
+
+// Middleware records a server span for each request. It continues the trace
+// from an incoming traceparent header, or starts a new one, and names the
+// span after the gorilla/mux route template.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		ctx, span := tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if requestID, ok := r.Context().Value("request_id").(string); ok {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+
+		wrapped := middleware.NewStatusWriter(w)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.Status()))
+		if wrapped.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.Status()))
+		}
+	})
+}
+
+// Transport wraps base so each outgoing request records a client span and
+// carries the caller's trace context and request ID
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+// NewClient returns an HTTP client for calls to other services
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: Transport(nil), Timeout: timeout}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer().Start(req.Context(), req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+
+	out := req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(out.Header))
+	if requestID, ok := ctx.Value("request_id").(string); ok && out.Header.Get("X-Request-ID") == "" {
+		out.Header.Set("X-Request-ID", requestID)
+	}
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"truckify/shared/pkg/logger"
+)
+
+const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter := NewInMemory()
+
+	var inner trace.SpanContext
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/123", nil)
+	req.Header.Set("traceparent", parent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /jobs/{id}", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, span.SpanContext.SpanID(), inner.SpanID())
+	assert.Equal(t, "Error", span.Status.Code.String())
+}
+
+func TestTransportInjectsTraceContext(t *testing.T) {
+	exporter := NewInMemory()
+
+	var traceparent, requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		requestID = r.Header.Get("X-Request-ID")
+	}))
+	defer server.Close()
+
+	ctx, span := tracer().Start(context.WithValue(context.Background(), "request_id", "req-1"), "caller")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/drivers", nil)
+	resp, err := NewClient(0).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	client := spans[0]
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, span.SpanContext().TraceID(), client.SpanContext.TraceID())
+	assert.Equal(t, "00-"+client.SpanContext.TraceID().String()+"-"+client.SpanContext.SpanID().String()+"-01", traceparent)
+	assert.Equal(t, "req-1", requestID)
+}
+
+func TestLoggerAddsTraceIDs(t *testing.T) {
+	NewInMemory()
+
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	ctx, span := tracer().Start(context.Background(), "work")
+	defer span.End()
+	log.WithContext(ctx).Info("hello")
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+}